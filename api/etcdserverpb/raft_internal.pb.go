@@ -72,18 +72,22 @@ var xxx_messageInfo_RequestHeader proto.InternalMessageInfo
 // An InternalRaftRequest is the union of all requests which can be
 // sent via raft.
 type InternalRaftRequest struct {
-	Header                   *RequestHeader                            `protobuf:"bytes,100,opt,name=header,proto3" json:"header,omitempty"`
-	ID                       uint64                                    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	V2                       *Request                                  `protobuf:"bytes,2,opt,name=v2,proto3" json:"v2,omitempty"`
-	Range                    *RangeRequest                             `protobuf:"bytes,3,opt,name=range,proto3" json:"range,omitempty"`
-	Put                      *PutRequest                               `protobuf:"bytes,4,opt,name=put,proto3" json:"put,omitempty"`
-	DeleteRange              *DeleteRangeRequest                       `protobuf:"bytes,5,opt,name=delete_range,json=deleteRange,proto3" json:"delete_range,omitempty"`
-	Txn                      *TxnRequest                               `protobuf:"bytes,6,opt,name=txn,proto3" json:"txn,omitempty"`
-	Compaction               *CompactionRequest                        `protobuf:"bytes,7,opt,name=compaction,proto3" json:"compaction,omitempty"`
-	LeaseGrant               *LeaseGrantRequest                        `protobuf:"bytes,8,opt,name=lease_grant,json=leaseGrant,proto3" json:"lease_grant,omitempty"`
-	LeaseRevoke              *LeaseRevokeRequest                       `protobuf:"bytes,9,opt,name=lease_revoke,json=leaseRevoke,proto3" json:"lease_revoke,omitempty"`
-	Alarm                    *AlarmRequest                             `protobuf:"bytes,10,opt,name=alarm,proto3" json:"alarm,omitempty"`
-	LeaseCheckpoint          *LeaseCheckpointRequest                   `protobuf:"bytes,11,opt,name=lease_checkpoint,json=leaseCheckpoint,proto3" json:"lease_checkpoint,omitempty"`
+	Header          *RequestHeader          `protobuf:"bytes,100,opt,name=header,proto3" json:"header,omitempty"`
+	ID              uint64                  `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	V2              *Request                `protobuf:"bytes,2,opt,name=v2,proto3" json:"v2,omitempty"`
+	Range           *RangeRequest           `protobuf:"bytes,3,opt,name=range,proto3" json:"range,omitempty"`
+	Put             *PutRequest             `protobuf:"bytes,4,opt,name=put,proto3" json:"put,omitempty"`
+	DeleteRange     *DeleteRangeRequest     `protobuf:"bytes,5,opt,name=delete_range,json=deleteRange,proto3" json:"delete_range,omitempty"`
+	Txn             *TxnRequest             `protobuf:"bytes,6,opt,name=txn,proto3" json:"txn,omitempty"`
+	Compaction      *CompactionRequest      `protobuf:"bytes,7,opt,name=compaction,proto3" json:"compaction,omitempty"`
+	LeaseGrant      *LeaseGrantRequest      `protobuf:"bytes,8,opt,name=lease_grant,json=leaseGrant,proto3" json:"lease_grant,omitempty"`
+	LeaseRevoke     *LeaseRevokeRequest     `protobuf:"bytes,9,opt,name=lease_revoke,json=leaseRevoke,proto3" json:"lease_revoke,omitempty"`
+	Alarm           *AlarmRequest           `protobuf:"bytes,10,opt,name=alarm,proto3" json:"alarm,omitempty"`
+	LeaseCheckpoint *LeaseCheckpointRequest `protobuf:"bytes,11,opt,name=lease_checkpoint,json=leaseCheckpoint,proto3" json:"lease_checkpoint,omitempty"`
+	// key_expiry is proposed by the leader to delete keys whose ttl elapsed;
+	// each key is deleted only if it was not modified since it expired.
+	KeyExpiry                *TxnRequest                               `protobuf:"bytes,12,opt,name=key_expiry,json=keyExpiry,proto3" json:"key_expiry,omitempty"`
+	PolicyPut                *PolicyPutRequest                         `protobuf:"bytes,13,opt,name=policy_put,json=policyPut,proto3" json:"policy_put,omitempty"`
 	AuthEnable               *AuthEnableRequest                        `protobuf:"bytes,1000,opt,name=auth_enable,json=authEnable,proto3" json:"auth_enable,omitempty"`
 	AuthDisable              *AuthDisableRequest                       `protobuf:"bytes,1011,opt,name=auth_disable,json=authDisable,proto3" json:"auth_disable,omitempty"`
 	AuthStatus               *AuthStatusRequest                        `protobuf:"bytes,1013,opt,name=auth_status,json=authStatus,proto3" json:"auth_status,omitempty"`
@@ -96,6 +100,7 @@ type InternalRaftRequest struct {
 	AuthUserRevokeRole       *AuthUserRevokeRoleRequest                `protobuf:"bytes,1105,opt,name=auth_user_revoke_role,json=authUserRevokeRole,proto3" json:"auth_user_revoke_role,omitempty"`
 	AuthUserList             *AuthUserListRequest                      `protobuf:"bytes,1106,opt,name=auth_user_list,json=authUserList,proto3" json:"auth_user_list,omitempty"`
 	AuthRoleList             *AuthRoleListRequest                      `protobuf:"bytes,1107,opt,name=auth_role_list,json=authRoleList,proto3" json:"auth_role_list,omitempty"`
+	AuthUserTokenRevoke      *AuthUserTokenRevokeRequest               `protobuf:"bytes,1108,opt,name=auth_user_token_revoke,json=authUserTokenRevoke,proto3" json:"auth_user_token_revoke,omitempty"`
 	AuthRoleAdd              *AuthRoleAddRequest                       `protobuf:"bytes,1200,opt,name=auth_role_add,json=authRoleAdd,proto3" json:"auth_role_add,omitempty"`
 	AuthRoleDelete           *AuthRoleDeleteRequest                    `protobuf:"bytes,1201,opt,name=auth_role_delete,json=authRoleDelete,proto3" json:"auth_role_delete,omitempty"`
 	AuthRoleGet              *AuthRoleGetRequest                       `protobuf:"bytes,1202,opt,name=auth_role_get,json=authRoleGet,proto3" json:"auth_role_get,omitempty"`
@@ -237,70 +242,75 @@ func init() {
 func init() { proto.RegisterFile("raft_internal.proto", fileDescriptor_b4c9a9be0cfca103) }
 
 var fileDescriptor_b4c9a9be0cfca103 = []byte{
-	// 1003 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x96, 0xd9, 0x72, 0x1b, 0x45,
-	0x14, 0x86, 0x23, 0xc5, 0x71, 0xac, 0x96, 0xed, 0x38, 0x6d, 0x87, 0x34, 0x72, 0x95, 0x70, 0x1c,
-	0x12, 0xcc, 0x66, 0x53, 0xca, 0x03, 0x80, 0x90, 0x5c, 0x8e, 0xab, 0x42, 0x70, 0x4d, 0xcc, 0x52,
-	0xc5, 0xc5, 0xd0, 0x9a, 0x39, 0x96, 0x06, 0xcf, 0x46, 0x77, 0x4b, 0x31, 0xef, 0x11, 0x28, 0x1e,
-	0x83, 0xed, 0x21, 0x72, 0xc1, 0x62, 0xe0, 0x05, 0xc0, 0xdc, 0x70, 0x0f, 0xdc, 0x53, 0xbd, 0xcc,
-	0x26, 0xb5, 0x7c, 0xa7, 0xf9, 0xcf, 0x7f, 0xbe, 0x73, 0xba, 0xe7, 0xf4, 0xa8, 0xd1, 0x3a, 0xa3,
-	0x27, 0xc2, 0x0d, 0x62, 0x01, 0x2c, 0xa6, 0xe1, 0x6e, 0xca, 0x12, 0x91, 0xe0, 0x65, 0x10, 0x9e,
-	0xcf, 0x81, 0x4d, 0x80, 0xa5, 0x83, 0xd6, 0xc6, 0x30, 0x19, 0x26, 0x2a, 0xb0, 0x27, 0x7f, 0x69,
-	0x4f, 0x6b, 0xad, 0xf0, 0x18, 0xa5, 0xc1, 0x52, 0xcf, 0xfc, 0xbc, 0x2f, 0x83, 0x7b, 0x34, 0x0d,
-	0xf6, 0x22, 0x88, 0x06, 0xc0, 0xf8, 0x28, 0x48, 0xd3, 0x41, 0xe9, 0x41, 0xfb, 0xb6, 0x3f, 0x45,
-	0x2b, 0x0e, 0x7c, 0x3e, 0x06, 0x2e, 0x1e, 0x02, 0xf5, 0x81, 0xe1, 0x55, 0x54, 0x3f, 0xec, 0x93,
-	0xda, 0x56, 0x6d, 0x67, 0xc1, 0xa9, 0x1f, 0xf6, 0x71, 0x0b, 0x2d, 0x8d, 0xb9, 0x6c, 0x2d, 0x02,
-	0x52, 0xdf, 0xaa, 0xed, 0x34, 0x9c, 0xfc, 0x19, 0xdf, 0x45, 0x2b, 0x74, 0x2c, 0x46, 0x2e, 0x83,
-	0x49, 0xc0, 0x83, 0x24, 0x26, 0x57, 0x55, 0xda, 0xb2, 0x14, 0x1d, 0xa3, 0x6d, 0x3f, 0xc3, 0x68,
-	0xfd, 0xd0, 0xac, 0xce, 0xa1, 0x27, 0xc2, 0x94, 0xc3, 0x0f, 0xd0, 0xe2, 0x48, 0x95, 0x24, 0xfe,
-	0x56, 0x6d, 0xa7, 0xd9, 0xd9, 0xdc, 0x2d, 0xaf, 0x79, 0xb7, 0xd2, 0x95, 0x63, 0xac, 0x33, 0xdd,
-	0xdd, 0x43, 0xf5, 0x49, 0x47, 0xf5, 0xd5, 0xec, 0xdc, 0xb2, 0x02, 0x9c, 0xfa, 0xa4, 0x83, 0xdf,
-	0x42, 0xd7, 0x18, 0x8d, 0x87, 0xa0, 0x1a, 0x6c, 0x76, 0x5a, 0x53, 0x4e, 0x19, 0xca, 0xec, 0xda,
-	0x88, 0x5f, 0x43, 0x57, 0xd3, 0xb1, 0x20, 0x0b, 0xca, 0x4f, 0xaa, 0xfe, 0xa3, 0x71, 0xb6, 0x08,
-	0x47, 0x9a, 0x70, 0x0f, 0x2d, 0xfb, 0x10, 0x82, 0x00, 0x57, 0x17, 0xb9, 0xa6, 0x92, 0xb6, 0xaa,
-	0x49, 0x7d, 0xe5, 0xa8, 0x94, 0x6a, 0xfa, 0x85, 0x26, 0x0b, 0x8a, 0xb3, 0x98, 0x2c, 0xda, 0x0a,
-	0x1e, 0x9f, 0xc5, 0x79, 0x41, 0x71, 0x16, 0xe3, 0xb7, 0x11, 0xf2, 0x92, 0x28, 0xa5, 0x9e, 0x90,
-	0x9b, 0x7e, 0x5d, 0xa5, 0xbc, 0x54, 0x4d, 0xe9, 0xe5, 0xf1, 0x2c, 0xb3, 0x94, 0x82, 0xdf, 0x41,
-	0xcd, 0x10, 0x28, 0x07, 0x77, 0xc8, 0x68, 0x2c, 0xc8, 0x92, 0x8d, 0xf0, 0x48, 0x1a, 0x0e, 0x64,
-	0x3c, 0x27, 0x84, 0xb9, 0x24, 0xd7, 0xac, 0x09, 0x0c, 0x26, 0xc9, 0x29, 0x90, 0x86, 0x6d, 0xcd,
-	0x0a, 0xe1, 0x28, 0x43, 0xbe, 0xe6, 0xb0, 0xd0, 0xe4, 0x6b, 0xa1, 0x21, 0x65, 0x11, 0x41, 0xb6,
-	0xd7, 0xd2, 0x95, 0xa1, 0xfc, 0xb5, 0x28, 0x23, 0x7e, 0x1f, 0xad, 0xe9, 0xb2, 0xde, 0x08, 0xbc,
-	0xd3, 0x34, 0x09, 0x62, 0x41, 0x9a, 0x2a, 0xf9, 0x65, 0x4b, 0xe9, 0x5e, 0x6e, 0xca, 0x30, 0x37,
-	0xc2, 0xaa, 0x8e, 0xbb, 0xa8, 0xa9, 0x46, 0x18, 0x62, 0x3a, 0x08, 0x81, 0xfc, 0x6d, 0xdd, 0xcc,
-	0xee, 0x58, 0x8c, 0xf6, 0x95, 0x21, 0xdf, 0x0a, 0x9a, 0x4b, 0xb8, 0x8f, 0xd4, 0xc0, 0xbb, 0x7e,
-	0xc0, 0x15, 0xe3, 0x9f, 0xeb, 0xb6, 0xbd, 0x90, 0x8c, 0xbe, 0x76, 0xe4, 0x7b, 0x41, 0x0b, 0x2d,
-	0x6f, 0x84, 0x0b, 0x2a, 0xc6, 0x9c, 0xfc, 0x37, 0xb7, 0x91, 0x27, 0xca, 0x50, 0x69, 0x44, 0x4b,
-	0xf8, 0xb1, 0x6e, 0x04, 0x62, 0x11, 0x78, 0x54, 0x00, 0xf9, 0x57, 0x33, 0x5e, 0xad, 0x32, 0xb2,
-	0xb3, 0xd8, 0x2d, 0x59, 0x33, 0x5a, 0x25, 0x1f, 0xef, 0x9b, 0xe3, 0x2d, 0xcf, 0xbb, 0x4b, 0x7d,
-	0x9f, 0xfc, 0xb8, 0x34, 0x6f, 0x65, 0x1f, 0x70, 0x60, 0x5d, 0xdf, 0xaf, 0xac, 0xcc, 0x68, 0xf8,
-	0x31, 0x5a, 0x2b, 0x30, 0x7a, 0xe4, 0xc9, 0x4f, 0x9a, 0x74, 0xd7, 0x4e, 0x32, 0x67, 0xc5, 0xc0,
-	0x56, 0x69, 0x45, 0xae, 0xb6, 0x35, 0x04, 0x41, 0x7e, 0xbe, 0xb4, 0xad, 0x03, 0x10, 0x33, 0x6d,
-	0x1d, 0x80, 0xc0, 0x43, 0xf4, 0x62, 0x81, 0xf1, 0x46, 0xf2, 0x10, 0xba, 0x29, 0xe5, 0xfc, 0x69,
-	0xc2, 0x7c, 0xf2, 0x8b, 0x46, 0xbe, 0x6e, 0x47, 0xf6, 0x94, 0xfb, 0xc8, 0x98, 0x33, 0xfa, 0x0b,
-	0xd4, 0x1a, 0xc6, 0x1f, 0xa3, 0x8d, 0x52, 0xbf, 0xf2, 0xf4, 0xb8, 0x2c, 0x09, 0x81, 0x9c, 0xeb,
-	0x1a, 0xf7, 0xe7, 0xb4, 0xad, 0x4e, 0x5e, 0x52, 0x4c, 0xcb, 0x4d, 0x3a, 0x1d, 0xc1, 0x9f, 0xa0,
-	0x5b, 0x05, 0x59, 0x1f, 0x44, 0x8d, 0xfe, 0x55, 0xa3, 0x5f, 0xb1, 0xa3, 0xcd, 0x89, 0x2c, 0xb1,
-	0x31, 0x9d, 0x09, 0xe1, 0x87, 0x68, 0xb5, 0x80, 0x87, 0x01, 0x17, 0xe4, 0x37, 0x4d, 0xbd, 0x63,
-	0xa7, 0x3e, 0x0a, 0xb8, 0xa8, 0xcc, 0x51, 0x26, 0xe6, 0x24, 0xd9, 0x9a, 0x26, 0xfd, 0x3e, 0x97,
-	0x24, 0x4b, 0xcf, 0x90, 0x32, 0x31, 0x7f, 0xf5, 0x8a, 0x24, 0x27, 0xf2, 0x9b, 0xc6, 0xbc, 0x57,
-	0x2f, 0x73, 0xa6, 0x27, 0xd2, 0x68, 0xf9, 0x44, 0x2a, 0x8c, 0x99, 0xc8, 0x6f, 0x1b, 0xf3, 0x26,
-	0x52, 0x66, 0x59, 0x26, 0xb2, 0x90, 0xab, 0x6d, 0xc9, 0x89, 0xfc, 0xee, 0xd2, 0xb6, 0xa6, 0x27,
-	0xd2, 0x68, 0xf8, 0x33, 0xd4, 0x2a, 0x61, 0xd4, 0xa0, 0xa4, 0xc0, 0xa2, 0x80, 0xab, 0xff, 0xd6,
-	0xef, 0x35, 0xf3, 0x8d, 0x39, 0x4c, 0x69, 0x3f, 0xca, 0xdd, 0x19, 0xff, 0x36, 0xb5, 0xc7, 0x71,
-	0x84, 0x36, 0x8b, 0x5a, 0x66, 0x74, 0x4a, 0xc5, 0x7e, 0xd0, 0xc5, 0xde, 0xb4, 0x17, 0xd3, 0x53,
-	0x32, 0x5b, 0x8d, 0xd0, 0x39, 0x06, 0xfc, 0x11, 0x5a, 0xf7, 0xc2, 0x31, 0x17, 0xc0, 0xdc, 0x09,
-	0x30, 0x29, 0xb9, 0x1c, 0x04, 0x79, 0x86, 0xcc, 0x11, 0x28, 0x5f, 0x52, 0x76, 0x7b, 0xda, 0xf9,
-	0xa1, 0x36, 0x3e, 0x29, 0x76, 0xeb, 0xa6, 0x37, 0x1d, 0xc1, 0x14, 0xdd, 0xce, 0xc0, 0x9a, 0xe1,
-	0x52, 0x21, 0x98, 0x82, 0x7f, 0x89, 0xcc, 0xe7, 0xcf, 0x06, 0x7f, 0x4f, 0x69, 0x5d, 0x21, 0x58,
-	0x89, 0xbf, 0xe1, 0x59, 0x82, 0xf8, 0x18, 0x61, 0x3f, 0x79, 0x1a, 0x0f, 0x19, 0xf5, 0xc1, 0x0d,
-	0xe2, 0x93, 0x44, 0xd1, 0xbf, 0xd2, 0xf4, 0x7b, 0x55, 0x7a, 0x3f, 0x33, 0x1e, 0xc6, 0x27, 0x49,
-	0x89, 0xbc, 0xe6, 0x4f, 0x05, 0xb6, 0x6f, 0xa0, 0x95, 0xfd, 0x28, 0x15, 0x5f, 0x38, 0xc0, 0xd3,
-	0x24, 0xe6, 0xb0, 0x9d, 0xa2, 0xcd, 0x4b, 0x3e, 0xcd, 0x18, 0xa3, 0x05, 0x75, 0x07, 0xab, 0xa9,
-	0x3b, 0x98, 0xfa, 0x2d, 0xef, 0x66, 0xf9, 0x17, 0xcb, 0xdc, 0xcd, 0xb2, 0x67, 0x7c, 0x07, 0x2d,
-	0xf3, 0x20, 0x4a, 0x43, 0x70, 0x45, 0x72, 0x0a, 0xfa, 0x6a, 0xd6, 0x70, 0x9a, 0x5a, 0x3b, 0x96,
-	0xd2, 0xbb, 0x1b, 0xcf, 0xff, 0x6c, 0x5f, 0x79, 0x7e, 0xd1, 0xae, 0x9d, 0x5f, 0xb4, 0x6b, 0x7f,
-	0x5c, 0xb4, 0x6b, 0x5f, 0xff, 0xd5, 0xbe, 0x32, 0x58, 0x54, 0x17, 0xc3, 0x07, 0xff, 0x07, 0x00,
-	0x00, 0xff, 0xff, 0x94, 0x6f, 0x64, 0x0a, 0x98, 0x0a, 0x00, 0x00,
+	// 1078 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x96, 0x5b, 0x73, 0x1b, 0x35,
+	0x14, 0xc7, 0x6b, 0x37, 0x4d, 0x63, 0xd9, 0x49, 0x53, 0x39, 0x6d, 0x85, 0x33, 0x63, 0xd2, 0x94,
+	0x96, 0x70, 0x4b, 0x18, 0xf7, 0x81, 0x27, 0x06, 0x8c, 0x9d, 0x49, 0x33, 0x53, 0x4a, 0x66, 0x1b,
+	0x2e, 0x33, 0xcc, 0xb0, 0xc8, 0xbb, 0x27, 0xf6, 0xe2, 0xf5, 0xee, 0x22, 0xc9, 0x6e, 0xfc, 0x3d,
+	0x80, 0xe1, 0x63, 0x70, 0xfb, 0x10, 0x7d, 0xe0, 0x52, 0x2e, 0x1f, 0x00, 0xc2, 0x0b, 0x0f, 0xbc,
+	0x01, 0xef, 0x8c, 0xa4, 0xbd, 0xda, 0x72, 0xfa, 0x66, 0x9f, 0xf3, 0x3f, 0xbf, 0xf3, 0xd7, 0xea,
+	0x48, 0x23, 0x54, 0x67, 0xf4, 0x44, 0xd8, 0x5e, 0x20, 0x80, 0x05, 0xd4, 0xdf, 0x8d, 0x58, 0x28,
+	0x42, 0x5c, 0x03, 0xe1, 0xb8, 0x1c, 0xd8, 0x04, 0x58, 0xd4, 0x6b, 0x6c, 0xf4, 0xc3, 0x7e, 0xa8,
+	0x12, 0x7b, 0xf2, 0x97, 0xd6, 0x34, 0xd6, 0x33, 0x4d, 0x1c, 0xa9, 0xb0, 0xc8, 0x89, 0x7f, 0xde,
+	0x91, 0xc9, 0x3d, 0x1a, 0x79, 0x7b, 0x23, 0x18, 0xf5, 0x80, 0xf1, 0x81, 0x17, 0x45, 0xbd, 0xdc,
+	0x1f, 0xad, 0xdb, 0xfe, 0x18, 0xad, 0x5a, 0xf0, 0xe9, 0x18, 0xb8, 0xb8, 0x07, 0xd4, 0x05, 0x86,
+	0xd7, 0x50, 0xf9, 0xb0, 0x4b, 0x4a, 0x5b, 0xa5, 0x9d, 0x25, 0xab, 0x7c, 0xd8, 0xc5, 0x0d, 0xb4,
+	0x32, 0xe6, 0xd2, 0xda, 0x08, 0x48, 0x79, 0xab, 0xb4, 0x53, 0xb1, 0xd2, 0xff, 0xf8, 0x16, 0x5a,
+	0xa5, 0x63, 0x31, 0xb0, 0x19, 0x4c, 0x3c, 0xee, 0x85, 0x01, 0xb9, 0xa8, 0xca, 0x6a, 0x32, 0x68,
+	0xc5, 0xb1, 0xed, 0xbf, 0xeb, 0xa8, 0x7e, 0x18, 0xaf, 0xce, 0xa2, 0x27, 0x22, 0x6e, 0x87, 0xef,
+	0xa2, 0xe5, 0x81, 0x6a, 0x49, 0xdc, 0xad, 0xd2, 0x4e, 0xb5, 0xb5, 0xb9, 0x9b, 0x5f, 0xf3, 0x6e,
+	0xc1, 0x95, 0xb5, 0x3c, 0x30, 0xbb, 0xbb, 0x8d, 0xca, 0x93, 0x96, 0xf2, 0x55, 0x6d, 0x5d, 0x33,
+	0x02, 0xac, 0xf2, 0xa4, 0x85, 0x5f, 0x45, 0x97, 0x18, 0x0d, 0xfa, 0xa0, 0x0c, 0x56, 0x5b, 0x8d,
+	0x19, 0xa5, 0x4c, 0x25, 0x72, 0x2d, 0xc4, 0x2f, 0xa2, 0x8b, 0xd1, 0x58, 0x90, 0x25, 0xa5, 0x27,
+	0x45, 0xfd, 0xd1, 0x38, 0x59, 0x84, 0x25, 0x45, 0xb8, 0x83, 0x6a, 0x2e, 0xf8, 0x20, 0xc0, 0xd6,
+	0x4d, 0x2e, 0xa9, 0xa2, 0xad, 0x62, 0x51, 0x57, 0x29, 0x0a, 0xad, 0xaa, 0x6e, 0x16, 0x93, 0x0d,
+	0xc5, 0x69, 0x40, 0x96, 0x4d, 0x0d, 0x8f, 0x4f, 0x83, 0xb4, 0xa1, 0x38, 0x0d, 0xf0, 0x1b, 0x08,
+	0x39, 0xe1, 0x28, 0xa2, 0x8e, 0x90, 0x1f, 0xfd, 0xb2, 0x2a, 0x79, 0xb6, 0x58, 0xd2, 0x49, 0xf3,
+	0x49, 0x65, 0xae, 0x04, 0xbf, 0x89, 0xaa, 0x3e, 0x50, 0x0e, 0x76, 0x9f, 0xd1, 0x40, 0x90, 0x15,
+	0x13, 0xe1, 0xbe, 0x14, 0x1c, 0xc8, 0x7c, 0x4a, 0xf0, 0xd3, 0x90, 0x5c, 0xb3, 0x26, 0x30, 0x98,
+	0x84, 0x43, 0x20, 0x15, 0xd3, 0x9a, 0x15, 0xc2, 0x52, 0x82, 0x74, 0xcd, 0x7e, 0x16, 0x93, 0xdb,
+	0x42, 0x7d, 0xca, 0x46, 0x04, 0x99, 0xb6, 0xa5, 0x2d, 0x53, 0xe9, 0xb6, 0x28, 0x21, 0x7e, 0x07,
+	0xad, 0xeb, 0xb6, 0xce, 0x00, 0x9c, 0x61, 0x14, 0x7a, 0x81, 0x20, 0x55, 0x55, 0xfc, 0x9c, 0xa1,
+	0x75, 0x27, 0x15, 0x25, 0x98, 0x2b, 0x7e, 0x31, 0x8e, 0x5f, 0x43, 0x68, 0x08, 0x53, 0x1b, 0x4e,
+	0x23, 0x8f, 0x4d, 0x49, 0xed, 0x29, 0x5f, 0xbf, 0x32, 0x84, 0xe9, 0xbe, 0x92, 0xe2, 0xd7, 0x11,
+	0x8a, 0x42, 0xdf, 0x73, 0xa6, 0xb6, 0x9c, 0x93, 0x55, 0x55, 0xd8, 0x9c, 0x99, 0x13, 0x95, 0xcf,
+	0x4d, 0x4b, 0x25, 0x4a, 0x22, 0xb8, 0x8d, 0xaa, 0xea, 0xe8, 0x40, 0x40, 0x7b, 0x3e, 0x90, 0xbf,
+	0x8c, 0x9b, 0xd8, 0x1e, 0x8b, 0xc1, 0xbe, 0x12, 0xa4, 0x5b, 0x40, 0xd3, 0x10, 0xee, 0x22, 0x75,
+	0xd0, 0x6c, 0xd7, 0xe3, 0x8a, 0xf1, 0xcf, 0x65, 0xd3, 0x1e, 0x48, 0x46, 0xd7, 0xe3, 0x79, 0x48,
+	0x95, 0x66, 0xb1, 0xd4, 0x08, 0x17, 0x54, 0x8c, 0x39, 0xf9, 0x6f, 0xa1, 0x91, 0x87, 0x4a, 0x50,
+	0x30, 0xa2, 0x43, 0xf8, 0x81, 0x36, 0x02, 0x81, 0xf0, 0x1c, 0x2a, 0x80, 0xfc, 0xab, 0x19, 0x2f,
+	0x14, 0x19, 0xc9, 0x1d, 0xd0, 0xce, 0x49, 0x13, 0x5a, 0xa1, 0x1e, 0xef, 0xc7, 0xd7, 0xca, 0x98,
+	0x03, 0xb3, 0xa9, 0xeb, 0x92, 0xef, 0x57, 0x16, 0xad, 0xec, 0x5d, 0x0e, 0xac, 0xed, 0xba, 0x85,
+	0x95, 0xc5, 0x31, 0xfc, 0x00, 0xad, 0x67, 0x18, 0x7d, 0xd4, 0xc8, 0x0f, 0x9a, 0x74, 0xcb, 0x4c,
+	0x8a, 0xcf, 0x68, 0x0c, 0x5b, 0xa3, 0x85, 0x70, 0xd1, 0x56, 0x1f, 0x04, 0xf9, 0xf1, 0x5c, 0x5b,
+	0x07, 0x20, 0xe6, 0x6c, 0x1d, 0x80, 0xc0, 0x7d, 0xf4, 0x4c, 0x86, 0x71, 0x06, 0xf2, 0xf0, 0xdb,
+	0x11, 0xe5, 0xfc, 0x51, 0xc8, 0x5c, 0xf2, 0x93, 0x46, 0xbe, 0x64, 0x46, 0x76, 0x94, 0xfa, 0x28,
+	0x16, 0x27, 0xf4, 0xeb, 0xd4, 0x98, 0xc6, 0x1f, 0xa0, 0x8d, 0x9c, 0x5f, 0x79, 0x6a, 0x6d, 0x16,
+	0xfa, 0x40, 0x9e, 0xe8, 0x1e, 0x77, 0x16, 0xd8, 0x56, 0x27, 0x3e, 0xcc, 0xa6, 0xe5, 0x2a, 0x9d,
+	0xcd, 0xe0, 0x0f, 0xd1, 0xb5, 0x8c, 0xac, 0x2f, 0x00, 0x8d, 0xfe, 0x59, 0xa3, 0x9f, 0x37, 0xa3,
+	0xe3, 0x9b, 0x20, 0xc7, 0xc6, 0x74, 0x2e, 0x85, 0xef, 0xa1, 0xb5, 0x0c, 0xee, 0x7b, 0x5c, 0x90,
+	0x5f, 0x34, 0xf5, 0xa6, 0x99, 0x7a, 0xdf, 0xe3, 0xa2, 0x30, 0x47, 0x49, 0x30, 0x25, 0x49, 0x6b,
+	0x9a, 0xf4, 0xeb, 0x42, 0x92, 0x6c, 0x3d, 0x47, 0x4a, 0x82, 0xf8, 0x23, 0x74, 0x3d, 0xf3, 0x24,
+	0xc2, 0x21, 0x04, 0xc9, 0xbd, 0xf7, 0x9b, 0x26, 0xee, 0x98, 0xbd, 0x1d, 0x4b, 0x69, 0xf1, 0x02,
+	0xac, 0xd3, 0xf9, 0x5c, 0x3a, 0x5a, 0xca, 0xa9, 0x9c, 0xf8, 0xaf, 0x2a, 0x8b, 0x46, 0x4b, 0x7a,
+	0x9a, 0x9d, 0xf8, 0x38, 0x96, 0x4e, 0xbc, 0xc2, 0xc4, 0x13, 0xff, 0x75, 0x65, 0xd1, 0xc4, 0xcb,
+	0x2a, 0xc3, 0xc4, 0x67, 0xe1, 0xa2, 0x2d, 0x39, 0xf1, 0xdf, 0x9c, 0x6b, 0x6b, 0x76, 0xe2, 0xe3,
+	0x18, 0xfe, 0x04, 0x35, 0x72, 0x18, 0x35, 0x88, 0x11, 0xb0, 0x91, 0xc7, 0xd5, 0x9b, 0xe1, 0x5b,
+	0xcd, 0x7c, 0x79, 0x01, 0x53, 0xca, 0x8f, 0x52, 0x75, 0xc2, 0xbf, 0x41, 0xcd, 0x79, 0x3c, 0x42,
+	0x9b, 0x59, 0xaf, 0x78, 0x34, 0x73, 0xcd, 0xbe, 0xd3, 0xcd, 0x5e, 0x31, 0x37, 0xd3, 0xbb, 0x31,
+	0xdf, 0x8d, 0xd0, 0x05, 0x02, 0xfc, 0x3e, 0xaa, 0x3b, 0xfe, 0x98, 0x0b, 0x60, 0xf6, 0x04, 0x98,
+	0x0c, 0xd9, 0x1c, 0x04, 0xf9, 0x0c, 0xc5, 0x47, 0x2c, 0xff, 0xf8, 0xda, 0xed, 0x68, 0xe5, 0x7b,
+	0x5a, 0xf8, 0x30, 0xfb, 0x5a, 0x57, 0x9d, 0xd9, 0x0c, 0xa6, 0xe8, 0x46, 0x02, 0xd6, 0x0c, 0x9b,
+	0x0a, 0xc1, 0x14, 0xfc, 0x73, 0x14, 0x5f, 0xaf, 0x26, 0xf8, 0xdb, 0x2a, 0xd6, 0x16, 0x82, 0xe5,
+	0xf8, 0x1b, 0x8e, 0x21, 0x89, 0x8f, 0x11, 0x76, 0xc3, 0x47, 0x41, 0x9f, 0x51, 0x17, 0x6c, 0x2f,
+	0x38, 0x09, 0x15, 0xfd, 0x0b, 0x4d, 0xbf, 0x5d, 0xa4, 0x77, 0x13, 0xe1, 0x61, 0x70, 0x12, 0xe6,
+	0xc8, 0xeb, 0xee, 0x4c, 0x62, 0xfb, 0x0a, 0x5a, 0xdd, 0x1f, 0x45, 0x62, 0x6a, 0x01, 0x8f, 0xc2,
+	0x80, 0xc3, 0x76, 0x84, 0x36, 0xcf, 0xb9, 0xfa, 0x31, 0x46, 0x4b, 0xea, 0x6d, 0x59, 0x52, 0x6f,
+	0x4b, 0xf5, 0x5b, 0xbe, 0x39, 0xd3, 0x1b, 0x31, 0x7e, 0x73, 0x26, 0xff, 0xf1, 0x4d, 0x54, 0xe3,
+	0xde, 0x28, 0xf2, 0x41, 0x9f, 0x43, 0xf5, 0xa2, 0xab, 0x58, 0x55, 0x1d, 0x53, 0x67, 0xea, 0xad,
+	0x8d, 0xc7, 0x7f, 0x34, 0x2f, 0x3c, 0x3e, 0x6b, 0x96, 0x9e, 0x9c, 0x35, 0x4b, 0xbf, 0x9f, 0x35,
+	0x4b, 0x5f, 0xfe, 0xd9, 0xbc, 0xd0, 0x5b, 0x56, 0x0f, 0xde, 0xbb, 0xff, 0x0f, 0x00, 0x0c, 0xee,
+	0xba, 0x4e, 0x70, 0x0b, 0x00, 0x00,
 }
 
 func (m *RequestHeader) Marshal() (dAtA []byte, err error) {
@@ -483,6 +493,20 @@ func (m *InternalRaftRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x82
 	}
+	if m.AuthUserTokenRevoke != nil {
+		{
+			size, err := m.AuthUserTokenRevoke.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x45
+		i--
+		dAtA[i] = 0xa2
+	}
 	if m.AuthRoleList != nil {
 		{
 			size, err := m.AuthRoleList.MarshalToSizedBuffer(dAtA[:i])
@@ -665,6 +689,30 @@ func (m *InternalRaftRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0xa2
 	}
+	if m.PolicyPut != nil {
+		{
+			size, err := m.PolicyPut.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x6a
+	}
+	if m.KeyExpiry != nil {
+		{
+			size, err := m.KeyExpiry.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintRaftInternal(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x62
+	}
 	if m.LeaseCheckpoint != nil {
 		{
 			size, err := m.LeaseCheckpoint.MarshalToSizedBuffer(dAtA[:i])
@@ -950,6 +998,14 @@ func (m *InternalRaftRequest) Size() (n int) {
 		l = m.LeaseCheckpoint.Size()
 		n += 1 + l + sovRaftInternal(uint64(l))
 	}
+	if m.KeyExpiry != nil {
+		l = m.KeyExpiry.Size()
+		n += 1 + l + sovRaftInternal(uint64(l))
+	}
+	if m.PolicyPut != nil {
+		l = m.PolicyPut.Size()
+		n += 1 + l + sovRaftInternal(uint64(l))
+	}
 	if m.Header != nil {
 		l = m.Header.Size()
 		n += 2 + l + sovRaftInternal(uint64(l))
@@ -1002,6 +1058,10 @@ func (m *InternalRaftRequest) Size() (n int) {
 		l = m.AuthRoleList.Size()
 		n += 2 + l + sovRaftInternal(uint64(l))
 	}
+	if m.AuthUserTokenRevoke != nil {
+		l = m.AuthUserTokenRevoke.Size()
+		n += 2 + l + sovRaftInternal(uint64(l))
+	}
 	if m.AuthRoleAdd != nil {
 		l = m.AuthRoleAdd.Size()
 		n += 2 + l + sovRaftInternal(uint64(l))
@@ -1614,6 +1674,78 @@ func (m *InternalRaftRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyExpiry", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.KeyExpiry == nil {
+				m.KeyExpiry = &TxnRequest{}
+			}
+			if err := m.KeyExpiry.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PolicyPut", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.PolicyPut == nil {
+				m.PolicyPut = &PolicyPutRequest{}
+			}
+			if err := m.PolicyPut.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 100:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Header", wireType)
@@ -2082,6 +2214,42 @@ func (m *InternalRaftRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 1108:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AuthUserTokenRevoke", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.AuthUserTokenRevoke == nil {
+				m.AuthUserTokenRevoke = &AuthUserTokenRevokeRequest{}
+			}
+			if err := m.AuthUserTokenRevoke.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 1200:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field AuthRoleAdd", wireType)
@@ -25,12 +25,21 @@ var (
 	ErrGRPCKeyNotFound   = status.New(codes.InvalidArgument, "etcdserver: key not found").Err()
 	ErrGRPCValueProvided = status.New(codes.InvalidArgument, "etcdserver: value is provided").Err()
 	ErrGRPCLeaseProvided = status.New(codes.InvalidArgument, "etcdserver: lease is provided").Err()
+	ErrGRPCInvalidKeyTTL = status.New(codes.InvalidArgument, "etcdserver: key ttl is negative").Err()
 	ErrGRPCTooManyOps    = status.New(codes.InvalidArgument, "etcdserver: too many operations in txn request").Err()
 	ErrGRPCDuplicateKey  = status.New(codes.InvalidArgument, "etcdserver: duplicate key given in txn request").Err()
 	ErrGRPCCompacted     = status.New(codes.OutOfRange, "etcdserver: mvcc: required revision has been compacted").Err()
 	ErrGRPCFutureRev     = status.New(codes.OutOfRange, "etcdserver: mvcc: required revision is a future revision").Err()
 	ErrGRPCNoSpace       = status.New(codes.ResourceExhausted, "etcdserver: mvcc: database space exceeded").Err()
 
+	ErrGRPCKeyTTLNotSupported = status.New(codes.FailedPrecondition, "etcdserver: key ttl requires cluster version 3.5 or later").Err()
+
+	ErrGRPCPrefixQuotaExceeded = status.New(codes.ResourceExhausted, "etcdserver: key prefix quota exceeded").Err()
+
+	ErrGRPCInvalidWatchFilter = status.New(codes.InvalidArgument, "etcdserver: invalid watch filter").Err()
+
+	ErrGRPCRateLimitExceeded = status.New(codes.ResourceExhausted, "etcdserver: rate limit exceeded").Err()
+
 	ErrGRPCLeaseNotFound    = status.New(codes.NotFound, "etcdserver: requested lease not found").Err()
 	ErrGRPCLeaseExist       = status.New(codes.FailedPrecondition, "etcdserver: lease already exists").Err()
 	ErrGRPCLeaseTTLTooLarge = status.New(codes.OutOfRange, "etcdserver: too large lease TTL").Err()
@@ -82,11 +91,15 @@ var (
 	ErrGRPCDowngradeInProcess            = status.New(codes.FailedPrecondition, "etcdserver: cluster has a downgrade job in progress").Err()
 	ErrGRPCNoInflightDowngrade           = status.New(codes.FailedPrecondition, "etcdserver: no inflight downgrade job").Err()
 
+	ErrGRPCHashedPasswordNotAllowed = status.New(codes.FailedPrecondition, "etcdserver: client hashed password is not allowed").Err()
+	ErrGRPCInvalidHashedPassword    = status.New(codes.InvalidArgument, "etcdserver: invalid or too weak hashed password").Err()
+
 	errStringToError = map[string]error{
 		ErrorDesc(ErrGRPCEmptyKey):      ErrGRPCEmptyKey,
 		ErrorDesc(ErrGRPCKeyNotFound):   ErrGRPCKeyNotFound,
 		ErrorDesc(ErrGRPCValueProvided): ErrGRPCValueProvided,
 		ErrorDesc(ErrGRPCLeaseProvided): ErrGRPCLeaseProvided,
+		ErrorDesc(ErrGRPCInvalidKeyTTL): ErrGRPCInvalidKeyTTL,
 
 		ErrorDesc(ErrGRPCTooManyOps):   ErrGRPCTooManyOps,
 		ErrorDesc(ErrGRPCDuplicateKey): ErrGRPCDuplicateKey,
@@ -94,6 +107,14 @@ var (
 		ErrorDesc(ErrGRPCFutureRev):    ErrGRPCFutureRev,
 		ErrorDesc(ErrGRPCNoSpace):      ErrGRPCNoSpace,
 
+		ErrorDesc(ErrGRPCKeyTTLNotSupported): ErrGRPCKeyTTLNotSupported,
+
+		ErrorDesc(ErrGRPCPrefixQuotaExceeded): ErrGRPCPrefixQuotaExceeded,
+
+		ErrorDesc(ErrGRPCInvalidWatchFilter): ErrGRPCInvalidWatchFilter,
+
+		ErrorDesc(ErrGRPCRateLimitExceeded): ErrGRPCRateLimitExceeded,
+
 		ErrorDesc(ErrGRPCLeaseNotFound):    ErrGRPCLeaseNotFound,
 		ErrorDesc(ErrGRPCLeaseExist):       ErrGRPCLeaseExist,
 		ErrorDesc(ErrGRPCLeaseTTLTooLarge): ErrGRPCLeaseTTLTooLarge,
@@ -144,6 +165,9 @@ var (
 		ErrorDesc(ErrGRPCInvalidDowngradeTargetVersion): ErrGRPCInvalidDowngradeTargetVersion,
 		ErrorDesc(ErrGRPCDowngradeInProcess):            ErrGRPCDowngradeInProcess,
 		ErrorDesc(ErrGRPCNoInflightDowngrade):           ErrGRPCNoInflightDowngrade,
+
+		ErrorDesc(ErrGRPCHashedPasswordNotAllowed): ErrGRPCHashedPasswordNotAllowed,
+		ErrorDesc(ErrGRPCInvalidHashedPassword):    ErrGRPCInvalidHashedPassword,
 	}
 )
 
@@ -153,12 +177,21 @@ var (
 	ErrKeyNotFound   = Error(ErrGRPCKeyNotFound)
 	ErrValueProvided = Error(ErrGRPCValueProvided)
 	ErrLeaseProvided = Error(ErrGRPCLeaseProvided)
+	ErrInvalidKeyTTL = Error(ErrGRPCInvalidKeyTTL)
 	ErrTooManyOps    = Error(ErrGRPCTooManyOps)
 	ErrDuplicateKey  = Error(ErrGRPCDuplicateKey)
 	ErrCompacted     = Error(ErrGRPCCompacted)
 	ErrFutureRev     = Error(ErrGRPCFutureRev)
 	ErrNoSpace       = Error(ErrGRPCNoSpace)
 
+	ErrKeyTTLNotSupported = Error(ErrGRPCKeyTTLNotSupported)
+
+	ErrPrefixQuotaExceeded = Error(ErrGRPCPrefixQuotaExceeded)
+
+	ErrInvalidWatchFilter = Error(ErrGRPCInvalidWatchFilter)
+
+	ErrRateLimitExceeded = Error(ErrGRPCRateLimitExceeded)
+
 	ErrLeaseNotFound    = Error(ErrGRPCLeaseNotFound)
 	ErrLeaseExist       = Error(ErrGRPCLeaseExist)
 	ErrLeaseTTLTooLarge = Error(ErrGRPCLeaseTTLTooLarge)
@@ -208,6 +241,9 @@ var (
 	ErrInvalidDowngradeTargetVersion = Error(ErrGRPCInvalidDowngradeTargetVersion)
 	ErrDowngradeInProcess            = Error(ErrGRPCDowngradeInProcess)
 	ErrNoInflightDowngrade           = Error(ErrGRPCNoInflightDowngrade)
+
+	ErrHashedPasswordNotAllowed = Error(ErrGRPCHashedPasswordNotAllowed)
+	ErrInvalidHashedPassword    = Error(ErrGRPCInvalidHashedPassword)
 )
 
 // EtcdError defines gRPC server errors.
@@ -121,6 +121,9 @@ type AuthStore interface {
 	// UserChangePassword changes a password of a user
 	UserChangePassword(r *pb.AuthUserChangePasswordRequest) (*pb.AuthUserChangePasswordResponse, error)
 
+	// UserTokenRevoke invalidates the simple tokens of a user
+	UserTokenRevoke(r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error)
+
 	// UserGrantRole grants a role to the user
 	UserGrantRole(r *pb.AuthUserGrantRoleRequest) (*pb.AuthUserGrantRoleResponse, error)
 
@@ -504,6 +507,28 @@ func (as *authStore) UserChangePassword(r *pb.AuthUserChangePasswordRequest) (*p
 	return &pb.AuthUserChangePasswordResponse{}, nil
 }
 
+// UserTokenRevoke invalidates the simple tokens assigned to the user. The JWT
+// tokens cannot be revoked; they are valid until they expire.
+func (as *authStore) UserTokenRevoke(r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error) {
+	if !as.IsAuthEnabled() {
+		return nil, ErrAuthNotEnabled
+	}
+
+	tx := as.be.BatchTx()
+	tx.Lock()
+	user := getUser(as.lg, tx, r.Name)
+	tx.Unlock()
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	as.tokenProvider.invalidateUser(r.Name)
+
+	as.lg.Info("revoked the tokens of a user", zap.String("user-name", r.Name))
+	return &pb.AuthUserTokenRevokeResponse{}, nil
+}
+
 func (as *authStore) UserGrantRole(r *pb.AuthUserGrantRoleRequest) (*pb.AuthUserGrantRoleResponse, error) {
 	tx := as.be.BatchTx()
 	tx.Lock()
@@ -266,6 +266,40 @@ func TestUserChangePassword(t *testing.T) {
 	}
 }
 
+func TestUserTokenRevoke(t *testing.T) {
+	as, tearDown := setupAuthStore(t)
+	defer tearDown(t)
+
+	ctx := context.WithValue(context.WithValue(context.TODO(), AuthenticateParamIndex{}, uint64(1)), AuthenticateParamSimpleTokenPrefix{}, "dummy")
+	resp, err := as.Authenticate(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{rpctypes.TokenFieldNameGRPC: resp.Token}))
+	if _, err = as.AuthInfoFromCtx(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = as.UserTokenRevoke(&pb.AuthUserTokenRevokeRequest{Name: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = as.AuthInfoFromCtx(ctx); err != ErrInvalidAuthToken {
+		t.Fatalf("expected %v, got %v", ErrInvalidAuthToken, err)
+	}
+
+	// revoke the tokens of a non-existing user
+	_, err = as.UserTokenRevoke(&pb.AuthUserTokenRevokeRequest{Name: "foo-test"})
+	if err != ErrUserNotFound {
+		t.Fatalf("expected %v, got %v", ErrUserNotFound, err)
+	}
+
+	as.AuthDisable()
+	_, err = as.UserTokenRevoke(&pb.AuthUserTokenRevokeRequest{Name: "foo"})
+	if err != ErrAuthNotEnabled {
+		t.Fatalf("expected %v, got %v", ErrAuthNotEnabled, err)
+	}
+}
+
 func TestRoleAdd(t *testing.T) {
 	as, tearDown := setupAuthStore(t)
 	defer tearDown(t)
@@ -0,0 +1,112 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultAsyncKVDepth is the default maximum number of requests an AsyncKV
+// has in flight.
+const DefaultAsyncKVDepth = 64
+
+// AsyncKV sends KV requests without waiting for their responses, returning
+// an OpFuture per request, with at most a configured depth of requests in
+// flight; a request made while the pipeline is full waits for a slot, so
+// that writers are held back rather than queueing requests without bound.
+// The requests in flight are multiplexed over the connections of the
+// client.
+//
+// Requests in flight at the same time may be applied in any order, e.g.
+// when one of them is retried; write with a Batcher, or wait for the
+// future of a request before sending the next, where order matters.
+//
+// AsyncKV is EXPERIMENTAL and may change in later releases.
+type AsyncKV struct {
+	kv    KV
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewAsyncKV returns an AsyncKV sending requests with kv, such as a Client,
+// with at most depth requests in flight, or DefaultAsyncKVDepth if depth is
+// not positive.
+func NewAsyncKV(kv KV, depth int) *AsyncKV {
+	if depth <= 0 {
+		depth = DefaultAsyncKVDepth
+	}
+	return &AsyncKV{kv: kv, slots: make(chan struct{}, depth)}
+}
+
+// OpFuture is the pending response of a request sent by an AsyncKV.
+type OpFuture struct {
+	done chan struct{}
+	resp OpResponse
+	err  error
+}
+
+// Done returns a channel closed once the response is received or the
+// request failed.
+func (f *OpFuture) Done() <-chan struct{} { return f.done }
+
+// Wait waits for the response of the request, or for ctx to be done.
+func (f *OpFuture) Wait(ctx context.Context) (OpResponse, error) {
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		return OpResponse{}, ctx.Err()
+	}
+}
+
+// PutAsync puts a key-value pair as KV.Put does.
+func (a *AsyncKV) PutAsync(ctx context.Context, key, val string, opts ...OpOption) *OpFuture {
+	return a.DoAsync(ctx, OpPut(key, val, opts...))
+}
+
+// GetAsync retrieves keys as KV.Get does.
+func (a *AsyncKV) GetAsync(ctx context.Context, key string, opts ...OpOption) *OpFuture {
+	return a.DoAsync(ctx, OpGet(key, opts...))
+}
+
+// DeleteAsync deletes keys as KV.Delete does.
+func (a *AsyncKV) DeleteAsync(ctx context.Context, key string, opts ...OpOption) *OpFuture {
+	return a.DoAsync(ctx, OpDelete(key, opts...))
+}
+
+// DoAsync applies op as KV.Do does. It waits for a slot in the pipeline,
+// and fails with the error of ctx if ctx is done first.
+func (a *AsyncKV) DoAsync(ctx context.Context, op Op) *OpFuture {
+	f := &OpFuture{done: make(chan struct{})}
+	select {
+	case a.slots <- struct{}{}:
+	case <-ctx.Done():
+		f.err = ctx.Err()
+		close(f.done)
+		return f
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		f.resp, f.err = a.kv.Do(ctx, op)
+		<-a.slots
+		close(f.done)
+	}()
+	return f
+}
+
+// Wait waits for the requests in flight to complete.
+func (a *AsyncKV) Wait() { a.wg.Wait() }
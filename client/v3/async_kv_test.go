@@ -0,0 +1,77 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+// pipelineKV holds the requests until released, tracking the most in
+// flight at once.
+type pipelineKV struct {
+	KV
+	release chan struct{}
+
+	mu          sync.Mutex
+	inflight    int
+	maxInflight int
+}
+
+func (kv *pipelineKV) Do(ctx context.Context, op Op) (OpResponse, error) {
+	kv.mu.Lock()
+	kv.inflight++
+	if kv.inflight > kv.maxInflight {
+		kv.maxInflight = kv.inflight
+	}
+	kv.mu.Unlock()
+	<-kv.release
+	kv.mu.Lock()
+	kv.inflight--
+	kv.mu.Unlock()
+	return (&PutResponse{Header: &pb.ResponseHeader{Revision: 2}}).OpResponse(), nil
+}
+
+func TestAsyncKV(t *testing.T) {
+	kv := &pipelineKV{release: make(chan struct{})}
+	a := NewAsyncKV(kv, 2)
+
+	var fs []*OpFuture
+	for i := 0; i < 2; i++ {
+		fs = append(fs, a.PutAsync(context.Background(), "foo", "bar"))
+	}
+	// a request made with a full pipeline waits for a slot
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := a.GetAsync(ctx, "foo").Wait(context.Background()); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(kv.release)
+	for i, f := range fs {
+		resp, err := f.Wait(context.Background())
+		if err != nil || resp.Put().Header.Revision != 2 {
+			t.Fatalf("#%d: response = %+v, %v, want a put at revision 2", i, resp, err)
+		}
+	}
+	a.Wait()
+	if kv.maxInflight != 2 {
+		t.Fatalf("at most %d requests in flight, want 2", kv.maxInflight)
+	}
+}
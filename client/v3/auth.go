@@ -32,6 +32,7 @@ type (
 	AuthUserAddResponse              pb.AuthUserAddResponse
 	AuthUserDeleteResponse           pb.AuthUserDeleteResponse
 	AuthUserChangePasswordResponse   pb.AuthUserChangePasswordResponse
+	AuthUserTokenRevokeResponse      pb.AuthUserTokenRevokeResponse
 	AuthUserGrantRoleResponse        pb.AuthUserGrantRoleResponse
 	AuthUserGetResponse              pb.AuthUserGetResponse
 	AuthUserRevokeRoleResponse       pb.AuthUserRevokeRoleResponse
@@ -80,6 +81,10 @@ type Auth interface {
 	// UserChangePassword changes a password of a user.
 	UserChangePassword(ctx context.Context, name string, password string) (*AuthUserChangePasswordResponse, error)
 
+	// UserTokenRevoke invalidates the simple tokens of a user, or of the
+	// user of the client if name is empty.
+	UserTokenRevoke(ctx context.Context, name string) (*AuthUserTokenRevokeResponse, error)
+
 	// UserGrantRole grants a role to a user.
 	UserGrantRole(ctx context.Context, user string, role string) (*AuthUserGrantRoleResponse, error)
 
@@ -172,6 +177,11 @@ func (auth *authClient) UserChangePassword(ctx context.Context, name string, pas
 	return (*AuthUserChangePasswordResponse)(resp), toErr(ctx, err)
 }
 
+func (auth *authClient) UserTokenRevoke(ctx context.Context, name string) (*AuthUserTokenRevokeResponse, error) {
+	resp, err := auth.remote.UserTokenRevoke(ctx, &pb.AuthUserTokenRevokeRequest{Name: name}, auth.callOpts...)
+	return (*AuthUserTokenRevokeResponse)(resp), toErr(ctx, err)
+}
+
 func (auth *authClient) UserGrantRole(ctx context.Context, user string, role string) (*AuthUserGrantRoleResponse, error) {
 	resp, err := auth.remote.UserGrantRole(ctx, &pb.AuthUserGrantRoleRequest{User: user, Role: role}, auth.callOpts...)
 	return (*AuthUserGrantRoleResponse)(resp), toErr(ctx, err)
@@ -68,19 +68,69 @@ type builder struct {
 	cfg Config
 }
 
+// TargetConfig configures the balancers built for the dial targets with a
+// given authority.
+type TargetConfig struct {
+	// HealthPolicy, if set, quarantines the endpoints it finds unhealthy.
+	HealthPolicy picker.HealthPolicy
+
+	// Zone is the zone of the client, and EndpointZones maps endpoints to
+	// the zone of their member, for the ZoneAware policy.
+	Zone          string
+	EndpointZones map[string]string
+
+	// ConnsPerEndpoint is the number of connections opened to each
+	// endpoint; requests are striped over them. If 0, a single connection
+	// is opened.
+	ConnsPerEndpoint int
+}
+
+var targetConfigs = struct {
+	mu sync.RWMutex
+	m  map[string]TargetConfig
+}{m: make(map[string]TargetConfig)}
+
+// RegisterTarget configures the balancers built for the dial targets with
+// the given authority (e.g. "endpoint://<authority>/<endpoint>"). Unlike
+// RegisterBuilder, it may be invoked at any time, and applies to the
+// connections dialed afterwards.
+func RegisterTarget(authority string, cfg TargetConfig) {
+	targetConfigs.mu.Lock()
+	targetConfigs.m[authority] = cfg
+	targetConfigs.mu.Unlock()
+}
+
+// UnregisterTarget removes the configuration registered for authority.
+func UnregisterTarget(authority string) {
+	targetConfigs.mu.Lock()
+	delete(targetConfigs.m, authority)
+	targetConfigs.mu.Unlock()
+}
+
+func getTargetConfig(authority string) TargetConfig {
+	targetConfigs.mu.RLock()
+	defer targetConfigs.mu.RUnlock()
+	return targetConfigs.m[authority]
+}
+
 // Build is called initially when creating "ccBalancerWrapper".
 // "grpc.Dial" is called to this client connection.
 // Then, resolved addresses will be handled via "HandleResolvedAddrs".
 func (b *builder) Build(cc balancer.ClientConn, opt balancer.BuildOptions) balancer.Balancer {
+	tcfg := getTargetConfig(opt.Target.Authority)
 	bb := &baseBalancer{
 		id:     strconv.FormatInt(time.Now().UnixNano(), 36),
 		policy: b.cfg.Policy,
 		name:   b.cfg.Name,
 		lg:     b.cfg.Logger,
 
-		addrToSc: make(map[resolver.Address]balancer.SubConn),
-		scToAddr: make(map[balancer.SubConn]resolver.Address),
-		scToSt:   make(map[balancer.SubConn]grpcconnectivity.State),
+		zone:      tcfg.Zone,
+		addrZones: tcfg.EndpointZones,
+		conns:     tcfg.ConnsPerEndpoint,
+
+		addrToScs: make(map[resolver.Address][]balancer.SubConn),
+		scToAddr:  make(map[balancer.SubConn]resolver.Address),
+		scToSt:    make(map[balancer.SubConn]grpcconnectivity.State),
 
 		currentConn:          nil,
 		connectivityRecorder: connectivity.New(b.cfg.Logger),
@@ -88,6 +138,9 @@ func (b *builder) Build(cc balancer.ClientConn, opt balancer.BuildOptions) balan
 		// initialize picker always returns "ErrNoSubConnAvailable"
 		picker: picker.NewErr(balancer.ErrNoSubConnAvailable),
 	}
+	if tcfg.HealthPolicy != nil {
+		bb.health = picker.NewHealthTracker(tcfg.HealthPolicy, b.cfg.Logger)
+	}
 
 	// TODO: support multiple connections
 	bb.mu.Lock()
@@ -126,11 +179,22 @@ type baseBalancer struct {
 	name   string
 	lg     *zap.Logger
 
+	// health quarantines unhealthy endpoints across picker updates.
+	health *picker.HealthTracker
+
+	zone      string
+	addrZones map[string]string
+
+	// conns is the number of subconns per address.
+	conns int
+
 	mu sync.RWMutex
 
-	addrToSc map[resolver.Address]balancer.SubConn
-	scToAddr map[balancer.SubConn]resolver.Address
-	scToSt   map[balancer.SubConn]grpcconnectivity.State
+	// addrs are the resolved addresses, in the order they were resolved.
+	addrs     []resolver.Address
+	addrToScs map[resolver.Address][]balancer.SubConn
+	scToAddr  map[balancer.SubConn]resolver.Address
+	scToSt    map[balancer.SubConn]grpcconnectivity.State
 
 	currentConn          balancer.ClientConn
 	connectivityRecorder connectivity.Recorder
@@ -154,28 +218,34 @@ func (bb *baseBalancer) HandleResolvedAddrs(addrs []resolver.Address, err error)
 	bb.mu.Lock()
 	defer bb.mu.Unlock()
 
+	bb.addrs = addrs
 	resolved := make(map[resolver.Address]struct{})
 	for _, addr := range addrs {
 		resolved[addr] = struct{}{}
-		if _, ok := bb.addrToSc[addr]; !ok {
+		// each subconn is a separate HTTP/2 connection, with its own
+		// streams limit
+		for len(bb.addrToScs[addr]) < bb.connsPerAddr() {
 			sc, err := bb.currentConn.NewSubConn([]resolver.Address{addr}, balancer.NewSubConnOptions{})
 			if err != nil {
 				bb.lg.Warn("NewSubConn failed", zap.String("picker", bb.picker.String()), zap.String("balancer-id", bb.id), zap.Error(err), zap.String("address", addr.Addr))
-				continue
+				break
 			}
 			bb.lg.Info("created subconn", zap.String("address", addr.Addr))
-			bb.addrToSc[addr] = sc
+			bb.addrToScs[addr] = append(bb.addrToScs[addr], sc)
 			bb.scToAddr[sc] = addr
 			bb.scToSt[sc] = grpcconnectivity.Idle
 			sc.Connect()
 		}
 	}
 
-	for addr, sc := range bb.addrToSc {
-		if _, ok := resolved[addr]; !ok {
-			// was removed by resolver or failed to create subconn
+	for addr, scs := range bb.addrToScs {
+		if _, ok := resolved[addr]; ok {
+			continue
+		}
+		// was removed by resolver or failed to create subconn
+		delete(bb.addrToScs, addr)
+		for _, sc := range scs {
 			bb.currentConn.RemoveSubConn(sc)
-			delete(bb.addrToSc, addr)
 
 			bb.lg.Info(
 				"removed subconn",
@@ -250,6 +320,13 @@ func (bb *baseBalancer) HandleSubConnStateChange(sc balancer.SubConn, s grpcconn
 	bb.currentConn.UpdateBalancerState(bb.connectivityRecorder.GetCurrentState(), bb.picker)
 }
 
+func (bb *baseBalancer) connsPerAddr() int {
+	if bb.conns < 1 {
+		return 1
+	}
+	return bb.conns
+}
+
 func (bb *baseBalancer) updatePicker() {
 	if bb.connectivityRecorder.GetCurrentState() == grpcconnectivity.TransientFailure {
 		bb.picker = picker.NewErr(balancer.ErrTransientFailure)
@@ -264,9 +341,11 @@ func (bb *baseBalancer) updatePicker() {
 
 	// only pass ready subconns to picker
 	scToAddr := make(map[balancer.SubConn]resolver.Address)
-	for addr, sc := range bb.addrToSc {
-		if st, ok := bb.scToSt[sc]; ok && st == grpcconnectivity.Ready {
-			scToAddr[sc] = addr
+	for addr, scs := range bb.addrToScs {
+		for _, sc := range scs {
+			if st, ok := bb.scToSt[sc]; ok && st == grpcconnectivity.Ready {
+				scToAddr[sc] = addr
+			}
 		}
 	}
 
@@ -274,6 +353,10 @@ func (bb *baseBalancer) updatePicker() {
 		Policy:                   bb.policy,
 		Logger:                   bb.lg,
 		SubConnToResolverAddress: scToAddr,
+		Addrs:                    bb.addrs,
+		Health:                   bb.health,
+		Zone:                     bb.zone,
+		AddrZones:                bb.addrZones,
 	})
 	bb.lg.Info(
 		"updated picker",
@@ -28,8 +28,10 @@ import (
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	gbalancer "google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
 	"google.golang.org/grpc/status"
 )
 
@@ -308,3 +310,42 @@ func TestRoundRobinBalancedResolvableFailoverFromRequestFail(t *testing.T) {
 		t.Fatalf("expected balanced loads for %d requests, got switches %d", reqN, switches)
 	}
 }
+
+// subConnRecorder records the subconns created and removed by a balancer.
+type subConnRecorder struct {
+	gbalancer.ClientConn
+	created map[string]int
+	removed int
+}
+
+type recordedSubConn struct {
+	gbalancer.SubConn
+}
+
+func (sc *recordedSubConn) Connect() {}
+
+func (r *subConnRecorder) NewSubConn(addrs []resolver.Address, opts gbalancer.NewSubConnOptions) (gbalancer.SubConn, error) {
+	r.created[addrs[0].Addr]++
+	return &recordedSubConn{}, nil
+}
+
+func (r *subConnRecorder) RemoveSubConn(sc gbalancer.SubConn) { r.removed++ }
+
+func (r *subConnRecorder) Target() string { return "endpoint://conns/*" }
+
+func TestConnsPerEndpoint(t *testing.T) {
+	RegisterTarget("conns", TargetConfig{ConnsPerEndpoint: 3})
+	defer UnregisterTarget("conns")
+	b := &builder{Config{Policy: picker.RoundrobinBalanced, Name: genName(), Logger: zap.NewExample()}}
+	r := &subConnRecorder{created: make(map[string]int)}
+	bb := b.Build(r, gbalancer.BuildOptions{Target: resolver.Target{Authority: "conns"}})
+
+	bb.HandleResolvedAddrs([]resolver.Address{{Addr: "a"}, {Addr: "b"}}, nil)
+	if r.created["a"] != 3 || r.created["b"] != 3 {
+		t.Fatalf("created %v, want 3 subconns per endpoint", r.created)
+	}
+	bb.HandleResolvedAddrs([]resolver.Address{{Addr: "a"}}, nil)
+	if r.removed != 3 || r.created["a"] != 3 {
+		t.Fatalf("removed %d subconns, created %v, want the 3 of b removed", r.removed, r.created)
+	}
+}
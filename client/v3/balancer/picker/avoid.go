@@ -0,0 +1,78 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"context"
+	"sync"
+)
+
+type avoidedKey struct{}
+
+// Avoided is the set of endpoints picked for the requests sent with the
+// same WithAvoided context, such as the hedged copies of a request, so that
+// each is sent to a different endpoint while there are others available.
+type Avoided struct {
+	mu    sync.Mutex
+	addrs map[string]struct{}
+}
+
+// NewAvoided returns an empty set of avoided endpoints.
+func NewAvoided() *Avoided {
+	return &Avoided{addrs: make(map[string]struct{})}
+}
+
+// WithAvoided makes the requests sent with ctx avoid the endpoints in a,
+// and adds the endpoints picked for them to a.
+func WithAvoided(ctx context.Context, a *Avoided) context.Context {
+	return context.WithValue(ctx, avoidedKey{}, a)
+}
+
+func avoidedFrom(ctx context.Context) *Avoided {
+	a, _ := ctx.Value(avoidedKey{}).(*Avoided)
+	return a
+}
+
+// Len returns the number of endpoints in a.
+func (a *Avoided) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.addrs)
+}
+
+func (a *Avoided) has(addr string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.addrs[addr]
+	return ok
+}
+
+func (a *Avoided) add(addr string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.addrs[addr] = struct{}{}
+	a.mu.Unlock()
+}
+
+// skipped returns true if a pick for a request sent with ctx skips the
+// endpoint at addr, as it is quarantined or avoided by the request.
+func skipped(ctx context.Context, health *HealthTracker, addr string) bool {
+	return health.Quarantined(addr) || avoidedFrom(ctx).has(addr)
+}
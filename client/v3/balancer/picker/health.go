@@ -0,0 +1,196 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	quarantinesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "client",
+		Name:      "endpoint_quarantines_total",
+		Help:      "The total number of times an endpoint was quarantined.",
+	}, []string{"endpoint"})
+
+	quarantineBypassesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "client",
+		Name:      "endpoint_quarantine_bypasses_total",
+		Help:      "The total number of requests sent to a quarantined endpoint because all endpoints were quarantined.",
+	})
+)
+
+// HealthCollectors returns the metrics of the endpoint quarantine. They are
+// not registered by the client; register them to export them.
+func HealthCollectors() []prometheus.Collector {
+	return []prometheus.Collector{quarantinesTotal, quarantineBypassesTotal}
+}
+
+// Outcome is the outcome of a request sent to an endpoint.
+type Outcome struct {
+	// Addr is the address of the endpoint.
+	Addr string
+
+	// Method is the full gRPC method name of the request,
+	// e.g. "/etcdserverpb.KV/Range".
+	Method string
+
+	// Latency is the time from when the endpoint was picked to when the
+	// request ended. For streams, such as watches, it is the lifetime of
+	// the stream.
+	Latency time.Duration
+
+	// Err is the error the request failed with, nil if it succeeded.
+	Err error
+}
+
+// HealthPolicy decides which endpoints to quarantine from the outcome of the
+// requests sent to them. A quarantined endpoint is not picked until its
+// quarantine ends, unless all endpoints are quarantined.
+// Observe may be called concurrently.
+type HealthPolicy interface {
+	// Observe is called with the outcome of every request, and returns how
+	// long to quarantine the endpoint for, or 0 to keep picking it.
+	Observe(o Outcome) time.Duration
+}
+
+// NewConsecutiveFailurePolicy returns a HealthPolicy quarantining an
+// endpoint for cooldown once maxFailures requests in a row sent to it
+// failed. A request fails if it ends with an error showing the endpoint
+// is unavailable or overloaded, or, if slowThreshold is positive, if it is
+// a KV request that took longer than slowThreshold.
+func NewConsecutiveFailurePolicy(maxFailures int, slowThreshold, cooldown time.Duration) HealthPolicy {
+	if maxFailures < 1 {
+		maxFailures = 1
+	}
+	return &consecutiveFailurePolicy{
+		maxFailures:   maxFailures,
+		slowThreshold: slowThreshold,
+		cooldown:      cooldown,
+		failures:      make(map[string]int),
+	}
+}
+
+type consecutiveFailurePolicy struct {
+	maxFailures   int
+	slowThreshold time.Duration
+	cooldown      time.Duration
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+func (p *consecutiveFailurePolicy) Observe(o Outcome) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.failed(o) {
+		delete(p.failures, o.Addr)
+		return 0
+	}
+	p.failures[o.Addr]++
+	if p.failures[o.Addr] < p.maxFailures {
+		return 0
+	}
+	// the endpoint gets maxFailures more requests once out of quarantine
+	delete(p.failures, o.Addr)
+	return p.cooldown
+}
+
+func (p *consecutiveFailurePolicy) failed(o Outcome) bool {
+	if o.Err != nil {
+		return isUnhealthy(o.Err)
+	}
+	// streams and other services' requests, such as locks, may
+	// legitimately last long
+	return p.slowThreshold > 0 && strings.HasPrefix(o.Method, "/etcdserverpb.KV/") && o.Latency > p.slowThreshold
+}
+
+// isUnhealthy returns true if err shows the endpoint it was returned by is
+// unhealthy, rather than the request being invalid or canceled.
+func isUnhealthy(err error) bool {
+	ev, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch ev.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+// HealthTracker quarantines endpoints as decided by a HealthPolicy. It is
+// shared by the successive pickers of a balancer, so that quarantines
+// outlive picker updates. A nil *HealthTracker quarantines no endpoint.
+type HealthTracker struct {
+	policy HealthPolicy
+	lg     *zap.Logger
+
+	mu    sync.RWMutex
+	until map[string]time.Time
+}
+
+// NewHealthTracker returns a HealthTracker quarantining endpoints with policy.
+func NewHealthTracker(policy HealthPolicy, lg *zap.Logger) *HealthTracker {
+	return &HealthTracker{
+		policy: policy,
+		lg:     lg,
+		until:  make(map[string]time.Time),
+	}
+}
+
+// Quarantined returns true if the endpoint at addr is quarantined.
+func (ht *HealthTracker) Quarantined(addr string) bool {
+	if ht == nil {
+		return false
+	}
+	ht.mu.RLock()
+	until, ok := ht.until[addr]
+	ht.mu.RUnlock()
+	return ok && time.Now().Before(until)
+}
+
+// Observe records the outcome of a request.
+func (ht *HealthTracker) Observe(o Outcome) {
+	if ht == nil {
+		return
+	}
+	d := ht.policy.Observe(o)
+	if d <= 0 {
+		return
+	}
+	ht.mu.Lock()
+	ht.until[o.Addr] = time.Now().Add(d)
+	ht.mu.Unlock()
+
+	quarantinesTotal.WithLabelValues(o.Addr).Inc()
+	ht.lg.Warn(
+		"quarantined endpoint",
+		zap.String("address", o.Addr),
+		zap.String("method", o.Method),
+		zap.Duration("latency", o.Latency),
+		zap.Error(o.Err),
+		zap.Duration("cooldown", d),
+	)
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+func TestConsecutiveFailurePolicy(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	tests := []struct {
+		name      string
+		outcomes  []Outcome
+		wcooldown []time.Duration
+	}{
+		{
+			name:      "consecutive errors",
+			outcomes:  []Outcome{{Err: unavailable}, {Err: unavailable}, {Err: unavailable}, {Err: unavailable}},
+			wcooldown: []time.Duration{0, 0, time.Second, 0},
+		},
+		{
+			name:      "success resets failures",
+			outcomes:  []Outcome{{Err: unavailable}, {Err: unavailable}, {}, {Err: unavailable}, {Err: unavailable}},
+			wcooldown: []time.Duration{0, 0, 0, 0, 0},
+		},
+		{
+			name: "errors not caused by the endpoint",
+			outcomes: []Outcome{
+				{Err: status.Error(codes.Canceled, "canceled")},
+				{Err: status.Error(codes.InvalidArgument, "invalid")},
+				{Err: errors.New("not a status")},
+			},
+			wcooldown: []time.Duration{0, 0, 0},
+		},
+		{
+			name: "slow KV requests",
+			outcomes: []Outcome{
+				{Method: "/etcdserverpb.KV/Range", Latency: 200 * time.Millisecond},
+				{Method: "/etcdserverpb.Watch/Watch", Latency: time.Hour},
+				{Method: "/etcdserverpb.KV/Put", Latency: 200 * time.Millisecond},
+				{Method: "/etcdserverpb.KV/Range", Latency: 200 * time.Millisecond},
+				{Method: "/etcdserverpb.KV/Range", Latency: 200 * time.Millisecond},
+			},
+			wcooldown: []time.Duration{0, 0, 0, 0, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewConsecutiveFailurePolicy(3, 100*time.Millisecond, time.Second)
+			for i, o := range tt.outcomes {
+				o.Addr = "a"
+				if d := p.Observe(o); d != tt.wcooldown[i] {
+					t.Errorf("#%d: cooldown = %v, want %v", i, d, tt.wcooldown[i])
+				}
+			}
+		})
+	}
+}
+
+type fakeSubConn struct{ addr string }
+
+func (sc *fakeSubConn) UpdateAddresses([]resolver.Address) {}
+func (sc *fakeSubConn) Connect()                           {}
+
+func TestRoundrobinBalancedQuarantine(t *testing.T) {
+	scToAddr := make(map[balancer.SubConn]resolver.Address)
+	for _, addr := range []string{"a", "b", "c"} {
+		scToAddr[&fakeSubConn{addr: addr}] = resolver.Address{Addr: addr}
+	}
+	health := NewHealthTracker(NewConsecutiveFailurePolicy(1, 0, time.Hour), zap.NewExample())
+	p := New(Config{
+		Policy:                   RoundrobinBalanced,
+		Logger:                   zap.NewExample(),
+		SubConnToResolverAddress: scToAddr,
+		Health:                   health,
+	})
+
+	pick := func() (string, func(balancer.DoneInfo)) {
+		sc, done, err := p.Pick(context.Background(), balancer.PickInfo{FullMethodName: "/etcdserverpb.KV/Range"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sc.(*fakeSubConn).addr, done
+	}
+
+	// fail each endpoint in turn; the others keep being picked
+	quarantined := make(map[string]bool)
+	for len(quarantined) < len(scToAddr) {
+		for i := 0; i < 2*len(scToAddr); i++ {
+			addr, done := pick()
+			if quarantined[addr] {
+				t.Fatalf("picked quarantined endpoint %q", addr)
+			}
+			done(balancer.DoneInfo{})
+		}
+		addr, done := pick()
+		done(balancer.DoneInfo{Err: status.Error(codes.Unavailable, "unavailable")})
+		if !health.Quarantined(addr) {
+			t.Fatalf("expected %q to be quarantined", addr)
+		}
+		quarantined[addr] = true
+	}
+
+	// all endpoints are quarantined, so they are all picked again
+	picked := make(map[string]bool)
+	for i := 0; i < len(scToAddr); i++ {
+		addr, _ := pick()
+		picked[addr] = true
+	}
+	if len(picked) != len(scToAddr) {
+		t.Fatalf("picked %v, want all endpoints", picked)
+	}
+}
+
+func TestHealthTrackerNil(t *testing.T) {
+	var ht *HealthTracker
+	ht.Observe(Outcome{Addr: "a", Err: status.Error(codes.Unavailable, "unavailable")})
+	if ht.Quarantined("a") {
+		t.Fatal("nil tracker must not quarantine")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/resolver"
+)
+
+// newLeastPending returns a new least pending requests picker.
+func newLeastPending(cfg Config) Picker {
+	scs := make([]balancer.SubConn, 0, len(cfg.SubConnToResolverAddress))
+	for sc := range cfg.SubConnToResolverAddress {
+		scs = append(scs, sc)
+	}
+	return &leastPending{
+		p:        LeastPending,
+		lg:       cfg.Logger,
+		scs:      scs,
+		scToAddr: cfg.SubConnToResolverAddress,
+		health:   cfg.Health,
+		pending:  make(map[balancer.SubConn]int),
+	}
+}
+
+type leastPending struct {
+	p Policy
+
+	lg *zap.Logger
+
+	scs      []balancer.SubConn
+	scToAddr map[balancer.SubConn]resolver.Address
+	health   *HealthTracker
+
+	mu sync.Mutex
+	// next is where the search for the least pending subconn starts, so
+	// that ties are broken in roundrobin fashion.
+	next    int
+	pending map[balancer.SubConn]int
+}
+
+func (lp *leastPending) String() string { return lp.p.String() }
+
+// Pick is called for every client request.
+func (lp *leastPending) Pick(ctx context.Context, opts balancer.PickInfo) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	n := len(lp.scs)
+	if n == 0 {
+		return nil, nil, balancer.ErrNoSubConnAvailable
+	}
+
+	lp.mu.Lock()
+	// skip the quarantined and avoided endpoints, unless all are
+	cur, bypassed := -1, true
+	for _, quarantined := range []bool{false, true} {
+		for i := 0; i < n; i++ {
+			idx := (lp.next + i) % n
+			if !quarantined && skipped(ctx, lp.health, lp.scToAddr[lp.scs[idx]].Addr) {
+				continue
+			}
+			if cur == -1 || lp.pending[lp.scs[idx]] < lp.pending[lp.scs[cur]] {
+				cur = idx
+			}
+		}
+		if cur != -1 {
+			bypassed = quarantined
+			break
+		}
+	}
+	sc := lp.scs[cur]
+	lp.pending[sc]++
+	pending := lp.pending[sc]
+	lp.next = (lp.next + 1) % n
+	lp.mu.Unlock()
+
+	picked := lp.scToAddr[sc].Addr
+	if bypassed && lp.health.Quarantined(picked) {
+		quarantineBypassesTotal.Inc()
+	}
+	avoidedFrom(ctx).add(picked)
+
+	lp.lg.Debug(
+		"picked",
+		zap.String("picker", lp.p.String()),
+		zap.String("address", picked),
+		zap.Int("pending", pending),
+		zap.Int("subconn-size", n),
+	)
+
+	done := newDoneFunc(lp.p, lp.lg, lp.health, picked, opts.FullMethodName)
+	return sc, func(info balancer.DoneInfo) {
+		lp.mu.Lock()
+		lp.pending[sc]--
+		lp.mu.Unlock()
+		done(info)
+	}, nil
+}
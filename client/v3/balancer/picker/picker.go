@@ -16,8 +16,10 @@ package picker
 
 import (
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/resolver"
 )
@@ -39,6 +41,19 @@ type Config struct {
 	// SubConnToResolverAddress maps each gRPC sub-connection to an address.
 	// Basically, it is a list of addresses that the Picker can pick from.
 	SubConnToResolverAddress map[balancer.SubConn]resolver.Address
+
+	// Addrs lists all resolved addresses, in the order they were resolved.
+	// The Pinned policy prefers the first ones.
+	Addrs []resolver.Address
+
+	// Health quarantines the endpoints that are not picked.
+	// If nil, all endpoints are picked.
+	Health *HealthTracker
+
+	// Zone is the zone of the client, and AddrZones maps addresses to the
+	// zone of their member, for the ZoneAware policy.
+	Zone      string
+	AddrZones map[string]string
 }
 
 // Policy defines balancer picker policy.
@@ -55,6 +70,21 @@ const (
 	// Custom defines custom balancer picker.
 	// TODO: custom picker is not supported yet.
 	Custom
+
+	// Pinned sends all requests to the first endpoint available, in the
+	// order of the resolved addresses, and only fails over to the next one
+	// when it is not.
+	Pinned
+
+	// LeastPending sends each request to the endpoint with the fewest
+	// requests in flight.
+	LeastPending
+
+	// ZoneAware balances the requests marked with WithPreferLocal, such as
+	// serializable reads, over the endpoints in the zone of the client when
+	// some are available, and the other requests over all endpoints, in
+	// roundrobin fashion.
+	ZoneAware
 )
 
 func (p Policy) String() string {
@@ -68,6 +98,15 @@ func (p Policy) String() string {
 	case Custom:
 		panic("'custom' picker policy is not supported yet")
 
+	case Pinned:
+		return "picker-pinned"
+
+	case LeastPending:
+		return "picker-least-pending"
+
+	case ZoneAware:
+		return "picker-zone-aware"
+
 	default:
 		panic(fmt.Errorf("invalid balancer picker policy (%d)", p))
 	}
@@ -85,7 +124,44 @@ func New(cfg Config) Picker {
 	case Custom:
 		panic("'custom' picker policy is not supported yet")
 
+	case Pinned:
+		return newPinned(cfg)
+
+	case LeastPending:
+		return newLeastPending(cfg)
+
+	case ZoneAware:
+		return newZoneAware(cfg)
+
 	default:
 		panic(fmt.Errorf("invalid balancer picker policy (%d)", cfg.Policy))
 	}
 }
+
+// newDoneFunc returns the function called when a request sent to the
+// endpoint at picked is done.
+func newDoneFunc(p Policy, lg *zap.Logger, health *HealthTracker, picked, method string) func(balancer.DoneInfo) {
+	start := time.Now()
+	return func(info balancer.DoneInfo) {
+		health.Observe(Outcome{
+			Addr:    picked,
+			Method:  method,
+			Latency: time.Since(start),
+			Err:     info.Err,
+		})
+
+		fss := []zapcore.Field{
+			zap.Error(info.Err),
+			zap.String("picker", p.String()),
+			zap.String("address", picked),
+			zap.Bool("success", info.Err == nil),
+			zap.Bool("bytes-sent", info.BytesSent),
+			zap.Bool("bytes-received", info.BytesReceived),
+		}
+		if info.Err == nil {
+			lg.Debug("balancer done", fss...)
+		} else {
+			lg.Warn("balancer failed", fss...)
+		}
+	}
+}
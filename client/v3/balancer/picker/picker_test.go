@@ -0,0 +1,153 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+func newTestConfig(policy Policy, addrs ...string) Config {
+	cfg := Config{
+		Policy:                   policy,
+		Logger:                   zap.NewExample(),
+		SubConnToResolverAddress: make(map[balancer.SubConn]resolver.Address),
+	}
+	for _, addr := range addrs {
+		cfg.SubConnToResolverAddress[&fakeSubConn{addr: addr}] = resolver.Address{Addr: addr}
+		cfg.Addrs = append(cfg.Addrs, resolver.Address{Addr: addr})
+	}
+	return cfg
+}
+
+func mustPick(t *testing.T, p Picker, preferLocal bool) (string, func(balancer.DoneInfo)) {
+	ctx := context.Background()
+	if preferLocal {
+		ctx = WithPreferLocal(ctx)
+	}
+	sc, done, err := p.Pick(ctx, balancer.PickInfo{FullMethodName: "/etcdserverpb.KV/Range", Ctx: ctx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc.(*fakeSubConn).addr, done
+}
+
+func TestPinned(t *testing.T) {
+	cfg := newTestConfig(Pinned, "a", "b", "c")
+	cfg.Health = NewHealthTracker(NewConsecutiveFailurePolicy(1, 0, time.Hour), zap.NewExample())
+	p := New(cfg)
+
+	for i := 0; i < 3; i++ {
+		addr, done := mustPick(t, p, false)
+		if addr != "a" {
+			t.Fatalf("#%d: picked %q, want %q", i, addr, "a")
+		}
+		done(balancer.DoneInfo{})
+	}
+
+	// fail over to the next endpoint once the first is quarantined
+	_, done := mustPick(t, p, false)
+	done(balancer.DoneInfo{Err: status.Error(codes.Unavailable, "unavailable")})
+	if addr, _ := mustPick(t, p, false); addr != "b" {
+		t.Fatalf("picked %q, want %q", addr, "b")
+	}
+}
+
+func TestLeastPending(t *testing.T) {
+	p := New(newTestConfig(LeastPending, "a", "b", "c"))
+
+	// each endpoint gets a pending request before any gets a second
+	dones := make(map[string]func(balancer.DoneInfo))
+	for i := 0; i < 3; i++ {
+		addr, done := mustPick(t, p, false)
+		if _, ok := dones[addr]; ok {
+			t.Fatalf("picked %q twice with idle endpoints", addr)
+		}
+		dones[addr] = done
+	}
+
+	// the endpoint done with its request is the least pending one
+	dones["b"](balancer.DoneInfo{})
+	if addr, _ := mustPick(t, p, false); addr != "b" {
+		t.Fatalf("picked %q, want %q", addr, "b")
+	}
+}
+
+func TestZoneAware(t *testing.T) {
+	cfg := newTestConfig(ZoneAware, "a", "b", "c")
+	cfg.Zone = "z1"
+	cfg.AddrZones = map[string]string{"a": "z1", "b": "z2", "c": "z1"}
+	cfg.Health = NewHealthTracker(NewConsecutiveFailurePolicy(1, 0, time.Hour), zap.NewExample())
+	p := New(cfg)
+
+	picked := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		addr, _ := mustPick(t, p, true)
+		picked[addr] = true
+	}
+	if len(picked) != 2 || !picked["a"] || !picked["c"] {
+		t.Fatalf("local requests picked %v, want a and c", picked)
+	}
+
+	picked = make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		addr, _ := mustPick(t, p, false)
+		picked[addr] = true
+	}
+	if len(picked) != 3 {
+		t.Fatalf("other requests picked %v, want all endpoints", picked)
+	}
+
+	// local requests go to the other zones once the local endpoints are
+	// quarantined
+	for _, addr := range []string{"a", "c"} {
+		cfg.Health.Observe(Outcome{Addr: addr, Err: status.Error(codes.Unavailable, "unavailable")})
+	}
+	if addr, _ := mustPick(t, p, true); addr != "b" {
+		t.Fatalf("picked %q, want %q", addr, "b")
+	}
+}
+
+func TestAvoided(t *testing.T) {
+	for _, policy := range []Policy{Pinned, RoundrobinBalanced, LeastPending} {
+		p := New(newTestConfig(policy, "a", "b", "c"))
+		avoided := NewAvoided()
+		ctx := WithAvoided(context.Background(), avoided)
+
+		// requests sharing the avoided set go to distinct endpoints
+		picked := make(map[string]bool)
+		for i := 0; i < 3; i++ {
+			sc, _, err := p.Pick(ctx, balancer.PickInfo{FullMethodName: "/etcdserverpb.KV/Range", Ctx: ctx})
+			if err != nil {
+				t.Fatal(err)
+			}
+			picked[sc.(*fakeSubConn).addr] = true
+		}
+		if len(picked) != 3 || avoided.Len() != 3 {
+			t.Fatalf("%v: picked %v, want all endpoints", policy, picked)
+		}
+		// once all are avoided, any is picked
+		if _, _, err := p.Pick(ctx, balancer.PickInfo{FullMethodName: "/etcdserverpb.KV/Range", Ctx: ctx}); err != nil {
+			t.Fatalf("%v: %v", policy, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/resolver"
+)
+
+// newPinned returns a new pinned picker.
+func newPinned(cfg Config) Picker {
+	addrToScs := make(map[resolver.Address][]balancer.SubConn, len(cfg.SubConnToResolverAddress))
+	for sc, addr := range cfg.SubConnToResolverAddress {
+		addrToScs[addr] = append(addrToScs[addr], sc)
+	}
+	// keep the endpoints in the order of the resolved addresses
+	addrs := make([]resolver.Address, 0, len(addrToScs))
+	for _, addr := range cfg.Addrs {
+		if _, ok := addrToScs[addr]; ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	return &pinned{
+		p:         Pinned,
+		lg:        cfg.Logger,
+		addrs:     addrs,
+		addrToScs: addrToScs,
+		health:    cfg.Health,
+	}
+}
+
+type pinned struct {
+	p Policy
+
+	lg *zap.Logger
+
+	addrs     []resolver.Address
+	addrToScs map[resolver.Address][]balancer.SubConn
+	health    *HealthTracker
+
+	// next stripes the requests over the connections to the pinned
+	// endpoint
+	next uint32
+}
+
+func (pp *pinned) String() string { return pp.p.String() }
+
+// Pick is called for every client request.
+func (pp *pinned) Pick(ctx context.Context, opts balancer.PickInfo) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	if len(pp.addrs) == 0 {
+		return nil, nil, balancer.ErrNoSubConnAvailable
+	}
+
+	// skip the quarantined and avoided endpoints, unless all are
+	addr := pp.addrs[0]
+	bypassed := true
+	for _, a := range pp.addrs {
+		if !skipped(ctx, pp.health, a.Addr) {
+			addr, bypassed = a, false
+			break
+		}
+	}
+	picked := addr.Addr
+	if bypassed && pp.health.Quarantined(picked) {
+		quarantineBypassesTotal.Inc()
+	}
+	avoidedFrom(ctx).add(picked)
+	scs := pp.addrToScs[addr]
+	sc := scs[atomic.AddUint32(&pp.next, 1)%uint32(len(scs))]
+
+	pp.lg.Debug(
+		"picked",
+		zap.String("picker", pp.p.String()),
+		zap.String("address", picked),
+		zap.Int("endpoint-size", len(pp.addrToScs)),
+	)
+	return sc, newDoneFunc(pp.p, pp.lg, pp.health, picked, opts.FullMethodName), nil
+}
@@ -19,7 +19,6 @@ import (
 	"sync"
 
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/resolver"
 )
@@ -35,6 +34,7 @@ func newRoundrobinBalanced(cfg Config) Picker {
 		lg:       cfg.Logger,
 		scs:      scs,
 		scToAddr: cfg.SubConnToResolverAddress,
+		health:   cfg.Health,
 	}
 }
 
@@ -47,6 +47,7 @@ type rrBalanced struct {
 	next     int
 	scs      []balancer.SubConn
 	scToAddr map[balancer.SubConn]resolver.Address
+	health   *HealthTracker
 }
 
 func (rb *rrBalanced) String() string { return rb.p.String() }
@@ -61,12 +62,24 @@ func (rb *rrBalanced) Pick(ctx context.Context, opts balancer.PickInfo) (balance
 	}
 
 	rb.mu.Lock()
-	cur := rb.next
+	// skip the quarantined and avoided endpoints, unless all are
+	cur, bypassed := rb.next, true
+	for i := 0; i < n; i++ {
+		if idx := (rb.next + i) % n; !skipped(ctx, rb.health, rb.scToAddr[rb.scs[idx]].Addr) {
+			cur, bypassed = idx, false
+			break
+		}
+	}
 	sc := rb.scs[cur]
 	picked := rb.scToAddr[sc].Addr
-	rb.next = (rb.next + 1) % len(rb.scs)
+	rb.next = (cur + 1) % n
 	rb.mu.Unlock()
 
+	if bypassed && rb.health.Quarantined(picked) {
+		quarantineBypassesTotal.Inc()
+	}
+	avoidedFrom(ctx).add(picked)
+
 	rb.lg.Debug(
 		"picked",
 		zap.String("picker", rb.p.String()),
@@ -75,21 +88,15 @@ func (rb *rrBalanced) Pick(ctx context.Context, opts balancer.PickInfo) (balance
 		zap.Int("subconn-size", n),
 	)
 
-	doneFunc := func(info balancer.DoneInfo) {
-		// TODO: error handling?
-		fss := []zapcore.Field{
-			zap.Error(info.Err),
-			zap.String("picker", rb.p.String()),
-			zap.String("address", picked),
-			zap.Bool("success", info.Err == nil),
-			zap.Bool("bytes-sent", info.BytesSent),
-			zap.Bool("bytes-received", info.BytesReceived),
-		}
-		if info.Err == nil {
-			rb.lg.Debug("balancer done", fss...)
-		} else {
-			rb.lg.Warn("balancer failed", fss...)
+	return sc, newDoneFunc(rb.p, rb.lg, rb.health, picked, opts.FullMethodName), nil
+}
+
+// available returns true if some endpoint is not quarantined.
+func (rb *rrBalanced) available() bool {
+	for _, sc := range rb.scs {
+		if !rb.health.Quarantined(rb.scToAddr[sc].Addr) {
+			return true
 		}
 	}
-	return sc, doneFunc, nil
+	return false
 }
@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package picker
+
+import (
+	"context"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/resolver"
+)
+
+type preferLocalKey struct{}
+
+// WithPreferLocal marks the requests sent with ctx as better served by an
+// endpoint in the zone of the client, as serializable reads are.
+func WithPreferLocal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, preferLocalKey{}, true)
+}
+
+func isPreferLocal(ctx context.Context) bool {
+	local, _ := ctx.Value(preferLocalKey{}).(bool)
+	return local
+}
+
+// newZoneAware returns a new zone aware picker.
+func newZoneAware(cfg Config) Picker {
+	local := make(map[balancer.SubConn]resolver.Address)
+	for sc, addr := range cfg.SubConnToResolverAddress {
+		if zone, ok := cfg.AddrZones[addr.Addr]; ok && zone == cfg.Zone {
+			local[sc] = addr
+		}
+	}
+	all := newRoundrobinBalanced(cfg).(*rrBalanced)
+	cfg.SubConnToResolverAddress = local
+	locals := newRoundrobinBalanced(cfg).(*rrBalanced)
+	all.p, locals.p = ZoneAware, ZoneAware
+	return &zoneAware{p: ZoneAware, all: all, local: locals}
+}
+
+type zoneAware struct {
+	p     Policy
+	all   *rrBalanced
+	local *rrBalanced
+}
+
+func (za *zoneAware) String() string { return za.p.String() }
+
+// Pick is called for every client request.
+func (za *zoneAware) Pick(ctx context.Context, opts balancer.PickInfo) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	if isPreferLocal(ctx) && za.local.available() {
+		return za.local.Pick(ctx, opts)
+	}
+	return za.all.Pick(ctx, opts)
+}
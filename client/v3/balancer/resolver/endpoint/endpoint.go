@@ -91,6 +91,11 @@ func (e *ResolverGroup) SetEndpoints(endpoints []string) {
 	e.mu.Unlock()
 }
 
+// ID returns the id of the ResolverGroup, the authority of its targets.
+func (e *ResolverGroup) ID() string {
+	return e.id
+}
+
 // Target constructs a endpoint target using the endpoint id of the ResolverGroup.
 func (e *ResolverGroup) Target(endpoint string) string {
 	return Target(e.id, endpoint)
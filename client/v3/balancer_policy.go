@@ -0,0 +1,62 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"fmt"
+
+	"go.etcd.io/etcd/client/v3/balancer/picker"
+)
+
+// BalancerPolicy selects the endpoint each request is sent to.
+type BalancerPolicy int
+
+const (
+	// BalancerRoundRobin balances the requests over the endpoints in
+	// roundrobin fashion.
+	BalancerRoundRobin BalancerPolicy = iota
+
+	// BalancerPinned sends all requests to the first endpoint available,
+	// in the order of the endpoints, and only fails over to the next one
+	// when it is not.
+	BalancerPinned
+
+	// BalancerLeastPending sends each request to the endpoint with the
+	// fewest requests in flight.
+	BalancerLeastPending
+
+	// BalancerZoneAware sends the serializable reads to the endpoints in
+	// the zone of the client (see Config.BalancerZone) when some are
+	// available, and balances the other requests over all endpoints in
+	// roundrobin fashion.
+	BalancerZoneAware
+)
+
+// balancerPolicies are the picker policies of the balancer policies.
+var balancerPolicies = map[BalancerPolicy]picker.Policy{
+	BalancerRoundRobin:   picker.RoundrobinBalanced,
+	BalancerPinned:       picker.Pinned,
+	BalancerLeastPending: picker.LeastPending,
+	BalancerZoneAware:    picker.ZoneAware,
+}
+
+// balancerName returns the name of the balancer registered for the policy.
+func balancerName(p BalancerPolicy) (string, error) {
+	pp, ok := balancerPolicies[p]
+	if !ok {
+		return "", fmt.Errorf("invalid balancer policy (%d)", p)
+	}
+	return fmt.Sprintf("etcd-%s", pp.String()), nil
+}
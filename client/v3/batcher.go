@@ -0,0 +1,292 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchMaxOps is the default maximum number of operations per
+	// batch, the default --max-txn-ops of the server.
+	DefaultBatchMaxOps = 128
+	// DefaultBatchMaxBytes is the default maximum size of the keys and
+	// values of a batch, under the default --max-request-bytes of the
+	// server.
+	DefaultBatchMaxBytes = 1024 * 1024
+	// DefaultBatchFlushInterval is the default maximum time an operation
+	// waits for its batch to fill up.
+	DefaultBatchFlushInterval = 10 * time.Millisecond
+)
+
+// ErrBatcherClosed is returned for the operations given to a closed Batcher.
+var ErrBatcherClosed = errors.New("clientv3: batcher closed")
+
+// BatcherConfig configures a Batcher. Zero fields take their default value.
+type BatcherConfig struct {
+	// MaxOps is the maximum number of operations per batch. It must not
+	// exceed the --max-txn-ops of the server.
+	MaxOps int
+	// MaxBytes is the maximum size of the keys and values of a batch. It
+	// should leave room under the --max-request-bytes of the server. An
+	// operation larger than MaxBytes is sent in a batch of its own.
+	MaxBytes int
+	// FlushInterval is the maximum time an operation waits for its batch
+	// to fill up before the batch is sent.
+	FlushInterval time.Duration
+}
+
+// Batcher coalesces the puts and deletes given by concurrent callers into
+// transactions, to write at a higher rate than with a request per
+// operation. A batch is sent once it holds MaxOps operations or MaxBytes
+// bytes, or FlushInterval after its first operation was given, and before
+// an operation on a key already written by the batch, since a transaction
+// may only write each key once. Batches are sent one at a time, in order,
+// so operations are applied in the order they were given.
+//
+// Each operation returns a BatchFuture resolved with its response once its
+// batch is committed. If the transaction of a batch fails, all its
+// operations fail with its error.
+type Batcher struct {
+	kv  KV
+	cfg BatcherConfig
+
+	mu     sync.Mutex
+	ops    []*BatchFuture
+	size   int
+	gen    uint64
+	closed bool
+
+	batchc chan []*BatchFuture
+	donec  chan struct{}
+}
+
+// NewBatcher returns a Batcher writing with kv, such as a Client.
+func NewBatcher(kv KV, cfg BatcherConfig) *Batcher {
+	if cfg.MaxOps <= 0 {
+		cfg.MaxOps = DefaultBatchMaxOps
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultBatchMaxBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultBatchFlushInterval
+	}
+	b := &Batcher{
+		kv:     kv,
+		cfg:    cfg,
+		batchc: make(chan []*BatchFuture),
+		donec:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// BatchFuture is the pending result of an operation given to a Batcher.
+type BatchFuture struct {
+	ctx  context.Context
+	op   Op
+	done chan struct{}
+	resp OpResponse
+	err  error
+}
+
+// Done returns a channel closed once the operation is committed or failed.
+func (f *BatchFuture) Done() <-chan struct{} { return f.done }
+
+// Wait waits for the operation to be committed and returns its response,
+// whose Put or Del is set, with the header of the transaction of its batch.
+func (f *BatchFuture) Wait(ctx context.Context) (OpResponse, error) {
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		return OpResponse{}, ctx.Err()
+	}
+}
+
+func (f *BatchFuture) resolve(resp OpResponse, err error) {
+	f.resp, f.err = resp, err
+	close(f.done)
+}
+
+// Put adds a put to the current batch. It accepts the options of KV.Put.
+// If ctx is done before the batch is sent, the put is dropped and fails
+// with the error of ctx.
+func (b *Batcher) Put(ctx context.Context, key, val string, opts ...OpOption) *BatchFuture {
+	return b.add(ctx, OpPut(key, val, opts...))
+}
+
+// Delete adds a delete to the current batch. It accepts the options of
+// KV.Delete. If ctx is done before the batch is sent, the delete is
+// dropped and fails with the error of ctx.
+func (b *Batcher) Delete(ctx context.Context, key string, opts ...OpOption) *BatchFuture {
+	return b.add(ctx, OpDelete(key, opts...))
+}
+
+func (b *Batcher) add(ctx context.Context, op Op) *BatchFuture {
+	f := &BatchFuture{ctx: ctx, op: op, done: make(chan struct{})}
+	size := len(op.key) + len(op.end) + len(op.val)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		f.resolve(OpResponse{}, ErrBatcherClosed)
+		return f
+	}
+	if len(b.ops) > 0 && (b.size+size > b.cfg.MaxBytes || b.conflicts(op)) {
+		b.cut()
+	}
+	b.ops = append(b.ops, f)
+	b.size += size
+	if len(b.ops) == 1 {
+		gen := b.gen
+		time.AfterFunc(b.cfg.FlushInterval, func() { b.flushGen(gen) })
+	}
+	if len(b.ops) >= b.cfg.MaxOps {
+		b.cut()
+	}
+	return f
+}
+
+// conflicts returns true if op writes a key the current batch writes.
+func (b *Batcher) conflicts(op Op) bool {
+	for _, f := range b.ops {
+		if overlaps(f.op, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns true if the key ranges written by two ops overlap.
+func overlaps(a, b Op) bool {
+	aStart, aEnd := writtenRange(a)
+	bStart, bEnd := writtenRange(b)
+	return less(aStart, bEnd) && less(bStart, aEnd)
+}
+
+// writtenRange returns the range of keys op writes, where a nil end is the
+// end of the keyspace.
+func writtenRange(op Op) ([]byte, []byte) {
+	switch {
+	case len(op.end) == 0:
+		return op.key, append(append([]byte(nil), op.key...), 0)
+	case bytes.Equal(op.end, []byte{0}):
+		return op.key, nil
+	}
+	return op.key, op.end
+}
+
+// less returns true if key is before end, where a nil end is the end of the
+// keyspace.
+func less(key, end []byte) bool {
+	return end == nil || bytes.Compare(key, end) < 0
+}
+
+// Flush sends the current batch.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.cut()
+	}
+}
+
+// flushGen sends the current batch if it is still the batch gen.
+func (b *Batcher) flushGen(gen uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed && b.gen == gen {
+		b.cut()
+	}
+}
+
+// cut hands the current batch over to be sent, and starts a new one. The
+// lock must be held; it blocks while the previous batch is being sent, so
+// that batches are sent in order and callers are slowed down by a
+// lagging cluster rather than buffering without bound.
+func (b *Batcher) cut() {
+	if len(b.ops) == 0 {
+		return
+	}
+	b.batchc <- b.ops
+	b.ops, b.size = nil, 0
+	b.gen++
+}
+
+// Close sends the current batch, waits for all batches to be committed,
+// and fails the operations given afterwards with ErrBatcherClosed.
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		<-b.donec
+		return
+	}
+	b.cut()
+	b.closed = true
+	close(b.batchc)
+	b.mu.Unlock()
+	<-b.donec
+}
+
+func (b *Batcher) run() {
+	defer close(b.donec)
+	for batch := range b.batchc {
+		b.commit(batch)
+	}
+}
+
+func (b *Batcher) commit(batch []*BatchFuture) {
+	// drop the operations whose callers gave up
+	var fs []*BatchFuture
+	var ops []Op
+	for _, f := range batch {
+		if err := f.ctx.Err(); err != nil {
+			f.resolve(OpResponse{}, err)
+			continue
+		}
+		fs = append(fs, f)
+		ops = append(ops, f.op)
+	}
+	if len(ops) == 0 {
+		return
+	}
+
+	resp, err := b.kv.Do(context.Background(), OpTxn(nil, ops, nil))
+	if err != nil {
+		for _, f := range fs {
+			f.resolve(OpResponse{}, err)
+		}
+		return
+	}
+	txn := resp.Txn()
+	for i, f := range fs {
+		var oresp OpResponse
+		if r := txn.Responses[i].GetResponsePut(); r != nil {
+			r.Header = txn.Header
+			oresp.put = (*PutResponse)(r)
+		} else if r := txn.Responses[i].GetResponseDeleteRange(); r != nil {
+			r.Header = txn.Header
+			oresp.del = (*DeleteResponse)(r)
+		}
+		f.resolve(oresp, nil)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+// txnKV records the keys of the transactions it is given.
+type txnKV struct {
+	KV
+	mu   sync.Mutex
+	txns [][]string
+	err  error
+}
+
+func (kv *txnKV) Do(ctx context.Context, op Op) (OpResponse, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.err != nil {
+		return OpResponse{}, kv.err
+	}
+	_, ops, _ := op.Txn()
+	var keys []string
+	resp := &TxnResponse{Header: &pb.ResponseHeader{Revision: int64(len(kv.txns) + 1)}, Succeeded: true}
+	for _, op := range ops {
+		keys = append(keys, string(op.KeyBytes()))
+		if op.IsPut() {
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{}}})
+		} else {
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: &pb.DeleteRangeResponse{Deleted: 1}}})
+		}
+	}
+	kv.txns = append(kv.txns, keys)
+	return OpResponse{txn: resp}, nil
+}
+
+func TestBatcher(t *testing.T) {
+	kv := &txnKV{}
+	b := NewBatcher(kv, BatcherConfig{MaxOps: 3, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	fs := []*BatchFuture{
+		b.Put(ctx, "a", "1"),
+		b.Put(ctx, "b", "1"),
+		b.Delete(ctx, "c"),
+		// full batch sent, next one starts
+		b.Put(ctx, "d", "1"),
+		// conflicts with the put of d
+		b.Delete(ctx, "c", WithFromKey()),
+	}
+	b.Close()
+
+	wtxns := [][]string{{"a", "b", "c"}, {"d"}, {"c"}}
+	if !reflect.DeepEqual(kv.txns, wtxns) {
+		t.Fatalf("txns = %v, want %v", kv.txns, wtxns)
+	}
+	wrevs := []int64{1, 1, 1, 2, 3}
+	for i, f := range fs {
+		resp, err := f.Wait(ctx)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		var rev int64
+		if resp.Put() != nil {
+			rev = resp.Put().Header.Revision
+		} else {
+			rev = resp.Del().Header.Revision
+		}
+		if rev != wrevs[i] {
+			t.Errorf("#%d: revision = %d, want %d", i, rev, wrevs[i])
+		}
+	}
+
+	if _, err := b.Put(ctx, "e", "1").Wait(ctx); err != ErrBatcherClosed {
+		t.Fatalf("err = %v, want %v", err, ErrBatcherClosed)
+	}
+}
+
+func TestBatcherMaxBytes(t *testing.T) {
+	kv := &txnKV{}
+	b := NewBatcher(kv, BatcherConfig{MaxBytes: 4, FlushInterval: time.Hour})
+	ctx := context.Background()
+	b.Put(ctx, "a", "1")
+	b.Put(ctx, "b", "1")
+	b.Put(ctx, "c", "123456")
+	b.Close()
+
+	wtxns := [][]string{{"a", "b"}, {"c"}}
+	if !reflect.DeepEqual(kv.txns, wtxns) {
+		t.Fatalf("txns = %v, want %v", kv.txns, wtxns)
+	}
+}
+
+func TestBatcherFlushInterval(t *testing.T) {
+	kv := &txnKV{}
+	b := NewBatcher(kv, BatcherConfig{FlushInterval: 10 * time.Millisecond})
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := b.Put(ctx, "a", "1").Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatcherErrors(t *testing.T) {
+	errTxn := errors.New("txn failed")
+	kv := &txnKV{err: errTxn}
+	b := NewBatcher(kv, BatcherConfig{FlushInterval: time.Hour})
+
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	fs := []*BatchFuture{b.Put(ctx, "a", "1"), b.Put(canceled, "b", "1")}
+	b.Close()
+
+	if _, err := fs[0].Wait(ctx); err != errTxn {
+		t.Errorf("err = %v, want %v", err, errTxn)
+	}
+	if _, err := fs[1].Wait(ctx); err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache implements an in-memory mirror of the keys under a prefix,
+// kept up to date by a watch, to serve read-heavy workloads without issuing
+// a Range request per read.
+//
+// The cache lists the prefix, then applies the events of a watch started
+// right after the list, resuming it through failures and compactions with
+// clientv3.WatchRetrier. Reads are served at the revision of the last
+// update applied, so a sequence of reads never goes back in time, but may
+// lag behind the cluster. WithMaxStaleness bounds that lag.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+// progressRetryInterval is the wait before the progress of the watch is
+// requested again, when a read waits for the cache to be confirmed up to
+// date.
+var progressRetryInterval = 100 * time.Millisecond
+
+// ErrClosed is returned reading a closed cache.
+var ErrClosed = errors.New("cache: closed")
+
+type options struct {
+	maxStaleness time.Duration
+}
+
+// Option configures a Cache.
+type Option func(*options)
+
+// WithMaxStaleness bounds how long ago the cache may have been last
+// confirmed up to date with the cluster for a read to be served. A read of
+// a cache confirmed longer ago first requests the progress of the watch,
+// and waits for the cluster to confirm that the cache is up to date. If d
+// is not positive, reads are served whatever the lag of the cache.
+func WithMaxStaleness(d time.Duration) Option {
+	return func(o *options) { o.maxStaleness = d }
+}
+
+// Cache is an in-memory mirror of the keys under a prefix.
+type Cache struct {
+	w      clientv3.Watcher
+	ctx    context.Context
+	cancel context.CancelFunc
+	donec  chan struct{}
+	opts   options
+
+	mu  sync.RWMutex
+	kvs map[string]*mvccpb.KeyValue
+	rev int64
+	// synced is when the cache was last confirmed up to date, by the list
+	// or by a progress notification of the watch.
+	synced time.Time
+	// syncc is closed, and replaced, when the cache is confirmed up to
+	// date.
+	syncc chan struct{}
+	err   error
+}
+
+// New lists the keys under prefix and returns a cache of them, kept up to
+// date until it is closed. The context only bounds the list.
+func New(ctx context.Context, c *clientv3.Client, prefix string, opts ...Option) (*Cache, error) {
+	// the watch is resumed from another member if its member loses the
+	// leader, rather than lagging behind the cluster
+	cctx, cancel := context.WithCancel(clientv3.WithRequireLeader(c.Ctx()))
+	cc := &Cache{
+		w:      c.Watcher,
+		ctx:    cctx,
+		cancel: cancel,
+		donec:  make(chan struct{}),
+		kvs:    make(map[string]*mvccpb.KeyValue),
+		syncc:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&cc.opts)
+	}
+
+	wch := clientv3.NewWatchRetrier(c.KV, c.Watcher).ListAndWatch(cctx, prefix, clientv3.WithPrefix())
+	select {
+	case wresp, ok := <-wch:
+		if !ok {
+			cancel()
+			return nil, ErrClosed
+		}
+		if err := wresp.Err(); err != nil {
+			cancel()
+			return nil, err
+		}
+		cc.apply(wresp)
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	go cc.run(wch)
+	return cc, nil
+}
+
+func (cc *Cache) run(wch clientv3.WatchChan) {
+	defer close(cc.donec)
+	for wresp := range wch {
+		if err := wresp.Err(); err != nil {
+			cc.fail(err)
+			return
+		}
+		cc.apply(wresp)
+	}
+	cc.fail(ErrClosed)
+}
+
+func (cc *Cache) apply(wresp clientv3.WatchResponse) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for _, ev := range wresp.Events {
+		switch ev.Type {
+		case clientv3.EventTypePut:
+			cc.kvs[string(ev.Kv.Key)] = ev.Kv
+		case clientv3.EventTypeDelete:
+			delete(cc.kvs, string(ev.Kv.Key))
+		}
+	}
+	switch {
+	case wresp.IsList(), wresp.IsProgressNotify():
+		cc.rev = wresp.Header.Revision
+		cc.confirm()
+	case len(wresp.Events) > 0:
+		// the header of a response to a watch catching up may be ahead of
+		// its events
+		cc.rev = wresp.Events[len(wresp.Events)-1].Kv.ModRevision
+	}
+}
+
+// confirm records that the cache is up to date. The lock must be held.
+func (cc *Cache) confirm() {
+	cc.synced = time.Now()
+	close(cc.syncc)
+	cc.syncc = make(chan struct{})
+}
+
+func (cc *Cache) fail(err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.err == nil {
+		cc.err = err
+	}
+}
+
+// Get returns the key-value of the key, or nil if the key is not in the
+// cache, and the revision of the cache.
+func (cc *Cache) Get(ctx context.Context, key string) (*mvccpb.KeyValue, int64, error) {
+	if err := cc.fresh(ctx); err != nil {
+		return nil, 0, err
+	}
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.kvs[key], cc.rev, nil
+}
+
+// List returns the key-values of the keys with the prefix in the cache, in
+// key order, and the revision of the cache.
+func (cc *Cache) List(ctx context.Context, prefix string) ([]*mvccpb.KeyValue, int64, error) {
+	if err := cc.fresh(ctx); err != nil {
+		return nil, 0, err
+	}
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	var kvs []*mvccpb.KeyValue
+	for k, kv := range cc.kvs {
+		if strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return string(kvs[i].Key) < string(kvs[j].Key) })
+	return kvs, cc.rev, nil
+}
+
+// Rev returns the revision of the cache.
+func (cc *Cache) Rev() int64 {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.rev
+}
+
+// Close stops updating the cache, after which reads fail with ErrClosed.
+func (cc *Cache) Close() {
+	cc.cancel()
+	<-cc.donec
+}
+
+// fresh waits, if the cache was last confirmed up to date longer ago than
+// the maximum staleness, for it to be confirmed up to date again.
+func (cc *Cache) fresh(ctx context.Context) error {
+	for {
+		cc.mu.RLock()
+		err, synced, syncc := cc.err, cc.synced, cc.syncc
+		cc.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if cc.opts.maxStaleness <= 0 || time.Since(synced) <= cc.opts.maxStaleness {
+			return nil
+		}
+
+		// the progress is only notified once the watch caught up, so it is
+		// requested until then
+		if err = cc.w.RequestProgress(cc.ctx); err != nil {
+			return err
+		}
+		select {
+		case <-syncc:
+			// confirmed up to date after the read started, however short
+			// the maximum staleness is
+			cc.mu.RLock()
+			err = cc.err
+			cc.mu.RUnlock()
+			return err
+		case <-time.After(progressRetryInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cc.donec:
+		}
+	}
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/google/uuid"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/v3/balancer"
-	"go.etcd.io/etcd/client/v3/balancer/picker"
 	"go.etcd.io/etcd/client/v3/balancer/resolver/endpoint"
 	"go.etcd.io/etcd/client/v3/credentials"
 	"go.etcd.io/etcd/pkg/v3/logutil"
@@ -43,8 +42,6 @@ import (
 var (
 	ErrNoAvailableEndpoints = errors.New("etcdclient: no available endpoints")
 	ErrOldCluster           = errors.New("etcdclient: old cluster version")
-
-	roundRobinBalancerName = fmt.Sprintf("etcd-%s", picker.RoundrobinBalanced.String())
 )
 
 func init() {
@@ -61,11 +58,14 @@ func init() {
 	}
 
 	// TODO: support custom balancer
-	balancer.RegisterBuilder(balancer.Config{
-		Policy: picker.RoundrobinBalanced,
-		Name:   roundRobinBalancerName,
-		Logger: lg,
-	})
+	for p, pp := range balancerPolicies {
+		name, _ := balancerName(p)
+		balancer.RegisterBuilder(balancer.Config{
+			Policy: pp,
+			Name:   name,
+			Logger: lg,
+		})
+	}
 }
 
 // Client provides and manages an etcd v3 client session.
@@ -91,6 +91,7 @@ type Client struct {
 	Username string
 	// Password is a password for authentication.
 	Password        string
+	credsProvider   CredentialsProvider
 	authTokenBundle credentials.Bundle
 
 	callOpts []grpc.CallOption
@@ -100,6 +101,13 @@ type Client struct {
 
 // New creates a new etcdv3 client from a given configuration.
 func New(cfg Config) (*Client, error) {
+	if len(cfg.Endpoints) == 0 && cfg.EndpointDiscovery != nil && cfg.EndpointDiscovery.SRVDomain != "" {
+		eps, err := cfg.EndpointDiscovery.lookupSRV()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Endpoints = eps
+	}
 	if len(cfg.Endpoints) == 0 {
 		return nil, ErrNoAvailableEndpoints
 	}
@@ -142,6 +150,7 @@ func (c *Client) Close() error {
 	}
 	if c.resolverGroup != nil {
 		c.resolverGroup.Close()
+		balancer.UnregisterTarget(c.resolverGroup.ID())
 	}
 	if c.conn != nil {
 		return toErr(c.ctx, c.conn.Close())
@@ -187,7 +196,14 @@ func (c *Client) Sync(ctx context.Context) error {
 }
 
 func (c *Client) autoSync() {
-	if c.cfg.AutoSyncInterval == time.Duration(0) {
+	interval, sync := c.cfg.AutoSyncInterval, c.Sync
+	if d := c.cfg.EndpointDiscovery; d != nil && d.RefreshInterval > 0 {
+		interval = d.RefreshInterval
+		if d.SRVDomain != "" {
+			sync = c.SyncSRV
+		}
+	}
+	if interval == time.Duration(0) {
 		return
 	}
 
@@ -195,9 +211,9 @@ func (c *Client) autoSync() {
 		select {
 		case <-c.ctx.Done():
 			return
-		case <-time.After(c.cfg.AutoSyncInterval):
+		case <-time.After(interval):
 			ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
-			err := c.Sync(ctx)
+			err := sync(ctx)
 			cancel()
 			if err != nil && err != c.ctx.Err() {
 				lg.Lvl(4).Infof("Auto sync endpoints failed: %v", err)
@@ -247,12 +263,37 @@ func (c *Client) dialSetupOpts(creds grpccredentials.TransportCredentials, dopts
 	// TODO: Replace all of clientv3/retry.go with interceptor based retry, or with
 	// https://github.com/grpc/proposal/blob/master/A6-client-retries.md#retry-policy
 	// once it is available.
-	rrBackoff := withBackoff(c.roundRobinQuorumBackoff(defaultBackoffWaitBetween, defaultBackoffJitterFraction))
+	rp := c.cfg.RetryPolicy
+	if rp == nil {
+		rp = DefaultRetryPolicy()
+	}
+	backoff := withBackoff(c.retryBackoff(rp))
+	// Disable stream retry by default since go-grpc-middleware/retry does not support client streams.
+	// Streams that are safe to retry are enabled individually.
+	streamInt := c.streamClientInterceptor(c.lg, withMax(0), backoff)
+	unaryInt := c.unaryClientInterceptor(c.lg,
+		withMax(retryAttempts(rp.MaxImmutableRetries)),
+		withMutableMax(retryAttempts(rp.MaxMutableRetries)),
+		backoff,
+		withBudget(newRetryBudget(rp.Budget)),
+	)
+	// Telemetry wraps the retries, to trace each attempt of a request.
+	if t := newTelemetry(c.cfg.TracerProvider, c.cfg.MeterProvider); t != nil {
+		streamInt = t.streamClientInterceptor(streamInt)
+		unaryInt = t.unaryClientInterceptor(unaryInt)
+	}
+	if c.cfg.PrefixMetrics != nil {
+		unaryInt = c.cfg.PrefixMetrics.unaryClientInterceptor(unaryInt)
+	}
+	// The limits wrap everything else, so that requests waiting for them
+	// are neither sent nor measured as in progress.
+	if l := newLimiter(c.cfg.RateLimiter, c.cfg.MaxInFlightRequests, c.cfg.MeterProvider); l != nil {
+		streamInt = l.streamClientInterceptor(streamInt)
+		unaryInt = l.unaryClientInterceptor(unaryInt)
+	}
 	opts = append(opts,
-		// Disable stream retry by default since go-grpc-middleware/retry does not support client streams.
-		// Streams that are safe to retry are enabled individually.
-		grpc.WithStreamInterceptor(c.streamClientInterceptor(c.lg, withMax(0), rrBackoff)),
-		grpc.WithUnaryInterceptor(c.unaryClientInterceptor(c.lg, withMax(defaultUnaryMaxRetries), rrBackoff)),
+		grpc.WithStreamInterceptor(streamInt),
+		grpc.WithUnaryInterceptor(unaryInt),
 	)
 
 	return opts, nil
@@ -271,14 +312,33 @@ func (c *Client) Dial(ep string) (*grpc.ClientConn, error) {
 	return c.dial(fmt.Sprintf("passthrough:///%s", ep), creds)
 }
 
+// authEnabled returns true if the client has credentials to authenticate
+// with.
+func (c *Client) authEnabled() bool {
+	return c.credsProvider != nil || (c.Username != "" && c.Password != "")
+}
+
 func (c *Client) getToken(ctx context.Context) error {
 	var err error // return last error in a case of fail
 
-	if c.Username == "" || c.Password == "" {
+	if !c.authEnabled() {
 		return nil
 	}
 
-	resp, err := c.Auth.Authenticate(ctx, c.Username, c.Password)
+	username, password := c.Username, c.Password
+	if c.credsProvider != nil {
+		creds, err := c.credsProvider.Credentials(ctx)
+		if err != nil {
+			return err
+		}
+		if creds.Token != "" {
+			c.authTokenBundle.UpdateAuthToken(creds.Token)
+			return nil
+		}
+		username, password = creds.Username, creds.Password
+	}
+
+	resp, err := c.Auth.Authenticate(ctx, username, password)
 	if err != nil {
 		if err == rpctypes.ErrAuthNotEnabled {
 			return nil
@@ -305,7 +365,7 @@ func (c *Client) dial(target string, creds grpccredentials.TransportCredentials,
 		return nil, fmt.Errorf("failed to configure dialer: %v", err)
 	}
 
-	if c.Username != "" && c.Password != "" {
+	if c.authEnabled() {
 		c.authTokenBundle = credentials.NewBundle(credentials.Config{})
 		opts = append(opts, grpc.WithPerRPCCredentials(c.authTokenBundle.PerRPCCredentials()))
 	}
@@ -398,6 +458,7 @@ func newClient(cfg *Config) (*Client, error) {
 		client.Username = cfg.Username
 		client.Password = cfg.Password
 	}
+	client.credsProvider = cfg.CredentialsProvider
 	if cfg.MaxCallSendMsgSize > 0 || cfg.MaxCallRecvMsgSize > 0 {
 		if cfg.MaxCallRecvMsgSize > 0 && cfg.MaxCallSendMsgSize > cfg.MaxCallRecvMsgSize {
 			return nil, fmt.Errorf("gRPC message recv limit (%d bytes) must be greater than send limit (%d bytes)", cfg.MaxCallRecvMsgSize, cfg.MaxCallSendMsgSize)
@@ -431,12 +492,26 @@ func newClient(cfg *Config) (*Client, error) {
 	}
 	dialEndpoint := cfg.Endpoints[0]
 
+	bname, err := balancerName(cfg.BalancerPolicy)
+	if err != nil {
+		client.cancel()
+		client.resolverGroup.Close()
+		return nil, err
+	}
+	balancer.RegisterTarget(client.resolverGroup.ID(), balancer.TargetConfig{
+		HealthPolicy:     cfg.EndpointHealthPolicy,
+		Zone:             cfg.BalancerZone,
+		EndpointZones:    cfg.BalancerEndpointZones,
+		ConnsPerEndpoint: cfg.ConnectionsPerEndpoint,
+	})
+
 	// Use a provided endpoint target so that for https:// without any tls config given, then
 	// grpc will assume the certificate server name is the endpoint host.
-	conn, err := client.dialWithBalancer(dialEndpoint, grpc.WithBalancerName(roundRobinBalancerName))
+	conn, err := client.dialWithBalancer(dialEndpoint, grpc.WithBalancerName(bname))
 	if err != nil {
 		client.cancel()
 		client.resolverGroup.Close()
+		balancer.UnregisterTarget(client.resolverGroup.ID())
 		return nil, err
 	}
 	// TODO: With the old grpc balancer interface, we waited until the dial timeout
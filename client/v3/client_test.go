@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/v3/credentials"
 	"go.etcd.io/etcd/pkg/v3/testutil"
 
 	"google.golang.org/grpc"
@@ -179,3 +180,45 @@ func TestWithLogger(t *testing.T) {
 		t.Errorf("WithLogger should modify *zap.Logger")
 	}
 }
+
+// tokenAuth issues a token named after the user authenticating.
+type tokenAuth struct {
+	Auth
+}
+
+func (a tokenAuth) Authenticate(ctx context.Context, name string, password string) (*AuthenticateResponse, error) {
+	return &AuthenticateResponse{Token: name + "-token"}, nil
+}
+
+func TestGetTokenCredentialsProvider(t *testing.T) {
+	creds := Credentials{Username: "alice", Password: "secret"}
+	c := NewCtxClient(context.Background())
+	c.Auth = tokenAuth{}
+	c.credsProvider = CredentialsProviderFunc(func(context.Context) (Credentials, error) { return creds, nil })
+	c.authTokenBundle = credentials.NewBundle(credentials.Config{})
+
+	token := func() string {
+		md, err := c.authTokenBundle.PerRPCCredentials().GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return md[rpctypes.TokenFieldNameGRPC]
+	}
+	// rotated credentials are picked up on the next authentication
+	for _, tt := range []struct {
+		creds Credentials
+		token string
+	}{
+		{creds, "alice-token"},
+		{Credentials{Username: "bob", Password: "secret"}, "bob-token"},
+		{Credentials{Token: "jwt"}, "jwt"},
+	} {
+		creds = tt.creds
+		if err := c.getToken(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if tok := token(); tok != tt.token {
+			t.Errorf("token = %q, want %q", tok, tt.token)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+var defaultPromotionPollInterval = time.Second
+
+// ErrPromotionTimeout is returned by AddAndPromote when the learner did not
+// catch up with the leader before the timeout of the promotion policy.
+var ErrPromotionTimeout = errors.New("clientv3: learner did not catch up before the promotion timeout")
+
+// errLearnerNotStarted is the poll error of a learner which did not
+// publish its client URLs yet.
+var errLearnerNotStarted = errors.New("clientv3: learner not started")
+
+// PromotionPolicy configures when AddAndPromote promotes the learner.
+type PromotionPolicy struct {
+	// MaxLag is the maximum number of raft entries the applied index of
+	// the learner may lag behind the committed index of the leader for the
+	// learner to be promoted.
+	MaxLag uint64
+	// Timeout bounds the wait for the learner to catch up. If 0, it waits
+	// until the context is done.
+	Timeout time.Duration
+	// PollInterval is the interval the progress of the learner is polled
+	// at. It defaults to a second.
+	PollInterval time.Duration
+}
+
+// PromotionResult is the outcome of AddAndPromote.
+type PromotionResult struct {
+	// Member is the added member, a voting member if promoted.
+	Member *Member
+	// Promoted is true if the learner was promoted.
+	Promoted bool
+	// Lag is the last lag of the learner observed, in raft entries.
+	Lag uint64
+	// Polls is the number of times the progress of the learner was polled.
+	Polls int
+	// LastPollErr is the error of the last poll, if it failed, such as
+	// errors reaching a learner still starting.
+	LastPollErr error
+	// Took is the time from adding the learner to the outcome.
+	Took time.Duration
+}
+
+// AddAndPromote adds a learner with peerURLs, polls its progress with the
+// status of its endpoint and of the leader's, and promotes it once it lags
+// by at most policy.MaxLag entries. The result is returned with the error,
+// if any; a learner which is not promoted is left in the cluster, for the
+// caller to remove or promote later.
+func AddAndPromote(ctx context.Context, cluster Cluster, maint Maintenance, peerURLs []string, policy PromotionPolicy) (*PromotionResult, error) {
+	start := time.Now()
+	addResp, err := cluster.MemberAddAsLearner(ctx, peerURLs)
+	if err != nil {
+		return nil, err
+	}
+	res := &PromotionResult{Member: (*Member)(addResp.Member)}
+	defer func() { res.Took = time.Since(start) }()
+
+	wctx, cancel := ctx, context.CancelFunc(func() {})
+	if policy.Timeout > 0 {
+		wctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+	}
+	defer cancel()
+	interval := policy.PollInterval
+	if interval <= 0 {
+		interval = defaultPromotionPollInterval
+	}
+
+	for {
+		select {
+		case <-time.After(interval):
+		case <-wctx.Done():
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			return res, ErrPromotionTimeout
+		}
+		res.Polls++
+		lag, err := learnerLag(wctx, cluster, maint, res.Member.ID)
+		res.LastPollErr = err
+		if err != nil {
+			continue
+		}
+		res.Lag = lag
+		if lag > policy.MaxLag {
+			continue
+		}
+		switch _, err = cluster.MemberPromote(wctx, res.Member.ID); err {
+		case nil:
+			res.Promoted = true
+			res.Member.IsLearner = false
+			return res, nil
+		case rpctypes.ErrMemberLearnerNotReady:
+			// the leader does not consider the learner in sync yet
+			res.LastPollErr = err
+		default:
+			return res, err
+		}
+	}
+}
+
+// learnerLag returns the number of entries the applied index of the
+// learner lags behind the committed index of the leader.
+func learnerLag(ctx context.Context, cluster Cluster, maint Maintenance, id uint64) (uint64, error) {
+	mresp, err := cluster.MemberList(ctx)
+	if err != nil {
+		return 0, err
+	}
+	members := make(map[uint64]*Member, len(mresp.Members))
+	for _, m := range mresp.Members {
+		members[m.ID] = (*Member)(m)
+	}
+	learner := members[id]
+	if learner == nil {
+		return 0, fmt.Errorf("clientv3: learner %x not found in the cluster", id)
+	}
+	if len(learner.ClientURLs) == 0 {
+		return 0, errLearnerNotStarted
+	}
+	lresp, err := maint.Status(ctx, learner.ClientURLs[0])
+	if err != nil {
+		return 0, err
+	}
+	leader := members[lresp.Leader]
+	if leader == nil || len(leader.ClientURLs) == 0 {
+		return 0, fmt.Errorf("clientv3: leader %x of learner %x not found", lresp.Leader, id)
+	}
+	sresp, err := maint.Status(ctx, leader.ClientURLs[0])
+	if err != nil {
+		return 0, err
+	}
+	if sresp.RaftIndex <= lresp.RaftAppliedIndex {
+		return 0, nil
+	}
+	return sresp.RaftIndex - lresp.RaftAppliedIndex, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// learnerCluster adds a learner which starts at the second poll, and
+// catches up with the leader by applying 50 entries per poll, unless
+// stalled.
+type learnerCluster struct {
+	Cluster
+	Maintenance
+	stalled  bool
+	polls    int
+	promotes int
+}
+
+func (c *learnerCluster) MemberAddAsLearner(ctx context.Context, peerAddrs []string) (*MemberAddResponse, error) {
+	return &MemberAddResponse{Member: &pb.Member{ID: 2, PeerURLs: peerAddrs, IsLearner: true}}, nil
+}
+
+func (c *learnerCluster) MemberList(ctx context.Context) (*MemberListResponse, error) {
+	c.polls++
+	learner := &pb.Member{ID: 2, IsLearner: true}
+	if c.polls > 1 {
+		learner.ClientURLs = []string{"learner"}
+	}
+	return &MemberListResponse{Members: []*pb.Member{{ID: 1, ClientURLs: []string{"leader"}}, learner}}, nil
+}
+
+func (c *learnerCluster) Status(ctx context.Context, endpoint string) (*StatusResponse, error) {
+	if endpoint == "leader" {
+		return &StatusResponse{Leader: 1, RaftIndex: 200}, nil
+	}
+	if c.stalled {
+		return &StatusResponse{Leader: 1, IsLearner: true}, nil
+	}
+	return &StatusResponse{Leader: 1, RaftAppliedIndex: uint64(50 * c.polls), IsLearner: true}, nil
+}
+
+func (c *learnerCluster) MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error) {
+	c.promotes++
+	if c.promotes == 1 {
+		return nil, rpctypes.ErrMemberLearnerNotReady
+	}
+	return &MemberPromoteResponse{}, nil
+}
+
+func TestAddAndPromote(t *testing.T) {
+	c := &learnerCluster{}
+	res, err := AddAndPromote(context.TODO(), c, c, []string{"http://127.0.0.1:2380"}, PromotionPolicy{MaxLag: 60, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// polled: not started, lag 100, lag 50 but not ready, lag 0
+	if !res.Promoted || res.Member.IsLearner || res.Polls != 4 || res.Lag != 0 {
+		t.Fatalf("result = %+v, want promoted after 4 polls", res)
+	}
+
+	c = &learnerCluster{stalled: true}
+	res, err = AddAndPromote(context.TODO(), c, c, []string{"http://127.0.0.1:2380"}, PromotionPolicy{MaxLag: 60, PollInterval: time.Millisecond, Timeout: 50 * time.Millisecond})
+	if err != ErrPromotionTimeout {
+		t.Fatalf("err = %v, want %v", err, ErrPromotionTimeout)
+	}
+	if res.Promoted || !res.Member.IsLearner || res.Lag != 200 {
+		t.Fatalf("result = %+v, want a learner lagging by 200 entries", res)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// CompactHandler is called with the revision a read is pinned to once it
+// is compacted, and returns the revision to pin the read to instead, 0 for
+// the current revision, or an error to fail the read with. It may re-list
+// the state the application built from the reads at the compacted
+// revision.
+type CompactHandler func(ctx context.Context, rev int64) (int64, error)
+
+// RepinOnCompact is a CompactHandler pinning the reads to the current
+// revision, which is at or after the compaction revision.
+func RepinOnCompact(ctx context.Context, rev int64) (int64, error) { return 0, nil }
+
+// GetWithRetryOnCompact retrieves keys as kv.Get does. If the Get is pinned
+// to a revision with WithRev, and that revision is compacted, it is retried
+// at the revision returned by the CompactHandler given with WithOnCompact,
+// or at the current revision without one, rather than fail with
+// ErrCompacted. The revision of the response tells the revision the keys
+// were read at.
+func GetWithRetryOnCompact(ctx context.Context, kv KV, key string, opts ...OpOption) (*GetResponse, error) {
+	op := OpGet(key, opts...)
+	for {
+		resp, err := kv.Do(ctx, op)
+		if err != rpctypes.ErrCompacted || op.rev == 0 {
+			return resp.Get(), err
+		}
+		if op.rev, err = handleCompact(ctx, op); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// handleCompact returns the revision to retry the pinned read op at, once
+// its revision is compacted.
+func handleCompact(ctx context.Context, op Op) (int64, error) {
+	if op.onCompact == nil {
+		return 0, nil
+	}
+	return op.onCompact(ctx, op.rev)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+func TestGetWithRetryOnCompact(t *testing.T) {
+	kv := &rangeKV{keys: []string{"a/1", "a/2"}, rev: 7, compacted: 5}
+	if _, err := GetWithRetryOnCompact(context.TODO(), kv, "a/", WithPrefix(), WithRev(3)); err != nil {
+		t.Fatal(err)
+	}
+	// without a handler, the get is retried at the current revision
+	var revs []int64
+	for _, r := range kv.reqs {
+		revs = append(revs, r.Revision)
+	}
+	if !reflect.DeepEqual(revs, []int64{3, 0}) {
+		t.Fatalf("requests at revisions %v, want 3 then 0", revs)
+	}
+
+	var compacted []int64
+	h := func(ctx context.Context, rev int64) (int64, error) {
+		compacted = append(compacted, rev)
+		if rev == 4 {
+			return 0, errors.New("stop")
+		}
+		return rev + 1, nil
+	}
+	kv.reqs = nil
+	if _, err := GetWithRetryOnCompact(context.TODO(), kv, "a/", WithPrefix(), WithRev(3), WithOnCompact(h)); err == nil || err.Error() != "stop" {
+		t.Fatalf("err = %v, want the error of the handler", err)
+	}
+	if !reflect.DeepEqual(compacted, []int64{3, 4}) {
+		t.Fatalf("handler called with %v, want 3 and 4", compacted)
+	}
+}
+
+func TestPagedGetOnCompact(t *testing.T) {
+	kv := &rangeKV{keys: []string{"a/1", "a/2", "a/3"}, rev: 7}
+	pg := NewPagedGet(kv, "a/", 1, WithPrefix(), WithRev(3), WithOnCompact(RepinOnCompact))
+	var keys []string
+	for pg.Next(context.TODO()) {
+		keys = append(keys, string(pg.Page().Kvs[0].Key))
+		// the revision is compacted after the first page
+		kv.compacted = 5
+	}
+	if err := pg.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"a/1", "a/2", "a/3"}) || pg.Rev() != 7 {
+		t.Fatalf("keys = %v at revision %d, want all keys at revision 7", keys, pg.Rev())
+	}
+
+	kv.reqs = nil
+	pg = NewPagedGet(kv, "a/", 1, WithPrefix(), WithRev(3))
+	if pg.Next(context.TODO()) || pg.Err() != rpctypes.ErrCompacted {
+		t.Fatalf("err = %v, want %v", pg.Err(), rpctypes.ErrCompacted)
+	}
+}
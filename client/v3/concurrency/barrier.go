@@ -0,0 +1,76 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// ErrBarrierHeld is returned by Hold when the barrier is already held.
+	ErrBarrierHeld = errors.New("barrier: already held")
+	// ErrTooManyParticipants is returned when more participants than
+	// expected enter a DoubleBarrier or arrive at a Rendezvous.
+	ErrTooManyParticipants = errors.New("barrier: too many participants")
+)
+
+// Barrier is a single-use barrier: Hold creates a key in etcd blocking the
+// processes calling Wait, and Release deletes the key to unblock them all.
+type Barrier struct {
+	client *v3.Client
+	key    string
+}
+
+// NewBarrier creates a Barrier on key.
+func NewBarrier(client *v3.Client, key string) *Barrier {
+	return &Barrier{client: client, key: key}
+}
+
+// Hold creates the barrier key, causing processes to block on Wait. It
+// returns ErrBarrierHeld if the key exists already.
+func (b *Barrier) Hold(ctx context.Context) error {
+	cmp := v3.Compare(v3.CreateRevision(b.key), "=", 0)
+	resp, err := b.client.Txn(ctx).If(cmp).Then(v3.OpPut(b.key, "")).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrBarrierHeld
+	}
+	return nil
+}
+
+// Release deletes the barrier key to unblock all waiting processes.
+func (b *Barrier) Release(ctx context.Context) error {
+	_, err := b.client.Delete(ctx, b.key)
+	return err
+}
+
+// Wait blocks until the barrier key is deleted. If there is no key, Wait
+// assumes Release has already been called and returns immediately.
+func (b *Barrier) Wait(ctx context.Context) error {
+	resp, err := b.client.Get(ctx, b.key)
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 {
+		// key already removed
+		return nil
+	}
+	return waitDelete(ctx, b.client, b.key, resp.Header.Revision+1)
+}
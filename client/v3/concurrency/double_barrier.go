@@ -0,0 +1,127 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// DoubleBarrier blocks processes on Enter until an expected count enters,
+// then blocks again on Leave until all processes have left.
+//
+// Each process enters with a key under key/waiters/ owned by the lease of
+// its session, so the barrier resumes if a process fails and its session
+// lease expires.
+type DoubleBarrier struct {
+	s *Session
+
+	key   string // key for the collective barrier
+	count int
+	myKey string // key of this session on the barrier
+	myRev int64
+}
+
+// NewDoubleBarrier creates a DoubleBarrier on key for count processes, each
+// with its own session.
+func NewDoubleBarrier(s *Session, key string, count int) *DoubleBarrier {
+	return &DoubleBarrier{s: s, key: key, count: count}
+}
+
+func (b *DoubleBarrier) waitersPfx() string { return b.key + "/waiters/" }
+func (b *DoubleBarrier) readyKey() string   { return b.key + "/ready" }
+
+// Enter waits for count processes to enter the barrier, then returns. It
+// returns ErrTooManyParticipants if more than count processes entered.
+func (b *DoubleBarrier) Enter(ctx context.Context) error {
+	client := b.s.Client()
+	b.myKey = fmt.Sprintf("%s%x", b.waitersPfx(), b.s.Lease())
+	cmp := v3.Compare(v3.CreateRevision(b.myKey), "=", 0)
+	put := v3.OpPut(b.myKey, "", v3.WithLease(b.s.Lease()))
+	getWaiters := v3.OpGet(b.waitersPfx(), v3.WithPrefix(), v3.WithCountOnly())
+	resp, err := client.Txn(ctx).If(cmp).Then(put, getWaiters).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("barrier: session already entered %q", b.key)
+	}
+	b.myRev = resp.Header.Revision
+
+	switch n := resp.Responses[1].GetResponseRange().Count; {
+	case n > int64(b.count):
+		if _, err := client.Delete(ctx, b.myKey); err != nil {
+			return err
+		}
+		return ErrTooManyParticipants
+	case n == int64(b.count):
+		// unblock waiters
+		_, err = client.Put(ctx, b.readyKey(), "")
+		return err
+	}
+	return waitEvent(ctx, client, b.readyKey(), b.myRev, mvccpb.PUT)
+}
+
+// Leave waits for all processes to leave the barrier, then returns.
+func (b *DoubleBarrier) Leave(ctx context.Context) error {
+	client := b.s.Client()
+	for {
+		resp, err := client.Get(ctx, b.waitersPfx(), v3.WithPrefix())
+		if err != nil {
+			return err
+		}
+		if len(resp.Kvs) == 0 {
+			return nil
+		}
+
+		lowest, highest := resp.Kvs[0], resp.Kvs[0]
+		for _, k := range resp.Kvs {
+			if k.ModRevision < lowest.ModRevision {
+				lowest = k
+			}
+			if k.ModRevision > highest.ModRevision {
+				highest = k
+			}
+		}
+
+		if len(resp.Kvs) == 1 {
+			// this is the only process in the barrier; finish up
+			if _, err = client.Delete(ctx, b.readyKey()); err != nil {
+				return err
+			}
+			_, err = client.Delete(ctx, b.myKey)
+			return err
+		}
+
+		// if a process fails, its session lease is revoked and its barrier
+		// key is removed, so the barrier resumes
+
+		// lowest process waits on highest process; others delete themselves
+		// and wait on lowest process
+		wait := highest
+		if string(lowest.Key) != b.myKey {
+			if _, err = client.Delete(ctx, b.myKey); err != nil {
+				return err
+			}
+			wait = lowest
+		}
+		if err = waitDelete(ctx, client, string(wait.Key), wait.ModRevision); err != nil {
+			return err
+		}
+	}
+}
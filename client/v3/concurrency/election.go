@@ -102,6 +102,7 @@ func (e *Election) Campaign(ctx context.Context, val string) error {
 		return err
 	}
 	e.hdr = resp.Header
+	s.track(e)
 
 	return nil
 }
@@ -141,9 +142,12 @@ func (e *Election) Resign(ctx context.Context) (err error) {
 	}
 	e.leaderKey = ""
 	e.leaderSession = nil
+	e.session.untrack(e)
 	return err
 }
 
+func (e *Election) resignSession(ctx context.Context) error { return e.Resign(ctx) }
+
 // Leader returns the leader value for the current election.
 func (e *Election) Leader(ctx context.Context) (*v3.GetResponse, error) {
 	client := e.session.Client()
@@ -17,21 +17,27 @@ package concurrency
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	v3 "go.etcd.io/etcd/client/v3"
 )
 
-func waitDelete(ctx context.Context, client *v3.Client, key string, rev int64) error {
+func waitDelete(ctx context.Context, client *v3.Client, key string, rev int64, opts ...v3.OpOption) error {
+	return waitEvent(ctx, client, key, rev, mvccpb.DELETE, opts...)
+}
+
+// waitEvent waits for an event of the given type on key at or after rev.
+func waitEvent(ctx context.Context, client *v3.Client, key string, rev int64, typ mvccpb.Event_EventType, opts ...v3.OpOption) error {
 	cctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	var wr v3.WatchResponse
-	wch := client.Watch(cctx, key, v3.WithRev(rev))
+	wch := client.Watch(cctx, key, append(opts, v3.WithRev(rev))...)
 	for wr = range wch {
 		for _, ev := range wr.Events {
-			if ev.Type == mvccpb.DELETE {
+			if ev.Type == typ {
 				return nil
 			}
 		}
@@ -42,7 +48,7 @@ func waitDelete(ctx context.Context, client *v3.Client, key string, rev int64) e
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return fmt.Errorf("lost watcher waiting for delete")
+	return fmt.Errorf("lost watcher waiting for %s", strings.ToLower(typ.String()))
 }
 
 // waitDeletes efficiently waits until all keys matching the prefix and no greater
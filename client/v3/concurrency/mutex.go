@@ -54,6 +54,7 @@ func (m *Mutex) TryLock(ctx context.Context) error {
 	ownerKey := resp.Responses[1].GetResponseRange().Kvs
 	if len(ownerKey) == 0 || ownerKey[0].CreateRevision == m.myRev {
 		m.hdr = resp.Header
+		m.s.track(m)
 		return nil
 	}
 	client := m.s.Client()
@@ -77,6 +78,7 @@ func (m *Mutex) Lock(ctx context.Context) error {
 	ownerKey := resp.Responses[1].GetResponseRange().Kvs
 	if len(ownerKey) == 0 || ownerKey[0].CreateRevision == m.myRev {
 		m.hdr = resp.Header
+		m.s.track(m)
 		return nil
 	}
 	client := m.s.Client()
@@ -100,6 +102,7 @@ func (m *Mutex) Lock(ctx context.Context) error {
 		return ErrSessionExpired
 	}
 	m.hdr = gresp.Header
+	m.s.track(m)
 
 	return nil
 }
@@ -134,9 +137,12 @@ func (m *Mutex) Unlock(ctx context.Context) error {
 	}
 	m.myKey = "\x00"
 	m.myRev = -1
+	m.s.untrack(m)
 	return nil
 }
 
+func (m *Mutex) resignSession(ctx context.Context) error { return m.Unlock(ctx) }
+
 func (m *Mutex) IsOwner() v3.Cmp {
 	return v3.Compare(v3.CreateRevision(m.myKey), "=", m.myRev)
 }
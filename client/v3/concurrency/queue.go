@@ -0,0 +1,232 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrTaskLost is returned by Ack and Requeue when the session no longer
+// holds the claim of the task, because its lease expired. The task is then
+// delivered again, if it was not acked already.
+var ErrTaskLost = errors.New("queue: task claim lost")
+
+// Queue is a durable work queue. Tasks are stored under pfx/tasks/ without a
+// lease, so they outlive the processes enqueuing them. A worker claims a
+// task with a key under pfx/claims/ owned by the lease of its session, and
+// acks it once done, deleting both. If the worker fails, its session lease
+// expires, the claim key is deleted, and the task is delivered to another
+// worker, so every task is processed at least once.
+//
+// Tasks are delivered by ascending priority, and in the order they were
+// enqueued within a priority.
+type Queue struct {
+	s *Session
+
+	pfx string
+}
+
+// NewQueue creates a Queue on the keys under pfx, whose workers claim tasks
+// with the lease of s.
+func NewQueue(s *Session, pfx string) *Queue {
+	return &Queue{s: s, pfx: pfx + "/"}
+}
+
+func (q *Queue) tasksPfx() string  { return q.pfx + "tasks/" }
+func (q *Queue) claimsPfx() string { return q.pfx + "claims/" }
+
+// Task is a task claimed from a Queue.
+type Task struct {
+	// Key is the key of the task, unique within the queue.
+	Key string
+	// Value is the value the task was enqueued with.
+	Value    string
+	Priority uint16
+	// CreateRevision is the revision the task was enqueued at.
+	CreateRevision int64
+
+	claimKey string
+}
+
+type enqueueOptions struct {
+	priority uint16
+}
+
+// EnqueueOption configures Enqueue.
+type EnqueueOption func(*enqueueOptions)
+
+// WithPriority enqueues a task with the given priority. Tasks with a lower
+// priority value are delivered first; the default priority is 0.
+func WithPriority(pr uint16) EnqueueOption {
+	return func(o *enqueueOptions) { o.priority = pr }
+}
+
+// Enqueue adds a task with val to the queue, and returns its key.
+func (q *Queue) Enqueue(ctx context.Context, val string, opts ...EnqueueOption) (string, error) {
+	var o enqueueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	client := q.s.Client()
+	for {
+		key := fmt.Sprintf("%s%05d/%016x", q.tasksPfx(), o.priority, time.Now().UnixNano())
+		cmp := v3.Compare(v3.CreateRevision(key), "=", 0)
+		resp, err := client.Txn(ctx).If(cmp).Then(v3.OpPut(key, val)).Commit()
+		if err != nil {
+			return "", err
+		}
+		if resp.Succeeded {
+			return key, nil
+		}
+	}
+}
+
+// Claim claims the first unclaimed task, waiting for one if there is none.
+// The task is claimed until it is acked or requeued, or the session expires.
+func (q *Queue) Claim(ctx context.Context) (*Task, error) {
+	for {
+		t, rev, err := q.tryClaim(ctx)
+		if err != nil || t != nil {
+			return t, err
+		}
+		if err := q.waitChange(ctx, rev); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// TryClaim claims the first unclaimed task, and returns nil if there is none.
+func (q *Queue) TryClaim(ctx context.Context) (*Task, error) {
+	t, _, err := q.tryClaim(ctx)
+	return t, err
+}
+
+// tryClaim claims the first unclaimed task. If there is none, it returns the
+// revision the queue was read at.
+func (q *Queue) tryClaim(ctx context.Context) (*Task, int64, error) {
+	client := q.s.Client()
+	for {
+		// fetch the tasks and claims in a single revision
+		getTasks := v3.OpGet(q.tasksPfx(), v3.WithPrefix())
+		getClaims := v3.OpGet(q.claimsPfx(), v3.WithPrefix(), v3.WithKeysOnly())
+		resp, err := client.Txn(ctx).Then(getTasks, getClaims).Commit()
+		if err != nil {
+			return nil, 0, err
+		}
+		claimed := make(map[string]bool)
+		for _, kv := range resp.Responses[1].GetResponseRange().Kvs {
+			claimed[strings.TrimPrefix(string(kv.Key), q.claimsPfx())] = true
+		}
+		var tasks []*Task
+		for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+			if t := q.task(kv); t != nil && !claimed[strings.TrimPrefix(t.Key, q.tasksPfx())] {
+				tasks = append(tasks, t)
+			}
+		}
+		if len(tasks) == 0 {
+			return nil, resp.Header.Revision, nil
+		}
+		sort.Slice(tasks, func(i, j int) bool {
+			if tasks[i].Priority != tasks[j].Priority {
+				return tasks[i].Priority < tasks[j].Priority
+			}
+			return tasks[i].CreateRevision < tasks[j].CreateRevision
+		})
+
+		for _, t := range tasks {
+			cmps := []v3.Cmp{
+				v3.Compare(v3.CreateRevision(t.claimKey), "=", 0),
+				// the task may have been acked since it was read
+				v3.Compare(v3.CreateRevision(t.Key), "=", t.CreateRevision),
+			}
+			put := v3.OpPut(t.claimKey, "", v3.WithLease(q.s.Lease()))
+			cresp, err := client.Txn(ctx).If(cmps...).Then(put).Commit()
+			if err != nil {
+				return nil, 0, err
+			}
+			if cresp.Succeeded {
+				return t, 0, nil
+			}
+		}
+		// all the tasks were claimed by other workers; read the queue again
+	}
+}
+
+// task decodes the task stored in kv, or returns nil for a malformed key.
+func (q *Queue) task(kv *mvccpb.KeyValue) *Task {
+	name := strings.TrimPrefix(string(kv.Key), q.tasksPfx())
+	var pr uint16
+	if _, err := fmt.Sscanf(name, "%05d/", &pr); err != nil {
+		return nil
+	}
+	return &Task{
+		Key:            string(kv.Key),
+		Value:          string(kv.Value),
+		Priority:       pr,
+		CreateRevision: kv.CreateRevision,
+		claimKey:       q.claimsPfx() + name,
+	}
+}
+
+// waitChange waits for a change to the queue after rev.
+func (q *Queue) waitChange(ctx context.Context, rev int64) error {
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wr v3.WatchResponse
+	wch := q.s.Client().Watch(cctx, q.pfx, v3.WithPrefix(), v3.WithRev(rev+1))
+	for wr = range wch {
+		if len(wr.Events) > 0 {
+			return nil
+		}
+	}
+	if err := wr.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("lost watcher waiting for queue change")
+}
+
+// Ack removes a task claimed by the session from the queue, once processed.
+func (q *Queue) Ack(ctx context.Context, t *Task) error {
+	return q.release(ctx, t, v3.OpDelete(t.Key), v3.OpDelete(t.claimKey))
+}
+
+// Requeue releases the claim of a task, so it is delivered again.
+func (q *Queue) Requeue(ctx context.Context, t *Task) error {
+	return q.release(ctx, t, v3.OpDelete(t.claimKey))
+}
+
+func (q *Queue) release(ctx context.Context, t *Task, ops ...v3.Op) error {
+	cmp := v3.Compare(v3.LeaseValue(t.claimKey), "=", q.s.Lease())
+	resp, err := q.s.Client().Txn(ctx).If(cmp).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrTaskLost
+	}
+	return nil
+}
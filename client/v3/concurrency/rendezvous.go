@@ -0,0 +1,99 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// Rendezvous is a meeting point for a fixed number of processes, each with
+// its own session, to exchange a value: Arrive blocks until all of them
+// arrived, and returns the values they arrived with.
+//
+// Each process arrives with a key under the prefix owned by the lease of its
+// session, so the rendezvous may be met again once all of them departed or
+// their sessions expired.
+type Rendezvous struct {
+	s *Session
+
+	pfx   string
+	count int
+	myKey string
+}
+
+// NewRendezvous creates a Rendezvous on the keys under pfx for count
+// processes.
+func NewRendezvous(s *Session, pfx string, count int) *Rendezvous {
+	return &Rendezvous{s: s, pfx: pfx + "/", count: count}
+}
+
+// Arrive arrives at the rendezvous with val and waits for all processes to
+// arrive. It returns the values of the processes in the order they arrived,
+// or ErrTooManyParticipants if count processes arrived before this one.
+func (r *Rendezvous) Arrive(ctx context.Context, val string) ([]string, error) {
+	client := r.s.Client()
+	r.myKey = fmt.Sprintf("%s%x", r.pfx, r.s.Lease())
+	cmp := v3.Compare(v3.CreateRevision(r.myKey), "=", 0)
+	put := v3.OpPut(r.myKey, val, v3.WithLease(r.s.Lease()))
+	resp, err := client.Txn(ctx).If(cmp).Then(put).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Succeeded {
+		return nil, fmt.Errorf("rendezvous: session already arrived at %q", r.pfx)
+	}
+	myRev := resp.Header.Revision
+
+	opts := []v3.OpOption{
+		v3.WithPrefix(),
+		v3.WithSort(v3.SortByCreateRevision, v3.SortAscend),
+		v3.WithLimit(int64(r.count)),
+	}
+	for {
+		gresp, err := client.Get(ctx, r.pfx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if len(gresp.Kvs) == r.count {
+			if gresp.Kvs[r.count-1].CreateRevision < myRev {
+				// the rendezvous was met without this process
+				if _, err := client.Delete(ctx, r.myKey); err != nil {
+					return nil, err
+				}
+				return nil, ErrTooManyParticipants
+			}
+			vals := make([]string, len(gresp.Kvs))
+			for i, kv := range gresp.Kvs {
+				vals[i] = string(kv.Value)
+			}
+			return vals, nil
+		}
+		if err := waitEvent(ctx, client, r.pfx, gresp.Header.Revision+1, mvccpb.PUT, v3.WithPrefix()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Depart removes the value of this process from the rendezvous. A process
+// departing before the others returned from Arrive holds them back until
+// another process arrives.
+func (r *Rendezvous) Depart(ctx context.Context) error {
+	_, err := r.s.Client().Delete(ctx, r.myKey)
+	return err
+}
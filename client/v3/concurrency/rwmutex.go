@@ -0,0 +1,230 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"fmt"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// RWMutex is a reader/writer mutual exclusion lock with etcd. The lock can
+// be held by any number of readers or by a single writer.
+//
+// Like Mutex, waiters put a key under the lock prefix, owned by the lease of
+// their session, and are served in the order of the keys' create revisions:
+// readers under pfx/read/ and writers under pfx/write/. A reader holds the
+// lock once the writers queued before it are gone, and a writer once all
+// the waiters queued before it are gone. A reader queued after a waiting
+// writer therefore waits for it, so writers are not starved by a steady
+// flow of readers. The same prefix must not be shared with a Mutex.
+type RWMutex struct {
+	s    *Session
+	opts rwMutexOptions
+
+	pfx  string
+	rKey string
+	rRev int64
+	wKey string
+	wRev int64
+	hdr  *pb.ResponseHeader
+}
+
+type rwMutexOptions struct {
+	writerPriority bool
+}
+
+// RWMutexOption configures RWMutex.
+type RWMutexOption func(*rwMutexOptions)
+
+// WithWriterPriority makes readers wait for all writers, including the ones
+// queued after them, until they hold the lock: a reader that finds a writer
+// waiting does not queue until no writer is left. Readers may then starve
+// under a steady flow of writers.
+func WithWriterPriority() RWMutexOption {
+	return func(o *rwMutexOptions) { o.writerPriority = true }
+}
+
+// NewRWMutex creates a RWMutex on the keys under pfx.
+func NewRWMutex(s *Session, pfx string, opts ...RWMutexOption) *RWMutex {
+	rw := &RWMutex{s: s, pfx: pfx + "/", rKey: "\x00", rRev: -1, wKey: "\x00", wRev: -1}
+	for _, opt := range opts {
+		opt(&rw.opts)
+	}
+	return rw
+}
+
+func (rw *RWMutex) readPfx() string  { return rw.pfx + "read/" }
+func (rw *RWMutex) writePfx() string { return rw.pfx + "write/" }
+
+// TryRLock locks rw for reading if it is not locked or waited for by a
+// writer of another session, and returns ErrLocked otherwise.
+func (rw *RWMutex) TryRLock(ctx context.Context) error {
+	key, rev, first, hdr, err := rw.acquireRead(ctx)
+	if err != nil {
+		return err
+	}
+	if rev != 0 && (first == nil || first.CreateRevision > rev) {
+		rw.rKey, rw.rRev, rw.hdr = key, rev, hdr
+		return nil
+	}
+	if rev != 0 {
+		if _, err := rw.s.Client().Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return ErrLocked
+}
+
+// RLock locks rw for reading. If the context is canceled while waiting for
+// the lock, the reader tries to remove its key from the queue.
+func (rw *RWMutex) RLock(ctx context.Context) error {
+	for {
+		key, rev, first, hdr, err := rw.acquireRead(ctx)
+		if err != nil {
+			return err
+		}
+		if rev != 0 {
+			rw.rKey, rw.rRev = key, rev
+			if first == nil || first.CreateRevision > rev {
+				rw.hdr = hdr
+				return nil
+			}
+			break
+		}
+		// writers are waiting with writer priority; queue once they are gone
+		if _, err := waitDeletes(ctx, rw.s.Client(), rw.writePfx(), hdr.Revision); err != nil {
+			return err
+		}
+	}
+	return rw.wait(ctx, rw.rKey, rw.rRev, rw.writePfx(), rw.RUnlock)
+}
+
+func (rw *RWMutex) acquireRead(ctx context.Context) (string, int64, *mvccpb.KeyValue, *pb.ResponseHeader, error) {
+	var guard []v3.Cmp
+	if rw.opts.writerPriority {
+		guard = append(guard, v3.Compare(v3.CreateRevision(rw.writePfx()).WithPrefix(), "=", 0))
+	}
+	key := fmt.Sprintf("%s%x", rw.readPfx(), rw.s.Lease())
+	rev, first, hdr, err := rw.acquire(ctx, key, rw.writePfx(), guard...)
+	return key, rev, first, hdr, err
+}
+
+// RUnlock unlocks rw for reading.
+func (rw *RWMutex) RUnlock(ctx context.Context) error {
+	if _, err := rw.s.Client().Delete(ctx, rw.rKey); err != nil {
+		return err
+	}
+	rw.rKey, rw.rRev = "\x00", -1
+	return nil
+}
+
+// TryLock locks rw for writing if it is not locked or waited for by another
+// session, and returns ErrLocked otherwise.
+func (rw *RWMutex) TryLock(ctx context.Context) error {
+	key := fmt.Sprintf("%s%x", rw.writePfx(), rw.s.Lease())
+	rev, first, hdr, err := rw.acquire(ctx, key, rw.pfx)
+	if err != nil {
+		return err
+	}
+	if first == nil || first.CreateRevision == rev {
+		rw.wKey, rw.wRev, rw.hdr = key, rev, hdr
+		return nil
+	}
+	if _, err := rw.s.Client().Delete(ctx, key); err != nil {
+		return err
+	}
+	return ErrLocked
+}
+
+// Lock locks rw for writing. If the context is canceled while waiting for
+// the lock, the writer tries to remove its key from the queue.
+func (rw *RWMutex) Lock(ctx context.Context) error {
+	key := fmt.Sprintf("%s%x", rw.writePfx(), rw.s.Lease())
+	rev, first, hdr, err := rw.acquire(ctx, key, rw.pfx)
+	if err != nil {
+		return err
+	}
+	rw.wKey, rw.wRev = key, rev
+	if first == nil || first.CreateRevision == rev {
+		rw.hdr = hdr
+		return nil
+	}
+	return rw.wait(ctx, rw.wKey, rw.wRev, rw.pfx, rw.Unlock)
+}
+
+// Unlock unlocks rw for writing.
+func (rw *RWMutex) Unlock(ctx context.Context) error {
+	if _, err := rw.s.Client().Delete(ctx, rw.wKey); err != nil {
+		return err
+	}
+	rw.wKey, rw.wRev = "\x00", -1
+	return nil
+}
+
+// acquire puts key in the lock queue, unless the session queued it already,
+// and fetches the first created key under blockPfx in the same transaction.
+// It returns the create revision of key, or 0 if the guard failed and key
+// was not queued.
+func (rw *RWMutex) acquire(ctx context.Context, key, blockPfx string, guard ...v3.Cmp) (int64, *mvccpb.KeyValue, *pb.ResponseHeader, error) {
+	client := rw.s.Client()
+	cmps := append([]v3.Cmp{v3.Compare(v3.CreateRevision(key), "=", 0)}, guard...)
+	put := v3.OpPut(key, "", v3.WithLease(rw.s.Lease()))
+	// reuse key in case this session already queued it
+	get := v3.OpGet(key)
+	getFirst := v3.OpGet(blockPfx, v3.WithFirstCreate()...)
+	resp, err := client.Txn(ctx).If(cmps...).Then(put, getFirst).Else(get, getFirst).Commit()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	var first *mvccpb.KeyValue
+	if kvs := resp.Responses[1].GetResponseRange().Kvs; len(kvs) > 0 {
+		first = kvs[0]
+	}
+	if resp.Succeeded {
+		return resp.Header.Revision, first, resp.Header, nil
+	}
+	if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+		return kvs[0].CreateRevision, first, resp.Header, nil
+	}
+	return 0, first, resp.Header, nil
+}
+
+// wait waits for the keys under blockPfx created before rev to be deleted,
+// then makes sure the session still holds key.
+func (rw *RWMutex) wait(ctx context.Context, key string, rev int64, blockPfx string, release func(context.Context) error) error {
+	client := rw.s.Client()
+	// release key if wait failed
+	if _, err := waitDeletes(ctx, client, blockPfx, rev-1); err != nil {
+		release(client.Ctx())
+		return err
+	}
+	gresp, err := client.Get(ctx, key)
+	if err != nil {
+		release(client.Ctx())
+		return err
+	}
+	if len(gresp.Kvs) == 0 { // is the session key lost?
+		return ErrSessionExpired
+	}
+	rw.hdr = gresp.Header
+	return nil
+}
+
+// Header is the response header received from etcd on acquiring the lock.
+func (rw *RWMutex) Header() *pb.ResponseHeader { return rw.hdr }
@@ -0,0 +1,168 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrNoPermits is returned by TryAcquire when all permits of the Semaphore
+// are held by other sessions.
+var ErrNoPermits = errors.New("semaphore: all permits held by other sessions")
+
+// Semaphore is a counting semaphore with etcd, held by at most n sessions at
+// a time. Like Mutex, waiters put a key under the prefix, owned by the lease
+// of their session, and the n oldest waiters hold a permit. A permit is
+// released when its key is deleted, so the permits of a crashed process are
+// released once its session lease expires.
+//
+// A session holds at most one permit of a Semaphore.
+type Semaphore struct {
+	s *Session
+	n int
+
+	pfx   string
+	myKey string
+	myRev int64
+	hdr   *pb.ResponseHeader
+}
+
+// NewSemaphore creates a Semaphore on the keys under pfx, with n permits.
+// All the semaphores on the same prefix must have the same number of permits.
+func NewSemaphore(s *Session, pfx string, n int) *Semaphore {
+	return &Semaphore{s: s, n: n, pfx: pfx + "/", myKey: "\x00", myRev: -1}
+}
+
+// TryAcquire acquires a permit if one is free, and returns ErrNoPermits
+// otherwise.
+func (sm *Semaphore) TryAcquire(ctx context.Context) error {
+	held, err := sm.tryAcquire(ctx)
+	if err != nil || held {
+		return err
+	}
+	client := sm.s.Client()
+	// Cannot acquire, so delete the key
+	if _, err := client.Delete(ctx, sm.myKey); err != nil {
+		return err
+	}
+	sm.myKey = "\x00"
+	sm.myRev = -1
+	return ErrNoPermits
+}
+
+// Acquire acquires a permit, waiting for one to be released if all are held.
+// If the context is canceled while waiting, the semaphore tries to clean its
+// stale waiter key.
+func (sm *Semaphore) Acquire(ctx context.Context) error {
+	held, err := sm.tryAcquire(ctx)
+	if err != nil || held {
+		return err
+	}
+	client := sm.s.Client()
+	for !held {
+		// any release may free a permit for this waiter
+		if werr := waitDelete(ctx, client, sm.pfx, sm.hdr.Revision+1, v3.WithPrefix()); werr != nil {
+			sm.Release(client.Ctx())
+			return werr
+		}
+		if held, err = sm.holds(ctx); err != nil {
+			sm.Release(client.Ctx())
+			return err
+		}
+	}
+
+	// make sure the session is not expired, and the waiter key still exists.
+	gresp, err := client.Get(ctx, sm.myKey)
+	if err != nil {
+		sm.Release(client.Ctx())
+		return err
+	}
+	if len(gresp.Kvs) == 0 { // is the session key lost?
+		return ErrSessionExpired
+	}
+	sm.hdr = gresp.Header
+	return nil
+}
+
+// tryAcquire puts the waiter key of the session, unless it exists already,
+// and returns whether it holds a permit.
+func (sm *Semaphore) tryAcquire(ctx context.Context) (bool, error) {
+	s := sm.s
+	client := sm.s.Client()
+
+	sm.myKey = fmt.Sprintf("%s%x", sm.pfx, s.Lease())
+	cmp := v3.Compare(v3.CreateRevision(sm.myKey), "=", 0)
+	put := v3.OpPut(sm.myKey, "", v3.WithLease(s.Lease()))
+	// reuse key in case this session already holds a permit
+	get := v3.OpGet(sm.myKey)
+	// fetch the waiters to complete the uncontended path with only one RPC
+	getWaiters := v3.OpGet(sm.pfx, v3.WithPrefix(), v3.WithCountOnly())
+	resp, err := client.Txn(ctx).If(cmp).Then(put, getWaiters).Else(get).Commit()
+	if err != nil {
+		return false, err
+	}
+	sm.hdr = resp.Header
+	if resp.Succeeded {
+		sm.myRev = resp.Header.Revision
+		// the key just put is the newest waiter
+		if resp.Responses[1].GetResponseRange().Count <= int64(sm.n) {
+			return true, nil
+		}
+	} else {
+		sm.myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	return sm.holds(ctx)
+}
+
+// holds returns whether fewer than n waiters were queued before the key of
+// the session.
+func (sm *Semaphore) holds(ctx context.Context) (bool, error) {
+	// count filters by create revision after counting, so fetch the keys
+	opts := []v3.OpOption{
+		v3.WithPrefix(),
+		v3.WithKeysOnly(),
+		v3.WithMaxCreateRev(sm.myRev - 1),
+		v3.WithSort(v3.SortByCreateRevision, v3.SortAscend),
+		v3.WithLimit(int64(sm.n)),
+	}
+	resp, err := sm.s.Client().Get(ctx, sm.pfx, opts...)
+	if err != nil {
+		return false, err
+	}
+	sm.hdr = resp.Header
+	return len(resp.Kvs) < sm.n, nil
+}
+
+// Release releases the permit of the session.
+func (sm *Semaphore) Release(ctx context.Context) error {
+	client := sm.s.Client()
+	if _, err := client.Delete(ctx, sm.myKey); err != nil {
+		return err
+	}
+	sm.myKey = "\x00"
+	sm.myRev = -1
+	return nil
+}
+
+// Key returns the waiter key of the session.
+func (sm *Semaphore) Key() string { return sm.myKey }
+
+// Header is the response header received from etcd on acquiring a permit.
+func (sm *Semaphore) Header() *pb.ResponseHeader { return sm.hdr }
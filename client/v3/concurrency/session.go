@@ -16,6 +16,8 @@ package concurrency
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	v3 "go.etcd.io/etcd/client/v3"
@@ -23,31 +25,85 @@ import (
 
 const defaultSessionTTL = 60
 
+// SessionState is the liveness of a session lease, as seen by the client.
+type SessionState int
+
+const (
+	// SessionHealthy means the lease is refreshed in time.
+	SessionHealthy SessionState = iota
+	// SessionDegraded means the lease was not refreshed for long enough
+	// that its estimated remaining TTL fell below the health threshold.
+	SessionDegraded
+	// SessionLost means the lease is no longer refreshed: it expired, or
+	// the keepalive ended. A session with a re-establishment policy then
+	// tries to grant a new lease.
+	SessionLost
+)
+
+func (st SessionState) String() string {
+	switch st {
+	case SessionHealthy:
+		return "healthy"
+	case SessionDegraded:
+		return "degraded"
+	case SessionLost:
+		return "lost"
+	}
+	return fmt.Sprintf("SessionState(%d)", int(st))
+}
+
+// SessionHealth is a snapshot of the liveness of a session.
+type SessionHealth struct {
+	State SessionState
+	// Lease is the current lease of the session, which changes when the
+	// session is re-established.
+	Lease v3.LeaseID
+	// TTL is the estimated time left before the lease expires, from the
+	// last keepalive response.
+	TTL time.Duration
+}
+
 // Session represents a lease kept alive for the lifetime of a client.
 // Fault-tolerant applications may use sessions to reason about liveness.
 type Session struct {
 	client *v3.Client
 	opts   *sessionOptions
-	id     v3.LeaseID
+
+	mu      sync.Mutex
+	id      v3.LeaseID
+	expires time.Time
+	state   SessionState
+	held    map[sessionHolder]struct{}
 
 	cancel context.CancelFunc
 	donec  <-chan struct{}
 }
 
+// sessionHolder is a primitive held with the session lease, such as a
+// locked Mutex or an elected Election, which the session releases when it
+// is at risk with WithResignOnRisk.
+type sessionHolder interface {
+	resignSession(ctx context.Context) error
+}
+
 // NewSession gets the leased session for a client.
 func NewSession(client *v3.Client, opts ...SessionOption) (*Session, error) {
 	ops := &sessionOptions{ttl: defaultSessionTTL, ctx: client.Ctx()}
 	for _, opt := range opts {
 		opt(ops)
 	}
+	if ops.healthThreshold <= 0 {
+		ops.healthThreshold = time.Duration(ops.ttl) * time.Second / 3
+	}
 
 	id := ops.leaseID
+	ttl := int64(ops.ttl)
 	if id == v3.NoLease {
 		resp, err := client.Grant(ops.ctx, int64(ops.ttl))
 		if err != nil {
 			return nil, err
 		}
-		id = resp.ID
+		id, ttl = resp.ID, resp.TTL
 	}
 
 	ctx, cancel := context.WithCancel(ops.ctx)
@@ -58,29 +114,162 @@ func NewSession(client *v3.Client, opts ...SessionOption) (*Session, error) {
 	}
 
 	donec := make(chan struct{})
-	s := &Session{client: client, opts: ops, id: id, cancel: cancel, donec: donec}
+	s := &Session{
+		client:  client,
+		opts:    ops,
+		id:      id,
+		expires: time.Now().Add(time.Duration(ttl) * time.Second),
+		held:    make(map[sessionHolder]struct{}),
+		cancel:  cancel,
+		donec:   donec,
+	}
 
 	// keep the lease alive until client error or cancelled context
 	go func() {
 		defer close(donec)
-		for range keepAlive {
-			// eat messages until keep alive channel closes
-		}
+		s.run(ctx, keepAlive)
 	}()
 
 	return s, nil
 }
 
+func (s *Session) run(ctx context.Context, keepAlive <-chan *v3.LeaseKeepAliveResponse) {
+	var tickc <-chan time.Time
+	if s.opts.healthFn != nil || s.opts.resignOnRisk {
+		ticker := time.NewTicker(s.opts.healthThreshold / 4)
+		defer ticker.Stop()
+		tickc = ticker.C
+	}
+	for {
+		select {
+		case resp, ok := <-keepAlive:
+			if ok {
+				s.renewed(time.Duration(resp.TTL) * time.Second)
+				continue
+			}
+			if ctx.Err() != nil {
+				// orphaned
+				return
+			}
+			s.setState(SessionLost)
+			if keepAlive = s.reestablish(ctx); keepAlive == nil {
+				return
+			}
+		case <-tickc:
+			if s.Health().TTL < s.opts.healthThreshold {
+				s.setState(SessionDegraded)
+			}
+		}
+	}
+}
+
+// renewed records a keepalive response granting ttl.
+func (s *Session) renewed(ttl time.Duration) {
+	s.mu.Lock()
+	s.expires = time.Now().Add(ttl)
+	s.mu.Unlock()
+	s.setState(SessionHealthy)
+}
+
+// setState moves the session to state st, and notifies the transition.
+func (s *Session) setState(st SessionState) {
+	s.mu.Lock()
+	if s.state == st {
+		s.mu.Unlock()
+		return
+	}
+	s.state = st
+	var held []sessionHolder
+	if st != SessionHealthy && s.opts.resignOnRisk {
+		for h := range s.held {
+			held = append(held, h)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(held) > 0 {
+		// resign while the lease may still be alive, within its TTL
+		rctx, cancel := context.WithTimeout(s.client.Ctx(), s.opts.healthThreshold)
+		for _, h := range held {
+			h.resignSession(rctx)
+		}
+		cancel()
+	}
+	if s.opts.healthFn != nil {
+		s.opts.healthFn(s.Health())
+	}
+}
+
+// reestablish grants a new lease for the session according to its
+// re-establishment policy, and returns its keepalive channel, or nil if it
+// gave up.
+func (s *Session) reestablish(ctx context.Context) <-chan *v3.LeaseKeepAliveResponse {
+	for i := 0; i < s.opts.reestablishAttempts; i++ {
+		select {
+		case <-time.After(s.opts.reestablishBackoff):
+		case <-ctx.Done():
+			return nil
+		}
+		resp, err := s.client.Grant(ctx, int64(s.opts.ttl))
+		if err != nil {
+			continue
+		}
+		keepAlive, err := s.client.KeepAlive(ctx, resp.ID)
+		if err != nil || keepAlive == nil {
+			continue
+		}
+		s.mu.Lock()
+		s.id = resp.ID
+		s.mu.Unlock()
+		s.renewed(time.Duration(resp.TTL) * time.Second)
+		return keepAlive
+	}
+	return nil
+}
+
+// track registers h as held with the session lease.
+func (s *Session) track(h sessionHolder) {
+	s.mu.Lock()
+	s.held[h] = struct{}{}
+	s.mu.Unlock()
+}
+
+// untrack unregisters h once released.
+func (s *Session) untrack(h sessionHolder) {
+	s.mu.Lock()
+	delete(s.held, h)
+	s.mu.Unlock()
+}
+
 // Client is the etcd client that is attached to the session.
 func (s *Session) Client() *v3.Client {
 	return s.client
 }
 
-// Lease is the lease ID for keys bound to the session.
-func (s *Session) Lease() v3.LeaseID { return s.id }
+// Lease is the lease ID for keys bound to the session. It changes when the
+// session is re-established with a new lease.
+func (s *Session) Lease() v3.LeaseID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Health returns the current liveness of the session.
+func (s *Session) Health() SessionHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := SessionHealth{State: s.state, Lease: s.id}
+	if s.state != SessionLost {
+		if h.TTL = time.Until(s.expires); h.TTL < 0 {
+			h.TTL = 0
+		}
+	}
+	return h
+}
 
 // Done returns a channel that closes when the lease is orphaned, expires, or
-// is otherwise no longer being refreshed.
+// is otherwise no longer being refreshed. With a re-establishment policy, it
+// only closes once the policy gave up granting a new lease.
 func (s *Session) Done() <-chan struct{} { return s.donec }
 
 // Orphan ends the refresh for the session lease. This is useful
@@ -96,7 +285,7 @@ func (s *Session) Close() error {
 	s.Orphan()
 	// if revoke takes longer than the ttl, lease is expired anyway
 	ctx, cancel := context.WithTimeout(s.opts.ctx, time.Duration(s.opts.ttl)*time.Second)
-	_, err := s.client.Revoke(ctx, s.id)
+	_, err := s.client.Revoke(ctx, s.Lease())
 	cancel()
 	return err
 }
@@ -105,6 +294,12 @@ type sessionOptions struct {
 	ttl     int
 	leaseID v3.LeaseID
 	ctx     context.Context
+
+	healthThreshold     time.Duration
+	healthFn            func(SessionHealth)
+	resignOnRisk        bool
+	reestablishAttempts int
+	reestablishBackoff  time.Duration
 }
 
 // SessionOption configures Session.
@@ -139,3 +334,36 @@ func WithContext(ctx context.Context) SessionOption {
 		so.ctx = ctx
 	}
 }
+
+// WithHealthCallback calls fn on every change of the state of the session:
+// when it is degraded, because the estimated remaining TTL of its lease fell
+// below threshold without a keepalive response, when it is lost, and when it
+// is healthy again. If threshold is <= 0, a third of the TTL is used. fn is
+// called from the keepalive goroutine of the session, and must not block.
+func WithHealthCallback(threshold time.Duration, fn func(SessionHealth)) SessionOption {
+	return func(so *sessionOptions) {
+		so.healthThreshold = threshold
+		so.healthFn = fn
+	}
+}
+
+// WithResignOnRisk makes the session unlock its locked Mutexes and resign
+// its won Elections as soon as it is degraded or lost, rather than let
+// them be held by a lease that may expire at any moment. The holders learn
+// about it from the health callback.
+func WithResignOnRisk() SessionOption {
+	return func(so *sessionOptions) {
+		so.resignOnRisk = true
+	}
+}
+
+// WithReestablish makes the session grant a new lease when its lease is
+// lost, up to attempts times, waiting backoff before each attempt. Keys
+// written with the lost lease are gone: the locks and elections held by the
+// session must be acquired again with the new lease.
+func WithReestablish(attempts int, backoff time.Duration) SessionOption {
+	return func(so *sessionOptions) {
+		so.reestablishAttempts = attempts
+		so.reestablishBackoff = backoff
+	}
+}
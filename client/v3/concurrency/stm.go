@@ -17,6 +17,8 @@ package concurrency
 import (
 	"context"
 	"math"
+	"sort"
+	"strings"
 
 	v3 "go.etcd.io/etcd/client/v3"
 )
@@ -32,12 +34,29 @@ type STM interface {
 	Rev(key string) int64
 	// Del deletes a key.
 	Del(key string)
+	// GetPrefix returns the values of the keys with the given prefix, with
+	// the pending writes applied, and inserts the range in the txn's read
+	// set: unless reads are ReadCommitted, the txn conflicts with any write
+	// to a key of the range since it was read. If
+	// GetPrefix fails, it aborts the transaction with an error, never
+	// returning.
+	GetPrefix(prefix string) map[string]string
+	// PendingWrites returns the writes the txn commits, sorted by key.
+	PendingWrites() []STMWrite
 
 	// commit attempts to apply the txn's changes to the server.
 	commit() *v3.TxnResponse
 	reset()
 }
 
+// STMWrite is a pending write of an STM transaction.
+type STMWrite struct {
+	Key   string
+	Value string
+	// Delete is true for a key deleted with Del.
+	Delete bool
+}
+
 // Isolation is an enumeration of transactional isolation levels which
 // describes how transactions should interfere and conflict.
 type Isolation int
@@ -109,7 +128,11 @@ func mkSTM(c *v3.Client, opts *stmOptions) STM {
 			prefetch: make(map[string]*v3.GetResponse),
 		}
 		s.conflicts = func() []v3.Cmp {
-			return append(s.rset.cmps(), s.wset.cmps(s.rset.first()+1)...)
+			first := s.rset.first()
+			if pfirst := s.prset.first(); pfirst < first {
+				first = pfirst
+			}
+			return append(s.readCmps(), s.wset.cmps(first+1)...)
 		}
 		return s
 	case Serializable:
@@ -117,11 +140,11 @@ func mkSTM(c *v3.Client, opts *stmOptions) STM {
 			stm:      stm{client: c, ctx: opts.ctx},
 			prefetch: make(map[string]*v3.GetResponse),
 		}
-		s.conflicts = func() []v3.Cmp { return s.rset.cmps() }
+		s.conflicts = func() []v3.Cmp { return s.readCmps() }
 		return s
 	case RepeatableReads:
 		s := &stm{client: c, ctx: opts.ctx, getOpts: []v3.OpOption{v3.WithSerializable()}}
-		s.conflicts = func() []v3.Cmp { return s.rset.cmps() }
+		s.conflicts = func() []v3.Cmp { return s.readCmps() }
 		return s
 	case ReadCommitted:
 		s := &stm{client: c, ctx: opts.ctx, getOpts: []v3.OpOption{v3.WithSerializable()}}
@@ -172,6 +195,8 @@ type stm struct {
 	ctx    context.Context
 	// rset holds read key values and revisions
 	rset readSet
+	// prset holds read prefix values and revisions
+	prset readSet
 	// wset holds overwritten keys and their values
 	wset writeSet
 	// getOpts are the opts used for gets
@@ -213,6 +238,19 @@ func (rs readSet) cmps() []v3.Cmp {
 	return cmps
 }
 
+// prefixCmps guards the txn from updates to the read prefixes: no key of a
+// prefix was written since it was read, and none of its keys was deleted.
+func (rs readSet) prefixCmps() []v3.Cmp {
+	var cmps []v3.Cmp
+	for pfx, resp := range rs {
+		cmps = append(cmps, v3.Compare(v3.ModRevision(pfx), "<", resp.Header.Revision+1).WithPrefix())
+		for _, kv := range resp.Kvs {
+			cmps = append(cmps, v3.Compare(v3.ModRevision(string(kv.Key)), "=", kv.ModRevision))
+		}
+	}
+	return cmps
+}
+
 type writeSet map[string]stmPut
 
 func (ws writeSet) get(keys ...string) *stmPut {
@@ -242,6 +280,10 @@ func (ws writeSet) puts() []v3.Op {
 	return puts
 }
 
+func (s *stm) readCmps() []v3.Cmp {
+	return append(s.rset.cmps(), s.prset.prefixCmps()...)
+}
+
 func (s *stm) Get(keys ...string) string {
 	if wv := s.wset.get(keys...); wv != nil {
 		return wv.val
@@ -262,6 +304,58 @@ func (s *stm) Rev(key string) int64 {
 	return 0
 }
 
+func (s *stm) GetPrefix(prefix string) map[string]string {
+	return s.prefixValues(prefix, s.fetchPrefix(prefix))
+}
+
+// prefixValues returns the values of resp with the pending writes to the
+// keys of prefix applied.
+func (s *stm) prefixValues(prefix string, resp *v3.GetResponse) map[string]string {
+	vals := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		vals[string(kv.Key)] = string(kv.Value)
+	}
+	for key, wv := range s.wset {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if wv.op.IsDelete() {
+			delete(vals, key)
+		} else {
+			vals[key] = wv.val
+		}
+	}
+	return vals
+}
+
+func (s *stm) fetchPrefix(prefix string) *v3.GetResponse {
+	if resp, ok := s.prset[prefix]; ok {
+		return resp
+	}
+	op := v3.OpGet(prefix, append([]v3.OpOption{v3.WithPrefix()}, s.getOpts...)...)
+	oresp, err := s.client.Do(s.ctx, op)
+	if err != nil {
+		panic(stmError{err})
+	}
+	resp := oresp.Get()
+	if op.Rev() != 0 {
+		// the range is guarded from the revision it was read at, not the
+		// current one of the header
+		resp.Header.Revision = op.Rev()
+	}
+	s.prset[prefix] = resp
+	return resp
+}
+
+func (s *stm) PendingWrites() []STMWrite {
+	ws := make([]STMWrite, 0, len(s.wset))
+	for key, wv := range s.wset {
+		ws = append(ws, STMWrite{Key: key, Value: wv.val, Delete: wv.op.IsDelete()})
+	}
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Key < ws[j].Key })
+	return ws
+}
+
 func (s *stm) commit() *v3.TxnResponse {
 	txnresp, err := s.client.Txn(s.ctx).If(s.conflicts()...).Then(s.wset.puts()...).Commit()
 	if err != nil {
@@ -294,6 +388,7 @@ func (s *stm) fetch(keys ...string) *v3.GetResponse {
 
 func (s *stm) reset() {
 	s.rset = make(map[string]*v3.GetResponse)
+	s.prset = make(map[string]*v3.GetResponse)
 	s.wset = make(map[string]stmPut)
 }
 
@@ -306,7 +401,7 @@ func (s *stmSerializable) Get(keys ...string) string {
 	if wv := s.wset.get(keys...); wv != nil {
 		return wv.val
 	}
-	firstRead := len(s.rset) == 0
+	firstRead := len(s.rset) == 0 && len(s.prset) == 0
 	for _, key := range keys {
 		if resp, ok := s.prefetch[key]; ok {
 			delete(s.prefetch, key)
@@ -324,6 +419,19 @@ func (s *stmSerializable) Get(keys ...string) string {
 	return respToValue(resp)
 }
 
+func (s *stmSerializable) GetPrefix(prefix string) map[string]string {
+	firstRead := len(s.rset) == 0 && len(s.prset) == 0
+	resp := s.stm.fetchPrefix(prefix)
+	if firstRead {
+		// txn's base revision is defined by the first read
+		s.getOpts = []v3.OpOption{
+			v3.WithRev(resp.Header.Revision),
+			v3.WithSerializable(),
+		}
+	}
+	return s.prefixValues(prefix, resp)
+}
+
 func (s *stmSerializable) Rev(key string) int64 {
 	s.Get(key)
 	return s.stm.Rev(key)
@@ -371,6 +479,27 @@ func respToValue(resp *v3.GetResponse) string {
 	return string(resp.Kvs[0].Value)
 }
 
+// STMRunner runs STM transactions with shared default options, such as an
+// isolation level, which each transaction may override.
+type STMRunner struct {
+	c    *v3.Client
+	opts []stmOption
+}
+
+// NewSTMRunner returns an STMRunner running transactions with the default
+// options so.
+func NewSTMRunner(c *v3.Client, so ...stmOption) *STMRunner {
+	return &STMRunner{c: c, opts: so}
+}
+
+// Run runs apply as NewSTM does, with the options so applied after the
+// defaults of the runner, e.g. to run the transaction at another isolation
+// level with WithIsolation.
+func (r *STMRunner) Run(apply func(STM) error, so ...stmOption) (*v3.TxnResponse, error) {
+	opts := append(append([]stmOption(nil), r.opts...), so...)
+	return NewSTM(r.c, apply, opts...)
+}
+
 // NewSTMRepeatable is deprecated.
 func NewSTMRepeatable(ctx context.Context, c *v3.Client, apply func(STM) error) (*v3.TxnResponse, error) {
 	return NewSTM(c, apply, WithAbortContext(ctx), WithIsolation(RepeatableReads))
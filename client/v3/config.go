@@ -19,6 +19,8 @@ import (
 	"crypto/tls"
 	"time"
 
+	"go.etcd.io/etcd/client/v3/balancer/picker"
+
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
@@ -31,6 +33,11 @@ type Config struct {
 	// 0 disables auto-sync. By default auto-sync is disabled.
 	AutoSyncInterval time.Duration `json:"auto-sync-interval"`
 
+	// EndpointDiscovery, if set, refreshes the endpoints periodically from
+	// DNS SRV records, or from the members of the cluster. Its refreshes
+	// take the place of AutoSyncInterval.
+	EndpointDiscovery *EndpointDiscovery
+
 	// DialTimeout is the timeout for failing to establish a connection.
 	DialTimeout time.Duration `json:"dial-timeout"`
 
@@ -64,6 +71,14 @@ type Config struct {
 	// Password is a password for authentication.
 	Password string `json:"password"`
 
+	// CredentialsProvider, if set, provides the credentials of the client
+	// in place of Username and Password. It is asked for credentials when
+	// the client authenticates, when its token is rejected as invalid or
+	// expired, and before each stream is created, so that credentials
+	// rotated by a secret manager or short-lived tokens are picked up
+	// without recreating the client.
+	CredentialsProvider CredentialsProvider
+
 	// RejectOldCluster when set will refuse to create a client against an outdated cluster.
 	RejectOldCluster bool `json:"reject-old-cluster"`
 
@@ -84,5 +99,65 @@ type Config struct {
 	// PermitWithoutStream when set will allow client to send keepalive pings to server without any active streams(RPCs).
 	PermitWithoutStream bool `json:"permit-without-stream"`
 
+	// RetryPolicy configures the retries of failed requests.
+	// If nil, use DefaultRetryPolicy().
+	RetryPolicy *RetryPolicy
+
+	// EndpointHealthPolicy, if set, quarantines the endpoints it finds
+	// unhealthy from the outcome of the requests sent to them, e.g.
+	// picker.NewConsecutiveFailurePolicy. Quarantined endpoints are not
+	// picked by the balancer until their quarantine ends, unless all
+	// endpoints are quarantined.
+	EndpointHealthPolicy picker.HealthPolicy
+
+	// BalancerPolicy selects the endpoint each request is sent to.
+	// By default, requests are balanced in roundrobin fashion.
+	BalancerPolicy BalancerPolicy
+
+	// BalancerZone is the zone of the client, and BalancerEndpointZones maps
+	// the endpoints to the zone of their member, for BalancerZoneAware.
+	// Endpoints missing from BalancerEndpointZones, such as those added by
+	// auto-sync, are considered in another zone.
+	BalancerZone          string
+	BalancerEndpointZones map[string]string
+
+	// ConnectionsPerEndpoint is the number of HTTP/2 connections the client
+	// opens to each endpoint, striping requests and streams over them, so
+	// that many watches or large responses are not limited by the streams
+	// of a single connection, or blocked behind each other. If 0, a single
+	// connection is opened. Connections dialed to a single endpoint, such
+	// as for Maintenance requests, are not multiplied.
+	ConnectionsPerEndpoint int
+
+	// StreamRebalanceInterval, if positive, is the interval, jittered, at
+	// which the long-lived watch and lease keepalive streams are cycled:
+	// a new stream is opened, and balanced like any request, and the watches
+	// resume on it from the revision after the last one received, so that
+	// streams spread over the members again after a member restarts, rather
+	// than stay on the members they landed on.
+	StreamRebalanceInterval time.Duration
+
+	// TracerProvider, if set, traces every request, including the streams
+	// of watches and lease keepalives, with a span per request and a child
+	// span per attempt made by the retries.
+	TracerProvider TracerProvider
+
+	// MeterProvider, if set, measures the number and duration of requests,
+	// their attempts, and the messages of streams.
+	MeterProvider MeterProvider
+
+	// PrefixMetrics, if set, aggregates the key-value requests of the
+	// client by key prefix, e.g. NewPrefixMetrics("/app/a/", "/app/b/").
+	PrefixMetrics *PrefixMetrics
+
+	// RateLimiter, if set, limits the rate of the requests by class of
+	// requests, e.g. NewRateLimiter(map[MethodClass]RateLimit{...}).
+	RateLimiter *RateLimiter
+
+	// MaxInFlightRequests, if positive, limits the number of unary requests
+	// in flight; further requests wait for one of them to complete. The
+	// waits for both limits are measured with MeterProvider.
+	MaxInFlightRequests int
+
 	// TODO: support custom balancer picker
 }
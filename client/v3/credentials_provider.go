@@ -0,0 +1,41 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import "context"
+
+// Credentials are the credentials of a client: either a username and a
+// password the client authenticates with, or a token it uses as is, such
+// as a JWT issued outside of etcd.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// CredentialsProvider provides the credentials of a client, for instance
+// from a secret manager. Credentials may be called concurrently, and should
+// return cached credentials until they are rotated.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is a CredentialsProvider calling the function.
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials returns f(ctx).
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encoding translates the values of clientv3 between typed Go
+// values and bytes, with a pluggable Codec and an optional Compressor, so
+// applications do not marshal and unmarshal around every Get, Put and
+// Watch.
+//
+// JSON and Proto codecs are provided. Other formats, such as msgpack, are
+// supported by implementing Codec around their library:
+//
+//	type msgpackCodec struct{}
+//
+//	func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+//	func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// ErrNotProto is returned by the Proto codec for values which are not
+// protobuf messages.
+var ErrNotProto = errors.New("encoding: value is not a protobuf message")
+
+// Codec marshals and unmarshals values.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Compressor compresses and decompresses marshaled values.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+type jsonCodec struct{}
+
+// JSON returns a Codec encoding values as JSON with encoding/json.
+func JSON() Codec { return jsonCodec{} }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// protoMessage is implemented by generated protobuf messages, such as the
+// messages of the etcd API.
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+type protoCodec struct{}
+
+// Proto returns a Codec encoding protobuf messages with their generated
+// Marshal and Unmarshal methods.
+func Proto() Codec { return protoCodec{} }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(protoMessage)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotProto, v)
+	}
+	return m.Marshal()
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(protoMessage)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNotProto, v)
+	}
+	return m.Unmarshal(data)
+}
+
+type gzipCompressor struct {
+	level int
+}
+
+// Gzip returns a Compressor compressing values with gzip at the given
+// level, as defined by compress/gzip.
+func Gzip(level int) Compressor { return gzipCompressor{level} }
+
+func (c gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+type options struct {
+	compressor Compressor
+}
+
+// Option configures a TypedKV or a TypedWatcher.
+type Option func(*options)
+
+// WithCompressor compresses the marshaled values with c. The values are
+// then only readable by a TypedKV or TypedWatcher with the same compressor.
+func WithCompressor(c Compressor) Option {
+	return func(o *options) { o.compressor = c }
+}
+
+// valueCodec encodes and decodes values with a codec and an optional
+// compressor.
+type valueCodec struct {
+	codec      Codec
+	compressor Compressor
+}
+
+func newValueCodec(codec Codec, opts []Option) valueCodec {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return valueCodec{codec: codec, compressor: o.compressor}
+}
+
+func (c valueCodec) encode(v interface{}) (string, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if c.compressor != nil {
+		if data, err = c.compressor.Compress(data); err != nil {
+			return "", err
+		}
+	}
+	return string(data), nil
+}
+
+func (c valueCodec) decode(data []byte, v interface{}) (err error) {
+	if c.compressor != nil {
+		if data, err = c.compressor.Decompress(data); err != nil {
+			return err
+		}
+	}
+	return c.codec.Unmarshal(data, v)
+}
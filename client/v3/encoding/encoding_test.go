@@ -0,0 +1,122 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"compress/gzip"
+	"context"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+// mapKV puts and gets single keys in a map.
+type mapKV struct {
+	clientv3.KV
+	vals map[string]string
+}
+
+func (kv *mapKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	kv.vals[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (kv *mapKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp := &clientv3.GetResponse{}
+	if val, ok := kv.vals[key]; ok {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: []byte(key), Value: []byte(val)}}
+	}
+	return resp, nil
+}
+
+// chanWatcher returns its channel for any watch.
+type chanWatcher struct {
+	clientv3.Watcher
+	wch chan clientv3.WatchResponse
+}
+
+func (w *chanWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	return w.wch
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestTypedKV(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec Codec
+		opts  []Option
+		v     interface{}
+		out   func() interface{}
+	}{
+		{"json", JSON(), nil, &point{1, 2}, func() interface{} { return &point{} }},
+		{"json gzip", JSON(), []Option{WithCompressor(Gzip(gzip.BestSpeed))}, &point{3, 4}, func() interface{} { return &point{} }},
+		{"proto", Proto(), nil, &mvccpb.KeyValue{Key: []byte("k"), Version: 5}, func() interface{} { return &mvccpb.KeyValue{} }},
+	}
+	for _, tt := range tests {
+		kv := NewTypedKV(&mapKV{vals: make(map[string]string)}, tt.codec, tt.opts...)
+		if _, err := kv.Put(context.TODO(), "a", tt.v); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		out := tt.out()
+		if _, err := kv.Get(context.TODO(), "a", out); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(out, tt.v) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, out, tt.v)
+		}
+		if _, err := kv.Get(context.TODO(), "b", out); err != ErrKeyNotFound {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, ErrKeyNotFound)
+		}
+	}
+
+	kv := NewTypedKV(&mapKV{vals: make(map[string]string)}, Proto())
+	if _, err := kv.Put(context.TODO(), "a", &point{}); err == nil {
+		t.Error("expected an error putting a non-protobuf value with the proto codec")
+	}
+}
+
+func TestTypedWatcher(t *testing.T) {
+	w := &chanWatcher{wch: make(chan clientv3.WatchResponse, 1)}
+	tw := NewTypedWatcher(w, JSON())
+	w.wch <- clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("a"), Value: []byte(`{"X":1,"Y":2}`)}},
+		{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("a")}, PrevKv: &mvccpb.KeyValue{Key: []byte("a"), Value: []byte(`{"X":1,"Y":2}`)}},
+	}}
+	close(w.wch)
+
+	twch := tw.Watch(context.TODO(), "a", func() interface{} { return &point{} })
+	twr := <-twch
+	if twr.Err != nil {
+		t.Fatal(twr.Err)
+	}
+	want := []TypedEvent{
+		{Type: mvccpb.PUT, Key: "a", Value: &point{1, 2}},
+		{Type: mvccpb.DELETE, Key: "a", PrevValue: &point{1, 2}},
+	}
+	for i := range twr.Events {
+		twr.Events[i].Kv = nil
+	}
+	if !reflect.DeepEqual(twr.Events, want) {
+		t.Errorf("events = %+v, want %+v", twr.Events, want)
+	}
+	if _, ok := <-twch; ok {
+		t.Error("expected the typed watch channel to close with the watch channel")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+	"errors"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+// ErrKeyNotFound is returned getting a key which does not exist.
+var ErrKeyNotFound = errors.New("encoding: key not found")
+
+// TypedKV puts and gets typed values through a KV.
+type TypedKV struct {
+	kv clientv3.KV
+	c  valueCodec
+}
+
+// NewTypedKV wraps kv to encode and decode values with codec.
+func NewTypedKV(kv clientv3.KV, codec Codec, opts ...Option) *TypedKV {
+	return &TypedKV{kv: kv, c: newValueCodec(codec, opts)}
+}
+
+// Put encodes v and puts it as the value of key.
+func (kv *TypedKV) Put(ctx context.Context, key string, v interface{}, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	val, err := kv.c.encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return kv.kv.Put(ctx, key, val, opts...)
+}
+
+// Get decodes the value of key into v, which must be a pointer. It returns
+// ErrKeyNotFound if key does not exist.
+func (kv *TypedKV) Get(ctx context.Context, key string, v interface{}, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := kv.kv.Get(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return resp, ErrKeyNotFound
+	}
+	return resp, kv.c.decode(resp.Kvs[0].Value, v)
+}
+
+// GetAll decodes the values of the keys of a range Get, calling fn with
+// each key and its value, allocated by newValue. It stops at the first
+// error returned by fn.
+func (kv *TypedKV) GetAll(ctx context.Context, key string, newValue func() interface{}, fn func(key string, v interface{}) error, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := kv.kv.Get(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, kvp := range resp.Kvs {
+		v := newValue()
+		if err := kv.c.decode(kvp.Value, v); err != nil {
+			return resp, err
+		}
+		if err := fn(string(kvp.Key), v); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// Decode decodes the value of a key-value fetched outside of the TypedKV,
+// such as through a Txn, into v.
+func (kv *TypedKV) Decode(kvp *mvccpb.KeyValue, v interface{}) error {
+	return kv.c.decode(kvp.Value, v)
+}
+
+// Encode encodes v as a value to be put outside of the TypedKV, such as
+// through a Txn.
+func (kv *TypedKV) Encode(v interface{}) (string, error) {
+	return kv.c.encode(v)
+}
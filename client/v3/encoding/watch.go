@@ -0,0 +1,102 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"context"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+// TypedEvent is a watch event with its values decoded.
+type TypedEvent struct {
+	Type mvccpb.Event_EventType
+	Key  string
+	// Value is the decoded value of a put, nil for a delete.
+	Value interface{}
+	// PrevValue is the decoded previous value, when watching WithPrevKV.
+	PrevValue interface{}
+	// Kv is the key-value of the event, as received.
+	Kv *mvccpb.KeyValue
+}
+
+// TypedWatchResponse is a watch response with its events decoded.
+type TypedWatchResponse struct {
+	Header pb.ResponseHeader
+	Events []TypedEvent
+	// Response is the watch response, as received.
+	Response clientv3.WatchResponse
+	// Err is the error of the watch response, or the first error decoding
+	// its events, in which case Events holds the events before it.
+	Err error
+}
+
+// TypedWatcher watches typed values through a Watcher.
+type TypedWatcher struct {
+	w clientv3.Watcher
+	c valueCodec
+}
+
+// NewTypedWatcher wraps w to decode the values of the watch events with
+// codec.
+func NewTypedWatcher(w clientv3.Watcher, codec Codec, opts ...Option) *TypedWatcher {
+	return &TypedWatcher{w: w, c: newValueCodec(codec, opts)}
+}
+
+// Watch watches key as Watcher.Watch, decoding the values of the events
+// into values allocated by newValue. The returned channel is closed with
+// the watch channel.
+func (w *TypedWatcher) Watch(ctx context.Context, key string, newValue func() interface{}, opts ...clientv3.OpOption) <-chan TypedWatchResponse {
+	wch := w.w.Watch(ctx, key, opts...)
+	twch := make(chan TypedWatchResponse)
+	go func() {
+		defer close(twch)
+		for wr := range wch {
+			twr := w.decode(wr, newValue)
+			select {
+			case twch <- twr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return twch
+}
+
+func (w *TypedWatcher) decode(wr clientv3.WatchResponse, newValue func() interface{}) TypedWatchResponse {
+	twr := TypedWatchResponse{Header: wr.Header, Response: wr, Err: wr.Err()}
+	if twr.Err != nil {
+		return twr
+	}
+	for _, ev := range wr.Events {
+		tev := TypedEvent{Type: ev.Type, Key: string(ev.Kv.Key), Kv: ev.Kv}
+		if ev.Type == mvccpb.PUT {
+			tev.Value = newValue()
+			if twr.Err = w.c.decode(ev.Kv.Value, tev.Value); twr.Err != nil {
+				return twr
+			}
+		}
+		if ev.PrevKv != nil {
+			tev.PrevValue = newValue()
+			if twr.Err = w.c.decode(ev.PrevKv.Value, tev.PrevValue); twr.Err != nil {
+				return twr
+			}
+		}
+		twr.Events = append(twr.Events, tev)
+	}
+	return twr
+}
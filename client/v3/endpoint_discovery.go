@@ -0,0 +1,78 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/etcd/pkg/v3/srv"
+)
+
+// srvClientService is the SRV service of the client endpoints of a cluster,
+// as used by etcdctl --discovery-srv.
+const srvClientService = "etcd-client"
+
+// getSRVClients looks up the client endpoints of a domain; it is an
+// indirection for testing.
+var getSRVClients = srv.GetClient
+
+// EndpointDiscovery configures how a client refreshes its endpoints, so that
+// they do not go stale when the members of the cluster are replaced.
+//
+// Endpoints given as hostnames are resolved by DNS each time they are
+// dialed, so that their address records are always current.
+type EndpointDiscovery struct {
+	// SRVDomain, if set, is the domain whose "_etcd-client-ssl._tcp" and
+	// "_etcd-client._tcp" SRV records list the endpoints. The client is
+	// created with the endpoints they list if Config.Endpoints is empty.
+	SRVDomain string
+	// SRVServiceName is the optional suffix of the SRV services, as in
+	// "_etcd-client-<name>._tcp", to tell clusters sharing a domain apart.
+	SRVServiceName string
+	// RefreshInterval is the interval the endpoints are refreshed at. They
+	// are resolved from the SRV records of SRVDomain if set, or synced with
+	// the members of the cluster otherwise, as with AutoSyncInterval. 0
+	// disables refreshes.
+	RefreshInterval time.Duration
+}
+
+// lookupSRV returns the endpoints listed by the SRV records of d.
+func (d *EndpointDiscovery) lookupSRV() ([]string, error) {
+	clients, err := getSRVClients(srvClientService, d.SRVDomain, d.SRVServiceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(clients.Endpoints) == 0 {
+		return nil, errors.New("clientv3: no endpoints found in SRV records of " + d.SRVDomain)
+	}
+	return clients.Endpoints, nil
+}
+
+// SyncSRV sets the endpoints of the client to those listed by the SRV
+// records of Config.EndpointDiscovery.SRVDomain.
+func (c *Client) SyncSRV(ctx context.Context) error {
+	d := c.cfg.EndpointDiscovery
+	if d == nil || d.SRVDomain == "" {
+		return errors.New("clientv3: no SRV domain configured")
+	}
+	eps, err := d.lookupSRV()
+	if err != nil {
+		return err
+	}
+	c.SetEndpoints(eps...)
+	return nil
+}
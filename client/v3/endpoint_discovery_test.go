@@ -0,0 +1,50 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/pkg/v3/srv"
+)
+
+func TestNewWithSRVDiscovery(t *testing.T) {
+	defer func(f func(string, string, string) (*srv.SRVClients, error)) { getSRVClients = f }(getSRVClients)
+	var lookups []string
+	getSRVClients = func(service, domain, serviceName string) (*srv.SRVClients, error) {
+		lookups = append(lookups, service+"/"+domain+"/"+serviceName)
+		return &srv.SRVClients{Endpoints: []string{"http://127.0.0.1:2379", "http://127.0.0.1:22379"}}, nil
+	}
+
+	cli, err := New(Config{EndpointDiscovery: &EndpointDiscovery{SRVDomain: "example.com", SRVServiceName: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	if want := []string{"etcd-client/example.com/a"}; !reflect.DeepEqual(lookups, want) {
+		t.Fatalf("lookups = %v, want %v", lookups, want)
+	}
+	if eps, want := cli.Endpoints(), []string{"http://127.0.0.1:2379", "http://127.0.0.1:22379"}; !reflect.DeepEqual(eps, want) {
+		t.Fatalf("endpoints = %v, want %v", eps, want)
+	}
+
+	getSRVClients = func(service, domain, serviceName string) (*srv.SRVClients, error) {
+		return &srv.SRVClients{}, nil
+	}
+	if err = cli.SyncSRV(cli.Ctx()); err == nil {
+		t.Fatal("expected an error syncing with no endpoints in the SRV records")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// KeySeparator separates the segments of the keys of a KeyPath.
+const KeySeparator = "/"
+
+// ErrInvalidKeyPath is returned when parsing a key that is not a KeyPath.
+var ErrInvalidKeyPath = errors.New("clientv3: invalid key path")
+
+// KeyPath is a key made of path segments, each preceded by KeySeparator,
+// such as "/services/api". Segments are escaped as URL path segments, so
+// that any string, including one holding the separator, is a segment of
+// its own.
+//
+// The keys under a KeyPath, its descendants, start with its Prefix, which
+// unlike the key itself does not also match its siblings that start with
+// the same characters, such as "/services/apis". Its range, from Prefix to
+// RangeEnd, may be given to Get, Delete and Watch, or to
+// Auth.RoleGrantPermission:
+//
+//	p := clientv3.NewKeyPath("services", "api")
+//	cli.Put(ctx, p.Child("10.0.0.1:2379").Key(), addr)
+//	cli.Get(ctx, p.Prefix(), clientv3.WithPrefix())
+//	cli.RoleGrantPermission(ctx, "api", p.Prefix(), p.RangeEnd(), clientv3.PermissionType(clientv3.PermReadWrite))
+type KeyPath struct {
+	key string
+}
+
+// NewKeyPath returns the key path of the given segments. The key path of
+// no segments is the root, of empty key, whose descendants are all the
+// keys starting with KeySeparator.
+func NewKeyPath(segments ...string) KeyPath {
+	return KeyPath{}.Child(segments...)
+}
+
+// ParseKeyPath returns the key path of key, which must start with
+// KeySeparator and hold no invalid escape.
+func ParseKeyPath(key string) (KeyPath, error) {
+	if key == "" {
+		return KeyPath{}, nil
+	}
+	if !strings.HasPrefix(key, KeySeparator) {
+		return KeyPath{}, ErrInvalidKeyPath
+	}
+	for _, seg := range strings.Split(key[len(KeySeparator):], KeySeparator) {
+		if _, err := url.PathUnescape(seg); err != nil {
+			return KeyPath{}, ErrInvalidKeyPath
+		}
+	}
+	return KeyPath{key: key}, nil
+}
+
+// Child returns the key path of the given segments under p.
+func (p KeyPath) Child(segments ...string) KeyPath {
+	var b strings.Builder
+	b.WriteString(p.key)
+	for _, seg := range segments {
+		b.WriteString(KeySeparator)
+		b.WriteString(url.PathEscape(seg))
+	}
+	return KeyPath{key: b.String()}
+}
+
+// Parent returns the key path of p without its last segment. The parent of
+// the root is the root.
+func (p KeyPath) Parent() KeyPath {
+	i := strings.LastIndex(p.key, KeySeparator)
+	if i < 0 {
+		return KeyPath{}
+	}
+	return KeyPath{key: p.key[:i]}
+}
+
+// Segments returns the unescaped segments of p.
+func (p KeyPath) Segments() []string {
+	if p.key == "" {
+		return nil
+	}
+	segs := strings.Split(p.key[len(KeySeparator):], KeySeparator)
+	for i, seg := range segs {
+		segs[i], _ = url.PathUnescape(seg)
+	}
+	return segs
+}
+
+// Base returns the unescaped last segment of p, or "" for the root.
+func (p KeyPath) Base() string {
+	segs := p.Segments()
+	if len(segs) == 0 {
+		return ""
+	}
+	return segs[len(segs)-1]
+}
+
+// IsRoot returns true if p has no segments.
+func (p KeyPath) IsRoot() bool { return p.key == "" }
+
+// Key returns the key of p.
+func (p KeyPath) Key() string { return p.key }
+
+func (p KeyPath) String() string { return p.key }
+
+// Prefix returns the prefix of the descendants of p, its key followed by
+// KeySeparator.
+func (p KeyPath) Prefix() string { return p.key + KeySeparator }
+
+// RangeEnd returns the end of the range of the descendants of p, starting
+// at Prefix.
+func (p KeyPath) RangeEnd() string { return GetPrefixRangeEnd(p.Prefix()) }
+
+// Contains returns true if key is a descendant of p.
+func (p KeyPath) Contains(key string) bool {
+	return strings.HasPrefix(key, p.Prefix())
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyPath(t *testing.T) {
+	tests := []struct {
+		p KeyPath
+
+		wkey      string
+		wsegments []string
+		wparent   string
+		wprefix   string
+		wend      string
+	}{
+		{NewKeyPath(), "", nil, "", "/", "0"},
+		{NewKeyPath("a"), "/a", []string{"a"}, "", "/a/", "/a0"},
+		{NewKeyPath("a", "b"), "/a/b", []string{"a", "b"}, "/a", "/a/b/", "/a/b0"},
+		{NewKeyPath("a").Child("b/c"), "/a/b%2Fc", []string{"a", "b/c"}, "/a", "/a/b%2Fc/", "/a/b%2Fc0"},
+		{NewKeyPath("a", ""), "/a/", []string{"a", ""}, "/a", "/a//", "/a/0"},
+	}
+	for i, tt := range tests {
+		if key := tt.p.Key(); key != tt.wkey {
+			t.Errorf("#%d: key = %q, want %q", i, key, tt.wkey)
+		}
+		if segs := tt.p.Segments(); !reflect.DeepEqual(segs, tt.wsegments) {
+			t.Errorf("#%d: segments = %q, want %q", i, segs, tt.wsegments)
+		}
+		if parent := tt.p.Parent().Key(); parent != tt.wparent {
+			t.Errorf("#%d: parent = %q, want %q", i, parent, tt.wparent)
+		}
+		if prefix := tt.p.Prefix(); prefix != tt.wprefix {
+			t.Errorf("#%d: prefix = %q, want %q", i, prefix, tt.wprefix)
+		}
+		if end := tt.p.RangeEnd(); end != tt.wend {
+			t.Errorf("#%d: range end = %q, want %q", i, end, tt.wend)
+		}
+	}
+
+	p := NewKeyPath("services", "api")
+	for key, want := range map[string]bool{
+		"/services/api/x":   true,
+		"/services/api":     false,
+		"/services/apis/x":  false,
+		"/services/api0":    false,
+		"/services/api/x/y": true,
+	} {
+		if got := p.Contains(key); got != want {
+			t.Errorf("contains %q = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestParseKeyPath(t *testing.T) {
+	tests := []struct {
+		key  string
+		werr error
+	}{
+		{"", nil},
+		{"/a/b%2Fc", nil},
+		{"a/b", ErrInvalidKeyPath},
+		{"/a//b", nil},
+		{"/a/%zz", ErrInvalidKeyPath},
+	}
+	for i, tt := range tests {
+		p, err := ParseKeyPath(tt.key)
+		if err != tt.werr {
+			t.Errorf("#%d: err = %v, want %v", i, err, tt.werr)
+			continue
+		}
+		if err == nil && p.Key() != tt.key {
+			t.Errorf("#%d: key = %q, want %q", i, p.Key(), tt.key)
+		}
+	}
+}
@@ -16,8 +16,10 @@ package clientv3
 
 import (
 	"context"
+	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/v3/balancer/picker"
 
 	"google.golang.org/grpc"
 )
@@ -145,6 +147,13 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 	var err error
 	switch op.t {
 	case tRange:
+		if op.serializable && op.hedgeDelay > 0 && op.hedgeMax > 1 {
+			return kv.hedgedRange(ctx, op)
+		}
+		if op.serializable {
+			// any member serves serializable reads, so the closest one does
+			ctx = picker.WithPreferLocal(ctx)
+		}
 		var resp *pb.RangeResponse
 		resp, err = kv.remote.Range(ctx, op.toRangeRequest(), kv.callOpts...)
 		if err == nil {
@@ -152,7 +161,7 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 		}
 	case tPut:
 		var resp *pb.PutResponse
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, Ttl: op.ttl}
 		resp, err = kv.remote.Put(ctx, r, kv.callOpts...)
 		if err == nil {
 			return OpResponse{put: (*PutResponse)(resp)}, nil
@@ -175,3 +184,49 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 	}
 	return OpResponse{}, toErr(ctx, err)
 }
+
+// hedgedRange sends copies of a serializable range to distinct endpoints,
+// every hedgeDelay until one succeeds, and returns the first response.
+func (kv *kv) hedgedRange(ctx context.Context, op Op) (OpResponse, error) {
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	hctx = picker.WithAvoided(hctx, picker.NewAvoided())
+
+	type result struct {
+		resp *pb.RangeResponse
+		err  error
+	}
+	resc := make(chan result, op.hedgeMax)
+	sent := 0
+	send := func() {
+		sent++
+		go func() {
+			resp, err := kv.remote.Range(hctx, op.toRangeRequest(), kv.callOpts...)
+			resc <- result{resp, err}
+		}()
+	}
+
+	send()
+	t := time.NewTimer(op.hedgeDelay)
+	defer t.Stop()
+	var err error
+	for received := 0; received < sent; {
+		select {
+		case <-t.C:
+			if sent < op.hedgeMax {
+				send()
+				t.Reset(op.hedgeDelay)
+			}
+		case res := <-resc:
+			received++
+			if res.err == nil {
+				return OpResponse{get: (*GetResponse)(res.resp)}, nil
+			}
+			err = res.err
+			if sent < op.hedgeMax && ctx.Err() == nil {
+				send()
+			}
+		}
+	}
+	return OpResponse{}, toErr(ctx, err)
+}
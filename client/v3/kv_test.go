@@ -0,0 +1,76 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stallingKV stalls its first Range until canceled, fails its second, and
+// answers the others with their number as revision.
+type stallingKV struct {
+	pb.KVClient
+	mu    sync.Mutex
+	calls int
+}
+
+func (kv *stallingKV) Range(ctx context.Context, in *pb.RangeRequest, opts ...grpc.CallOption) (*pb.RangeResponse, error) {
+	kv.mu.Lock()
+	kv.calls++
+	call := kv.calls
+	kv.mu.Unlock()
+	switch call {
+	case 1:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case 2:
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	return &pb.RangeResponse{Header: &pb.ResponseHeader{Revision: int64(call)}}, nil
+}
+
+func TestHedgedRange(t *testing.T) {
+	remote := &stallingKV{}
+	kv := &kv{remote: remote}
+
+	// the stalled request is hedged after the delay, and the failed hedge
+	// right away
+	resp, err := kv.Get(context.TODO(), "a", WithSerializable(), WithHedging(10*time.Millisecond, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Revision != 3 || remote.calls != 3 {
+		t.Fatalf("revision = %d after %d calls, want 3 after 3", resp.Header.Revision, remote.calls)
+	}
+
+	// linearizable reads are not hedged
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	remote.calls = 0
+	if _, err = kv.Get(ctx, "a", WithHedging(10*time.Millisecond, 3)); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if remote.calls != 1 {
+		t.Fatalf("calls = %d, want 1", remote.calls)
+	}
+}
@@ -174,6 +174,11 @@ type lessor struct {
 	callOpts []grpc.CallOption
 
 	lg *zap.Logger
+
+	// rebalanceInterval is the interval the keepalive stream is cycled at,
+	// if positive, and rebalanced is set when it is canceled to be cycled.
+	rebalanceInterval time.Duration
+	rebalanced        bool
 }
 
 // keepAlive multiplexes a keepalive for a lease over multiple channels
@@ -205,6 +210,7 @@ func NewLeaseFromLeaseClient(remote pb.LeaseClient, c *Client, keepAliveTimeout
 	}
 	if c != nil {
 		l.callOpts = c.callOpts
+		l.rebalanceInterval = c.cfg.StreamRebalanceInterval
 	}
 	reqLeaderCtx := WithRequireLeader(context.Background())
 	l.stopCtx, l.stopCancel = context.WithCancel(reqLeaderCtx)
@@ -298,6 +304,9 @@ func (l *lessor) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAl
 	l.firstKeepAliveOnce.Do(func() {
 		go l.recvKeepAliveLoop()
 		go l.deadlineLoop()
+		if l.rebalanceInterval > 0 {
+			go l.rebalanceLoop()
+		}
 	})
 
 	return ch, nil
@@ -460,6 +469,15 @@ func (l *lessor) recvKeepAliveLoop() (gerr error) {
 			}
 		}
 
+		l.mu.Lock()
+		rebalanced := l.rebalanced
+		l.rebalanced = false
+		l.mu.Unlock()
+		if rebalanced {
+			// open the next stream right away
+			continue
+		}
+
 		select {
 		case <-time.After(retryConnWait):
 		case <-l.stopCtx.Done():
@@ -0,0 +1,260 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+const (
+	// DefaultLeaseManagerTTL is the default TTL, in seconds, of the leases
+	// of a LeaseManager.
+	DefaultLeaseManagerTTL = 60
+	// DefaultLeaseManagerPoolSize is the default number of leases of a
+	// LeaseManager.
+	DefaultLeaseManagerPoolSize = 4
+	// DefaultLeaseManagerJitter is the default fraction of the keepalive
+	// interval of a LeaseManager randomly added or subtracted.
+	DefaultLeaseManagerJitter = 0.2
+)
+
+// leaseManagerRetryInterval is the wait before a LeaseManager retries a
+// failed keepalive.
+var leaseManagerRetryInterval = 500 * time.Millisecond
+
+// ErrLeaseManagerClosed is returned for the keys put with a closed
+// LeaseManager.
+var ErrLeaseManagerClosed = errors.New("clientv3: lease manager closed")
+
+// LeaseManagerConfig configures a LeaseManager. Zero fields take their
+// default value.
+type LeaseManagerConfig struct {
+	// TTL is the TTL of the leases, in seconds.
+	TTL int64
+	// PoolSize is the maximum number of leases the keys are spread over.
+	PoolSize int
+	// Jitter is the fraction of the keepalive interval, a third of the TTL,
+	// randomly added to or subtracted from each interval, so that the
+	// keepalives of many clients do not line up.
+	Jitter float64
+	// OnLeaseLost is called when a lease expired or could not be kept
+	// alive until its TTL ran out, with the keys attached to it, sorted.
+	// The keys are deleted by the server with their lease. The lease is
+	// replaced by a new one for the keys put afterwards.
+	OnLeaseLost func(id LeaseID, keys []string)
+}
+
+// LeaseManager attaches many keys to a small pool of leases it grants and
+// keeps alive, rather than a lease per key, which at scale overloads the
+// lease subsystem of the cluster with grants, keepalives and expiries.
+// Keys are put on the lease holding the fewest keys, and leases are granted
+// as needed up to PoolSize.
+//
+// Each lease is kept alive with a KeepAliveOnce request every third of its
+// TTL, jittered. When a lease is lost, the keys attached to it are reported
+// to OnLeaseLost, to be put again.
+type LeaseManager struct {
+	lease Lease
+	kv    KV
+	cfg   LeaseManagerConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	leases map[LeaseID]*managedLease
+	keys   map[string]*managedLease
+	closed bool
+}
+
+type managedLease struct {
+	id   LeaseID
+	ttl  time.Duration
+	keys map[string]struct{}
+}
+
+// NewLeaseManager returns a LeaseManager granting and keeping leases alive
+// with lease, and putting keys with kv, such as a Client for both.
+func NewLeaseManager(lease Lease, kv KV, cfg LeaseManagerConfig) *LeaseManager {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultLeaseManagerTTL
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = DefaultLeaseManagerPoolSize
+	}
+	if cfg.Jitter <= 0 {
+		cfg.Jitter = DefaultLeaseManagerJitter
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LeaseManager{
+		lease:  lease,
+		kv:     kv,
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		leases: make(map[LeaseID]*managedLease),
+		keys:   make(map[string]*managedLease),
+	}
+}
+
+// Put puts a key on a lease of the pool. It accepts the options of KV.Put,
+// but for WithLease. A key put again stays on its lease.
+func (m *LeaseManager) Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error) {
+	ml, err := m.acquire(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.kv.Put(ctx, key, val, append(opts, WithLease(ml.id))...)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leases[ml.id] == ml {
+		if prev := m.keys[key]; prev != nil && prev != ml {
+			delete(prev.keys, key)
+		}
+		ml.keys[key] = struct{}{}
+		m.keys[key] = ml
+	}
+	return resp, nil
+}
+
+// Delete deletes a key, and detaches it from its lease.
+func (m *LeaseManager) Delete(ctx context.Context, key string) (*DeleteResponse, error) {
+	resp, err := m.kv.Delete(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	if ml := m.keys[key]; ml != nil {
+		delete(ml.keys, key)
+		delete(m.keys, key)
+	}
+	m.mu.Unlock()
+	return resp, nil
+}
+
+// LeaseOf returns the lease key is attached to.
+func (m *LeaseManager) LeaseOf(key string) (LeaseID, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ml := m.keys[key]; ml != nil {
+		return ml.id, true
+	}
+	return NoLease, false
+}
+
+// acquire returns the lease of key, or the lease to put it on, granting it
+// if the pool is not full.
+func (m *LeaseManager) acquire(ctx context.Context, key string) (*managedLease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrLeaseManagerClosed
+	}
+	if ml := m.keys[key]; ml != nil {
+		return ml, nil
+	}
+	if len(m.leases) < m.cfg.PoolSize {
+		resp, err := m.lease.Grant(ctx, m.cfg.TTL)
+		if err != nil {
+			return nil, err
+		}
+		ml := &managedLease{id: resp.ID, ttl: time.Duration(resp.TTL) * time.Second, keys: make(map[string]struct{})}
+		m.leases[ml.id] = ml
+		m.wg.Add(1)
+		go m.keepAlive(ml)
+		return ml, nil
+	}
+	var least *managedLease
+	for _, ml := range m.leases {
+		if least == nil || len(ml.keys) < len(least.keys) {
+			least = ml
+		}
+	}
+	return least, nil
+}
+
+func (m *LeaseManager) keepAlive(ml *managedLease) {
+	defer m.wg.Done()
+	expires := time.Now().Add(ml.ttl)
+	wait := jitterUp(ml.ttl/3, m.cfg.Jitter)
+	for {
+		select {
+		case <-time.After(wait):
+		case <-m.ctx.Done():
+			return
+		}
+		resp, err := m.lease.KeepAliveOnce(m.ctx, ml.id)
+		switch {
+		case err == nil:
+			expires = time.Now().Add(time.Duration(resp.TTL) * time.Second)
+			wait = jitterUp(time.Duration(resp.TTL)*time.Second/3, m.cfg.Jitter)
+		case m.ctx.Err() != nil:
+			return
+		case err == rpctypes.ErrLeaseNotFound || !time.Now().Add(leaseManagerRetryInterval).Before(expires):
+			m.lost(ml)
+			return
+		default:
+			wait = leaseManagerRetryInterval
+		}
+	}
+}
+
+// lost removes a lost lease from the pool, and reports its keys.
+func (m *LeaseManager) lost(ml *managedLease) {
+	m.mu.Lock()
+	delete(m.leases, ml.id)
+	keys := make([]string, 0, len(ml.keys))
+	for key := range ml.keys {
+		keys = append(keys, key)
+		delete(m.keys, key)
+	}
+	m.mu.Unlock()
+
+	sort.Strings(keys)
+	if m.cfg.OnLeaseLost != nil {
+		m.cfg.OnLeaseLost(ml.id, keys)
+	}
+}
+
+// Close stops keeping the leases alive and revokes them, deleting their
+// keys. It returns the first error revoking a lease.
+func (m *LeaseManager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	leases := m.leases
+	m.leases = make(map[LeaseID]*managedLease)
+	m.keys = make(map[string]*managedLease)
+	m.mu.Unlock()
+
+	m.cancel()
+	m.wg.Wait()
+	var err error
+	for id := range leases {
+		if _, rerr := m.lease.Revoke(ctx, id); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
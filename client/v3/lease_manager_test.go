@@ -0,0 +1,158 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// poolLease grants leases with a TTL of ttl seconds, and keeps them alive
+// until they are expired by the test.
+type poolLease struct {
+	Lease
+	ttl int64
+
+	mu      sync.Mutex
+	next    LeaseID
+	expired map[LeaseID]bool
+	revoked []LeaseID
+}
+
+func (l *poolLease) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next++
+	return &LeaseGrantResponse{ID: l.next, TTL: l.ttl}, nil
+}
+
+func (l *poolLease) KeepAliveOnce(ctx context.Context, id LeaseID) (*LeaseKeepAliveResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.expired[id] {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+	return &LeaseKeepAliveResponse{ID: id, TTL: l.ttl}, nil
+}
+
+func (l *poolLease) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.revoked = append(l.revoked, id)
+	return &LeaseRevokeResponse{}, nil
+}
+
+func (l *poolLease) expire(id LeaseID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expired[id] = true
+}
+
+// leasedKV records the lease of the keys it puts.
+type leasedKV struct {
+	KV
+	mu     sync.Mutex
+	leases map[string]LeaseID
+}
+
+func (kv *leasedKV) Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.leases[key] = OpPut(key, val, opts...).leaseID
+	return &PutResponse{}, nil
+}
+
+func TestLeaseManager(t *testing.T) {
+	lease := &poolLease{ttl: 1, expired: make(map[LeaseID]bool)}
+	kv := &leasedKV{leases: make(map[string]LeaseID)}
+	type loss struct {
+		id   LeaseID
+		keys []string
+	}
+	lostc := make(chan loss, 1)
+	m := NewLeaseManager(lease, kv, LeaseManagerConfig{
+		PoolSize:    2,
+		OnLeaseLost: func(id LeaseID, keys []string) { lostc <- loss{id, keys} },
+	})
+	ctx := context.Background()
+
+	// keys are spread over the pool
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if _, err := m.Put(ctx, key, "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := make(map[LeaseID]int)
+	for key, id := range kv.leases {
+		if mid, ok := m.LeaseOf(key); !ok || mid != id {
+			t.Fatalf("lease of %q = %v, %v, want %v", key, mid, ok, id)
+		}
+		count[id]++
+	}
+	if !reflect.DeepEqual(count, map[LeaseID]int{1: 2, 2: 2}) {
+		t.Fatalf("keys per lease = %v, want 2 on each of 2 leases", count)
+	}
+	// a key put again stays on its lease
+	id, _ := m.LeaseOf("a")
+	if _, err := m.Put(ctx, "a", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if kv.leases["a"] != id {
+		t.Fatalf("lease of a = %v, want %v", kv.leases["a"], id)
+	}
+
+	// the keys of a lost lease are reported
+	lease.expire(id)
+	var wkeys []string
+	for key, kid := range kv.leases {
+		if kid == id {
+			wkeys = append(wkeys, key)
+		}
+	}
+	select {
+	case l := <-lostc:
+		if l.id != id || len(l.keys) != 2 || l.keys[0] > l.keys[1] {
+			t.Fatalf("lost %+v, want lease %v with sorted keys %v", l, id, wkeys)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for lease loss")
+	}
+	if _, ok := m.LeaseOf("a"); ok {
+		t.Fatal("key of a lost lease still attached")
+	}
+
+	// the lost lease is replaced
+	if _, err := m.Put(ctx, "a", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if kv.leases["a"] != 3 {
+		t.Fatalf("lease of a = %v, want new lease 3", kv.leases["a"])
+	}
+
+	if err := m.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(lease.revoked) != 2 {
+		t.Fatalf("revoked %v, want the 2 leases of the pool", lease.revoked)
+	}
+	if _, err := m.Put(ctx, "e", "1"); err != ErrLeaseManagerClosed {
+		t.Fatalf("err = %v, want %v", err, ErrLeaseManagerClosed)
+	}
+}
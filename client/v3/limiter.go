@@ -0,0 +1,226 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// MethodClass is a class of requests sharing a rate limit.
+type MethodClass int
+
+const (
+	// MethodClassRead is the class of Range requests.
+	MethodClassRead MethodClass = iota
+	// MethodClassWrite is the class of the other KV requests: Put,
+	// DeleteRange, Txn and Compact.
+	MethodClassWrite
+	// MethodClassLease is the class of the Lease requests, including the
+	// creation of keepalive streams.
+	MethodClassLease
+	// MethodClassWatch is the class of the creation of watch streams.
+	MethodClassWatch
+	// MethodClassOther is the class of the remaining requests, such as the
+	// Cluster, Maintenance and Auth requests.
+	MethodClassOther
+)
+
+// methodClass returns the class of a gRPC method.
+func methodClass(method string) MethodClass {
+	switch {
+	case method == "/etcdserverpb.KV/Range":
+		return MethodClassRead
+	case strings.HasPrefix(method, "/etcdserverpb.KV/"):
+		return MethodClassWrite
+	case strings.HasPrefix(method, "/etcdserverpb.Lease/"):
+		return MethodClassLease
+	case strings.HasPrefix(method, "/etcdserverpb.Watch/"):
+		return MethodClassWatch
+	}
+	return MethodClassOther
+}
+
+// RateLimit is the limit of a token bucket: requests are sent at up to
+// Rate per second, in bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter limits the rate of the requests of a client, with a token
+// bucket per class of requests. Requests over the limit wait for a token,
+// until their context is done. A RateLimiter may be shared by clients to
+// limit them together.
+//
+// Requests are limited once, whatever the number of their retries, which
+// are limited by RetryPolicy.Budget. Streams are limited when created.
+type RateLimiter struct {
+	buckets map[MethodClass]*tokenBucket
+}
+
+// NewRateLimiter returns a RateLimiter with the given limits. Classes
+// without a limit are not limited. A limit must have a positive rate.
+func NewRateLimiter(limits map[MethodClass]RateLimit) (*RateLimiter, error) {
+	rl := &RateLimiter{buckets: make(map[MethodClass]*tokenBucket)}
+	for class, limit := range limits {
+		if !(limit.Rate > 0) {
+			return nil, fmt.Errorf("invalid rate %v of method class %d, must be positive", limit.Rate, class)
+		}
+		rl.buckets[class] = newTokenBucket(limit)
+	}
+	return rl, nil
+}
+
+// Wait waits for the rate limit of class to allow a request.
+func (rl *RateLimiter) Wait(ctx context.Context, class MethodClass) error {
+	b, ok := rl.buckets[class]
+	if !ok {
+		return nil
+	}
+	return b.wait(ctx, 1)
+}
+
+type tokenBucket struct {
+	limit RateLimit
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// reserve takes n tokens, possibly ahead of time, and returns the wait
+// until they are available.
+func (b *tokenBucket) reserve(now time.Time, n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if burst := float64(b.limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+}
+
+// cancel gives back n tokens taken by reserve.
+func (b *tokenBucket) cancel(n float64) {
+	b.mu.Lock()
+	b.tokens += n
+	b.mu.Unlock()
+}
+
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	d := b.reserve(time.Now(), n)
+	if d == 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel(n)
+		return ctx.Err()
+	}
+}
+
+// limiter limits the requests of the client with the configured rate
+// limiter and maximum of requests in flight.
+type limiter struct {
+	rate     *RateLimiter
+	inflight chan struct{}
+	wait     Float64Histogram
+}
+
+// newLimiter returns the limiter of the client, nil if neither a rate
+// limiter nor a maximum of requests in flight is configured.
+func newLimiter(rl *RateLimiter, maxInFlight int, mp MeterProvider) *limiter {
+	if rl == nil && maxInFlight <= 0 {
+		return nil
+	}
+	l := &limiter{rate: rl}
+	if maxInFlight > 0 {
+		l.inflight = make(chan struct{}, maxInFlight)
+	}
+	if mp == nil {
+		mp = noopMeterProvider{}
+	}
+	l.wait = mp.Meter(instrumentationName).Float64Histogram("etcd.client.limiter.wait", "s", "Time requests waited for the client-side limiters.")
+	return l
+}
+
+// acquire waits for the limits to allow a request. Unary requests take a
+// slot of the requests in flight, to be released with the returned
+// function.
+func (l *limiter) acquire(ctx context.Context, method string, unary bool) (func(), error) {
+	start := time.Now()
+	release := func() {}
+	var err error
+	if l.rate != nil {
+		err = l.rate.Wait(ctx, methodClass(method))
+	}
+	if err == nil && unary && l.inflight != nil {
+		select {
+		case l.inflight <- struct{}{}:
+			release = func() { <-l.inflight }
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	l.wait.Record(ctx, time.Since(start).Seconds(), methodAttr(method))
+	return release, err
+}
+
+// unaryClientInterceptor returns an interceptor limiting the requests
+// before they reach next.
+func (l *limiter) unaryClientInterceptor(next grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		release, err := l.acquire(ctx, method, true)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return next(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+// streamClientInterceptor returns an interceptor limiting the rate of the
+// streams created by next. Streams do not count as requests in flight, as
+// watches and keepalives last as long as the client.
+func (l *limiter) streamClientInterceptor(next grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if _, err := l.acquire(ctx, method, false); err != nil {
+			return nil, err
+		}
+		return next(ctx, desc, cc, method, streamer, opts...)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestMethodClass(t *testing.T) {
+	tests := map[string]MethodClass{
+		"/etcdserverpb.KV/Range":          MethodClassRead,
+		"/etcdserverpb.KV/Txn":            MethodClassWrite,
+		"/etcdserverpb.Lease/LeaseGrant":  MethodClassLease,
+		"/etcdserverpb.Watch/Watch":       MethodClassWatch,
+		"/etcdserverpb.Cluster/MemberAdd": MethodClassOther,
+	}
+	for method, want := range tests {
+		if class := methodClass(method); class != want {
+			t.Errorf("class of %s = %v, want %v", method, class, want)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 10, Burst: 2})
+	now := b.last
+	for i := 0; i < 2; i++ {
+		if d := b.reserve(now, 1); d != 0 {
+			t.Fatalf("wait of burst request %d = %v, want 0", i, d)
+		}
+	}
+	if d := b.reserve(now, 1); d != 100*time.Millisecond {
+		t.Fatalf("wait = %v, want 100ms", d)
+	}
+	// the wait for the next token is given back when canceled
+	b.cancel(1)
+	if d := b.reserve(now.Add(50*time.Millisecond), 1); d != 50*time.Millisecond {
+		t.Fatalf("wait = %v, want 50ms", d)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	rl, err := NewRateLimiter(map[MethodClass]RateLimit{MethodClassWrite: {Rate: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the burst of one request, then a wait over the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = rl.Wait(ctx, MethodClassWrite); err != nil {
+		t.Fatal(err)
+	}
+	if err = rl.Wait(ctx, MethodClassWrite); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// other classes are not limited
+	if err = rl.Wait(ctx, MethodClassRead); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewRateLimiterInvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		if _, err := NewRateLimiter(map[MethodClass]RateLimit{MethodClassWrite: {Rate: rate, Burst: 1}}); err == nil {
+			t.Errorf("rate %v: expected error", rate)
+		}
+	}
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	l := newLimiter(nil, 1, nil)
+	next := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	unaryInt := l.unaryClientInterceptor(next)
+
+	started, donec := make(chan struct{}), make(chan struct{})
+	go unaryInt(context.Background(), "/etcdserverpb.KV/Range", nil, nil, nil, func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		close(started)
+		<-donec
+		return nil
+	})
+	<-started
+
+	// a second request waits for the first one
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		return nil
+	}
+	if err := unaryInt(ctx, "/etcdserverpb.KV/Range", nil, nil, nil, invoker); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(donec)
+	if err := unaryInt(context.Background(), "/etcdserverpb.KV/Range", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewLimiterDisabled(t *testing.T) {
+	if l := newLimiter(nil, 0, nil); l != nil {
+		t.Fatalf("limiter = %+v, want nil", l)
+	}
+}
@@ -31,6 +31,26 @@ type (
 	StatusResponse     pb.StatusResponse
 	HashKVResponse     pb.HashKVResponse
 	MoveLeaderResponse pb.MoveLeaderResponse
+	DowngradeResponse  pb.DowngradeResponse
+
+	MaintenanceProgressResponse pb.MaintenanceProgressResponse
+	AlarmHistoryResponse        pb.AlarmHistoryResponse
+	ScheduleStatusResponse      pb.ScheduleStatusResponse
+	RateLimitStatusResponse     pb.RateLimitStatusResponse
+	PolicyPutResponse           pb.PolicyPutResponse
+	PolicyGetResponse           pb.PolicyGetResponse
+
+	DowngradeAction pb.DowngradeRequest_DowngradeAction
+	PolicyType      pb.PolicyType
+)
+
+const (
+	DowngradeValidate = DowngradeAction(pb.DowngradeRequest_VALIDATE)
+	DowngradeEnable   = DowngradeAction(pb.DowngradeRequest_ENABLE)
+	DowngradeCancel   = DowngradeAction(pb.DowngradeRequest_CANCEL)
+
+	PolicyMaintenance = PolicyType(pb.PolicyType_MAINTENANCE)
+	PolicyRateLimit   = PolicyType(pb.PolicyType_RATE_LIMIT)
 )
 
 type Maintenance interface {
@@ -40,6 +60,9 @@ type Maintenance interface {
 	// AlarmDisarm disarms a given alarm.
 	AlarmDisarm(ctx context.Context, m *AlarmMember) (*AlarmResponse, error)
 
+	// AlarmHistory gets the most recent alarms raised and cleared, oldest first.
+	AlarmHistory(ctx context.Context) (*AlarmHistoryResponse, error)
+
 	// Defragment releases wasted space from internal fragmentation on a given etcd member.
 	// Defragment is only needed when deleting a large number of keys and want to reclaim
 	// the resources.
@@ -62,9 +85,44 @@ type Maintenance interface {
 	// "io.ReadCloser" would error out (e.g. context.Canceled, context.DeadlineExceeded).
 	Snapshot(ctx context.Context) (io.ReadCloser, error)
 
+	// SnapshotTo writes a point-in-time snapshot of etcd to w, followed by
+	// its sha256 checksum, and returns the number of bytes written. The
+	// snapshot is verified against the checksum, failing with
+	// ErrSnapshotChecksum. A download failing with a transient error is
+	// restarted from the beginning, as a new stream sends a new snapshot,
+	// if nothing was written yet or w can be rewound and truncated, like an
+	// *os.File.
+	SnapshotTo(ctx context.Context, w io.Writer, opts ...SnapshotOption) (int64, error)
+
 	// MoveLeader requests current leader to transfer its leadership to the transferee.
 	// Request must be made to the leader.
 	MoveLeader(ctx context.Context, transfereeID uint64) (*MoveLeaderResponse, error)
+
+	// Downgrade validates, enables or cancels the downgrade of the cluster
+	// version to the given version. The version is only used to validate
+	// and to enable a downgrade.
+	Downgrade(ctx context.Context, action DowngradeAction, version string) (*DowngradeResponse, error)
+
+	// MaintenanceProgress gets the progress of the compaction and the
+	// defragmentation running on the endpoint.
+	MaintenanceProgress(ctx context.Context, endpoint string) (*MaintenanceProgressResponse, error)
+
+	// ScheduleStatus gets the maintenance policy applied by the scheduler of
+	// the endpoint and its most recent runs.
+	ScheduleStatus(ctx context.Context, endpoint string) (*ScheduleStatusResponse, error)
+
+	// RateLimitStatus gets the rate limit policy enforced by the endpoint
+	// and the requests it rejected.
+	RateLimitStatus(ctx context.Context, endpoint string) (*RateLimitStatusResponse, error)
+
+	// PolicyPut stores the policy of the given type shared by the members
+	// of the cluster, encoded as JSON, or removes it if policy is empty.
+	// Only root may store policies.
+	PolicyPut(ctx context.Context, t PolicyType, policy []byte) (*PolicyPutResponse, error)
+
+	// PolicyGet gets the stored policy of the given type, empty if there is
+	// none. Only root may get policies.
+	PolicyGet(ctx context.Context, t PolicyType) (*PolicyGetResponse, error)
 }
 
 type maintenance struct {
@@ -162,6 +220,14 @@ func (m *maintenance) AlarmDisarm(ctx context.Context, am *AlarmMember) (*AlarmR
 	return nil, toErr(ctx, err)
 }
 
+func (m *maintenance) AlarmHistory(ctx context.Context) (*AlarmHistoryResponse, error) {
+	resp, err := m.remote.AlarmHistory(ctx, &pb.AlarmHistoryRequest{}, m.callOpts...)
+	if err == nil {
+		return (*AlarmHistoryResponse)(resp), nil
+	}
+	return nil, toErr(ctx, err)
+}
+
 func (m *maintenance) Defragment(ctx context.Context, endpoint string) (*DefragmentResponse, error) {
 	remote, cancel, err := m.dial(endpoint)
 	if err != nil {
@@ -252,3 +318,64 @@ func (m *maintenance) MoveLeader(ctx context.Context, transfereeID uint64) (*Mov
 	resp, err := m.remote.MoveLeader(ctx, &pb.MoveLeaderRequest{TargetID: transfereeID}, m.callOpts...)
 	return (*MoveLeaderResponse)(resp), toErr(ctx, err)
 }
+
+func (m *maintenance) Downgrade(ctx context.Context, action DowngradeAction, version string) (*DowngradeResponse, error) {
+	req := &pb.DowngradeRequest{Action: pb.DowngradeRequest_DowngradeAction(action), Version: version}
+	resp, err := m.remote.Downgrade(ctx, req, m.callOpts...)
+	return (*DowngradeResponse)(resp), toErr(ctx, err)
+}
+
+func (m *maintenance) MaintenanceProgress(ctx context.Context, endpoint string) (*MaintenanceProgressResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.MaintenanceProgress(ctx, &pb.MaintenanceProgressRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*MaintenanceProgressResponse)(resp), nil
+}
+
+func (m *maintenance) ScheduleStatus(ctx context.Context, endpoint string) (*ScheduleStatusResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.ScheduleStatus(ctx, &pb.ScheduleStatusRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*ScheduleStatusResponse)(resp), nil
+}
+
+func (m *maintenance) RateLimitStatus(ctx context.Context, endpoint string) (*RateLimitStatusResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.RateLimitStatus(ctx, &pb.RateLimitStatusRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*RateLimitStatusResponse)(resp), nil
+}
+
+func (m *maintenance) PolicyPut(ctx context.Context, t PolicyType, policy []byte) (*PolicyPutResponse, error) {
+	resp, err := m.remote.PolicyPut(ctx, &pb.PolicyPutRequest{Type: pb.PolicyType(t), Policy: policy}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*PolicyPutResponse)(resp), nil
+}
+
+func (m *maintenance) PolicyGet(ctx context.Context, t PolicyType) (*PolicyGetResponse, error) {
+	resp, err := m.remote.PolicyGet(ctx, &pb.PolicyGetRequest{Type: pb.PolicyType(t)}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*PolicyGetResponse)(resp), nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.uber.org/zap"
+)
+
+const defaultSnapshotRetries = 3
+
+// snapshotRetryWait is the wait before a failed snapshot download is
+// restarted.
+var snapshotRetryWait = 500 * time.Millisecond
+
+// ErrSnapshotChecksum is returned by SnapshotTo when the snapshot does not
+// match its trailing sha256 checksum.
+var ErrSnapshotChecksum = errors.New("clientv3: snapshot checksum mismatch")
+
+// SnapshotProgress is the progress of a snapshot download.
+type SnapshotProgress struct {
+	// Written is the number of bytes written.
+	Written int64
+	// Remaining is the number of bytes of the database left to receive,
+	// as reported by the server. The checksum follows them.
+	Remaining int64
+	// Attempt is the attempt of the download, starting at 1.
+	Attempt int
+}
+
+type snapshotOptions struct {
+	progress  func(SnapshotProgress)
+	bandwidth int64
+	retries   int
+}
+
+// SnapshotOption configures SnapshotTo.
+type SnapshotOption func(*snapshotOptions)
+
+// WithSnapshotProgress calls fn after each chunk of the snapshot is
+// written.
+func WithSnapshotProgress(fn func(SnapshotProgress)) SnapshotOption {
+	return func(o *snapshotOptions) { o.progress = fn }
+}
+
+// WithSnapshotBandwidth limits the download to bytesPerSecond, so that the
+// snapshot of a large database does not saturate the network of the
+// member serving it.
+func WithSnapshotBandwidth(bytesPerSecond int64) SnapshotOption {
+	return func(o *snapshotOptions) { o.bandwidth = bytesPerSecond }
+}
+
+// WithSnapshotRetries sets the number of times a download failing with a
+// transient error is restarted. It defaults to 3.
+func WithSnapshotRetries(n int) SnapshotOption {
+	return func(o *snapshotOptions) { o.retries = n }
+}
+
+// snapshotRestarter is a writer a download can be restarted on, such as
+// an *os.File.
+type snapshotRestarter interface {
+	io.Seeker
+	Truncate(size int64) error
+}
+
+func (m *maintenance) SnapshotTo(ctx context.Context, w io.Writer, opts ...SnapshotOption) (int64, error) {
+	o := snapshotOptions{retries: defaultSnapshotRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var bw *tokenBucket
+	if o.bandwidth > 0 {
+		bw = newTokenBucket(RateLimit{Rate: float64(o.bandwidth), Burst: int(o.bandwidth)})
+	}
+	restarter, _ := w.(snapshotRestarter)
+	for attempt := 1; ; attempt++ {
+		n, err := m.snapshotTo(ctx, w, bw, attempt, o.progress)
+		if err == nil {
+			return n, nil
+		}
+		// the server cannot resume a snapshot, as a new stream sends a new
+		// snapshot; a partially written one is discarded
+		if attempt > o.retries || !isSafeRetryImmutableRPC(err) || ctx.Err() != nil || (n > 0 && restarter == nil) {
+			return n, toErr(ctx, err)
+		}
+		if n > 0 {
+			if _, serr := restarter.Seek(0, io.SeekStart); serr != nil {
+				return n, serr
+			}
+			if terr := restarter.Truncate(0); terr != nil {
+				return n, terr
+			}
+		}
+		m.lg.Warn("failed to download snapshot; restarting", zap.Int("attempt", attempt), zap.Error(err))
+		select {
+		case <-time.After(snapshotRetryWait):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func (m *maintenance) snapshotTo(ctx context.Context, w io.Writer, bw *tokenBucket, attempt int, progress func(SnapshotProgress)) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ss, err := m.remote.Snapshot(ctx, &pb.SnapshotRequest{}, append(m.callOpts, withMax(defaultStreamMaxRetries))...)
+	if err != nil {
+		return 0, err
+	}
+	cw := &checksumWriter{w: w, h: sha256.New()}
+	for {
+		resp, err := ss.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return cw.n, err
+		}
+		if bw != nil {
+			if err := bw.wait(ctx, float64(len(resp.Blob))); err != nil {
+				return cw.n, err
+			}
+		}
+		if _, err := cw.Write(resp.Blob); err != nil {
+			return cw.n, err
+		}
+		if progress != nil {
+			progress(SnapshotProgress{Written: cw.n, Remaining: int64(resp.RemainingBytes), Attempt: attempt})
+		}
+	}
+	if !cw.verify() {
+		return cw.n, ErrSnapshotChecksum
+	}
+	return cw.n, nil
+}
+
+// checksumWriter hashes the bytes written to w but the last sha256.Size
+// ones, the checksum of the snapshot.
+type checksumWriter struct {
+	w    io.Writer
+	h    hash.Hash
+	tail []byte
+	n    int64
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	if err != nil {
+		return n, err
+	}
+	buf := append(cw.tail, p...)
+	if len(buf) > sha256.Size {
+		cw.h.Write(buf[:len(buf)-sha256.Size])
+		buf = buf[len(buf)-sha256.Size:]
+	}
+	cw.tail = append([]byte(nil), buf...)
+	return n, nil
+}
+
+func (cw *checksumWriter) verify() bool {
+	return len(cw.tail) == sha256.Size && bytes.Equal(cw.h.Sum(nil), cw.tail)
+}
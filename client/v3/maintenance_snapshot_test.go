@@ -0,0 +1,123 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// snapshotRemote serves the snapshot streams of its attempts in turn.
+type snapshotRemote struct {
+	pb.MaintenanceClient
+	attempts [][]*pb.SnapshotResponse
+	errs     []error
+}
+
+func (r *snapshotRemote) Snapshot(ctx context.Context, in *pb.SnapshotRequest, opts ...grpc.CallOption) (pb.Maintenance_SnapshotClient, error) {
+	ss := &snapshotStream{resps: r.attempts[0], err: r.errs[0]}
+	r.attempts, r.errs = r.attempts[1:], r.errs[1:]
+	return ss, nil
+}
+
+type snapshotStream struct {
+	grpc.ClientStream
+	resps []*pb.SnapshotResponse
+	err   error
+}
+
+func (ss *snapshotStream) Recv() (*pb.SnapshotResponse, error) {
+	if len(ss.resps) == 0 {
+		return nil, ss.err
+	}
+	resp := ss.resps[0]
+	ss.resps = ss.resps[1:]
+	return resp, nil
+}
+
+// snapshotFile is a buffer that can be rewound and truncated.
+type snapshotFile struct {
+	bytes.Buffer
+}
+
+func (f *snapshotFile) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (f *snapshotFile) Truncate(size int64) error {
+	f.Buffer.Truncate(int(size))
+	return nil
+}
+
+func snapshotResponses(db []byte, chunk int) []*pb.SnapshotResponse {
+	var resps []*pb.SnapshotResponse
+	for sent := 0; sent < len(db); sent += chunk {
+		end := sent + chunk
+		if end > len(db) {
+			end = len(db)
+		}
+		resps = append(resps, &pb.SnapshotResponse{RemainingBytes: uint64(len(db) - end), Blob: db[sent:end]})
+	}
+	sha := sha256.Sum256(db)
+	return append(resps, &pb.SnapshotResponse{Blob: sha[:]})
+}
+
+func TestSnapshotTo(t *testing.T) {
+	defer func(wait time.Duration) { snapshotRetryWait = wait }(snapshotRetryWait)
+	snapshotRetryWait = 0
+
+	db := bytes.Repeat([]byte("etcd"), 100)
+	resps := snapshotResponses(db, 64)
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	// the download is restarted after a transient failure
+	m := &maintenance{lg: zap.NewNop(), remote: &snapshotRemote{
+		attempts: [][]*pb.SnapshotResponse{resps[:2], resps},
+		errs:     []error{unavailable, io.EOF},
+	}}
+	var f snapshotFile
+	var last SnapshotProgress
+	n, err := m.SnapshotTo(context.TODO(), &f, WithSnapshotProgress(func(p SnapshotProgress) { last = p }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(db) + sha256.Size); n != want || int64(f.Len()) != want {
+		t.Fatalf("wrote %d bytes, %d in the file, want %d", n, f.Len(), want)
+	}
+	if last != (SnapshotProgress{Written: n, Attempt: 2}) {
+		t.Fatalf("last progress = %+v", last)
+	}
+
+	// a partial download cannot be restarted on a writer that cannot be rewound
+	m.remote = &snapshotRemote{attempts: [][]*pb.SnapshotResponse{resps[:2]}, errs: []error{unavailable}}
+	if _, err = m.SnapshotTo(context.TODO(), &bytes.Buffer{}); err != unavailable {
+		t.Fatalf("err = %v, want %v", err, unavailable)
+	}
+
+	// a corrupted snapshot fails verification
+	corrupted := snapshotResponses(db, 64)
+	corrupted[0] = &pb.SnapshotResponse{Blob: []byte("corrupted")}
+	m.remote = &snapshotRemote{attempts: [][]*pb.SnapshotResponse{corrupted}, errs: []error{io.EOF}}
+	if _, err = m.SnapshotTo(context.TODO(), &bytes.Buffer{}); err != ErrSnapshotChecksum {
+		t.Fatalf("err = %v, want %v", err, ErrSnapshotChecksum)
+	}
+}
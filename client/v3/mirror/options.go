@@ -0,0 +1,101 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// Option configures a Syncer.
+type Option func(*syncer)
+
+// WithPrefixes syncs the keys with the given prefixes in addition to the
+// prefix of the Syncer. The updates of all the prefixes are watched by a
+// single watch over the range covering them, so the events of other keys
+// in that range are received, and then dropped, too.
+func WithPrefixes(prefixes ...string) Option {
+	return func(s *syncer) { s.extraPrefixes = append(s.extraPrefixes, prefixes...) }
+}
+
+// WithExcludePrefixes skips the keys with any of the given prefixes.
+func WithExcludePrefixes(prefixes ...string) Option {
+	return func(s *syncer) { s.excludes = append(s.excludes, prefixes...) }
+}
+
+// WithFilter skips the key-values for which filter returns false. It is
+// called with the key-values as stored in the cluster, before any
+// transformation, and with the deleted key-values of delete events.
+func WithFilter(filter func(kv *mvccpb.KeyValue) bool) Option {
+	return func(s *syncer) { s.filter = filter }
+}
+
+// TransformFunc transforms the key and value of a key-value. The value of
+// deleted key-values is empty.
+type TransformFunc func(key, value []byte) (newKey, newValue []byte)
+
+// WithTransform transforms the key-values sent by the Syncer, e.g. to
+// rewrite their prefix or to encrypt their values. The key-values of the
+// responses are copies; the transformation does not modify them.
+func WithTransform(transform TransformFunc) Option {
+	return func(s *syncer) { s.transform = transform }
+}
+
+// WithRateLimit limits the number of key-values and events sent by the
+// Syncer per second. Responses are sent whole, each waiting for the time
+// taken by the previous ones at the given rate.
+func WithRateLimit(keysPerSecond float64) Option {
+	return func(s *syncer) { s.rate = keysPerSecond }
+}
+
+// pacer paces the keys sent at a rate.
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(keysPerSecond float64) *pacer {
+	if keysPerSecond <= 0 {
+		return nil
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / keysPerSecond)}
+}
+
+// wait waits for the time taken by the previous keys, then accounts for
+// the next n keys.
+func (p *pacer) wait(ctx context.Context, n int) error {
+	if p == nil || n == 0 {
+		return nil
+	}
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	d := p.next.Sub(now)
+	p.next = p.next.Add(time.Duration(n) * p.interval)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
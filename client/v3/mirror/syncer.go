@@ -17,7 +17,11 @@ package mirror
 
 import (
 	"context"
+	"sort"
+	"strings"
+	"sync"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/client/v3"
 )
 
@@ -33,17 +37,45 @@ type Syncer interface {
 	// SyncUpdates syncs the updates of the key-value state.
 	// The update events are sent through the returned chan.
 	SyncUpdates(ctx context.Context) clientv3.WatchChan
+	// Progress returns the progress of the sync so far.
+	Progress() Progress
+}
+
+// Progress is the progress of a Syncer.
+type Progress struct {
+	// Revision is the revision the key-value state is synced up to.
+	Revision int64
+	// BaseKeys is the number of keys sent by SyncBase.
+	BaseKeys int64
+	// Updates is the number of events sent by SyncUpdates.
+	Updates int64
 }
 
 // NewSyncer creates a Syncer.
-func NewSyncer(c *clientv3.Client, prefix string, rev int64) Syncer {
-	return &syncer{c: c, prefix: prefix, rev: rev}
+func NewSyncer(c *clientv3.Client, prefix string, rev int64, opts ...Option) Syncer {
+	s := &syncer{c: c, prefix: prefix, rev: rev}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.prefixes = normalizePrefixes(append([]string{prefix}, s.extraPrefixes...))
+	return s
 }
 
 type syncer struct {
 	c      *clientv3.Client
 	rev    int64
 	prefix string
+
+	extraPrefixes []string
+	// prefixes are the sorted prefixes synced, none of them under another.
+	prefixes  []string
+	excludes  []string
+	filter    func(kv *mvccpb.KeyValue) bool
+	transform TransformFunc
+	rate      float64
+
+	mu       sync.Mutex
+	progress Progress
 }
 
 func (s *syncer) SyncBase(ctx context.Context) (<-chan clientv3.GetResponse, chan error) {
@@ -66,38 +98,60 @@ func (s *syncer) SyncBase(ctx context.Context) (<-chan clientv3.GetResponse, cha
 		defer close(respchan)
 		defer close(errchan)
 
-		var key string
-
-		opts := []clientv3.OpOption{clientv3.WithLimit(batchLimit), clientv3.WithRev(s.rev)}
-
-		if len(s.prefix) == 0 {
-			// If len(s.prefix) == 0, we will sync the entire key-value space.
-			// We then range from the smallest key (0x00) to the end.
-			opts = append(opts, clientv3.WithFromKey())
-			key = "\x00"
-		} else {
-			// If len(s.prefix) != 0, we will sync key-value space with given prefix.
-			// We then range from the prefix to the next prefix if exists. Or we will
-			// range from the prefix to the end if the next prefix does not exists.
-			opts = append(opts, clientv3.WithRange(clientv3.GetPrefixRangeEnd(s.prefix)))
-			key = s.prefix
-		}
+		p := newPacer(s.rate)
+		for i, prefix := range s.prefixes {
+			var key string
 
-		for {
-			resp, err := s.c.Get(ctx, key, opts...)
-			if err != nil {
-				errchan <- err
-				return
+			opts := []clientv3.OpOption{clientv3.WithLimit(batchLimit), clientv3.WithRev(s.rev)}
+
+			if len(prefix) == 0 {
+				// If len(prefix) == 0, we will sync the entire key-value space.
+				// We then range from the smallest key (0x00) to the end.
+				opts = append(opts, clientv3.WithFromKey())
+				key = "\x00"
+			} else {
+				// If len(prefix) != 0, we will sync key-value space with given prefix.
+				// We then range from the prefix to the next prefix if exists. Or we will
+				// range from the prefix to the end if the next prefix does not exists.
+				opts = append(opts, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)))
+				key = prefix
 			}
 
-			respchan <- *resp
+			for {
+				resp, err := s.c.Get(ctx, key, opts...)
+				if err != nil {
+					errchan <- err
+					return
+				}
 
-			if !resp.More {
-				return
+				more := resp.More
+				var last []byte
+				if len(resp.Kvs) > 0 {
+					last = resp.Kvs[len(resp.Kvs)-1].Key
+				}
+				resp.Kvs = s.apply(resp.Kvs)
+				// the responses of all but the last prefix are followed by more
+				resp.More = more || i < len(s.prefixes)-1
+
+				if err := p.wait(ctx, len(resp.Kvs)); err != nil {
+					errchan <- err
+					return
+				}
+				respchan <- *resp
+				s.mu.Lock()
+				s.progress.BaseKeys += int64(len(resp.Kvs))
+				s.mu.Unlock()
+
+				if !more {
+					break
+				}
+				// move to next key
+				key = string(append(last, 0))
 			}
-			// move to next key
-			key = string(append(resp.Kvs[len(resp.Kvs)-1].Key, 0))
 		}
+		s.mu.Lock()
+		s.progress.Revision = s.rev
+		s.mu.Unlock()
 	}()
 
 	return respchan, errchan
@@ -107,5 +161,141 @@ func (s *syncer) SyncUpdates(ctx context.Context) clientv3.WatchChan {
 	if s.rev == 0 {
 		panic("unexpected revision = 0. Calling SyncUpdates before SyncBase finishes?")
 	}
-	return s.c.Watch(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithRev(s.rev+1))
+	// the prefixes are watched by a single watch covering all of them, so
+	// that the events of a revision are sent together and in order.
+	key, end := watchRange(s.prefixes)
+	opts := []clientv3.OpOption{clientv3.WithRev(s.rev + 1)}
+	switch end {
+	case "":
+		opts = append(opts, clientv3.WithPrefix())
+	case "\x00":
+		opts = append(opts, clientv3.WithFromKey())
+	default:
+		opts = append(opts, clientv3.WithRange(end))
+	}
+	wch := s.c.Watch(ctx, key, opts...)
+
+	respchan := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(respchan)
+
+		p := newPacer(s.rate)
+		for wr := range wch {
+			n := len(wr.Events)
+			events := make([]*clientv3.Event, 0, n)
+			for _, ev := range wr.Events {
+				if kvs := s.apply([]*mvccpb.KeyValue{ev.Kv}); len(kvs) > 0 {
+					nev := *ev
+					nev.Kv = kvs[0]
+					events = append(events, &nev)
+				}
+			}
+			wr.Events = events
+
+			s.mu.Lock()
+			if wr.Header.Revision > s.progress.Revision && wr.Err() == nil {
+				s.progress.Revision = wr.Header.Revision
+			}
+			s.mu.Unlock()
+			if n > 0 && len(events) == 0 {
+				// all the events are filtered out
+				continue
+			}
+
+			if p.wait(ctx, len(events)) != nil {
+				return
+			}
+			s.mu.Lock()
+			s.progress.Updates += int64(len(events))
+			s.mu.Unlock()
+			select {
+			case respchan <- wr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return respchan
+}
+
+func (s *syncer) Progress() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progress
+}
+
+// apply filters and transforms the given key-values in place.
+func (s *syncer) apply(kvs []*mvccpb.KeyValue) []*mvccpb.KeyValue {
+	if len(s.prefixes) == 1 && len(s.excludes) == 0 && s.filter == nil && s.transform == nil {
+		return kvs
+	}
+	out := kvs[:0]
+	for _, kv := range kvs {
+		if !s.keep(kv) {
+			continue
+		}
+		if s.transform != nil {
+			nkv := *kv
+			nkv.Key, nkv.Value = s.transform(kv.Key, kv.Value)
+			kv = &nkv
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func (s *syncer) keep(kv *mvccpb.KeyValue) bool {
+	key := string(kv.Key)
+	if !hasAnyPrefix(key, s.prefixes) {
+		return false
+	}
+	for _, exclude := range s.excludes {
+		if strings.HasPrefix(key, exclude) {
+			return false
+		}
+	}
+	return s.filter == nil || s.filter(kv)
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePrefixes sorts and deduplicates the prefixes, dropping those
+// under another prefix, so that no key is synced twice.
+func normalizePrefixes(prefixes []string) []string {
+	sorted := append([]string(nil), prefixes...)
+	sort.Strings(sorted)
+	var out []string
+	for _, prefix := range sorted {
+		if len(out) > 0 && strings.HasPrefix(prefix, out[len(out)-1]) {
+			continue
+		}
+		out = append(out, prefix)
+	}
+	return out
+}
+
+// watchRange returns the smallest range covering the sorted prefixes. An
+// empty end means the range of the key as a prefix, and "\x00" the range
+// from the key to the end of the key-value space.
+func watchRange(prefixes []string) (key, end string) {
+	if len(prefixes) == 1 {
+		return prefixes[0], ""
+	}
+	key = prefixes[0]
+	for _, prefix := range prefixes {
+		pend := clientv3.GetPrefixRangeEnd(prefix)
+		if pend == "\x00" || end == "\x00" {
+			end = "\x00"
+		} else if pend > end {
+			end = pend
+		}
+	}
+	return key, end
 }
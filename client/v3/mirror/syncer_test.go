@@ -0,0 +1,93 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestNormalizePrefixes(t *testing.T) {
+	tests := []struct {
+		prefixes   []string
+		wprefixes  []string
+		wkey, wend string
+	}{
+		{[]string{"foo"}, []string{"foo"}, "foo", ""},
+		{[]string{"foo", "bar", "foobar"}, []string{"bar", "foo"}, "bar", "fop"},
+		{[]string{"foo", ""}, []string{""}, "", ""},
+		{[]string{"a", "\xff"}, []string{"a", "\xff"}, "a", "\x00"},
+	}
+	for i, tt := range tests {
+		prefixes := normalizePrefixes(tt.prefixes)
+		if !reflect.DeepEqual(prefixes, tt.wprefixes) {
+			t.Errorf("#%d: prefixes = %q, want %q", i, prefixes, tt.wprefixes)
+		}
+		if key, end := watchRange(prefixes); key != tt.wkey || end != tt.wend {
+			t.Errorf("#%d: range = [%q, %q), want [%q, %q)", i, key, end, tt.wkey, tt.wend)
+		}
+	}
+}
+
+func TestSyncerApply(t *testing.T) {
+	s := NewSyncer(nil, "foo", 1,
+		WithPrefixes("bar"),
+		WithExcludePrefixes("foo/skip"),
+		WithFilter(func(kv *mvccpb.KeyValue) bool { return string(kv.Value) != "drop" }),
+		WithTransform(func(key, value []byte) ([]byte, []byte) {
+			return append([]byte("dst/"), key...), value
+		}),
+	).(*syncer)
+
+	kvs := []*mvccpb.KeyValue{
+		{Key: []byte("bar/1"), Value: []byte("v")},
+		{Key: []byte("baz/1"), Value: []byte("v")},
+		{Key: []byte("foo/1"), Value: []byte("drop")},
+		{Key: []byte("foo/2"), Value: []byte("v")},
+		{Key: []byte("foo/skip/1"), Value: []byte("v")},
+	}
+	var keys []string
+	for _, kv := range s.apply(kvs) {
+		keys = append(keys, string(kv.Key))
+	}
+	wkeys := []string{"dst/bar/1", "dst/foo/2"}
+	if !reflect.DeepEqual(keys, wkeys) {
+		t.Fatalf("keys = %q, want %q", keys, wkeys)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	p := newPacer(100)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := p.wait(context.Background(), 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first 10 keys take 100ms at 100 keys per second
+	if took := time.Since(start); took < 90*time.Millisecond {
+		t.Fatalf("took %v, want at least 100ms", took)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.wait(ctx, 1); err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -28,6 +28,7 @@
 //	cli.KV = namespace.NewKV(cli.KV, "my-prefix/")
 //	cli.Watcher = namespace.NewWatcher(cli.Watcher, "my-prefix/")
 //	cli.Lease = namespace.NewLease(cli.Lease, "my-prefix/")
+//	cli.Maintenance = namespace.NewMaintenance(cli.Maintenance, "my-prefix/")
 //
 // Now calls using 'cli' will namespace / prefix all keys with "my-prefix/":
 //
@@ -40,4 +41,11 @@
 //	fmt.Printf("%s\n", resp.Kvs[0].Value)
 //	// Output: 456
 //
+// Namespaces stack: wrapping an already namespaced interface appends the
+// new prefix to the existing one, so that
+//
+//	namespace.NewKV(namespace.NewKV(kv, "a/"), "b/")
+//
+// is the same as namespace.NewKV(kv, "a/b/"), with a single translation.
+//
 package namespace
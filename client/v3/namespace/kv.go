@@ -30,6 +30,10 @@ type kvPrefix struct {
 // NewKV wraps a KV instance so that all requests
 // are prefixed with a given string.
 func NewKV(kv clientv3.KV, prefix string) clientv3.KV {
+	// flatten stacked namespaces into a single prefix
+	if inner, ok := kv.(*kvPrefix); ok {
+		return &kvPrefix{inner.KV, inner.pfx + prefix}
+	}
 	return &kvPrefix{kv, prefix}
 }
 
@@ -29,6 +29,9 @@ type leasePrefix struct {
 // NewLease wraps a Lease interface to filter for only keys with a prefix
 // and remove that prefix when fetching attached keys through TimeToLive.
 func NewLease(l clientv3.Lease, prefix string) clientv3.Lease {
+	if inner, ok := l.(*leasePrefix); ok {
+		return &leasePrefix{inner.Lease, append(append([]byte{}, inner.pfx...), prefix...)}
+	}
 	return &leasePrefix{l, []byte(prefix)}
 }
 
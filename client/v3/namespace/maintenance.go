@@ -0,0 +1,36 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"go.etcd.io/etcd/client/v3"
+)
+
+type maintenancePrefix struct {
+	clientv3.Maintenance
+	pfx string
+}
+
+// NewMaintenance wraps a Maintenance interface so that its requests
+// carrying keys are prefixed with a given string. None of the current
+// maintenance requests carry keys, so all of them are passed through;
+// in particular, HashKV and Snapshot cover the whole keyspace, not only
+// the namespace.
+func NewMaintenance(m clientv3.Maintenance, prefix string) clientv3.Maintenance {
+	if inner, ok := m.(*maintenancePrefix); ok {
+		return &maintenancePrefix{inner.Maintenance, inner.pfx + prefix}
+	}
+	return &maintenancePrefix{m, prefix}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// keysLease returns keys for any lease.
+type keysLease struct {
+	clientv3.Lease
+	keys [][]byte
+}
+
+func (l *keysLease) TimeToLive(ctx context.Context, id clientv3.LeaseID, opts ...clientv3.LeaseOption) (*clientv3.LeaseTimeToLiveResponse, error) {
+	return &clientv3.LeaseTimeToLiveResponse{ID: id, Keys: l.keys}, nil
+}
+
+func TestStackedNamespaces(t *testing.T) {
+	var kv clientv3.KV
+	if nkv := NewKV(NewKV(kv, "a/"), "b/").(*kvPrefix); nkv.pfx != "a/b/" || nkv.KV != nil {
+		t.Errorf("stacked kv prefix = %q, want %q on the unwrapped kv", nkv.pfx, "a/b/")
+	}
+	var w clientv3.Watcher
+	if nw := NewWatcher(NewWatcher(w, "a/"), "b/").(*watcherPrefix); nw.pfx != "a/b/" || nw.Watcher != nil {
+		t.Errorf("stacked watcher prefix = %q, want %q on the unwrapped watcher", nw.pfx, "a/b/")
+	}
+	var m clientv3.Maintenance
+	if nm := NewMaintenance(NewMaintenance(m, "a/"), "b/").(*maintenancePrefix); nm.pfx != "a/b/" || nm.Maintenance != nil {
+		t.Errorf("stacked maintenance prefix = %q, want %q on the unwrapped maintenance", nm.pfx, "a/b/")
+	}
+
+	l := &keysLease{keys: [][]byte{[]byte("a/b/x"), []byte("a/c/y"), []byte("a/b"), []byte("z")}}
+	resp, err := NewLease(NewLease(l, "a/"), "b/").TimeToLive(context.TODO(), 1, clientv3.WithAttachedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{[]byte("x")}; !reflect.DeepEqual(resp.Keys, want) {
+		t.Errorf("keys = %q, want %q", resp.Keys, want)
+	}
+}
@@ -32,8 +32,12 @@ type watcherPrefix struct {
 
 // NewWatcher wraps a Watcher instance so that all Watch requests
 // are prefixed with a given string and all Watch responses have
-// the prefix removed.
+// the prefix removed. Responses without events, such as progress
+// notifications, are passed through as is.
 func NewWatcher(w clientv3.Watcher, prefix string) clientv3.Watcher {
+	if inner, ok := w.(*watcherPrefix); ok {
+		return &watcherPrefix{Watcher: inner.Watcher, pfx: inner.pfx + prefix, stopc: make(chan struct{})}
+	}
 	return &watcherPrefix{Watcher: w, pfx: prefix, stopc: make(chan struct{})}
 }
 
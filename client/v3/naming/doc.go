@@ -53,4 +53,22 @@
 //		return r.Update(c.Ctx(), service, naming.Update{Op: naming.Add, Addr: addr}, clientv3.WithLease(lid))
 //	}
 //
+// A ServiceResolver registers endpoints with a weight and attributes, under
+// a lease kept alive until the registration is closed:
+//
+//	func etcdRegister(c *clientv3.Client, service, addr string) (*clientv3.Registration, error) {
+//		r := &etcdnaming.ServiceResolver{Client: c}
+//		ep := etcdnaming.Endpoint{Addr: addr, Weight: 2, Attributes: map[string]string{"zone": "a"}}
+//		return r.Register(c.Ctx(), service, ep, 10)
+//	}
+//
+// and resolves targets listing one or more services, spreading the
+// connections of a balancer over their endpoints by weight:
+//
+//	func etcdDialServices(c *clientv3.Client, services ...string) (*grpc.ClientConn, error) {
+//		r := &etcdnaming.ServiceResolver{Client: c}
+//		b := grpc.RoundRobin(r)
+//		return grpc.Dial(strings.Join(services, ","), grpc.WithBalancer(b))
+//	}
+//
 package naming
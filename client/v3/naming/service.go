@@ -0,0 +1,271 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package naming
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	etcd "go.etcd.io/etcd/client/v3"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/naming"
+	"google.golang.org/grpc/status"
+)
+
+// MaxWeight is the largest weight an endpoint is resolved with.
+const MaxWeight = 100
+
+// Endpoint is an address registered for a service. It is stored as JSON
+// under the key of the address, as the updates of GRPCResolver are, so
+// that a GRPCResolver resolves it too, without its weight and attributes.
+type Endpoint struct {
+	Addr string
+	// Weight is the share of the connections to the service made to the
+	// endpoint, relative to its other endpoints. Zero is taken as 1.
+	Weight int `json:",omitempty"`
+	// Attributes describe the endpoint, such as its zone or version.
+	Attributes map[string]string `json:",omitempty"`
+}
+
+func (ep *Endpoint) weight() int {
+	switch {
+	case ep.Weight <= 0:
+		return 1
+	case ep.Weight > MaxWeight:
+		return MaxWeight
+	}
+	return ep.Weight
+}
+
+// Instance is the metadata of the updates of a ServiceResolver. As a gRPC
+// balancer spreads its connections evenly over the addresses it resolves,
+// an endpoint of weight n is resolved as n addresses, of replicas 0 to
+// n-1.
+type Instance struct {
+	Service string
+	Replica int
+}
+
+// ServiceResolver registers endpoints of services, and resolves targets
+// listing one or more services to their endpoints. It is a naming.Resolver
+// for the balancers of gRPC, such as grpc.RoundRobin.
+type ServiceResolver struct {
+	// Client is an initialized etcd client.
+	Client *etcd.Client
+}
+
+// Register registers ep for service under a lease of ttl seconds, which
+// is kept alive until the registration is closed and replaced if it is
+// lost, so that the endpoint is resolved as long as its process lives. See
+// Client.Register for the options.
+func (sr *ServiceResolver) Register(ctx context.Context, service string, ep Endpoint, ttl int64, opts ...etcd.RegisterOption) (*etcd.Registration, error) {
+	v, err := json.Marshal(ep)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return sr.Client.Register(ctx, service+"/"+ep.Addr, string(v), ttl, opts...), nil
+}
+
+// Endpoints returns the endpoints registered for service.
+func (sr *ServiceResolver) Endpoints(ctx context.Context, service string) ([]Endpoint, error) {
+	resp, err := sr.Client.Get(ctx, service+"/", etcd.WithPrefix(), etcd.WithSerializable())
+	if err != nil {
+		return nil, err
+	}
+	eps := make([]Endpoint, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var ep Endpoint
+		if err := json.Unmarshal(kv.Value, &ep); err != nil {
+			continue
+		}
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
+
+// Resolve returns a naming.Watcher of the endpoints of target, a comma
+// separated list of services. Its updates have an Instance as metadata.
+func (sr *ServiceResolver) Resolve(target string) (naming.Watcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &serviceWatcher{
+		c:         sr.Client,
+		services:  strings.Split(target, ","),
+		ctx:       ctx,
+		cancel:    cancel,
+		endpoints: make(map[string]resolvedEndpoint),
+	}
+	return w, nil
+}
+
+// resolvedEndpoint is the address of an endpoint, and the number of
+// replicas it is resolved as.
+type resolvedEndpoint struct {
+	addr     string
+	replicas int
+}
+
+// serviceResponse is a watch response on the endpoints of a service.
+type serviceResponse struct {
+	service string
+	wr      etcd.WatchResponse
+	closed  bool
+}
+
+type serviceWatcher struct {
+	c        *etcd.Client
+	services []string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	respc    chan serviceResponse
+	err      error
+
+	// endpoints are the endpoints resolved so far, by key.
+	endpoints map[string]resolvedEndpoint
+}
+
+// Next gets the next set of updates from the etcd resolver.
+// Calls to Next should be serialized; concurrent calls are not safe since
+// there is no way to reconcile the update ordering.
+func (sw *serviceWatcher) Next() ([]*naming.Update, error) {
+	if sw.respc == nil {
+		// first Next() returns all addresses
+		return sw.firstNext()
+	}
+	if sw.err != nil {
+		return nil, sw.err
+	}
+
+	var sresp serviceResponse
+	select {
+	case sresp = <-sw.respc:
+	case <-sw.ctx.Done():
+		sresp.closed = true
+	}
+	if sresp.closed {
+		sw.err = status.Error(codes.Unavailable, ErrWatcherClosed.Error())
+		return nil, sw.err
+	}
+	if sw.err = sresp.wr.Err(); sw.err != nil {
+		return nil, sw.err
+	}
+
+	var updates []*naming.Update
+	for _, e := range sresp.wr.Events {
+		var ep *Endpoint
+		if e.Type == etcd.EventTypePut {
+			ep = &Endpoint{}
+			if err := json.Unmarshal(e.Kv.Value, ep); err != nil {
+				// no longer a valid endpoint
+				ep = nil
+			}
+		}
+		updates = append(updates, sw.resolve(sresp.service, string(e.Kv.Key), ep)...)
+	}
+	return updates, nil
+}
+
+func (sw *serviceWatcher) firstNext() ([]*naming.Update, error) {
+	// Use serialized requests so resolution still works if the target etcd
+	// server is partitioned away from the quorum, all at the revision of
+	// the first one.
+	var rev int64
+	var updates []*naming.Update
+	for _, service := range sw.services {
+		opts := []etcd.OpOption{etcd.WithPrefix(), etcd.WithSerializable()}
+		if rev > 0 {
+			opts = append(opts, etcd.WithRev(rev))
+		}
+		resp, err := sw.c.Get(sw.ctx, service+"/", opts...)
+		if sw.err = err; err != nil {
+			return nil, err
+		}
+		rev = resp.Header.Revision
+		for _, kv := range resp.Kvs {
+			var ep Endpoint
+			if err := json.Unmarshal(kv.Value, &ep); err != nil {
+				continue
+			}
+			updates = append(updates, sw.resolve(service, string(kv.Key), &ep)...)
+		}
+	}
+
+	sw.respc = make(chan serviceResponse)
+	for _, service := range sw.services {
+		opts := []etcd.OpOption{etcd.WithRev(rev + 1), etcd.WithPrefix()}
+		go sw.forward(service, sw.c.Watch(sw.ctx, service+"/", opts...))
+	}
+	return updates, nil
+}
+
+// forward hands the responses of the watch on the endpoints of service
+// over to Next, until the watch is closed.
+func (sw *serviceWatcher) forward(service string, wch etcd.WatchChan) {
+	for wr := range wch {
+		select {
+		case sw.respc <- serviceResponse{service: service, wr: wr}:
+		case <-sw.ctx.Done():
+			return
+		}
+	}
+	select {
+	case sw.respc <- serviceResponse{service: service, closed: true}:
+	case <-sw.ctx.Done():
+	}
+}
+
+// resolve sets the endpoint of key to ep, or removes it if ep is nil, and
+// returns the updates of its replicas.
+func (sw *serviceWatcher) resolve(service, key string, ep *Endpoint) []*naming.Update {
+	prev := sw.endpoints[key]
+	replicas, kept := 0, 0
+	if ep != nil {
+		replicas = ep.weight()
+		if ep.Addr == prev.addr {
+			kept = min(replicas, prev.replicas)
+		}
+	}
+	var updates []*naming.Update
+	for i := kept; i < prev.replicas; i++ {
+		updates = append(updates, &naming.Update{
+			Op:       naming.Delete,
+			Addr:     prev.addr,
+			Metadata: Instance{Service: service, Replica: i},
+		})
+	}
+	for i := kept; i < replicas; i++ {
+		updates = append(updates, &naming.Update{
+			Op:       naming.Add,
+			Addr:     ep.Addr,
+			Metadata: Instance{Service: service, Replica: i},
+		})
+	}
+	if ep == nil {
+		delete(sw.endpoints, key)
+	} else {
+		sw.endpoints[key] = resolvedEndpoint{addr: ep.Addr, replicas: replicas}
+	}
+	return updates
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (sw *serviceWatcher) Close() { sw.cancel() }
@@ -14,7 +14,11 @@
 
 package clientv3
 
-import pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
 
 type opType int
 
@@ -44,6 +48,12 @@ type Op struct {
 	maxModRev    int64
 	minCreateRev int64
 	maxCreateRev int64
+	showDeleted  bool
+	// hedging of serializable ranges
+	hedgeDelay time.Duration
+	hedgeMax   int
+	// onCompact handles the compaction of the revision of pinned ranges
+	onCompact CompactHandler
 
 	// for range, watch
 	rev int64
@@ -68,10 +78,15 @@ type Op struct {
 	// filters for watchers
 	filterPut    bool
 	filterDelete bool
+	// eventFilter selects events by key and value at server side
+	eventFilter eventFilter
+	// coalesce is the window events are merged over
+	coalesce time.Duration
 
 	// for put
 	val     []byte
 	leaseID LeaseID
+	ttl     int64
 
 	// txn
 	cmps    []Cmp
@@ -121,6 +136,9 @@ func (op Op) IsKeysOnly() bool { return op.keysOnly }
 // IsCountOnly returns whether countOnly is set.
 func (op Op) IsCountOnly() bool { return op.countOnly }
 
+// IsShowDeleted returns whether showDeleted is set.
+func (op Op) IsShowDeleted() bool { return op.showDeleted }
+
 // MinModRev returns the operation's minimum modify revision.
 func (op Op) MinModRev() int64 { return op.minModRev }
 
@@ -158,6 +176,7 @@ func (op Op) toRangeRequest() *pb.RangeRequest {
 		MaxModRevision:    op.maxModRev,
 		MinCreateRevision: op.minCreateRev,
 		MaxCreateRevision: op.maxCreateRev,
+		ShowDeleted:       op.showDeleted,
 	}
 	if op.sort != nil {
 		r.SortOrder = pb.RangeRequest_SortOrder(op.sort.Order)
@@ -187,7 +206,7 @@ func (op Op) toRequestOp() *pb.RequestOp {
 	case tRange:
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: op.toRangeRequest()}}
 	case tPut:
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, Ttl: op.ttl}
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: r}}
 	case tDeleteRange:
 		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV}
@@ -238,6 +257,8 @@ func OpDelete(key string, opts ...OpOption) Op {
 	switch {
 	case ret.leaseID != 0:
 		panic("unexpected lease in delete")
+	case ret.ttl != 0:
+		panic("unexpected expiry in delete")
 	case ret.limit != 0:
 		panic("unexpected limit in delete")
 	case ret.rev != 0:
@@ -252,7 +273,7 @@ func OpDelete(key string, opts ...OpOption) Op {
 		panic("unexpected mod revision filter in delete")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in delete")
-	case ret.filterDelete, ret.filterPut:
+	case ret.filterDelete, ret.filterPut, !ret.eventFilter.isZero():
 		panic("unexpected filter in delete")
 	case ret.createdNotify:
 		panic("unexpected createdNotify in delete")
@@ -281,7 +302,7 @@ func OpPut(key, val string, opts ...OpOption) Op {
 		panic("unexpected mod revision filter in put")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in put")
-	case ret.filterDelete, ret.filterPut:
+	case ret.filterDelete, ret.filterPut, !ret.eventFilter.isZero():
 		panic("unexpected filter in put")
 	case ret.createdNotify:
 		panic("unexpected createdNotify in put")
@@ -300,6 +321,8 @@ func opWatch(key string, opts ...OpOption) Op {
 	switch {
 	case ret.leaseID != 0:
 		panic("unexpected lease in watch")
+	case ret.ttl != 0:
+		panic("unexpected expiry in watch")
 	case ret.limit != 0:
 		panic("unexpected limit in watch")
 	case ret.sort != nil:
@@ -427,6 +450,12 @@ func WithCountOnly() OpOption {
 	return func(op *Op) { op.countOnly = true }
 }
 
+// WithShowDeleted makes a 'Get' request at a past revision list in
+// DeletedKeys the returned keys that have been deleted since.
+func WithShowDeleted() OpOption {
+	return func(op *Op) { op.showDeleted = true }
+}
+
 // WithMinModRev filters out keys for Get with modification revisions less than the given revision.
 func WithMinModRev(rev int64) OpOption { return func(op *Op) { op.minModRev = rev } }
 
@@ -488,6 +517,38 @@ func WithFilterDelete() OpOption {
 	return func(op *Op) { op.filterDelete = true }
 }
 
+// WithKeyPattern makes the server send only the events of the watched keys
+// matching the glob pattern, as of path.Match. A '*' does not match the '/'
+// separator of key segments. The watch is canceled if the pattern is malformed.
+func WithKeyPattern(pattern string) OpOption {
+	return func(op *Op) { op.eventFilter.keyPattern = pattern }
+}
+
+// WithKeyRegex makes the server send only the events of the watched keys
+// matching the regular expression, in RE2 syntax. The watch is canceled if
+// the expression is malformed.
+func WithKeyRegex(expr string) OpOption {
+	return func(op *Op) { op.eventFilter.keyRegex = expr }
+}
+
+// WithValueContains makes the server discard the PUT events whose value does
+// not contain sub. DELETE events, which hold no value, are not discarded.
+func WithValueContains(sub string) OpOption {
+	return func(op *Op) { op.eventFilter.valueContains = []byte(sub) }
+}
+
+// WithValueJSONPath makes the server discard the PUT events whose value, a
+// JSON document, has nothing at the dot separated jsonPath, such as
+// "spec.replicas" or "items.0.name". If jsonValue is not empty, it is the JSON
+// encoded value that must be at the path, such as `"running"` or `3`.
+// PUT events whose value is not JSON are discarded; DELETE events are not.
+func WithValueJSONPath(jsonPath, jsonValue string) OpOption {
+	return func(op *Op) {
+		op.eventFilter.valueJSONPath = jsonPath
+		op.eventFilter.valueJSONEquals = []byte(jsonValue)
+	}
+}
+
 // WithPrevKV gets the previous key-value pair before the event happens. If the previous KV is already compacted,
 // nothing will be returned.
 func WithPrevKV() OpOption {
@@ -507,6 +568,38 @@ func WithFragment() OpOption {
 	return func(op *Op) { op.fragment = true }
 }
 
+// WithHedging hedges a serializable Get: if no response is received within
+// delay, a copy of the request is sent to another endpoint, up to
+// maxEndpoints endpoints in total, and the first response received is
+// returned. A failed request is hedged right away. It cuts the tail latency
+// of reads served by a member stalled by a GC pause or a slow disk, at the
+// cost of extra requests. Linearizable reads are not hedged, as they are
+// served through the leader whatever the endpoint.
+func WithHedging(delay time.Duration, maxEndpoints int) OpOption {
+	return func(op *Op) {
+		op.hedgeDelay = delay
+		op.hedgeMax = maxEndpoints
+	}
+}
+
+// WithOnCompact makes the reads pinned to a revision by
+// GetWithRetryOnCompact and PagedGet call h when that revision is compacted,
+// and retry at the revision it returns, rather than fail with ErrCompacted.
+// Get ignores it.
+func WithOnCompact(h CompactHandler) OpOption {
+	return func(op *Op) { op.onCompact = h }
+}
+
+// WithCoalesce makes Watch merge the events received within window of
+// the first one, delivering only the latest event of each key, in revision
+// order. With WithPrevKV, a merged event keeps the previous key-value of
+// the first event of its key. Responses without events, such as progress
+// notifications, and errors, first flush the merged events. Watchers that
+// only act on the current state of the keys process fewer events.
+func WithCoalesce(window time.Duration) OpOption {
+	return func(op *Op) { op.coalesce = window }
+}
+
 // WithIgnoreValue updates the key using its current value.
 // This option can not be combined with non-empty values.
 // Returns an error if the key does not exist.
@@ -525,6 +618,13 @@ func WithIgnoreLease() OpOption {
 	}
 }
 
+// WithExpiry puts the key with a time to live, in seconds, after which the
+// server deletes the key unless it is put again. Unlike WithLease, no lease
+// needs to be granted and kept alive; each key expires on its own.
+func WithExpiry(ttl int64) OpOption {
+	return func(op *Op) { op.ttl = ttl }
+}
+
 // LeaseOp represents an Operation that lease can execute.
 type LeaseOp struct {
 	id LeaseID
@@ -558,3 +658,17 @@ func IsOptsWithPrefix(opts []OpOption) bool { return isOpFuncCalled("WithPrefix"
 
 // IsOptsWithFromKey returns true if WithFromKey option is called in the given opts.
 func IsOptsWithFromKey(opts []OpOption) bool { return isOpFuncCalled("WithFromKey", opts) }
+
+// eventFilter holds the filters of the watched events by key and value that
+// the server evaluates.
+type eventFilter struct {
+	keyPattern      string
+	keyRegex        string
+	valueContains   []byte
+	valueJSONPath   string
+	valueJSONEquals []byte
+}
+
+func (f eventFilter) isZero() bool {
+	return f.keyPattern == "" && f.keyRegex == "" && len(f.valueContains) == 0 && f.valueJSONPath == "" && len(f.valueJSONEquals) == 0
+}
@@ -36,3 +36,17 @@ func TestOpWithSort(t *testing.T) {
 		t.Fatalf("expected %+v, got %+v", wreq, req)
 	}
 }
+
+// TestOpWithExpiry tests if WithExpiry sets the ttl of the PutRequest.
+func TestOpWithExpiry(t *testing.T) {
+	opReq := OpPut("foo", "bar", WithExpiry(10)).toRequestOp().Request
+	q, ok := opReq.(*pb.RequestOp_RequestPut)
+	if !ok {
+		t.Fatalf("expected put request, got %v", reflect.TypeOf(opReq))
+	}
+	req := q.RequestPut
+	wreq := &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar"), Ttl: 10}
+	if !reflect.DeepEqual(req, wreq) {
+		t.Fatalf("expected %+v, got %+v", wreq, req)
+	}
+}
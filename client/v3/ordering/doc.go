@@ -39,4 +39,15 @@
 //
 // Now calls using 'cli' will reject order violations with an error.
 //
+// Alternatively, NewKVWithOptions tracks revisions per key with
+// WithPerKeyOrdering, so that only a stale response for a key read or
+// written before is a violation, and moves the client to another endpoint
+// on violations with WithRetargeting, so that read-your-writes holds
+// without handling ErrNoGreaterRev:
+//
+//	cli.KV = ordering.NewKVWithOptions(cli.KV,
+//		ordering.WithPerKeyOrdering(0),
+//		ordering.WithRetargeting(cli),
+//	)
+//
 package ordering
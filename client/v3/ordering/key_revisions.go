@@ -0,0 +1,123 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ordering
+
+import (
+	"bytes"
+	"sync"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+const defaultMaxKeys = 10000
+
+// keyRange is a range of keys [key, end), where an empty end is the single
+// key, and an end of "\x00" is the end of the keyspace.
+type keyRange struct {
+	key, end string
+}
+
+func (r keyRange) bounds() ([]byte, []byte) {
+	switch r.end {
+	case "":
+		return []byte(r.key), append([]byte(r.key), 0)
+	case "\x00":
+		return []byte(r.key), nil
+	}
+	return []byte(r.key), []byte(r.end)
+}
+
+// overlaps returns true if the two ranges share a key.
+func (r keyRange) overlaps(o keyRange) bool {
+	rStart, rEnd := r.bounds()
+	oStart, oEnd := o.bounds()
+	return before(rStart, oEnd) && before(oStart, rEnd)
+}
+
+// before returns true if key is before end, where a nil end is the end of
+// the keyspace.
+func before(key, end []byte) bool {
+	return end == nil || bytes.Compare(key, end) < 0
+}
+
+// keyRevisions tracks the revisions of the responses received for the key
+// ranges read and written.
+type keyRevisions struct {
+	mu      sync.RWMutex
+	maxKeys int
+	revs    map[keyRange]int64
+	// floor is the highest revision of the forgotten ranges
+	floor int64
+}
+
+func newKeyRevisions(maxKeys int) *keyRevisions {
+	if maxKeys <= 0 {
+		maxKeys = defaultMaxKeys
+	}
+	return &keyRevisions{maxKeys: maxKeys, revs: make(map[keyRange]int64)}
+}
+
+// ranges returns the key ranges op reads or writes.
+func ranges(op clientv3.Op) []keyRange {
+	if !op.IsTxn() {
+		return []keyRange{{string(op.KeyBytes()), string(op.RangeBytes())}}
+	}
+	cmps, thenOps, elseOps := op.Txn()
+	var rs []keyRange
+	for _, cmp := range cmps {
+		rs = append(rs, keyRange{string(cmp.Key), string(cmp.RangeEnd)})
+	}
+	for _, op := range append(thenOps, elseOps...) {
+		rs = append(rs, ranges(op)...)
+	}
+	return rs
+}
+
+// required returns the highest revision recorded for the ranges of op.
+func (kr *keyRevisions) required(op clientv3.Op) int64 {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	rev := kr.floor
+	for _, r := range ranges(op) {
+		if rrev, ok := kr.revs[r]; ok && rrev > rev {
+			rev = rrev
+		}
+		for tracked, trev := range kr.revs {
+			if trev > rev && tracked.overlaps(r) {
+				rev = trev
+			}
+		}
+	}
+	return rev
+}
+
+// record records rev for the ranges of op.
+func (kr *keyRevisions) record(op clientv3.Op, rev int64) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	for _, r := range ranges(op) {
+		if rev > kr.revs[r] {
+			kr.revs[r] = rev
+		}
+	}
+	if len(kr.revs) > kr.maxKeys {
+		for _, trev := range kr.revs {
+			if trev > kr.floor {
+				kr.floor = trev
+			}
+		}
+		kr.revs = make(map[keyRange]int64)
+	}
+}
@@ -29,10 +29,75 @@ type kvOrdering struct {
 	orderViolationFunc OrderViolationFunc
 	prevRev            int64
 	revMu              sync.RWMutex
+
+	// keys, if set, tracks the revisions per key instead of prevRev
+	keys *keyRevisions
+	// retarget, if set, handles violations instead of orderViolationFunc
+	retarget *retargeter
 }
 
 func NewKV(kv clientv3.KV, orderViolationFunc OrderViolationFunc) *kvOrdering {
-	return &kvOrdering{kv, orderViolationFunc, 0, sync.RWMutex{}}
+	return &kvOrdering{KV: kv, orderViolationFunc: orderViolationFunc}
+}
+
+// NewKVWithOptions returns an ordering wrapper of kv configured by opts. By
+// default, like NewKV, responses must not have a revision less than any
+// response received before, and violations fail with ErrNoGreaterRev.
+func NewKVWithOptions(kv clientv3.KV, opts ...Option) *kvOrdering {
+	o := options{orderViolationFunc: func(clientv3.Op, clientv3.OpResponse, int64) error { return ErrNoGreaterRev }}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	kvo := NewKV(kv, o.orderViolationFunc)
+	if o.perKey {
+		kvo.keys = newKeyRevisions(o.maxKeys)
+	}
+	if o.retargetClient != nil {
+		kvo.retarget = newRetargeter(o.retargetClient)
+	}
+	return kvo
+}
+
+type options struct {
+	orderViolationFunc OrderViolationFunc
+	perKey             bool
+	maxKeys            int
+	retargetClient     *clientv3.Client
+}
+
+// Option configures NewKVWithOptions.
+type Option func(*options)
+
+// WithOrderViolationFunc handles violations with f, instead of failing with
+// ErrNoGreaterRev.
+func WithOrderViolationFunc(f OrderViolationFunc) Option {
+	return func(o *options) { o.orderViolationFunc = f }
+}
+
+// WithPerKeyOrdering only requires responses not to be older than the
+// responses received before for the keys they read or write, rather than
+// for any key. A read of a key is then not held back by a more recent
+// response for another key, while a read of a key written before sees the
+// write. Puts and deletes are tracked as well, for read-your-writes.
+//
+// The revisions of at most maxKeys key ranges are tracked; beyond that,
+// they are forgotten and their highest revision applies to all keys. If
+// maxKeys is <= 0, 10000 ranges are tracked.
+func WithPerKeyOrdering(maxKeys int) Option {
+	return func(o *options) {
+		o.perKey = true
+		o.maxKeys = maxKeys
+	}
+}
+
+// WithRetargeting handles violations by moving c to another endpoint,
+// one after the other, and reissuing the request until a member serves it
+// at a recent enough revision or its context is done, rather than failing
+// with ErrNoGreaterRev. c must be the client kv belongs to, and its
+// endpoints must not be set elsewhere while it is in use: requests moving
+// it are served one at a time.
+func WithRetargeting(c *clientv3.Client) Option {
+	return func(o *options) { o.retargetClient = c }
 }
 
 func (kv *kvOrdering) getPrevRev() int64 {
@@ -49,32 +114,83 @@ func (kv *kvOrdering) setPrevRev(currRev int64) {
 	}
 }
 
-func (kv *kvOrdering) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+// requiredRev returns the revision a response to op must not be less than.
+func (kv *kvOrdering) requiredRev(op clientv3.Op) int64 {
+	if kv.keys != nil {
+		return kv.keys.required(op)
+	}
+	return kv.getPrevRev()
+}
+
+// record records the revision of the response to op.
+func (kv *kvOrdering) record(op clientv3.Op, rev int64) {
+	if kv.keys != nil {
+		kv.keys.record(op, rev)
+		return
+	}
+	kv.setPrevRev(rev)
+}
+
+// violated handles a response to op with a revision less than prevRev.
+func (kv *kvOrdering) violated(ctx context.Context, rt *retargeting, op clientv3.Op, resp clientv3.OpResponse, prevRev int64) error {
+	if rt != nil {
+		return rt.next(ctx)
+	}
+	return kv.orderViolationFunc(op, resp, prevRev)
+}
+
+// do issues op until its response is not older than the revision required
+// for it, and records the revision of the response.
+func (kv *kvOrdering) do(ctx context.Context, op clientv3.Op, rev func(clientv3.OpResponse) int64) (clientv3.OpResponse, error) {
+	var rt *retargeting
+	if kv.retarget != nil {
+		rt = kv.retarget.request()
+		defer rt.restore()
+	}
 	// prevRev is stored in a local variable in order to record the prevRev
-	// at the beginning of the Get operation, because concurrent
-	// access to kvOrdering could change the prevRev field in the
-	// middle of the Get operation.
-	prevRev := kv.getPrevRev()
-	op := clientv3.OpGet(key, opts...)
+	// at the beginning of the operation, because concurrent access to
+	// kvOrdering could change the prevRev field in the middle of the
+	// operation.
+	prevRev := kv.requiredRev(op)
 	for {
 		r, err := kv.KV.Do(ctx, op)
 		if err != nil {
-			return nil, err
+			return r, err
 		}
-		resp := r.Get()
-		if resp.Header.Revision == prevRev {
-			return resp, nil
-		} else if resp.Header.Revision > prevRev {
-			kv.setPrevRev(resp.Header.Revision)
-			return resp, nil
+		if currRev := rev(r); currRev >= prevRev {
+			kv.record(op, currRev)
+			return r, nil
 		}
-		err = kv.orderViolationFunc(op, r, prevRev)
-		if err != nil {
-			return nil, err
+		if err = kv.violated(ctx, rt, op, r, prevRev); err != nil {
+			return clientv3.OpResponse{}, err
 		}
 	}
 }
 
+func (kv *kvOrdering) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	r, err := kv.do(ctx, clientv3.OpGet(key, opts...), func(r clientv3.OpResponse) int64 { return r.Get().Header.Revision })
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(), nil
+}
+
+func (kv *kvOrdering) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	resp, err := kv.KV.Put(ctx, key, val, opts...)
+	if err == nil && kv.keys != nil {
+		kv.keys.record(clientv3.OpPut(key, val, opts...), resp.Header.Revision)
+	}
+	return resp, err
+}
+
+func (kv *kvOrdering) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	resp, err := kv.KV.Delete(ctx, key, opts...)
+	if err == nil && kv.keys != nil {
+		kv.keys.record(clientv3.OpDelete(key, opts...), resp.Header.Revision)
+	}
+	return resp, err
+}
+
 func (kv *kvOrdering) Txn(ctx context.Context) clientv3.Txn {
 	return &txnOrdering{
 		kv.KV.Txn(ctx),
@@ -125,25 +241,10 @@ func (txn *txnOrdering) Else(ops ...clientv3.Op) clientv3.Txn {
 }
 
 func (txn *txnOrdering) Commit() (*clientv3.TxnResponse, error) {
-	// prevRev is stored in a local variable in order to record the prevRev
-	// at the beginning of the Commit operation, because concurrent
-	// access to txnOrdering could change the prevRev field in the
-	// middle of the Commit operation.
-	prevRev := txn.getPrevRev()
 	opTxn := clientv3.OpTxn(txn.cmps, txn.thenOps, txn.elseOps)
-	for {
-		opResp, err := txn.KV.Do(txn.ctx, opTxn)
-		if err != nil {
-			return nil, err
-		}
-		txnResp := opResp.Txn()
-		if txnResp.Header.Revision >= prevRev {
-			txn.setPrevRev(txnResp.Header.Revision)
-			return txnResp, nil
-		}
-		err = txn.orderViolationFunc(opTxn, opResp, prevRev)
-		if err != nil {
-			return nil, err
-		}
+	r, err := txn.do(txn.ctx, opTxn, func(r clientv3.OpResponse) int64 { return r.Txn().Header.Revision })
+	if err != nil {
+		return nil, err
 	}
+	return r.Txn(), nil
 }
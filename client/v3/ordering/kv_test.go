@@ -17,8 +17,11 @@ package ordering
 import (
 	"context"
 	gContext "context"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/client/v3"
@@ -67,15 +70,14 @@ func TestKvOrdering(t *testing.T) {
 	for i, tt := range rangeTests {
 		mKV := &mockKV{clientv3.NewKVFromKVClient(nil, nil), tt.response.OpResponse()}
 		kv := &kvOrdering{
-			mKV,
-			func(r *clientv3.GetResponse) OrderViolationFunc {
+			KV: mKV,
+			orderViolationFunc: func(r *clientv3.GetResponse) OrderViolationFunc {
 				return func(op clientv3.Op, resp clientv3.OpResponse, prevRev int64) error {
 					r.Header.Revision++
 					return nil
 				}
 			}(tt.response),
-			tt.prevRev,
-			sync.RWMutex{},
+			prevRev: tt.prevRev,
 		}
 		res, err := kv.Get(nil, "mockKey")
 		if err != nil {
@@ -121,15 +123,14 @@ func TestTxnOrdering(t *testing.T) {
 	for i, tt := range txnTests {
 		mKV := &mockKV{clientv3.NewKVFromKVClient(nil, nil), tt.response.OpResponse()}
 		kv := &kvOrdering{
-			mKV,
-			func(r *clientv3.TxnResponse) OrderViolationFunc {
+			KV: mKV,
+			orderViolationFunc: func(r *clientv3.TxnResponse) OrderViolationFunc {
 				return func(op clientv3.Op, resp clientv3.OpResponse, prevRev int64) error {
 					r.Header.Revision++
 					return nil
 				}
 			}(tt.response),
-			tt.prevRev,
-			sync.RWMutex{},
+			prevRev: tt.prevRev,
 		}
 		txn := &txnOrdering{
 			kv.Txn(context.Background()),
@@ -149,3 +150,143 @@ func TestTxnOrdering(t *testing.T) {
 		}
 	}
 }
+
+// revKV serves every request at revision rev.
+type revKV struct {
+	clientv3.KV
+	rev int64
+}
+
+func (kv *revKV) Do(ctx gContext.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	return (&clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: kv.rev}}).OpResponse(), nil
+}
+
+func (kv *revKV) Put(ctx gContext.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	return &clientv3.PutResponse{Header: &pb.ResponseHeader{Revision: kv.rev}}, nil
+}
+
+func TestKvOrderingPerKey(t *testing.T) {
+	mKV := &revKV{rev: 10}
+	var violations []int64
+	kv := NewKVWithOptions(mKV,
+		WithPerKeyOrdering(2),
+		WithOrderViolationFunc(func(op clientv3.Op, resp clientv3.OpResponse, prevRev int64) error {
+			violations = append(violations, prevRev)
+			mKV.rev = prevRev
+			return nil
+		}),
+	)
+	if _, err := kv.Put(context.TODO(), "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a stale revision is fine for other keys
+	mKV.rev = 5
+	if _, err := kv.Get(context.TODO(), "b"); err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("violations = %v, want none for another key", violations)
+	}
+	// but not for the key written, including in a range
+	for _, opts := range [][]clientv3.OpOption{nil, {clientv3.WithPrefix()}} {
+		mKV.rev = 5
+		resp, err := kv.Get(context.TODO(), "a", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Header.Revision != 10 {
+			t.Fatalf("revision = %d, want 10", resp.Header.Revision)
+		}
+	}
+	if len(violations) != 2 || violations[0] != 10 || violations[1] != 10 {
+		t.Fatalf("violations = %v, want 2 at revision 10", violations)
+	}
+
+	// beyond maxKeys, the highest revision applies to all keys
+	mKV.rev = 12
+	for _, key := range []string{"c", "d", "e"} {
+		if _, err := kv.Get(context.TODO(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mKV.rev = 11
+	if _, err := kv.Get(context.TODO(), "f"); err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 3 || violations[2] != 12 {
+		t.Fatalf("violations = %v, want a third at revision 12", violations)
+	}
+}
+
+func TestKvOrderingDefaultViolation(t *testing.T) {
+	kv := NewKVWithOptions(&revKV{rev: 5})
+	kv.setPrevRev(10)
+	if _, err := kv.Get(context.TODO(), "a"); err != ErrNoGreaterRev {
+		t.Fatalf("err = %v, want %v", err, ErrNoGreaterRev)
+	}
+}
+
+// endpointKV serves requests at revision 10 when its client is moved to
+// the endpoint fresh, and at revision 5 otherwise.
+type endpointKV struct {
+	clientv3.KV
+	c     *clientv3.Client
+	fresh string
+	calls int64
+}
+
+func (kv *endpointKV) Do(ctx gContext.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	atomic.AddInt64(&kv.calls, 1)
+	rev := int64(5)
+	if eps := kv.c.Endpoints(); len(eps) == 1 && eps[0] == kv.fresh {
+		rev = 10
+	}
+	return (&clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: rev}}).OpResponse(), nil
+}
+
+func TestKvOrderingRetargetingConcurrent(t *testing.T) {
+	defer func(wait time.Duration) { retargetWait = wait }(retargetWait)
+	retargetWait = 10 * time.Millisecond
+
+	eps := []string{"127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3"}
+	c, err := clientv3.New(clientv3.Config{Endpoints: eps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	mKV := &endpointKV{c: c, fresh: eps[1]}
+	kv := NewKVWithOptions(mKV, WithRetargeting(c))
+	kv.setPrevRev(10)
+
+	const requests = 5
+	var wg sync.WaitGroup
+	errc := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := kv.Get(ctx, "a")
+			errc <- err
+		}()
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a request moving the client is never moved by another one, so it
+	// reaches the fresh endpoint at its second move
+	if calls := atomic.LoadInt64(&mKV.calls); calls > 3*requests {
+		t.Fatalf("calls = %d, want at most %d", calls, 3*requests)
+	}
+	if got := c.Endpoints(); !reflect.DeepEqual(got, eps) {
+		t.Fatalf("endpoints = %v, want %v", got, eps)
+	}
+}
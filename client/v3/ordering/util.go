@@ -15,6 +15,7 @@
 package ordering
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -49,3 +50,69 @@ func NewOrderViolationSwitchEndpointClosure(c clientv3.Client) OrderViolationFun
 		return nil
 	}
 }
+
+// retargetWait is the time given to the client to connect to the endpoint
+// it is moved to, before the request is reissued.
+var retargetWait = time.Second
+
+// retargeter moves a client from endpoint to endpoint after ordering
+// violations, and back to all its endpoints once the request is served.
+// The client is shared by all the requests, so only one request at a time
+// moves it: the others wait for it to be served before moving the client
+// in turn.
+type retargeter struct {
+	c *clientv3.Client
+	// owner is held by the request moving the client, from its first
+	// violation until it is served
+	owner chan struct{}
+}
+
+func newRetargeter(c *clientv3.Client) *retargeter {
+	return &retargeter{c: c, owner: make(chan struct{}, 1)}
+}
+
+// retargeting is the state of a request moving the client of a retargeter.
+type retargeting struct {
+	*retargeter
+	// eps are the endpoints of the client while it is moved to one of them
+	eps []string
+	// idx is the index of the next endpoint to move to
+	idx int
+}
+
+// request returns the retargeting state of a new request.
+func (r *retargeter) request() *retargeting {
+	return &retargeting{retargeter: r}
+}
+
+// next moves the client to the next endpoint, and waits for it to connect.
+func (rt *retargeting) next(ctx context.Context) error {
+	if rt.eps == nil {
+		select {
+		case rt.owner <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		rt.eps = rt.c.Endpoints()
+	}
+	ep := rt.eps[rt.idx%len(rt.eps)]
+	rt.idx++
+	// force client to connect to given endpoint by limiting to a single endpoint
+	rt.c.SetEndpoints(ep)
+
+	select {
+	case <-time.After(retargetWait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// restore sets the endpoints of the client back, if the request moved it.
+func (rt *retargeting) restore() {
+	if rt.eps != nil {
+		rt.c.SetEndpoints(rt.eps...)
+		rt.eps = nil
+		<-rt.owner
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// DefaultPageSize is the number of keys fetched per Range request by a
+// PagedGet given no page size.
+const DefaultPageSize = 1000
+
+// PagedGet iterates over the keys of a large range in pages, each fetched
+// by a limited Range request. All pages are served at the revision of the
+// first one, so the iteration sees a consistent view of the range even if
+// it is modified meanwhile; if that revision is compacted before the last
+// page is fetched, the iteration fails with ErrCompacted, unless a
+// CompactHandler is given with WithOnCompact: the iteration then continues
+// from the last key seen at the revision the handler returns, and Rev
+// changes accordingly.
+//
+//	pg := clientv3.NewPagedGet(cli, "prefix", 500, clientv3.WithPrefix())
+//	for pg.Next(ctx) {
+//		for _, kv := range pg.Page().Kvs {
+//			...
+//		}
+//	}
+//	if err := pg.Err(); err != nil {
+//		...
+//	}
+type PagedGet struct {
+	kv   KV
+	op   Op
+	page *GetResponse
+	done bool
+	err  error
+}
+
+// NewPagedGet returns a PagedGet over the keys Get(key, opts...) returns,
+// fetched pageSize keys at a time, or DefaultPageSize if pageSize is not
+// positive. Since pages are requested from the last key seen, the keys must
+// be returned in ascending key order: WithLimit, WithCountOnly and
+// WithSort, other than by ascending key, are not supported.
+func NewPagedGet(kv KV, key string, pageSize int64, opts ...OpOption) *PagedGet {
+	op := OpGet(key, opts...)
+	pg := &PagedGet{kv: kv, op: op}
+	switch {
+	case op.limit != 0:
+		pg.err = errors.New("clientv3: WithLimit is not supported by PagedGet, set the page size instead")
+	case op.countOnly:
+		pg.err = errors.New("clientv3: WithCountOnly is not supported by PagedGet")
+	case op.sort != nil && op.sort.Order != SortNone:
+		pg.err = errors.New("clientv3: PagedGet only supports ascending key order")
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	pg.op.limit = pageSize
+	return pg
+}
+
+// Next fetches the next page, and returns false once there are no more
+// pages or a request failed.
+func (pg *PagedGet) Next(ctx context.Context) bool {
+	if pg.done || pg.err != nil {
+		return false
+	}
+	if pg.page != nil {
+		if !pg.page.More || len(pg.page.Kvs) == 0 {
+			pg.done = true
+			return false
+		}
+		// continue right after the last key of the previous page
+		pg.op.key = append(append([]byte(nil), pg.page.Kvs[len(pg.page.Kvs)-1].Key...), 0)
+	}
+	resp, err := pg.kv.Do(ctx, pg.op)
+	for err == rpctypes.ErrCompacted && pg.op.onCompact != nil && pg.op.rev != 0 {
+		if pg.op.rev, err = pg.op.onCompact(ctx, pg.op.rev); err != nil {
+			break
+		}
+		resp, err = pg.kv.Do(ctx, pg.op)
+	}
+	if err != nil {
+		pg.err = err
+		return false
+	}
+	pg.page = resp.Get()
+	if pg.op.rev == 0 {
+		pg.op.rev = pg.page.Header.Revision
+	}
+	return true
+}
+
+// Page returns the page fetched by the last call to Next.
+func (pg *PagedGet) Page() *GetResponse { return pg.page }
+
+// Rev returns the revision the pages are served at, or 0 before the first
+// page is fetched.
+func (pg *PagedGet) Rev() int64 { return pg.op.rev }
+
+// Err returns the error that ended the iteration, if any.
+func (pg *PagedGet) Err() error { return pg.err }
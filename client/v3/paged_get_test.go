@@ -0,0 +1,127 @@
+// Copyright 2016 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// rangeKV serves Range requests from a sorted list of keys, and their
+// revisions if given, recording the requests. Requests at revisions before
+// compacted fail with ErrCompacted.
+type rangeKV struct {
+	KV
+	keys      []string
+	mods      map[string]int64
+	rev       int64
+	compacted int64
+	reqs      []*pb.RangeRequest
+}
+
+func (kv *rangeKV) Do(ctx context.Context, op Op) (OpResponse, error) {
+	r := op.toRangeRequest()
+	kv.reqs = append(kv.reqs, r)
+	if r.Revision > 0 && r.Revision < kv.compacted {
+		return OpResponse{}, rpctypes.ErrCompacted
+	}
+	i := sort.SearchStrings(kv.keys, string(r.Key))
+	resp := &GetResponse{Header: &pb.ResponseHeader{Revision: kv.rev}}
+	end := r.RangeEnd
+	if len(end) == 0 {
+		end = append(append([]byte(nil), r.Key...), 0)
+	}
+	for ; i < len(kv.keys) && bytes.Compare([]byte(kv.keys[i]), end) < 0; i++ {
+		if r.Limit > 0 && int64(len(resp.Kvs)) == r.Limit {
+			resp.More = true
+			break
+		}
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(kv.keys[i]), ModRevision: kv.mods[kv.keys[i]]})
+	}
+	return resp.OpResponse(), nil
+}
+
+func TestPagedGet(t *testing.T) {
+	tests := []struct {
+		pageSize int64
+		opts     []OpOption
+
+		pages [][]string
+		revs  []int64
+		rev   int64
+	}{
+		{2, []OpOption{WithPrefix()}, [][]string{{"a/1", "a/2"}, {"a/3", "a/4"}, {"a/5"}}, []int64{0, 7, 7}, 7},
+		{5, []OpOption{WithPrefix()}, [][]string{{"a/1", "a/2", "a/3", "a/4", "a/5"}}, []int64{0}, 7},
+		{3, []OpOption{WithPrefix(), WithRev(5)}, [][]string{{"a/1", "a/2", "a/3"}, {"a/4", "a/5"}}, []int64{5, 5}, 5},
+		{0, []OpOption{WithPrefix(), WithSort(SortByKey, SortAscend)}, [][]string{{"a/1", "a/2", "a/3", "a/4", "a/5"}}, []int64{0}, 7},
+	}
+	for i, tt := range tests {
+		kv := &rangeKV{keys: []string{"a/1", "a/2", "a/3", "a/4", "a/5", "b/1"}, rev: 7}
+		pg := NewPagedGet(kv, "a/", tt.pageSize, tt.opts...)
+		var pages [][]string
+		for pg.Next(context.TODO()) {
+			var keys []string
+			for _, kv := range pg.Page().Kvs {
+				keys = append(keys, string(kv.Key))
+			}
+			pages = append(pages, keys)
+		}
+		if err := pg.Err(); err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		if !reflect.DeepEqual(pages, tt.pages) {
+			t.Errorf("#%d: expected pages %v, got %v", i, tt.pages, pages)
+		}
+		var revs []int64
+		for _, r := range kv.reqs {
+			revs = append(revs, r.Revision)
+		}
+		if !reflect.DeepEqual(revs, tt.revs) {
+			t.Errorf("#%d: expected request revisions %v, got %v", i, tt.revs, revs)
+		}
+		if pg.Rev() != tt.rev {
+			t.Errorf("#%d: expected revision %d, got %d", i, tt.rev, pg.Rev())
+		}
+	}
+}
+
+func TestPagedGetUnsupportedOptions(t *testing.T) {
+	for i, opts := range [][]OpOption{
+		{WithPrefix(), WithLimit(10)},
+		{WithPrefix(), WithCountOnly()},
+		{WithPrefix(), WithSort(SortByKey, SortDescend)},
+		{WithPrefix(), WithSort(SortByModRevision, SortAscend)},
+	} {
+		kv := &rangeKV{rev: 1}
+		pg := NewPagedGet(kv, "a/", 10, opts...)
+		if pg.Next(context.TODO()) {
+			t.Errorf("#%d: expected no page", i)
+		}
+		if pg.Err() == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+		if len(kv.reqs) != 0 {
+			t.Errorf("#%d: expected no request, got %s", i, fmt.Sprint(kv.reqs))
+		}
+	}
+}
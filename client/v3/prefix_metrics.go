@@ -0,0 +1,182 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"google.golang.org/grpc"
+)
+
+// PrefixMetrics aggregates the key-value requests of a client, Range, Put,
+// DeleteRange and Txn, by key prefix, so that applications can find which
+// of their prefixes load etcd the most. A request is aggregated under the
+// longest configured prefix of its key, or under "" if none matches; a
+// transaction under the first key it compares or operates on.
+//
+// The requests are measured as made by the application, including their
+// retries, but not the time they wait for the client-side limiters.
+type PrefixMetrics struct {
+	// prefixes are sorted longest first, to match the longest prefix.
+	prefixes []string
+
+	mu    sync.Mutex
+	stats map[string]*PrefixStats
+}
+
+// PrefixStats are the metrics of the requests on a key prefix.
+type PrefixStats struct {
+	// Requests is the number of requests, and Errors the number of them
+	// that failed.
+	Requests int64
+	Errors   int64
+	// RequestBytes and ResponseBytes are the encoded sizes of the requests
+	// and of the responses of those that succeeded.
+	RequestBytes  int64
+	ResponseBytes int64
+	// Latency is the total latency of the requests, and MaxLatency the
+	// longest.
+	Latency    time.Duration
+	MaxLatency time.Duration
+}
+
+// NewPrefixMetrics returns PrefixMetrics aggregating the requests under
+// the given prefixes.
+func NewPrefixMetrics(prefixes ...string) *PrefixMetrics {
+	sorted := append([]string(nil), prefixes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return &PrefixMetrics{prefixes: sorted, stats: make(map[string]*PrefixStats)}
+}
+
+// Stats returns the metrics aggregated so far, by prefix.
+func (m *PrefixMetrics) Stats() map[string]PrefixStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make(map[string]PrefixStats, len(m.stats))
+	for prefix, s := range m.stats {
+		stats[prefix] = *s
+	}
+	return stats
+}
+
+// Reset discards the metrics aggregated so far, e.g. after exporting them.
+func (m *PrefixMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = make(map[string]*PrefixStats)
+}
+
+// prefix returns the longest prefix of key.
+func (m *PrefixMetrics) prefix(key []byte) string {
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(string(key), prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func (m *PrefixMetrics) observe(key []byte, reqBytes, respBytes int, took time.Duration, err error) {
+	prefix := m.prefix(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats[prefix]
+	if s == nil {
+		s = &PrefixStats{}
+		m.stats[prefix] = s
+	}
+	s.Requests++
+	if err != nil {
+		s.Errors++
+	}
+	s.RequestBytes += int64(reqBytes)
+	s.ResponseBytes += int64(respBytes)
+	s.Latency += took
+	if took > s.MaxLatency {
+		s.MaxLatency = took
+	}
+}
+
+// unaryClientInterceptor returns an interceptor measuring the key-value
+// requests made through next.
+func (m *PrefixMetrics) unaryClientInterceptor(next grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key, ok := requestKey(req)
+		if !ok {
+			return next(ctx, method, req, reply, cc, invoker, opts...)
+		}
+		start := time.Now()
+		err := next(ctx, method, req, reply, cc, invoker, opts...)
+		respBytes := 0
+		if err == nil {
+			respBytes = messageSize(reply)
+		}
+		m.observe(key, messageSize(req), respBytes, time.Since(start), err)
+		return err
+	}
+}
+
+// requestKey returns the key of a key-value request, false if req is not
+// one.
+func requestKey(req interface{}) ([]byte, bool) {
+	switch r := req.(type) {
+	case *pb.RangeRequest:
+		return r.Key, true
+	case *pb.PutRequest:
+		return r.Key, true
+	case *pb.DeleteRangeRequest:
+		return r.Key, true
+	case *pb.TxnRequest:
+		return txnKey(r), true
+	}
+	return nil, false
+}
+
+// txnKey returns the first key a transaction compares or operates on.
+func txnKey(r *pb.TxnRequest) []byte {
+	if len(r.Compare) > 0 {
+		return r.Compare[0].Key
+	}
+	for _, ops := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, op := range ops {
+			switch o := op.Request.(type) {
+			case *pb.RequestOp_RequestRange:
+				return o.RequestRange.Key
+			case *pb.RequestOp_RequestPut:
+				return o.RequestPut.Key
+			case *pb.RequestOp_RequestDeleteRange:
+				return o.RequestDeleteRange.Key
+			case *pb.RequestOp_RequestTxn:
+				if key := txnKey(o.RequestTxn); key != nil {
+					return key
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func messageSize(m interface{}) int {
+	if s, ok := m.(interface{ Size() int }); ok {
+		return s.Size()
+	}
+	return 0
+}
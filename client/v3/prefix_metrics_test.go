@@ -0,0 +1,74 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"google.golang.org/grpc"
+)
+
+func TestPrefixMetrics(t *testing.T) {
+	m := NewPrefixMetrics("/a/", "/a/b/", "/c/")
+	next := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	interceptor := m.unaryClientInterceptor(next)
+	failed := errors.New("failed")
+
+	reqs := []struct {
+		req interface{}
+		err error
+	}{
+		{&pb.RangeRequest{Key: []byte("/a/1")}, nil},
+		{&pb.PutRequest{Key: []byte("/a/b/1"), Value: []byte("v")}, nil},
+		{&pb.DeleteRangeRequest{Key: []byte("/a/b/2")}, failed},
+		{&pb.TxnRequest{Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("/c/1")}}}}}, nil},
+		{&pb.RangeRequest{Key: []byte("/d/1")}, nil},
+		// requests other than key-value ones are not measured
+		{&pb.LeaseGrantRequest{TTL: 1}, nil},
+	}
+	for _, r := range reqs {
+		invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			return r.err
+		}
+		if err := interceptor(context.Background(), "/etcdserverpb.KV/Range", r.req, &pb.RangeResponse{Count: 1}, nil, invoker); err != r.err {
+			t.Fatalf("err = %v, want %v", err, r.err)
+		}
+	}
+
+	stats := m.Stats()
+	wrequests := map[string]int64{"/a/": 1, "/a/b/": 2, "/c/": 1, "": 1}
+	if len(stats) != len(wrequests) {
+		t.Fatalf("stats = %+v, want prefixes %v", stats, wrequests)
+	}
+	for prefix, n := range wrequests {
+		if stats[prefix].Requests != n {
+			t.Errorf("%q: requests = %d, want %d", prefix, stats[prefix].Requests, n)
+		}
+	}
+	if s := stats["/a/b/"]; s.Errors != 1 || s.RequestBytes == 0 || s.ResponseBytes != int64((&pb.RangeResponse{Count: 1}).Size()) {
+		t.Errorf("stats = %+v, want an error, and the response of a single request", s)
+	}
+
+	m.Reset()
+	if stats = m.Stats(); len(stats) != 0 {
+		t.Fatalf("stats = %+v after reset, want none", stats)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// registrationRetryInterval is the wait before a Registration registers
+// its key again after losing it, or failing to register it.
+var registrationRetryInterval = time.Second
+
+var (
+	// ErrRegistrationLeaseLost is the error of a registration whose lease
+	// expired, was revoked, or could not be kept alive.
+	ErrRegistrationLeaseLost = errors.New("clientv3: registration lease lost")
+	// ErrRegistrationKeyDeleted is the error of a registration whose key
+	// was deleted while its lease was alive.
+	ErrRegistrationKeyDeleted = errors.New("clientv3: registration key deleted")
+)
+
+// RegistrationEvent is a change of the liveness of a Registration.
+type RegistrationEvent struct {
+	// Registered is true if the key is present, on Lease.
+	Registered bool
+	Lease      LeaseID
+	// Err is the reason the key is no longer registered.
+	Err error
+}
+
+// RegisterOption configures a Registration.
+type RegisterOption func(*Registration)
+
+// WithRegistrationNotify calls fn on each change of the liveness of the
+// registration, from the goroutine maintaining it, which fn must not block.
+func WithRegistrationNotify(fn func(RegistrationEvent)) RegisterOption {
+	return func(r *Registration) { r.notify = fn }
+}
+
+// Registration maintains the presence of a key attached to a lease, as
+// services register themselves for discovery: it grants a lease, puts the
+// key on it and keeps it alive, and when the lease is lost, or the key is
+// deleted, it registers the key again on a new lease, until ctx is done or
+// the registration is closed.
+//
+// The key of a lease that could not be kept alive, e.g. while the client is
+// partitioned, stays present until the lease expires on the server; the key
+// is reported as no longer registered when the client gives up on the
+// lease, which is never later than its expiry.
+type Registration struct {
+	lease  Lease
+	kv     KV
+	w      Watcher
+	key    string
+	val    string
+	ttl    int64
+	notify func(RegistrationEvent)
+
+	cancel context.CancelFunc
+	donec  chan struct{}
+
+	mu         sync.Mutex
+	registered bool
+	leaseID    LeaseID
+}
+
+// Register registers key with value val, on a lease of ttl seconds,
+// maintained until ctx is done or the registration is closed.
+func (c *Client) Register(ctx context.Context, key, val string, ttl int64, opts ...RegisterOption) *Registration {
+	return register(ctx, c.Lease, c.KV, c.Watcher, key, val, ttl, opts...)
+}
+
+func register(ctx context.Context, lease Lease, kv KV, w Watcher, key, val string, ttl int64, opts ...RegisterOption) *Registration {
+	r := &Registration{lease: lease, kv: kv, w: w, key: key, val: val, ttl: ttl, donec: make(chan struct{})}
+	for _, opt := range opts {
+		opt(r)
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.run(ctx)
+	return r
+}
+
+// Registered returns the lease of the key, and true if it is registered.
+func (r *Registration) Registered() (LeaseID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leaseID, r.registered
+}
+
+// Done returns a channel closed once the registration is no longer
+// maintained.
+func (r *Registration) Done() <-chan struct{} { return r.donec }
+
+// Close stops maintaining the registration and revokes its lease, if any,
+// deleting the key.
+func (r *Registration) Close(ctx context.Context) error {
+	r.cancel()
+	<-r.donec
+	r.mu.Lock()
+	id := r.leaseID
+	r.mu.Unlock()
+	if id == NoLease {
+		return nil
+	}
+	_, err := r.lease.Revoke(ctx, id)
+	return err
+}
+
+func (r *Registration) run(ctx context.Context) {
+	defer close(r.donec)
+	for {
+		err := r.registerOnce(ctx)
+		if ctx.Err() != nil {
+			r.set(false, r.leaseID, ctx.Err())
+			return
+		}
+		r.set(false, NoLease, err)
+		select {
+		case <-time.After(registrationRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// registerOnce registers the key on a new lease, and returns once it is
+// no longer registered.
+func (r *Registration) registerOnce(ctx context.Context) error {
+	resp, err := r.lease.Grant(ctx, r.ttl)
+	if err != nil {
+		return err
+	}
+	id := resp.ID
+	// stop keeping the lease alive once it is given up on
+	lctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	kach, err := r.lease.KeepAlive(lctx, id)
+	if err != nil {
+		r.revoke(ctx, id)
+		return err
+	}
+	presp, err := r.kv.Put(ctx, r.key, r.val, WithLease(id))
+	if err != nil {
+		r.revoke(ctx, id)
+		return err
+	}
+	r.set(true, id, nil)
+
+	wch := r.w.Watch(lctx, r.key, WithRev(presp.Header.Revision+1), WithFilterPut())
+	for {
+		select {
+		case _, ok := <-kach:
+			if !ok {
+				return ErrRegistrationLeaseLost
+			}
+		case wr, ok := <-wch:
+			switch {
+			case !ok || wr.Err() != nil:
+				// keep the registration without noticing deletes, rather
+				// than re-registering on watch failures
+				wch = nil
+			case len(wr.Events) > 0:
+				r.revoke(ctx, id)
+				return ErrRegistrationKeyDeleted
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// revoke revokes a lease given up on, so that it does not linger until it
+// expires.
+func (r *Registration) revoke(ctx context.Context, id LeaseID) {
+	if ctx.Err() == nil {
+		r.lease.Revoke(ctx, id)
+	}
+}
+
+// set records the liveness of the registration, and notifies its changes.
+func (r *Registration) set(registered bool, id LeaseID, err error) {
+	r.mu.Lock()
+	changed := registered != r.registered || (registered && id != r.leaseID)
+	r.registered, r.leaseID = registered, id
+	r.mu.Unlock()
+	if changed && r.notify != nil {
+		r.notify(RegistrationEvent{Registered: registered, Lease: id, Err: err})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+// registrationLease grants leases kept alive until their keepalive channel
+// is closed by the test.
+type registrationLease struct {
+	Lease
+
+	mu      sync.Mutex
+	next    LeaseID
+	kachs   map[LeaseID]chan *LeaseKeepAliveResponse
+	revoked []LeaseID
+}
+
+func (l *registrationLease) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next++
+	return &LeaseGrantResponse{ID: l.next, TTL: ttl}, nil
+}
+
+func (l *registrationLease) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAliveResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kach := make(chan *LeaseKeepAliveResponse)
+	l.kachs[id] = kach
+	return kach, nil
+}
+
+func (l *registrationLease) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.revoked = append(l.revoked, id)
+	return &LeaseRevokeResponse{}, nil
+}
+
+func (l *registrationLease) expire(id LeaseID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	close(l.kachs[id])
+}
+
+type registrationKV struct{ KV }
+
+func (kv registrationKV) Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error) {
+	return &PutResponse{Header: &pb.ResponseHeader{Revision: 1}}, nil
+}
+
+type registrationWatcher struct{ Watcher }
+
+func (w registrationWatcher) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	return make(chan WatchResponse)
+}
+
+func TestRegistration(t *testing.T) {
+	old := registrationRetryInterval
+	defer func() { registrationRetryInterval = old }()
+	registrationRetryInterval = time.Millisecond
+
+	lease := &registrationLease{kachs: make(map[LeaseID]chan *LeaseKeepAliveResponse)}
+	evc := make(chan RegistrationEvent, 8)
+	r := register(context.Background(), lease, registrationKV{}, registrationWatcher{}, "svc/a", "addr", 10,
+		WithRegistrationNotify(func(ev RegistrationEvent) { evc <- ev }))
+
+	next := func() RegistrationEvent {
+		select {
+		case ev := <-evc:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("no registration event in one second")
+		}
+		return RegistrationEvent{}
+	}
+	if ev := next(); !reflect.DeepEqual(ev, RegistrationEvent{Registered: true, Lease: 1}) {
+		t.Fatalf("event = %+v, want registered on lease 1", ev)
+	}
+
+	// the key is registered again on a new lease once its lease is lost
+	lease.expire(1)
+	if ev := next(); !reflect.DeepEqual(ev, RegistrationEvent{Err: ErrRegistrationLeaseLost}) {
+		t.Fatalf("event = %+v, want lease lost", ev)
+	}
+	if ev := next(); !reflect.DeepEqual(ev, RegistrationEvent{Registered: true, Lease: 2}) {
+		t.Fatalf("event = %+v, want registered on lease 2", ev)
+	}
+	if id, ok := r.Registered(); !ok || id != 2 {
+		t.Fatalf("registered = %v, %v, want lease 2", id, ok)
+	}
+
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lease.revoked, []LeaseID{2}) {
+		t.Fatalf("revoked = %v, want lease 2", lease.revoked)
+	}
+}
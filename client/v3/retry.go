@@ -226,6 +226,30 @@ func (rmc *retryMaintenanceClient) Downgrade(ctx context.Context, in *pb.Downgra
 	return rmc.mc.Downgrade(ctx, in, opts...)
 }
 
+func (rmc *retryMaintenanceClient) AlarmHistory(ctx context.Context, in *pb.AlarmHistoryRequest, opts ...grpc.CallOption) (resp *pb.AlarmHistoryResponse, err error) {
+	return rmc.mc.AlarmHistory(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) MaintenanceProgress(ctx context.Context, in *pb.MaintenanceProgressRequest, opts ...grpc.CallOption) (resp *pb.MaintenanceProgressResponse, err error) {
+	return rmc.mc.MaintenanceProgress(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) ScheduleStatus(ctx context.Context, in *pb.ScheduleStatusRequest, opts ...grpc.CallOption) (resp *pb.ScheduleStatusResponse, err error) {
+	return rmc.mc.ScheduleStatus(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) RateLimitStatus(ctx context.Context, in *pb.RateLimitStatusRequest, opts ...grpc.CallOption) (resp *pb.RateLimitStatusResponse, err error) {
+	return rmc.mc.RateLimitStatus(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) PolicyPut(ctx context.Context, in *pb.PolicyPutRequest, opts ...grpc.CallOption) (resp *pb.PolicyPutResponse, err error) {
+	return rmc.mc.PolicyPut(ctx, in, opts...)
+}
+
+func (rmc *retryMaintenanceClient) PolicyGet(ctx context.Context, in *pb.PolicyGetRequest, opts ...grpc.CallOption) (resp *pb.PolicyGetResponse, err error) {
+	return rmc.mc.PolicyGet(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
 type retryAuthClient struct {
 	ac pb.AuthClient
 }
@@ -277,6 +301,10 @@ func (rac *retryAuthClient) UserChangePassword(ctx context.Context, in *pb.AuthU
 	return rac.ac.UserChangePassword(ctx, in, opts...)
 }
 
+func (rac *retryAuthClient) UserTokenRevoke(ctx context.Context, in *pb.AuthUserTokenRevokeRequest, opts ...grpc.CallOption) (resp *pb.AuthUserTokenRevokeResponse, err error) {
+	return rac.ac.UserTokenRevoke(ctx, in, opts...)
+}
+
 func (rac *retryAuthClient) UserGrantRole(ctx context.Context, in *pb.AuthUserGrantRoleRequest, opts ...grpc.CallOption) (resp *pb.AuthUserGrantRoleResponse, err error) {
 	return rac.ac.UserGrantRole(ctx, in, opts...)
 }
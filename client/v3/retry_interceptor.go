@@ -41,12 +41,24 @@ func (c *Client) unaryClientInterceptor(logger *zap.Logger, optFuncs ...retryOpt
 		ctx = withVersion(ctx)
 		grpcOpts, retryOpts := filterCallOptions(opts)
 		callOpts := reuseOrNewWithCallOptions(intOpts, retryOpts)
+		max := callOpts.max
+		if callOpts.retryPolicy == nonRepeatable {
+			max = callOpts.mutableMax
+		}
 		// short circuit for simplicity, and avoiding allocations.
-		if callOpts.max == 0 {
+		if max == 0 {
 			return invoker(ctx, method, req, reply, cc, grpcOpts...)
 		}
 		var lastErr error
-		for attempt := uint(0); attempt < callOpts.max; attempt++ {
+		for attempt := uint(0); attempt < max; attempt++ {
+			if attempt > 0 && !callOpts.budget.allow() {
+				logger.Warn(
+					"retrying of unary invoker stopped by the retry budget",
+					zap.String("target", cc.Target()),
+					zap.Uint("attempt", attempt),
+				)
+				return lastErr
+			}
 			if err := waitRetryBackoff(ctx, attempt, callOpts); err != nil {
 				return err
 			}
@@ -57,6 +69,7 @@ func (c *Client) unaryClientInterceptor(logger *zap.Logger, optFuncs ...retryOpt
 			)
 			lastErr = invoker(ctx, method, req, reply, cc, grpcOpts...)
 			if lastErr == nil {
+				callOpts.budget.success()
 				return nil
 			}
 			logger.Warn(
@@ -88,6 +101,7 @@ func (c *Client) unaryClientInterceptor(logger *zap.Logger, optFuncs ...retryOpt
 			if !isSafeRetry(c.lg, lastErr, callOpts) {
 				return lastErr
 			}
+			callOpts.budget.failure()
 		}
 		return lastErr
 	}
@@ -108,7 +122,7 @@ func (c *Client) streamClientInterceptor(logger *zap.Logger, optFuncs ...retryOp
 		// getToken automatically
 		// TODO(cfc4n): keep this code block, remove codes about getToken in client.go after pr #12165 merged.
 		if c.authTokenBundle != nil {
-			// equal to c.Username != "" && c.Password != ""
+			// equal to c.authEnabled()
 			err := c.getToken(ctx)
 			if err != nil && rpctypes.Error(err) != rpctypes.ErrAuthNotEnabled {
 				logger.Error("clientv3/retry_interceptor: getToken failed", zap.Error(err))
@@ -349,6 +363,21 @@ func withMax(maxRetries uint) retryOption {
 	}}
 }
 
+// withMutableMax sets the maximum number of retries of unary calls with the
+// nonRepeatable retry policy on this interceptor.
+func withMutableMax(maxRetries uint) retryOption {
+	return retryOption{applyFunc: func(o *options) {
+		o.mutableMax = maxRetries
+	}}
+}
+
+// withBudget sets the retry budget of this interceptor.
+func withBudget(b *retryBudget) retryOption {
+	return retryOption{applyFunc: func(o *options) {
+		o.budget = b
+	}}
+}
+
 // WithBackoff sets the `BackoffFunc `used to control time between retries.
 func withBackoff(bf backoffFunc) retryOption {
 	return retryOption{applyFunc: func(o *options) {
@@ -359,8 +388,10 @@ func withBackoff(bf backoffFunc) retryOption {
 type options struct {
 	retryPolicy retryPolicy
 	max         uint
+	mutableMax  uint
 	backoffFunc backoffFunc
 	retryAuth   bool
+	budget      *retryBudget
 }
 
 // retryOption is a grpc.CallOption that is local to clientv3's retry interceptor.
@@ -0,0 +1,157 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures how the client retries the requests that fail with
+// an error showing they were not processed, such as an unavailable member.
+type RetryPolicy struct {
+	// MaxImmutableRetries is the maximum number of times a unary request
+	// that does not modify the cluster, such as Range, is retried. Such
+	// requests are retried on any unavailable error. 0 disables retries.
+	MaxImmutableRetries uint
+
+	// MaxMutableRetries is the maximum number of times a unary request that
+	// may modify the cluster, such as Put or Txn, is retried. Such requests
+	// are only retried when they could not be sent to any member, so they
+	// are never applied twice. 0 disables retries.
+	MaxMutableRetries uint
+
+	// Backoff returns the wait before the given retry attempt, starting at
+	// 1. If nil, the client retries right away against the next members,
+	// and waits BackoffWaitBetween after each round of retries across a
+	// quorum of the endpoints.
+	Backoff func(attempt uint) time.Duration
+
+	// BackoffWaitBetween is the wait between rounds of retries when Backoff
+	// is nil.
+	BackoffWaitBetween time.Duration
+
+	// BackoffJitterFraction randomly adjusts the waits by up to this
+	// fraction of their duration, so that clients failing together do not
+	// retry together.
+	BackoffJitterFraction float64
+
+	// Budget, if set, limits the retries of unary requests when most of
+	// them fail, so that retries do not overload a struggling cluster.
+	Budget *RetryBudget
+}
+
+// RetryBudget limits retries as gRPC retry throttling does. The client
+// holds up to MaxTokens tokens; a failed attempt takes one, and a
+// successful one gives back TokenRatio. Requests are only retried while
+// more than half of the tokens are left.
+type RetryBudget struct {
+	MaxTokens  float64
+	TokenRatio float64
+}
+
+// DefaultRetryPolicy returns the retry policy of a client configured with
+// no RetryPolicy.
+func DefaultRetryPolicy() *RetryPolicy {
+	// defaultUnaryMaxRetries counts the first attempt too.
+	return &RetryPolicy{
+		MaxImmutableRetries:   defaultUnaryMaxRetries - 1,
+		MaxMutableRetries:     defaultUnaryMaxRetries - 1,
+		BackoffWaitBetween:    defaultBackoffWaitBetween,
+		BackoffJitterFraction: defaultBackoffJitterFraction,
+	}
+}
+
+// BackoffExponential returns a RetryPolicy.Backoff doubling the wait from
+// base at each attempt, up to max.
+func BackoffExponential(base, max time.Duration) func(attempt uint) time.Duration {
+	return func(attempt uint) time.Duration {
+		wait := base
+		for i := uint(1); i < attempt && wait < max; i++ {
+			wait *= 2
+		}
+		if wait > max {
+			wait = max
+		}
+		return wait
+	}
+}
+
+// retryBackoff returns the backoff of the retry policy.
+func (c *Client) retryBackoff(rp *RetryPolicy) backoffFunc {
+	if rp.Backoff == nil {
+		return c.roundRobinQuorumBackoff(rp.BackoffWaitBetween, rp.BackoffJitterFraction)
+	}
+	return func(attempt uint) time.Duration {
+		return jitterUp(rp.Backoff(attempt), rp.BackoffJitterFraction)
+	}
+}
+
+// retryAttempts returns the number of attempts of a request retried at most
+// retries times.
+func retryAttempts(retries uint) uint {
+	if retries == ^uint(0) {
+		return retries
+	}
+	return retries + 1
+}
+
+// retryBudget is the state of a RetryBudget. A nil retryBudget allows every
+// retry.
+type retryBudget struct {
+	mu     sync.Mutex
+	max    float64
+	ratio  float64
+	tokens float64
+}
+
+func newRetryBudget(b *RetryBudget) *retryBudget {
+	if b == nil {
+		return nil
+	}
+	return &retryBudget{max: b.MaxTokens, ratio: b.TokenRatio, tokens: b.MaxTokens}
+}
+
+// allow returns true if a request may be retried.
+func (b *retryBudget) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.max/2
+}
+
+func (b *retryBudget) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens--; b.tokens < 0 {
+		b.tokens = 0
+	}
+}
+
+func (b *retryBudget) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens += b.ratio; b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
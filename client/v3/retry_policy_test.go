@@ -0,0 +1,108 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoffExponential(t *testing.T) {
+	backoff := BackoffExponential(10*time.Millisecond, 50*time.Millisecond)
+	for attempt, want := range []time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 4: 50 * time.Millisecond, 5: 50 * time.Millisecond} {
+		if attempt == 0 {
+			continue
+		}
+		if got := backoff(uint(attempt)); got != want {
+			t.Errorf("attempt %d: expected wait %v, got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestRetryAttempts(t *testing.T) {
+	tests := []struct {
+		retries  uint
+		attempts uint
+	}{
+		{0, 1},
+		{1, 2},
+		{100, 101},
+		{^uint(0), ^uint(0)},
+	}
+	for i, tt := range tests {
+		if got := retryAttempts(tt.retries); got != tt.attempts {
+			t.Errorf("#%d: expected %d attempts for %d retries, got %d", i, tt.attempts, tt.retries, got)
+		}
+	}
+}
+
+func TestDefaultRetryPolicyAttempts(t *testing.T) {
+	attempts := uint(0)
+	unavailable := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		attempts++
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	c, err := New(Config{
+		Endpoints:   []string{"127.0.0.1:12345"},
+		DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(unavailable)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err = c.Get(context.Background(), "foo"); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+	if attempts != defaultUnaryMaxRetries {
+		t.Fatalf("expected %d attempts with no retry policy, got %d", defaultUnaryMaxRetries, attempts)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	var unlimited *retryBudget
+	unlimited.failure()
+	if !unlimited.allow() {
+		t.Fatal("expected a nil budget to allow retries")
+	}
+
+	b := newRetryBudget(&RetryBudget{MaxTokens: 4, TokenRatio: 0.5})
+	if !b.allow() {
+		t.Fatal("expected a full budget to allow retries")
+	}
+	b.failure()
+	if !b.allow() {
+		t.Fatal("expected 3 of 4 tokens to allow retries")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatal("expected 2 of 4 tokens not to allow retries")
+	}
+	b.success()
+	if !b.allow() {
+		t.Fatal("expected 2.5 of 4 tokens to allow retries")
+	}
+	for i := 0; i < 10; i++ {
+		b.success()
+	}
+	if b.tokens != 4 {
+		t.Fatalf("expected tokens to be capped at 4, got %v", b.tokens)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+)
+
+// RangeSpec is a range read by SnapshotGet: the keys Get(Key, Opts...)
+// returns.
+type RangeSpec struct {
+	Key  string
+	Opts []OpOption
+}
+
+// SnapshotGetResponse holds the responses of the ranges read by SnapshotGet,
+// in the order of the specs, and the revision they were all read at.
+type SnapshotGetResponse struct {
+	Rev       int64
+	Responses []*GetResponse
+}
+
+// SnapshotGet reads several ranges at a single revision, so that they are
+// consistent with each other, with a Range request per range. Unless the
+// first spec sets a revision with WithRev, the ranges are read at the
+// revision the first one is served at. The other specs must not set a
+// different revision. If that revision is compacted before the last range
+// is read, SnapshotGet fails with ErrCompacted.
+//
+// Unlike a transaction of gets, the ranges may together exceed the limits
+// of a single request, and are only bounded by those of a Range request.
+func SnapshotGet(ctx context.Context, kv KV, specs []RangeSpec) (*SnapshotGetResponse, error) {
+	if len(specs) == 0 {
+		return &SnapshotGetResponse{}, nil
+	}
+	ops := make([]Op, len(specs))
+	for i, spec := range specs {
+		ops[i] = OpGet(spec.Key, spec.Opts...)
+		if i > 0 && ops[i].rev != 0 && ops[i].rev != ops[0].rev {
+			return nil, fmt.Errorf("clientv3: range %d of SnapshotGet is at revision %d, not at revision %d of the first range", i, ops[i].rev, ops[0].rev)
+		}
+	}
+
+	resp := &SnapshotGetResponse{Rev: ops[0].rev, Responses: make([]*GetResponse, 0, len(ops))}
+	for _, op := range ops {
+		op.rev = resp.Rev
+		oresp, err := kv.Do(ctx, op)
+		if err != nil {
+			return nil, err
+		}
+		gresp := oresp.Get()
+		if resp.Rev == 0 {
+			resp.Rev = gresp.Header.Revision
+		}
+		resp.Responses = append(resp.Responses, gresp)
+	}
+	return resp, nil
+}
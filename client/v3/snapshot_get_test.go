@@ -0,0 +1,76 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotGet(t *testing.T) {
+	tests := []struct {
+		specs []RangeSpec
+
+		keys [][]string
+		revs []int64
+		rev  int64
+	}{
+		{
+			[]RangeSpec{{"a/", []OpOption{WithPrefix()}}, {"b/1", nil}},
+			[][]string{{"a/1", "a/2"}, {"b/1"}}, []int64{0, 7}, 7,
+		},
+		{
+			[]RangeSpec{{"a/1", []OpOption{WithRev(5)}}, {"b/", []OpOption{WithPrefix()}}, {"a/2", []OpOption{WithRev(5)}}},
+			[][]string{{"a/1"}, {"b/1"}, {"a/2"}}, []int64{5, 5, 5}, 5,
+		},
+		{nil, nil, nil, 0},
+	}
+	for i, tt := range tests {
+		kv := &rangeKV{keys: []string{"a/1", "a/2", "b/1"}, rev: 7}
+		resp, err := SnapshotGet(context.TODO(), kv, tt.specs)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		var keys [][]string
+		for _, gresp := range resp.Responses {
+			var ks []string
+			for _, kv := range gresp.Kvs {
+				ks = append(ks, string(kv.Key))
+			}
+			keys = append(keys, ks)
+		}
+		if !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("#%d: expected keys %v, got %v", i, tt.keys, keys)
+		}
+		var revs []int64
+		for _, r := range kv.reqs {
+			revs = append(revs, r.Revision)
+		}
+		if !reflect.DeepEqual(revs, tt.revs) {
+			t.Errorf("#%d: expected request revisions %v, got %v", i, tt.revs, revs)
+		}
+		if resp.Rev != tt.rev {
+			t.Errorf("#%d: expected revision %d, got %d", i, tt.rev, resp.Rev)
+		}
+	}
+}
+
+func TestSnapshotGetConflictingRevisions(t *testing.T) {
+	specs := []RangeSpec{{"a/1", []OpOption{WithRev(5)}}, {"a/2", []OpOption{WithRev(6)}}}
+	if _, err := SnapshotGet(context.TODO(), &rangeKV{}, specs); err == nil {
+		t.Fatal("expected error for ranges at different revisions")
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"errors"
+	"time"
+)
+
+// streamRebalanceJitter is the fraction of the stream rebalance interval
+// randomly added or subtracted, so that the streams of many clients are not
+// cycled at once.
+const streamRebalanceJitter = 0.2
+
+// errWatchStreamRebalanced ends a watch grpc stream canceled to be cycled.
+var errWatchStreamRebalanced = errors.New("clientv3: watch stream rebalanced")
+
+// rebalanceLoop cycles the keepalive stream every rebalance interval, for
+// the lifetime of the keepalives. The keepalives are sent on the next
+// stream, opened right away.
+func (l *lessor) rebalanceLoop() {
+	for {
+		select {
+		case <-time.After(jitterUp(l.rebalanceInterval, streamRebalanceJitter)):
+		case <-l.donec:
+			return
+		}
+		l.mu.Lock()
+		if l.stream != nil && l.streamCancel != nil {
+			l.rebalanced = true
+			l.streamCancel()
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// rebalanceWatchClient opens streams creating the watches requested, and
+// records the revision each watch is created at.
+type rebalanceWatchClient struct {
+	mu   sync.Mutex
+	revs []int64
+	// events are sent on the first stream once the watch is created
+	events []*mvccpb.Event
+}
+
+func (c *rebalanceWatchClient) Watch(ctx context.Context, _ ...grpc.CallOption) (pb.Watch_WatchClient, error) {
+	return &rebalanceWatchStream{ctx: ctx, c: c, respc: make(chan *pb.WatchResponse, 2)}, nil
+}
+
+func (c *rebalanceWatchClient) created() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int64(nil), c.revs...)
+}
+
+type rebalanceWatchStream struct {
+	pb.Watch_WatchClient
+	ctx   context.Context
+	c     *rebalanceWatchClient
+	respc chan *pb.WatchResponse
+}
+
+func (s *rebalanceWatchStream) Send(req *pb.WatchRequest) error {
+	cr := req.GetCreateRequest()
+	if cr == nil {
+		return nil
+	}
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	s.c.revs = append(s.c.revs, cr.StartRevision)
+	s.respc <- &pb.WatchResponse{Header: &pb.ResponseHeader{Revision: 4}, Created: true}
+	if len(s.c.events) > 0 {
+		s.respc <- &pb.WatchResponse{Header: &pb.ResponseHeader{Revision: 5}, Events: s.c.events}
+		s.c.events = nil
+	}
+	return nil
+}
+
+func (s *rebalanceWatchStream) Recv() (*pb.WatchResponse, error) {
+	select {
+	case resp := <-s.respc:
+		return resp, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func TestWatchStreamRebalance(t *testing.T) {
+	wc := &rebalanceWatchClient{events: []*mvccpb.Event{{Kv: &mvccpb.KeyValue{Key: []byte("foo"), ModRevision: 5}}}}
+	w := &watcher{remote: wc, streams: make(map[string]*watchGrpcStream), lg: zap.NewNop(), rebalanceInterval: 10 * time.Millisecond}
+	defer w.Close()
+
+	wch := w.Watch(context.Background(), "foo")
+	select {
+	case wr := <-wch:
+		if len(wr.Events) != 1 {
+			t.Fatalf("events = %v, want one", wr.Events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event in one second")
+	}
+
+	// the watch resumes on the next streams after the event received
+	deadline := time.Now().Add(time.Second)
+	for len(wc.created()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	revs := wc.created()
+	if len(revs) < 3 {
+		t.Fatalf("watch created %d times, want the stream cycled", len(revs))
+	}
+	for i, rev := range revs[1:] {
+		if rev != 6 {
+			t.Fatalf("#%d: watch resumed at revision %d, want 6", i+1, rev)
+		}
+	}
+}
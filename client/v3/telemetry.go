@@ -0,0 +1,250 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// instrumentationName names the tracer and meter of the client.
+const instrumentationName = "go.etcd.io/etcd/client/v3"
+
+// The interfaces below are the subset of the OpenTelemetry trace and metric
+// APIs the client instruments its requests with, so that providers of any
+// OpenTelemetry SDK, or of other telemetry libraries, can be plugged in with
+// a thin adapter.
+
+// TracerProvider provides the tracer of the client.
+type TracerProvider interface {
+	Tracer(instrumentationName string) Tracer
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span, child of the span in ctx if any, and returns a
+	// context holding it.
+	Start(ctx context.Context, spanName string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// MeterProvider provides the meter of the client.
+type MeterProvider interface {
+	Meter(instrumentationName string) Meter
+}
+
+// Meter creates instruments.
+type Meter interface {
+	Int64Counter(name, unit, description string) Int64Counter
+	Float64Histogram(name, unit, description string) Float64Histogram
+}
+
+// Int64Counter is a monotonic counter.
+type Int64Counter interface {
+	Add(ctx context.Context, incr int64, attrs ...Attribute)
+}
+
+// Float64Histogram records a distribution of values.
+type Float64Histogram interface {
+	Record(ctx context.Context, value float64, attrs ...Attribute)
+}
+
+// Attribute is a key-value pair describing a span or a measurement. Values
+// are strings, bools, int64s or float64s.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// telemetry instruments the client requests with the configured tracer and
+// meter.
+type telemetry struct {
+	tracer Tracer
+
+	requests Int64Counter
+	attempts Int64Counter
+	duration Float64Histogram
+	messages Int64Counter
+}
+
+// newTelemetry returns the telemetry of the client, nil if neither a tracer
+// nor a meter provider is configured.
+func newTelemetry(tp TracerProvider, mp MeterProvider) *telemetry {
+	if tp == nil && mp == nil {
+		return nil
+	}
+	t := &telemetry{tracer: noopTracer{}}
+	if tp != nil {
+		t.tracer = tp.Tracer(instrumentationName)
+	}
+	if mp == nil {
+		mp = noopMeterProvider{}
+	}
+	m := mp.Meter(instrumentationName)
+	t.requests = m.Int64Counter("etcd.client.requests", "{request}", "Number of requests, counting each retried request once.")
+	t.attempts = m.Int64Counter("etcd.client.attempts", "{attempt}", "Number of attempts of requests, including retries.")
+	t.duration = m.Float64Histogram("etcd.client.request.duration", "s", "Duration of requests, including retries, or lifetime of streams.")
+	t.messages = m.Int64Counter("etcd.client.stream.messages", "{message}", "Number of messages sent and received on streams.")
+	return t
+}
+
+// unaryClientInterceptor returns an interceptor tracing and measuring the
+// requests retried by the retry interceptor, with a span per request and a
+// child span per attempt.
+func (t *telemetry) unaryClientInterceptor(retry grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, span := t.tracer.Start(ctx, method, methodAttr(method))
+		attempt := int64(0)
+		err := retry(ctx, method, req, reply, cc, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			attempt++
+			actx, aspan := t.tracer.Start(ctx, "attempt", Attribute{Key: "rpc.etcd.attempt", Value: attempt})
+			err := invoker(actx, method, req, reply, cc, opts...)
+			t.attempts.Add(ctx, 1, methodAttr(method), codeAttr(err))
+			endSpan(aspan, err)
+			return err
+		}, opts...)
+		span.SetAttributes(Attribute{Key: "rpc.etcd.attempts", Value: attempt})
+		t.end(ctx, span, method, start, err)
+		return err
+	}
+}
+
+// streamClientInterceptor returns an interceptor tracing and measuring the
+// streams, such as watches and lease keepalives, created by the retry
+// interceptor. The span of a stream lasts until it ends, and records an
+// event per attempt to create it; messages are only counted, as long-lived
+// streams may carry many.
+func (t *telemetry) streamClientInterceptor(retry grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, span := t.tracer.Start(ctx, method, methodAttr(method))
+		attempt := int64(0)
+		cs, err := retry(ctx, desc, cc, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			attempt++
+			span.AddEvent("attempt", Attribute{Key: "rpc.etcd.attempt", Value: attempt})
+			cs, err := streamer(ctx, desc, cc, method, opts...)
+			t.attempts.Add(ctx, 1, methodAttr(method), codeAttr(err))
+			return cs, err
+		}, opts...)
+		if err != nil {
+			t.end(ctx, span, method, start, err)
+			return nil, err
+		}
+		return &tracedStream{ClientStream: cs, t: t, ctx: ctx, span: span, method: method, start: start}, nil
+	}
+}
+
+// end ends the span of a request, and records its metrics.
+func (t *telemetry) end(ctx context.Context, span Span, method string, start time.Time, err error) {
+	attrs := []Attribute{methodAttr(method), codeAttr(err)}
+	t.requests.Add(ctx, 1, attrs...)
+	t.duration.Record(ctx, time.Since(start).Seconds(), attrs...)
+	endSpan(span, err)
+}
+
+type tracedStream struct {
+	grpc.ClientStream
+	t      *telemetry
+	ctx    context.Context
+	span   Span
+	method string
+	start  time.Time
+	once   sync.Once
+}
+
+func (s *tracedStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.t.messages.Add(s.ctx, 1, methodAttr(s.method), Attribute{Key: "rpc.message.type", Value: "SENT"})
+	} else if err != io.EOF {
+		// io.EOF is returned when the stream ended, the error is then
+		// returned by RecvMsg
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *tracedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch err {
+	case nil:
+		s.t.messages.Add(s.ctx, 1, methodAttr(s.method), Attribute{Key: "rpc.message.type", Value: "RECEIVED"})
+	case io.EOF:
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *tracedStream) finish(err error) {
+	s.once.Do(func() { s.t.end(s.ctx, s.span, s.method, s.start, err) })
+}
+
+func endSpan(span Span, err error) {
+	span.SetAttributes(codeAttr(err))
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func methodAttr(method string) Attribute {
+	return Attribute{Key: "rpc.method", Value: method}
+}
+
+func codeAttr(err error) Attribute {
+	return Attribute{Key: "rpc.grpc.status_code", Value: status.Code(err).String()}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute)    {}
+func (noopSpan) AddEvent(string, ...Attribute) {}
+func (noopSpan) RecordError(error)             {}
+func (noopSpan) End()                          {}
+
+type noopMeterProvider struct{}
+
+func (noopMeterProvider) Meter(string) Meter { return noopMeter{} }
+
+type noopMeter struct{}
+
+func (noopMeter) Int64Counter(string, string, string) Int64Counter         { return noopInstrument{} }
+func (noopMeter) Float64Histogram(string, string, string) Float64Histogram { return noopInstrument{} }
+
+type noopInstrument struct{}
+
+func (noopInstrument) Add(context.Context, int64, ...Attribute)      {}
+func (noopInstrument) Record(context.Context, float64, ...Attribute) {}
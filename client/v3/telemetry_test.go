@@ -0,0 +1,122 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type recordingTelemetry struct {
+	mu       sync.Mutex
+	spans    []string
+	counters map[string]int64
+}
+
+func newRecordingTelemetry() *recordingTelemetry {
+	return &recordingTelemetry{counters: make(map[string]int64)}
+}
+
+func (r *recordingTelemetry) Tracer(string) Tracer { return r }
+func (r *recordingTelemetry) Meter(string) Meter   { return r }
+
+func (r *recordingTelemetry) Start(ctx context.Context, name string, _ ...Attribute) (context.Context, Span) {
+	return ctx, &recordingSpan{r: r, name: name}
+}
+
+func (r *recordingTelemetry) Int64Counter(name, _, _ string) Int64Counter {
+	return &recordingCounter{r: r, name: name}
+}
+
+func (r *recordingTelemetry) Float64Histogram(string, string, string) Float64Histogram {
+	return noopInstrument{}
+}
+
+type recordingSpan struct {
+	r    *recordingTelemetry
+	name string
+	code string
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		if attr.Key == "rpc.grpc.status_code" {
+			s.code = attr.Value.(string)
+		}
+	}
+}
+func (s *recordingSpan) AddEvent(string, ...Attribute) {}
+func (s *recordingSpan) RecordError(error)             {}
+func (s *recordingSpan) End() {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.spans = append(s.r.spans, s.name+":"+s.code)
+}
+
+type recordingCounter struct {
+	r    *recordingTelemetry
+	name string
+}
+
+func (c *recordingCounter) Add(_ context.Context, incr int64, _ ...Attribute) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.counters[c.name] += incr
+}
+
+func TestTelemetryUnaryClientInterceptor(t *testing.T) {
+	r := newRecordingTelemetry()
+	tel := newTelemetry(r, r)
+
+	// retry once after an unavailable error
+	retry := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err == nil {
+			return nil
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		if calls == 1 {
+			return status.Error(codes.Unavailable, "unavailable")
+		}
+		return nil
+	}
+
+	if err := tel.unaryClientInterceptor(retry)(context.Background(), "/etcdserverpb.KV/Range", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	wspans := []string{"attempt:Unavailable", "attempt:OK", "/etcdserverpb.KV/Range:OK"}
+	if !reflect.DeepEqual(r.spans, wspans) {
+		t.Errorf("spans = %v, want %v", r.spans, wspans)
+	}
+	wcounters := map[string]int64{"etcd.client.requests": 1, "etcd.client.attempts": 2}
+	if !reflect.DeepEqual(r.counters, wcounters) {
+		t.Errorf("counters = %v, want %v", r.counters, wcounters)
+	}
+}
+
+func TestNewTelemetryDisabled(t *testing.T) {
+	if tel := newTelemetry(nil, nil); tel != nil {
+		t.Fatalf("telemetry = %+v, want nil", tel)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testing provides an in-memory fake of etcd for the unit tests of
+// applications using clientv3, serving KV, Watcher, Lease and Txn requests
+// with the semantics of an etcd server, without starting one.
+//
+// Create a Fake, and clients sharing its key-value store:
+//
+//	f := testing.New()
+//	cli := f.Client()
+//	defer cli.Close()
+//
+//	cli.Put(ctx, "foo", "bar")
+//	resp, _ := cli.Get(ctx, "foo")
+//
+// The fake keeps the revisions of the keys until they are compacted, so
+// that they can be read at past revisions and watched from them, and
+// answers requests at compacted or future revisions with the errors of
+// etcd, as it does invalid transactions and requests on missing leases.
+//
+// Leases and keys put with a TTL expire on the clock of the fake, which
+// starts at the time the fake is created and only moves with Advance, so
+// that tests control when they expire:
+//
+//	resp, _ := cli.Grant(ctx, 10)
+//	cli.Put(ctx, "foo", "bar", clientv3.WithLease(resp.ID))
+//	cli.Put(ctx, "baz", "qux", clientv3.WithExpiry(10))
+//	f.Advance(10 * time.Second) // the lease expires, deleting "foo", and "baz" expires
+//
+// Cluster, Maintenance and Auth requests are not supported; the clients
+// of the fake have no Cluster, Maintenance and Auth APIs.
+package testing
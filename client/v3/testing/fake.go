@@ -0,0 +1,60 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// Fake is an in-memory etcd serving the clients it creates.
+type Fake struct {
+	s *store
+}
+
+// New returns a Fake with an empty key-value store, at revision 1.
+func New() *Fake {
+	return &Fake{s: newStore()}
+}
+
+// Client returns a new client of the fake, with KV, Watcher and Lease
+// APIs. Clients of the same fake share its key-value store.
+func (f *Fake) Client() *clientv3.Client {
+	c := clientv3.NewCtxClient(context.Background())
+	c.KV = clientv3.NewKVFromKVClient(&kvClient{s: f.s}, c)
+	c.Lease = clientv3.NewLeaseFromLeaseClient(&leaseClient{s: f.s}, c, time.Second)
+	c.Watcher = clientv3.NewWatchFromWatchClient(&watchClient{s: f.s}, c)
+	return c
+}
+
+// Advance moves the clock of the fake forward by d, expiring the leases
+// whose TTL elapsed since they were granted or last kept alive, and the
+// keys whose TTL elapsed since they were put. The keep alives of clients
+// are sent on the real clock.
+func (f *Fake) Advance(d time.Duration) {
+	f.s.mu.Lock()
+	defer f.s.mu.Unlock()
+	f.s.now = f.s.now.Add(d)
+	f.s.expire()
+}
+
+// Revision returns the current revision of the key-value store.
+func (f *Fake) Revision() int64 {
+	f.s.mu.Lock()
+	defer f.s.mu.Unlock()
+	return f.s.rev
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/v3"
+)
+
+func TestFakeKV(t *testing.T) {
+	cli := New().Client()
+	defer cli.Close()
+	ctx := context.Background()
+
+	for _, v := range []string{"a", "b"} {
+		if _, err := cli.Put(ctx, "foo", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := cli.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "b" || resp.Kvs[0].Version != 2 || resp.Header.Revision != 3 {
+		t.Fatalf("get = %+v, want foo=b at version 2, revision 3", resp)
+	}
+	if resp, err = cli.Get(ctx, "foo", clientv3.WithRev(2)); err != nil || string(resp.Kvs[0].Value) != "a" {
+		t.Fatalf("get at revision 2 = %+v, %v, want foo=a", resp, err)
+	}
+
+	if _, err = cli.Compact(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Get(ctx, "foo", clientv3.WithRev(2)); err != rpctypes.ErrCompacted {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrCompacted)
+	}
+	if _, err = cli.Get(ctx, "foo", clientv3.WithRev(4)); err != rpctypes.ErrFutureRev {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrFutureRev)
+	}
+
+	// a transaction applies at a single revision
+	tresp, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value("foo"), "=", "b")).
+		Then(clientv3.OpPut("bar", "1"), clientv3.OpDelete("foo"), clientv3.OpGet("", clientv3.WithPrefix())).
+		Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tresp.Succeeded || tresp.Header.Revision != 4 {
+		t.Fatalf("txn = %+v, want succeeded at revision 4", tresp)
+	}
+	if kvs := tresp.Responses[2].GetResponseRange().Kvs; len(kvs) != 1 || string(kvs[0].Key) != "bar" {
+		t.Fatalf("txn get = %+v, want bar", kvs)
+	}
+	_, err = cli.Txn(ctx).Then(clientv3.OpPut("baz", "1"), clientv3.OpPut("baz", "2")).Commit()
+	if err != rpctypes.ErrDuplicateKey {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrDuplicateKey)
+	}
+	if resp, err = cli.Get(ctx, "baz"); err != nil || len(resp.Kvs) != 0 || resp.Header.Revision != 4 {
+		t.Fatalf("get = %+v, %v, want no key at revision 4", resp, err)
+	}
+}
+
+func TestFakeWatch(t *testing.T) {
+	cli := New().Client()
+	defer cli.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := cli.Put(ctx, "foo", "a"); err != nil {
+		t.Fatal(err)
+	}
+	wch := cli.Watch(ctx, "foo", clientv3.WithRev(1), clientv3.WithPrevKV())
+	if _, err := cli.Delete(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []mvccpb.Event_EventType{mvccpb.PUT, mvccpb.DELETE} {
+		select {
+		case wr := <-wch:
+			if len(wr.Events) != 1 || wr.Events[0].Type != want {
+				t.Fatalf("events = %v, want a %v", wr.Events, want)
+			}
+			if want == mvccpb.DELETE && string(wr.Events[0].PrevKv.Value) != "a" {
+				t.Fatalf("prev kv = %v, want foo=a", wr.Events[0].PrevKv)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("failed to receive event in one second")
+		}
+	}
+}
+
+func TestFakeLeaseExpiry(t *testing.T) {
+	f := New()
+	cli := f.Client()
+	defer cli.Close()
+	ctx := context.Background()
+
+	lresp, err := cli.Grant(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(ctx, "foo", "bar", clientv3.WithLease(lresp.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(ctx, "bar", "baz", clientv3.WithLease(lresp.ID+1)); err != rpctypes.ErrLeaseNotFound {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrLeaseNotFound)
+	}
+
+	f.Advance(5 * time.Second)
+	if tresp, err := cli.TimeToLive(ctx, lresp.ID, clientv3.WithAttachedKeys()); err != nil || tresp.TTL != 5 || len(tresp.Keys) != 1 {
+		t.Fatalf("ttl = %+v, %v, want 5s with one key", tresp, err)
+	}
+	f.Advance(5 * time.Second)
+	if resp, err := cli.Get(ctx, "foo"); err != nil || len(resp.Kvs) != 0 {
+		t.Fatalf("get = %+v, %v, want foo deleted", resp, err)
+	}
+	if tresp, err := cli.TimeToLive(ctx, lresp.ID); err != nil || tresp.TTL != -1 {
+		t.Fatalf("ttl = %+v, %v, want -1", tresp, err)
+	}
+}
+
+func TestFakeKeyExpiry(t *testing.T) {
+	f := New()
+	cli := f.Client()
+	defer cli.Close()
+	ctx := context.Background()
+
+	if _, err := cli.Put(ctx, "foo", "bar", clientv3.WithExpiry(10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Put(ctx, "baz", "qux", clientv3.WithExpiry(20)); err != nil {
+		t.Fatal(err)
+	}
+	wch := cli.Watch(ctx, "foo")
+
+	f.Advance(5 * time.Second)
+	// putting the key again restarts its TTL
+	if _, err := cli.Put(ctx, "baz", "qux", clientv3.WithExpiry(10)); err != nil {
+		t.Fatal(err)
+	}
+	f.Advance(5 * time.Second)
+	if resp, err := cli.Get(ctx, "", clientv3.WithPrefix()); err != nil || len(resp.Kvs) != 1 || string(resp.Kvs[0].Key) != "baz" {
+		t.Fatalf("get = %+v, %v, want foo deleted", resp, err)
+	}
+	select {
+	case wr := <-wch:
+		if len(wr.Events) != 1 || wr.Events[0].Type != mvccpb.DELETE {
+			t.Fatalf("events = %v, want the delete of foo", wr.Events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed to receive event in one second")
+	}
+	f.Advance(5 * time.Second)
+	if resp, err := cli.Get(ctx, "baz"); err != nil || len(resp.Kvs) != 0 {
+		t.Fatalf("get = %+v, %v, want baz deleted", resp, err)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"google.golang.org/grpc"
+)
+
+// kvClient is a pb.KVClient of a store.
+type kvClient struct {
+	s *store
+}
+
+func (c *kvClient) Range(ctx context.Context, req *pb.RangeRequest, _ ...grpc.CallOption) (*pb.RangeResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	resp, err := c.s.rangeReq(req, c.s.rev)
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = c.s.header()
+	return resp, nil
+}
+
+func (c *kvClient) Put(ctx context.Context, req *pb.PutRequest, _ ...grpc.CallOption) (*pb.PutResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	w := c.s.write()
+	resp, err := w.put(req)
+	if err != nil {
+		w.abort()
+		return nil, err
+	}
+	w.commit()
+	resp.Header = c.s.header()
+	return resp, nil
+}
+
+func (c *kvClient) DeleteRange(ctx context.Context, req *pb.DeleteRangeRequest, _ ...grpc.CallOption) (*pb.DeleteRangeResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	w := c.s.write()
+	resp, err := w.deleteRange(req)
+	if err != nil {
+		w.abort()
+		return nil, err
+	}
+	w.commit()
+	resp.Header = c.s.header()
+	return resp, nil
+}
+
+func (c *kvClient) Txn(ctx context.Context, req *pb.TxnRequest, _ ...grpc.CallOption) (*pb.TxnResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	w := c.s.write()
+	resp, err := w.txn(req)
+	if err != nil {
+		w.abort()
+		return nil, err
+	}
+	w.commit()
+	setHeaders(resp, c.s.header())
+	return resp, nil
+}
+
+func (c *kvClient) Compact(ctx context.Context, req *pb.CompactionRequest, _ ...grpc.CallOption) (*pb.CompactionResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if err := c.s.compact(req.Revision); err != nil {
+		return nil, err
+	}
+	return &pb.CompactionResponse{Header: c.s.header()}, nil
+}
+
+// rangeReq serves a range request, reading at rev unless the request
+// gives a revision.
+func (s *store) rangeReq(req *pb.RangeRequest, rev int64) (*pb.RangeResponse, error) {
+	if err := s.checkRev(req.Revision); err != nil {
+		return nil, err
+	}
+	if req.Revision > 0 {
+		rev = req.Revision
+	}
+	kvs := s.rangeKeys(req.Key, req.RangeEnd, rev)
+	resp := &pb.RangeResponse{Count: int64(len(kvs))}
+	if req.CountOnly {
+		return resp, nil
+	}
+
+	sortKVs(kvs, req.SortOrder, req.SortTarget)
+	filtered := kvs[:0]
+	for _, kv := range kvs {
+		if (req.MinModRevision > 0 && kv.ModRevision < req.MinModRevision) ||
+			(req.MaxModRevision > 0 && kv.ModRevision > req.MaxModRevision) ||
+			(req.MinCreateRevision > 0 && kv.CreateRevision < req.MinCreateRevision) ||
+			(req.MaxCreateRevision > 0 && kv.CreateRevision > req.MaxCreateRevision) {
+			continue
+		}
+		filtered = append(filtered, kv)
+	}
+	kvs = filtered
+	if req.Limit > 0 && int64(len(kvs)) > req.Limit {
+		kvs, resp.More = kvs[:req.Limit], true
+	}
+	for _, kv := range kvs {
+		kv := *kv
+		if req.KeysOnly {
+			kv.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, &kv)
+	}
+	return resp, nil
+}
+
+func sortKVs(kvs []*mvccpb.KeyValue, order pb.RangeRequest_SortOrder, target pb.RangeRequest_SortTarget) {
+	if order == pb.RangeRequest_NONE {
+		if target == pb.RangeRequest_KEY {
+			return
+		}
+		order = pb.RangeRequest_ASCEND
+	}
+	var less func(a, b *mvccpb.KeyValue) bool
+	switch target {
+	case pb.RangeRequest_KEY:
+		less = func(a, b *mvccpb.KeyValue) bool { return bytes.Compare(a.Key, b.Key) < 0 }
+	case pb.RangeRequest_VERSION:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.Version < b.Version }
+	case pb.RangeRequest_CREATE:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.CreateRevision < b.CreateRevision }
+	case pb.RangeRequest_MOD:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.ModRevision < b.ModRevision }
+	case pb.RangeRequest_VALUE:
+		less = func(a, b *mvccpb.KeyValue) bool { return bytes.Compare(a.Value, b.Value) < 0 }
+	}
+	if order == pb.RangeRequest_DESCEND {
+		asc := less
+		less = func(a, b *mvccpb.KeyValue) bool { return asc(b, a) }
+	}
+	sort.SliceStable(kvs, func(i, j int) bool { return less(kvs[i], kvs[j]) })
+}
+
+func (w *writer) txn(req *pb.TxnRequest) (*pb.TxnResponse, error) {
+	resp := &pb.TxnResponse{Succeeded: true}
+	for _, cmp := range req.Compare {
+		if !w.compare(cmp) {
+			resp.Succeeded = false
+			break
+		}
+	}
+	ops := req.Success
+	if !resp.Succeeded {
+		ops = req.Failure
+	}
+	for _, op := range ops {
+		rop := &pb.ResponseOp{}
+		switch r := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			resp, err := w.s.rangeReq(r.RequestRange, w.rev)
+			if err != nil {
+				return nil, err
+			}
+			rop.Response = &pb.ResponseOp_ResponseRange{ResponseRange: resp}
+		case *pb.RequestOp_RequestPut:
+			resp, err := w.put(r.RequestPut)
+			if err != nil {
+				return nil, err
+			}
+			rop.Response = &pb.ResponseOp_ResponsePut{ResponsePut: resp}
+		case *pb.RequestOp_RequestDeleteRange:
+			resp, err := w.deleteRange(r.RequestDeleteRange)
+			if err != nil {
+				return nil, err
+			}
+			rop.Response = &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: resp}
+		case *pb.RequestOp_RequestTxn:
+			resp, err := w.txn(r.RequestTxn)
+			if err != nil {
+				return nil, err
+			}
+			rop.Response = &pb.ResponseOp_ResponseTxn{ResponseTxn: resp}
+		}
+		resp.Responses = append(resp.Responses, rop)
+	}
+	return resp, nil
+}
+
+// compare returns true if all the keys in the range of cmp compare as
+// required, at the revision of the writes so far.
+func (w *writer) compare(cmp *pb.Compare) bool {
+	kvs := w.s.rangeKeys(cmp.Key, cmp.RangeEnd, w.rev)
+	if len(kvs) == 0 {
+		// the value of a missing key compares with nothing
+		if cmp.Target == pb.Compare_VALUE {
+			return false
+		}
+		kvs = []*mvccpb.KeyValue{{}}
+	}
+	for _, kv := range kvs {
+		var c int
+		switch u := cmp.TargetUnion.(type) {
+		case *pb.Compare_Version:
+			c = compareInt64(kv.Version, u.Version)
+		case *pb.Compare_CreateRevision:
+			c = compareInt64(kv.CreateRevision, u.CreateRevision)
+		case *pb.Compare_ModRevision:
+			c = compareInt64(kv.ModRevision, u.ModRevision)
+		case *pb.Compare_Value:
+			c = bytes.Compare(kv.Value, u.Value)
+		case *pb.Compare_Lease:
+			c = compareInt64(kv.Lease, u.Lease)
+		}
+		var ok bool
+		switch cmp.Result {
+		case pb.Compare_EQUAL:
+			ok = c == 0
+		case pb.Compare_NOT_EQUAL:
+			ok = c != 0
+		case pb.Compare_GREATER:
+			ok = c > 0
+		case pb.Compare_LESS:
+			ok = c < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// setHeaders sets the header of the response of a transaction and of the
+// responses of its operations.
+func setHeaders(resp *pb.TxnResponse, h *pb.ResponseHeader) {
+	resp.Header = h
+	for _, rop := range resp.Responses {
+		switch r := rop.Response.(type) {
+		case *pb.ResponseOp_ResponseRange:
+			r.ResponseRange.Header = h
+		case *pb.ResponseOp_ResponsePut:
+			r.ResponsePut.Header = h
+		case *pb.ResponseOp_ResponseDeleteRange:
+			r.ResponseDeleteRange.Header = h
+		case *pb.ResponseOp_ResponseTxn:
+			setHeaders(r.ResponseTxn, h)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+
+	"google.golang.org/grpc"
+)
+
+// leaseClient is a pb.LeaseClient of a store.
+type leaseClient struct {
+	s *store
+}
+
+func (c *leaseClient) LeaseGrant(ctx context.Context, req *pb.LeaseGrantRequest, _ ...grpc.CallOption) (*pb.LeaseGrantResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	id := req.ID
+	if id == 0 {
+		for c.s.leases[c.s.nextLeaseID] != nil {
+			c.s.nextLeaseID++
+		}
+		id = c.s.nextLeaseID
+		c.s.nextLeaseID++
+	}
+	if c.s.leases[id] != nil {
+		return nil, rpctypes.ErrGRPCLeaseExist
+	}
+	c.s.leases[id] = &lease{
+		id:     id,
+		ttl:    req.TTL,
+		expiry: c.s.now.Add(time.Duration(req.TTL) * time.Second),
+		keys:   make(map[string]struct{}),
+	}
+	return &pb.LeaseGrantResponse{Header: c.s.header(), ID: id, TTL: req.TTL}, nil
+}
+
+func (c *leaseClient) LeaseRevoke(ctx context.Context, req *pb.LeaseRevokeRequest, _ ...grpc.CallOption) (*pb.LeaseRevokeResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if c.s.leases[req.ID] == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	w := c.s.write()
+	w.revoke(req.ID)
+	w.commit()
+	return &pb.LeaseRevokeResponse{Header: c.s.header()}, nil
+}
+
+func (c *leaseClient) LeaseKeepAlive(ctx context.Context, _ ...grpc.CallOption) (pb.Lease_LeaseKeepAliveClient, error) {
+	return &keepAliveStream{clientStream: clientStream{ctx: ctx}, s: c.s, q: newQueue()}, nil
+}
+
+func (c *leaseClient) LeaseTimeToLive(ctx context.Context, req *pb.LeaseTimeToLiveRequest, _ ...grpc.CallOption) (*pb.LeaseTimeToLiveResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	l := c.s.leases[req.ID]
+	if l == nil {
+		return &pb.LeaseTimeToLiveResponse{Header: c.s.header(), ID: req.ID, TTL: -1}, nil
+	}
+	resp := &pb.LeaseTimeToLiveResponse{Header: c.s.header(), ID: req.ID, TTL: c.s.remaining(l), GrantedTTL: l.ttl}
+	if req.Keys {
+		for key := range l.keys {
+			resp.Keys = append(resp.Keys, []byte(key))
+		}
+		sort.Slice(resp.Keys, func(i, j int) bool { return string(resp.Keys[i]) < string(resp.Keys[j]) })
+	}
+	return resp, nil
+}
+
+func (c *leaseClient) LeaseLeases(ctx context.Context, req *pb.LeaseLeasesRequest, _ ...grpc.CallOption) (*pb.LeaseLeasesResponse, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	resp := &pb.LeaseLeasesResponse{Header: c.s.header()}
+	for id := range c.s.leases {
+		resp.Leases = append(resp.Leases, &pb.LeaseStatus{ID: id})
+	}
+	sort.Slice(resp.Leases, func(i, j int) bool { return resp.Leases[i].ID < resp.Leases[j].ID })
+	return resp, nil
+}
+
+// remaining returns the remaining TTL of the lease, in seconds rounded up.
+func (s *store) remaining(l *lease) int64 {
+	return int64((l.expiry.Sub(s.now) + time.Second - 1) / time.Second)
+}
+
+// keepAliveStream is a pb.Lease_LeaseKeepAliveClient of a store.
+type keepAliveStream struct {
+	clientStream
+	s *store
+	q *queue
+}
+
+func (ks *keepAliveStream) Send(req *pb.LeaseKeepAliveRequest) error {
+	if err := ks.ctx.Err(); err != nil {
+		return err
+	}
+	ks.s.mu.Lock()
+	defer ks.s.mu.Unlock()
+	resp := &pb.LeaseKeepAliveResponse{Header: ks.s.header(), ID: req.ID}
+	// the keepalive of a missing lease is responded with a TTL of 0
+	if l := ks.s.leases[req.ID]; l != nil {
+		l.expiry = ks.s.now.Add(time.Duration(l.ttl) * time.Second)
+		resp.TTL = l.ttl
+	}
+	ks.q.push(resp)
+	return nil
+}
+
+func (ks *keepAliveStream) Recv() (*pb.LeaseKeepAliveResponse, error) {
+	msg, err := ks.q.pop(ks.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*pb.LeaseKeepAliveResponse), nil
+}
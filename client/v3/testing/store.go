@@ -0,0 +1,369 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+const (
+	clusterID = 0x1000
+	memberID  = 0x1
+)
+
+// entry is a revision of a key, deleted or not.
+type entry struct {
+	kv      *mvccpb.KeyValue
+	deleted bool
+	// expiry is the time the key expires if it has a TTL.
+	expiry time.Time
+}
+
+type lease struct {
+	id     int64
+	ttl    int64
+	expiry time.Time
+	keys   map[string]struct{}
+}
+
+// store is an in-memory multi-version key-value store.
+type store struct {
+	mu sync.Mutex
+
+	now        time.Time
+	rev        int64
+	compactRev int64
+
+	// keys are the revisions of the keys since the compaction, in
+	// revision order.
+	keys map[string][]entry
+	// events are the events since the compaction, with their previous
+	// key-value, in revision order.
+	events []*mvccpb.Event
+
+	leases      map[int64]*lease
+	nextLeaseID int64
+
+	watchers map[*watcher]struct{}
+}
+
+func newStore() *store {
+	return &store{
+		now:         time.Now(),
+		rev:         1,
+		keys:        make(map[string][]entry),
+		leases:      make(map[int64]*lease),
+		nextLeaseID: 1,
+		watchers:    make(map[*watcher]struct{}),
+	}
+}
+
+func (s *store) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{ClusterId: clusterID, MemberId: memberID, Revision: s.rev, RaftTerm: 1}
+}
+
+// inRange returns true if key is in the range of a request, the single key
+// k if end is empty, or [k, end), where an end of "\x00" is the end of the
+// key space.
+func inRange(key, k, end []byte) bool {
+	switch {
+	case len(end) == 0:
+		return bytes.Equal(key, k)
+	case len(end) == 1 && end[0] == 0:
+		return bytes.Compare(key, k) >= 0
+	default:
+		return bytes.Compare(key, k) >= 0 && bytes.Compare(key, end) < 0
+	}
+}
+
+// get returns the key-value of key at rev, nil if there is none.
+func (s *store) get(key string, rev int64) *mvccpb.KeyValue {
+	entries := s.keys[key]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].kv.ModRevision <= rev {
+			if entries[i].deleted {
+				return nil
+			}
+			return entries[i].kv
+		}
+	}
+	return nil
+}
+
+// rangeKeys returns the key-values in the range at rev, in key order.
+func (s *store) rangeKeys(k, end []byte, rev int64) []*mvccpb.KeyValue {
+	var kvs []*mvccpb.KeyValue
+	for key := range s.keys {
+		if !inRange([]byte(key), k, end) {
+			continue
+		}
+		if kv := s.get(key, rev); kv != nil {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return bytes.Compare(kvs[i].Key, kvs[j].Key) < 0 })
+	return kvs
+}
+
+func (s *store) checkRev(rev int64) error {
+	switch {
+	case rev > s.rev:
+		return rpctypes.ErrGRPCFutureRev
+	case rev > 0 && rev < s.compactRev:
+		return rpctypes.ErrGRPCCompacted
+	}
+	return nil
+}
+
+func (s *store) compact(rev int64) error {
+	switch {
+	case rev <= s.compactRev:
+		return rpctypes.ErrGRPCCompacted
+	case rev > s.rev:
+		return rpctypes.ErrGRPCFutureRev
+	}
+	for key, entries := range s.keys {
+		// keep the revision of the key at rev, unless deleted, and the
+		// later ones
+		i := 0
+		for i < len(entries)-1 && entries[i+1].kv.ModRevision <= rev {
+			i++
+		}
+		if entries[i].kv.ModRevision <= rev && entries[i].deleted {
+			i++
+		}
+		if i == len(entries) {
+			delete(s.keys, key)
+			continue
+		}
+		s.keys[key] = append([]entry(nil), entries[i:]...)
+	}
+	i := 0
+	for i < len(s.events) && s.events[i].Kv.ModRevision < rev {
+		i++
+	}
+	s.events = append([]*mvccpb.Event(nil), s.events[i:]...)
+	s.compactRev = rev
+	return nil
+}
+
+// expire revokes the leases expired at the current time, then deletes
+// the keys whose TTL elapsed, at a single revision as etcd does.
+func (s *store) expire() {
+	var ids []int64
+	for id, l := range s.leases {
+		if !l.expiry.After(s.now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		w := s.write()
+		w.revoke(id)
+		w.commit()
+	}
+
+	var keys []string
+	for key, entries := range s.keys {
+		e := entries[len(entries)-1]
+		if !e.deleted && e.kv.Ttl > 0 && !e.expiry.After(s.now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	w := s.write()
+	for _, key := range keys {
+		w.delete(s.get(key, s.rev))
+	}
+	w.commit()
+}
+
+// write starts a write at the next revision.
+func (s *store) write() *writer {
+	return &writer{s: s, rev: s.rev + 1, nevents: len(s.events), puts: make(map[string]struct{})}
+}
+
+// writer applies the writes of a request at a single revision. Writes can
+// be undone until committed.
+type writer struct {
+	s       *store
+	rev     int64
+	nevents int
+	undo    []func()
+
+	// puts and dels are the keys put and the ranges deleted, to detect
+	// keys written twice
+	puts map[string]struct{}
+	dels [][2][]byte
+}
+
+func (w *writer) put(req *pb.PutRequest) (*pb.PutResponse, error) {
+	key := string(req.Key)
+	if _, ok := w.puts[key]; ok {
+		return nil, rpctypes.ErrGRPCDuplicateKey
+	}
+	for _, del := range w.dels {
+		if inRange(req.Key, del[0], del[1]) {
+			return nil, rpctypes.ErrGRPCDuplicateKey
+		}
+	}
+	if req.IgnoreValue && len(req.Value) != 0 {
+		return nil, rpctypes.ErrGRPCValueProvided
+	}
+	if req.IgnoreLease && req.Lease != 0 {
+		return nil, rpctypes.ErrGRPCLeaseProvided
+	}
+	if req.Ttl < 0 {
+		return nil, rpctypes.ErrGRPCInvalidKeyTTL
+	}
+	prev := w.s.get(key, w.rev)
+	if prev == nil && (req.IgnoreValue || req.IgnoreLease) {
+		return nil, rpctypes.ErrGRPCKeyNotFound
+	}
+
+	kv := &mvccpb.KeyValue{Key: req.Key, Value: req.Value, Lease: req.Lease, Ttl: req.Ttl, CreateRevision: w.rev, ModRevision: w.rev, Version: 1}
+	if prev != nil {
+		kv.CreateRevision, kv.Version = prev.CreateRevision, prev.Version+1
+		if req.IgnoreValue {
+			kv.Value = prev.Value
+		}
+		if req.IgnoreLease {
+			kv.Lease = prev.Lease
+		}
+	}
+	if kv.Lease != 0 && w.s.leases[kv.Lease] == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	if prev != nil && prev.Lease != kv.Lease {
+		w.detach(prev.Lease, key)
+	}
+	if kv.Lease != 0 {
+		w.attach(kv.Lease, key)
+	}
+	e := entry{kv: kv}
+	if kv.Ttl > 0 {
+		e.expiry = w.s.now.Add(time.Duration(kv.Ttl) * time.Second)
+	}
+	w.append(key, e, &mvccpb.Event{Type: mvccpb.PUT, Kv: kv, PrevKv: prev})
+	w.puts[key] = struct{}{}
+
+	resp := &pb.PutResponse{}
+	if req.PrevKv {
+		resp.PrevKv = prev
+	}
+	return resp, nil
+}
+
+func (w *writer) deleteRange(req *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	for key := range w.puts {
+		if inRange([]byte(key), req.Key, req.RangeEnd) {
+			return nil, rpctypes.ErrGRPCDuplicateKey
+		}
+	}
+	w.dels = append(w.dels, [2][]byte{req.Key, req.RangeEnd})
+
+	resp := &pb.DeleteRangeResponse{}
+	for _, prev := range w.s.rangeKeys(req.Key, req.RangeEnd, w.rev) {
+		w.delete(prev)
+		resp.Deleted++
+		if req.PrevKv {
+			resp.PrevKvs = append(resp.PrevKvs, prev)
+		}
+	}
+	return resp, nil
+}
+
+func (w *writer) delete(prev *mvccpb.KeyValue) {
+	key := string(prev.Key)
+	if prev.Lease != 0 {
+		w.detach(prev.Lease, key)
+	}
+	kv := &mvccpb.KeyValue{Key: prev.Key, ModRevision: w.rev}
+	w.append(key, entry{kv: kv, deleted: true}, &mvccpb.Event{Type: mvccpb.DELETE, Kv: kv, PrevKv: prev})
+}
+
+// revoke revokes the lease and deletes its keys.
+func (w *writer) revoke(id int64) {
+	l := w.s.leases[id]
+	keys := make([]string, 0, len(l.keys))
+	for key := range l.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if prev := w.s.get(key, w.rev); prev != nil {
+			w.delete(prev)
+		}
+	}
+	delete(w.s.leases, id)
+	w.undo = append(w.undo, func() { w.s.leases[id] = l })
+}
+
+func (w *writer) append(key string, e entry, ev *mvccpb.Event) {
+	w.s.keys[key] = append(w.s.keys[key], e)
+	w.s.events = append(w.s.events, ev)
+	w.undo = append(w.undo, func() {
+		entries := w.s.keys[key]
+		if len(entries) == 1 {
+			delete(w.s.keys, key)
+		} else {
+			w.s.keys[key] = entries[:len(entries)-1]
+		}
+	})
+}
+
+func (w *writer) attach(id int64, key string) {
+	w.s.leases[id].keys[key] = struct{}{}
+	w.undo = append(w.undo, func() {
+		if l := w.s.leases[id]; l != nil {
+			delete(l.keys, key)
+		}
+	})
+}
+
+func (w *writer) detach(id int64, key string) {
+	if l := w.s.leases[id]; l != nil {
+		delete(l.keys, key)
+		w.undo = append(w.undo, func() { l.keys[key] = struct{}{} })
+	}
+}
+
+// abort undoes the writes.
+func (w *writer) abort() {
+	for i := len(w.undo) - 1; i >= 0; i-- {
+		w.undo[i]()
+	}
+	w.s.events = w.s.events[:w.nevents]
+}
+
+// commit moves the store to the revision of the writes, if any, and sends
+// their events to the watchers.
+func (w *writer) commit() {
+	events := w.s.events[w.nevents:]
+	if len(events) == 0 {
+		return
+	}
+	w.s.rev = w.rev
+	for wa := range w.s.watchers {
+		wa.notify(events)
+	}
+}
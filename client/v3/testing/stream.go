@@ -0,0 +1,82 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// clientStream implements grpc.ClientStream for the streams of the fake,
+// whose messages are exchanged with Send and Recv.
+type clientStream struct {
+	ctx context.Context
+}
+
+func (cs clientStream) Header() (metadata.MD, error) { return nil, nil }
+func (cs clientStream) Trailer() metadata.MD         { return nil }
+func (cs clientStream) CloseSend() error             { return nil }
+func (cs clientStream) Context() context.Context     { return cs.ctx }
+func (cs clientStream) SendMsg(m interface{}) error {
+	return errors.New("clientv3/testing: SendMsg not supported")
+}
+func (cs clientStream) RecvMsg(m interface{}) error {
+	return errors.New("clientv3/testing: RecvMsg not supported")
+}
+
+// queue is the unbounded queue of the messages received by a stream, so
+// that the store never blocks on slow receivers.
+type queue struct {
+	mu     sync.Mutex
+	msgs   []interface{}
+	notify chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{notify: make(chan struct{}, 1)}
+}
+
+func (q *queue) push(msg interface{}) {
+	q.mu.Lock()
+	q.msgs = append(q.msgs, msg)
+	q.mu.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for a message until the context is done.
+func (q *queue) pop(ctx context.Context) (interface{}, error) {
+	for {
+		q.mu.Lock()
+		if len(q.msgs) > 0 {
+			msg := q.msgs[0]
+			q.msgs[0] = nil
+			q.msgs = q.msgs[1:]
+			q.mu.Unlock()
+			return msg, nil
+		}
+		q.mu.Unlock()
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+
+	"google.golang.org/grpc"
+)
+
+// watchClient is a pb.WatchClient of a store.
+type watchClient struct {
+	s *store
+}
+
+func (c *watchClient) Watch(ctx context.Context, _ ...grpc.CallOption) (pb.Watch_WatchClient, error) {
+	ws := &watchStream{
+		clientStream: clientStream{ctx: ctx},
+		s:            c.s,
+		q:            newQueue(),
+		watchers:     make(map[int64]*watcher),
+	}
+	go func() {
+		<-ctx.Done()
+		c.s.mu.Lock()
+		defer c.s.mu.Unlock()
+		for _, w := range ws.watchers {
+			delete(c.s.watchers, w)
+		}
+	}()
+	return ws, nil
+}
+
+// watchStream is a pb.Watch_WatchClient of a store.
+type watchStream struct {
+	clientStream
+	s *store
+	q *queue
+
+	// guarded by the mutex of the store
+	nextID   int64
+	watchers map[int64]*watcher
+}
+
+func (ws *watchStream) Send(req *pb.WatchRequest) error {
+	if err := ws.ctx.Err(); err != nil {
+		return err
+	}
+	ws.s.mu.Lock()
+	defer ws.s.mu.Unlock()
+	switch r := req.RequestUnion.(type) {
+	case *pb.WatchRequest_CreateRequest:
+		ws.create(r.CreateRequest)
+	case *pb.WatchRequest_CancelRequest:
+		id := r.CancelRequest.WatchId
+		if w := ws.watchers[id]; w != nil {
+			delete(ws.watchers, id)
+			delete(ws.s.watchers, w)
+			ws.q.push(&pb.WatchResponse{Header: ws.s.header(), WatchId: id, Canceled: true})
+		}
+	case *pb.WatchRequest_ProgressRequest:
+		ws.q.push(&pb.WatchResponse{Header: ws.s.header(), WatchId: -1})
+	}
+	return nil
+}
+
+func (ws *watchStream) Recv() (*pb.WatchResponse, error) {
+	msg, err := ws.q.pop(ws.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(*pb.WatchResponse), nil
+}
+
+func (ws *watchStream) create(req *pb.WatchCreateRequest) {
+	id := req.WatchId
+	if id == 0 {
+		for ws.watchers[ws.nextID] != nil {
+			ws.nextID++
+		}
+		id = ws.nextID
+		ws.nextID++
+	}
+	ws.q.push(&pb.WatchResponse{Header: ws.s.header(), WatchId: id, Created: true})
+
+	w := &watcher{ws: ws, id: id, key: req.Key, end: req.RangeEnd, prevKV: req.PrevKv, startRev: req.StartRevision}
+	for _, filter := range req.Filters {
+		switch filter {
+		case pb.WatchCreateRequest_NOPUT:
+			w.noPut = true
+		case pb.WatchCreateRequest_NODELETE:
+			w.noDelete = true
+		}
+	}
+	if w.startRev == 0 {
+		w.startRev = ws.s.rev + 1
+	}
+	if w.startRev < ws.s.compactRev {
+		ws.q.push(&pb.WatchResponse{Header: ws.s.header(), WatchId: id, CompactRevision: ws.s.compactRev, Canceled: true})
+		return
+	}
+	ws.watchers[id] = w
+	ws.s.watchers[w] = struct{}{}
+
+	// send the past events a revision at a time
+	events := ws.s.events
+	for len(events) > 0 {
+		n := 1
+		for n < len(events) && events[n].Kv.ModRevision == events[0].Kv.ModRevision {
+			n++
+		}
+		w.notify(events[:n])
+		events = events[n:]
+	}
+}
+
+// watcher is a watch of a watchStream.
+type watcher struct {
+	ws       *watchStream
+	id       int64
+	key, end []byte
+	startRev int64
+	prevKV   bool
+	noPut    bool
+	noDelete bool
+}
+
+// notify sends the events of a revision matching the watch.
+func (w *watcher) notify(events []*mvccpb.Event) {
+	if events[0].Kv.ModRevision < w.startRev {
+		return
+	}
+	var matched []*mvccpb.Event
+	for _, ev := range events {
+		if !inRange(ev.Kv.Key, w.key, w.end) ||
+			(ev.Type == mvccpb.PUT && w.noPut) || (ev.Type == mvccpb.DELETE && w.noDelete) {
+			continue
+		}
+		if !w.prevKV {
+			ev = &mvccpb.Event{Type: ev.Type, Kv: ev.Kv}
+		}
+		matched = append(matched, ev)
+	}
+	if len(matched) == 0 {
+		return
+	}
+	h := *w.ws.s.header()
+	h.Revision = events[0].Kv.ModRevision
+	w.ws.q.push(&pb.WatchResponse{Header: &h, WatchId: w.id, Events: matched})
+}
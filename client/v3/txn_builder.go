@@ -0,0 +1,281 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+// Cond is the condition of a transaction built by a TxnBuilder: a Cmp, or
+// a combination of conditions with And, Or and Not.
+type Cond interface {
+	// compile returns the transaction taking the then branch if the
+	// condition holds, and the else branch otherwise.
+	compile(then, els txnBranch) *compiledTxn
+}
+
+// compile implements Cond.
+func (cmp Cmp) compile(then, els txnBranch) *compiledTxn {
+	return &compiledTxn{cmps: []Cmp{cmp}, then: then, els: els}
+}
+
+type andCond []Cond
+
+// And returns a condition holding if all of conds hold, or if there are none.
+func And(conds ...Cond) Cond { return andCond(conds) }
+
+func (c andCond) compile(then, els txnBranch) *compiledTxn {
+	// the leading comparisons are compared by the same transaction
+	var cmps []Cmp
+	for len(c) > 0 {
+		cmp, ok := c[0].(Cmp)
+		if !ok {
+			break
+		}
+		cmps, c = append(cmps, cmp), c[1:]
+	}
+	switch {
+	case len(c) == 0:
+		return &compiledTxn{cmps: cmps, then: then, els: els}
+	case len(cmps) > 0:
+		return &compiledTxn{cmps: cmps, then: txnBranch{nested: c.compile(then, els)}, els: els}
+	case len(c) == 1:
+		return c[0].compile(then, els)
+	}
+	return c[0].compile(txnBranch{nested: c[1:].compile(then, els)}, els)
+}
+
+type orCond []Cond
+
+// Or returns a condition holding if any of conds holds. Each condition
+// after the first is compared by a transaction nested in the else branch
+// of the previous one.
+func Or(conds ...Cond) Cond { return orCond(conds) }
+
+func (c orCond) compile(then, els txnBranch) *compiledTxn {
+	switch len(c) {
+	case 0:
+		// a transaction without comparisons always succeeds
+		return &compiledTxn{then: els, els: then}
+	case 1:
+		return c[0].compile(then, els)
+	}
+	return c[0].compile(then, txnBranch{nested: c[1:].compile(then, els)})
+}
+
+type notCond struct{ c Cond }
+
+// Not returns a condition holding if c does not hold.
+func Not(c Cond) Cond { return notCond{c} }
+
+func (c notCond) compile(then, els txnBranch) *compiledTxn {
+	return c.c.compile(els, then)
+}
+
+// txnBranch is a branch of a compiled transaction: the operations given to
+// Then or Else, or a nested transaction.
+type txnBranch struct {
+	then   bool
+	nested *compiledTxn
+}
+
+var (
+	thenBranch = txnBranch{then: true}
+	elseBranch = txnBranch{}
+)
+
+// compiledTxn is a transaction compiled from a condition.
+type compiledTxn struct {
+	cmps      []Cmp
+	then, els txnBranch
+}
+
+func (ct *compiledTxn) op(thenOps, elseOps []Op) Op {
+	ops := func(b txnBranch) []Op {
+		switch {
+		case b.nested != nil:
+			return []Op{b.nested.op(thenOps, elseOps)}
+		case b.then:
+			return thenOps
+		}
+		return elseOps
+	}
+	return OpTxn(ct.cmps, ops(ct.then), ops(ct.els))
+}
+
+// responses returns whether the condition held, and the responses of the
+// operations of the branch taken.
+func (ct *compiledTxn) responses(resp *pb.TxnResponse) (bool, []*pb.ResponseOp) {
+	b := ct.els
+	if resp.Succeeded {
+		b = ct.then
+	}
+	if b.nested == nil {
+		return b.then, resp.Responses
+	}
+	return b.nested.responses(resp.Responses[0].GetResponseTxn())
+}
+
+// TxnBuilder builds a transaction whose condition combines comparisons with
+// And, Or and Not, and whose operations are named to look their responses
+// up in the result. Since etcd transactions only AND their comparisons, the
+// condition is compiled into transactions nested in each other's branches,
+// which the server runs atomically as a single transaction.
+//
+//	res, err := clientv3.NewTxnBuilder(cli).
+//		If(clientv3.Or(
+//			clientv3.Compare(clientv3.Version("lock"), "=", 0),
+//			clientv3.Compare(clientv3.Value("owner"), "=", "me"),
+//		)).
+//		Then("acquire", clientv3.OpPut("owner", "me")).
+//		Else("owner", clientv3.OpGet("owner")).
+//		Commit(ctx)
+//	if err == nil && !res.Succeeded {
+//		holder := res.Get("owner").Kvs[0].Value
+//	}
+//
+// The server rejects a transaction writing a key more than once within a
+// branch, including a branch nested under another one.
+type TxnBuilder struct {
+	kv        KV
+	cond      Cond
+	thenOps   []Op
+	elseOps   []Op
+	thenNames map[string]int
+	elseNames map[string]int
+	err       error
+}
+
+// NewTxnBuilder returns a TxnBuilder committing with kv.
+func NewTxnBuilder(kv KV) *TxnBuilder {
+	return &TxnBuilder{
+		kv:        kv,
+		thenNames: make(map[string]int),
+		elseNames: make(map[string]int),
+	}
+}
+
+// If sets the condition of the transaction. Without one, the operations
+// given to Then are always run.
+func (b *TxnBuilder) If(c Cond) *TxnBuilder {
+	b.cond = c
+	return b
+}
+
+// Then adds an operation run if the condition holds. Its response is
+// looked up in the result with the given name, unless name is empty.
+func (b *TxnBuilder) Then(name string, op Op) *TxnBuilder {
+	b.thenOps = b.add(b.thenNames, b.thenOps, name, op)
+	return b
+}
+
+// Else adds an operation run if the condition does not hold. Its response
+// is looked up in the result with the given name, unless name is empty.
+func (b *TxnBuilder) Else(name string, op Op) *TxnBuilder {
+	b.elseOps = b.add(b.elseNames, b.elseOps, name, op)
+	return b
+}
+
+func (b *TxnBuilder) add(names map[string]int, ops []Op, name string, op Op) []Op {
+	if name != "" {
+		if _, ok := names[name]; ok && b.err == nil {
+			b.err = fmt.Errorf("clientv3: duplicate transaction operation name %q", name)
+		}
+		names[name] = len(ops)
+	}
+	return append(ops, op)
+}
+
+func (b *TxnBuilder) compile() *compiledTxn {
+	if b.cond == nil {
+		return &compiledTxn{then: thenBranch, els: elseBranch}
+	}
+	return b.cond.compile(thenBranch, elseBranch)
+}
+
+// Op returns the transaction as an Op, e.g. to nest it in another
+// transaction. Its response is decoded with TxnResult.
+func (b *TxnBuilder) Op() (Op, error) {
+	if b.err != nil {
+		return Op{}, b.err
+	}
+	return b.compile().op(b.thenOps, b.elseOps), nil
+}
+
+// Commit commits the transaction.
+func (b *TxnBuilder) Commit(ctx context.Context) (*TxnResult, error) {
+	op, err := b.Op()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.kv.Do(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	return b.TxnResult(resp.Txn()), nil
+}
+
+// TxnResult decodes the response of the transaction returned by Op.
+func (b *TxnBuilder) TxnResult(resp *TxnResponse) *TxnResult {
+	succeeded, resps := b.compile().responses((*pb.TxnResponse)(resp))
+	names := b.elseNames
+	if succeeded {
+		names = b.thenNames
+	}
+	return &TxnResult{Succeeded: succeeded, Header: resp.Header, resps: resps, names: names}
+}
+
+// TxnResult is the result of a transaction built by a TxnBuilder.
+type TxnResult struct {
+	// Succeeded is true if the condition held, and the operations given to
+	// Then were run.
+	Succeeded bool
+	Header    *pb.ResponseHeader
+
+	resps []*pb.ResponseOp
+	names map[string]int
+}
+
+func (r *TxnResult) response(name string) *pb.ResponseOp {
+	i, ok := r.names[name]
+	if !ok {
+		return nil
+	}
+	return r.resps[i]
+}
+
+// Get returns the response of the named get, nil if it was not run.
+func (r *TxnResult) Get(name string) *GetResponse {
+	return (*GetResponse)(r.response(name).GetResponseRange())
+}
+
+// Put returns the response of the named put, nil if it was not run.
+func (r *TxnResult) Put(name string) *PutResponse {
+	return (*PutResponse)(r.response(name).GetResponsePut())
+}
+
+// Del returns the response of the named delete, nil if it was not run.
+func (r *TxnResult) Del(name string) *DeleteResponse {
+	return (*DeleteResponse)(r.response(name).GetResponseDeleteRange())
+}
+
+// Txn returns the response of the named transaction, nil if it was not
+// run.
+func (r *TxnResult) Txn(name string) *TxnResponse {
+	return (*TxnResponse)(r.response(name).GetResponseTxn())
+}
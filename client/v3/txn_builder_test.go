@@ -0,0 +1,161 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// evalKV evaluates transactions of value equality comparisons and gets
+// over a fixed set of keys.
+type evalKV struct {
+	KV
+	vals map[string]string
+}
+
+func (kv *evalKV) Do(ctx context.Context, op Op) (OpResponse, error) {
+	return OpResponse{txn: kv.txn(op)}, nil
+}
+
+func (kv *evalKV) txn(op Op) *TxnResponse {
+	cmps, thenOps, elseOps := op.Txn()
+	resp := &TxnResponse{Header: &pb.ResponseHeader{}, Succeeded: true}
+	for _, cmp := range cmps {
+		if kv.vals[string(cmp.Key)] != string(cmp.ValueBytes()) {
+			resp.Succeeded = false
+		}
+	}
+	ops := thenOps
+	if !resp.Succeeded {
+		ops = elseOps
+	}
+	for _, op := range ops {
+		switch {
+		case op.IsTxn():
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: (*pb.TxnResponse)(kv.txn(op))}})
+		case op.IsGet():
+			key := string(op.KeyBytes())
+			rr := &pb.RangeResponse{Kvs: []*mvccpb.KeyValue{{Key: []byte(key), Value: []byte(kv.vals[key])}}}
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: rr}})
+		default:
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{}}})
+		}
+	}
+	return resp
+}
+
+func TestTxnBuilderConditions(t *testing.T) {
+	a := Compare(Value("a"), "=", "1")
+	b := Compare(Value("b"), "=", "1")
+	c := Compare(Value("c"), "=", "1")
+
+	tests := []struct {
+		name string
+		cond Cond
+		// want returns whether the condition holds for the values of a, b
+		// and c.
+		want func(a, b, c bool) bool
+	}{
+		{"cmp", a, func(a, b, c bool) bool { return a }},
+		{"and", And(a, b, c), func(a, b, c bool) bool { return a && b && c }},
+		{"or", Or(a, b, c), func(a, b, c bool) bool { return a || b || c }},
+		{"not", Not(a), func(a, b, c bool) bool { return !a }},
+		{"and or", And(a, Or(b, c)), func(a, b, c bool) bool { return a && (b || c) }},
+		{"or and", Or(And(a, b), Not(c)), func(a, b, c bool) bool { return (a && b) || !c }},
+		{"and not or", And(Not(Or(a, b)), c), func(a, b, c bool) bool { return !(a || b) && c }},
+		{"empty and", And(), func(a, b, c bool) bool { return true }},
+		{"empty or", Or(), func(a, b, c bool) bool { return false }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 8; i++ {
+				av, bv, cv := i&1 != 0, i&2 != 0, i&4 != 0
+				vals := map[string]string{}
+				for k, v := range map[string]bool{"a": av, "b": bv, "c": cv} {
+					if v {
+						vals[k] = "1"
+					}
+				}
+				res, err := NewTxnBuilder(&evalKV{vals: vals}).
+					If(tt.cond).
+					Then("then", OpGet("a")).
+					Else("else", OpGet("b")).
+					Commit(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if w := tt.want(av, bv, cv); res.Succeeded != w {
+					t.Fatalf("a=%v b=%v c=%v: succeeded = %v, want %v", av, bv, cv, res.Succeeded, w)
+				}
+				name, key := "else", "b"
+				if res.Succeeded {
+					name, key = "then", "a"
+				}
+				if resp := res.Get(name); resp == nil || string(resp.Kvs[0].Key) != key {
+					t.Fatalf("a=%v b=%v c=%v: get %q = %v, want key %q", av, bv, cv, name, resp, key)
+				}
+			}
+		})
+	}
+}
+
+func TestTxnBuilderOp(t *testing.T) {
+	a := Compare(Value("a"), "=", "1")
+	b := Compare(Value("b"), "=", "1")
+	put, del := OpPut("x", "1"), OpDelete("x")
+
+	op, err := NewTxnBuilder(nil).If(Or(a, b)).Then("", put).Else("", del).Op()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wop := OpTxn([]Cmp{a}, []Op{put}, []Op{OpTxn([]Cmp{b}, []Op{put}, []Op{del})})
+	if !reflect.DeepEqual(op, wop) {
+		t.Fatalf("op = %+v, want %+v", op, wop)
+	}
+
+	// leading comparisons of an and share a transaction
+	op, err = NewTxnBuilder(nil).If(And(a, b)).Then("", put).Op()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wop = OpTxn([]Cmp{a, b}, []Op{put}, nil); !reflect.DeepEqual(op, wop) {
+		t.Fatalf("op = %+v, want %+v", op, wop)
+	}
+}
+
+func TestTxnBuilderDuplicateName(t *testing.T) {
+	_, err := NewTxnBuilder(nil).Then("x", OpGet("a")).Then("x", OpGet("b")).Op()
+	if err == nil {
+		t.Fatal("expected error for duplicate operation name")
+	}
+}
+
+func TestTxnResultMissing(t *testing.T) {
+	res, err := NewTxnBuilder(&evalKV{}).Then("get", OpGet("a")).Else("put", OpPut("a", "1")).Commit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Put("put") != nil || res.Get("unknown") != nil {
+		t.Fatal("expected no response for operations not run")
+	}
+	if res.Get("get") == nil {
+		t.Fatal("expected response for get")
+	}
+}
@@ -96,10 +96,20 @@ type WatchResponse struct {
 	// Created is used to indicate the creation of the watcher.
 	Created bool
 
+	// CoalescedFrom, on the responses of a watch WithCoalesce, is the
+	// revision of the first event merged into the response. The response
+	// holds the latest event of each key changed from CoalescedFrom to the
+	// revision of its last event.
+	CoalescedFrom int64
+
 	closeErr error
 
 	// cancelReason is a reason of canceling watch
 	cancelReason string
+
+	// listed is set on the responses a WatchRetrier delivers for a list of
+	// the watched range.
+	listed bool
 }
 
 // IsCreate returns true if the event tells that the key is newly created.
@@ -128,6 +138,13 @@ func (wr *WatchResponse) Err() error {
 	return nil
 }
 
+// IsList returns true if the WatchResponse is delivered by a WatchRetrier for
+// a list of the watched range, whose events bring the keys delivered so far
+// up to date with the header revision.
+func (wr *WatchResponse) IsList() bool {
+	return wr.listed
+}
+
 // IsProgressNotify returns true if the WatchResponse is progress notification.
 func (wr *WatchResponse) IsProgressNotify() bool {
 	return len(wr.Events) == 0 && !wr.Canceled && !wr.Created && wr.CompactRevision == 0 && wr.Header.Revision != 0
@@ -144,6 +161,9 @@ type watcher struct {
 	// streams holds all the active grpc streams keyed by ctx value.
 	streams map[string]*watchGrpcStream
 	lg      *zap.Logger
+
+	// rebalanceInterval is the interval streams are cycled at, if positive
+	rebalanceInterval time.Duration
 }
 
 // watchGrpcStream tracks all watch resources attached to a single grpc stream.
@@ -154,6 +174,12 @@ type watchGrpcStream struct {
 
 	// ctx controls internal remote.Watch requests
 	ctx context.Context
+	// streamCtx is the context of the current grpc stream, canceled by
+	// streamCancel
+	streamCtx    context.Context
+	streamCancel context.CancelFunc
+	// rebalanceInterval is the interval the grpc stream is cycled at
+	rebalanceInterval time.Duration
 	// ctxKey is the key used when looking up this stream's context
 	ctxKey string
 	cancel context.CancelFunc
@@ -207,6 +233,8 @@ type watchRequest struct {
 
 	// filters is the list of events to filter out
 	filters []pb.WatchCreateRequest_FilterType
+	// eventFilter selects events by key and value
+	eventFilter eventFilter
 	// get the previous key-value pair before the event happens
 	prevKV bool
 	// retc receives a chan WatchResponse once the watcher is established
@@ -249,6 +277,7 @@ func NewWatchFromWatchClient(wc pb.WatchClient, c *Client) Watcher {
 	if c != nil {
 		w.callOpts = c.callOpts
 		w.lg = c.lg
+		w.rebalanceInterval = c.cfg.StreamRebalanceInterval
 	}
 	return w
 }
@@ -281,6 +310,8 @@ func (w *watcher) newWatcherGrpcStream(inctx context.Context) *watchGrpcStream {
 		closingc:   make(chan *watcherStream),
 		resumec:    make(chan struct{}),
 		lg:         w.lg,
+
+		rebalanceInterval: w.rebalanceInterval,
 	}
 	go wgs.run()
 	return wgs
@@ -307,6 +338,7 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 		progressNotify: ow.progressNotify,
 		fragment:       ow.fragment,
 		filters:        filters,
+		eventFilter:    ow.eventFilter,
 		prevKV:         ow.prevKV,
 		retc:           make(chan chan WatchResponse, 1),
 	}
@@ -359,6 +391,9 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 		if ok {
 			select {
 			case ret := <-wr.retc:
+				if ow.coalesce > 0 {
+					return coalesceWatch(ctx, ret, ow.coalesce)
+				}
 				return ret
 			case <-ctx.Done():
 			case <-donec:
@@ -532,6 +567,11 @@ func (w *watchGrpcStream) run() {
 
 	cancelSet := make(map[int64]struct{})
 
+	var rebalancec <-chan time.Time
+	if w.rebalanceInterval > 0 {
+		rebalancec = time.After(jitterUp(w.rebalanceInterval, streamRebalanceJitter))
+	}
+
 	var cur *pb.WatchResponse
 	for {
 		select {
@@ -641,9 +681,20 @@ func (w *watchGrpcStream) run() {
 				}
 			}
 
+		// cycle the grpc stream, once the watchers are established; the
+		// responses received on it are dispatched before it ends
+		case <-rebalancec:
+			rebalancec = time.After(jitterUp(w.rebalanceInterval, streamRebalanceJitter))
+			if w.nextResume() == nil && len(w.substreams) > 0 {
+				w.streamCancel()
+			}
+
 		// watch client failed on Recv; spawn another if possible
 		case err := <-w.errc:
-			if isHaltErr(w.ctx, err) || toErr(w.ctx, err) == v3rpc.ErrNoLeader {
+			if err == errWatchStreamRebalanced {
+				// drop the fragments of a response cut by the cycle
+				cur = nil
+			} else if isHaltErr(w.ctx, err) || toErr(w.ctx, err) == v3rpc.ErrNoLeader {
 				closeErr = err
 				return
 			}
@@ -750,10 +801,14 @@ func (w *watchGrpcStream) unicastResponse(wr *WatchResponse, watchId int64) bool
 }
 
 // serveWatchClient forwards messages from the grpc stream to run()
-func (w *watchGrpcStream) serveWatchClient(wc pb.Watch_WatchClient) {
+func (w *watchGrpcStream) serveWatchClient(sctx context.Context, wc pb.Watch_WatchClient) {
 	for {
 		resp, err := wc.Recv()
 		if err != nil {
+			if sctx.Err() != nil && w.ctx.Err() == nil {
+				// the stream was canceled to be cycled
+				err = errWatchStreamRebalanced
+			}
 			select {
 			case w.errc <- err:
 			case <-w.donec:
@@ -907,7 +962,7 @@ func (w *watchGrpcStream) newWatchClient() (pb.Watch_WatchClient, error) {
 	}
 
 	// receive data from new grpc stream
-	go w.serveWatchClient(wc)
+	go w.serveWatchClient(w.streamCtx, wc)
 	return wc, nil
 }
 
@@ -965,6 +1020,9 @@ var maxBackoff = 100 * time.Millisecond
 // manually retry in case "ws==nil && err==nil"
 // TODO: remove FailFast=false
 func (w *watchGrpcStream) openWatchClient() (ws pb.Watch_WatchClient, err error) {
+	if w.streamCancel != nil {
+		w.streamCancel()
+	}
 	backoff := time.Millisecond
 	for {
 		select {
@@ -975,9 +1033,12 @@ func (w *watchGrpcStream) openWatchClient() (ws pb.Watch_WatchClient, err error)
 			return nil, err
 		default:
 		}
-		if ws, err = w.remote.Watch(w.ctx, w.callOpts...); ws != nil && err == nil {
+		sctx, cancel := context.WithCancel(w.ctx)
+		if ws, err = w.remote.Watch(sctx, w.callOpts...); ws != nil && err == nil {
+			w.streamCtx, w.streamCancel = sctx, cancel
 			break
 		}
+		cancel()
 		if isHaltErr(w.ctx, err) {
 			return nil, v3rpc.Error(err)
 		}
@@ -1006,6 +1067,12 @@ func (wr *watchRequest) toPB() *pb.WatchRequest {
 		Filters:        wr.filters,
 		PrevKv:         wr.prevKV,
 		Fragment:       wr.fragment,
+
+		KeyPattern:      wr.eventFilter.keyPattern,
+		KeyRegex:        wr.eventFilter.keyRegex,
+		ValueContains:   wr.eventFilter.valueContains,
+		ValueJsonPath:   wr.eventFilter.valueJSONPath,
+		ValueJsonEquals: wr.eventFilter.valueJSONEquals,
 	}
 	cr := &pb.WatchRequest_CreateRequest{CreateRequest: req}
 	return &pb.WatchRequest{RequestUnion: cr}
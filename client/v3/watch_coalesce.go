@@ -0,0 +1,94 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// coalesceWatch merges the events of wch received within window of the
+// first one, keeping the latest event of each key.
+func coalesceWatch(ctx context.Context, wch WatchChan, window time.Duration) WatchChan {
+	outc := make(chan WatchResponse)
+	go func() {
+		defer close(outc)
+		var (
+			pending *WatchResponse
+			byKey   map[string]*Event
+			timec   <-chan time.Time
+		)
+		send := func(wr WatchResponse) bool {
+			select {
+			case outc <- wr:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		flush := func() bool {
+			if pending == nil {
+				return true
+			}
+			wr := *pending
+			wr.Events = make([]*Event, 0, len(byKey))
+			for _, ev := range byKey {
+				wr.Events = append(wr.Events, ev)
+			}
+			sort.Slice(wr.Events, func(i, j int) bool {
+				return wr.Events[i].Kv.ModRevision < wr.Events[j].Kv.ModRevision
+			})
+			pending, byKey, timec = nil, nil, nil
+			return send(wr)
+		}
+		for {
+			select {
+			case wr, ok := <-wch:
+				if !ok {
+					flush()
+					return
+				}
+				if len(wr.Events) == 0 || wr.Err() != nil {
+					if !flush() || !send(wr) {
+						return
+					}
+					continue
+				}
+				if pending == nil {
+					pending = &WatchResponse{CoalescedFrom: wr.Events[0].Kv.ModRevision}
+					byKey = make(map[string]*Event)
+					timec = time.After(window)
+				}
+				pending.Header = wr.Header
+				for _, ev := range wr.Events {
+					if prev, ok := byKey[string(ev.Kv.Key)]; ok {
+						merged := *ev
+						merged.PrevKv = prev.PrevKv
+						ev = &merged
+					}
+					byKey[string(ev.Kv.Key)] = ev
+				}
+			case <-timec:
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return outc
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrWatchClosed is the error of a monitored watch whose channel closed
+// without an error, e.g. because the Watcher was closed.
+var ErrWatchClosed = errors.New("clientv3: watch closed")
+
+// WatchStatus is the status of a monitored watch.
+type WatchStatus struct {
+	// Revision is the revision the watch is known to be up to date with,
+	// that of its last response.
+	Revision int64
+	// LastResponse is the time of the last response of the watch, with
+	// events or a progress notification, or the time the watch started.
+	LastResponse time.Time
+	// LastEvent is the time of the last response with events, zero if none.
+	LastEvent time.Time
+	// Healthy is true if the watch responded to the last progress request
+	// within the interval of the monitor.
+	Healthy bool
+	// Err is the error the watch ended with, if it ended.
+	Err error
+}
+
+func (s WatchStatus) String() string {
+	last := "no event"
+	if !s.LastEvent.IsZero() {
+		last = fmt.Sprintf("last event %v ago", time.Since(s.LastEvent).Round(time.Second))
+	}
+	switch {
+	case s.Err != nil:
+		return fmt.Sprintf("watch ended at revision %d (%v), %s", s.Revision, s.Err, last)
+	case s.Healthy:
+		return fmt.Sprintf("watch healthy as of revision %d, %s", s.Revision, last)
+	default:
+		return fmt.Sprintf("watch stuck at revision %d for %v, %s", s.Revision, time.Since(s.LastResponse).Round(time.Second), last)
+	}
+}
+
+// WatchMonitor tracks the progress of watches, requesting a progress
+// notification from them every interval with RequestProgress, so that a
+// watch that stopped receiving responses, e.g. because its stream is stuck
+// on a partitioned member, can be told apart from a watch on keys that are
+// not modified.
+//
+// Progress notifications are only sent to watches that are synced with the
+// revision of the member; a watch catching up with a backlog of events is
+// healthy as long as it receives them. The responses of a monitored watch
+// are received by the monitor as they are consumed, so a watch consumed
+// slower than it is responded to is reported as stuck too.
+type WatchMonitor struct {
+	w        Watcher
+	interval time.Duration
+}
+
+// NewWatchMonitor returns a WatchMonitor of the watches of w, requesting
+// their progress every interval.
+func NewWatchMonitor(w Watcher, interval time.Duration) *WatchMonitor {
+	return &WatchMonitor{w: w, interval: interval}
+}
+
+// Watch watches on a key or prefix as Watcher.Watch does, and accepts the
+// same options. The progress notifications requested by the monitor are
+// delivered on the channel of the watch, along with its events.
+func (m *WatchMonitor) Watch(ctx context.Context, key string, opts ...OpOption) *MonitoredWatch {
+	mw := &MonitoredWatch{
+		interval: m.interval,
+		out:      make(chan WatchResponse),
+		status:   WatchStatus{LastResponse: time.Now()},
+	}
+	go mw.run(ctx, m.w, m.w.Watch(ctx, key, opts...))
+	return mw
+}
+
+// MonitoredWatch is a watch monitored by a WatchMonitor.
+type MonitoredWatch struct {
+	interval time.Duration
+	out      chan WatchResponse
+
+	mu        sync.Mutex
+	status    WatchStatus
+	requested time.Time
+}
+
+// Chan returns the channel of the watch responses.
+func (mw *MonitoredWatch) Chan() WatchChan { return mw.out }
+
+// Status returns the status of the watch.
+func (mw *MonitoredWatch) Status() WatchStatus {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	s := mw.status
+	// the watch is stuck if the last progress request is still unanswered
+	// after the interval
+	stuck := mw.requested.After(s.LastResponse) && time.Since(mw.requested) > mw.interval
+	s.Healthy = s.Err == nil && !stuck
+	return s
+}
+
+func (mw *MonitoredWatch) run(ctx context.Context, w Watcher, wch WatchChan) {
+	defer close(mw.out)
+
+	t := time.NewTicker(mw.interval)
+	defer t.Stop()
+	for {
+		select {
+		case wr, ok := <-wch:
+			if !ok {
+				mw.end(ctx.Err())
+				return
+			}
+			mw.observe(wr)
+			select {
+			case mw.out <- wr:
+			case <-ctx.Done():
+				mw.end(ctx.Err())
+				return
+			}
+		case <-t.C:
+			mw.mu.Lock()
+			// keep the time of the first unanswered request
+			if !mw.requested.After(mw.status.LastResponse) {
+				mw.requested = time.Now()
+			}
+			mw.mu.Unlock()
+			// a failed request leaves the watch reported as stuck
+			w.RequestProgress(ctx)
+		case <-ctx.Done():
+			mw.end(ctx.Err())
+			return
+		}
+	}
+}
+
+func (mw *MonitoredWatch) observe(wr WatchResponse) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	now := time.Now()
+	mw.status.LastResponse = now
+	if wr.Header.Revision > mw.status.Revision {
+		mw.status.Revision = wr.Header.Revision
+	}
+	if len(wr.Events) > 0 {
+		mw.status.LastEvent = now
+	}
+	if err := wr.Err(); err != nil {
+		mw.status.Err = err
+	}
+}
+
+func (mw *MonitoredWatch) end(err error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	if mw.status.Err != nil {
+		return
+	}
+	if err == nil {
+		err = ErrWatchClosed
+	}
+	mw.status.Err = err
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+type progressWatcher struct {
+	Watcher
+	wch chan WatchResponse
+
+	mu         sync.Mutex
+	rev        int64
+	responsive bool
+}
+
+func (w *progressWatcher) Watch(context.Context, string, ...OpOption) WatchChan { return w.wch }
+
+func (w *progressWatcher) RequestProgress(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.responsive {
+		return nil
+	}
+	w.rev++
+	select {
+	case w.wch <- WatchResponse{Header: pb.ResponseHeader{Revision: w.rev}}:
+	default:
+	}
+	return nil
+}
+
+func (w *progressWatcher) setResponsive(responsive bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.responsive = responsive
+}
+
+func TestWatchMonitor(t *testing.T) {
+	w := &progressWatcher{wch: make(chan WatchResponse, 1), responsive: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	mw := NewWatchMonitor(w, 10*time.Millisecond).Watch(ctx, "foo")
+	go func() {
+		for range mw.Chan() {
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if s := mw.Status(); !s.Healthy || s.Revision == 0 || !s.LastEvent.IsZero() {
+		t.Fatalf("status = %+v, want healthy with progress and no event", s)
+	}
+
+	// the watch is stuck once a progress request is unanswered
+	w.setResponsive(false)
+	time.Sleep(50 * time.Millisecond)
+	if s := mw.Status(); s.Healthy {
+		t.Fatalf("status = %+v, want unhealthy", s)
+	}
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if s := mw.Status(); s.Healthy || s.Err != context.Canceled {
+		t.Fatalf("status = %+v, want ended with %v", s, context.Canceled)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchRetryInterval is the wait before a WatchRetrier re-creates a watch,
+// or re-lists its range, after a transient failure.
+var watchRetryInterval = 500 * time.Millisecond
+
+// WatchRetrier watches key ranges as Watcher does, but keeps a watch going
+// through the failures that would end it:
+//
+//   - when the watch is canceled because the member lost its leader (see
+//     WithRequireLeader), or another transient error, it is re-created from
+//     the revision after the last one delivered.
+//   - when the revision to resume from is compacted, the range is re-listed
+//     at the current revision and the difference with the keys delivered so
+//     far is delivered as a single response, of delete events for the keys
+//     that are gone and put events for the keys that were created or
+//     modified, with the revision of the re-list. The watch then resumes
+//     after that revision. Intermediate values of the keys modified while
+//     the watch was compacted are lost.
+//
+// To be able to compute that difference, the retrier lists the range
+// before watching it, at the revision before the one the watch starts at,
+// and tracks the keys of the range and their revisions, as well as their
+// values with WithPrevKV.
+//
+// Responses are delivered on a single channel, in revision order, and the
+// channel is only closed when the context is done, the Watcher is closed,
+// or the watch fails with a non-transient error, after a last response
+// holding the error.
+type WatchRetrier struct {
+	kv KV
+	w  Watcher
+}
+
+// NewWatchRetrier returns a WatchRetrier watching with w, and listing the
+// watched ranges with kv.
+func NewWatchRetrier(kv KV, w Watcher) *WatchRetrier {
+	return &WatchRetrier{kv: kv, w: w}
+}
+
+// Watch watches on a key or prefix as Watcher.Watch does, and accepts the
+// same options.
+func (wr *WatchRetrier) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	return wr.watch(ctx, key, opts, false)
+}
+
+// ListAndWatch watches as Watch does, but first delivers the keys listed
+// before the watch starts as a response of put events, with the revision
+// of the list, even if there are none. Unless the watch starts at revision
+// 1, which nothing precedes, the response holds every key of the range the
+// watch events apply to.
+func (wr *WatchRetrier) ListAndWatch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	return wr.watch(ctx, key, opts, true)
+}
+
+func (wr *WatchRetrier) watch(ctx context.Context, key string, opts []OpOption, deliverList bool) WatchChan {
+	rw := &retriedWatch{
+		kv:          wr.kv,
+		w:           wr.w,
+		key:         key,
+		opts:        opts,
+		op:          opWatch(key, opts...),
+		deliverList: deliverList,
+		keys:        make(map[string]*mvccpb.KeyValue),
+		out:         make(chan WatchResponse),
+	}
+	go rw.run(ctx)
+	return rw.out
+}
+
+type retriedWatch struct {
+	kv   KV
+	w    Watcher
+	key  string
+	opts []OpOption
+	// op is the watch requested, for its range, start revision, filters and
+	// prevKV option.
+	op Op
+	// deliverList is true if the first list is delivered.
+	deliverList bool
+
+	// keys are the keys of the range delivered so far, without values
+	// unless op.prevKV is set.
+	keys map[string]*mvccpb.KeyValue
+	// next is the revision to resume the watch from.
+	next    int64
+	listed  bool
+	created bool
+
+	out chan WatchResponse
+}
+
+func (rw *retriedWatch) run(ctx context.Context) {
+	defer close(rw.out)
+
+	// list the keys before the first revision watched, at the current
+	// revision if none is given
+	rw.next = rw.op.rev
+	if rw.next != 1 && !rw.relist(ctx, rw.next-1, rw.deliverList) {
+		return
+	}
+
+	// the watch tracks every event to keep the keys up to date, filters are
+	// applied when the events are delivered
+	noFilters := func(op *Op) { op.filterPut, op.filterDelete = false, false }
+	for {
+		wch := rw.w.Watch(ctx, rw.key, append(rw.opts, WithRev(rw.next), noFilters)...)
+		err := rw.forward(ctx, wch)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err == v3rpc.ErrCompacted:
+			if !rw.relist(ctx, 0, true) {
+				return
+			}
+		case isWatchRetryable(err):
+			if !rw.wait(ctx) {
+				return
+			}
+		default:
+			rw.send(ctx, WatchResponse{Canceled: true, closeErr: err})
+			return
+		}
+	}
+}
+
+// forward delivers the responses of the watch until it ends, and returns
+// the error it ended with, if any.
+func (rw *retriedWatch) forward(ctx context.Context, wch WatchChan) error {
+	for wresp := range wch {
+		if err := wresp.Err(); err != nil {
+			return err
+		}
+		switch {
+		case wresp.Created:
+			// only the first watch is reported as created
+			if rw.created {
+				continue
+			}
+			rw.created = true
+		case wresp.IsProgressNotify():
+			rw.next = wresp.Header.Revision + 1
+		default:
+			for _, ev := range wresp.Events {
+				rw.apply(ev)
+			}
+			if len(wresp.Events) > 0 {
+				rw.next = wresp.Events[len(wresp.Events)-1].Kv.ModRevision + 1
+			}
+			if wresp.Events = rw.filter(wresp.Events); len(wresp.Events) == 0 {
+				continue
+			}
+		}
+		if !rw.send(ctx, wresp) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// relist lists the range at rev, or at the current revision if rev is not
+// positive, and resumes the watch after it. If deliver is true, the
+// difference with the keys delivered so far is delivered as events. relist
+// returns false if the watch has ended.
+func (rw *retriedWatch) relist(ctx context.Context, rev int64, deliver bool) bool {
+	for {
+		keys, wresp, err := rw.list(ctx, rev)
+		if err == nil {
+			wresp.listed = true
+			if deliver {
+				wresp.Events = rw.filter(rw.diff(keys, wresp.Header.Revision))
+				// the first list is delivered even if empty, for its revision
+				if (len(wresp.Events) > 0 || !rw.listed) && !rw.send(ctx, wresp) {
+					return false
+				}
+			}
+			for k, kv := range keys {
+				keys[k] = rw.tracked(kv)
+			}
+			rw.keys, rw.next, rw.listed = keys, wresp.Header.Revision+1, true
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if !isWatchRetryable(err) {
+			rw.send(ctx, WatchResponse{Canceled: true, closeErr: err})
+			return false
+		}
+		if !rw.wait(ctx) {
+			return false
+		}
+	}
+}
+
+func (rw *retriedWatch) list(ctx context.Context, rev int64) (map[string]*mvccpb.KeyValue, WatchResponse, error) {
+	opts := []OpOption{WithRange(string(rw.op.end))}
+	if rev > 0 {
+		opts = append(opts, WithRev(rev))
+	}
+	keys := make(map[string]*mvccpb.KeyValue)
+	pg := NewPagedGet(rw.kv, rw.key, 0, opts...)
+	var wresp WatchResponse
+	for pg.Next(ctx) {
+		wresp.Header = *pg.Page().Header
+		for _, kv := range pg.Page().Kvs {
+			keys[string(kv.Key)] = kv
+		}
+	}
+	return keys, wresp, pg.Err()
+}
+
+// diff returns the events turning the keys delivered so far into the listed
+// keys, in key order.
+func (rw *retriedWatch) diff(keys map[string]*mvccpb.KeyValue, rev int64) []*Event {
+	var evs []*Event
+	for k, prev := range rw.keys {
+		if _, ok := keys[k]; !ok {
+			ev := &Event{Type: EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(k), ModRevision: rev}}
+			if rw.op.prevKV {
+				ev.PrevKv = prev
+			}
+			evs = append(evs, ev)
+		}
+	}
+	for k, kv := range keys {
+		prev, ok := rw.keys[k]
+		if ok && prev.ModRevision == kv.ModRevision {
+			continue
+		}
+		ev := &Event{Type: EventTypePut, Kv: kv}
+		if ok && rw.op.prevKV {
+			ev.PrevKv = prev
+		}
+		evs = append(evs, ev)
+	}
+	sort.Slice(evs, func(i, j int) bool { return string(evs[i].Kv.Key) < string(evs[j].Kv.Key) })
+	return evs
+}
+
+func (rw *retriedWatch) apply(ev *Event) {
+	switch ev.Type {
+	case EventTypePut:
+		rw.keys[string(ev.Kv.Key)] = rw.tracked(ev.Kv)
+	case EventTypeDelete:
+		delete(rw.keys, string(ev.Kv.Key))
+	}
+}
+
+// tracked returns the key-value to track for kv.
+func (rw *retriedWatch) tracked(kv *mvccpb.KeyValue) *mvccpb.KeyValue {
+	if rw.op.prevKV {
+		return kv
+	}
+	return &mvccpb.KeyValue{Key: kv.Key, ModRevision: kv.ModRevision}
+}
+
+// filter removes the events filtered out by the watch options.
+func (rw *retriedWatch) filter(evs []*Event) []*Event {
+	if !rw.op.filterPut && !rw.op.filterDelete {
+		return evs
+	}
+	var kept []*Event
+	for _, ev := range evs {
+		if (ev.Type == EventTypePut && rw.op.filterPut) || (ev.Type == EventTypeDelete && rw.op.filterDelete) {
+			continue
+		}
+		kept = append(kept, ev)
+	}
+	return kept
+}
+
+func (rw *retriedWatch) send(ctx context.Context, wresp WatchResponse) bool {
+	select {
+	case rw.out <- wresp:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (rw *retriedWatch) wait(ctx context.Context) bool {
+	select {
+	case <-time.After(watchRetryInterval):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isWatchRetryable returns true if a watch, or the list of its range, that
+// failed with err can be retried.
+func isWatchRetryable(err error) bool {
+	if ev, ok := v3rpc.Error(err).(v3rpc.EtcdError); ok {
+		return ev.Code() == codes.Unavailable
+	}
+	ev, ok := status.FromError(err)
+	return ok && ev.Code() == codes.Unavailable
+}
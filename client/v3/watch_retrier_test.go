@@ -0,0 +1,137 @@
+// Copyright 2016 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// scriptedWatcher replies to each Watch with the next scripted responses,
+// recording the revisions the watches start at. Once the script is over,
+// watches last until their context is done.
+type scriptedWatcher struct {
+	Watcher
+	script [][]WatchResponse
+	before []func()
+	revs   []int64
+}
+
+func (w *scriptedWatcher) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	w.revs = append(w.revs, opWatch(key, opts...).rev)
+	ch := make(chan WatchResponse, 16)
+	if len(w.script) == 0 {
+		go func() {
+			<-ctx.Done()
+			close(ch)
+		}()
+		return ch
+	}
+	if w.before[0] != nil {
+		w.before[0]()
+	}
+	for _, wresp := range w.script[0] {
+		ch <- wresp
+	}
+	close(ch)
+	w.script, w.before = w.script[1:], w.before[1:]
+	return ch
+}
+
+func putEvent(key string, mod int64) *Event {
+	return &Event{Type: EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: mod}}
+}
+
+func TestWatchRetrier(t *testing.T) {
+	defer func(d time.Duration) { watchRetryInterval = d }(watchRetryInterval)
+	watchRetryInterval = time.Millisecond
+
+	kv := &rangeKV{keys: []string{"a/1", "a/2"}, mods: map[string]int64{"a/1": 2, "a/2": 3}, rev: 3}
+	w := &scriptedWatcher{
+		script: [][]WatchResponse{
+			{{Events: []*Event{putEvent("a/3", 4)}}, {Canceled: true, closeErr: v3rpc.ErrGRPCNoLeader}},
+			{{Canceled: true, CompactRevision: 6}},
+		},
+		before: []func(){
+			nil,
+			func() {
+				// a/2 is deleted, a/3 modified and a/4 created while the
+				// watch is compacted
+				kv.keys, kv.mods, kv.rev = []string{"a/1", "a/3", "a/4"}, map[string]int64{"a/1": 2, "a/3": 7, "a/4": 8}, 9
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wch := NewWatchRetrier(kv, w).Watch(ctx, "a/", WithPrefix())
+
+	wantEvents := [][]*Event{
+		{putEvent("a/3", 4)},
+		{
+			{Type: EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("a/2"), ModRevision: 9}},
+			putEvent("a/3", 7),
+			putEvent("a/4", 8),
+		},
+	}
+	for i, want := range wantEvents {
+		select {
+		case wresp := <-wch:
+			if err := wresp.Err(); err != nil {
+				t.Fatalf("#%d: unexpected error %v", i, err)
+			}
+			if !reflect.DeepEqual(wresp.Events, want) {
+				t.Fatalf("#%d: expected events %v, got %v", i, want, wresp.Events)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("#%d: timed out waiting for a watch response", i)
+		}
+	}
+
+	cancel()
+	for range wch {
+	}
+	// the watch resumes after the put, again after the no leader error, and
+	// after the revision of the re-list
+	if wantRevs := []int64{4, 5, 10}; !reflect.DeepEqual(w.revs, wantRevs) {
+		t.Errorf("expected watches from revisions %v, got %v", wantRevs, w.revs)
+	}
+}
+
+func TestWatchRetrierFilters(t *testing.T) {
+	kv := &rangeKV{keys: []string{"a/1"}, mods: map[string]int64{"a/1": 2}, rev: 2}
+	w := &scriptedWatcher{
+		script: [][]WatchResponse{
+			{{Events: []*Event{
+				putEvent("a/1", 3),
+				{Type: EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("a/1"), ModRevision: 4}},
+			}}},
+		},
+		before: []func(){nil},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wch := NewWatchRetrier(kv, w).Watch(ctx, "a/", WithPrefix(), WithFilterPut())
+	wresp := <-wch
+	if len(wresp.Events) != 1 || wresp.Events[0].Type != EventTypeDelete {
+		t.Fatalf("expected only the delete event, got %v", wresp.Events)
+	}
+}
@@ -15,8 +15,12 @@
 package clientv3
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 )
 
@@ -53,3 +57,39 @@ func TestEvent(t *testing.T) {
 		}
 	}
 }
+
+func TestCoalesceWatch(t *testing.T) {
+	put := func(key string, rev int64) *Event {
+		return &Event{Type: EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: rev}}
+	}
+	del := func(key string, rev int64, prev *mvccpb.KeyValue) *Event {
+		return &Event{Type: EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: rev}, PrevKv: prev}
+	}
+	wch := make(chan WatchResponse, 4)
+	wch <- WatchResponse{Header: pb.ResponseHeader{Revision: 3}, Events: []*Event{put("a", 2), put("b", 3)}}
+	wch <- WatchResponse{Header: pb.ResponseHeader{Revision: 5}, Events: []*Event{put("a", 4), del("b", 5, put("b", 3).Kv)}}
+	// a progress notification flushes the merged events
+	wch <- WatchResponse{Header: pb.ResponseHeader{Revision: 6}}
+	wch <- WatchResponse{Header: pb.ResponseHeader{Revision: 7}, Events: []*Event{put("c", 7)}}
+	close(wch)
+
+	outc := coalesceWatch(context.Background(), wch, time.Hour)
+	wrs := []WatchResponse{
+		{Header: pb.ResponseHeader{Revision: 5}, CoalescedFrom: 2, Events: []*Event{put("a", 4), del("b", 5, nil)}},
+		{Header: pb.ResponseHeader{Revision: 6}},
+		// the watch channel closing flushes the merged events
+		{Header: pb.ResponseHeader{Revision: 7}, CoalescedFrom: 7, Events: []*Event{put("c", 7)}},
+	}
+	for i, want := range wrs {
+		wr, ok := <-outc
+		if !ok {
+			t.Fatalf("#%d: channel closed", i)
+		}
+		if !reflect.DeepEqual(wr, want) {
+			t.Errorf("#%d: response = %+v, want %+v", i, wr, want)
+		}
+	}
+	if _, ok := <-outc; ok {
+		t.Fatal("expected the channel to close with the watch channel")
+	}
+}
@@ -14,4 +14,8 @@
 
 // Package recipe contains experimental client-side distributed
 // synchronization primitives.
+//
+// The barriers are supported by the concurrency package of the client,
+// as concurrency.Barrier and concurrency.DoubleBarrier, along with a
+// Rendezvous; new code should use them instead.
 package recipe
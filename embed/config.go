@@ -34,6 +34,7 @@ import (
 	"go.etcd.io/etcd/pkg/v3/transport"
 	"go.etcd.io/etcd/pkg/v3/types"
 	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3audit"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3compactor"
 
 	bolt "go.etcd.io/bbolt"
@@ -273,6 +274,9 @@ type Config struct {
 
 	AuthToken  string `json:"auth-token"`
 	BcryptCost uint   `json:"bcrypt-cost"`
+	// AuthAllowHashedPassword accepts passwords hashed by the client, as long
+	// as their bcrypt cost is not lower than BcryptCost.
+	AuthAllowHashedPassword bool `json:"auth-allow-hashed-password"`
 
 	//The AuthTokenTTL in seconds of the simple token
 	AuthTokenTTL uint `json:"auth-token-ttl"`
@@ -284,6 +288,19 @@ type Config struct {
 	ExperimentalEnableLeaseCheckpoint       bool          `json:"experimental-enable-lease-checkpoint"`
 	ExperimentalCompactionBatchLimit        int           `json:"experimental-compaction-batch-limit"`
 	ExperimentalWatchProgressNotifyInterval time.Duration `json:"experimental-watch-progress-notify-interval"`
+	// ExperimentalEnableMaintenanceScheduler enables the compactions and defragmentations scheduled by "etcdctl maintenance schedule".
+	ExperimentalEnableMaintenanceScheduler bool `json:"experimental-enable-maintenance-scheduler"`
+	// ExperimentalPrefixQuotas is a comma separated list of quotas on the size and number of the keys under a prefix, each "prefix=bytes[:keys]".
+	ExperimentalPrefixQuotas string `json:"experimental-prefix-quotas"`
+	// ExperimentalAuditLogOutputs are the sinks of the audit log of the mutating and auth requests, file paths or "stdout" and "stderr".
+	// The audit log is disabled if empty.
+	ExperimentalAuditLogOutputs []string `json:"experimental-audit-log-outputs"`
+	// ExperimentalAuditLogRateLimit is the maximum number of audit records written per second, 0 is no limit.
+	ExperimentalAuditLogRateLimit float64 `json:"experimental-audit-log-rate-limit"`
+	// ExperimentalAuditLogRedactFields are the fields of the audit records whose values are replaced by a hash.
+	ExperimentalAuditLogRedactFields []string `json:"experimental-audit-log-redact-fields"`
+	// ExperimentalEnableRateLimit enables the rate limits of the client requests set by "etcdctl ratelimit set".
+	ExperimentalEnableRateLimit bool `json:"experimental-enable-rate-limit"`
 
 	// ForceNewCluster starts a new cluster even if previously started; unsafe.
 	ForceNewCluster bool `json:"force-new-cluster"`
@@ -585,6 +602,16 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("--election-timeout[%vms] is too long, and should be set less than %vms", cfg.ElectionMs, maxElectionMs)
 	}
 
+	if _, err := etcdserver.ParsePrefixQuotas(cfg.ExperimentalPrefixQuotas); err != nil {
+		return fmt.Errorf("--experimental-prefix-quotas %q is invalid (%v)", cfg.ExperimentalPrefixQuotas, err)
+	}
+
+	if acfg := cfg.auditLogConfig(); acfg != nil {
+		if err := acfg.Validate(); err != nil {
+			return fmt.Errorf("invalid audit log configuration (%v)", err)
+		}
+	}
+
 	// check this last since proxying in etcdmain may make this OK
 	if cfg.LCUrls != nil && cfg.ACUrls == nil {
 		return ErrUnsetAdvertiseClientURLsFlag
@@ -706,6 +733,19 @@ func (cfg Config) InitialClusterFromName(name string) (ret string) {
 func (cfg Config) IsNewCluster() bool { return cfg.ClusterState == ClusterStateFlagNew }
 func (cfg Config) ElectionTicks() int { return int(cfg.ElectionMs / cfg.TickMs) }
 
+// auditLogConfig returns the configuration of the audit log, or nil if it is
+// disabled.
+func (cfg Config) auditLogConfig() *v3audit.Config {
+	if len(cfg.ExperimentalAuditLogOutputs) == 0 {
+		return nil
+	}
+	return &v3audit.Config{
+		OutputPaths:  cfg.ExperimentalAuditLogOutputs,
+		RateLimit:    cfg.ExperimentalAuditLogRateLimit,
+		RedactFields: cfg.ExperimentalAuditLogRedactFields,
+	}
+}
+
 func (cfg Config) defaultPeerHost() bool {
 	return len(cfg.APUrls) == 1 && cfg.APUrls[0].String() == DefaultInitialAdvertisePeerURLs
 }
@@ -154,6 +154,11 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 
 	backendFreelistType := parseBackendFreelistType(cfg.BackendFreelistType)
 
+	prefixQuotas, err := etcdserver.ParsePrefixQuotas(cfg.ExperimentalPrefixQuotas)
+	if err != nil {
+		return e, err
+	}
+
 	srvcfg := etcdserver.ServerConfig{
 		Name:                        cfg.Name,
 		ClientURLs:                  cfg.ACUrls,
@@ -185,6 +190,7 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		ClientCertAuthEnabled:       cfg.ClientTLSInfo.ClientCertAuth,
 		AuthToken:                   cfg.AuthToken,
 		BcryptCost:                  cfg.BcryptCost,
+		AuthAllowHashedPassword:     cfg.AuthAllowHashedPassword,
 		TokenTTL:                    cfg.AuthTokenTTL,
 		CORS:                        cfg.CORS,
 		HostWhitelist:               cfg.HostWhitelist,
@@ -201,6 +207,10 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		EnableLeaseCheckpoint:       cfg.ExperimentalEnableLeaseCheckpoint,
 		CompactionBatchLimit:        cfg.ExperimentalCompactionBatchLimit,
 		WatchProgressNotifyInterval: cfg.ExperimentalWatchProgressNotifyInterval,
+		EnableMaintenanceScheduler:  cfg.ExperimentalEnableMaintenanceScheduler,
+		PrefixQuotas:                prefixQuotas,
+		AuditLog:                    cfg.auditLogConfig(),
+		EnableRateLimit:             cfg.ExperimentalEnableRateLimit,
 	}
 	print(e.cfg.logger, *cfg, srvcfg, memberInitialized)
 	if e.Server, err = etcdserver.NewServer(srvcfg); err != nil {
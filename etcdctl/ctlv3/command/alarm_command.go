@@ -16,11 +16,15 @@ package command
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	v3 "go.etcd.io/etcd/client/v3"
 )
 
+var alarmListDetails bool
+
 // NewAlarmCommand returns the cobra command for "alarm".
 func NewAlarmCommand() *cobra.Command {
 	ac := &cobra.Command{
@@ -30,6 +34,7 @@ func NewAlarmCommand() *cobra.Command {
 
 	ac.AddCommand(NewAlarmDisarmCommand())
 	ac.AddCommand(NewAlarmListCommand())
+	ac.AddCommand(NewAlarmHistoryCommand())
 
 	return ac
 }
@@ -63,6 +68,7 @@ func NewAlarmListCommand() *cobra.Command {
 		Short: "Lists all alarms",
 		Run:   alarmListCommandFunc,
 	}
+	cmd.Flags().BoolVar(&alarmListDetails, "details", false, "Show the name and endpoint of the alarming member and when the alarm was raised")
 	return &cmd
 }
 
@@ -72,10 +78,123 @@ func alarmListCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("alarm list command accepts no arguments"))
 	}
 	ctx, cancel := commandCtx(cmd)
-	resp, err := mustClientFromCmd(cmd).AlarmList(ctx)
+	c := mustClientFromCmd(cmd)
+	resp, err := c.AlarmList(ctx)
 	cancel()
 	if err != nil {
 		ExitWithError(ExitError, err)
 	}
-	display.Alarm(*resp)
+	if !alarmListDetails {
+		display.Alarm(*resp)
+		return
+	}
+
+	ctx, cancel = commandCtx(cmd)
+	mresp, err := c.MemberList(ctx)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	history, err := fetchAlarmHistory(cmd, c)
+	if err != nil {
+		// older servers do not keep a history; list the alarms without it
+		fmt.Fprintf(os.Stderr, "failed to fetch alarm history (%v)\n", err)
+	}
+
+	infos := make([]alarmInfo, 0, len(resp.Alarms))
+	for _, a := range resp.Alarms {
+		info := alarmInfo{MemberID: a.MemberID, Alarm: a.Alarm.String()}
+		for _, m := range mresp.Members {
+			if m.ID == a.MemberID {
+				info.Name = m.Name
+				if len(m.ClientURLs) > 0 {
+					info.Endpoint = m.ClientURLs[0]
+				}
+			}
+		}
+		info.RaisedAt = alarmRaisedAt(history, info.MemberID, info.Alarm)
+		infos = append(infos, info)
+	}
+	display.AlarmInfos(infos)
+}
+
+func NewAlarmHistoryCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "history",
+		Short: "Lists when alarms were raised and cleared",
+		Long: `Lists the most recent alarms raised and cleared, oldest first, as recorded by the member
+serving the request. The times are when that member applied the alarm change, in UTC.
+`,
+		Run: alarmHistoryCommandFunc,
+	}
+	return &cmd
+}
+
+// alarmHistoryCommandFunc executes the "alarm history" command.
+func alarmHistoryCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("alarm history command accepts no arguments"))
+	}
+	history, err := fetchAlarmHistory(cmd, mustClientFromCmd(cmd))
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.AlarmHistory(history)
+}
+
+// alarmInfo is an active alarm with details about the alarming member.
+type alarmInfo struct {
+	MemberID uint64     `json:"member_id"`
+	Alarm    string     `json:"alarm"`
+	Name     string     `json:"name"`
+	Endpoint string     `json:"endpoint"`
+	RaisedAt *time.Time `json:"raised_at,omitempty"`
+}
+
+// alarmEvent is an alarm raised or cleared, at a time in UTC.
+type alarmEvent struct {
+	MemberID uint64    `json:"member_id"`
+	Alarm    string    `json:"alarm"`
+	Raised   bool      `json:"raised"`
+	Time     time.Time `json:"time"`
+}
+
+// alarmRaisedAt returns when the alarm was last raised according to the
+// history, or nil if the history does not tell.
+func alarmRaisedAt(history []alarmEvent, memberID uint64, alarm string) *time.Time {
+	for i := len(history) - 1; i >= 0; i-- {
+		ev := history[i]
+		if ev.MemberID == memberID && ev.Alarm == alarm {
+			if !ev.Raised {
+				return nil
+			}
+			return &ev.Time
+		}
+	}
+	return nil
+}
+
+// fetchAlarmHistory returns the alarm history of the cluster, as recorded by
+// the member serving the request.
+func fetchAlarmHistory(cmd *cobra.Command, c *v3.Client) ([]alarmEvent, error) {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.AlarmHistory(ctx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	return alarmEvents(resp), nil
+}
+
+func alarmEvents(resp *v3.AlarmHistoryResponse) []alarmEvent {
+	history := make([]alarmEvent, 0, len(resp.Events))
+	for _, ev := range resp.Events {
+		history = append(history, alarmEvent{
+			MemberID: ev.MemberID,
+			Alarm:    ev.Alarm.String(),
+			Raised:   ev.Raised,
+			Time:     time.Unix(0, ev.Time).UTC(),
+		})
+	}
+	return history
 }
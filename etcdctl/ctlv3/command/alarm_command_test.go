@@ -0,0 +1,72 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestAlarmRaisedAt(t *testing.T) {
+	t1 := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	history := []alarmEvent{
+		{MemberID: 1, Alarm: "NOSPACE", Raised: true, Time: t1},
+		{MemberID: 1, Alarm: "NOSPACE", Raised: false, Time: t1.Add(time.Minute)},
+		{MemberID: 2, Alarm: "CORRUPT", Raised: true, Time: t1},
+		{MemberID: 1, Alarm: "NOSPACE", Raised: true, Time: t2},
+		{MemberID: 2, Alarm: "CORRUPT", Raised: false, Time: t2},
+	}
+	tt := []struct {
+		memberID uint64
+		alarm    string
+		raised   *time.Time
+	}{
+		{1, "NOSPACE", &t2},
+		{2, "CORRUPT", nil},
+		{3, "NOSPACE", nil},
+	}
+	for i, tc := range tt {
+		raised := alarmRaisedAt(history, tc.memberID, tc.alarm)
+		if (raised == nil) != (tc.raised == nil) || (raised != nil && !raised.Equal(*tc.raised)) {
+			t.Errorf("#%d: raised = %v, want %v", i, raised, tc.raised)
+		}
+	}
+}
+
+func TestAlarmEvents(t *testing.T) {
+	t1 := time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC)
+	resp := &v3.AlarmHistoryResponse{Events: []*pb.AlarmEvent{
+		{MemberID: 1, Alarm: pb.AlarmType_NOSPACE, Raised: true, Time: t1.UnixNano()},
+		{MemberID: 2, Alarm: pb.AlarmType_CORRUPT, Raised: false, Time: t1.Add(time.Second).UnixNano()},
+	}}
+	want := []alarmEvent{
+		{MemberID: 1, Alarm: "NOSPACE", Raised: true, Time: t1},
+		{MemberID: 2, Alarm: "CORRUPT", Raised: false, Time: t1.Add(time.Second)},
+	}
+	got := alarmEvents(resp)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+	for i, ev := range got {
+		if ev.Time.Location() != time.UTC {
+			t.Errorf("#%d: time %v is not in UTC", i, ev.Time)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/authpb"
+	"go.etcd.io/etcd/client/v3"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	authApplyFile   string
+	authApplyPrune  bool
+	authApplyDryRun bool
+)
+
+func newAuthApplyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply -f <file>",
+		Short: "Reconciles users, roles and permissions with a declarative spec",
+		Long: `Creates the users and roles of the spec that do not exist, grants and revokes
+permissions and roles until they match the spec and, with --prune, deletes the
+users and roles that are not in the spec. The root user and role are never pruned.
+`,
+		Run: authApplyCommandFunc,
+	}
+
+	cmd.Flags().StringVarP(&authApplyFile, "file", "f", "", "YAML or JSON file with the users and roles")
+	cmd.Flags().BoolVar(&authApplyPrune, "prune", false, "delete the users and roles not listed in the file")
+	cmd.Flags().BoolVar(&authApplyDryRun, "dry-run", false, "print the changes without applying them")
+
+	return cmd
+}
+
+// authSpec is the desired state of the users and roles of a cluster.
+type authSpec struct {
+	Roles []authSpecRole `json:"roles"`
+	Users []authSpecUser `json:"users"`
+}
+
+type authSpecRole struct {
+	Name        string         `json:"name"`
+	Permissions []authSpecPerm `json:"permissions"`
+}
+
+type authSpecPerm struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"rangeEnd,omitempty"`
+	Prefix   bool   `json:"prefix,omitempty"`
+	FromKey  bool   `json:"fromKey,omitempty"`
+	// Type is one of read, write or readwrite.
+	Type string `json:"type"`
+}
+
+type authSpecUser struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+	// The password is only used to create a missing user; existing
+	// passwords are left untouched.
+	Password     string `json:"password,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	NoPassword   bool   `json:"noPassword,omitempty"`
+}
+
+// keyRange returns the key range the permission is granted on, expressed
+// the same way as by "role grant-permission".
+func (p authSpecPerm) keyRange() (key, end string, err error) {
+	switch {
+	case p.Prefix && p.FromKey:
+		return "", "", errors.New("prefix and fromKey are mutually exclusive")
+	case (p.Prefix || p.FromKey) && p.RangeEnd != "":
+		return "", "", errors.New("rangeEnd cannot be combined with prefix or fromKey")
+	case p.Key == "" && !p.Prefix && !p.FromKey:
+		return "", "", errors.New("empty key")
+	case p.Key == "":
+		// the whole key space
+		return "\x00", "\x00", nil
+	case p.Prefix:
+		return p.Key, clientv3.GetPrefixRangeEnd(p.Key), nil
+	case p.FromKey:
+		return p.Key, "\x00", nil
+	}
+	return p.Key, p.RangeEnd, nil
+}
+
+// loadAuthSpec reads and validates the spec file. Password files are read
+// relative to the working directory.
+func loadAuthSpec(path string) (authSpec, error) {
+	var spec authSpec
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+	if err = yaml.UnmarshalStrict(b, &spec); err != nil {
+		return spec, err
+	}
+	roles := make(map[string]bool)
+	for _, r := range spec.Roles {
+		if r.Name == "" {
+			return spec, errors.New("role without a name")
+		}
+		if roles[r.Name] {
+			return spec, fmt.Errorf("duplicate role %q", r.Name)
+		}
+		roles[r.Name] = true
+	}
+	users := make(map[string]bool)
+	for i, u := range spec.Users {
+		if u.Name == "" {
+			return spec, errors.New("user without a name")
+		}
+		if users[u.Name] {
+			return spec, fmt.Errorf("duplicate user %q", u.Name)
+		}
+		users[u.Name] = true
+		if u.PasswordFile != "" {
+			if u.Password != "" {
+				return spec, fmt.Errorf("user %q: password and passwordFile are mutually exclusive", u.Name)
+			}
+			pw, err := ioutil.ReadFile(u.PasswordFile)
+			if err != nil {
+				return spec, fmt.Errorf("user %q: %v", u.Name, err)
+			}
+			spec.Users[i].Password = strings.TrimRight(string(pw), "\r\n")
+		}
+	}
+	return spec, nil
+}
+
+// authState is the current state of the users and roles of a cluster.
+type authState struct {
+	roles map[string][]*authpb.Permission
+	users map[string][]string
+}
+
+func getAuthState(ctx context.Context, auth clientv3.Auth) (authState, error) {
+	st := authState{
+		roles: make(map[string][]*authpb.Permission),
+		users: make(map[string][]string),
+	}
+	rl, err := auth.RoleList(ctx)
+	if err != nil {
+		return st, err
+	}
+	for _, r := range rl.Roles {
+		resp, err := auth.RoleGet(ctx, r)
+		if err != nil {
+			return st, err
+		}
+		st.roles[r] = resp.Perm
+	}
+	ul, err := auth.UserList(ctx)
+	if err != nil {
+		return st, err
+	}
+	for _, u := range ul.Users {
+		resp, err := auth.UserGet(ctx, u)
+		if err != nil {
+			return st, err
+		}
+		st.users[u] = resp.Roles
+	}
+	return st, nil
+}
+
+type authActionType int
+
+const (
+	authAddRole authActionType = iota
+	authGrantPermission
+	authRevokePermission
+	authAddUser
+	authGrantRole
+	authRevokeRole
+	authDeleteUser
+	authDeleteRole
+)
+
+// authAction is a single change made by "auth apply".
+type authAction struct {
+	typ  authActionType
+	role string
+	user string
+
+	// permission changes
+	key, end string
+	perm     clientv3.PermissionType
+	// oldPerm is set if the permission replaces another one on the same range.
+	oldPerm *clientv3.PermissionType
+
+	// user creation
+	password   string
+	noPassword bool
+}
+
+func (a authAction) String() string {
+	switch a.typ {
+	case authAddRole:
+		return fmt.Sprintf("+ role %s", a.role)
+	case authGrantPermission:
+		r := permRangeString(a.key, a.end)
+		if a.oldPerm != nil {
+			return fmt.Sprintf("~ role %s permission %s %s -> %s", a.role, r, authpb.Permission_Type(*a.oldPerm), authpb.Permission_Type(a.perm))
+		}
+		return fmt.Sprintf("+ role %s permission %s %s", a.role, r, authpb.Permission_Type(a.perm))
+	case authRevokePermission:
+		return fmt.Sprintf("- role %s permission %s %s", a.role, permRangeString(a.key, a.end), authpb.Permission_Type(a.perm))
+	case authAddUser:
+		return fmt.Sprintf("+ user %s", a.user)
+	case authGrantRole:
+		return fmt.Sprintf("+ user %s role %s", a.user, a.role)
+	case authRevokeRole:
+		return fmt.Sprintf("- user %s role %s", a.user, a.role)
+	case authDeleteUser:
+		return fmt.Sprintf("- user %s", a.user)
+	case authDeleteRole:
+		return fmt.Sprintf("- role %s", a.role)
+	}
+	return "unknown change"
+}
+
+func (a authAction) apply(ctx context.Context, auth clientv3.Auth) (err error) {
+	switch a.typ {
+	case authAddRole:
+		_, err = auth.RoleAdd(ctx, a.role)
+	case authGrantPermission:
+		_, err = auth.RoleGrantPermission(ctx, a.role, a.key, a.end, a.perm)
+	case authRevokePermission:
+		_, err = auth.RoleRevokePermission(ctx, a.role, a.key, a.end)
+	case authAddUser:
+		_, err = auth.UserAddWithOptions(ctx, a.user, a.password, &clientv3.UserAddOptions{NoPassword: a.noPassword})
+	case authGrantRole:
+		_, err = auth.UserGrantRole(ctx, a.user, a.role)
+	case authRevokeRole:
+		_, err = auth.UserRevokeRole(ctx, a.user, a.role)
+	case authDeleteUser:
+		_, err = auth.UserDelete(ctx, a.user)
+	case authDeleteRole:
+		_, err = auth.RoleDelete(ctx, a.role)
+	}
+	return err
+}
+
+// planAuthApply returns the changes that make the current state match the
+// spec, in the order they must be applied: roles and their permissions
+// first, then users and their roles, then the pruned users and roles.
+func planAuthApply(spec authSpec, cur authState, prune bool) ([]authAction, error) {
+	var acts []authAction
+
+	type permRange struct{ key, end string }
+	wantRoles := make(map[string]bool)
+	for _, r := range spec.Roles {
+		wantRoles[r.Name] = true
+		have := make(map[permRange]clientv3.PermissionType)
+		perms, ok := cur.roles[r.Name]
+		if !ok {
+			acts = append(acts, authAction{typ: authAddRole, role: r.Name})
+		}
+		for _, p := range perms {
+			have[permRange{string(p.Key), string(p.RangeEnd)}] = clientv3.PermissionType(p.PermType)
+		}
+
+		want := make(map[permRange]bool)
+		for _, p := range r.Permissions {
+			key, end, err := p.keyRange()
+			if err != nil {
+				return nil, fmt.Errorf("role %q: %v", r.Name, err)
+			}
+			typ, err := clientv3.StrToPermissionType(p.Type)
+			if err != nil {
+				return nil, fmt.Errorf("role %q: %v", r.Name, err)
+			}
+			pr := permRange{key, end}
+			if want[pr] {
+				return nil, fmt.Errorf("role %q: duplicate permission on %s", r.Name, permRangeString(key, end))
+			}
+			want[pr] = true
+			old, ok := have[pr]
+			switch {
+			case !ok:
+				acts = append(acts, authAction{typ: authGrantPermission, role: r.Name, key: key, end: end, perm: typ})
+			case old != typ:
+				old := old
+				acts = append(acts, authAction{typ: authGrantPermission, role: r.Name, key: key, end: end, perm: typ, oldPerm: &old})
+			}
+		}
+		for _, p := range perms {
+			if !want[permRange{string(p.Key), string(p.RangeEnd)}] {
+				acts = append(acts, authAction{typ: authRevokePermission, role: r.Name, key: string(p.Key), end: string(p.RangeEnd), perm: clientv3.PermissionType(p.PermType)})
+			}
+		}
+	}
+
+	wantUsers := make(map[string]bool)
+	for _, u := range spec.Users {
+		wantUsers[u.Name] = true
+		roles, ok := cur.users[u.Name]
+		if !ok {
+			if u.Password == "" && !u.NoPassword {
+				return nil, fmt.Errorf("user %q does not exist and has no password, passwordFile or noPassword", u.Name)
+			}
+			acts = append(acts, authAction{typ: authAddUser, user: u.Name, password: u.Password, noPassword: u.NoPassword})
+		}
+		have := make(map[string]bool)
+		for _, r := range roles {
+			have[r] = true
+		}
+		want := make(map[string]bool)
+		for _, r := range u.Roles {
+			if _, ok := cur.roles[r]; !ok && !wantRoles[r] {
+				return nil, fmt.Errorf("user %q: role %q does not exist", u.Name, r)
+			}
+			want[r] = true
+			if !have[r] {
+				acts = append(acts, authAction{typ: authGrantRole, user: u.Name, role: r})
+			}
+		}
+		for _, r := range roles {
+			if !want[r] {
+				acts = append(acts, authAction{typ: authRevokeRole, user: u.Name, role: r})
+			}
+		}
+	}
+
+	if prune {
+		var users, roles []string
+		for u := range cur.users {
+			if !wantUsers[u] && u != "root" {
+				users = append(users, u)
+			}
+		}
+		for r := range cur.roles {
+			if !wantRoles[r] && r != "root" {
+				roles = append(roles, r)
+			}
+		}
+		sort.Strings(users)
+		sort.Strings(roles)
+		for _, u := range users {
+			acts = append(acts, authAction{typ: authDeleteUser, user: u})
+		}
+		for _, r := range roles {
+			acts = append(acts, authAction{typ: authDeleteRole, role: r})
+		}
+	}
+	return acts, nil
+}
+
+// authApplyCommandFunc executes the "auth apply" command.
+func authApplyCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("auth apply command does not accept any arguments"))
+	}
+	if authApplyFile == "" {
+		ExitWithError(ExitBadArgs, fmt.Errorf("auth apply command requires --file"))
+	}
+	spec, err := loadAuthSpec(authApplyFile)
+	if err != nil {
+		ExitWithError(ExitBadArgs, fmt.Errorf("failed to load %s (%v)", authApplyFile, err))
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	cur, err := getAuthState(ctx, c.Auth)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	acts, err := planAuthApply(spec, cur, authApplyPrune)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+
+	for i, a := range acts {
+		fmt.Println(a)
+		if authApplyDryRun {
+			continue
+		}
+		ctx, cancel := commandCtx(cmd)
+		err = a.apply(ctx, c.Auth)
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, fmt.Errorf("failed to apply %q after %d change(s) (%v)", a, i, err))
+		}
+	}
+	if authApplyDryRun {
+		fmt.Printf("dry-run: %d change(s) not applied\n", len(acts))
+		return
+	}
+	fmt.Printf("%d change(s) applied\n", len(acts))
+}
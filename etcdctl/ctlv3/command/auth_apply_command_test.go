@@ -0,0 +1,106 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/authpb"
+)
+
+func TestAuthSpecPermKeyRange(t *testing.T) {
+	tt := []struct {
+		perm authSpecPerm
+
+		key, end string
+		err      bool
+	}{
+		{perm: authSpecPerm{Key: "foo"}, key: "foo"},
+		{perm: authSpecPerm{Key: "a", RangeEnd: "c"}, key: "a", end: "c"},
+		{perm: authSpecPerm{Key: "foo", Prefix: true}, key: "foo", end: "fop"},
+		{perm: authSpecPerm{Key: "foo", FromKey: true}, key: "foo", end: "\x00"},
+		{perm: authSpecPerm{Prefix: true}, key: "\x00", end: "\x00"},
+		{perm: authSpecPerm{}, err: true},
+		{perm: authSpecPerm{Key: "foo", Prefix: true, FromKey: true}, err: true},
+		{perm: authSpecPerm{Key: "foo", RangeEnd: "g", Prefix: true}, err: true},
+	}
+	for i, tc := range tt {
+		key, end, err := tc.perm.keyRange()
+		if (err != nil) != tc.err || key != tc.key || end != tc.end {
+			t.Errorf("#%d: expected (%q, %q, err %v), got (%q, %q, %v)", i, tc.key, tc.end, tc.err, key, end, err)
+		}
+	}
+}
+
+func TestPlanAuthApply(t *testing.T) {
+	cur := authState{
+		roles: map[string][]*authpb.Permission{
+			"root":   nil,
+			"reader": {{Key: []byte("foo"), PermType: authpb.READ}, {Key: []byte("old"), PermType: authpb.READ}},
+			"stale":  nil,
+		},
+		users: map[string][]string{
+			"root":  {"root"},
+			"alice": {"stale"},
+			"bob":   nil,
+		},
+	}
+	spec := authSpec{
+		Roles: []authSpecRole{
+			{Name: "reader", Permissions: []authSpecPerm{{Key: "foo", Type: "readwrite"}}},
+			{Name: "writer", Permissions: []authSpecPerm{{Key: "/app/", Prefix: true, Type: "write"}}},
+		},
+		Users: []authSpecUser{
+			{Name: "alice", Roles: []string{"reader"}},
+			{Name: "carol", Roles: []string{"writer"}, NoPassword: true},
+		},
+	}
+
+	acts, err := planAuthApply(spec, cur, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range acts {
+		got = append(got, a.String())
+	}
+	want := []string{
+		"~ role reader permission foo READ -> READWRITE",
+		"- role reader permission old READ",
+		"+ role writer",
+		"+ role writer permission [/app/, /app0) (prefix /app/) WRITE",
+		"+ user alice role reader",
+		"- user alice role stale",
+		"+ user carol",
+		"+ user carol role writer",
+		"- user bob",
+		"- role stale",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// an empty spec changes nothing on an empty cluster
+	if acts, err = planAuthApply(authSpec{}, authState{}, false); err != nil || len(acts) != 0 {
+		t.Errorf("expected no change, got %v (%v)", acts, err)
+	}
+
+	// a missing user needs a password
+	spec.Users = append(spec.Users, authSpecUser{Name: "dave"})
+	if _, err = planAuthApply(spec, cur, false); err == nil {
+		t.Error("expected an error for a new user without password")
+	}
+}
@@ -18,9 +18,17 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/v3/auth"
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var authDataDir string
+
 // NewAuthCommand returns the cobra command for "auth".
 func NewAuthCommand() *cobra.Command {
 	ac := &cobra.Command{
@@ -31,6 +39,9 @@ func NewAuthCommand() *cobra.Command {
 	ac.AddCommand(newAuthEnableCommand())
 	ac.AddCommand(newAuthDisableCommand())
 	ac.AddCommand(newAuthStatusCommand())
+	ac.AddCommand(newAuthApplyCommand())
+	ac.AddCommand(newAuthTokenCommand())
+	ac.AddCommand(newAuthAddRootUserCommand())
 
 	return ac
 }
@@ -98,11 +109,13 @@ func authEnableCommandFunc(cmd *cobra.Command, args []string) {
 }
 
 func newAuthDisableCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "disable",
 		Short: "Disables authentication",
 		Run:   authDisableCommandFunc,
 	}
+	cmd.Flags().StringVar(&authDataDir, "data-dir", "", "Disables authentication in the data directory of a stopped member instead")
+	return cmd
 }
 
 // authDisableCommandFunc executes the "auth disable" command.
@@ -111,6 +124,15 @@ func authDisableCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("auth disable command does not accept any arguments"))
 	}
 
+	if authDataDir != "" {
+		be, as := openDataAuthStore(authDataDir)
+		as.AuthDisable()
+		as.Close()
+		be.Close()
+		fmt.Println("Authentication Disabled")
+		return
+	}
+
 	ctx, cancel := commandCtx(cmd)
 	_, err := mustClientFromCmd(cmd).Auth.AuthDisable(ctx)
 	cancel()
@@ -120,3 +142,94 @@ func authDisableCommandFunc(cmd *cobra.Command, args []string) {
 
 	fmt.Println("Authentication Disabled")
 }
+
+func newAuthAddRootUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add-root-user --data-dir <data-dir>",
+		Short: "Adds, or resets the password of, the root user in the data directory of a stopped member",
+		Run:   authAddRootUserCommandFunc,
+	}
+	cmd.Flags().StringVar(&authDataDir, "data-dir", "", "Path to the data directory")
+	cmd.Flags().StringVar(&passwordFromFlag, "new-user-password", "", "Supply password from the command line flag")
+	cmd.Flags().BoolVar(&passwordInteractive, "interactive", true, "Read password from stdin instead of interactive terminal")
+	addPasswordFlags(cmd)
+	cmd.MarkFlagRequired("data-dir")
+	return cmd
+}
+
+// authAddRootUserCommandFunc executes the "auth add-root-user" command.
+func authAddRootUserCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("auth add-root-user command does not accept any arguments"))
+	}
+	password := mustReadPassword("root", passwordFromFlag)
+
+	be, as := openDataAuthStore(authDataDir)
+	defer be.Close()
+	defer as.Close()
+	if err := addRootUser(as, password); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Println("Root user added")
+}
+
+// openDataAuthStore opens the auth store of the data directory. The member
+// must be stopped; its other members do not see the changes, so they are
+// to be made in the data directory of every member.
+func openDataAuthStore(dataDir string) (backend.Backend, auth.AuthStore) {
+	be := openDataBackend(dataDir)
+	tp, err := auth.NewTokenProvider(zap.NewNop(), "", nil, 0)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	// load the consistent index, saved back along the auth changes
+	ci := cindex.NewConsistentIndex(be.BatchTx())
+	ci.ConsistentIndex()
+	return be, auth.NewAuthStore(zap.NewNop(), be, ci, tp, bcrypt.DefaultCost)
+}
+
+// addRootUser adds the root user with the password and the root role,
+// replacing the root user if it exists, for instance without a password,
+// but keeping its other roles. Authentication stays enabled if it was.
+func addRootUser(as auth.AuthStore, password string) error {
+	enabled := as.IsAuthEnabled()
+	if enabled {
+		// the root user cannot be deleted while authentication is enabled
+		as.AuthDisable()
+	}
+
+	roles := []string{"root"}
+	if resp, err := as.UserGet(&pb.AuthUserGetRequest{Name: "root"}); err == nil {
+		for _, r := range resp.Roles {
+			if r != "root" {
+				roles = append(roles, r)
+			}
+		}
+		if _, err = as.UserDelete(&pb.AuthUserDeleteRequest{Name: "root"}); err != nil {
+			return err
+		}
+	} else if err != auth.ErrUserNotFound {
+		return err
+	}
+
+	r := &pb.AuthUserAddRequest{Name: "root", Password: password}
+	if passwordBcryptCost != 0 {
+		r.Password, r.HashedPassword = "", mustHashPassword(password)
+	}
+	if _, err := as.UserAdd(r); err != nil {
+		return err
+	}
+	if _, err := as.RoleAdd(&pb.AuthRoleAddRequest{Name: "root"}); err != nil && err != auth.ErrRoleAlreadyExist {
+		return err
+	}
+	for _, role := range roles {
+		if _, err := as.UserGrantRole(&pb.AuthUserGrantRoleRequest{User: "root", Role: role}); err != nil {
+			return err
+		}
+	}
+
+	if enabled {
+		return as.AuthEnable()
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/metadata"
+)
+
+var authTokenFile string
+
+func newAuthTokenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token <subcommand>",
+		Short: "Auth token related commands",
+	}
+
+	cmd.AddCommand(newAuthTokenInspectCommand())
+	cmd.AddCommand(newAuthTokenRevokeCommand())
+
+	return cmd
+}
+
+func newAuthTokenInspectCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Shows the user, revision and TTL of an auth token",
+		Run:   authTokenInspectCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&authTokenFile, "token-file", "", "Inspect the token read from the given file instead of a token issued for --user")
+
+	return cmd
+}
+
+func newAuthTokenRevokeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke [user name]",
+		Short: "Invalidates the simple tokens of a user, or of the current user",
+		Run:   authTokenRevokeCommandFunc,
+	}
+}
+
+// authTokenInfo describes an auth token as seen by the cluster.
+type authTokenInfo struct {
+	Type string `json:"type"`
+	User string `json:"user,omitempty"`
+	// Index is the raft index a simple token was issued at.
+	Index uint64 `json:"index,omitempty"`
+	// Revision is the auth revision the token is checked against. JWT
+	// tokens carry the revision they were issued at; simple tokens are
+	// always checked against the current one.
+	Revision uint64 `json:"revision,omitempty"`
+	// AuthRevision is the current auth revision of the cluster, if the
+	// owner of the token may read it.
+	AuthRevision uint64 `json:"authRevision,omitempty"`
+	// TTL is the remaining lifetime in seconds, or -1 if it is only known
+	// to the server.
+	TTL   int64 `json:"ttl"`
+	Valid bool  `json:"valid"`
+}
+
+// Stale reports whether requests with the token are rejected because the
+// auth configuration changed after it was issued.
+func (t authTokenInfo) Stale() bool { return t.Type == "jwt" && t.Revision < t.AuthRevision }
+
+// authTokenInspectCommandFunc executes the "auth token inspect" command.
+func authTokenInspectCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("auth token inspect command does not accept any arguments"))
+	}
+
+	cc := clientConfigFromCmd(cmd)
+	acfg := cc.acfg
+	// the token is attached to the requests by hand
+	cc.acfg = nil
+	c := cc.mustClient()
+
+	var token string
+	if authTokenFile != "" {
+		line, err := readPasswordFile(authTokenFile)
+		if err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+		token = line
+	} else {
+		if acfg == nil {
+			ExitWithError(ExitBadArgs, errors.New("either --user or --token-file is required"))
+		}
+		ctx, cancel := commandCtx(cmd)
+		resp, err := c.Auth.Authenticate(ctx, acfg.username, acfg.password)
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		token = resp.Token
+	}
+
+	info, err := parseAuthToken(token, time.Now())
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	if info.User == "" && acfg != nil && authTokenFile == "" {
+		info.User = acfg.username
+	}
+
+	// the server checks the token before the permissions of the request,
+	// so a permission error still means the token itself is valid
+	ctx, cancel := commandCtx(cmd)
+	ctx = metadata.AppendToOutgoingContext(ctx, rpctypes.TokenFieldNameGRPC, token)
+	st, err := c.Auth.AuthStatus(ctx)
+	cancel()
+	switch err {
+	case nil:
+		info.Valid = true
+		info.AuthRevision = st.AuthRevision
+		if info.Type == "simple" {
+			info.Revision = st.AuthRevision
+		}
+	case rpctypes.ErrPermissionDenied:
+		// only admins can read the current auth revision
+		info.Valid = true
+	case rpctypes.ErrInvalidAuthToken:
+	default:
+		ExitWithError(ExitError, err)
+	}
+
+	display.AuthTokenInspect(info)
+}
+
+// authTokenRevokeCommandFunc executes the "auth token revoke" command.
+func authTokenRevokeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		ExitWithError(ExitBadArgs, errors.New("auth token revoke command accepts at most one user name as its argument"))
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	}
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).Auth.UserTokenRevoke(ctx, name)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.AuthTokenRevoke(name, *resp)
+}
+
+// parseAuthToken decodes what the client can learn from a token by itself.
+// The signature of JWT tokens is not verified.
+func parseAuthToken(token string, now time.Time) (authTokenInfo, error) {
+	parts := strings.Split(token, ".")
+	switch len(parts) {
+	case 2:
+		index, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return authTokenInfo{}, fmt.Errorf("malformed simple token (%v)", err)
+		}
+		return authTokenInfo{Type: "simple", Index: index, TTL: -1}, nil
+	case 3:
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err != nil {
+			return authTokenInfo{}, fmt.Errorf("malformed JWT token (%v)", err)
+		}
+		var claims struct {
+			Username string  `json:"username"`
+			Revision uint64  `json:"revision"`
+			Exp      float64 `json:"exp"`
+		}
+		if err = json.Unmarshal(payload, &claims); err != nil {
+			return authTokenInfo{}, fmt.Errorf("malformed JWT token (%v)", err)
+		}
+		info := authTokenInfo{Type: "jwt", User: claims.Username, Revision: claims.Revision, TTL: -1}
+		if claims.Exp != 0 {
+			info.TTL = int64(claims.Exp) - now.Unix()
+			if info.TTL < 0 {
+				info.TTL = 0
+			}
+		}
+		return info, nil
+	}
+	return authTokenInfo{}, errors.New("unknown auth token format")
+}
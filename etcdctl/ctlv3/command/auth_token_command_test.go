@@ -0,0 +1,79 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAuthToken(t *testing.T) {
+	now := time.Unix(1000, 0)
+	jwt := func(claims string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2ln"
+	}
+
+	tt := []struct {
+		token string
+
+		info authTokenInfo
+		err  bool
+	}{
+		{
+			token: "dHhWOZyfyaaIPqWK.22",
+			info:  authTokenInfo{Type: "simple", Index: 22, TTL: -1},
+		},
+		{
+			token: jwt(`{"exp":1300,"revision":4,"username":"root"}`),
+			info:  authTokenInfo{Type: "jwt", User: "root", Revision: 4, TTL: 300},
+		},
+		{
+			token: jwt(`{"exp":900,"revision":4,"username":"root"}`),
+			info:  authTokenInfo{Type: "jwt", User: "root", Revision: 4, TTL: 0},
+		},
+		{token: "dHhWOZyfyaaIPqWK.x", err: true},
+		{token: jwt(`not json`), err: true},
+		{token: "token", err: true},
+	}
+	for i, tc := range tt {
+		info, err := parseAuthToken(tc.token, now)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: expected error %v, got %v", i, tc.err, err)
+		}
+		if !reflect.DeepEqual(info, tc.info) {
+			t.Errorf("#%d: expected %+v, got %+v", i, tc.info, info)
+		}
+	}
+}
+
+func TestAuthTokenInfoStale(t *testing.T) {
+	tt := []struct {
+		info  authTokenInfo
+		stale bool
+	}{
+		{info: authTokenInfo{Type: "jwt", Revision: 3, AuthRevision: 3}},
+		{info: authTokenInfo{Type: "jwt", Revision: 2, AuthRevision: 3}, stale: true},
+		// the current auth revision is unknown
+		{info: authTokenInfo{Type: "jwt", Revision: 2}},
+		{info: authTokenInfo{Type: "simple", AuthRevision: 3}},
+	}
+	for i, tc := range tt {
+		if stale := tc.info.Stale(); stale != tc.stale {
+			t.Errorf("#%d: expected stale %v, got %v", i, tc.stale, stale)
+		}
+	}
+}
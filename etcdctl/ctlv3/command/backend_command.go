@@ -0,0 +1,409 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	bolt "go.etcd.io/bbolt"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/etcdctl/v3/snapshot"
+	"go.uber.org/zap"
+)
+
+var (
+	backendDumpFormat       string
+	backendDumpAllRevisions bool
+
+	backendHashKVRev int64
+)
+
+// NewBackendCommand returns the cobra command for "backend".
+func NewBackendCommand() *cobra.Command {
+	bc := &cobra.Command{
+		Use:   "backend <subcommand>",
+		Short: "Backend database related commands",
+	}
+
+	bc.AddCommand(newBackendInspectCommand())
+	bc.AddCommand(newBackendDumpCommand())
+	bc.AddCommand(newBackendHashKVCommand())
+
+	return bc
+}
+
+func newBackendInspectCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <db file or data directory>",
+		Short: "Reports the buckets and the free pages of a backend database",
+		Long: `Reports the record count and the size of every bucket of a backend database
+not in use by etcd, such as a snapshot file or the db file of a data
+directory, along with its free pages and its fragmentation: the share of the
+file that a defragmentation would give back.
+`,
+		Run: backendInspectCommandFunc,
+	}
+}
+
+// backendInspection describes the storage of a backend database.
+type backendInspection struct {
+	Path     string `json:"path"`
+	PageSize int    `json:"page_size"`
+	Size     int64  `json:"size"`
+	// SizeInUse is the size of the pages holding data.
+	SizeInUse int64 `json:"size_in_use"`
+	FreePages int   `json:"free_pages"`
+	// PendingPages are freed pages still reachable by a past transaction.
+	PendingPages int `json:"pending_pages"`
+	// FreelistSize is the size of the page list of the free pages.
+	FreelistSize int `json:"freelist_size"`
+	// Fragmentation is the share of the file made of free pages.
+	Fragmentation float64     `json:"fragmentation"`
+	Buckets       []bucketUse `json:"buckets"`
+}
+
+// bucketUse describes the storage of a bucket.
+type bucketUse struct {
+	Name       string `json:"name"`
+	Keys       int    `json:"keys"`
+	KeyBytes   int64  `json:"key_bytes"`
+	ValueBytes int64  `json:"value_bytes"`
+	Pages      int    `json:"pages"`
+	// Allocated is the size of the pages of the bucket, of which InUse
+	// bytes hold data.
+	Allocated int64 `json:"allocated"`
+	InUse     int64 `json:"in_use"`
+	Depth     int   `json:"depth"`
+}
+
+// backendInspectCommandFunc executes the "backend inspect" command.
+func backendInspectCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("backend inspect requires exactly one argument"))
+	}
+	bi, err := inspectBackend(backendPath(args[0]))
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.BackendInspection(bi)
+}
+
+// backendPath returns the db file of a data directory, or p if it is not a
+// directory.
+func backendPath(p string) string {
+	if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+		return filepath.Join(p, "member", "snap", "db")
+	}
+	return p
+}
+
+func inspectBackend(dbPath string) (bi backendInspection, err error) {
+	if _, err = os.Stat(dbPath); err != nil {
+		return bi, err
+	}
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return bi, err
+	}
+	defer db.Close()
+
+	st := db.Stats()
+	bi = backendInspection{
+		Path:         dbPath,
+		PageSize:     db.Info().PageSize,
+		FreePages:    st.FreePageN,
+		PendingPages: st.PendingPageN,
+		FreelistSize: st.FreelistInuse,
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		bi.Size = tx.Size()
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			bs := b.Stats()
+			bu := bucketUse{
+				Name:      string(name),
+				Keys:      bs.KeyN,
+				Pages:     bs.BranchPageN + bs.BranchOverflowN + bs.LeafPageN + bs.LeafOverflowN,
+				Allocated: int64(bs.BranchAlloc + bs.LeafAlloc),
+				InUse:     int64(bs.BranchInuse + bs.LeafInuse),
+				Depth:     bs.Depth,
+			}
+			if err := b.ForEach(func(k, v []byte) error {
+				bu.KeyBytes += int64(len(k))
+				bu.ValueBytes += int64(len(v))
+				return nil
+			}); err != nil {
+				return err
+			}
+			bi.Buckets = append(bi.Buckets, bu)
+			return nil
+		})
+	})
+	if err != nil {
+		return bi, err
+	}
+
+	free := int64(bi.FreePages+bi.PendingPages) * int64(bi.PageSize)
+	bi.SizeInUse = bi.Size - free
+	if bi.Size > 0 {
+		bi.Fragmentation = float64(free) / float64(bi.Size)
+	}
+	return bi, nil
+}
+
+func newBackendDumpCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dump [options] <db file or data directory>",
+		Short: "Dumps the keys of a backend database to standard output",
+		Long: `Dumps the keys of a backend database not in use by etcd, such as a snapshot
+file or the db file of a data directory, as JSON lines or as SQL statements.
+Only the keys at the latest revision are dumped unless --all-revisions is
+given, in which case every revision kept in the database is dumped in
+revision order, deletions included.
+`,
+		Run: backendDumpCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&backendDumpFormat, "format", "jsonl", "Output format (jsonl or sql)")
+	cmd.Flags().BoolVar(&backendDumpAllRevisions, "all-revisions", false, "Dumps every revision instead of the keys at the latest revision")
+
+	return cmd
+}
+
+// dumpedKV is a revision of a key of a backend database. Key and Value are
+// base64 encoded in JSON.
+type dumpedKV struct {
+	Key            []byte `json:"key"`
+	Value          []byte `json:"value,omitempty"`
+	CreateRevision int64  `json:"create_revision,omitempty"`
+	ModRevision    int64  `json:"mod_revision"`
+	// SubRevision orders the changes of a same transaction.
+	SubRevision int64 `json:"sub_revision"`
+	Version     int64 `json:"version,omitempty"`
+	Lease       int64 `json:"lease,omitempty"`
+	// Tombstone marks the deletion of the key.
+	Tombstone bool `json:"tombstone,omitempty"`
+}
+
+// backendDumpCommandFunc executes the "backend dump" command.
+func backendDumpCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("backend dump requires exactly one argument"))
+	}
+	var w kvDumpWriter
+	out := bufio.NewWriter(os.Stdout)
+	switch backendDumpFormat {
+	case "jsonl":
+		w = &jsonlDumpWriter{enc: json.NewEncoder(out)}
+	case "sql":
+		w = &sqlDumpWriter{w: out, allRevisions: backendDumpAllRevisions}
+	default:
+		ExitWithError(ExitBadArgs, fmt.Errorf("unknown format %q (expected jsonl or sql)", backendDumpFormat))
+	}
+
+	err := dumpBackend(backendPath(args[0]), backendDumpAllRevisions, w)
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+}
+
+// kvDumpWriter writes the revisions of a dump.
+type kvDumpWriter interface {
+	Begin() error
+	Write(kv dumpedKV) error
+	End() error
+}
+
+// dumpBackend writes the keys at the latest revision of the database, in key
+// order, or all its revisions in revision order.
+func dumpBackend(dbPath string, allRevisions bool, w kvDumpWriter) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return err
+	}
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = w.Begin(); err != nil {
+		return err
+	}
+	latest := make(map[string]dumpedKV)
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return errors.New("database has no key bucket")
+		}
+		return b.ForEach(func(k, v []byte) error {
+			kv, err := decodeDumpedKV(k, v)
+			if err != nil {
+				return err
+			}
+			if allRevisions {
+				return w.Write(kv)
+			}
+			latest[string(kv.Key)] = kv
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if !allRevisions {
+		keys := make([]string, 0, len(latest))
+		for k, kv := range latest {
+			if !kv.Tombstone {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err = w.Write(latest[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return w.End()
+}
+
+// decodeDumpedKV decodes a record of the key bucket, keyed by its revision.
+func decodeDumpedKV(k, v []byte) (dumpedKV, error) {
+	if len(k) < 17 {
+		return dumpedKV{}, fmt.Errorf("invalid revision %x", k)
+	}
+	var kv mvccpb.KeyValue
+	if err := kv.Unmarshal(v); err != nil {
+		return dumpedKV{}, fmt.Errorf("cannot decode revision %x (%v)", k, err)
+	}
+	return dumpedKV{
+		Key:            kv.Key,
+		Value:          kv.Value,
+		CreateRevision: kv.CreateRevision,
+		ModRevision:    int64(binary.BigEndian.Uint64(k[0:8])),
+		SubRevision:    int64(binary.BigEndian.Uint64(k[9:17])),
+		Version:        kv.Version,
+		Lease:          kv.Lease,
+		Tombstone:      len(k) == 18 && k[17] == 't',
+	}, nil
+}
+
+type jsonlDumpWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonlDumpWriter) Begin() error            { return nil }
+func (w *jsonlDumpWriter) Write(kv dumpedKV) error { return w.enc.Encode(kv) }
+func (w *jsonlDumpWriter) End() error              { return nil }
+
+// sqlDumpWriter writes the revisions as the statements creating and filling
+// a "kvs" table. Keys and values are blob literals.
+type sqlDumpWriter struct {
+	w            io.Writer
+	allRevisions bool
+}
+
+func (w *sqlDumpWriter) Begin() error {
+	pk, tombstone := "PRIMARY KEY (key)", ""
+	if w.allRevisions {
+		pk, tombstone = "PRIMARY KEY (mod_revision, sub_revision)", "\n  tombstone BOOLEAN NOT NULL,"
+	}
+	_, err := fmt.Fprintf(w.w, `BEGIN TRANSACTION;
+CREATE TABLE kvs (
+  key BLOB NOT NULL,
+  value BLOB NOT NULL,
+  create_revision INTEGER NOT NULL,
+  mod_revision INTEGER NOT NULL,
+  sub_revision INTEGER NOT NULL,
+  version INTEGER NOT NULL,
+  lease INTEGER NOT NULL,%s
+  %s
+);
+`, tombstone, pk)
+	return err
+}
+
+func (w *sqlDumpWriter) Write(kv dumpedKV) error {
+	tombstone := ""
+	if w.allRevisions {
+		tombstone = fmt.Sprintf(", %t", kv.Tombstone)
+	}
+	_, err := fmt.Fprintf(w.w, "INSERT INTO kvs VALUES (X'%s', X'%s', %d, %d, %d, %d, %d%s);\n",
+		hex.EncodeToString(kv.Key), hex.EncodeToString(kv.Value),
+		kv.CreateRevision, kv.ModRevision, kv.SubRevision, kv.Version, kv.Lease, tombstone)
+	return err
+}
+
+func (w *sqlDumpWriter) End() error {
+	_, err := io.WriteString(w.w, "COMMIT;\n")
+	return err
+}
+
+func newBackendHashKVCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hashkv [options] <db file or data directory>",
+		Short: "Prints the KV history hash of a backend database",
+		Long: `Prints the hash of the keyspace of a backend database not in use by etcd,
+such as a snapshot file or the db file of a data directory, as "endpoint
+hashkv" prints the hash of a member. Hashes at a same revision match if the
+keyspaces match, so a backup can be checked against the live members
+without being restored.
+`,
+		Run: backendHashKVCommandFunc,
+	}
+
+	cmd.Flags().Int64Var(&backendHashKVRev, "rev", 0, "maximum revision to hash (default: latest revision)")
+
+	return cmd
+}
+
+// backendHashKVCommandFunc executes the "backend hashkv" command.
+func backendHashKVCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("backend hashkv requires exactly one argument"))
+	}
+	if backendHashKVRev < 0 {
+		ExitWithError(ExitBadArgs, errors.New("--rev must not be negative"))
+	}
+
+	dbPath := backendPath(args[0])
+	h, err := snapshot.NewV3(zap.NewNop()).HashKV(dbPath, backendHashKVRev)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.EndpointHashKV([]epHashKV{{
+		Ep: dbPath,
+		Resp: &v3.HashKVResponse{
+			Header:          &pb.ResponseHeader{Revision: h.Revision},
+			Hash:            h.Hash,
+			CompactRevision: h.CompactRevision,
+		},
+	}})
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestInspectBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backend-inspect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "member", "snap", "db")
+	if err = os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.Open(dbPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		kb, err := tx.CreateBucket([]byte("key"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 3; i++ {
+			if err = kb.Put([]byte(fmt.Sprintf("k%d", i)), []byte("value")); err != nil {
+				return err
+			}
+		}
+		_, err = tx.CreateBucket([]byte("meta"))
+		return err
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := backendPath(dir); p != dbPath {
+		t.Fatalf("backend path = %q, want %q", p, dbPath)
+	}
+	bi, err := inspectBackend(backendPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bi.PageSize == 0 || bi.Size == 0 || bi.SizeInUse > bi.Size {
+		t.Fatalf("unexpected sizes %+v", bi)
+	}
+	if bi.Fragmentation < 0 || bi.Fragmentation >= 1 {
+		t.Fatalf("unexpected fragmentation %v", bi.Fragmentation)
+	}
+	if len(bi.Buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %+v", bi.Buckets)
+	}
+	kb := bi.Buckets[0]
+	if kb.Name != "key" || kb.Keys != 3 || kb.KeyBytes != 6 || kb.ValueBytes != 15 {
+		t.Fatalf("unexpected key bucket %+v", kb)
+	}
+	if mb := bi.Buckets[1]; mb.Name != "meta" || mb.Keys != 0 {
+		t.Fatalf("unexpected meta bucket %+v", mb)
+	}
+}
+
+func TestDumpBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backend-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "db")
+
+	db, err := bolt.Open(dbPath, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revs := []struct {
+		main      uint64
+		tombstone bool
+		kv        mvccpb.KeyValue
+	}{
+		{2, false, mvccpb.KeyValue{Key: []byte("b"), Value: []byte("1"), CreateRevision: 2, ModRevision: 2, Version: 1}},
+		{3, false, mvccpb.KeyValue{Key: []byte("a"), Value: []byte("2"), CreateRevision: 3, ModRevision: 3, Version: 1, Lease: 7}},
+		{4, false, mvccpb.KeyValue{Key: []byte("c"), Value: []byte("3"), CreateRevision: 4, ModRevision: 4, Version: 1}},
+		{5, true, mvccpb.KeyValue{Key: []byte("c")}},
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		kb, err := tx.CreateBucket([]byte("key"))
+		if err != nil {
+			return err
+		}
+		for _, r := range revs {
+			k := make([]byte, 17, 18)
+			binary.BigEndian.PutUint64(k, r.main)
+			k[8] = '_'
+			if r.tombstone {
+				k = append(k, 't')
+			}
+			v, err := r.kv.Marshal()
+			if err != nil {
+				return err
+			}
+			if err = kb.Put(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = dumpBackend(dbPath, false, &jsonlDumpWriter{enc: json.NewEncoder(&buf)}); err != nil {
+		t.Fatal(err)
+	}
+	var kvs []dumpedKV
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var kv dumpedKV
+		if err = json.Unmarshal([]byte(line), &kv); err != nil {
+			t.Fatal(err)
+		}
+		kvs = append(kvs, kv)
+	}
+	wkvs := []dumpedKV{
+		{Key: []byte("a"), Value: []byte("2"), CreateRevision: 3, ModRevision: 3, Version: 1, Lease: 7},
+		{Key: []byte("b"), Value: []byte("1"), CreateRevision: 2, ModRevision: 2, Version: 1},
+	}
+	if !reflect.DeepEqual(kvs, wkvs) {
+		t.Fatalf("latest keys = %+v, want %+v", kvs, wkvs)
+	}
+
+	buf.Reset()
+	if err = dumpBackend(dbPath, true, &sqlDumpWriter{w: &buf, allRevisions: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "BEGIN TRANSACTION;\nCREATE TABLE kvs (") || !strings.HasSuffix(out, "COMMIT;\n") {
+		t.Fatalf("unexpected statements %q", out)
+	}
+	if n := strings.Count(out, "INSERT INTO kvs"); n != len(revs) {
+		t.Fatalf("expected %d inserts, got %d", len(revs), n)
+	}
+	if w := "INSERT INTO kvs VALUES (X'63', X'', 0, 5, 0, 0, 0, true);\n"; !strings.Contains(out, w) {
+		t.Fatalf("expected %q in %q", w, out)
+	}
+}
@@ -17,6 +17,7 @@ package command
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -36,6 +37,13 @@ import (
 var (
 	checkPerfLoad        string
 	checkPerfPrefix      string
+	checkPerfRate        int
+	checkPerfClients     int
+	checkPerfConns       int
+	checkPerfDuration    time.Duration
+	checkPerfWriteRatio  float64
+	checkPerfKeySize     int
+	checkPerfValueSize   int
 	checkDatascaleLoad   string
 	checkDatascalePrefix string
 	autoCompact          bool
@@ -72,6 +80,102 @@ var checkPerfCfgMap = map[string]checkPerfCfg{
 	},
 }
 
+// checkPerfWorkload is the workload run by "check perf": the "--load"
+// preset with any of the workload flags applied on top.
+type checkPerfWorkload struct {
+	Load       string  `json:"load"`
+	Rate       int     `json:"rate"`
+	Clients    int     `json:"clients"`
+	Conns      int     `json:"conns"`
+	Duration   int     `json:"duration"`
+	WriteRatio float64 `json:"write_ratio"`
+	KeySize    int     `json:"key_size"`
+	ValueSize  int     `json:"value_size"`
+}
+
+// checkPerfLatency is the request latency distribution in seconds.
+type checkPerfLatency struct {
+	Fastest     float64            `json:"fastest"`
+	Slowest     float64            `json:"slowest"`
+	Average     float64            `json:"average"`
+	Stddev      float64            `json:"stddev"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+type checkPerfResult struct {
+	Workload   checkPerfWorkload `json:"workload"`
+	Requests   int               `json:"requests"`
+	Throughput float64           `json:"throughput"`
+	Errors     map[string]int    `json:"errors,omitempty"`
+	Latency    checkPerfLatency  `json:"latency"`
+	Pass       bool              `json:"pass"`
+}
+
+const (
+	checkPerfMinThroughputRatio = 0.9
+	checkPerfMaxSlowest         = 0.5 // seconds
+	checkPerfMaxStddev          = 0.1 // seconds
+)
+
+// checkPerfLines returns the pass or fail line of every criterion of the
+// check and whether all of them passed.
+func checkPerfLines(r checkPerfResult) (lines []string, pass bool) {
+	pass = true
+	unit := "ops/s"
+	if r.Workload.WriteRatio == 1 {
+		unit = "writes/s"
+	}
+	if len(r.Errors) != 0 {
+		lines = append(lines, "FAIL: too many errors")
+		for k, v := range r.Errors {
+			lines = append(lines, fmt.Sprintf("FAIL: ERROR(%v) -> %d", k, v))
+		}
+		pass = false
+	}
+	if r.Throughput/float64(r.Workload.Rate) <= checkPerfMinThroughputRatio {
+		lines = append(lines, fmt.Sprintf("FAIL: Throughput too low: %d %s", int(r.Throughput)+1, unit))
+		pass = false
+	} else {
+		lines = append(lines, fmt.Sprintf("PASS: Throughput is %d %s", int(r.Throughput)+1, unit))
+	}
+	if r.Latency.Slowest > checkPerfMaxSlowest {
+		lines = append(lines, fmt.Sprintf("Slowest request took too long: %fs", r.Latency.Slowest))
+		pass = false
+	} else {
+		lines = append(lines, fmt.Sprintf("PASS: Slowest request took %fs", r.Latency.Slowest))
+	}
+	if r.Latency.Stddev > checkPerfMaxStddev {
+		lines = append(lines, fmt.Sprintf("Stddev too high: %fs", r.Latency.Stddev))
+		pass = false
+	} else {
+		lines = append(lines, fmt.Sprintf("PASS: Stddev is %fs", r.Latency.Stddev))
+	}
+	return lines, pass
+}
+
+// newCheckPerfResult summarizes the report stats of the workload.
+func newCheckPerfResult(w checkPerfWorkload, s report.Stats) checkPerfResult {
+	r := checkPerfResult{
+		Workload:   w,
+		Requests:   len(s.Lats),
+		Throughput: s.RPS,
+		Errors:     s.ErrorDist,
+		Latency: checkPerfLatency{
+			Fastest:     s.Fastest,
+			Slowest:     s.Slowest,
+			Average:     s.Average,
+			Stddev:      s.Stddev,
+			Percentiles: make(map[string]float64),
+		},
+	}
+	pcs, data := report.Percentiles(s.Lats)
+	for i, pc := range pcs {
+		r.Latency.Percentiles["p"+strconv.FormatFloat(pc, 'f', -1, 64)] = data[i]
+	}
+	_, r.Pass = checkPerfLines(r)
+	return r
+}
+
 type checkDatascaleCfg struct {
 	limit   int
 	kvSize  int
@@ -123,9 +227,15 @@ func NewCheckPerfCommand() *cobra.Command {
 		Run:   newCheckPerfCommand,
 	}
 
-	// TODO: support customized configuration
 	cmd.Flags().StringVar(&checkPerfLoad, "load", "s", "The performance check's workload model. Accepted workloads: s(small), m(medium), l(large), xl(xLarge)")
 	cmd.Flags().StringVar(&checkPerfPrefix, "prefix", "/etcdctl-check-perf/", "The prefix for writing the performance check's keys.")
+	cmd.Flags().IntVar(&checkPerfRate, "rate", 0, "Target number of requests per second; overrides the --load preset.")
+	cmd.Flags().IntVar(&checkPerfClients, "clients", 0, "Number of concurrent clients; overrides the --load preset.")
+	cmd.Flags().IntVar(&checkPerfConns, "conns", 0, "Number of gRPC connections shared by the clients (defaults to one per client).")
+	cmd.Flags().DurationVar(&checkPerfDuration, "duration", 0, "Duration of the check; overrides the --load preset.")
+	cmd.Flags().Float64Var(&checkPerfWriteRatio, "write-ratio", 1, "Ratio of writes to all requests; the rest are linearizable reads of recently written keys.")
+	cmd.Flags().IntVar(&checkPerfKeySize, "key-size", 256, "Size of the written keys, not counting the prefix.")
+	cmd.Flags().IntVar(&checkPerfValueSize, "value-size", 1024, "Size of the written values.")
 	cmd.Flags().BoolVar(&autoCompact, "auto-compact", false, "Compact storage with last revision after test is finished.")
 	cmd.Flags().BoolVar(&autoDefrag, "auto-defrag", false, "Defragment storage after test is finished.")
 
@@ -146,17 +256,53 @@ func newCheckPerfCommand(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadFeature, fmt.Errorf("unknown load option %v", checkPerfLoad))
 	}
 	cfg := checkPerfCfgMap[model]
+	w := checkPerfWorkload{
+		Load:       model,
+		Rate:       cfg.limit,
+		Clients:    cfg.clients,
+		Duration:   cfg.duration,
+		WriteRatio: checkPerfWriteRatio,
+		KeySize:    checkPerfKeySize,
+		ValueSize:  checkPerfValueSize,
+	}
+	if checkPerfRate != 0 {
+		w.Rate = checkPerfRate
+	}
+	if checkPerfClients != 0 {
+		w.Clients = checkPerfClients
+	}
+	if checkPerfDuration != 0 {
+		w.Duration = int(checkPerfDuration.Seconds())
+	}
+	w.Conns = w.Clients
+	if checkPerfConns != 0 {
+		w.Conns = checkPerfConns
+	}
+	switch {
+	case w.Rate < 1, w.Clients < 1, w.Conns < 1, w.Duration < 1:
+		ExitWithError(ExitBadArgs, errors.New("--rate, --clients, --conns and --duration must be positive (duration of at least 1s)"))
+	case w.Conns > w.Clients:
+		ExitWithError(ExitBadArgs, fmt.Errorf("--conns (%d) must not exceed --clients (%d)", w.Conns, w.Clients))
+	case w.WriteRatio < 0 || w.WriteRatio > 1:
+		ExitWithError(ExitBadArgs, fmt.Errorf("--write-ratio must be between 0 and 1, got %v", w.WriteRatio))
+	case w.KeySize < 1 || w.ValueSize < 0:
+		ExitWithError(ExitBadArgs, errors.New("--key-size must be positive and --value-size must not be negative"))
+	}
 
-	requests := make(chan v3.Op, cfg.clients)
-	limit := rate.NewLimiter(rate.Limit(cfg.limit), 1)
+	requests := make(chan v3.Op, w.Clients)
+	limit := rate.NewLimiter(rate.Limit(w.Rate), 1)
 
 	cc := clientConfigFromCmd(cmd)
-	clients := make([]*v3.Client, cfg.clients)
-	for i := 0; i < cfg.clients; i++ {
-		clients[i] = cc.mustClient()
+	conns := make([]*v3.Client, w.Conns)
+	for i := range conns {
+		conns[i] = cc.mustClient()
+	}
+	clients := make([]*v3.Client, w.Clients)
+	for i := range clients {
+		clients[i] = conns[i%len(conns)]
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.duration)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(w.Duration)*time.Second)
 	resp, err := clients[0].Get(ctx, checkPerfPrefix, v3.WithPrefix(), v3.WithLimit(1))
 	cancel()
 	if err != nil {
@@ -166,11 +312,14 @@ func newCheckPerfCommand(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitInvalidInput, fmt.Errorf("prefix %q has keys. Delete with etcdctl del --prefix %s first", checkPerfPrefix, checkPerfPrefix))
 	}
 
-	ksize, vsize := 256, 1024
-	k, v := make([]byte, ksize), string(make([]byte, vsize))
+	k, v := make([]byte, w.KeySize), string(make([]byte, w.ValueSize))
 
-	bar := pb.New(cfg.duration)
+	bar := pb.New(w.Duration)
 	bar.Format("Bom !")
+	if _, ok := display.(*jsonPrinter); ok {
+		// keep stdout for the JSON result
+		bar.Output = os.Stderr
+	}
 	bar.Start()
 
 	r := report.NewReport("%4.4f")
@@ -189,17 +338,29 @@ func newCheckPerfCommand(cmd *cobra.Command, args []string) {
 	}
 
 	go func() {
-		cctx, ccancel := context.WithTimeout(context.Background(), time.Duration(cfg.duration)*time.Second)
+		cctx, ccancel := context.WithTimeout(context.Background(), time.Duration(w.Duration)*time.Second)
 		defer ccancel()
-		for limit.Wait(cctx) == nil {
-			binary.PutVarint(k, rand.Int63n(math.MaxInt64))
-			requests <- v3.OpPut(checkPerfPrefix+string(k), v)
+		// recently written keys, for the reads to hit existing keys
+		written := make([]string, 0, 1024)
+		for i := 0; limit.Wait(cctx) == nil; i++ {
+			if len(written) > 0 && rand.Float64() >= w.WriteRatio {
+				requests <- v3.OpGet(written[rand.Intn(len(written))])
+				continue
+			}
+			rand.Read(k)
+			key := checkPerfPrefix + string(k)
+			requests <- v3.OpPut(key, v)
+			if len(written) < cap(written) {
+				written = append(written, key)
+			} else {
+				written[i%len(written)] = key
+			}
 		}
 		close(requests)
 	}()
 
 	go func() {
-		for i := 0; i < cfg.duration; i++ {
+		for i := 0; i < w.Duration; i++ {
 			time.Sleep(time.Second)
 			bar.Add(1)
 		}
@@ -229,38 +390,9 @@ func newCheckPerfCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	ok = true
-	if len(s.ErrorDist) != 0 {
-		fmt.Println("FAIL: too many errors")
-		for k, v := range s.ErrorDist {
-			fmt.Printf("FAIL: ERROR(%v) -> %d\n", k, v)
-		}
-		ok = false
-	}
-
-	if s.RPS/float64(cfg.limit) <= 0.9 {
-		fmt.Printf("FAIL: Throughput too low: %d writes/s\n", int(s.RPS)+1)
-		ok = false
-	} else {
-		fmt.Printf("PASS: Throughput is %d writes/s\n", int(s.RPS)+1)
-	}
-	if s.Slowest > 0.5 { // slowest request > 500ms
-		fmt.Printf("Slowest request took too long: %fs\n", s.Slowest)
-		ok = false
-	} else {
-		fmt.Printf("PASS: Slowest request took %fs\n", s.Slowest)
-	}
-	if s.Stddev > 0.1 { // stddev > 100ms
-		fmt.Printf("Stddev too high: %fs\n", s.Stddev)
-		ok = false
-	} else {
-		fmt.Printf("PASS: Stddev is %fs\n", s.Stddev)
-	}
-
-	if ok {
-		fmt.Println("PASS")
-	} else {
-		fmt.Println("FAIL")
+	result := newCheckPerfResult(w, s)
+	display.CheckPerf(result)
+	if !result.Pass {
 		os.Exit(ExitError)
 	}
 }
@@ -0,0 +1,65 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckPerfLines(t *testing.T) {
+	tt := []struct {
+		r checkPerfResult
+
+		lines []string
+		pass  bool
+	}{
+		{
+			r: checkPerfResult{
+				Workload:   checkPerfWorkload{Rate: 150, WriteRatio: 1},
+				Throughput: 149.5,
+				Latency:    checkPerfLatency{Slowest: 0.25, Stddev: 0.01},
+			},
+			lines: []string{
+				"PASS: Throughput is 150 writes/s",
+				"PASS: Slowest request took 0.250000s",
+				"PASS: Stddev is 0.010000s",
+			},
+			pass: true,
+		},
+		{
+			r: checkPerfResult{
+				Workload:   checkPerfWorkload{Rate: 1000, WriteRatio: 0.5},
+				Throughput: 800,
+				Errors:     map[string]int{"timeout": 3},
+				Latency:    checkPerfLatency{Slowest: 0.75, Stddev: 0.2},
+			},
+			lines: []string{
+				"FAIL: too many errors",
+				"FAIL: ERROR(timeout) -> 3",
+				"FAIL: Throughput too low: 801 ops/s",
+				"Slowest request took too long: 0.750000s",
+				"Stddev too high: 0.200000s",
+			},
+			pass: false,
+		},
+	}
+	for i, tc := range tt {
+		lines, pass := checkPerfLines(tc.r)
+		if !reflect.DeepEqual(lines, tc.lines) || pass != tc.pass {
+			t.Errorf("#%d: got %q, %v, want %q, %v", i, lines, pass, tc.lines, tc.pass)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	v3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/spf13/cobra"
+)
+
+// NewClusterCommand returns the cobra command for "cluster".
+func NewClusterCommand() *cobra.Command {
+	cc := &cobra.Command{
+		Use:   "cluster <subcommand>",
+		Short: "Cluster related commands",
+	}
+
+	cc.AddCommand(newClusterHealthCommand())
+
+	return cc
+}
+
+func newClusterHealthCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Checks the health of every member of the cluster",
+		Long: `Checks the health of every member in the cluster member list, combining the member list,
+endpoint status, endpoint health, alarms and leader state into a single report.
+
+Exits with a non-zero status if the cluster is not healthy.
+`,
+		Run: clusterHealthCommandFunc,
+	}
+}
+
+// clusterMemberHealth is the health of a single member.
+type clusterMemberHealth struct {
+	ID        uint64 `json:"id"`
+	Name      string `json:"name"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	IsLearner bool   `json:"is_learner"`
+	IsLeader  bool   `json:"is_leader"`
+	Healthy   bool   `json:"healthy"`
+	Took      string `json:"took,omitempty"`
+
+	Version          string   `json:"version,omitempty"`
+	DBSize           int64    `json:"db_size,omitempty"`
+	Leader           uint64   `json:"leader,omitempty"`
+	RaftTerm         uint64   `json:"raft_term,omitempty"`
+	RaftIndex        uint64   `json:"raft_index,omitempty"`
+	RaftAppliedIndex uint64   `json:"raft_applied_index,omitempty"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+type clusterHealth struct {
+	Healthy   bool                  `json:"healthy"`
+	ClusterID uint64                `json:"cluster_id"`
+	Leader    uint64                `json:"leader"`
+	Members   []clusterMemberHealth `json:"members"`
+	Alarms    []string              `json:"alarms,omitempty"`
+	Problems  []string              `json:"problems,omitempty"`
+}
+
+func clusterHealthCommandFunc(cmd *cobra.Command, args []string) {
+	cc := clientConfigFromCmd(cmd)
+	c := cc.mustClient()
+
+	ctx, cancel := commandCtx(cmd)
+	mresp, err := c.MemberList(ctx)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	h := clusterHealth{ClusterID: mresp.Header.ClusterId}
+
+	ctx, cancel = commandCtx(cmd)
+	aresp, err := c.AlarmList(ctx)
+	cancel()
+	if err != nil {
+		h.Problems = append(h.Problems, fmt.Sprintf("failed to list alarms (%v)", err))
+	} else {
+		for _, a := range aresp.Alarms {
+			h.Alarms = append(h.Alarms, fmt.Sprintf("memberID:%d alarm:%v", a.MemberID, a.Alarm))
+		}
+	}
+
+	h.Members = make([]clusterMemberHealth, len(mresp.Members))
+	var wg sync.WaitGroup
+	for i, m := range mresp.Members {
+		wg.Add(1)
+		go func(i int, m *pb.Member) {
+			defer wg.Done()
+			h.Members[i] = checkMemberHealth(cmd, cc, m)
+		}(i, m)
+	}
+	wg.Wait()
+
+	evaluateClusterHealth(&h)
+	display.ClusterHealth(h)
+	if !h.Healthy {
+		ExitWithError(ExitError, errors.New("unhealthy cluster"))
+	}
+}
+
+// checkMemberHealth checks the health and fetches the status of the member
+// through its first client URL.
+func checkMemberHealth(cmd *cobra.Command, cc *clientConfig, m *pb.Member) clusterMemberHealth {
+	mh := clusterMemberHealth{ID: m.ID, Name: m.Name, IsLearner: m.IsLearner}
+	if len(m.ClientURLs) == 0 {
+		mh.Errors = append(mh.Errors, "member is not started")
+		return mh
+	}
+	mh.Endpoint = m.ClientURLs[0]
+
+	cli, err := cc.endpointClient(mh.Endpoint)
+	if err != nil {
+		mh.Errors = append(mh.Errors, err.Error())
+		return mh
+	}
+	defer cli.Close()
+
+	st := time.Now()
+	ctx, cancel := commandCtx(cmd)
+	_, err = cli.Get(ctx, "health", healthGetOptions(m)...)
+	cancel()
+	mh.Took = time.Since(st).String()
+	// permission denied is OK since proposal goes through consensus to get it
+	if err != nil && err != rpctypes.ErrPermissionDenied {
+		mh.Errors = append(mh.Errors, err.Error())
+	}
+
+	ctx, cancel = commandCtx(cmd)
+	sresp, err := cli.Status(ctx, mh.Endpoint)
+	cancel()
+	if err != nil {
+		mh.Errors = append(mh.Errors, err.Error())
+		return mh
+	}
+	mh.Version = sresp.Version
+	mh.DBSize = sresp.DbSize
+	mh.Leader = sresp.Leader
+	mh.IsLeader = sresp.Leader == sresp.Header.MemberId
+	mh.RaftTerm = sresp.RaftTerm
+	mh.RaftIndex = sresp.RaftIndex
+	mh.RaftAppliedIndex = sresp.RaftAppliedIndex
+	mh.Errors = append(mh.Errors, sresp.Errors...)
+	mh.Healthy = len(mh.Errors) == 0
+	return mh
+}
+
+// healthGetOptions returns the options of the read checking that the member
+// serves requests. A learner only serves serializable reads.
+func healthGetOptions(m *pb.Member) []v3.OpOption {
+	if m.IsLearner {
+		return []v3.OpOption{v3.WithSerializable()}
+	}
+	return nil
+}
+
+// evaluateClusterHealth records the problems of the cluster and whether it
+// is healthy: every member is healthy and agrees on a single leader, and
+// no alarm is raised.
+func evaluateClusterHealth(h *clusterHealth) {
+	leaders := make(map[uint64]bool)
+	for _, m := range h.Members {
+		for _, err := range m.Errors {
+			h.Problems = append(h.Problems, fmt.Sprintf("member %x (%s): %s", m.ID, m.Name, err))
+		}
+		if m.Leader != 0 {
+			leaders[m.Leader] = true
+		}
+	}
+	switch len(leaders) {
+	case 0:
+		h.Problems = append(h.Problems, "no leader")
+	case 1:
+		for id := range leaders {
+			h.Leader = id
+		}
+	default:
+		h.Problems = append(h.Problems, fmt.Sprintf("members disagree on the leader (%d different leaders)", len(leaders)))
+	}
+	for _, a := range h.Alarms {
+		h.Problems = append(h.Problems, "alarm raised: "+a)
+	}
+	h.Healthy = len(h.Problems) == 0
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestEvaluateClusterHealth(t *testing.T) {
+	tt := []struct {
+		h clusterHealth
+
+		leader   uint64
+		problems []string
+	}{
+		{
+			h: clusterHealth{Members: []clusterMemberHealth{
+				{ID: 1, Name: "a", Leader: 1, IsLeader: true, Healthy: true},
+				{ID: 2, Name: "b", Leader: 1, Healthy: true},
+			}},
+			leader: 1,
+		},
+		{
+			h: clusterHealth{Members: []clusterMemberHealth{
+				{ID: 1, Name: "a", Leader: 1, IsLeader: true, Healthy: true},
+				{ID: 2, Name: "b", Errors: []string{"context deadline exceeded"}},
+				{ID: 3, Errors: []string{"member is not started"}},
+			}},
+			leader: 1,
+			problems: []string{
+				"member 2 (b): context deadline exceeded",
+				"member 3 (): member is not started",
+			},
+		},
+		{
+			h: clusterHealth{Members: []clusterMemberHealth{
+				{ID: 1, Name: "a", Leader: 1, IsLeader: true, Healthy: true},
+				{ID: 2, Name: "b", Leader: 2, IsLeader: true, Healthy: true},
+			}},
+			problems: []string{"members disagree on the leader (2 different leaders)"},
+		},
+		{
+			h: clusterHealth{
+				Members: []clusterMemberHealth{{ID: 1, Name: "a", Errors: []string{"etcdserver: no leader"}}},
+				Alarms:  []string{"memberID:1 alarm:NOSPACE"},
+			},
+			problems: []string{
+				"member 1 (a): etcdserver: no leader",
+				"no leader",
+				"alarm raised: memberID:1 alarm:NOSPACE",
+			},
+		},
+	}
+	for i, tc := range tt {
+		evaluateClusterHealth(&tc.h)
+		if tc.h.Leader != tc.leader {
+			t.Errorf("#%d: leader = %x, want %x", i, tc.h.Leader, tc.leader)
+		}
+		if !reflect.DeepEqual(tc.h.Problems, tc.problems) {
+			t.Errorf("#%d: problems = %q, want %q", i, tc.h.Problems, tc.problems)
+		}
+		if tc.h.Healthy != (len(tc.problems) == 0) {
+			t.Errorf("#%d: healthy = %v, want %v", i, tc.h.Healthy, len(tc.problems) == 0)
+		}
+	}
+}
+
+func TestHealthGetOptions(t *testing.T) {
+	tt := []struct {
+		m            *pb.Member
+		serializable bool
+	}{
+		{&pb.Member{ID: 1}, false},
+		{&pb.Member{ID: 2, IsLearner: true}, true},
+	}
+	for i, ts := range tt {
+		op := v3.OpGet("health", healthGetOptions(ts.m)...)
+		if op.IsSerializable() != ts.serializable {
+			t.Errorf("#%d: expected serializable %v, got %v", i, ts.serializable, op.IsSerializable())
+		}
+	}
+}
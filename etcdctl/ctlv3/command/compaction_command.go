@@ -15,48 +15,235 @@
 package command
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.uber.org/zap"
 )
 
-var compactPhysical bool
+var (
+	compactPhysical      bool
+	compactDryRun        bool
+	compactRetention     time.Duration
+	compactKeepRevisions int64
+	compactHistoryKey    string
+	compactWait          bool
+	compactDataDirs      []string
+)
+
+// revisionSample records the revision of the cluster at a point in time.
+type revisionSample struct {
+	Time     time.Time `json:"time"`
+	Revision int64     `json:"revision"`
+}
 
 // NewCompactionCommand returns the cobra command for "compaction".
 func NewCompactionCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "compaction [options] <revision>",
+		Use:   "compaction [options] [revision]",
 		Short: "Compacts the event history in etcd",
 		Run:   compactionCommandFunc,
 	}
 	cmd.Flags().BoolVar(&compactPhysical, "physical", false, "'true' to wait for compaction to physically remove all old revisions")
+	cmd.Flags().BoolVar(&compactDryRun, "dry-run", false, "validate the revision without compacting")
+	cmd.Flags().DurationVar(&compactRetention, "retention", 0, "compact revisions older than the given duration, instead of a given revision")
+	cmd.Flags().Int64Var(&compactKeepRevisions, "keep-revisions", 0, "compact all but the given number of most recent revisions, instead of a given revision")
+	cmd.Flags().BoolVar(&compactWait, "wait", false, "wait for compaction to physically remove all old revisions with no time limit, reporting its progress")
+	cmd.Flags().StringArrayVar(&compactDataDirs, "data-dir", nil, "compact a data directory not in use by etcd instead of the cluster (repeatable; the data directories are compacted in parallel)")
+	cmd.Flags().StringVar(&compactHistoryKey, "history-key", "/etcdctl/compaction/history", "with --retention, key storing the revision history sampled by previous runs")
 	return cmd
 }
 
 // compactionCommandFunc executes the "compaction" command.
 func compactionCommandFunc(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
+	byRetention := compactRetention != 0 || compactKeepRevisions != 0
+	switch {
+	case compactRetention != 0 && compactKeepRevisions != 0:
+		ExitWithError(ExitBadArgs, errors.New("--retention and --keep-revisions are mutually exclusive"))
+	case compactRetention < 0 || compactKeepRevisions < 0:
+		ExitWithError(ExitBadArgs, errors.New("--retention and --keep-revisions must not be negative"))
+	case byRetention && len(args) != 0:
+		ExitWithError(ExitBadArgs, errors.New("compaction command takes no revision argument with --retention or --keep-revisions"))
+	case !byRetention && len(args) != 1:
 		ExitWithError(ExitBadArgs, fmt.Errorf("compaction command needs 1 argument"))
 	}
 
-	rev, err := strconv.ParseInt(args[0], 10, 64)
-	if err != nil {
-		ExitWithError(ExitError, err)
+	if len(compactDataDirs) > 0 {
+		if byRetention || compactDryRun {
+			ExitWithError(ExitBadArgs, errors.New("--data-dir cannot be combined with --retention, --keep-revisions or --dry-run"))
+		}
+		rev, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		compact := func(dataDir string) error { return compactData(dataDir, rev) }
+		if forEachDataDir(compactDataDirs, "compact", "compacting", compact) != 0 {
+			os.Exit(ExitError)
+		}
+		return
+	}
+
+	c := mustClientFromCmd(cmd)
+
+	var rev int64
+	if byRetention {
+		rev = compactionRetentionRev(cmd, c)
+		if rev <= 0 {
+			return
+		}
+	} else {
+		var err error
+		rev, err = strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
 	}
 
 	var opts []clientv3.CompactOption
-	if compactPhysical {
+	if compactPhysical || compactWait {
 		opts = append(opts, clientv3.WithCompactPhysical())
 	}
 
-	c := mustClientFromCmd(cmd)
+	if compactDryRun {
+		compactionDryRunFunc(cmd, c, rev)
+		return
+	}
+	var p *progress
 	ctx, cancel := commandCtx(cmd)
+	if compactWait {
+		// every member compacts its backend, the progress of the first
+		// endpoint stands for the others
+		poll := maintenanceProgressPoller(cmd, c, c.Endpoints()[0], func(resp *clientv3.MaintenanceProgressResponse) *pb.OperationProgress {
+			return resp.Compaction
+		})
+		p = startProgress(fmt.Sprintf("compacting revision %d", rev), poll)
+		cancel()
+		ctx, cancel = waitCtx()
+	}
 	_, cerr := c.Compact(ctx, rev, opts...)
 	cancel()
+	if p != nil {
+		p.stop()
+	}
 	if cerr != nil {
 		ExitWithError(ExitError, cerr)
 	}
 	fmt.Println("compacted revision", rev)
 }
+
+// compactionDryRunFunc verifies that the revision is neither compacted nor
+// in the future by reading at that revision.
+func compactionDryRunFunc(cmd *cobra.Command, c *clientv3.Client, rev int64) {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithRev(rev), clientv3.WithCountOnly())
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("dry-run: would compact revisions older than %d (current revision %d)\n", rev, resp.Header.Revision)
+}
+
+// compactionRetentionRev resolves the revision to compact for --keep-revisions
+// and --retention. It returns 0 if there is nothing to compact yet.
+func compactionRetentionRev(cmd *cobra.Command, c *clientv3.Client) int64 {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Get(ctx, compactHistoryKey)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	cur := resp.Header.Revision
+
+	if compactKeepRevisions != 0 {
+		if cur <= compactKeepRevisions {
+			fmt.Printf("current revision %d is within the %d revisions to keep, nothing to compact\n", cur, compactKeepRevisions)
+			return 0
+		}
+		return cur - compactKeepRevisions
+	}
+
+	// etcd does not record when a revision was written, so every run samples
+	// the current revision into the history key and compacts up to the newest
+	// sample that is older than the retention.
+	var history []revisionSample
+	if len(resp.Kvs) != 0 {
+		if err := json.Unmarshal(resp.Kvs[0].Value, &history); err != nil {
+			ExitWithError(ExitError, fmt.Errorf("invalid revision history in %q (%v)", compactHistoryKey, err))
+		}
+	}
+	now := time.Now()
+	target, history := retentionRevision(history, now, compactRetention)
+	history = append(history, revisionSample{Time: now, Revision: cur})
+
+	if !compactDryRun {
+		b, err := json.Marshal(history)
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		// fail instead of losing samples if another run updated the history
+		cmp := clientv3.Compare(clientv3.CreateRevision(compactHistoryKey), "=", 0)
+		if len(resp.Kvs) != 0 {
+			cmp = clientv3.Compare(clientv3.ModRevision(compactHistoryKey), "=", resp.Kvs[0].ModRevision)
+		}
+		ctx, cancel := commandCtx(cmd)
+		tresp, err := c.Txn(ctx).If(cmp).Then(clientv3.OpPut(compactHistoryKey, string(b))).Commit()
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		if !tresp.Succeeded {
+			ExitWithError(ExitError, fmt.Errorf("revision history in %q was updated concurrently, retry", compactHistoryKey))
+		}
+	}
+
+	if target == 0 {
+		fmt.Printf("no revision sampled at least %v ago, recorded revision %d for later runs\n", compactRetention, cur)
+	}
+	return target
+}
+
+// retentionRevision returns the revision of the newest sample taken at least
+// retention before now, or 0 if there is none, along with the samples that
+// are still needed by later runs.
+func retentionRevision(history []revisionSample, now time.Time, retention time.Duration) (int64, []revisionSample) {
+	cutoff := now.Add(-retention)
+	idx := -1
+	for i, s := range history {
+		if s.Time.After(cutoff) {
+			break
+		}
+		idx = i
+	}
+	if idx < 0 {
+		return 0, history
+	}
+	return history[idx].Revision, history[idx:]
+}
+
+// compactData compacts the backend of a data directory not in use by etcd
+// at the given revision, and waits for the old revisions to be removed.
+func compactData(dataDir string, rev int64) error {
+	be := openDataBackend(dataDir)
+	defer be.Close()
+
+	s := mvcc.NewStore(zap.NewNop(), be, &lease.FakeLessor{}, cindex.NewConsistentIndex(be.BatchTx()), mvcc.StoreConfig{})
+	defer s.Close()
+	done, err := s.Compact(traceutil.TODO(), rev)
+	if err != nil {
+		return err
+	}
+	<-done
+	s.Commit()
+	return nil
+}
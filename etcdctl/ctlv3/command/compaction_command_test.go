@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionRevision(t *testing.T) {
+	now := time.Unix(100000, 0)
+	sample := func(ago time.Duration, rev int64) revisionSample {
+		return revisionSample{Time: now.Add(-ago), Revision: rev}
+	}
+	tt := []struct {
+		history   []revisionSample
+		retention time.Duration
+
+		rev  int64
+		kept []revisionSample
+	}{
+		{nil, time.Hour, 0, nil},
+		{
+			[]revisionSample{sample(30*time.Minute, 10)},
+			time.Hour,
+			0, []revisionSample{sample(30*time.Minute, 10)},
+		},
+		{
+			[]revisionSample{sample(3*time.Hour, 5), sample(2*time.Hour, 10), sample(30*time.Minute, 20)},
+			time.Hour,
+			10, []revisionSample{sample(2*time.Hour, 10), sample(30*time.Minute, 20)},
+		},
+		{
+			[]revisionSample{sample(2*time.Hour, 10), sample(time.Hour, 15)},
+			time.Hour,
+			15, []revisionSample{sample(time.Hour, 15)},
+		},
+	}
+	for i, ts := range tt {
+		rev, kept := retentionRevision(ts.history, now, ts.retention)
+		if rev != ts.rev {
+			t.Errorf("#%d: revision expected %d, got %d", i, ts.rev, rev)
+		}
+		if !reflect.DeepEqual(kept, ts.kept) {
+			t.Errorf("#%d: kept samples expected %v, got %v", i, ts.kept, kept)
+		}
+	}
+}
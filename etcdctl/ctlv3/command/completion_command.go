@@ -0,0 +1,181 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// completionTimeout bounds the requests made while completing, so that
+	// an unreachable cluster does not hang the shell.
+	completionTimeout = time.Second
+	// completionKeysLimit bounds the keys fetched to complete a key.
+	completionKeysLimit = 100
+)
+
+// NewCompletionCommand returns the cobra command for "completion".
+func NewCompletionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion <bash|zsh|fish|powershell>",
+		Short: "Generates the shell completion script",
+		Long: `Generates the shell completion script, e.g.
+
+	source <(etcdctl completion bash)
+
+Keys are only completed with --complete-keys (or ETCDCTL_COMPLETE_KEYS=true),
+since completing them queries the cluster.
+`,
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Run:       completionCommandFunc,
+	}
+}
+
+// completionCommandFunc executes the "completion" command.
+func completionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("completion command requires the shell as its argument"))
+	}
+
+	var err error
+	root := cmd.Root()
+	switch args[0] {
+	case "bash":
+		err = root.GenBashCompletion(os.Stdout)
+	case "zsh":
+		err = root.GenZshCompletion(os.Stdout)
+	case "fish":
+		err = root.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		err = root.GenPowerShellCompletion(os.Stdout)
+	default:
+		ExitWithError(ExitBadArgs, fmt.Errorf("unsupported shell %q", args[0]))
+	}
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+}
+
+// completionClientConfig returns the client configuration for completing
+// arguments. It never prompts for a password, which would garble the shell.
+func completionClientConfig(cmd *cobra.Command) *clientConfig {
+	passwordPromptDisabled = true
+	cc := clientConfigFromCmd(cmd)
+	if cc.dialTimeout == 0 || cc.dialTimeout > completionTimeout {
+		cc.dialTimeout = completionTimeout
+	}
+	return cc
+}
+
+// completeKeys completes the key argument of "get", "del" and "watch" with
+// the keys of the cluster, if enabled by "--complete-keys".
+func completeKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		// only the first argument is a key
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// the flags are only complete once read from the environment and the
+	// context
+	cc := completionClientConfig(cmd)
+	if enabled, err := cmd.Flags().GetBool("complete-keys"); err != nil || !enabled {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	c := cc.mustClient()
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	resp, err := c.Get(ctx, toComplete,
+		clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithSerializable(),
+		clientv3.WithLimit(completionKeysLimit))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	keys := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		keys[i] = string(kv.Key)
+	}
+	comps, dirs := keyCompletions(keys, toComplete)
+	if dirs {
+		return comps, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+	return comps, cobra.ShellCompDirectiveNoFileComp
+}
+
+// keyCompletions returns the completions of the prefix among the keys.
+// Keys are completed one "/" separated segment at a time, so that large
+// key spaces remain browsable; it reports whether any completion ends on
+// such a directory.
+func keyCompletions(keys []string, prefix string) (comps []string, dirs bool) {
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		comp := k
+		rest := k[len(prefix):]
+		if i := strings.Index(rest, "/"); i >= 0 && i < len(rest)-1 {
+			comp = prefix + rest[:i+1]
+			dirs = true
+		}
+		if !seen[comp] {
+			seen[comp] = true
+			comps = append(comps, comp)
+		}
+	}
+	return comps, dirs
+}
+
+// completeMemberIDs completes a member ID argument with the members of the
+// cluster, described by their names.
+func completeMemberIDs(voters bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		c := completionClientConfig(cmd).mustClient()
+		defer c.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		resp, err := c.MemberList(ctx)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var comps []string
+		for _, m := range resp.Members {
+			if voters && m.IsLearner {
+				continue
+			}
+			id := fmt.Sprintf("%x", m.ID)
+			if !strings.HasPrefix(id, toComplete) {
+				continue
+			}
+			name := m.Name
+			if name == "" {
+				name = "unstarted"
+			}
+			comps = append(comps, id+"\t"+name)
+		}
+		return comps, cobra.ShellCompDirectiveNoFileComp
+	}
+}
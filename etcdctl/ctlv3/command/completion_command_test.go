@@ -0,0 +1,45 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyCompletions(t *testing.T) {
+	keys := []string{"/app/a/x", "/app/a/y", "/app/b", "/app/c/", "/other", "foo"}
+	tt := []struct {
+		prefix string
+
+		comps []string
+		dirs  bool
+	}{
+		{prefix: "", comps: []string{"/", "foo"}, dirs: true},
+		{prefix: "/app/", comps: []string{"/app/a/", "/app/b", "/app/c/"}, dirs: true},
+		{prefix: "/app/a/", comps: []string{"/app/a/x", "/app/a/y"}},
+		{prefix: "f", comps: []string{"foo"}},
+		{prefix: "bar"},
+	}
+	for i, tc := range tt {
+		comps, dirs := keyCompletions(keys, tc.prefix)
+		if !reflect.DeepEqual(comps, tc.comps) {
+			t.Errorf("#%d: expected completions %q, got %q", i, tc.comps, comps)
+		}
+		if dirs != tc.dirs {
+			t.Errorf("#%d: expected dirs %v, got %v", i, tc.dirs, dirs)
+		}
+	}
+}
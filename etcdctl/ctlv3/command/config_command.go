@@ -0,0 +1,268 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// NewConfigCommand returns the cobra command for "config".
+func NewConfigCommand() *cobra.Command {
+	cc := &cobra.Command{
+		Use:   "config <subcommand>",
+		Short: "Manages the named contexts of the etcdctl configuration file",
+	}
+
+	cc.AddCommand(newConfigUseContextCommand())
+	cc.AddCommand(newConfigCurrentContextCommand())
+	cc.AddCommand(newConfigGetContextsCommand())
+
+	return cc
+}
+
+func newConfigUseContextCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use-context <name>",
+		Short: "Sets the context used when --context is not given",
+		Run:   configUseContextCommandFunc,
+	}
+}
+
+func newConfigCurrentContextCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "current-context",
+		Short: "Prints the context used when --context is not given",
+		Run:   configCurrentContextCommandFunc,
+	}
+}
+
+func newConfigGetContextsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get-contexts",
+		Short: "Lists the contexts of the configuration file",
+		Run:   configGetContextsCommandFunc,
+	}
+}
+
+// ctlConfig is the etcdctl configuration file.
+type ctlConfig struct {
+	CurrentContext string       `json:"currentContext,omitempty"`
+	Contexts       []ctlContext `json:"contexts,omitempty"`
+}
+
+// ctlContext is a named set of default global flags, usually one per
+// cluster.
+type ctlContext struct {
+	Name                  string   `json:"name"`
+	Endpoints             []string `json:"endpoints,omitempty"`
+	CACert                string   `json:"cacert,omitempty"`
+	Cert                  string   `json:"cert,omitempty"`
+	Key                   string   `json:"key,omitempty"`
+	InsecureTransport     *bool    `json:"insecureTransport,omitempty"`
+	InsecureSkipTLSVerify bool     `json:"insecureSkipTLSVerify,omitempty"`
+	User                  string   `json:"user,omitempty"`
+	WriteOut              string   `json:"writeOut,omitempty"`
+}
+
+// flagValues returns the global flags the context sets.
+func (c ctlContext) flagValues() map[string]string {
+	vs := make(map[string]string)
+	set := func(name, v string) {
+		if v != "" {
+			vs[name] = v
+		}
+	}
+	set("endpoints", strings.Join(c.Endpoints, ","))
+	set("cacert", c.CACert)
+	set("cert", c.Cert)
+	set("key", c.Key)
+	if c.InsecureTransport != nil {
+		vs["insecure-transport"] = strconv.FormatBool(*c.InsecureTransport)
+	}
+	if c.InsecureSkipTLSVerify {
+		vs["insecure-skip-tls-verify"] = "true"
+	}
+	set("user", c.User)
+	set("write-out", c.WriteOut)
+	return vs
+}
+
+func (c *ctlConfig) context(name string) (ctlContext, bool) {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx, true
+		}
+	}
+	return ctlContext{}, false
+}
+
+// ctlConfigPath returns the path of the configuration file, which is
+// "~/.etcdctl/config.yaml" unless "--config-file" is given.
+func ctlConfigPath(fs *pflag.FlagSet) (string, error) {
+	if f := fs.Lookup("config-file"); f != nil && f.Value.String() != "" {
+		return f.Value.String(), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".etcdctl", "config.yaml"), nil
+}
+
+// loadCtlConfig reads the configuration file. A missing file is an empty
+// configuration.
+func loadCtlConfig(path string) (*ctlConfig, error) {
+	cfg := &ctlConfig{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %q (%v)", path, err)
+	}
+	names := make(map[string]bool)
+	for _, c := range cfg.Contexts {
+		if c.Name == "" {
+			return nil, fmt.Errorf("context without a name in %q", path)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate context %q in %q", c.Name, path)
+		}
+		names[c.Name] = true
+	}
+	return cfg, nil
+}
+
+func saveCtlConfig(path string, cfg *ctlConfig) error {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// applyContext sets the global flags from the context selected by
+// "--context", or from the current context of the configuration file.
+// Flags given on the command line or through environment variables take
+// precedence over the context.
+func applyContext(fs *pflag.FlagSet) error {
+	name := ""
+	if f := fs.Lookup("context"); f != nil {
+		name = f.Value.String()
+	}
+	path, err := ctlConfigPath(fs)
+	if err != nil {
+		if name == "" {
+			return nil
+		}
+		return err
+	}
+	cfg, err := loadCtlConfig(path)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" {
+		return nil
+	}
+	ctx, ok := cfg.context(name)
+	if !ok {
+		return fmt.Errorf("context %q not found in %q", name, path)
+	}
+	for flag, v := range ctx.flagValues() {
+		if f := fs.Lookup(flag); f != nil && !f.Changed {
+			if err = fs.Set(flag, v); err != nil {
+				return fmt.Errorf("invalid %s in context %q (%v)", flag, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func mustLoadCtlConfigFromCmd(cmd *cobra.Command) (string, *ctlConfig) {
+	path, err := ctlConfigPath(cmd.Flags())
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	cfg, err := loadCtlConfig(path)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	return path, cfg
+}
+
+// configUseContextCommandFunc executes the "config use-context" command.
+func configUseContextCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("config use-context command requires context name as its argument"))
+	}
+
+	path, cfg := mustLoadCtlConfigFromCmd(cmd)
+	if _, ok := cfg.context(args[0]); !ok {
+		ExitWithError(ExitBadArgs, fmt.Errorf("context %q not found in %q", args[0], path))
+	}
+	cfg.CurrentContext = args[0]
+	if err := saveCtlConfig(path, cfg); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Switched to context %q\n", args[0])
+}
+
+// configCurrentContextCommandFunc executes the "config current-context" command.
+func configCurrentContextCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("config current-context command does not accept any arguments"))
+	}
+
+	_, cfg := mustLoadCtlConfigFromCmd(cmd)
+	if cfg.CurrentContext == "" {
+		ExitWithError(ExitError, errors.New("current context is not set"))
+	}
+	fmt.Println(cfg.CurrentContext)
+}
+
+// configGetContextsCommandFunc executes the "config get-contexts" command.
+func configGetContextsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("config get-contexts command does not accept any arguments"))
+	}
+
+	_, cfg := mustLoadCtlConfigFromCmd(cmd)
+	for _, c := range cfg.Contexts {
+		current := " "
+		if c.Name == cfg.CurrentContext {
+			current = "*"
+		}
+		fmt.Printf("%s %s\t%s\n", current, c.Name, strings.Join(c.Endpoints, ","))
+	}
+}
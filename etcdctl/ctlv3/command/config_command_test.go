@@ -0,0 +1,172 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func newContextFlagSet(path string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringSlice("endpoints", []string{"127.0.0.1:2379"}, "")
+	fs.Bool("insecure-transport", true, "")
+	fs.String("user", "", "")
+	fs.StringP("write-out", "w", "simple", "")
+	fs.String("context", "", "")
+	fs.String("config-file", path, "")
+	return fs
+}
+
+func TestApplyContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcdctl-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	cfg := `currentContext: prod
+contexts:
+- name: prod
+  endpoints: [https://a:2379, https://b:2379]
+  insecureTransport: false
+  user: root
+- name: dev
+  endpoints: [127.0.0.1:22379]
+  writeOut: json
+`
+	if err = ioutil.WriteFile(path, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		args []string
+
+		endpoints []string
+		insecure  bool
+		user      string
+		writeOut  string
+	}{
+		{
+			endpoints: []string{"https://a:2379", "https://b:2379"},
+			user:      "root",
+			writeOut:  "simple",
+		},
+		{
+			args:      []string{"--context", "dev"},
+			endpoints: []string{"127.0.0.1:22379"},
+			insecure:  true,
+			writeOut:  "json",
+		},
+		{
+			// flags take precedence over the context
+			args:      []string{"--user", "alice", "-w", "table"},
+			endpoints: []string{"https://a:2379", "https://b:2379"},
+			user:      "alice",
+			writeOut:  "table",
+		},
+	}
+	for i, tc := range tt {
+		fs := newContextFlagSet(path)
+		if err = fs.Parse(tc.args); err != nil {
+			t.Fatal(err)
+		}
+		if err = applyContext(fs); err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		eps, _ := fs.GetStringSlice("endpoints")
+		insecure, _ := fs.GetBool("insecure-transport")
+		user, _ := fs.GetString("user")
+		writeOut, _ := fs.GetString("write-out")
+		if !reflect.DeepEqual(eps, tc.endpoints) {
+			t.Errorf("#%d: expected endpoints %v, got %v", i, tc.endpoints, eps)
+		}
+		if insecure != tc.insecure {
+			t.Errorf("#%d: expected insecure-transport %v, got %v", i, tc.insecure, insecure)
+		}
+		if user != tc.user {
+			t.Errorf("#%d: expected user %q, got %q", i, tc.user, user)
+		}
+		if writeOut != tc.writeOut {
+			t.Errorf("#%d: expected write-out %q, got %q", i, tc.writeOut, writeOut)
+		}
+	}
+
+	fs := newContextFlagSet(path)
+	if err = fs.Parse([]string{"--context", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = applyContext(fs); err == nil {
+		t.Error("expected error for a missing context")
+	}
+
+	// without a configuration file only the flags apply
+	fs = newContextFlagSet(filepath.Join(dir, "missing.yaml"))
+	if err = applyContext(fs); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestLoadCtlConfigInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcdctl-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, cfg := range []string{
+		"contexts:\n- endpoints: [a]\n",
+		"contexts:\n- name: a\n- name: a\n",
+		"contexts:\n- name: a\n  unknown: b\n",
+	} {
+		path := filepath.Join(dir, "config.yaml")
+		if err = ioutil.WriteFile(path, []byte(cfg), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = loadCtlConfig(path); err == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+	}
+}
+
+func TestSaveCtlConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcdctl-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "nested", "config.yaml")
+	insecure := false
+	cfg := &ctlConfig{
+		CurrentContext: "prod",
+		Contexts:       []ctlContext{{Name: "prod", Endpoints: []string{"https://a:2379"}, InsecureTransport: &insecure}},
+	}
+	if err = saveCtlConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadCtlConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cfg) {
+		t.Errorf("expected %+v, got %+v", cfg, got)
+	}
+}
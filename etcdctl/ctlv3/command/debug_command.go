@@ -0,0 +1,163 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pprofEndpoint string
+	pprofProfile  string
+	pprofOutput   string
+	pprofSeconds  int
+)
+
+// pprofProfiles are the profiles served by the members under "/debug/pprof/".
+var pprofProfiles = []string{"allocs", "block", "cpu", "goroutine", "heap", "mutex", "threadcreate"}
+
+// NewDebugCommand returns the cobra command for "debug".
+func NewDebugCommand() *cobra.Command {
+	dc := &cobra.Command{
+		Use:   "debug <subcommand>",
+		Short: "Debugging related commands",
+	}
+
+	dc.AddCommand(newDebugPprofCommand())
+
+	return dc
+}
+
+func newDebugPprofCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pprof --profile <profile> -o <file>",
+		Short: "Fetches a runtime profile from a member started with --enable-pprof",
+		Run:   debugPprofCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&pprofEndpoint, "endpoint", "", "client URL of the member to profile (defaults to the first of --endpoints)")
+	cmd.Flags().StringVar(&pprofProfile, "profile", "heap", "profile to fetch: "+strings.Join(pprofProfiles, ", "))
+	cmd.Flags().StringVarP(&pprofOutput, "output", "o", "", "file to write the profile to")
+	cmd.Flags().IntVar(&pprofSeconds, "seconds", 30, "duration of the cpu profile in seconds")
+
+	return cmd
+}
+
+// debugPprofCommandFunc executes the "debug pprof" command.
+func debugPprofCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("debug pprof command does not accept any arguments"))
+	}
+	if pprofOutput == "" {
+		ExitWithError(ExitBadArgs, errors.New("--output is required"))
+	}
+	if pprofSeconds <= 0 {
+		ExitWithError(ExitBadArgs, errors.New("--seconds must be positive"))
+	}
+
+	cc := clientConfigFromCmd(cmd)
+	cfg, err := newClientCfg(cc.endpoints, cc.dialTimeout, cc.keepAliveTime, cc.keepAliveTimeout, cc.scfg, nil)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	ep := pprofEndpoint
+	if ep == "" {
+		ep = cc.endpoints[0]
+	}
+	u, err := pprofURL(ep, pprofProfile, pprofSeconds, cfg.TLS != nil)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+
+	timeout, err := cmd.Flags().GetDuration("command-timeout")
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if pprofProfile == "cpu" {
+		timeout += time.Duration(pprofSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	hc := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg.TLS}}
+	if err = fetchProfile(ctx, hc, u, pprofOutput); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Wrote %s profile of %s to %s\n", pprofProfile, ep, pprofOutput)
+}
+
+// pprofURL returns the URL serving the profile on the member. Endpoints
+// without a scheme use https if TLS is configured.
+func pprofURL(ep, profile string, seconds int, secure bool) (string, error) {
+	known := false
+	for _, p := range pprofProfiles {
+		known = known || p == profile
+	}
+	if !known {
+		return "", fmt.Errorf("unknown profile %q, expected one of %s", profile, strings.Join(pprofProfiles, ", "))
+	}
+
+	if !strings.Contains(ep, "://") {
+		if secure {
+			ep = "https://" + ep
+		} else {
+			ep = "http://" + ep
+		}
+	}
+	u := strings.TrimSuffix(ep, "/") + "/debug/pprof/"
+	if profile == "cpu" {
+		return fmt.Sprintf("%sprofile?seconds=%d", u, seconds), nil
+	}
+	return u + profile, nil
+}
+
+// fetchProfile writes the profile served at the URL to the output file.
+func fetchProfile(ctx context.Context, hc *http.Client, u, output string) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.New("profiling is not enabled on the member (see the server flag --enable-pprof)")
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("fetching profile: %s", resp.Status)
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(output)
+		return err
+	}
+	return f.Close()
+}
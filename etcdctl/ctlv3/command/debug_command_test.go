@@ -0,0 +1,83 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPprofURL(t *testing.T) {
+	tt := []struct {
+		ep      string
+		profile string
+		secure  bool
+
+		url string
+		err bool
+	}{
+		{ep: "127.0.0.1:2379", profile: "heap", url: "http://127.0.0.1:2379/debug/pprof/heap"},
+		{ep: "127.0.0.1:2379", profile: "heap", secure: true, url: "https://127.0.0.1:2379/debug/pprof/heap"},
+		{ep: "http://a:2379/", profile: "goroutine", secure: true, url: "http://a:2379/debug/pprof/goroutine"},
+		{ep: "https://a:2379", profile: "cpu", url: "https://a:2379/debug/pprof/profile?seconds=10"},
+		{ep: "a:2379", profile: "cmdline", err: true},
+	}
+	for i, tc := range tt {
+		u, err := pprofURL(tc.ep, tc.profile, 10, tc.secure)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: expected error %v, got %v", i, tc.err, err)
+		}
+		if u != tc.url {
+			t.Errorf("#%d: expected %q, got %q", i, tc.url, u)
+		}
+	}
+}
+
+func TestFetchProfile(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/heap", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("profile"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "etcdctl-pprof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "heap.pb.gz")
+
+	if err = fetchProfile(context.Background(), srv.Client(), srv.URL+"/debug/pprof/heap", out); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "profile" {
+		t.Errorf("expected %q, got %q", "profile", b)
+	}
+
+	// the member does not serve profiles
+	if err = fetchProfile(context.Background(), srv.Client(), srv.URL+"/debug/pprof/block", out); err == nil {
+		t.Error("expected error")
+	}
+}
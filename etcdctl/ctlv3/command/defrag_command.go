@@ -15,17 +15,26 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	v3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/v3/mvcc/backend"
 )
 
 var (
-	defragDataDir string
+	defragDataDirs      []string
+	defragRolling       bool
+	defragMoveLeader    bool
+	defragHealthTimeout time.Duration
+	defragWait          bool
 )
 
 // NewDefragCommand returns the cobra command for "Defrag".
@@ -36,27 +45,39 @@ func NewDefragCommand() *cobra.Command {
 		Run:   defragCommandFunc,
 	}
 	cmd.PersistentFlags().BoolVar(&epClusterEndpoints, "cluster", false, "use all endpoints from the cluster member list")
-	cmd.Flags().StringVar(&defragDataDir, "data-dir", "", "Optional. If present, defragments a data directory not in use by etcd.")
+	cmd.Flags().StringArrayVar(&defragDataDirs, "data-dir", nil, "Optional. If present, defragments a data directory not in use by etcd. Repeatable; the data directories are defragmented in parallel.")
+	cmd.Flags().BoolVar(&defragRolling, "rolling", false, "defragment the members one at a time, leader last, waiting for each to become healthy (requires --cluster)")
+	cmd.Flags().BoolVar(&defragMoveLeader, "move-leader", false, "with --rolling, transfer leadership to a defragmented member before defragmenting the leader")
+	cmd.Flags().DurationVar(&defragHealthTimeout, "health-timeout", 30*time.Second, "with --rolling, time to wait for a defragmented member to become healthy")
+	cmd.Flags().BoolVar(&defragWait, "wait", false, "wait for each member's defragmentation with no time limit, reporting its progress")
 	return cmd
 }
 
 func defragCommandFunc(cmd *cobra.Command, args []string) {
-	if len(defragDataDir) > 0 {
-		err := defragData(defragDataDir)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to defragment etcd data[%s] (%v)\n", defragDataDir, err)
+	if len(defragDataDirs) > 0 {
+		if forEachDataDir(defragDataDirs, "defragment", "defragmenting", defragData) != 0 {
 			os.Exit(ExitError)
 		}
 		return
 	}
 
+	if defragRolling {
+		if !epClusterEndpoints {
+			ExitWithError(ExitBadArgs, errors.New("--rolling requires --cluster"))
+		}
+		if err := rollingDefrag(cmd); err != nil {
+			ExitWithError(ExitError, err)
+		}
+		return
+	}
+	if defragMoveLeader {
+		ExitWithError(ExitBadArgs, errors.New("--move-leader requires --rolling"))
+	}
+
 	failures := 0
 	c := mustClientFromCmd(cmd)
 	for _, ep := range endpointsFromCluster(cmd) {
-		ctx, cancel := commandCtx(cmd)
-		_, err := c.Defragment(ctx, ep)
-		cancel()
-		if err != nil {
+		if err := defragEndpoint(cmd, c, ep); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to defragment etcd member[%s] (%v)\n", ep, err)
 			failures++
 		} else {
@@ -69,7 +90,178 @@ func defragCommandFunc(cmd *cobra.Command, args []string) {
 	}
 }
 
+// defragEndpoint defragments the member at the given endpoint, reporting
+// its progress with --wait.
+func defragEndpoint(cmd *cobra.Command, c *v3.Client, ep string) error {
+	if !defragWait {
+		ctx, cancel := commandCtx(cmd)
+		_, err := c.Defragment(ctx, ep)
+		cancel()
+		return err
+	}
+
+	poll := maintenanceProgressPoller(cmd, c, ep, func(resp *v3.MaintenanceProgressResponse) *pb.OperationProgress {
+		return resp.Defragment
+	})
+	p := startProgress(fmt.Sprintf("defragmenting etcd member[%s]", ep), poll)
+	ctx, cancel := waitCtx()
+	_, err := c.Defragment(ctx, ep)
+	cancel()
+	p.stop()
+	return err
+}
+
+type defragMember struct {
+	id uint64
+	ep string
+}
+
+// rollingDefrag defragments the cluster members one at a time, followers
+// first, and aborts on the first member that fails to defragment or does
+// not become healthy again.
+func rollingDefrag(cmd *cobra.Command) error {
+	cc := clientConfigFromCmd(cmd)
+	c := cc.mustClient()
+	defer c.Close()
+
+	members, leader, err := defragMembers(cmd, c, endpointsFromCluster(cmd))
+	if err != nil {
+		return err
+	}
+
+	for i, m := range members {
+		if m.id == leader && defragMoveLeader && i > 0 {
+			// hand leadership to a member that has already been defragmented
+			target := members[0]
+			if err := defragMoveLeaderTo(cmd, cc, m, target); err != nil {
+				return fmt.Errorf("failed to move leader from etcd member[%s] to [%s] (%v)", m.ep, target.ep, err)
+			}
+			fmt.Printf("Moved leader from etcd member[%s] to [%s]\n", m.ep, target.ep)
+			if err := waitMemberHealthy(cmd, cc, target.ep); err != nil {
+				return err
+			}
+		}
+
+		if err := defragEndpoint(cmd, c, m.ep); err != nil {
+			return fmt.Errorf("failed to defragment etcd member[%s] (%v)", m.ep, err)
+		}
+		fmt.Printf("Finished defragmenting etcd member[%s]\n", m.ep)
+
+		if err := waitMemberHealthy(cmd, cc, m.ep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defragMembers returns one endpoint per member, with the leader last, and
+// the ID of the leader.
+func defragMembers(cmd *cobra.Command, c *v3.Client, eps []string) ([]defragMember, uint64, error) {
+	sts := make([]*v3.StatusResponse, len(eps))
+	for i, ep := range eps {
+		ctx, cancel := commandCtx(cmd)
+		resp, err := c.Status(ctx, ep)
+		cancel()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get the status of endpoint %s (%v)", ep, err)
+		}
+		sts[i] = resp
+	}
+	members, leadID := defragOrder(eps, sts)
+	return members, leadID, nil
+}
+
+// defragOrder orders the members of the endpoints by their statuses, one
+// endpoint per member and the leader last, and returns the ID of the leader.
+func defragOrder(eps []string, sts []*v3.StatusResponse) ([]defragMember, uint64) {
+	var (
+		members []defragMember
+		leader  *defragMember
+		leadID  uint64
+		seen    = make(map[uint64]bool)
+	)
+	for i, ep := range eps {
+		id := sts[i].Header.MemberId
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		leadID = sts[i].Leader
+		if id == sts[i].Leader {
+			leader = &defragMember{id: id, ep: ep}
+			continue
+		}
+		members = append(members, defragMember{id: id, ep: ep})
+	}
+	if leader != nil {
+		members = append(members, *leader)
+	}
+	return members, leadID
+}
+
+func defragMoveLeaderTo(cmd *cobra.Command, cc *clientConfig, leader, target defragMember) error {
+	cli, err := cc.endpointClient(leader.ep)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	_, err = cli.MoveLeader(ctx, target.id)
+	return err
+}
+
+// waitMemberHealthy waits until the member serves requests and reports a
+// leader and no errors, as checked by "endpoint health" and "endpoint status".
+func waitMemberHealthy(cmd *cobra.Command, cc *clientConfig, ep string) error {
+	deadline := time.Now().Add(defragHealthTimeout)
+	for {
+		err := memberHealth(cmd, cc, ep)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("etcd member[%s] is not healthy after %v (%v)", ep, defragHealthTimeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func memberHealth(cmd *cobra.Command, cc *clientConfig, ep string) error {
+	cli, err := cc.endpointClient(ep)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	// permission denied is OK since proposal goes through consensus to get it
+	if _, err = cli.Get(ctx, "health"); err != nil && err != rpctypes.ErrPermissionDenied {
+		return err
+	}
+	resp, err := cli.Status(ctx, ep)
+	if err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return errors.New(strings.Join(resp.Errors, ", "))
+	}
+	if resp.Leader == 0 {
+		return errors.New("no leader")
+	}
+	return nil
+}
+
 func defragData(dataDir string) error {
+	be := openDataBackend(dataDir)
+	defer be.Close()
+	return be.Defrag()
+}
+
+// openDataBackend opens the backend of a data directory, waiting for the
+// etcd instance using it, if any, to release its lock.
+func openDataBackend(dataDir string) backend.Backend {
 	var be backend.Backend
 
 	bch := make(chan struct{})
@@ -83,8 +275,37 @@ func defragData(dataDir string) error {
 	case <-bch:
 	case <-time.After(time.Second):
 		fmt.Fprintf(os.Stderr, "waiting for etcd to close and release its lock on %q. "+
-			"To defrag a running etcd instance, omit --data-dir.\n", dbDir)
+			"To operate on a running etcd instance, omit --data-dir.\n", dbDir)
 		<-bch
 	}
-	return be.Defrag()
+	return be
+}
+
+// forEachDataDir runs the operation on the data directories in parallel,
+// reports the outcome for each of them, and returns the number of failures.
+// The operation is named by its verb and its gerund in the reports.
+func forEachDataDir(dataDirs []string, verb, gerund string, f func(dataDir string) error) (failures int) {
+	type result struct {
+		dataDir string
+		took    time.Duration
+		err     error
+	}
+	rc := make(chan result, len(dataDirs))
+	for _, dir := range dataDirs {
+		go func(dir string) {
+			start := time.Now()
+			err := f(dir)
+			rc <- result{dataDir: dir, took: time.Since(start), err: err}
+		}(dir)
+	}
+	for range dataDirs {
+		r := <-rc
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %s etcd data[%s] (%v)\n", verb, r.dataDir, r.err)
+			failures++
+			continue
+		}
+		fmt.Printf("Finished %s etcd data[%s] in %v\n", gerund, r.dataDir, r.took.Round(time.Millisecond))
+	}
+	return failures
 }
@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestDefragOrder(t *testing.T) {
+	st := func(id, leader uint64) *v3.StatusResponse {
+		return &v3.StatusResponse{Header: &pb.ResponseHeader{MemberId: id}, Leader: leader}
+	}
+	tt := []struct {
+		eps []string
+		sts []*v3.StatusResponse
+
+		members []defragMember
+		leader  uint64
+	}{
+		{
+			[]string{"a", "b", "c"},
+			[]*v3.StatusResponse{st(1, 1), st(2, 1), st(3, 1)},
+			[]defragMember{{2, "b"}, {3, "c"}, {1, "a"}},
+			1,
+		},
+		{
+			// endpoints of the same member are defragmented once
+			[]string{"a", "a2", "b", "c"},
+			[]*v3.StatusResponse{st(1, 3), st(1, 3), st(2, 3), st(3, 3)},
+			[]defragMember{{1, "a"}, {2, "b"}, {3, "c"}},
+			3,
+		},
+		{
+			// the leader is not among the endpoints
+			[]string{"a", "b"},
+			[]*v3.StatusResponse{st(1, 3), st(2, 3)},
+			[]defragMember{{1, "a"}, {2, "b"}},
+			3,
+		},
+	}
+	for i, ts := range tt {
+		members, leader := defragOrder(ts.eps, ts.sts)
+		if !reflect.DeepEqual(members, ts.members) {
+			t.Errorf("#%d: expected members %v, got %v", i, ts.members, members)
+		}
+		if leader != ts.leader {
+			t.Errorf("#%d: expected leader %d, got %d", i, ts.leader, leader)
+		}
+	}
+}
@@ -15,7 +15,12 @@
 package command
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/client/v3"
@@ -25,27 +30,50 @@ var (
 	delPrefix  bool
 	delPrevKV  bool
 	delFromKey bool
+	delDryRun  bool
+
+	delInteractive bool
+	delYes         bool
 )
 
+var errDelAborted = errors.New("delete aborted by user")
+
 // NewDelCommand returns the cobra command for "del".
 func NewDelCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "del [options] <key> [range_end]",
 		Short: "Removes the specified key or range of keys [key, range_end)",
 		Run:   delCommandFunc,
+
+		ValidArgsFunction: completeKeys,
 	}
 
 	cmd.Flags().BoolVar(&delPrefix, "prefix", false, "delete keys with matching prefix")
 	cmd.Flags().BoolVar(&delPrevKV, "prev-kv", false, "return deleted key-value pairs")
 	cmd.Flags().BoolVar(&delFromKey, "from-key", false, "delete keys that are greater than or equal to the given key using byte compare")
+	cmd.Flags().BoolVar(&delDryRun, "dry-run", false, "count the keys that would be deleted without deleting them")
+	cmd.Flags().BoolVarP(&delInteractive, "interactive", "i", false, "ask for confirmation before deleting with --prefix or --from-key")
+	cmd.Flags().BoolVarP(&delYes, "yes", "y", false, "skip the confirmation asked by --interactive")
 	return cmd
 }
 
 // delCommandFunc executes the "del" command.
 func delCommandFunc(cmd *cobra.Command, args []string) {
 	key, opts := getDelOp(args)
+	c := mustClientFromCmd(cmd)
+	if delDryRun {
+		resp := delCount(cmd, c, key, opts)
+		fmt.Printf("dry-run: would delete %d key(s) at revision %d\n", resp.Count, resp.Header.Revision)
+		return
+	}
+	if delInteractive && !delYes && (delPrefix || delFromKey) {
+		resp := delCount(cmd, c, key, opts)
+		if err := confirmDel(os.Stdin, os.Stdout, resp.Count, args[0]); err != nil {
+			ExitWithError(ExitInterrupted, err)
+		}
+	}
 	ctx, cancel := commandCtx(cmd)
-	resp, err := mustClientFromCmd(cmd).Delete(ctx, key, opts...)
+	resp, err := c.Delete(ctx, key, opts...)
 	cancel()
 	if err != nil {
 		ExitWithError(ExitError, err)
@@ -53,6 +81,35 @@ func delCommandFunc(cmd *cobra.Command, args []string) {
 	display.Del(*resp)
 }
 
+// delCount counts the keys matched by the delete operation.
+func delCount(cmd *cobra.Command, c *clientv3.Client, key string, opts []clientv3.OpOption) *clientv3.GetResponse {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Get(ctx, key, append(opts, clientv3.WithCountOnly())...)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	return resp
+}
+
+// confirmDel asks the user to confirm the deletion of n keys under the
+// given prefix (or from the given key) by typing "yes".
+func confirmDel(in io.Reader, out io.Writer, n int64, key string) error {
+	scope := "under"
+	if delFromKey {
+		scope = "from"
+	}
+	fmt.Fprintf(out, "About to delete %d keys %s %q. Type 'yes' to continue: ", n, scope, key)
+	l, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(l) != "yes" {
+		return errDelAborted
+	}
+	return nil
+}
+
 func getDelOp(args []string) (string, []clientv3.OpOption) {
 	if len(args) == 0 || len(args) > 2 {
 		ExitWithError(ExitBadArgs, fmt.Errorf("del command needs one argument as key and an optional argument as range_end"))
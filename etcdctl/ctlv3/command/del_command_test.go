@@ -0,0 +1,44 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfirmDel(t *testing.T) {
+	tt := []struct {
+		input string
+		err   error
+	}{
+		{"yes\n", nil},
+		{"  yes  \n", nil},
+		{"yes", nil},
+		{"y\n", errDelAborted},
+		{"no\n", errDelAborted},
+		{"", errDelAborted},
+	}
+	for i, ts := range tt {
+		var out bytes.Buffer
+		if err := confirmDel(strings.NewReader(ts.input), &out, 3, "foo"); err != ts.err {
+			t.Fatalf("#%d: error expected %v, got %v", i, ts.err, err)
+		}
+		if !strings.Contains(out.String(), `About to delete 3 keys under "foo"`) {
+			t.Fatalf("#%d: unexpected prompt %q", i, out.String())
+		}
+	}
+}
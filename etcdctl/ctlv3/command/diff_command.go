@@ -0,0 +1,191 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffRevs          []int64
+	diffPrefix        string
+	diffDestEndpoints []string
+	diffPageSize      int64
+)
+
+// NewDiffCommand returns the cobra command for "diff".
+func NewDiffCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "diff [options]",
+		Short: "Compares the keyspace at two revisions or between two clusters",
+		Run:   diffCommandFunc,
+	}
+
+	c.Flags().Int64SliceVar(&diffRevs, "rev", nil, "Revision to compare; give it twice to compare two revisions of the same cluster, or at most once with --dest-endpoints")
+	c.Flags().StringVar(&diffPrefix, "prefix", "", "Only compare keys under this prefix")
+	c.Flags().Int64Var(&diffPageSize, "page-size", 1000, "Number of keys fetched per range request")
+	c.Flags().StringSliceVar(&diffDestEndpoints, "dest-endpoints", nil, "Endpoints of the cluster to compare against")
+	c.Flags().StringVar(&mmdestprefix, "dest-prefix", "", "Prefix the keys under --prefix are stored under in the destination cluster")
+	c.Flags().StringVar(&mmcert, "dest-cert", "", "Identify secure client using this TLS certificate file for the destination cluster")
+	c.Flags().StringVar(&mmkey, "dest-key", "", "Identify secure client using this TLS key file")
+	c.Flags().StringVar(&mmcacert, "dest-cacert", "", "Verify certificates of TLS enabled secure servers using this CA bundle")
+	// TODO: secure by default when etcd enables secure gRPC by default.
+	c.Flags().BoolVar(&mminsecureTr, "dest-insecure-transport", true, "Disable transport security for client connections")
+	c.Flags().StringVar(&mmuser, "dest-user", "", "Destination username[:password] for authentication (prompt if password is not supplied)")
+	c.Flags().StringVar(&mmpassword, "dest-password", "", "Destination password for authentication (if this option is used, --user option shouldn't include password)")
+
+	return c
+}
+
+// keyspaceDiff lists the keys that differ between a source and a destination
+// keyspace. Keys are relative to the source, e.g. keys only present in the
+// destination are "added".
+type keyspaceDiff struct {
+	Revision     int64    `json:"revision"`
+	DestRevision int64    `json:"dest_revision"`
+	Added        []string `json:"added"`
+	Removed      []string `json:"removed"`
+	Changed      []string `json:"changed"`
+
+	// summary is "true" to only print the counts of the keys.
+	summary bool
+}
+
+func diffCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("diff command does not accept arguments"))
+	}
+	if diffPageSize <= 0 {
+		ExitWithError(ExitBadArgs, errors.New("--page-size must be positive"))
+	}
+
+	c := mustClientFromCmd(cmd)
+	dc, rev, destRev := c, int64(0), int64(0)
+	if len(diffDestEndpoints) > 0 {
+		if len(diffRevs) > 1 {
+			ExitWithError(ExitBadArgs, errors.New("at most one --rev can be given with --dest-endpoints"))
+		}
+		if len(diffRevs) == 1 {
+			rev = diffRevs[0]
+		}
+		dc = diffDestClient(cmd)
+	} else {
+		if len(diffRevs) != 2 {
+			ExitWithError(ExitBadArgs, errors.New("diff command needs either two --rev or --dest-endpoints"))
+		}
+		if mmdestprefix != "" {
+			ExitWithError(ExitBadArgs, errors.New("--dest-prefix requires --dest-endpoints"))
+		}
+		rev, destRev = diffRevs[0], diffRevs[1]
+	}
+
+	destPrefix := diffPrefix
+	if mmdestprefix != "" {
+		destPrefix = mmdestprefix
+	}
+	kd := newKeyspaceDiffer(diffPrefix, destPrefix)
+	key, end := diffRange(diffPrefix)
+	rev, err := rangeAll(cmd, c, key, end, rev, diffPageSize, kd.addSource)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	key, end = diffRange(destPrefix)
+	destRev, err = rangeAll(cmd, dc, key, end, destRev, diffPageSize, kd.addDest)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+
+	d := kd.diff()
+	d.Revision, d.DestRevision = rev, destRev
+	display.KeyspaceDiff(d)
+}
+
+// keyspaceDiffer compares the keys of a source keyspace under a prefix with
+// the keys of a destination keyspace under a destination prefix. All the
+// source keys must be added before the destination keys.
+type keyspaceDiffer struct {
+	prefix, destPrefix string
+	src                map[string][sha256.Size]byte
+	d                  keyspaceDiff
+}
+
+func newKeyspaceDiffer(prefix, destPrefix string) *keyspaceDiffer {
+	return &keyspaceDiffer{
+		prefix:     prefix,
+		destPrefix: destPrefix,
+		src:        make(map[string][sha256.Size]byte),
+	}
+}
+
+func (kd *keyspaceDiffer) addSource(kv *mvccpb.KeyValue) {
+	kd.src[string(kv.Key)] = sha256.Sum256(kv.Value)
+}
+
+func (kd *keyspaceDiffer) addDest(kv *mvccpb.KeyValue) {
+	k := kd.prefix + strings.TrimPrefix(string(kv.Key), kd.destPrefix)
+	h, ok := kd.src[k]
+	switch {
+	case !ok:
+		kd.d.Added = append(kd.d.Added, k)
+	case h != sha256.Sum256(kv.Value):
+		kd.d.Changed = append(kd.d.Changed, k)
+	}
+	delete(kd.src, k)
+}
+
+// diff returns the keys that differ, sorted. It must be called once, after
+// all the keys are added.
+func (kd *keyspaceDiffer) diff() keyspaceDiff {
+	for k := range kd.src {
+		kd.d.Removed = append(kd.d.Removed, k)
+	}
+	sort.Strings(kd.d.Added)
+	sort.Strings(kd.d.Removed)
+	sort.Strings(kd.d.Changed)
+	return kd.d
+}
+
+func diffRange(prefix string) (key, end string) {
+	key, end = prefix, clientv3.GetPrefixRangeEnd(prefix)
+	if key == "" {
+		key = "\x00"
+	}
+	return key, end
+}
+
+func diffDestClient(cmd *cobra.Command) *clientv3.Client {
+	cc := &clientConfig{
+		endpoints:        diffDestEndpoints,
+		dialTimeout:      dialTimeoutFromCmd(cmd),
+		keepAliveTime:    keepAliveTimeFromCmd(cmd),
+		keepAliveTimeout: keepAliveTimeoutFromCmd(cmd),
+		scfg: &secureCfg{
+			cert:              mmcert,
+			key:               mmkey,
+			cacert:            mmcacert,
+			insecureTransport: mminsecureTr,
+		},
+		acfg: authDestCfg(),
+	}
+	return cc.mustClient()
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestKeyspaceDiffer(t *testing.T) {
+	tt := []struct {
+		prefix, destPrefix string
+		src, dest          map[string]string
+
+		added, removed, changed []string
+	}{
+		{
+			src:  map[string]string{"a": "1", "b": "2"},
+			dest: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			src:  map[string]string{"a": "1", "b": "2", "c": "3"},
+			dest: map[string]string{"b": "20", "c": "3", "e": "5", "d": "4"},
+
+			added:   []string{"d", "e"},
+			removed: []string{"a"},
+			changed: []string{"b"},
+		},
+		{
+			prefix:     "/src/",
+			destPrefix: "/dst/",
+			src:        map[string]string{"/src/a": "1", "/src/b": "2"},
+			dest:       map[string]string{"/dst/a": "1", "/dst/c": "3"},
+
+			added:   []string{"/src/c"},
+			removed: []string{"/src/b"},
+		},
+		{
+			src: map[string]string{"a": "1"},
+
+			removed: []string{"a"},
+		},
+	}
+	for i, ts := range tt {
+		kd := newKeyspaceDiffer(ts.prefix, ts.destPrefix)
+		for k, v := range ts.src {
+			kd.addSource(&mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+		for k, v := range ts.dest {
+			kd.addDest(&mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+		d := kd.diff()
+		if !reflect.DeepEqual(d.Added, ts.added) {
+			t.Errorf("#%d: expected added %v, got %v", i, ts.added, d.Added)
+		}
+		if !reflect.DeepEqual(d.Removed, ts.removed) {
+			t.Errorf("#%d: expected removed %v, got %v", i, ts.removed, d.Removed)
+		}
+		if !reflect.DeepEqual(d.Changed, ts.changed) {
+			t.Errorf("#%d: expected changed %v, got %v", i, ts.changed, d.Changed)
+		}
+	}
+}
+
+func TestDiffRange(t *testing.T) {
+	tt := []struct {
+		prefix   string
+		key, end string
+	}{
+		{"", "\x00", "\x00"},
+		{"foo", "foo", "fop"},
+		{"a\xff", "a\xff", "b"},
+	}
+	for i, ts := range tt {
+		if key, end := diffRange(ts.prefix); key != ts.key || end != ts.end {
+			t.Errorf("#%d: expected range [%q, %q), got [%q, %q)", i, ts.key, ts.end, key, end)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/v3"
+)
+
+var (
+	downgradeStatusOnly bool
+	downgradeWatch      bool
+	downgradeInterval   time.Duration
+)
+
+// NewDowngradeCommand returns the cobra command for "downgrade".
+func NewDowngradeCommand() *cobra.Command {
+	dc := &cobra.Command{
+		Use:   "downgrade <subcommand>",
+		Short: "Downgrade related commands",
+	}
+
+	dc.AddCommand(newDowngradeValidateCommand())
+	dc.AddCommand(newDowngradeEnableCommand())
+	dc.AddCommand(newDowngradeCancelCommand())
+	dc.AddCommand(newDowngradeRunCommand())
+
+	return dc
+}
+
+func newDowngradeValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate <TARGET_VERSION>",
+		Short: "Validates whether the cluster can be downgraded to the target version",
+		Run:   downgradeValidateCommandFunc,
+	}
+}
+
+func newDowngradeEnableCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable <TARGET_VERSION>",
+		Short: "Allows members of the target version to join the cluster",
+		Run:   downgradeEnableCommandFunc,
+	}
+}
+
+func newDowngradeCancelCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancels the ongoing downgrade",
+		Run:   downgradeCancelCommandFunc,
+	}
+}
+
+func newDowngradeRunCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <TARGET_VERSION>",
+		Short: "Validates and enables a downgrade, then reports its progress",
+		Long: `Validates and enables the downgrade of the cluster to the target version, then
+shows the version of every member and whether it is safe to replace the
+binary of the next member. With --watch, the progress is reported until
+every member runs the target version.
+`,
+		Run: downgradeRunCommandFunc,
+	}
+
+	cmd.Flags().BoolVar(&downgradeStatusOnly, "status", false, "only report the progress of the downgrade, without enabling it")
+	cmd.Flags().BoolVar(&downgradeWatch, "watch", false, "report the progress until every member runs the target version")
+	cmd.Flags().DurationVar(&downgradeInterval, "interval", 5*time.Second, "time between progress reports with --watch")
+
+	return cmd
+}
+
+// downgradeValidateCommandFunc executes the "downgrade validate" command.
+func downgradeValidateCommandFunc(cmd *cobra.Command, args []string) {
+	target := mustDowngradeTarget(args)
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Downgrade(ctx, clientv3.DowngradeValidate, target)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Downgrade validate success, cluster version %s\n", resp.Version)
+}
+
+// downgradeEnableCommandFunc executes the "downgrade enable" command.
+func downgradeEnableCommandFunc(cmd *cobra.Command, args []string) {
+	target := mustDowngradeTarget(args)
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Downgrade(ctx, clientv3.DowngradeEnable, target)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Downgrade enable success, cluster version %s\n", resp.Version)
+}
+
+// downgradeCancelCommandFunc executes the "downgrade cancel" command.
+func downgradeCancelCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("downgrade cancel command does not accept any arguments"))
+	}
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Downgrade(ctx, clientv3.DowngradeCancel, "")
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Downgrade cancel success, cluster version %s\n", resp.Version)
+}
+
+// downgradeRunCommandFunc executes the "downgrade run" command.
+func downgradeRunCommandFunc(cmd *cobra.Command, args []string) {
+	target := mustDowngradeTarget(args)
+	if downgradeInterval <= 0 {
+		ExitWithError(ExitBadArgs, errors.New("--interval must be positive"))
+	}
+	c := mustClientFromCmd(cmd)
+
+	// validating tells whether the downgrade is already enabled
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Downgrade(ctx, clientv3.DowngradeValidate, target)
+	cancel()
+	switch {
+	case err == rpctypes.ErrDowngradeInProcess:
+		fmt.Printf("Downgrade to %s is enabled\n", target)
+	case err != nil:
+		ExitWithError(ExitError, err)
+	case downgradeStatusOnly:
+		ExitWithError(ExitError, fmt.Errorf("downgrade to %s is not enabled (cluster version %s)", target, resp.Version))
+	default:
+		ctx, cancel = commandCtx(cmd)
+		resp, err = c.Downgrade(ctx, clientv3.DowngradeEnable, target)
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		fmt.Printf("Downgrade to %s enabled, cluster version %s\n", target, resp.Version)
+	}
+
+	for i := 0; ; i++ {
+		if i > 0 {
+			time.Sleep(downgradeInterval)
+			fmt.Println()
+		}
+		ctx, cancel = commandCtx(cmd)
+		ms, err := downgradeMembers(ctx, c)
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		done := printDowngradeProgress(os.Stdout, target, ms)
+		if done || !downgradeWatch {
+			return
+		}
+	}
+}
+
+// mustDowngradeTarget returns the target version argument as
+// "major.minor", the form of the cluster version.
+func mustDowngradeTarget(args []string) string {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("downgrade command requires target version as its argument"))
+	}
+	v, err := majorMinor(args[0])
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	return v
+}
+
+// majorMinor returns the "major.minor" part of a version such as "3.4.14"
+// or "3.5.0-pre".
+func majorMinor(v string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) < 2 {
+		return "", fmt.Errorf("invalid version %q", v)
+	}
+	if len(parts) == 3 {
+		parts[2] = strings.SplitN(parts[2], "-", 2)[0]
+	}
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 32); err != nil {
+			return "", fmt.Errorf("invalid version %q", v)
+		}
+	}
+	return parts[0] + "." + parts[1], nil
+}
+
+// downgradeMember is the version of a member during a downgrade.
+type downgradeMember struct {
+	ID      uint64
+	Name    string
+	Version string
+	Err     string
+}
+
+func downgradeMembers(ctx context.Context, c *clientv3.Client) ([]downgradeMember, error) {
+	mresp, err := c.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ms := make([]downgradeMember, len(mresp.Members))
+	for i, m := range mresp.Members {
+		ms[i] = downgradeMember{ID: m.ID, Name: m.Name}
+		if len(m.ClientURLs) == 0 {
+			ms[i].Err = "not started"
+			continue
+		}
+		st, err := c.Status(ctx, m.ClientURLs[0])
+		if err != nil {
+			ms[i].Err = err.Error()
+			continue
+		}
+		ms[i].Version = st.Version
+	}
+	return ms, nil
+}
+
+// printDowngradeProgress prints the version of every member and the next
+// step of the downgrade. It reports whether every member runs the target
+// version.
+func printDowngradeProgress(w io.Writer, target string, ms []downgradeMember) bool {
+	downgraded, unhealthy := 0, 0
+	for _, m := range ms {
+		state := "pending"
+		switch v, err := majorMinor(m.Version); {
+		case m.Err != "":
+			state = "unreachable: " + m.Err
+			unhealthy++
+		case err == nil && v == target:
+			state = "downgraded"
+			downgraded++
+		}
+		fmt.Fprintf(w, "%x, %s, %s, %s\n", m.ID, m.Name, m.Version, state)
+	}
+
+	switch {
+	case downgraded == len(ms):
+		fmt.Fprintf(w, "All %d members run %s, the downgrade is complete\n", len(ms), target)
+		return true
+	case unhealthy > 0:
+		fmt.Fprintf(w, "%d/%d members downgraded; wait until every member is reachable before replacing the next binary\n", downgraded, len(ms))
+	default:
+		fmt.Fprintf(w, "%d/%d members downgraded; it is safe to replace the binary of the next pending member with %s\n", downgraded, len(ms), target)
+	}
+	return false
+}
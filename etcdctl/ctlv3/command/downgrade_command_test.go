@@ -0,0 +1,87 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMajorMinor(t *testing.T) {
+	tt := []struct {
+		v string
+
+		mm  string
+		err bool
+	}{
+		{v: "3.4", mm: "3.4"},
+		{v: "3.4.14", mm: "3.4"},
+		{v: "v3.5.0-pre", mm: "3.5"},
+		{v: "3", err: true},
+		{v: "3.x", err: true},
+		{v: "", err: true},
+	}
+	for i, tc := range tt {
+		mm, err := majorMinor(tc.v)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: expected error %v, got %v", i, tc.err, err)
+		}
+		if mm != tc.mm {
+			t.Errorf("#%d: expected %q, got %q", i, tc.mm, mm)
+		}
+	}
+}
+
+func TestPrintDowngradeProgress(t *testing.T) {
+	tt := []struct {
+		ms []downgradeMember
+
+		done bool
+		next string
+	}{
+		{
+			ms: []downgradeMember{
+				{ID: 1, Name: "a", Version: "3.4.14"},
+				{ID: 2, Name: "b", Version: "3.5.0"},
+			},
+			next: "1/2 members downgraded; it is safe",
+		},
+		{
+			ms: []downgradeMember{
+				{ID: 1, Name: "a", Version: "3.4.14"},
+				{ID: 2, Name: "b", Err: "context deadline exceeded"},
+			},
+			next: "wait until every member is reachable",
+		},
+		{
+			ms: []downgradeMember{
+				{ID: 1, Name: "a", Version: "3.4.14"},
+				{ID: 2, Name: "b", Version: "3.4.13"},
+			},
+			done: true,
+			next: "the downgrade is complete",
+		},
+	}
+	for i, tc := range tt {
+		var b bytes.Buffer
+		if done := printDowngradeProgress(&b, "3.4", tc.ms); done != tc.done {
+			t.Errorf("#%d: expected done %v, got %v", i, tc.done, done)
+		}
+		if !strings.Contains(b.String(), tc.next) {
+			t.Errorf("#%d: expected %q in %q", i, tc.next, b.String())
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 
@@ -28,9 +29,20 @@ import (
 )
 
 var (
-	electListen bool
+	electListen   bool
+	electSinceRev int64
 )
 
+// electionLeader is a proclamation of the election leader as seen by an
+// observer.
+type electionLeader struct {
+	Key            string           `json:"key"`
+	Value          string           `json:"value"`
+	Lease          clientv3.LeaseID `json:"lease"`
+	CreateRevision int64            `json:"create_revision"`
+	Revision       int64            `json:"revision"`
+}
+
 // NewElectCommand returns the cobra command for "elect".
 func NewElectCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -39,6 +51,7 @@ func NewElectCommand() *cobra.Command {
 		Run:   electCommandFunc,
 	}
 	cmd.Flags().BoolVarP(&electListen, "listen", "l", false, "observation mode")
+	cmd.Flags().Int64Var(&electSinceRev, "since-rev", 0, "in observation mode, replay leadership changes starting from the given revision")
 	return cmd
 }
 
@@ -58,6 +71,9 @@ func electCommandFunc(cmd *cobra.Command, args []string) {
 		if electListen {
 			ExitWithError(ExitBadArgs, errors.New("proposal given but -l is set"))
 		}
+		if electSinceRev != 0 {
+			ExitWithError(ExitBadArgs, errors.New("--since-rev is only supported with -l"))
+		}
 		err = campaign(c, args[0], args[1])
 	}
 	if err != nil {
@@ -66,14 +82,12 @@ func electCommandFunc(cmd *cobra.Command, args []string) {
 }
 
 func observe(c *clientv3.Client, election string) error {
-	s, err := concurrency.NewSession(c)
-	if err != nil {
-		return err
+	if electSinceRev < 0 {
+		return errors.New("elect: --since-rev must not be negative")
 	}
-	e := concurrency.NewElection(s, election)
 	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
 
-	donec := make(chan struct{})
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
@@ -81,14 +95,43 @@ func observe(c *clientv3.Client, election string) error {
 		cancel()
 	}()
 
-	go func() {
-		for resp := range e.Observe(ctx) {
-			display.Get(resp)
-		}
-		close(donec)
-	}()
+	// the candidates are the keys under the election prefix; the leader is
+	// the one created first. Track them from the starting revision so every
+	// proclamation after it is reported, even across leadership changes.
+	pfx := election + "/"
+	opts := []clientv3.OpOption{clientv3.WithPrefix()}
+	if electSinceRev > 0 {
+		opts = append(opts, clientv3.WithRev(electSinceRev))
+	}
+	resp, err := c.Get(ctx, pfx, opts...)
+	if err != nil {
+		return err
+	}
+	rev := resp.Header.Revision
+	if electSinceRev > 0 {
+		rev = electSinceRev
+	}
+
+	o := &electionObserver{candidates: make(map[string]*mvccpb.KeyValue)}
+	for _, kv := range resp.Kvs {
+		o.candidates[string(kv.Key)] = kv
+	}
+	o.report()
 
-	<-donec
+	for wr := range c.Watch(ctx, pfx, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+		if err := wr.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wr.Events {
+			switch ev.Type {
+			case mvccpb.PUT:
+				o.candidates[string(ev.Kv.Key)] = ev.Kv
+			case mvccpb.DELETE:
+				delete(o.candidates, string(ev.Kv.Key))
+			}
+			o.report()
+		}
+	}
 
 	select {
 	case <-ctx.Done():
@@ -99,6 +142,41 @@ func observe(c *clientv3.Client, election string) error {
 	return nil
 }
 
+// electionObserver tracks the candidates of an election and reports every
+// new leader or proclamation.
+type electionObserver struct {
+	candidates map[string]*mvccpb.KeyValue
+	last       *mvccpb.KeyValue
+}
+
+func (o *electionObserver) report() {
+	if l, ok := o.update(); ok {
+		display.ElectionLeader(l)
+	}
+}
+
+// update returns the leader if it is new or proclaimed a new value since the
+// last update.
+func (o *electionObserver) update() (electionLeader, bool) {
+	var leader *mvccpb.KeyValue
+	for _, kv := range o.candidates {
+		if leader == nil || kv.CreateRevision < leader.CreateRevision {
+			leader = kv
+		}
+	}
+	if leader == nil || (o.last != nil && o.last.CreateRevision == leader.CreateRevision && o.last.ModRevision == leader.ModRevision) {
+		return electionLeader{}, false
+	}
+	o.last = leader
+	return electionLeader{
+		Key:            string(leader.Key),
+		Value:          string(leader.Value),
+		Lease:          clientv3.LeaseID(leader.Lease),
+		CreateRevision: leader.CreateRevision,
+		Revision:       leader.ModRevision,
+	}, true
+}
+
 func campaign(c *clientv3.Client, election string, prop string) error {
 	s, err := concurrency.NewSession(c)
 	if err != nil {
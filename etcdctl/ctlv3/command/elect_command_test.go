@@ -0,0 +1,61 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestElectionObserverUpdate(t *testing.T) {
+	kv := func(key, val string, create, mod, lease int64) *mvccpb.KeyValue {
+		return &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), CreateRevision: create, ModRevision: mod, Lease: lease}
+	}
+	o := &electionObserver{candidates: make(map[string]*mvccpb.KeyValue)}
+	tt := []struct {
+		put    *mvccpb.KeyValue
+		delete string
+
+		leader   electionLeader
+		reported bool
+	}{
+		// the first candidate leads
+		{put: kv("e/a", "1", 2, 2, 0xa), leader: electionLeader{Key: "e/a", Value: "1", Lease: 0xa, CreateRevision: 2, Revision: 2}, reported: true},
+		// a later candidate does not change the leader
+		{put: kv("e/b", "2", 3, 3, 0xb)},
+		// a proclamation of the leader is reported with its revision
+		{put: kv("e/a", "10", 2, 4, 0xa), leader: electionLeader{Key: "e/a", Value: "10", Lease: 0xa, CreateRevision: 2, Revision: 4}, reported: true},
+		// a proclamation of another candidate is not
+		{put: kv("e/b", "20", 3, 5, 0xb)},
+		// the next candidate leads once the leader resigns
+		{delete: "e/a", leader: electionLeader{Key: "e/b", Value: "20", Lease: 0xb, CreateRevision: 3, Revision: 5}, reported: true},
+		{delete: "e/b"},
+	}
+	for i, ts := range tt {
+		if ts.put != nil {
+			o.candidates[string(ts.put.Key)] = ts.put
+		} else {
+			delete(o.candidates, ts.delete)
+		}
+		l, ok := o.update()
+		if ok != ts.reported {
+			t.Fatalf("#%d: expected reported %v, got %v", i, ts.reported, ok)
+		}
+		if l != ts.leader {
+			t.Errorf("#%d: expected leader %+v, got %+v", i, ts.leader, l)
+		}
+	}
+}
@@ -15,21 +15,24 @@
 package command
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	v3 "go.etcd.io/etcd/client/v3"
-	"go.etcd.io/etcd/pkg/v3/flags"
 
 	"github.com/spf13/cobra"
-	"go.uber.org/zap"
 )
 
 var epClusterEndpoints bool
 var epHashKVRev int64
+var epLatencySamples int
+var epLatencyKey string
 
 // NewEndpointCommand returns the cobra command for "endpoint".
 func NewEndpointCommand() *cobra.Command {
@@ -42,6 +45,7 @@ func NewEndpointCommand() *cobra.Command {
 	ec.AddCommand(newEpHealthCommand())
 	ec.AddCommand(newEpStatusCommand())
 	ec.AddCommand(newEpHashKVCommand())
+	ec.AddCommand(newEpLatencyCommand())
 
 	return ec
 }
@@ -77,6 +81,20 @@ func newEpHashKVCommand() *cobra.Command {
 	return hc
 }
 
+func newEpLatencyCommand() *cobra.Command {
+	lc := &cobra.Command{
+		Use:   "latency",
+		Short: "Measures the request latency of each endpoint in --endpoints",
+		Long: `Measures the round-trip time of linearizable reads, serializable reads and small writes
+against each endpoint, one endpoint at a time, and prints their p50/p95/p99 latencies.
+`,
+		Run: epLatencyCommandFunc,
+	}
+	lc.Flags().IntVar(&epLatencySamples, "samples", 10, "number of requests of each kind sent to each endpoint")
+	lc.Flags().StringVar(&epLatencyKey, "latency-key", "/etcdctl/endpoint/latency", "key read and written by the measurement; deleted afterwards")
+	return lc
+}
+
 type epHealth struct {
 	Ep     string `json:"endpoint"`
 	Health bool   `json:"health"`
@@ -86,11 +104,6 @@ type epHealth struct {
 
 // epHealthCommandFunc executes the "endpoint-health" command.
 func epHealthCommandFunc(cmd *cobra.Command, args []string) {
-	lg, err := zap.NewProduction()
-	if err != nil {
-		ExitWithError(ExitError, err)
-	}
-	flags.SetPflagsFromEnv(lg, "ETCDCTL", cmd.InheritedFlags())
 	initDisplayFromCmd(cmd)
 
 	sec := secureCfgFromCmd(cmd)
@@ -211,6 +224,131 @@ func epHashKVCommandFunc(cmd *cobra.Command, args []string) {
 	}
 }
 
+// latencyStats holds latency percentiles, in nanoseconds in JSON output.
+type latencyStats struct {
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+}
+
+func newLatencyStats(samples []time.Duration) latencyStats {
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return latencyStats{
+		P50: latencyPercentile(samples, 50),
+		P95: latencyPercentile(samples, 95),
+		P99: latencyPercentile(samples, 99),
+	}
+}
+
+// latencyPercentile returns the p-th percentile of the sorted samples,
+// using the nearest-rank method.
+func latencyPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p / 100 * float64(len(sorted)))
+	if float64(rank) < p/100*float64(len(sorted)) {
+		rank++
+	}
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+type epLatency struct {
+	Ep           string       `json:"endpoint"`
+	Linearizable latencyStats `json:"linearizable_read"`
+	Serializable latencyStats `json:"serializable_read"`
+	Write        latencyStats `json:"write"`
+	Error        string       `json:"error,omitempty"`
+}
+
+func epLatencyCommandFunc(cmd *cobra.Command, args []string) {
+	if epLatencySamples < 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--samples must be positive, got %d", epLatencySamples))
+	}
+
+	sec := secureCfgFromCmd(cmd)
+	dt := dialTimeoutFromCmd(cmd)
+	ka := keepAliveTimeFromCmd(cmd)
+	kat := keepAliveTimeoutFromCmd(cmd)
+	auth := authCfgFromCmd(cmd)
+
+	// measure one endpoint at a time so that they do not skew each other
+	latencyList := []epLatency{}
+	errs := false
+	for _, ep := range endpointsFromCluster(cmd) {
+		lat := epLatency{Ep: ep}
+		cfg, err := newClientCfg([]string{ep}, dt, ka, kat, sec, auth)
+		if err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+		cli, err := v3.New(*cfg)
+		if err == nil {
+			err = measureEpLatency(cmd, cli, &lat)
+			cli.Close()
+		}
+		if err != nil {
+			lat.Error = err.Error()
+			errs = true
+		}
+		latencyList = append(latencyList, lat)
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	_, err := c.Delete(ctx, epLatencyKey)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to delete %q (%v)\n", epLatencyKey, err)
+	}
+
+	display.EndpointLatency(latencyList)
+	if errs {
+		ExitWithError(ExitError, errors.New("failed to measure the latency of some endpoints"))
+	}
+}
+
+func measureEpLatency(cmd *cobra.Command, cli *v3.Client, lat *epLatency) error {
+	measure := func(f func(ctx context.Context) error) (latencyStats, error) {
+		samples := make([]time.Duration, 0, epLatencySamples)
+		for i := 0; i < epLatencySamples; i++ {
+			ctx, cancel := commandCtx(cmd)
+			st := time.Now()
+			err := f(ctx)
+			took := time.Since(st)
+			cancel()
+			if err != nil {
+				return latencyStats{}, err
+			}
+			samples = append(samples, took)
+		}
+		return newLatencyStats(samples), nil
+	}
+
+	var err error
+	lat.Write, err = measure(func(ctx context.Context) error {
+		_, err := cli.Put(ctx, epLatencyKey, "latency")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	lat.Linearizable, err = measure(func(ctx context.Context) error {
+		_, err := cli.Get(ctx, epLatencyKey)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	lat.Serializable, err = measure(func(ctx context.Context) error {
+		_, err := cli.Get(ctx, epLatencyKey, v3.WithSerializable())
+		return err
+	})
+	return err
+}
+
 func endpointsFromCluster(cmd *cobra.Command) []string {
 	if !epClusterEndpoints {
 		endpoints, err := cmd.Flags().GetStringSlice("endpoints")
@@ -0,0 +1,44 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLatencyStats(t *testing.T) {
+	samples := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	tt := []struct {
+		samples []time.Duration
+		stats   latencyStats
+	}{
+		{nil, latencyStats{}},
+		{[]time.Duration{time.Second}, latencyStats{P50: time.Second, P95: time.Second, P99: time.Second}},
+		{
+			[]time.Duration{3 * time.Millisecond, time.Millisecond, 2 * time.Millisecond},
+			latencyStats{P50: 2 * time.Millisecond, P95: 3 * time.Millisecond, P99: 3 * time.Millisecond},
+		},
+		{samples, latencyStats{P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond}},
+	}
+	for i, tc := range tt {
+		if stats := newLatencyStats(tc.samples); stats != tc.stats {
+			t.Errorf("#%d: stats = %+v, want %+v", i, stats, tc.stats)
+		}
+	}
+}
@@ -30,6 +30,7 @@ const (
 	ExitBadFeature   // provided a valid flag with an unsupported value
 	ExitInterrupted
 	ExitIO
+	ExitLocked  // for lock try, the lock is held by another session
 	ExitBadArgs = 128
 )
 
@@ -15,10 +15,14 @@
 package command
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/client/v3"
 )
 
@@ -32,15 +36,26 @@ var (
 	getRev         int64
 	getKeysOnly    bool
 	getCountOnly   bool
+	getShowDeleted bool
+	getStream      bool
+	getStreamPage  int64
+	getKeysFile    string
 	printValueOnly bool
 )
 
+// getKeysBatchSize is the number of keys read per transaction with
+// "--keys-from-file", within the default limit of operations per
+// transaction of the server.
+const getKeysBatchSize = 128
+
 // NewGetCommand returns the cobra command for "get".
 func NewGetCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get [options] <key> [range_end]",
 		Short: "Gets the key or a range of keys",
 		Run:   getCommandFunc,
+
+		ValidArgsFunction: completeKeys,
 	}
 
 	cmd.Flags().StringVar(&getConsistency, "consistency", "l", "Linearizable(l) or Serializable(s)")
@@ -53,19 +68,37 @@ func NewGetCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&getKeysOnly, "keys-only", false, "Get only the keys")
 	cmd.Flags().BoolVar(&getCountOnly, "count-only", false, "Get only the count")
 	cmd.Flags().BoolVar(&printValueOnly, "print-value-only", false, `Only write values when using the "simple" output format`)
+	cmd.Flags().BoolVar(&getShowDeleted, "show-deleted", false, "With --keys-only and --rev, mark the keys that have been deleted since that revision")
+	cmd.Flags().BoolVar(&getStream, "stream", false, "Write the keys as they are read, one page at a time, instead of reading the whole range first")
+	cmd.Flags().Int64Var(&getStreamPage, "stream-page-size", 1000, "Number of keys read per request with --stream")
+	cmd.Flags().StringVar(&getKeysFile, "keys-from-file", "", "Get the keys listed in the given file, one per line, at the same revision ('-' reads standard input)")
 	return cmd
 }
 
 // getCommandFunc executes the "get" command.
 func getCommandFunc(cmd *cobra.Command, args []string) {
+	if getKeysFile != "" {
+		getKeysFromFile(cmd, args)
+		return
+	}
 	key, opts := getGetOp(args)
+	c := mustClientFromCmd(cmd)
+	if getStream {
+		getStreamRange(cmd, c, key, opts)
+		return
+	}
 	ctx, cancel := commandCtx(cmd)
-	resp, err := mustClientFromCmd(cmd).Get(ctx, key, opts...)
+	resp, err := c.Get(ctx, key, opts...)
 	cancel()
 	if err != nil {
 		ExitWithError(ExitError, err)
 	}
 
+	if getShowDeleted {
+		display.GetDeleted(*resp)
+		return
+	}
+
 	if getCountOnly {
 		if _, fields := display.(*fieldsPrinter); !fields {
 			ExitWithError(ExitBadArgs, fmt.Errorf("--count-only is only for `--write-out=fields`"))
@@ -95,6 +128,19 @@ func getGetOp(args []string) (string, []clientv3.OpOption) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("`--keys-only` and `--count-only` cannot be set at the same time, choose one"))
 	}
 
+	if getShowDeleted && (!getKeysOnly || getRev <= 0) {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--show-deleted` requires `--keys-only` and `--rev`"))
+	}
+
+	if getStream {
+		if getLimit != 0 || getSortOrder != "" || getSortTarget != "" || getCountOnly || getShowDeleted {
+			ExitWithError(ExitBadArgs, fmt.Errorf("`--stream` cannot be combined with `--limit`, `--order`, `--sort-by`, `--count-only` or `--show-deleted`"))
+		}
+		if getStreamPage <= 0 {
+			ExitWithError(ExitBadArgs, fmt.Errorf("`--stream-page-size` must be positive"))
+		}
+	}
+
 	opts := []clientv3.OpOption{}
 	switch getConsistency {
 	case "s":
@@ -116,6 +162,9 @@ func getGetOp(args []string) (string, []clientv3.OpOption) {
 	if getRev > 0 {
 		opts = append(opts, clientv3.WithRev(getRev))
 	}
+	if getShowDeleted {
+		opts = append(opts, clientv3.WithShowDeleted())
+	}
 
 	sortByOrder := clientv3.SortNone
 	sortOrder := strings.ToUpper(getSortOrder)
@@ -177,3 +226,148 @@ func getGetOp(args []string) (string, []clientv3.OpOption) {
 
 	return key, opts
 }
+
+// getStreamRange prints the range of the get operation page by page, all
+// pages being read at the revision of the first one, so that ranges larger
+// than the memory of the client can be exported consistently.
+func getStreamRange(cmd *cobra.Command, c *clientv3.Client, key string, opts []clientv3.OpOption) {
+	dp, simple := display.(*simplePrinter)
+	if !simple {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--stream is only for `--write-out=simple`"))
+	}
+	if printValueOnly {
+		dp.valueOnly = true
+	}
+
+	op := clientv3.OpGet(key, opts...)
+	var extra []clientv3.OpOption
+	if getConsistency == "s" {
+		extra = append(extra, clientv3.WithSerializable())
+	}
+	if getKeysOnly {
+		extra = append(extra, clientv3.WithKeysOnly())
+	}
+	_, err := rangeAll(cmd, c, string(op.KeyBytes()), string(op.RangeBytes()), getRev, getStreamPage, func(kv *mvccpb.KeyValue) {
+		printKV(dp.isHex, dp.valueOnly, kv)
+	}, extra...)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+}
+
+// getKeysResponse is the result of getting a list of keys at one revision.
+type getKeysResponse struct {
+	Revision int64              `json:"revision"`
+	Kvs      []*mvccpb.KeyValue `json:"kvs,omitempty"`
+	Missing  [][]byte           `json:"missing,omitempty"`
+	// Keys are the keys in the order they were requested.
+	Keys [][]byte `json:"-"`
+}
+
+// getKeysFromFile gets the keys listed in the file of "--keys-from-file".
+func getKeysFromFile(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--keys-from-file` does not accept key arguments"))
+	}
+	if getPrefix || getFromKey || getLimit != 0 || getSortOrder != "" || getSortTarget != "" || getCountOnly || getShowDeleted || getStream {
+		ExitWithError(ExitBadArgs, fmt.Errorf("`--keys-from-file` cannot be combined with `--prefix`, `--from-key`, `--limit`, `--order`, `--sort-by`, `--count-only`, `--show-deleted` or `--stream`"))
+	}
+
+	var opts []clientv3.OpOption
+	switch getConsistency {
+	case "s":
+		opts = append(opts, clientv3.WithSerializable())
+	case "l":
+	default:
+		ExitWithError(ExitBadFeature, fmt.Errorf("unknown consistency flag %q", getConsistency))
+	}
+	if getKeysOnly {
+		opts = append(opts, clientv3.WithKeysOnly())
+	}
+	if printValueOnly {
+		dp, simple := display.(*simplePrinter)
+		if !simple {
+			ExitWithError(ExitBadArgs, fmt.Errorf("print-value-only is only for `--write-out=simple`"))
+		}
+		dp.valueOnly = true
+	}
+
+	r := io.Reader(os.Stdin)
+	if getKeysFile != "-" {
+		f, err := os.Open(getKeysFile)
+		if err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	keys, err := readKeyList(r)
+	if err != nil {
+		ExitWithError(ExitInvalidInput, err)
+	}
+
+	c := mustClientFromCmd(cmd)
+	resp, err := getKeys(cmd, c, keys, getRev, opts...)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.GetKeys(*resp)
+}
+
+// readKeyList reads one key per line, skipping empty lines.
+func readKeyList(r io.Reader) ([]string, error) {
+	var keys []string
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if k := strings.TrimRight(line, "\r\n"); k != "" {
+			keys = append(keys, k)
+		}
+		if err == io.EOF {
+			return keys, nil
+		}
+	}
+}
+
+// getKeys gets the keys in batches of read-only transactions, all at the
+// revision of the first batch unless rev is given.
+func getKeys(cmd *cobra.Command, c *clientv3.Client, keys []string, rev int64, opts ...clientv3.OpOption) (*getKeysResponse, error) {
+	resp := &getKeysResponse{Revision: rev}
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > getKeysBatchSize {
+			batch = batch[:getKeysBatchSize]
+		}
+		keys = keys[len(batch):]
+
+		bopts := opts
+		if resp.Revision > 0 {
+			bopts = append(append([]clientv3.OpOption{}, opts...), clientv3.WithRev(resp.Revision))
+		}
+		ops := make([]clientv3.Op, len(batch))
+		for i, k := range batch {
+			ops[i] = clientv3.OpGet(k, bopts...)
+		}
+		ctx, cancel := commandCtx(cmd)
+		tresp, err := c.Txn(ctx).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Revision == 0 {
+			resp.Revision = tresp.Header.Revision
+		}
+		for i, r := range tresp.Responses {
+			resp.Keys = append(resp.Keys, []byte(batch[i]))
+			if kvs := r.GetResponseRange().Kvs; len(kvs) != 0 {
+				resp.Kvs = append(resp.Kvs, kvs[0])
+			} else {
+				resp.Missing = append(resp.Missing, []byte(batch[i]))
+			}
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKeyList(t *testing.T) {
+	tt := []struct {
+		in   string
+		keys []string
+	}{
+		{in: "", keys: nil},
+		{in: "foo\nbar\n", keys: []string{"foo", "bar"}},
+		{in: "foo\r\n\r\n\nbar", keys: []string{"foo", "bar"}},
+		{in: " key with spaces \n", keys: []string{" key with spaces "}},
+	}
+	for i, tc := range tt {
+		keys, err := readKeyList(strings.NewReader(tc.in))
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		if !reflect.DeepEqual(keys, tc.keys) {
+			t.Errorf("#%d: expected %q, got %q", i, tc.keys, keys)
+		}
+	}
+}
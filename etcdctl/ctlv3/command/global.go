@@ -57,6 +57,10 @@ type GlobalFlags struct {
 	User     string
 	Password string
 
+	Context      string
+	ConfigFile   string
+	CompleteKeys bool
+
 	Debug bool
 }
 
@@ -83,6 +87,10 @@ type discoveryCfg struct {
 
 var display printer = &simplePrinter{}
 
+// passwordPromptDisabled makes a missing password an error instead of
+// asking for it.
+var passwordPromptDisabled bool
+
 func initDisplayFromCmd(cmd *cobra.Command) {
 	isHex, err := cmd.Flags().GetBool("hex")
 	if err != nil {
@@ -112,7 +120,10 @@ func (*discardValue) String() string   { return "" }
 func (*discardValue) Set(string) error { return nil }
 func (*discardValue) Type() string     { return "" }
 
-func clientConfigFromCmd(cmd *cobra.Command) *clientConfig {
+// InitGlobalFlags sets the global flags not given on the command line from
+// the environment variables, then from the selected context. It runs before
+// every command, so that all of them honor the same settings.
+func InitGlobalFlags(cmd *cobra.Command) {
 	lg, err := zap.NewProduction()
 	if err != nil {
 		ExitWithError(ExitError, err)
@@ -125,7 +136,13 @@ func clientConfigFromCmd(cmd *cobra.Command) *clientConfig {
 		fs.AddFlag(&pflag.Flag{Name: "watch-range-end", Value: &discardValue{}})
 	}
 	flags.SetPflagsFromEnv(lg, "ETCDCTL", fs)
+	if err = applyContext(fs); err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+}
 
+func clientConfigFromCmd(cmd *cobra.Command) *clientConfig {
+	fs := cmd.InheritedFlags()
 	debug, err := cmd.Flags().GetBool("debug")
 	if err != nil {
 		ExitWithError(ExitError, err)
@@ -188,6 +205,16 @@ func (cc *clientConfig) mustClient() *clientv3.Client {
 	return client
 }
 
+// endpointClient returns a client connected only to the given endpoint,
+// reusing the rest of the configuration.
+func (cc *clientConfig) endpointClient(ep string) (*clientv3.Client, error) {
+	cfg, err := newClientCfg([]string{ep}, cc.dialTimeout, cc.keepAliveTime, cc.keepAliveTimeout, cc.scfg, cc.acfg)
+	if err != nil {
+		return nil, err
+	}
+	return clientv3.New(*cfg)
+}
+
 func newClientCfg(endpoints []string, dialTimeout, keepAliveTime, keepAliveTimeout time.Duration, scfg *secureCfg, acfg *authCfg) (*clientv3.Config, error) {
 	// set tls if any one tls option set
 	var cfgtls *transport.TLSInfo
@@ -363,6 +390,9 @@ func authCfgFromCmd(cmd *cobra.Command) *authCfg {
 	if passwordFlag == "" {
 		splitted := strings.SplitN(userFlag, ":", 2)
 		if len(splitted) < 2 {
+			if passwordPromptDisabled {
+				ExitWithError(ExitBadArgs, errors.New("password is required"))
+			}
 			cfg.username = userFlag
 			cfg.password, err = speakeasy.Ask("Password: ")
 			if err != nil {
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 
 	v3 "go.etcd.io/etcd/client/v3"
 
@@ -36,6 +37,8 @@ func NewLeaseCommand() *cobra.Command {
 	lc.AddCommand(NewLeaseTimeToLiveCommand())
 	lc.AddCommand(NewLeaseListCommand())
 	lc.AddCommand(NewLeaseKeepAliveCommand())
+	lc.AddCommand(NewLeaseAttachCommand())
+	lc.AddCommand(NewLeaseDetachCommand())
 
 	return lc
 }
@@ -72,6 +75,8 @@ func leaseGrantCommandFunc(cmd *cobra.Command, args []string) {
 	display.Grant(*resp)
 }
 
+var leaseRevokeDryRun bool
+
 // NewLeaseRevokeCommand returns the cobra command for "lease revoke".
 func NewLeaseRevokeCommand() *cobra.Command {
 	lc := &cobra.Command{
@@ -80,6 +85,7 @@ func NewLeaseRevokeCommand() *cobra.Command {
 
 		Run: leaseRevokeCommandFunc,
 	}
+	lc.Flags().BoolVar(&leaseRevokeDryRun, "dry-run", false, "show the keys that would be deleted without revoking the lease")
 
 	return lc
 }
@@ -91,6 +97,10 @@ func leaseRevokeCommandFunc(cmd *cobra.Command, args []string) {
 	}
 
 	id := leaseFromArgs(args[0])
+	if leaseRevokeDryRun {
+		leaseRevokeDryRunFunc(cmd, id)
+		return
+	}
 	ctx, cancel := commandCtx(cmd)
 	resp, err := mustClientFromCmd(cmd).Revoke(ctx, id)
 	cancel()
@@ -100,6 +110,24 @@ func leaseRevokeCommandFunc(cmd *cobra.Command, args []string) {
 	display.Revoke(id, *resp)
 }
 
+// leaseRevokeDryRunFunc verifies that the lease exists and reports
+// the keys that would be deleted along with it.
+func leaseRevokeDryRunFunc(cmd *cobra.Command, id v3.LeaseID) {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).TimeToLive(ctx, id, v3.WithAttachedKeys())
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if resp.TTL == -1 {
+		ExitWithError(ExitError, fmt.Errorf("lease %016x already expired", id))
+	}
+	fmt.Printf("dry-run: would revoke lease %016x and delete %d attached key(s)\n", id, len(resp.Keys))
+	for _, k := range resp.Keys {
+		fmt.Println(string(k))
+	}
+}
+
 var timeToLiveKeys bool
 
 // NewLeaseTimeToLiveCommand returns the cobra command for "lease timetolive".
@@ -131,23 +159,95 @@ func leaseTimeToLiveCommandFunc(cmd *cobra.Command, args []string) {
 	display.TimeToLive(*resp, timeToLiveKeys)
 }
 
+var (
+	leaseListTTL         bool
+	leaseListKeys        bool
+	leaseListConcurrency int
+	leaseListKeySample   int
+)
+
 // NewLeaseListCommand returns the cobra command for "lease list".
 func NewLeaseListCommand() *cobra.Command {
 	lc := &cobra.Command{
-		Use:   "list",
+		Use:   "list [options]",
 		Short: "List all active leases",
 		Run:   leaseListCommandFunc,
 	}
+	lc.Flags().BoolVar(&leaseListTTL, "ttl", false, "Get the granted and remaining TTL of each lease")
+	lc.Flags().BoolVar(&leaseListKeys, "keys", false, "Get the keys attached to each lease")
+	lc.Flags().IntVar(&leaseListConcurrency, "concurrency", 10, "Maximum number of concurrent lease requests used by --ttl and --keys")
+	lc.Flags().IntVar(&leaseListKeySample, "key-sample", 3, "Maximum number of attached keys printed per lease with --keys")
 	return lc
 }
 
+// leaseInfo is the time-to-live information of a lease listed by
+// "lease list --ttl --keys".
+type leaseInfo struct {
+	ID         v3.LeaseID `json:"id"`
+	GrantedTTL int64      `json:"granted_ttl"`
+	TTL        int64      `json:"ttl"`
+	Keys       int        `json:"keys"`
+	KeySample  []string   `json:"key_sample,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
 // leaseListCommandFunc executes the "lease list" command.
 func leaseListCommandFunc(cmd *cobra.Command, args []string) {
-	resp, rerr := mustClientFromCmd(cmd).Leases(context.TODO())
+	if leaseListConcurrency < 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--concurrency must be at least 1"))
+	}
+	c := mustClientFromCmd(cmd)
+	resp, rerr := c.Leases(context.TODO())
 	if rerr != nil {
 		ExitWithError(ExitBadConnection, rerr)
 	}
-	display.Leases(*resp)
+	if !leaseListTTL && !leaseListKeys {
+		display.Leases(*resp)
+		return
+	}
+
+	infos := make([]leaseInfo, len(resp.Leases))
+	sem := make(chan struct{}, leaseListConcurrency)
+	var wg sync.WaitGroup
+	for i, l := range resp.Leases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id v3.LeaseID) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			infos[i] = getLeaseInfo(cmd, c, id)
+		}(i, l.ID)
+	}
+	wg.Wait()
+	display.LeaseInfos(infos, leaseListKeys)
+}
+
+func getLeaseInfo(cmd *cobra.Command, c *v3.Client, id v3.LeaseID) leaseInfo {
+	var opts []v3.LeaseOption
+	if leaseListKeys {
+		opts = append(opts, v3.WithAttachedKeys())
+	}
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.TimeToLive(ctx, id, opts...)
+	cancel()
+	return newLeaseInfo(id, resp, err, leaseListKeySample)
+}
+
+// newLeaseInfo returns the information of the lease from its time-to-live
+// response, with at most sample of its attached keys.
+func newLeaseInfo(id v3.LeaseID, resp *v3.LeaseTimeToLiveResponse, err error, sample int) leaseInfo {
+	info := leaseInfo{ID: id}
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.GrantedTTL, info.TTL, info.Keys = resp.GrantedTTL, resp.TTL, len(resp.Keys)
+	for i := 0; i < len(resp.Keys) && i < sample; i++ {
+		info.KeySample = append(info.KeySample, string(resp.Keys[i]))
+	}
+	return info
 }
 
 var (
@@ -198,6 +298,69 @@ func leaseKeepAliveCommandFunc(cmd *cobra.Command, args []string) {
 	}
 }
 
+// NewLeaseAttachCommand returns the cobra command for "lease attach".
+func NewLeaseAttachCommand() *cobra.Command {
+	lc := &cobra.Command{
+		Use:   "attach <leaseID> <key>",
+		Short: "Attaches an existing key to a lease, keeping its value",
+
+		Run: leaseAttachCommandFunc,
+	}
+
+	return lc
+}
+
+// leaseAttachCommandFunc executes the "lease attach" command.
+func leaseAttachCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("lease attach command needs lease ID and key as arguments"))
+	}
+
+	id := leaseFromArgs(args[0])
+	setKeyLease(cmd, args[1], id)
+	fmt.Printf("key %q attached to lease %016x\n", args[1], id)
+}
+
+// NewLeaseDetachCommand returns the cobra command for "lease detach".
+func NewLeaseDetachCommand() *cobra.Command {
+	lc := &cobra.Command{
+		Use:   "detach <key>",
+		Short: "Detaches an existing key from its lease, keeping its value",
+
+		Run: leaseDetachCommandFunc,
+	}
+
+	return lc
+}
+
+// leaseDetachCommandFunc executes the "lease detach" command.
+func leaseDetachCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("lease detach command needs key as argument"))
+	}
+
+	setKeyLease(cmd, args[0], v3.NoLease)
+	fmt.Printf("key %q detached from its lease\n", args[0])
+}
+
+// setKeyLease rebinds an existing key to the given lease in a single
+// transaction. The value is left untouched by the server, so concurrent
+// writers cannot be overwritten with a stale value.
+func setKeyLease(cmd *cobra.Command, key string, id v3.LeaseID) {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).Txn(ctx).
+		If(v3.Compare(v3.CreateRevision(key), ">", 0)).
+		Then(v3.OpPut(key, "", v3.WithIgnoreValue(), v3.WithLease(id))).
+		Commit()
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if !resp.Succeeded {
+		ExitWithError(ExitError, fmt.Errorf("key %q not found", key))
+	}
+}
+
 func leaseFromArgs(arg string) v3.LeaseID {
 	id, err := strconv.ParseInt(arg, 16, 64)
 	if err != nil {
@@ -0,0 +1,88 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestNewLeaseInfo(t *testing.T) {
+	resp := &v3.LeaseTimeToLiveResponse{
+		GrantedTTL: 60,
+		TTL:        42,
+		Keys:       [][]byte{[]byte("a"), []byte("b"), []byte("c")},
+	}
+	tt := []struct {
+		resp   *v3.LeaseTimeToLiveResponse
+		err    error
+		sample int
+
+		info leaseInfo
+	}{
+		{resp, nil, 3, leaseInfo{ID: 1, GrantedTTL: 60, TTL: 42, Keys: 3, KeySample: []string{"a", "b", "c"}}},
+		{resp, nil, 2, leaseInfo{ID: 1, GrantedTTL: 60, TTL: 42, Keys: 3, KeySample: []string{"a", "b"}}},
+		{resp, nil, 0, leaseInfo{ID: 1, GrantedTTL: 60, TTL: 42, Keys: 3}},
+		{&v3.LeaseTimeToLiveResponse{TTL: -1}, nil, 3, leaseInfo{ID: 1, TTL: -1}},
+		{nil, errors.New("timeout"), 3, leaseInfo{ID: 1, Error: "timeout"}},
+	}
+	for i, ts := range tt {
+		if info := newLeaseInfo(1, ts.resp, ts.err, ts.sample); !reflect.DeepEqual(info, ts.info) {
+			t.Errorf("#%d: expected %+v, got %+v", i, ts.info, info)
+		}
+	}
+}
+
+func TestMakeLeaseInfosTable(t *testing.T) {
+	infos := []leaseInfo{
+		{ID: 0x10, GrantedTTL: 60, TTL: 42, Keys: 3, KeySample: []string{"a", "b"}},
+		{ID: 0x20, Error: "timeout"},
+	}
+	tt := []struct {
+		keys bool
+
+		hdr  []string
+		rows [][]string
+	}{
+		{
+			false,
+			[]string{"ID", "granted TTL", "remaining TTL", "error"},
+			[][]string{
+				{"0000000000000010", "60", "42", ""},
+				{"0000000000000020", "0", "0", "timeout"},
+			},
+		},
+		{
+			true,
+			[]string{"ID", "granted TTL", "remaining TTL", "attached keys", "key sample", "error"},
+			[][]string{
+				{"0000000000000010", "60", "42", "3", "a,b", ""},
+				{"0000000000000020", "0", "0", "0", "", "timeout"},
+			},
+		},
+	}
+	for i, ts := range tt {
+		hdr, rows := makeLeaseInfosTable(infos, ts.keys)
+		if !reflect.DeepEqual(hdr, ts.hdr) {
+			t.Errorf("#%d: expected header %v, got %v", i, ts.hdr, hdr)
+		}
+		if !reflect.DeepEqual(rows, ts.rows) {
+			t.Errorf("#%d: expected rows %v, got %v", i, ts.rows, rows)
+		}
+	}
+}
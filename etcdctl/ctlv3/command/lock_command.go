@@ -22,14 +22,30 @@ import (
 	"os/exec"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"go.etcd.io/etcd/client/v3"
+	v3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 
 	"github.com/spf13/cobra"
 )
 
-var lockTTL = 10
+var (
+	lockTTL           = 10
+	lockTimeout       int
+	lockRenewInterval int
+)
+
+var errLockTimeout = errors.New("timed out waiting for lock")
+
+// lockInfo describes a held lock and the session lease keeping it alive.
+type lockInfo struct {
+	Key      string     `json:"key"`
+	Lease    v3.LeaseID `json:"lease"`
+	TTL      int64      `json:"ttl"`
+	Revision int64      `json:"revision"`
+	Waiters  int64      `json:"waiters"`
+}
 
 // NewLockCommand returns the cobra command for "lock".
 func NewLockCommand() *cobra.Command {
@@ -39,20 +55,108 @@ func NewLockCommand() *cobra.Command {
 		Run:   lockCommandFunc,
 	}
 	c.Flags().IntVarP(&lockTTL, "ttl", "", lockTTL, "timeout for session")
+	c.Flags().IntVarP(&lockTimeout, "timeout", "", 0, "give up acquiring the lock after the given seconds (0 waits forever)")
+	c.Flags().IntVarP(&lockRenewInterval, "renew-interval", "", 0, "interval in seconds between explicit session lease renewals (0 relies on the automatic keep-alive)")
+
+	c.AddCommand(NewLockTryCommand())
+	c.AddCommand(NewLockStatusCommand())
+	return c
+}
+
+// NewLockTryCommand returns the cobra command for "lock try".
+func NewLockTryCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "try <lockname> [exec-command arg1 arg2 ...]",
+		Short: "Acquires a named lock if it is not held, without waiting",
+		Run:   lockTryCommandFunc,
+	}
+	c.Flags().IntVarP(&lockTTL, "ttl", "", lockTTL, "timeout for session")
+	c.Flags().IntVarP(&lockRenewInterval, "renew-interval", "", 0, "interval in seconds between explicit session lease renewals (0 relies on the automatic keep-alive)")
 	return c
 }
 
+// NewLockStatusCommand returns the cobra command for "lock status".
+func NewLockStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <lockname>",
+		Short: "Shows the current holder of a named lock",
+		Run:   lockStatusCommandFunc,
+	}
+}
+
 func lockCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
 		ExitWithError(ExitBadArgs, errors.New("lock takes a lock name argument and an optional command to execute"))
 	}
 	c := mustClientFromCmd(cmd)
-	if err := lockUntilSignal(c, args[0], args[1:]); err != nil {
+	if err := lockUntilSignal(c, args[0], args[1:], false); err != nil {
+		ExitWithError(ExitError, err)
+	}
+}
+
+// lockTryCommandFunc executes the "lock try" command.
+func lockTryCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		ExitWithError(ExitBadArgs, errors.New("lock try takes a lock name argument and an optional command to execute"))
+	}
+	c := mustClientFromCmd(cmd)
+	if err := lockUntilSignal(c, args[0], args[1:], true); err != nil {
+		if err == concurrency.ErrLocked {
+			ExitWithError(ExitLocked, fmt.Errorf("lock %q is held by another session", args[0]))
+		}
+		ExitWithError(ExitError, err)
+	}
+}
+
+// lockStatusCommandFunc executes the "lock status" command.
+func lockStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("lock status takes a lock name argument"))
+	}
+	c := mustClientFromCmd(cmd)
+
+	// the oldest key under the lock prefix is the holder; the count
+	// covers the whole prefix, so the rest are waiters
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Get(ctx, args[0]+"/", v3.WithFirstCreate()...)
+	if err != nil {
+		cancel()
+		ExitWithError(ExitError, err)
+	}
+	l, err := lockHolder(args[0], resp)
+	if err != nil {
+		cancel()
+		ExitWithError(ExitError, err)
+	}
+	ttl, err := c.TimeToLive(ctx, l.Lease)
+	cancel()
+	if err != nil {
 		ExitWithError(ExitError, err)
 	}
+	l.TTL = ttl.TTL
+	display.Lock(l)
+}
+
+// lockHolder returns the holder of the lock from the oldest key under the
+// lock prefix, with the count of the keys under the prefix.
+func lockHolder(lockname string, resp *v3.GetResponse) (lockInfo, error) {
+	if len(resp.Kvs) == 0 {
+		return lockInfo{}, fmt.Errorf("lock %q is not held", lockname)
+	}
+	holder := resp.Kvs[0]
+	return lockInfo{
+		Key:      string(holder.Key),
+		Lease:    v3.LeaseID(holder.Lease),
+		Revision: holder.CreateRevision,
+		Waiters:  resp.Count - 1,
+	}, nil
 }
 
-func lockUntilSignal(c *clientv3.Client, lockname string, cmdArgs []string) error {
+func lockUntilSignal(c *v3.Client, lockname string, cmdArgs []string, try bool) error {
+	if err := checkLockOptions(lockTTL, lockTimeout, lockRenewInterval); err != nil {
+		return err
+	}
+
 	s, err := concurrency.NewSession(c, concurrency.WithTTL(lockTTL))
 	if err != nil {
 		return err
@@ -60,6 +164,7 @@ func lockUntilSignal(c *clientv3.Client, lockname string, cmdArgs []string) erro
 
 	m := concurrency.NewMutex(s, lockname)
 	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
 
 	// unlock in case of ordinary shutdown
 	donec := make(chan struct{})
@@ -71,10 +176,24 @@ func lockUntilSignal(c *clientv3.Client, lockname string, cmdArgs []string) erro
 		close(donec)
 	}()
 
-	if err := m.Lock(ctx); err != nil {
+	if try {
+		if err := m.TryLock(ctx); err != nil {
+			s.Close()
+			return err
+		}
+	} else if err := lockWait(ctx, m); err != nil {
+		if err == errLockTimeout {
+			// release the session lease right away instead of waiting for it to expire
+			s.Close()
+			return errLockTimeout
+		}
 		return err
 	}
 
+	if lockRenewInterval > 0 {
+		go renewLockSession(ctx, c, s.Lease(), time.Duration(lockRenewInterval)*time.Second)
+	}
+
 	if len(cmdArgs) > 0 {
 		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
 		cmd.Env = append(environLockResponse(m), os.Environ()...)
@@ -94,7 +213,21 @@ func lockUntilSignal(c *clientv3.Client, lockname string, cmdArgs []string) erro
 	if len(k.Kvs) == 0 {
 		return errors.New("lock lost on init")
 	}
-	display.Get(*k)
+	ttl, terr := c.TimeToLive(ctx, s.Lease())
+	if terr != nil {
+		return terr
+	}
+	w, werr := c.Get(ctx, lockname+"/", v3.WithPrefix(), v3.WithCountOnly())
+	if werr != nil {
+		return werr
+	}
+	display.Lock(lockInfo{
+		Key:      m.Key(),
+		Lease:    s.Lease(),
+		TTL:      ttl.TTL,
+		Revision: k.Kvs[0].CreateRevision,
+		Waiters:  w.Count - 1,
+	})
 
 	select {
 	case <-donec:
@@ -105,9 +238,54 @@ func lockUntilSignal(c *clientv3.Client, lockname string, cmdArgs []string) erro
 	return errors.New("session expired")
 }
 
+// checkLockOptions checks the lock timeout and the renew interval of a
+// session of the given TTL, all in seconds.
+func checkLockOptions(ttl, timeout, renewInterval int) error {
+	if timeout < 0 {
+		return fmt.Errorf("lock timeout must not be negative, got %d", timeout)
+	}
+	if renewInterval < 0 || (renewInterval > 0 && renewInterval >= ttl) {
+		return fmt.Errorf("lock renew interval must be between 0 and the session TTL (%d), got %d", ttl, renewInterval)
+	}
+	return nil
+}
+
 func environLockResponse(m *concurrency.Mutex) []string {
 	return []string{
 		"ETCD_LOCK_KEY=" + m.Key(),
 		fmt.Sprintf("ETCD_LOCK_REV=%d", m.Header().Revision),
 	}
 }
+
+// lockWait blocks until the mutex is acquired, giving up after the
+// configured lock timeout.
+func lockWait(ctx context.Context, m *concurrency.Mutex) error {
+	if lockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(lockTimeout)*time.Second)
+		defer cancel()
+	}
+	err := m.Lock(ctx)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return errLockTimeout
+	}
+	return err
+}
+
+// renewLockSession refreshes the session lease every interval until ctx is
+// canceled. Failures are reported but not fatal; an expired session is
+// detected through the session's done channel.
+func renewLockSession(ctx context.Context, c *v3.Client, id v3.LeaseID, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if _, err := c.KeepAliveOnce(ctx, id); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "failed to renew lock lease %016x (%v)\n", id, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestCheckLockOptions(t *testing.T) {
+	tt := []struct {
+		ttl, timeout, renewInterval int
+
+		ok bool
+	}{
+		{10, 0, 0, true},
+		{10, 5, 0, true},
+		{10, 0, 5, true},
+		{10, 0, 9, true},
+		{10, -1, 0, false},
+		{10, 0, -1, false},
+		{10, 0, 10, false},
+		{10, 0, 15, false},
+	}
+	for i, ts := range tt {
+		if err := checkLockOptions(ts.ttl, ts.timeout, ts.renewInterval); (err == nil) != ts.ok {
+			t.Errorf("#%d: expected ok %v, got error %v", i, ts.ok, err)
+		}
+	}
+}
+
+func TestLockInfoJSON(t *testing.T) {
+	b, err := json.Marshal(lockInfo{Key: "lk/10", Lease: 0x10, TTL: 9, Revision: 5, Waiters: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"key":"lk/10","lease":16,"ttl":9,"revision":5,"waiters":2}`
+	if string(b) != exp {
+		t.Errorf("expected %s, got %s", exp, b)
+	}
+}
+
+func TestLockHolder(t *testing.T) {
+	holder := &mvccpb.KeyValue{Key: []byte("lk/10"), CreateRevision: 5, Lease: 0x10}
+	tt := []struct {
+		resp *v3.GetResponse
+
+		info lockInfo
+		ok   bool
+	}{
+		{&v3.GetResponse{}, lockInfo{}, false},
+		{&v3.GetResponse{Kvs: []*mvccpb.KeyValue{holder}, Count: 1}, lockInfo{Key: "lk/10", Lease: 0x10, Revision: 5}, true},
+		{&v3.GetResponse{Kvs: []*mvccpb.KeyValue{holder}, Count: 3}, lockInfo{Key: "lk/10", Lease: 0x10, Revision: 5, Waiters: 2}, true},
+	}
+	for i, ts := range tt {
+		info, err := lockHolder("lk", ts.resp)
+		if (err == nil) != ts.ok {
+			t.Errorf("#%d: expected ok %v, got error %v", i, ts.ok, err)
+		}
+		if !reflect.DeepEqual(info, ts.info) {
+			t.Errorf("#%d: expected %+v, got %+v", i, ts.info, info)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3maintenance"
+)
+
+var (
+	maintenanceCompactRetention string
+	maintenanceDefragCron       string
+	maintenanceDefragStagger    string
+	maintenanceClear            bool
+)
+
+// NewMaintenanceCommand returns the cobra command for "maintenance".
+func NewMaintenanceCommand() *cobra.Command {
+	mc := &cobra.Command{
+		Use:   "maintenance <subcommand>",
+		Short: "Scheduled maintenance related commands",
+	}
+
+	mc.AddCommand(newMaintenanceScheduleCommand())
+	mc.AddCommand(newMaintenanceStatusCommand())
+
+	return mc
+}
+
+func newMaintenanceScheduleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule [options]",
+		Short: "Sets the maintenance policy of the cluster",
+		Long: `Sets the policy followed by the maintenance scheduler of the members started
+with --experimental-enable-maintenance-scheduler. The leader compacts the
+revisions older than the compaction retention, and every member defragments
+its backend at the times of the cron expression, one stagger apart from each
+other so that the members are not all defragmenting at once.
+`,
+		Run: maintenanceScheduleCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&maintenanceCompactRetention, "compact-retention", "", "duration of the revisions to keep when compacting (e.g. 1h)")
+	cmd.Flags().StringVar(&maintenanceDefragCron, "defrag-cron", "", "cron expression of the defragmentations, in the local time of the members (e.g. \"0 3 * * *\")")
+	cmd.Flags().StringVar(&maintenanceDefragStagger, "defrag-stagger", "", "delay between the defragmentations of two members (default 10m)")
+	cmd.Flags().BoolVar(&maintenanceClear, "clear", false, "removes the maintenance policy")
+
+	return cmd
+}
+
+func newMaintenanceStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Shows the maintenance policy and the last runs of every member",
+		Run:   maintenanceStatusCommandFunc,
+	}
+}
+
+// maintenanceScheduleCommandFunc executes the "maintenance schedule" command.
+func maintenanceScheduleCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("maintenance schedule command does not accept any arguments"))
+	}
+	p := v3maintenance.Policy{
+		CompactRetention: maintenanceCompactRetention,
+		DefragCron:       maintenanceDefragCron,
+		DefragStagger:    maintenanceDefragStagger,
+	}
+	if maintenanceClear {
+		if p != (v3maintenance.Policy{}) {
+			ExitWithError(ExitBadArgs, errors.New("--clear cannot be combined with a policy"))
+		}
+	} else if err := p.Validate(); err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	if maintenanceClear {
+		if _, err := c.PolicyPut(ctx, clientv3.PolicyMaintenance, nil); err != nil {
+			ExitWithError(ExitError, err)
+		}
+		fmt.Println("Maintenance policy cleared")
+		return
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if _, err = c.PolicyPut(ctx, clientv3.PolicyMaintenance, b); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Println("Maintenance policy set")
+}
+
+// maintenanceStatus is the maintenance status of a member.
+type maintenanceStatus struct {
+	Endpoint string                `json:"endpoint"`
+	Status   *v3maintenance.Status `json:"status,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// maintenanceStatusCommandFunc executes the "maintenance status" command.
+func maintenanceStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("maintenance status command does not accept any arguments"))
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	mresp, err := c.MemberList(ctx)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	var sts []maintenanceStatus
+	for _, m := range mresp.Members {
+		if len(m.ClientURLs) == 0 {
+			continue
+		}
+		ep := m.ClientURLs[0]
+		ms := maintenanceStatus{Endpoint: ep}
+		resp, err := c.ScheduleStatus(ctx, ep)
+		if err != nil {
+			ms.Error = err.Error()
+		} else if !resp.Enabled {
+			ms.Error = "maintenance scheduler is not enabled (see the server flag --experimental-enable-maintenance-scheduler)"
+		} else {
+			ms.Status = scheduleStatus(resp)
+		}
+		sts = append(sts, ms)
+	}
+	display.MaintenanceStatus(sts)
+}
+
+// scheduleStatus converts the schedule status of a member to its times in UTC.
+func scheduleStatus(resp *clientv3.ScheduleStatusResponse) *v3maintenance.Status {
+	st := &v3maintenance.Status{PolicyError: resp.PolicyError}
+	if resp.Header != nil {
+		st.MemberID = resp.Header.MemberId
+	}
+	if resp.Policy != nil {
+		st.Policy = &v3maintenance.Policy{
+			CompactRetention: resp.Policy.CompactRetention,
+			DefragCron:       resp.Policy.DefragCron,
+			DefragStagger:    resp.Policy.DefragStagger,
+		}
+	}
+	if resp.NextDefrag != 0 {
+		next := time.Unix(0, resp.NextDefrag).UTC()
+		st.NextDefrag = &next
+	}
+	for _, r := range resp.Runs {
+		st.Runs = append(st.Runs, v3maintenance.Run{
+			Kind:     r.Kind,
+			Start:    time.Unix(0, r.Start).UTC(),
+			Duration: time.Duration(r.Duration),
+			Revision: r.Revision,
+			Error:    r.Error,
+		})
+	}
+	return st
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3maintenance"
+)
+
+func TestScheduleStatus(t *testing.T) {
+	t1 := time.Date(2020, 10, 1, 3, 0, 0, 0, time.UTC)
+	next := t1.Add(24 * time.Hour)
+	resp := &v3.ScheduleStatusResponse{
+		Header:     &pb.ResponseHeader{MemberId: 1},
+		Enabled:    true,
+		Policy:     &pb.MaintenancePolicy{DefragCron: "0 3 * * *"},
+		NextDefrag: next.UnixNano(),
+		Runs: []*pb.ScheduleRun{
+			{Kind: "compaction", Start: t1.UnixNano(), Duration: int64(time.Second), Revision: 10},
+			{Kind: "defrag", Start: t1.UnixNano(), Duration: int64(time.Minute), Error: "timeout"},
+		},
+	}
+	want := &v3maintenance.Status{
+		MemberID:   1,
+		Policy:     &v3maintenance.Policy{DefragCron: "0 3 * * *"},
+		NextDefrag: &next,
+		Runs: []v3maintenance.Run{
+			{Kind: "compaction", Start: t1, Duration: time.Second, Revision: 10},
+			{Kind: "defrag", Start: t1, Duration: time.Minute, Error: "timeout"},
+		},
+	}
+	if got := scheduleStatus(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("status = %+v, want %+v", got, want)
+	}
+
+	if got := scheduleStatus(&v3.ScheduleStatusResponse{Enabled: true, PolicyError: "bad"}); got.NextDefrag != nil || got.PolicyError != "bad" {
+		t.Errorf("unexpected status %+v", got)
+	}
+}
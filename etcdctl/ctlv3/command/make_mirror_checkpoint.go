@@ -0,0 +1,164 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// mirrorCheckpoint records how far make-mirror has synced the source
+// cluster, so that a restarted mirror only needs to catch up on the delta.
+type mirrorCheckpoint struct {
+	ClusterID  uint64 `json:"cluster_id"`
+	Prefix     string `json:"prefix"`
+	DestPrefix string `json:"dest_prefix"`
+	Revision   int64  `json:"revision"`
+}
+
+// matches returns an error if the checkpoint was saved by a mirror of a
+// different source cluster or key range than cur.
+func (cp *mirrorCheckpoint) matches(cur mirrorCheckpoint) error {
+	if cp.ClusterID != cur.ClusterID {
+		return fmt.Errorf("checkpoint is for cluster %x, but the source cluster is %x", cp.ClusterID, cur.ClusterID)
+	}
+	if cp.Prefix != cur.Prefix || cp.DestPrefix != cur.DestPrefix {
+		return fmt.Errorf("checkpoint is for prefix %q to %q, but mirroring %q to %q", cp.Prefix, cp.DestPrefix, cur.Prefix, cur.DestPrefix)
+	}
+	if cp.Revision > cur.Revision {
+		return fmt.Errorf("checkpoint revision %d is ahead of the source cluster revision %d", cp.Revision, cur.Revision)
+	}
+	return nil
+}
+
+// checkpointStore saves checkpoints to either a local file or a key on the
+// destination cluster.
+type checkpointStore struct {
+	file string
+	key  string
+	w    *mirrorWriter
+}
+
+// load returns the saved checkpoint, or nil if there is none.
+func (s *checkpointStore) load(ctx context.Context) (*mirrorCheckpoint, error) {
+	var data []byte
+	if s.file != "" {
+		b, err := ioutil.ReadFile(s.file)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	} else {
+		resp, err := s.w.dc.Get(ctx, s.key)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Kvs) == 0 {
+			return nil, nil
+		}
+		data = resp.Kvs[0].Value
+	}
+	cp := &mirrorCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint (%v)", err)
+	}
+	return cp, nil
+}
+
+func (s *checkpointStore) save(ctx context.Context, cp mirrorCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if s.file == "" {
+		// go through the writer so the write detector knows it is ours
+		return s.w.txn(ctx, []clientv3.Op{clientv3.OpPut(s.key, string(data))})
+	}
+	// write a temporary file and rename it so that a crash never leaves
+	// a partial checkpoint behind
+	f, err := ioutil.TempFile(filepath.Dir(s.file), filepath.Base(s.file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.file)
+}
+
+// run saves cp with the revision in synced every interval until ctx is
+// done. The returned function stops the checkpointing and saves the last
+// synced revision.
+func (s *checkpointStore) run(ctx context.Context, cp mirrorCheckpoint, synced *int64, interval time.Duration) func() error {
+	saved := int64(0)
+	checkpoint := func(ctx context.Context) error {
+		rev := atomic.LoadInt64(synced)
+		if rev == saved {
+			return nil
+		}
+		cp.Revision = rev
+		if err := s.save(ctx, cp); err != nil {
+			return err
+		}
+		saved = rev
+		return nil
+	}
+
+	stopc, donec := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(donec)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := checkpoint(ctx); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to save checkpoint (%v)\n", err)
+				}
+			case <-stopc:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(stopc)
+		<-donec
+		// ctx may already be canceled; the last checkpoint must still be saved
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return checkpoint(ctx)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointStoreFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &checkpointStore{file: filepath.Join(dir, "checkpoint")}
+	cp, err := s.load(context.Background())
+	if err != nil || cp != nil {
+		t.Fatalf("load() = %v, %v, want no checkpoint", cp, err)
+	}
+
+	want := mirrorCheckpoint{ClusterID: 0x1234, Prefix: "/a/", DestPrefix: "/b/", Revision: 42}
+	if err = s.save(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+	cp, err = s.load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cp != want {
+		t.Errorf("load() = %+v, want %+v", *cp, want)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d files, want only the checkpoint", len(files))
+	}
+}
+
+func TestMirrorCheckpointMatches(t *testing.T) {
+	cur := mirrorCheckpoint{ClusterID: 1, Prefix: "/a/", DestPrefix: "/b/", Revision: 100}
+	tt := []struct {
+		cp  mirrorCheckpoint
+		err bool
+	}{
+		{mirrorCheckpoint{ClusterID: 1, Prefix: "/a/", DestPrefix: "/b/", Revision: 50}, false},
+		{mirrorCheckpoint{ClusterID: 1, Prefix: "/a/", DestPrefix: "/b/", Revision: 100}, false},
+		{mirrorCheckpoint{ClusterID: 2, Prefix: "/a/", DestPrefix: "/b/", Revision: 50}, true},
+		{mirrorCheckpoint{ClusterID: 1, Prefix: "/c/", DestPrefix: "/b/", Revision: 50}, true},
+		{mirrorCheckpoint{ClusterID: 1, Prefix: "/a/", DestPrefix: "/a/", Revision: 50}, true},
+		{mirrorCheckpoint{ClusterID: 1, Prefix: "/a/", DestPrefix: "/b/", Revision: 101}, true},
+	}
+	for i, tc := range tt {
+		if err := tc.cp.matches(cur); (err != nil) != tc.err {
+			t.Errorf("#%d: err = %v, want error %v", i, err, tc.err)
+		}
+	}
+}
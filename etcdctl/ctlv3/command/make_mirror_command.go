@@ -15,12 +15,16 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/bgentry/speakeasy"
+	"os"
+	"regexp"
 	"strings"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
@@ -29,6 +33,7 @@ import (
 	"go.etcd.io/etcd/client/v3/mirror"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
 )
 
 var (
@@ -41,6 +46,21 @@ var (
 	mmuser         string
 	mmpassword     string
 	mmnodestprefix bool
+
+	mmexcludeprefixes []string
+	mmincluderegex    string
+	mmexcluderegex    string
+	mmvaluestrip      string
+	mmvaluetemplate   string
+	mmmaxtxnops       int
+	mmratelimit       int
+
+	mmdetectwrites string
+
+	mmcheckpointfile     string
+	mmcheckpointkey      string
+	mmcheckpointinterval time.Duration
+	mmresume             bool
 )
 
 // NewMakeMirrorCommand returns the cobra command for "makeMirror".
@@ -62,9 +82,169 @@ func NewMakeMirrorCommand() *cobra.Command {
 	c.Flags().StringVar(&mmuser, "dest-user", "", "Destination username[:password] for authentication (prompt if password is not supplied)")
 	c.Flags().StringVar(&mmpassword, "dest-password", "", "Destination password for authentication (if this option is used, --user option shouldn't include password)")
 
+	c.Flags().StringArrayVar(&mmexcludeprefixes, "exclude-prefix", nil, "Do not mirror keys under this prefix (repeatable)")
+	c.Flags().StringVar(&mmincluderegex, "include-regex", "", "Only mirror keys matching this regular expression")
+	c.Flags().StringVar(&mmexcluderegex, "exclude-regex", "", "Do not mirror keys matching this regular expression")
+	c.Flags().StringVar(&mmvaluestrip, "value-strip-prefix", "", "Strip this prefix from mirrored values")
+	c.Flags().StringVar(&mmvaluetemplate, "value-template", "", "Rewrite mirrored values with this Go template, given .Key, .DestKey and .Value")
+	c.Flags().IntVar(&mmmaxtxnops, "max-txn-ops", 128, "Maximum number of operations per transaction copying the key space to the destination cluster; the updates of a revision are always mirrored in one transaction")
+	c.Flags().IntVar(&mmratelimit, "rate-limit", 0, "Maximum number of operations per second on the destination cluster (0 is unlimited)")
+	c.Flags().StringVar(&mmcheckpointfile, "checkpoint-file", "", "Periodically save the last synced revision to this local file")
+	c.Flags().StringVar(&mmcheckpointkey, "checkpoint-key", "", "Periodically save the last synced revision to this key on the destination cluster")
+	c.Flags().DurationVar(&mmcheckpointinterval, "checkpoint-interval", 10*time.Second, "Interval between checkpoints of the last synced revision")
+	c.Flags().BoolVar(&mmresume, "resume", false, "Resume from the saved checkpoint instead of copying the whole key space again")
+	c.Flags().StringVar(&mmdetectwrites, "detect-writes", "", "Watch the destination range for writes not made by make-mirror, and either 'log' or 'abort' on them")
+
 	return c
 }
 
+// mirrorRules selects the keys to mirror and transforms their values.
+type mirrorRules struct {
+	excludePrefixes []string
+	include         *regexp.Regexp
+	exclude         *regexp.Regexp
+
+	valueStripPrefix string
+	valueTemplate    *template.Template
+}
+
+// mirrorValue is the data passed to the "--value-template" template.
+type mirrorValue struct {
+	Key     string
+	DestKey string
+	Value   string
+}
+
+func newMirrorRules(excludePrefixes []string, include, exclude, valueStripPrefix, valueTemplate string) (*mirrorRules, error) {
+	r := &mirrorRules{excludePrefixes: excludePrefixes, valueStripPrefix: valueStripPrefix}
+	var err error
+	if include != "" {
+		if r.include, err = regexp.Compile(include); err != nil {
+			return nil, fmt.Errorf("invalid --include-regex (%v)", err)
+		}
+	}
+	if exclude != "" {
+		if r.exclude, err = regexp.Compile(exclude); err != nil {
+			return nil, fmt.Errorf("invalid --exclude-regex (%v)", err)
+		}
+	}
+	if valueTemplate != "" {
+		if r.valueTemplate, err = template.New("value").Option("missingkey=error").Parse(valueTemplate); err != nil {
+			return nil, fmt.Errorf("invalid --value-template (%v)", err)
+		}
+	}
+	return r, nil
+}
+
+// match returns true if the source key should be mirrored.
+func (r *mirrorRules) match(key string) bool {
+	for _, p := range r.excludePrefixes {
+		if strings.HasPrefix(key, p) {
+			return false
+		}
+	}
+	if r.include != nil && !r.include.MatchString(key) {
+		return false
+	}
+	return r.exclude == nil || !r.exclude.MatchString(key)
+}
+
+// value returns the value to store at destKey for the source key-value.
+func (r *mirrorRules) value(key, destKey string, value []byte) (string, error) {
+	v := strings.TrimPrefix(string(value), r.valueStripPrefix)
+	if r.valueTemplate == nil {
+		return v, nil
+	}
+	var buf bytes.Buffer
+	if err := r.valueTemplate.Execute(&buf, mirrorValue{Key: key, DestKey: destKey, Value: v}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// mirrorWriter applies operations to the destination cluster at a bounded
+// rate, in transactions of bounded size when copying the key space and in
+// one transaction per revision when syncing the updates.
+type mirrorWriter struct {
+	dc      *clientv3.Client
+	maxOps  int
+	limiter *rate.Limiter
+
+	// detector, if set, is told the revisions of the mirror's own writes.
+	detector *writeDetector
+}
+
+// commit applies the operations in transactions of at most maxOps
+// operations.
+func (w *mirrorWriter) commit(ctx context.Context, ops []clientv3.Op) error {
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > w.maxOps {
+			n = w.maxOps
+		}
+		if err := w.wait(ctx, n); err != nil {
+			return err
+		}
+		if err := w.txn(ctx, ops[:n]); err != nil {
+			return err
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// commitRevision applies the operations of one source revision in a single
+// transaction, whatever their number, so that the destination never holds
+// part of a revision. The source cluster bounds the operations of a
+// revision by its --max-txn-ops, which the destination must allow too.
+func (w *mirrorWriter) commitRevision(ctx context.Context, ops []clientv3.Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := w.wait(ctx, len(ops)); err != nil {
+		return err
+	}
+	return w.txn(ctx, ops)
+}
+
+// wait waits until the rate limit allows n operations, which may be more
+// than its burst.
+func (w *mirrorWriter) wait(ctx context.Context, n int) error {
+	if w.limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		k := n
+		if b := w.limiter.Burst(); k > b {
+			k = b
+		}
+		if err := w.limiter.WaitN(ctx, k); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
+
+func (w *mirrorWriter) txn(ctx context.Context, ops []clientv3.Op) error {
+	if w.detector == nil {
+		_, err := w.dc.Txn(ctx).Then(ops...).Commit()
+		return err
+	}
+	// hold the detector until the revision is recorded so that the watch
+	// event of this write is not mistaken for a foreign one
+	w.detector.mu.Lock()
+	defer w.detector.mu.Unlock()
+	resp, err := w.dc.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if txnModified(resp) {
+		w.detector.revs = append(w.detector.revs, resp.Header.Revision)
+	}
+	return nil
+}
+
 func authDestCfg() *authCfg {
 	if mmuser == "" {
 		return nil
@@ -125,19 +305,18 @@ func makeMirrorCommandFunc(cmd *cobra.Command, args []string) {
 	ExitWithError(ExitError, err)
 }
 
-func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) error {
-	total := int64(0)
-
-	go func() {
-		for {
-			time.Sleep(30 * time.Second)
-			fmt.Println(atomic.LoadInt64(&total))
-		}
-	}()
-
-	s := mirror.NewSyncer(c, mmprefix, 0)
-
-	rc, errc := s.SyncBase(ctx)
+func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) (err error) {
+	rules, err := newMirrorRules(mmexcludeprefixes, mmincluderegex, mmexcluderegex, mmvaluestrip, mmvaluetemplate)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	if mmmaxtxnops < 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--max-txn-ops must be at least 1, got %d", mmmaxtxnops))
+	}
+	w := &mirrorWriter{dc: dc, maxOps: mmmaxtxnops}
+	if mmratelimit > 0 {
+		w.limiter = rate.NewLimiter(rate.Limit(mmratelimit), mmmaxtxnops)
+	}
 
 	// if destination prefix is specified and remove destination prefix is true return error
 	if mmnodestprefix && len(mmdestprefix) > 0 {
@@ -149,25 +328,92 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 		mmdestprefix = mmprefix
 	}
 
-	for r := range rc {
-		for _, kv := range r.Kvs {
-			_, err := dc.Put(ctx, modifyPrefix(string(kv.Key)), string(kv.Value))
-			if err != nil {
-				return err
-			}
-			atomic.AddInt64(&total, 1)
+	if mmdetectwrites != "" {
+		if mmdetectwrites != "log" && mmdetectwrites != "abort" {
+			ExitWithError(ExitBadArgs, fmt.Errorf("--detect-writes must be 'log' or 'abort', got %q", mmdetectwrites))
 		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		d := &writeDetector{abort: mmdetectwrites == "abort", cancel: cancel}
+		if err = d.start(ctx, dc, mmdestprefix); err != nil {
+			return err
+		}
+		w.detector = d
+		defer func() {
+			if derr := d.conflict(); derr != nil {
+				err = derr
+			}
+		}()
 	}
 
-	err := <-errc
+	var cps *checkpointStore
+	switch {
+	case mmcheckpointfile != "" && mmcheckpointkey != "":
+		ExitWithError(ExitBadArgs, errors.New("--checkpoint-file and --checkpoint-key cannot be set at the same time, choose one"))
+	case mmcheckpointfile != "" || mmcheckpointkey != "":
+		cps = &checkpointStore{file: mmcheckpointfile, key: mmcheckpointkey, w: w}
+	case mmresume:
+		ExitWithError(ExitBadArgs, errors.New("--resume requires --checkpoint-file or --checkpoint-key"))
+	}
+
+	// the revision the mirror starts from, and the source cluster it belongs to
+	resp, err := c.Get(ctx, "foo", clientv3.WithCountOnly())
 	if err != nil {
 		return err
 	}
+	cp := mirrorCheckpoint{ClusterID: resp.Header.ClusterId, Prefix: mmprefix, DestPrefix: mmdestprefix, Revision: resp.Header.Revision}
+	resumed := false
+	if mmresume {
+		saved, err := cps.load(ctx)
+		if err != nil {
+			return err
+		}
+		if saved == nil {
+			fmt.Fprintln(os.Stderr, "no checkpoint found; mirroring the whole key space")
+		} else {
+			if err = saved.matches(cp); err != nil {
+				ExitWithError(ExitBadArgs, err)
+			}
+			cp.Revision, resumed = saved.Revision, true
+		}
+	}
+
+	total := int64(0)
+
+	go func() {
+		for {
+			time.Sleep(30 * time.Second)
+			fmt.Println(atomic.LoadInt64(&total))
+		}
+	}()
+
+	s := mirror.NewSyncer(c, mmprefix, cp.Revision)
+
+	if !resumed {
+		if err = mirrorBase(ctx, s, rules, w, &total); err != nil {
+			return err
+		}
+	}
+
+	var synced int64
+	if cps != nil {
+		atomic.StoreInt64(&synced, cp.Revision)
+		ckpt := cps.run(ctx, cp, &synced, mmcheckpointinterval)
+		defer func() {
+			if cerr := ckpt(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}()
+	}
 
 	wc := s.SyncUpdates(ctx)
 
 	for wr := range wc {
 		if wr.CompactRevision != 0 {
+			if resumed {
+				return fmt.Errorf("checkpoint revision %d is compacted; mirror again without --resume", cp.Revision)
+			}
 			return rpctypes.ErrCompacted
 		}
 
@@ -177,16 +423,22 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 		for _, ev := range wr.Events {
 			nextRev := ev.Kv.ModRevision
 			if lastRev != 0 && nextRev > lastRev {
-				_, err := dc.Txn(ctx).Then(ops...).Commit()
-				if err != nil {
+				if err := w.commitRevision(ctx, ops); err != nil {
 					return err
 				}
 				ops = []clientv3.Op{}
 			}
 			lastRev = nextRev
+			if !rules.match(string(ev.Kv.Key)) {
+				continue
+			}
 			switch ev.Type {
 			case mvccpb.PUT:
-				ops = append(ops, clientv3.OpPut(modifyPrefix(string(ev.Kv.Key)), string(ev.Kv.Value)))
+				op, _, err := mirrorPut(rules, ev.Kv)
+				if err != nil {
+					return err
+				}
+				ops = append(ops, op)
 				atomic.AddInt64(&total, 1)
 			case mvccpb.DELETE:
 				ops = append(ops, clientv3.OpDelete(modifyPrefix(string(ev.Kv.Key))))
@@ -197,16 +449,66 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 		}
 
 		if len(ops) != 0 {
-			_, err := dc.Txn(ctx).Then(ops...).Commit()
-			if err != nil {
+			if err := w.commitRevision(ctx, ops); err != nil {
 				return err
 			}
 		}
+		if lastRev != 0 {
+			atomic.StoreInt64(&synced, lastRev)
+		}
 	}
 
 	return nil
 }
 
+// mirrorBase copies the key space at the syncer's revision to the
+// destination cluster.
+func mirrorBase(ctx context.Context, s mirror.Syncer, rules *mirrorRules, w *mirrorWriter, total *int64) error {
+	rc, errc := s.SyncBase(ctx)
+
+	ops := []clientv3.Op{}
+	for r := range rc {
+		for _, kv := range r.Kvs {
+			op, ok, err := mirrorPut(rules, kv)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			ops = append(ops, op)
+			if len(ops) == w.maxOps {
+				if err = w.commit(ctx, ops); err != nil {
+					return err
+				}
+				atomic.AddInt64(total, int64(len(ops)))
+				ops = ops[:0]
+			}
+		}
+	}
+	if err := w.commit(ctx, ops); err != nil {
+		return err
+	}
+	atomic.AddInt64(total, int64(len(ops)))
+
+	return <-errc
+}
+
+// mirrorPut returns the put of the key-value on the destination cluster, or
+// false if the key is not mirrored.
+func mirrorPut(rules *mirrorRules, kv *mvccpb.KeyValue) (clientv3.Op, bool, error) {
+	key := string(kv.Key)
+	if !rules.match(key) {
+		return clientv3.Op{}, false, nil
+	}
+	destKey := modifyPrefix(key)
+	v, err := rules.value(key, destKey, kv.Value)
+	if err != nil {
+		return clientv3.Op{}, false, fmt.Errorf("value template error on key %q (%v)", key, err)
+	}
+	return clientv3.OpPut(destKey, v), true, nil
+}
+
 func modifyPrefix(key string) string {
 	return strings.Replace(key, mmprefix, mmdestprefix, 1)
 }
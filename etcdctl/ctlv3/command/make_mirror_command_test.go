@@ -0,0 +1,124 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestMirrorRulesMatch(t *testing.T) {
+	r, err := newMirrorRules([]string{"/app/locks/"}, "^/app/", `\.tmp$`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		key   string
+		match bool
+	}{
+		{"/app/a", true},
+		{"/app/locks/a", false},
+		{"/app/a.tmp", false},
+		{"/other/a", false},
+	}
+	for i, tc := range tt {
+		if m := r.match(tc.key); m != tc.match {
+			t.Errorf("#%d: match(%q) = %v, want %v", i, tc.key, m, tc.match)
+		}
+	}
+}
+
+func TestMirrorRulesValue(t *testing.T) {
+	tt := []struct {
+		strip string
+		tmpl  string
+
+		value string
+		err   bool
+	}{
+		{value: "enc:v"},
+		{strip: "enc:", value: "v"},
+		{strip: "enc:", tmpl: "{{.Key}}->{{.DestKey}}={{.Value}}", value: "/a->/b/a=v"},
+		{tmpl: "{{.Missing}}", err: true},
+	}
+	for i, tc := range tt {
+		r, err := newMirrorRules(nil, "", "", tc.strip, tc.tmpl)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		v, err := r.value("/a", "/b/a", []byte("enc:v"))
+		if (err != nil) != tc.err {
+			t.Fatalf("#%d: err = %v, want error %v", i, err, tc.err)
+		}
+		if err == nil && v != tc.value {
+			t.Errorf("#%d: value = %q, want %q", i, v, tc.value)
+		}
+	}
+}
+
+func TestNewMirrorRulesInvalid(t *testing.T) {
+	if _, err := newMirrorRules(nil, "(", "", "", ""); err == nil {
+		t.Error("expected error on invalid --include-regex")
+	}
+	if _, err := newMirrorRules(nil, "", "", "", "{{"); err == nil {
+		t.Error("expected error on invalid --value-template")
+	}
+}
+
+func TestWriteDetectorOwn(t *testing.T) {
+	d := &writeDetector{revs: []int64{3, 5, 8}}
+	tt := []struct {
+		rev int64
+		own bool
+	}{
+		{2, false},
+		{3, true},
+		{3, true}, // several events in one transaction
+		{4, false},
+		{5, true},
+		{9, false},
+		{10, false},
+	}
+	for i, tc := range tt {
+		if own := d.own(tc.rev); own != tc.own {
+			t.Errorf("#%d: own(%d) = %v, want %v", i, tc.rev, own, tc.own)
+		}
+	}
+	if len(d.revs) != 0 {
+		t.Errorf("revs = %v, want none left", d.revs)
+	}
+}
+
+func TestMirrorWriterWait(t *testing.T) {
+	tt := []struct {
+		burst, n int
+	}{
+		{2, 1},
+		{2, 2},
+		// a revision with more operations than the burst is not split
+		{2, 5},
+	}
+	for i, tc := range tt {
+		w := &mirrorWriter{maxOps: tc.burst, limiter: rate.NewLimiter(rate.Limit(1e6), tc.burst)}
+		if err := w.wait(context.Background(), tc.n); err != nil {
+			t.Errorf("#%d: wait(%d) with burst %d failed (%v)", i, tc.n, tc.burst, err)
+		}
+	}
+	if err := (&mirrorWriter{}).wait(context.Background(), 10); err != nil {
+		t.Errorf("wait without a rate limit failed (%v)", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// writeDetector watches the mirrored range on the destination cluster and
+// reports writes that were not made by make-mirror itself, which means
+// another writer is modifying the mirror behind its back.
+type writeDetector struct {
+	abort  bool
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	// revs holds the revisions of the mirror's own writes not yet
+	// observed on the watch, in ascending order.
+	revs []int64
+	err  error
+}
+
+// start begins watching the destination range under prefix from its
+// current revision.
+func (d *writeDetector) start(ctx context.Context, dc *clientv3.Client, prefix string) error {
+	resp, err := dc.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return err
+	}
+	wc := dc.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+	go func() {
+		for wr := range wc {
+			if err := wr.Err(); err != nil {
+				d.fail(fmt.Errorf("destination watch failed (%v)", err))
+				return
+			}
+			for _, ev := range wr.Events {
+				if d.own(ev.Kv.ModRevision) {
+					continue
+				}
+				err := fmt.Errorf("destination key %q was modified outside of make-mirror at revision %d", ev.Kv.Key, ev.Kv.ModRevision)
+				if d.abort {
+					d.fail(err)
+					return
+				}
+				fmt.Fprintln(os.Stderr, "warning:", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// own returns true if rev is the revision of one of the mirror's writes.
+// Watch events arrive in revision order, so older revisions are dropped.
+func (d *writeDetector) own(rev int64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for len(d.revs) > 0 && d.revs[0] < rev {
+		d.revs = d.revs[1:]
+	}
+	return len(d.revs) > 0 && d.revs[0] == rev
+}
+
+func (d *writeDetector) fail(err error) {
+	d.mu.Lock()
+	d.err = err
+	d.mu.Unlock()
+	d.cancel()
+}
+
+// conflict returns the error that aborted the mirror, if any.
+func (d *writeDetector) conflict() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// txnModified returns true if the transaction created a new revision.
+func txnModified(resp *clientv3.TxnResponse) bool {
+	for _, r := range resp.Responses {
+		if r.GetResponsePut() != nil {
+			return true
+		}
+		if dr := r.GetResponseDeleteRange(); dr != nil && dr.Deleted > 0 {
+			return true
+		}
+	}
+	return false
+}
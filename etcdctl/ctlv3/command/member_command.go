@@ -17,16 +17,25 @@ package command
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/v3"
 )
 
 var (
-	memberPeerURLs string
-	isLearner      bool
+	memberPeerURLs     string
+	isLearner          bool
+	memberRemoveDryRun bool
+	memberPreflight    bool
+
+	memberReplaceName           string
+	memberReplacePromoteTimeout time.Duration
 )
 
 // NewMemberCommand returns the cobra command for "member".
@@ -41,6 +50,7 @@ func NewMemberCommand() *cobra.Command {
 	mc.AddCommand(NewMemberUpdateCommand())
 	mc.AddCommand(NewMemberListCommand())
 	mc.AddCommand(NewMemberPromoteCommand())
+	mc.AddCommand(NewMemberReplaceCommand())
 
 	return mc
 }
@@ -56,6 +66,7 @@ func NewMemberAddCommand() *cobra.Command {
 
 	cc.Flags().StringVar(&memberPeerURLs, "peer-urls", "", "comma separated peer URLs for the new member.")
 	cc.Flags().BoolVar(&isLearner, "learner", false, "indicates if the new member is raft learner")
+	cc.Flags().BoolVar(&memberPreflight, "preflight", false, "check that the peer URLs are reachable and warn about reduced failure tolerance before adding the member")
 
 	return cc
 }
@@ -66,8 +77,10 @@ func NewMemberRemoveCommand() *cobra.Command {
 		Use:   "remove <memberID>",
 		Short: "Removes a member from the cluster",
 
-		Run: memberRemoveCommandFunc,
+		Run:               memberRemoveCommandFunc,
+		ValidArgsFunction: completeMemberIDs(false),
 	}
+	cc.Flags().BoolVar(&memberRemoveDryRun, "dry-run", false, "verify the member exists without removing it")
 
 	return cc
 }
@@ -115,6 +128,25 @@ func NewMemberPromoteCommand() *cobra.Command {
 	return cc
 }
 
+// NewMemberReplaceCommand returns the cobra command for "member replace".
+func NewMemberReplaceCommand() *cobra.Command {
+	cc := &cobra.Command{
+		Use:   "replace <memberID> [options]",
+		Short: "Replaces a failed member with a new one",
+		Long: `Removes the given member, adds a new member as a learner and promotes it
+to a voting member once it has caught up with the leader.
+`,
+
+		Run: memberReplaceCommandFunc,
+	}
+
+	cc.Flags().StringVar(&memberPeerURLs, "peer-urls", "", "comma separated peer URLs for the new member.")
+	cc.Flags().StringVar(&memberReplaceName, "name", "", "name of the new member (defaults to the name of the replaced member)")
+	cc.Flags().DurationVar(&memberReplacePromoteTimeout, "promote-timeout", 5*time.Minute, "how long to wait for the new member to catch up before giving up on promoting it")
+
+	return cc
+}
+
 // memberAddCommandFunc executes the "member add" command.
 func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) < 1 {
@@ -136,6 +168,10 @@ func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 	}
 
 	urls := strings.Split(memberPeerURLs, ",")
+	if memberPreflight {
+		memberAddPreflight(cmd, urls)
+	}
+
 	ctx, cancel := commandCtx(cmd)
 	cli := mustClientFromCmd(cmd)
 	var (
@@ -151,26 +187,29 @@ func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 	if err != nil {
 		ExitWithError(ExitError, err)
 	}
-	newID := resp.Member.ID
-
 	display.MemberAdd(*resp)
+	printMemberAddEnv(*resp, newMemberName, memberPeerURLs)
+}
 
+// printMemberAddEnv prints the environment to start the newly added member
+// with when using the simple printer.
+func printMemberAddEnv(resp clientv3.MemberAddResponse, name, peerURLs string) {
 	if _, ok := (display).(*simplePrinter); ok {
 		conf := []string{}
 		for _, memb := range resp.Members {
 			for _, u := range memb.PeerURLs {
 				n := memb.Name
-				if memb.ID == newID {
-					n = newMemberName
+				if memb.ID == resp.Member.ID {
+					n = name
 				}
 				conf = append(conf, fmt.Sprintf("%s=%s", n, u))
 			}
 		}
 
 		fmt.Print("\n")
-		fmt.Printf("ETCD_NAME=%q\n", newMemberName)
+		fmt.Printf("ETCD_NAME=%q\n", name)
 		fmt.Printf("ETCD_INITIAL_CLUSTER=%q\n", strings.Join(conf, ","))
-		fmt.Printf("ETCD_INITIAL_ADVERTISE_PEER_URLS=%q\n", memberPeerURLs)
+		fmt.Printf("ETCD_INITIAL_ADVERTISE_PEER_URLS=%q\n", peerURLs)
 		fmt.Printf("ETCD_INITIAL_CLUSTER_STATE=\"existing\"\n")
 	}
 }
@@ -186,6 +225,11 @@ func memberRemoveCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("bad member ID arg (%v), expecting ID in Hex", err))
 	}
 
+	if memberRemoveDryRun {
+		memberRemoveDryRunFunc(cmd, id)
+		return
+	}
+
 	ctx, cancel := commandCtx(cmd)
 	resp, err := mustClientFromCmd(cmd).MemberRemove(ctx, id)
 	cancel()
@@ -195,6 +239,35 @@ func memberRemoveCommandFunc(cmd *cobra.Command, args []string) {
 	display.MemberRemove(id, *resp)
 }
 
+// memberRemoveDryRunFunc verifies that the member exists and reports
+// the resulting cluster size.
+func memberRemoveDryRunFunc(cmd *cobra.Command, id uint64) {
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).MemberList(ctx)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	voters := 0
+	var target *pb.Member
+	for _, m := range resp.Members {
+		if m.ID == id {
+			target = m
+		}
+		if !m.IsLearner {
+			voters++
+		}
+	}
+	if target == nil {
+		ExitWithError(ExitError, fmt.Errorf("member %x not found in cluster %x", id, resp.Header.ClusterId))
+	}
+	if !target.IsLearner {
+		voters--
+	}
+	fmt.Printf("dry-run: would remove member %x (name %q, learner %v) from cluster %x, leaving %d voting member(s)\n",
+		id, target.Name, target.IsLearner, resp.Header.ClusterId, voters)
+}
+
 // memberUpdateCommandFunc executes the "member update" command.
 func memberUpdateCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
@@ -253,3 +326,93 @@ func memberPromoteCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	display.MemberPromote(id, *resp)
 }
+
+// memberReplaceCommandFunc executes the "member replace" command.
+func memberReplaceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("member ID is not provided"))
+	}
+
+	id, err := strconv.ParseUint(args[0], 16, 64)
+	if err != nil {
+		ExitWithError(ExitBadArgs, fmt.Errorf("bad member ID arg (%v), expecting ID in Hex", err))
+	}
+
+	if len(memberPeerURLs) == 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("member peer urls not provided"))
+	}
+	urls := strings.Split(memberPeerURLs, ",")
+
+	cli := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	lresp, err := cli.MemberList(ctx)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	var old *pb.Member
+	voters := 0
+	for _, m := range lresp.Members {
+		if m.ID == id {
+			old = m
+		}
+		if !m.IsLearner {
+			voters++
+		}
+	}
+	if old == nil {
+		ExitWithError(ExitError, fmt.Errorf("member %x not found in cluster %x", id, lresp.Header.ClusterId))
+	}
+	if !old.IsLearner && voters == 1 {
+		ExitWithError(ExitError, fmt.Errorf("member %x is the only voting member of cluster %x", id, lresp.Header.ClusterId))
+	}
+	name := memberReplaceName
+	if name == "" {
+		name = old.Name
+	}
+	if name == "" {
+		ExitWithError(ExitBadArgs, fmt.Errorf("member %x has no name, set one with --name", id))
+	}
+
+	ctx, cancel = commandCtx(cmd)
+	rresp, err := cli.MemberRemove(ctx, id)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.MemberRemove(id, *rresp)
+
+	ctx, cancel = commandCtx(cmd)
+	aresp, err := cli.MemberAddAsLearner(ctx, urls)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, fmt.Errorf("member %x was removed but its replacement could not be added (%v)", id, err))
+	}
+	display.MemberAdd(*aresp)
+	printMemberAddEnv(*aresp, name, memberPeerURLs)
+
+	if old.IsLearner {
+		// the replaced member was not voting either
+		return
+	}
+
+	newID := aresp.Member.ID
+	fmt.Fprintf(os.Stderr, "waiting for member %x to catch up with the leader...\n", newID)
+	deadline := time.Now().Add(memberReplacePromoteTimeout)
+	for {
+		ctx, cancel = commandCtx(cmd)
+		presp, err := cli.MemberPromote(ctx, newID)
+		cancel()
+		if err == nil {
+			display.MemberPromote(newID, *presp)
+			return
+		}
+		if err != rpctypes.ErrMemberLearnerNotReady {
+			ExitWithError(ExitError, err)
+		}
+		if time.Now().After(deadline) {
+			ExitWithError(ExitError, fmt.Errorf("member %x did not catch up within %v, promote it with 'member promote %x'", newID, memberReplacePromoteTimeout, newID))
+		}
+		time.Sleep(time.Second)
+	}
+}
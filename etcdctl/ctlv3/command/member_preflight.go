@@ -0,0 +1,144 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// memberAddPreflight checks the peer URLs of the member about to be added
+// and warns about the loss of failure tolerance the change would cause.
+// It exits if any of the peer URLs is unusable.
+func memberAddPreflight(cmd *cobra.Command, urls []string) {
+	cfg, err := newClientCfg(nil, 0, 0, 0, secureCfgFromCmd(cmd), nil)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	var tlsCfg *tls.Config
+	if cfg.TLS != nil {
+		// only the reachability of the peer matters here; peer certificates
+		// are usually issued by a different CA than client certificates
+		tlsCfg = cfg.TLS.Clone()
+		tlsCfg.InsecureSkipVerify = true
+	}
+
+	failed := false
+	for _, u := range urls {
+		ctx, cancel := commandCtx(cmd)
+		warn, err := checkPeerURL(ctx, u, tlsCfg)
+		cancel()
+		switch {
+		case err != nil:
+			failed = true
+			fmt.Fprintf(os.Stderr, "preflight: peer URL %s failed: %v\n", u, err)
+		case warn != "":
+			fmt.Fprintf(os.Stderr, "preflight: warning: peer URL %s: %s\n", u, warn)
+		default:
+			fmt.Fprintf(os.Stderr, "preflight: peer URL %s is reachable\n", u)
+		}
+	}
+	if failed {
+		ExitWithError(ExitError, errors.New("preflight checks failed, member not added"))
+	}
+
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).MemberList(ctx)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	voters := 0
+	for _, m := range resp.Members {
+		if !m.IsLearner {
+			voters++
+		}
+	}
+	if warn := memberAddToleranceWarning(voters, isLearner); warn != "" {
+		fmt.Fprintf(os.Stderr, "preflight: warning: %s\n", warn)
+	}
+}
+
+// checkPeerURL verifies that the peer URL resolves and that its address is
+// reachable, performing a TLS handshake for https URLs. A refused
+// connection is only a warning since the new member is usually started
+// after it has been added.
+func checkPeerURL(ctx context.Context, rawURL string, tlsCfg *tls.Config) (warn string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		return "", errors.New("missing port")
+	}
+	if _, err = net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+		return "", fmt.Errorf("cannot resolve %q (%v)", u.Hostname(), err)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return "nothing is listening yet", nil
+		}
+		return "", fmt.Errorf("unreachable (%v)", err)
+	}
+	defer conn.Close()
+	if u.Scheme != "https" {
+		return "", nil
+	}
+
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{InsecureSkipVerify: true}
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	if err = tls.Client(conn, tlsCfg).Handshake(); err != nil {
+		return "", fmt.Errorf("TLS handshake failed (%v)", err)
+	}
+	return "", nil
+}
+
+// memberAddToleranceWarning describes how adding a member to a cluster with
+// the given number of voting members affects its failure tolerance, or
+// returns an empty string if the tolerance is not reduced.
+func memberAddToleranceWarning(voters int, learner bool) string {
+	if learner || voters < 1 {
+		return ""
+	}
+	before := (voters - 1) / 2
+	// the new voting member counts towards the quorum as soon as it is
+	// added, but cannot vote before it is started and caught up
+	during := voters/2 - 1
+	switch {
+	case during < 0:
+		return fmt.Sprintf("the cluster of %d voting member(s) loses quorum until the new member is started; consider adding it as a learner", voters)
+	case during < before:
+		return fmt.Sprintf("the cluster tolerates %d failure(s) instead of %d until the new member is started", during, before)
+	}
+	return ""
+}
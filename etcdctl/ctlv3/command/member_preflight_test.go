@@ -0,0 +1,83 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemberAddToleranceWarning(t *testing.T) {
+	tt := []struct {
+		voters  int
+		learner bool
+
+		warn bool
+	}{
+		{voters: 1, warn: true},
+		{voters: 1, learner: true, warn: false},
+		{voters: 2, warn: false},
+		{voters: 3, warn: true},
+		{voters: 4, warn: false},
+		{voters: 5, warn: true},
+	}
+	for i, tc := range tt {
+		if warn := memberAddToleranceWarning(tc.voters, tc.learner); (warn != "") != tc.warn {
+			t.Errorf("#%d: expected warning %v, got %q", i, tc.warn, warn)
+		}
+	}
+}
+
+func TestCheckPeerURL(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsSrv.Close()
+
+	// a port nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + ln.Addr().String()
+	ln.Close()
+
+	tt := []struct {
+		url string
+
+		warn bool
+		err  bool
+	}{
+		{url: srv.URL},
+		{url: tlsSrv.URL},
+		{url: closed, warn: true},
+		{url: "unix://127.0.0.1:2380", err: true},
+		{url: "http://127.0.0.1", err: true},
+		// a plain listener does not complete a TLS handshake
+		{url: "https" + srv.URL[len("http"):], err: true},
+	}
+	for i, tc := range tt {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		warn, err := checkPeerURL(ctx, tc.url, nil)
+		cancel()
+		if (warn != "") != tc.warn || (err != nil) != tc.err {
+			t.Errorf("#%d: %s: expected (warn %v, err %v), got (%q, %v)", i, tc.url, tc.warn, tc.err, warn, err)
+		}
+	}
+}
@@ -41,6 +41,7 @@ import (
 	"go.etcd.io/etcd/v3/wal"
 	"go.etcd.io/etcd/v3/wal/walpb"
 
+	"github.com/coreos/go-semver/semver"
 	"github.com/gogo/protobuf/proto"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -51,6 +52,7 @@ var (
 	migrateDatadir       string
 	migrateWALdir        string
 	migrateTransformer   string
+	migrateTargetVersion string
 )
 
 // NewMigrateCommand returns the cobra command for "migrate".
@@ -58,17 +60,29 @@ func NewMigrateCommand() *cobra.Command {
 	mc := &cobra.Command{
 		Use:   "migrate",
 		Short: "Migrates keys in a v2 store to a mvcc store",
-		Run:   migrateCommandFunc,
+		Long: `Migrates keys in a v2 store to a mvcc store.
+
+With --target-version, migrates the storage of the data directory to another
+minor version instead, so that a member of that version can start from it.
+The member must be stopped.
+`,
+		Run: migrateCommandFunc,
 	}
 
 	mc.Flags().BoolVar(&migrateExcludeTTLKey, "no-ttl", false, "Do not convert TTL keys")
 	mc.Flags().StringVar(&migrateDatadir, "data-dir", "", "Path to the data directory")
 	mc.Flags().StringVar(&migrateWALdir, "wal-dir", "", "Path to the WAL directory")
 	mc.Flags().StringVar(&migrateTransformer, "transformer", "", "Path to the user-provided transformer program")
+	mc.Flags().StringVar(&migrateTargetVersion, "target-version", "", "Storage version to migrate the data directory to, 3.4 or 3.5")
 	return mc
 }
 
 func migrateCommandFunc(cmd *cobra.Command, args []string) {
+	if migrateTargetVersion != "" {
+		migrateStorage()
+		return
+	}
+
 	var (
 		writer io.WriteCloser
 		reader io.ReadCloser
@@ -130,35 +144,11 @@ func rebuildStoreV2() (v2store.Store, uint64) {
 	var index uint64
 	cl := membership.NewCluster(zap.NewExample(), "")
 
-	waldir := migrateWALdir
-	if len(waldir) == 0 {
-		waldir = filepath.Join(migrateDatadir, "member", "wal")
-	}
-	snapdir := filepath.Join(migrateDatadir, "member", "snap")
-
-	ss := snap.New(zap.NewExample(), snapdir)
-	snapshot, err := ss.Load()
-	if err != nil && err != snap.ErrNoSnapshot {
-		ExitWithError(ExitError, err)
-	}
-
-	var walsnap walpb.Snapshot
+	_, snapshot, ents := readSnapshotAndWAL()
 	if snapshot != nil {
-		walsnap.Index, walsnap.Term = snapshot.Metadata.Index, snapshot.Metadata.Term
 		index = snapshot.Metadata.Index
 	}
 
-	w, err := wal.OpenForRead(zap.NewExample(), waldir, walsnap)
-	if err != nil {
-		ExitWithError(ExitError, err)
-	}
-	defer w.Close()
-
-	_, _, ents, err := w.ReadAll()
-	if err != nil {
-		ExitWithError(ExitError, err)
-	}
-
 	st := v2store.New()
 	if snapshot != nil {
 		err := st.Recovery(snapshot.Data)
@@ -198,6 +188,178 @@ func rebuildStoreV2() (v2store.Store, uint64) {
 	return st, index
 }
 
+// readSnapshotAndWAL returns the latest v2 snapshot of the data directory,
+// or nil if there is none, and the WAL entries after it.
+func readSnapshotAndWAL() (*snap.Snapshotter, *raftpb.Snapshot, []raftpb.Entry) {
+	waldir := migrateWALdir
+	if len(waldir) == 0 {
+		waldir = filepath.Join(migrateDatadir, "member", "wal")
+	}
+	snapdir := filepath.Join(migrateDatadir, "member", "snap")
+
+	ss := snap.New(zap.NewExample(), snapdir)
+	snapshot, err := ss.Load()
+	if err != nil && err != snap.ErrNoSnapshot {
+		ExitWithError(ExitError, err)
+	}
+
+	var walsnap walpb.Snapshot
+	if snapshot != nil {
+		walsnap.Index, walsnap.Term = snapshot.Metadata.Index, snapshot.Metadata.Term
+	}
+
+	w, err := wal.OpenForRead(zap.NewExample(), waldir, walsnap)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	defer w.Close()
+
+	_, _, ents, err := w.ReadAll()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	return ss, snapshot, ents
+}
+
+var (
+	storageV34 = semver.Version{Major: 3, Minor: 4}
+	storageV35 = semver.Version{Major: 3, Minor: 5}
+
+	clusterBucketName     = []byte("cluster")
+	clusterVersionKeyName = []byte("clusterVersion")
+	downgradeKeyName      = []byte("downgrade")
+)
+
+// migrateStorage migrates the storage of the data directory between 3.4 and
+// 3.5. Unlike 3.4, 3.5 records the cluster version and the downgrade
+// information in the backend, and sets them, as well as member attributes,
+// with raft requests 3.4 cannot apply.
+func migrateStorage() {
+	target, err := semver.NewVersion(migrateTargetVersion + ".0")
+	if err != nil || !(target.Equal(storageV34) || target.Equal(storageV35)) {
+		ExitWithError(ExitBadArgs, fmt.Errorf("unsupported target version %q, expected 3.4 or 3.5", migrateTargetVersion))
+	}
+	if _, err = os.Stat(filepath.Join(migrateDatadir, "member", "snap", "db")); err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+
+	be := prepareBackend()
+	defer be.Close()
+	current := storageVersion(be)
+	switch {
+	case current.Equal(*target):
+		fmt.Printf("storage is already at version %d.%d\n", target.Major, target.Minor)
+		return
+	case target.LessThan(current):
+		downgradeStorage(be, *target)
+	default:
+		upgradeStorage(be)
+	}
+	fmt.Printf("migrated storage from version %d.%d to %d.%d\n", current.Major, current.Minor, target.Major, target.Minor)
+}
+
+// storageVersion returns 3.5 if the backend records the cluster version or
+// the downgrade information, 3.4 otherwise.
+func storageVersion(be backend.Backend) semver.Version {
+	tx := be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	tx.UnsafeCreateBucket(clusterBucketName)
+	for _, k := range [][]byte{clusterVersionKeyName, downgradeKeyName} {
+		if keys, _ := tx.UnsafeRange(clusterBucketName, k, nil, 0); len(keys) != 0 {
+			return storageV35
+		}
+	}
+	return storageV34
+}
+
+// downgradeStorage drops the cluster version and the downgrade information
+// from the backend, and lowers the cluster version of the v2 snapshot to the
+// target, since a member refuses to start in a cluster of a higher version.
+// It fails if the WAL has entries after the snapshot the target version
+// cannot apply; these must first be covered by a snapshot.
+func downgradeStorage(be backend.Backend, target semver.Version) {
+	ss, snapshot, ents := readSnapshotAndWAL()
+	if idx := incompatibleEntries(ents); len(idx) != 0 {
+		ExitWithError(ExitError, fmt.Errorf("%d WAL entries after the latest snapshot, from index %d, cannot be applied by %d.%d; "+
+			"restart the member with a lower --snapshot-count so that a snapshot covers them, then retry", len(idx), idx[0], target.Major, target.Minor))
+	}
+
+	if snapshot != nil {
+		st := v2store.New()
+		if err := st.Recovery(snapshot.Data); err != nil {
+			ExitWithError(ExitError, err)
+		}
+		if ver := storeClusterVersion(st); ver != nil && target.LessThan(semver.Version{Major: ver.Major, Minor: ver.Minor}) {
+			_, err := st.Set(membership.StoreClusterVersionKey(), false, target.String(), v2store.TTLOptionSet{ExpireTime: v2store.Permanent})
+			if err != nil {
+				ExitWithError(ExitError, err)
+			}
+			if snapshot.Data, err = st.Save(); err != nil {
+				ExitWithError(ExitError, err)
+			}
+			if err = ss.SaveSnap(*snapshot); err != nil {
+				ExitWithError(ExitError, err)
+			}
+			fmt.Printf("lowered the cluster version of the snapshot at index %d from %s to %s\n", snapshot.Metadata.Index, ver, target)
+		}
+	}
+
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafeDelete(clusterBucketName, clusterVersionKeyName)
+	tx.UnsafeDelete(clusterBucketName, downgradeKeyName)
+	tx.Unlock()
+	be.ForceCommit()
+}
+
+// upgradeStorage records the cluster version of the v2 store in the backend.
+func upgradeStorage(be backend.Backend) {
+	st, _ := rebuildStoreV2()
+	ver := storeClusterVersion(st)
+	if ver == nil {
+		return
+	}
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafePut(clusterBucketName, clusterVersionKeyName, []byte(ver.String()))
+	tx.Unlock()
+	be.ForceCommit()
+}
+
+// incompatibleEntries returns the indexes of the entries with raft requests
+// added in 3.5.
+func incompatibleEntries(ents []raftpb.Entry) (idx []uint64) {
+	for _, ent := range ents {
+		if ent.Type != raftpb.EntryNormal {
+			continue
+		}
+		var r pb.InternalRaftRequest
+		if !pbutil.MaybeUnmarshal(&r, ent.Data) {
+			continue
+		}
+		if r.ClusterVersionSet != nil || r.ClusterMemberAttrSet != nil || r.DowngradeInfoSet != nil {
+			idx = append(idx, ent.Index)
+		}
+	}
+	return idx
+}
+
+func storeClusterVersion(st v2store.Store) *semver.Version {
+	e, err := st.Get(membership.StoreClusterVersionKey(), false, false)
+	if err != nil {
+		if eerr, ok := err.(*v2error.Error); ok && eerr.ErrorCode == v2error.EcodeKeyNotFound {
+			return nil
+		}
+		ExitWithError(ExitError, err)
+	}
+	ver, err := semver.NewVersion(*e.Node.Value)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	return ver
+}
+
 func applyConf(cc raftpb.ConfChange, cl *membership.RaftCluster) {
 	if err := cl.ValidateConfigurationChange(cc); err != nil {
 		return
@@ -15,25 +15,42 @@
 package command
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/types"
 )
 
+var moveLeaderAuto bool
+
 // NewMoveLeaderCommand returns the cobra command for "move-leader".
 func NewMoveLeaderCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "move-leader <transferee-member-id>",
 		Short: "Transfers leadership to another etcd cluster member.",
 		Run:   transferLeadershipCommandFunc,
+
+		ValidArgsFunction: completeMemberIDs(true),
 	}
+	cmd.Flags().BoolVar(&moveLeaderAuto, "auto", false, "transfer leadership to the voting follower with the smallest backlog and round-trip time")
 	return cmd
 }
 
 // transferLeadershipCommandFunc executes the "compaction" command.
 func transferLeadershipCommandFunc(cmd *cobra.Command, args []string) {
+	if moveLeaderAuto {
+		if len(args) != 0 {
+			ExitWithError(ExitBadArgs, fmt.Errorf("move-leader --auto does not take a transferee"))
+		}
+		autoTransferLeadership(cmd)
+		return
+	}
 	if len(args) != 1 {
 		ExitWithError(ExitBadArgs, fmt.Errorf("move-leader command needs 1 argument"))
 	}
@@ -80,3 +97,100 @@ func transferLeadershipCommandFunc(cmd *cobra.Command, args []string) {
 
 	display.MoveLeader(leaderID, target, *resp)
 }
+
+// transfereeCandidate is a member considered by "move-leader --auto".
+type transfereeCandidate struct {
+	id uint64
+	ep string
+	// rtt is the duration of the status request to the member.
+	rtt time.Duration
+	// backlog is the number of entries the member has yet to apply to
+	// catch up with the leader's log.
+	backlog uint64
+}
+
+// pickTransferee returns the candidate with the smallest backlog, breaking
+// ties by round-trip time.
+func pickTransferee(cands []transfereeCandidate) (transfereeCandidate, bool) {
+	if len(cands) == 0 {
+		return transfereeCandidate{}, false
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].backlog != cands[j].backlog {
+			return cands[i].backlog < cands[j].backlog
+		}
+		return cands[i].rtt < cands[j].rtt
+	})
+	return cands[0], true
+}
+
+// autoTransferLeadership queries the status of every voting member and
+// transfers leadership to the best follower.
+func autoTransferLeadership(cmd *cobra.Command) {
+	cc := clientConfigFromCmd(cmd)
+	c := cc.mustClient()
+	defer c.Close()
+
+	ctx, cancel := commandCtx(cmd)
+	membs, err := c.MemberList(ctx)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+
+	var (
+		leaderID, leaderIndex uint64
+		leaderEp              string
+		cands                 []transfereeCandidate
+		applied               = make(map[uint64]uint64)
+	)
+	for _, m := range membs.Members {
+		if m.IsLearner {
+			continue
+		}
+		for _, ep := range m.ClientURLs {
+			ctx, cancel := commandCtx(cmd)
+			start := time.Now()
+			resp, serr := c.Status(ctx, ep)
+			rtt := time.Since(start)
+			cancel()
+			if serr != nil {
+				fmt.Fprintf(os.Stderr, "failed to get the status of endpoint %s (%v)\n", ep, serr)
+				continue
+			}
+			if resp.Leader == m.ID {
+				leaderID, leaderIndex, leaderEp = m.ID, resp.RaftIndex, ep
+			} else if len(resp.Errors) == 0 {
+				cands = append(cands, transfereeCandidate{id: m.ID, ep: ep, rtt: rtt})
+				applied[m.ID] = resp.RaftAppliedIndex
+			}
+			break
+		}
+	}
+	if leaderEp == "" {
+		ExitWithError(ExitError, errors.New("failed to find the leader"))
+	}
+	for i := range cands {
+		if a := applied[cands[i].id]; a < leaderIndex {
+			cands[i].backlog = leaderIndex - a
+		}
+	}
+	target, ok := pickTransferee(cands)
+	if !ok {
+		ExitWithError(ExitError, errors.New("no healthy voting follower to transfer leadership to"))
+	}
+	fmt.Fprintf(os.Stderr, "Selected member %s at %s (backlog %d entries, rtt %v)\n", types.ID(target.id), target.ep, target.backlog, target.rtt)
+
+	leaderCli, err := cc.endpointClient(leaderEp)
+	if err != nil {
+		ExitWithError(ExitBadConnection, err)
+	}
+	defer leaderCli.Close()
+	ctx, cancel = commandCtx(cmd)
+	resp, err := leaderCli.MoveLeader(ctx, target.id)
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.MoveLeader(leaderID, target.id, *resp)
+}
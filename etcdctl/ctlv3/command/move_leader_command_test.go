@@ -0,0 +1,45 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPickTransferee(t *testing.T) {
+	tt := []struct {
+		cands []transfereeCandidate
+
+		id uint64
+		ok bool
+	}{
+		{cands: nil, ok: false},
+		{
+			cands: []transfereeCandidate{{id: 1, backlog: 10, rtt: time.Millisecond}, {id: 2, backlog: 0, rtt: 5 * time.Millisecond}},
+			id:    2, ok: true,
+		},
+		{
+			cands: []transfereeCandidate{{id: 1, backlog: 3, rtt: 5 * time.Millisecond}, {id: 2, backlog: 3, rtt: time.Millisecond}},
+			id:    2, ok: true,
+		},
+	}
+	for i, tc := range tt {
+		target, ok := pickTransferee(tc.cands)
+		if ok != tc.ok || target.id != tc.id {
+			t.Errorf("#%d: expected (%d, %v), got (%d, %v)", i, tc.id, tc.ok, target.id, ok)
+		}
+	}
+}
@@ -29,15 +29,24 @@ import (
 type printer interface {
 	Del(v3.DeleteResponse)
 	Get(v3.GetResponse)
+	GetDeleted(v3.GetResponse)
+	GetKeys(getKeysResponse)
 	Put(v3.PutResponse)
 	Txn(v3.TxnResponse)
 	Watch(v3.WatchResponse)
+	TaggedWatch(watcher string, r v3.WatchResponse)
 
 	Grant(r v3.LeaseGrantResponse)
 	Revoke(id v3.LeaseID, r v3.LeaseRevokeResponse)
 	KeepAlive(r v3.LeaseKeepAliveResponse)
 	TimeToLive(r v3.LeaseTimeToLiveResponse, keys bool)
 	Leases(r v3.LeaseLeasesResponse)
+	LeaseInfos(infos []leaseInfo, keys bool)
+
+	Lock(lockInfo)
+	ElectionLeader(electionLeader)
+
+	CheckPerf(checkPerfResult)
 
 	MemberAdd(v3.MemberAddResponse)
 	MemberRemove(id uint64, r v3.MemberRemoveResponse)
@@ -48,10 +57,21 @@ type printer interface {
 	EndpointHealth([]epHealth)
 	EndpointStatus([]epStatus)
 	EndpointHashKV([]epHashKV)
+	EndpointLatency([]epLatency)
+	ClusterHealth(clusterHealth)
 	MoveLeader(leader, target uint64, r v3.MoveLeaderResponse)
 
 	Alarm(v3.AlarmResponse)
+	AlarmInfos([]alarmInfo)
+	AlarmHistory([]alarmEvent)
+	MaintenanceStatus([]maintenanceStatus)
+	RateLimitStatus([]rateLimitStatus)
+	KeyspaceUsage([]prefixUsage)
+	KeyspaceDiff(keyspaceDiff)
 	DBStatus(snapshot.Status)
+	SnapshotAnalysis(snapshot.Analysis)
+	WALInspection(walInspection)
+	BackendInspection(backendInspection)
 
 	RoleAdd(role string, r v3.AuthRoleAddResponse)
 	RoleGet(role string, r v3.AuthRoleGetResponse)
@@ -59,6 +79,8 @@ type printer interface {
 	RoleList(v3.AuthRoleListResponse)
 	RoleGrantPermission(role string, r v3.AuthRoleGrantPermissionResponse)
 	RoleRevokePermission(role string, key string, end string, r v3.AuthRoleRevokePermissionResponse)
+	RoleClone(src, dst string, r v3.AuthRoleGetResponse)
+	RoleDiff(roleDiff)
 
 	UserAdd(user string, r v3.AuthUserAddResponse)
 	UserGet(user string, r v3.AuthUserGetResponse)
@@ -69,6 +91,8 @@ type printer interface {
 	UserDelete(user string, r v3.AuthUserDeleteResponse)
 
 	AuthStatus(r v3.AuthStatusResponse)
+	AuthTokenInspect(authTokenInfo)
+	AuthTokenRevoke(user string, r v3.AuthUserTokenRevokeResponse)
 }
 
 func NewPrinter(printerType string, isHex bool) printer {
@@ -97,6 +121,9 @@ func (p *printerRPC) Get(r v3.GetResponse)     { p.p((*pb.RangeResponse)(&r)) }
 func (p *printerRPC) Put(r v3.PutResponse)     { p.p((*pb.PutResponse)(&r)) }
 func (p *printerRPC) Txn(r v3.TxnResponse)     { p.p((*pb.TxnResponse)(&r)) }
 func (p *printerRPC) Watch(r v3.WatchResponse) { p.p(&r) }
+func (p *printerRPC) TaggedWatch(watcher string, r v3.WatchResponse) {
+	p.p(&taggedWatchResponse{Watcher: watcher, WatchResponse: r})
+}
 
 func (p *printerRPC) Grant(r v3.LeaseGrantResponse)                      { p.p(r) }
 func (p *printerRPC) Revoke(id v3.LeaseID, r v3.LeaseRevokeResponse)     { p.p(r) }
@@ -128,6 +155,9 @@ func (p *printerRPC) RoleGrantPermission(_ string, r v3.AuthRoleGrantPermissionR
 func (p *printerRPC) RoleRevokePermission(_ string, _ string, _ string, r v3.AuthRoleRevokePermissionResponse) {
 	p.p((*pb.AuthRoleRevokePermissionResponse)(&r))
 }
+func (p *printerRPC) RoleClone(_, _ string, r v3.AuthRoleGetResponse) {
+	p.p((*pb.AuthRoleGetResponse)(&r))
+}
 func (p *printerRPC) UserAdd(_ string, r v3.AuthUserAddResponse) { p.p((*pb.AuthUserAddResponse)(&r)) }
 func (p *printerRPC) UserGet(_ string, r v3.AuthUserGetResponse) { p.p((*pb.AuthUserGetResponse)(&r)) }
 func (p *printerRPC) UserList(r v3.AuthUserListResponse)         { p.p((*pb.AuthUserListResponse)(&r)) }
@@ -146,6 +176,9 @@ func (p *printerRPC) UserDelete(_ string, r v3.AuthUserDeleteResponse) {
 func (p *printerRPC) AuthStatus(r v3.AuthStatusResponse) {
 	p.p((*pb.AuthStatusResponse)(&r))
 }
+func (p *printerRPC) AuthTokenRevoke(_ string, r v3.AuthUserTokenRevokeResponse) {
+	p.p((*pb.AuthUserTokenRevokeResponse)(&r))
+}
 
 type printerUnsupported struct{ printerRPC }
 
@@ -161,8 +194,37 @@ func (p *printerUnsupported) EndpointStatus([]epStatus) { p.p(nil) }
 func (p *printerUnsupported) EndpointHashKV([]epHashKV) { p.p(nil) }
 func (p *printerUnsupported) DBStatus(snapshot.Status)  { p.p(nil) }
 
+func (p *printerUnsupported) KeyspaceUsage([]prefixUsage)  { p.p(nil) }
+func (p *printerUnsupported) KeyspaceDiff(keyspaceDiff)    { p.p(nil) }
+func (p *printerUnsupported) LeaseInfos([]leaseInfo, bool) { p.p(nil) }
+
+func (p *printerUnsupported) Lock(lockInfo)                 { p.p(nil) }
+func (p *printerUnsupported) ElectionLeader(electionLeader) { p.p(nil) }
+
+func (p *printerUnsupported) CheckPerf(checkPerfResult) { p.p(nil) }
+
+func (p *printerUnsupported) EndpointLatency([]epLatency) { p.p(nil) }
+func (p *printerUnsupported) ClusterHealth(clusterHealth) { p.p(nil) }
+
+func (p *printerUnsupported) GetDeleted(v3.GetResponse) { p.p(nil) }
+func (p *printerUnsupported) GetKeys(getKeysResponse)   { p.p(nil) }
+
+func (p *printerUnsupported) AlarmInfos([]alarmInfo)    { p.p(nil) }
+func (p *printerUnsupported) AlarmHistory([]alarmEvent) { p.p(nil) }
+
+func (p *printerUnsupported) MaintenanceStatus([]maintenanceStatus) { p.p(nil) }
+func (p *printerUnsupported) RateLimitStatus([]rateLimitStatus)     { p.p(nil) }
+
+func (p *printerUnsupported) SnapshotAnalysis(snapshot.Analysis)  { p.p(nil) }
+func (p *printerUnsupported) WALInspection(walInspection)         { p.p(nil) }
+func (p *printerUnsupported) BackendInspection(backendInspection) { p.p(nil) }
+
 func (p *printerUnsupported) MoveLeader(leader, target uint64, r v3.MoveLeaderResponse) { p.p(nil) }
 
+func (p *printerUnsupported) RoleDiff(roleDiff) { p.p(nil) }
+
+func (p *printerUnsupported) AuthTokenInspect(authTokenInfo) { p.p(nil) }
+
 func makeMemberListTable(r v3.MemberListResponse) (hdr []string, rows [][]string) {
 	hdr = []string{"ID", "Status", "Name", "Peer Addrs", "Client Addrs", "Is Learner"}
 	for _, m := range r.Members {
@@ -219,6 +281,48 @@ func makeEndpointStatusTable(statusList []epStatus) (hdr []string, rows [][]stri
 	return hdr, rows
 }
 
+func makeEndpointLatencyTable(latencyList []epLatency) (hdr []string, rows [][]string) {
+	hdr = []string{"endpoint", "linearizable read p50/p95/p99", "serializable read p50/p95/p99", "write p50/p95/p99", "error"}
+	stats := func(l latencyStats) string {
+		return fmt.Sprintf("%v/%v/%v", l.P50, l.P95, l.P99)
+	}
+	for _, l := range latencyList {
+		rows = append(rows, []string{
+			l.Ep,
+			stats(l.Linearizable),
+			stats(l.Serializable),
+			stats(l.Write),
+			l.Error,
+		})
+	}
+	return hdr, rows
+}
+
+func makeClusterHealthTable(h clusterHealth) (hdr []string, rows [][]string) {
+	hdr = []string{"ID", "name", "endpoint", "role", "healthy", "took", "version", "raft term", "raft applied index"}
+	for _, m := range h.Members {
+		role := "follower"
+		switch {
+		case m.IsLearner:
+			role = "learner"
+		case m.IsLeader:
+			role = "leader"
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%x", m.ID),
+			m.Name,
+			m.Endpoint,
+			role,
+			fmt.Sprint(m.Healthy),
+			m.Took,
+			m.Version,
+			fmt.Sprint(m.RaftTerm),
+			fmt.Sprint(m.RaftAppliedIndex),
+		})
+	}
+	return hdr, rows
+}
+
 func makeEndpointHashKVTable(hashList []epHashKV) (hdr []string, rows [][]string) {
 	hdr = []string{"endpoint", "hash"}
 	for _, h := range hashList {
@@ -230,6 +334,41 @@ func makeEndpointHashKVTable(hashList []epHashKV) (hdr []string, rows [][]string
 	return hdr, rows
 }
 
+func makeLeaseInfosTable(infos []leaseInfo, keys bool) (hdr []string, rows [][]string) {
+	hdr = []string{"ID", "granted TTL", "remaining TTL"}
+	if keys {
+		hdr = append(hdr, "attached keys", "key sample")
+	}
+	hdr = append(hdr, "error")
+	for _, l := range infos {
+		row := []string{
+			fmt.Sprintf("%016x", l.ID),
+			fmt.Sprint(l.GrantedTTL),
+			fmt.Sprint(l.TTL),
+		}
+		if keys {
+			row = append(row, fmt.Sprint(l.Keys), strings.Join(l.KeySample, ","))
+		}
+		rows = append(rows, append(row, l.Error))
+	}
+	return hdr, rows
+}
+
+func makeKeyspaceUsageTable(usage []prefixUsage) (hdr []string, rows [][]string) {
+	hdr = []string{"prefix", "keys", "key size", "value size", "total size", "revisions"}
+	for _, u := range usage {
+		rows = append(rows, []string{
+			u.Prefix,
+			fmt.Sprint(u.Keys),
+			humanize.Bytes(uint64(u.KeyBytes)),
+			humanize.Bytes(uint64(u.ValueBytes)),
+			humanize.Bytes(uint64(u.KeyBytes + u.ValueBytes)),
+			fmt.Sprint(u.Revisions),
+		})
+	}
+	return hdr, rows
+}
+
 func makeDBStatusTable(ds snapshot.Status) (hdr []string, rows [][]string) {
 	hdr = []string{"hash", "revision", "total keys", "total size"}
 	rows = append(rows, []string{
@@ -93,6 +93,11 @@ func (p *fieldsPrinter) Watch(resp v3.WatchResponse) {
 	}
 }
 
+func (p *fieldsPrinter) TaggedWatch(watcher string, resp v3.WatchResponse) {
+	fmt.Printf("\"Watcher\" : %q\n", watcher)
+	p.Watch(resp)
+}
+
 func (p *fieldsPrinter) Grant(r v3.LeaseGrantResponse) {
 	p.hdr(r.ResponseHeader)
 	fmt.Println(`"ID" :`, r.ID)
@@ -193,6 +198,33 @@ func (p *fieldsPrinter) DBStatus(r snapshot.Status) {
 	fmt.Println(`"Size" :`, r.TotalSize)
 }
 
+func (p *fieldsPrinter) KeyspaceUsage(usage []prefixUsage) {
+	for _, u := range usage {
+		fmt.Printf("\"Prefix\" : %q\n", u.Prefix)
+		fmt.Println(`"Keys" :`, u.Keys)
+		fmt.Println(`"KeyBytes" :`, u.KeyBytes)
+		fmt.Println(`"ValueBytes" :`, u.ValueBytes)
+		fmt.Println(`"Revisions" :`, u.Revisions)
+		fmt.Println()
+	}
+}
+
+func (p *fieldsPrinter) Lock(l lockInfo) {
+	fmt.Printf("\"Key\" : %q\n", l.Key)
+	fmt.Println(`"Lease" :`, int64(l.Lease))
+	fmt.Println(`"TTL" :`, l.TTL)
+	fmt.Println(`"Revision" :`, l.Revision)
+	fmt.Println(`"Waiters" :`, l.Waiters)
+}
+
+func (p *fieldsPrinter) ElectionLeader(l electionLeader) {
+	fmt.Printf("\"Key\" : %q\n", l.Key)
+	fmt.Printf("\"Value\" : %q\n", l.Value)
+	fmt.Println(`"Lease" :`, int64(l.Lease))
+	fmt.Println(`"CreateRevision" :`, l.CreateRevision)
+	fmt.Println(`"Revision" :`, l.Revision)
+}
+
 func (p *fieldsPrinter) RoleAdd(role string, r v3.AuthRoleAddResponse) { p.hdr(r.Header) }
 func (p *fieldsPrinter) RoleGet(role string, r v3.AuthRoleGetResponse) {
 	p.hdr(r.Header)
@@ -226,3 +258,6 @@ func (p *fieldsPrinter) UserRevokeRole(user string, role string, r v3.AuthUserRe
 	p.hdr(r.Header)
 }
 func (p *fieldsPrinter) UserDelete(user string, r v3.AuthUserDeleteResponse) { p.hdr(r.Header) }
+func (p *fieldsPrinter) AuthTokenRevoke(user string, r v3.AuthUserTokenRevokeResponse) {
+	p.hdr(r.Header)
+}
@@ -42,6 +42,35 @@ func (p *jsonPrinter) EndpointStatus(r []epStatus) { printJSON(r) }
 func (p *jsonPrinter) EndpointHashKV(r []epHashKV) { printJSON(r) }
 func (p *jsonPrinter) DBStatus(r snapshot.Status)  { printJSON(r) }
 
+func (p *jsonPrinter) EndpointLatency(r []epLatency) { printJSON(r) }
+func (p *jsonPrinter) ClusterHealth(r clusterHealth) { printJSON(r) }
+
+func (p *jsonPrinter) GetDeleted(r clientv3.GetResponse) { printJSON(r) }
+
+func (p *jsonPrinter) GetKeys(r getKeysResponse) { printJSON(r) }
+
+func (p *jsonPrinter) AlarmInfos(r []alarmInfo)    { printJSON(r) }
+func (p *jsonPrinter) AlarmHistory(r []alarmEvent) { printJSON(r) }
+
+func (p *jsonPrinter) MaintenanceStatus(r []maintenanceStatus) { printJSON(r) }
+func (p *jsonPrinter) RateLimitStatus(r []rateLimitStatus)     { printJSON(r) }
+
+func (p *jsonPrinter) SnapshotAnalysis(r snapshot.Analysis)  { printJSON(r) }
+func (p *jsonPrinter) WALInspection(r walInspection)         { printJSON(r) }
+func (p *jsonPrinter) BackendInspection(r backendInspection) { printJSON(r) }
+
+func (p *jsonPrinter) KeyspaceUsage(r []prefixUsage) { printJSON(r) }
+func (p *jsonPrinter) KeyspaceDiff(r keyspaceDiff)   { printJSON(r) }
+
+func (p *jsonPrinter) RoleDiff(r roleDiff) { printJSON(r) }
+
+func (p *jsonPrinter) AuthTokenInspect(r authTokenInfo) { printJSON(r) }
+
+func (p *jsonPrinter) LeaseInfos(r []leaseInfo, keys bool) { printJSON(r) }
+func (p *jsonPrinter) Lock(r lockInfo)                     { printJSON(r) }
+func (p *jsonPrinter) ElectionLeader(r electionLeader)     { printJSON(r) }
+func (p *jsonPrinter) CheckPerf(r checkPerfResult)         { printJSON(r) }
+
 func (p *jsonPrinter) MemberList(r clientv3.MemberListResponse) {
 	if p.isHex {
 		printMemberListWithHexJSON(r)
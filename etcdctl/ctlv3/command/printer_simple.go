@@ -15,14 +15,19 @@
 package command
 
 import (
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	v3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/etcdctl/v3/snapshot"
 	"go.etcd.io/etcd/pkg/v3/types"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
 )
 
 type simplePrinter struct {
@@ -43,6 +48,50 @@ func (s *simplePrinter) Get(resp v3.GetResponse) {
 	}
 }
 
+// GetDeleted prints the keys of a keys-only get, followed by "[deleted]"
+// instead of the empty value for the keys deleted since.
+func (s *simplePrinter) GetDeleted(resp v3.GetResponse) {
+	isDeleted := make(map[string]bool, len(resp.DeletedKeys))
+	for _, k := range resp.DeletedKeys {
+		isDeleted[string(k)] = true
+	}
+	for _, kv := range resp.Kvs {
+		k := string(kv.Key)
+		if s.isHex {
+			k = addHexPrefix(hex.EncodeToString(kv.Key))
+		}
+		fmt.Println(k)
+		if isDeleted[string(kv.Key)] {
+			fmt.Println("[deleted]")
+		} else {
+			fmt.Println()
+		}
+	}
+}
+
+// GetKeys prints the keys in the order they were requested, each followed
+// by its value, or by "[missing]" if it does not exist.
+func (s *simplePrinter) GetKeys(r getKeysResponse) {
+	kvs := make(map[string]*mvccpb.KeyValue, len(r.Kvs))
+	for _, kv := range r.Kvs {
+		kvs[string(kv.Key)] = kv
+	}
+	for _, key := range r.Keys {
+		if kv, ok := kvs[string(key)]; ok {
+			printKV(s.isHex, s.valueOnly, kv)
+			continue
+		}
+		if !s.valueOnly {
+			k := string(key)
+			if s.isHex {
+				k = addHexPrefix(hex.EncodeToString(key))
+			}
+			fmt.Println(k)
+		}
+		fmt.Println("[missing]")
+	}
+}
+
 func (s *simplePrinter) Put(r v3.PutResponse) {
 	fmt.Println("OK")
 	if r.PrevKv != nil {
@@ -82,6 +131,17 @@ func (s *simplePrinter) Watch(resp v3.WatchResponse) {
 	}
 }
 
+func (s *simplePrinter) TaggedWatch(watcher string, resp v3.WatchResponse) {
+	for _, e := range resp.Events {
+		fmt.Println(watcher)
+		fmt.Println(e.Type)
+		if e.PrevKv != nil {
+			printKV(s.isHex, s.valueOnly, e.PrevKv)
+		}
+		printKV(s.isHex, s.valueOnly, e.Kv)
+	}
+}
+
 func (s *simplePrinter) Grant(resp v3.LeaseGrantResponse) {
 	fmt.Printf("lease %016x granted with TTL(%ds)\n", resp.ID, resp.TTL)
 }
@@ -118,12 +178,118 @@ func (s *simplePrinter) Leases(resp v3.LeaseLeasesResponse) {
 	}
 }
 
+func (s *simplePrinter) LeaseInfos(infos []leaseInfo, keys bool) {
+	_, rows := makeLeaseInfosTable(infos, keys)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) Lock(l lockInfo) {
+	fmt.Println(l.Key)
+	fmt.Printf("lease %016x, ttl(%d), revision(%d), waiters(%d)\n", l.Lease, l.TTL, l.Revision, l.Waiters)
+}
+
+func (s *simplePrinter) ElectionLeader(l electionLeader) {
+	printKV(s.isHex, s.valueOnly, &mvccpb.KeyValue{Key: []byte(l.Key), Value: []byte(l.Value)})
+}
+
+func (s *simplePrinter) CheckPerf(r checkPerfResult) {
+	lines, pass := checkPerfLines(r)
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	if pass {
+		fmt.Println("PASS")
+	} else {
+		fmt.Println("FAIL")
+	}
+}
+
 func (s *simplePrinter) Alarm(resp v3.AlarmResponse) {
 	for _, e := range resp.Alarms {
 		fmt.Printf("%+v\n", e)
 	}
 }
 
+func (s *simplePrinter) AlarmInfos(infos []alarmInfo) {
+	for _, a := range infos {
+		raised := "unknown"
+		if a.RaisedAt != nil {
+			raised = a.RaisedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("memberID:%d alarm:%s name:%s endpoint:%s raised:%s\n", a.MemberID, a.Alarm, a.Name, a.Endpoint, raised)
+	}
+}
+
+func (s *simplePrinter) AlarmHistory(history []alarmEvent) {
+	for _, ev := range history {
+		action := "cleared"
+		if ev.Raised {
+			action = "raised"
+		}
+		fmt.Printf("%s %s memberID:%d alarm:%s\n", ev.Time.Format(time.RFC3339), action, ev.MemberID, ev.Alarm)
+	}
+}
+
+func (s *simplePrinter) MaintenanceStatus(sts []maintenanceStatus) {
+	for _, ms := range sts {
+		if ms.Error != "" {
+			fmt.Printf("%s, error: %s\n", ms.Endpoint, ms.Error)
+			continue
+		}
+		st := ms.Status
+		policy := "none"
+		switch {
+		case st.PolicyError != "":
+			policy = "invalid (" + st.PolicyError + ")"
+		case st.Policy != nil:
+			policy = fmt.Sprintf("compact-retention=%q defrag-cron=%q defrag-stagger=%q", st.Policy.CompactRetention, st.Policy.DefragCron, st.Policy.DefragStagger)
+		}
+		fmt.Printf("%s, memberID:%x, policy: %s\n", ms.Endpoint, st.MemberID, policy)
+		if st.NextDefrag != nil {
+			fmt.Printf("  next defrag: %s\n", st.NextDefrag.Format(time.RFC3339))
+		}
+		for _, r := range st.Runs {
+			result := "ok"
+			if r.Error != "" {
+				result = "error: " + r.Error
+			}
+			if r.Revision != 0 {
+				fmt.Printf("  %s %s to revision %d, took %v, %s\n", r.Start.Format(time.RFC3339), r.Kind, r.Revision, r.Duration, result)
+			} else {
+				fmt.Printf("  %s %s, took %v, %s\n", r.Start.Format(time.RFC3339), r.Kind, r.Duration, result)
+			}
+		}
+	}
+}
+
+func (s *simplePrinter) RateLimitStatus(sts []rateLimitStatus) {
+	for _, rs := range sts {
+		if rs.Error != "" {
+			fmt.Printf("%s, error: %s\n", rs.Endpoint, rs.Error)
+			continue
+		}
+		st := rs.Status
+		policy := "none"
+		switch {
+		case st.PolicyError != "":
+			policy = "invalid (" + st.PolicyError + ")"
+		case st.Policy != nil:
+			policy = fmt.Sprintf("%d users, %d common names, %d networks", len(st.Policy.Users), len(st.Policy.CommonNames), len(st.Policy.Networks))
+			if st.Policy.Default != nil {
+				policy += ", default"
+			}
+		}
+		fmt.Printf("%s, memberID:%x, policy: %s, buckets: %d\n", rs.Endpoint, st.MemberID, policy, st.Buckets)
+		for _, c := range []v3ratelimit.Class{v3ratelimit.ClassRead, v3ratelimit.ClassWrite, v3ratelimit.ClassWatch} {
+			if n := st.Rejected[c]; n > 0 {
+				fmt.Printf("  rejected %s: %d\n", c, n)
+			}
+		}
+	}
+}
+
 func (s *simplePrinter) MemberAdd(r v3.MemberAddResponse) {
 	fmt.Printf("Member %16x added to cluster %16x\n", r.Member.ID, r.Header.ClusterId)
 }
@@ -171,6 +337,32 @@ func (s *simplePrinter) EndpointHashKV(hashList []epHashKV) {
 	}
 }
 
+func (s *simplePrinter) EndpointLatency(latencyList []epLatency) {
+	_, rows := makeEndpointLatencyTable(latencyList)
+	for i, row := range rows {
+		if latencyList[i].Error != "" {
+			fmt.Fprintf(os.Stderr, "%s failed to measure latency: %v\n", row[0], latencyList[i].Error)
+			continue
+		}
+		fmt.Println(strings.Join(row[:len(row)-1], ", "))
+	}
+}
+
+func (s *simplePrinter) ClusterHealth(h clusterHealth) {
+	_, rows := makeClusterHealthTable(h)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+	for _, p := range h.Problems {
+		fmt.Println("problem:", p)
+	}
+	if h.Healthy {
+		fmt.Printf("cluster %x is healthy\n", h.ClusterID)
+	} else {
+		fmt.Printf("cluster %x is unhealthy\n", h.ClusterID)
+	}
+}
+
 func (s *simplePrinter) DBStatus(ds snapshot.Status) {
 	_, rows := makeDBStatusTable(ds)
 	for _, row := range rows {
@@ -178,6 +370,113 @@ func (s *simplePrinter) DBStatus(ds snapshot.Status) {
 	}
 }
 
+func (s *simplePrinter) SnapshotAnalysis(a snapshot.Analysis) {
+	bytes := func(n int64) string { return humanize.Bytes(uint64(n)) }
+	count := func(n int64) string { return fmt.Sprint(n) }
+	histogram := func(h []snapshot.HistogramBucket, format func(int64) string) {
+		for i, b := range h {
+			if b.UpTo == 0 && i > 0 {
+				fmt.Printf("  > %s: %d\n", format(h[i-1].UpTo), b.Count)
+			} else {
+				fmt.Printf("  <= %s: %d\n", format(b.UpTo), b.Count)
+			}
+		}
+	}
+	key := func(k string) string {
+		if s.isHex {
+			return addHexPrefix(hex.EncodeToString([]byte(k)))
+		}
+		return k
+	}
+
+	fmt.Printf("revision: %d, compact revision: %d, size: %s\n", a.Revision, a.CompactRevision, bytes(a.TotalSize))
+	fmt.Printf("keys: %d, revisions: %d, tombstones: %d, key size: %s, value size: %s\n",
+		a.Keys, a.Revisions, a.Tombstones, bytes(a.KeyBytes), bytes(a.ValueBytes))
+	fmt.Println("value sizes:")
+	histogram(a.ValueSizes, bytes)
+	fmt.Println("revisions per key:")
+	histogram(a.RevisionCounts, count)
+	fmt.Println("prefixes:")
+	for _, p := range a.Prefixes {
+		fmt.Printf("  %s, %d keys, %d revisions, %s, %s live\n", key(p.Prefix), p.Keys, p.Revisions, bytes(p.Bytes), bytes(p.LiveBytes))
+	}
+	fmt.Printf("leases: %d, keys with lease: %d\n", a.Leases, a.KeysWithLease)
+	for _, l := range a.TopLeases {
+		fmt.Printf("  %016x, %d keys\n", l.ID, l.Keys)
+	}
+	if len(a.LargestKeys) > 0 {
+		fmt.Println("largest keys:")
+	}
+	for _, u := range a.LargestKeys {
+		fmt.Printf("  %s, %s, %d revisions\n", key(u.Key), bytes(u.ValueSize), u.Revisions)
+	}
+	if len(a.MostRevisedKeys) > 0 {
+		fmt.Println("most revised keys:")
+	}
+	for _, u := range a.MostRevisedKeys {
+		deleted := ""
+		if u.Deleted {
+			deleted = " (deleted)"
+		}
+		fmt.Printf("  %s, %d revisions%s\n", key(u.Key), u.Revisions, deleted)
+	}
+}
+
+func (s *simplePrinter) WALInspection(wi walInspection) {
+	fmt.Printf("nodeID:%x clusterID:%x term:%d commit:%d vote:%x snapshot index:%d\n",
+		wi.NodeID, wi.ClusterID, wi.Term, wi.Commit, wi.Vote, wi.SnapshotIndex)
+	for _, e := range wi.Entries {
+		keys := make([]string, len(e.Keys))
+		for i, k := range e.Keys {
+			keys[i] = k
+			if s.isHex {
+				keys[i] = addHexPrefix(hex.EncodeToString([]byte(k)))
+			}
+		}
+		fmt.Printf("%d, %d, %s, %s, %s, %s\n", e.Term, e.Index, e.Type, e.Request, strings.Join(keys, " "), e.Detail)
+	}
+}
+
+func (s *simplePrinter) BackendInspection(bi backendInspection) {
+	fmt.Printf("%s, size: %s, in use: %s, free pages: %d, pending pages: %d, page size: %d, fragmentation: %.1f%%\n",
+		bi.Path, humanize.Bytes(uint64(bi.Size)), humanize.Bytes(uint64(bi.SizeInUse)),
+		bi.FreePages, bi.PendingPages, bi.PageSize, bi.Fragmentation*100)
+	for _, b := range bi.Buckets {
+		fmt.Printf("%s, %d keys, key size: %s, value size: %s, %d pages, allocated: %s, in use: %s, depth: %d\n",
+			b.Name, b.Keys, humanize.Bytes(uint64(b.KeyBytes)), humanize.Bytes(uint64(b.ValueBytes)),
+			b.Pages, humanize.Bytes(uint64(b.Allocated)), humanize.Bytes(uint64(b.InUse)), b.Depth)
+	}
+}
+
+func (s *simplePrinter) KeyspaceUsage(usage []prefixUsage) {
+	_, rows := makeKeyspaceUsageTable(usage)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) KeyspaceDiff(d keyspaceDiff) {
+	k := func(key string) string {
+		if s.isHex {
+			return addHexPrefix(hex.EncodeToString([]byte(key)))
+		}
+		return key
+	}
+	if !d.summary {
+		for _, key := range d.Added {
+			fmt.Println("+", k(key))
+		}
+		for _, key := range d.Removed {
+			fmt.Println("-", k(key))
+		}
+		for _, key := range d.Changed {
+			fmt.Println("~", k(key))
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d changed (revision %d, destination revision %d)\n",
+		len(d.Added), len(d.Removed), len(d.Changed), d.Revision, d.DestRevision)
+}
+
 func (s *simplePrinter) MoveLeader(leader, target uint64, r v3.MoveLeaderResponse) {
 	fmt.Printf("Leadership transferred from %s to %s\n", types.ID(leader), types.ID(target))
 }
@@ -189,42 +488,54 @@ func (s *simplePrinter) RoleAdd(role string, r v3.AuthRoleAddResponse) {
 func (s *simplePrinter) RoleGet(role string, r v3.AuthRoleGetResponse) {
 	fmt.Printf("Role %s\n", role)
 	fmt.Println("KV Read:")
-
-	printRange := func(perm *v3.Permission) {
-		sKey := string(perm.Key)
-		sRangeEnd := string(perm.RangeEnd)
-		if sRangeEnd != "\x00" {
-			fmt.Printf("\t[%s, %s)", sKey, sRangeEnd)
-		} else {
-			fmt.Printf("\t[%s, <open ended>", sKey)
-		}
-		if v3.GetPrefixRangeEnd(sKey) == sRangeEnd {
-			fmt.Printf(" (prefix %s)", sKey)
-		}
-		fmt.Printf("\n")
-	}
-
 	for _, perm := range r.Perm {
 		if perm.PermType == v3.PermRead || perm.PermType == v3.PermReadWrite {
-			if len(perm.RangeEnd) == 0 {
-				fmt.Printf("\t%s\n", string(perm.Key))
-			} else {
-				printRange((*v3.Permission)(perm))
-			}
+			fmt.Printf("\t%s\n", permRangeString(string(perm.Key), string(perm.RangeEnd)))
 		}
 	}
 	fmt.Println("KV Write:")
 	for _, perm := range r.Perm {
 		if perm.PermType == v3.PermWrite || perm.PermType == v3.PermReadWrite {
-			if len(perm.RangeEnd) == 0 {
-				fmt.Printf("\t%s\n", string(perm.Key))
-			} else {
-				printRange((*v3.Permission)(perm))
-			}
+			fmt.Printf("\t%s\n", permRangeString(string(perm.Key), string(perm.RangeEnd)))
 		}
 	}
 }
 
+func (s *simplePrinter) RoleClone(src, dst string, r v3.AuthRoleGetResponse) {
+	fmt.Printf("Role %s cloned from %s with %d permission(s)\n", dst, src, len(r.Perm))
+}
+
+func (s *simplePrinter) RoleDiff(d roleDiff) {
+	for _, p := range d.Added {
+		fmt.Printf("+ %s %s\n", permRangeString(p.Key, p.RangeEnd), p.PermType)
+	}
+	for _, p := range d.Removed {
+		fmt.Printf("- %s %s\n", permRangeString(p.Key, p.RangeEnd), p.PermType)
+	}
+	for _, p := range d.Changed {
+		fmt.Printf("~ %s %s -> %s\n", permRangeString(p.Key, p.RangeEnd), p.From, p.To)
+	}
+	fmt.Printf("%d added, %d removed, %d changed (role %s to role %s)\n",
+		len(d.Added), len(d.Removed), len(d.Changed), d.From, d.To)
+}
+
+// permRangeString formats the key range of a permission.
+func permRangeString(key, end string) string {
+	if len(end) == 0 {
+		return key
+	}
+	var s string
+	if end != "\x00" {
+		s = fmt.Sprintf("[%s, %s)", key, end)
+	} else {
+		s = fmt.Sprintf("[%s, <open ended>", key)
+	}
+	if v3.GetPrefixRangeEnd(key) == end {
+		s += fmt.Sprintf(" (prefix %s)", key)
+	}
+	return s
+}
+
 func (s *simplePrinter) RoleList(r v3.AuthRoleListResponse) {
 	for _, role := range r.Roles {
 		fmt.Printf("%s\n", role)
@@ -290,3 +601,38 @@ func (s *simplePrinter) AuthStatus(r v3.AuthStatusResponse) {
 	fmt.Println("Authentication Status:", r.Enabled)
 	fmt.Println("AuthRevision:", r.AuthRevision)
 }
+
+func (s *simplePrinter) AuthTokenInspect(t authTokenInfo) {
+	fmt.Println("Type:", t.Type)
+	if t.User != "" {
+		fmt.Println("User:", t.User)
+	} else {
+		fmt.Println("User: unknown")
+	}
+	if t.Type == "simple" {
+		fmt.Println("Index:", t.Index)
+	}
+	if t.AuthRevision != 0 {
+		fmt.Println("Revision:", t.Revision)
+		fmt.Println("AuthRevision:", t.AuthRevision)
+	} else if t.Type == "jwt" {
+		fmt.Println("Revision:", t.Revision)
+	}
+	if t.TTL < 0 {
+		fmt.Println("TTL: unknown (renewed on use, see the server's --auth-token-ttl)")
+	} else {
+		fmt.Printf("TTL: %ds\n", t.TTL)
+	}
+	fmt.Println("Valid:", t.Valid)
+	if t.Stale() {
+		fmt.Println("Stale: true (auth changed since the token was issued, the client must re-authenticate)")
+	}
+}
+
+func (s *simplePrinter) AuthTokenRevoke(user string, r v3.AuthUserTokenRevokeResponse) {
+	if user == "" {
+		fmt.Println("Tokens revoked")
+		return
+	}
+	fmt.Printf("Tokens of user %s revoked\n", user)
+}
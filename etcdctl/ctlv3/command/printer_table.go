@@ -15,6 +15,7 @@
 package command
 
 import (
+	"fmt"
 	"os"
 
 	v3 "go.etcd.io/etcd/client/v3"
@@ -65,6 +66,29 @@ func (tp *tablePrinter) EndpointHashKV(r []epHashKV) {
 	table.SetAlignment(tablewriter.ALIGN_RIGHT)
 	table.Render()
 }
+func (tp *tablePrinter) EndpointLatency(r []epLatency) {
+	hdr, rows := makeEndpointLatencyTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
+func (tp *tablePrinter) ClusterHealth(h clusterHealth) {
+	hdr, rows := makeClusterHealthTable(h)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+	for _, p := range h.Problems {
+		fmt.Println("problem:", p)
+	}
+}
 func (tp *tablePrinter) DBStatus(r snapshot.Status) {
 	hdr, rows := makeDBStatusTable(r)
 	table := tablewriter.NewWriter(os.Stdout)
@@ -75,3 +99,23 @@ func (tp *tablePrinter) DBStatus(r snapshot.Status) {
 	table.SetAlignment(tablewriter.ALIGN_RIGHT)
 	table.Render()
 }
+func (tp *tablePrinter) KeyspaceUsage(r []prefixUsage) {
+	hdr, rows := makeKeyspaceUsageTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
+func (tp *tablePrinter) LeaseInfos(r []leaseInfo, keys bool) {
+	hdr, rows := makeLeaseInfosTable(r, keys)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
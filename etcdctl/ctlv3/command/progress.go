@@ -0,0 +1,110 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+const progressInterval = 5 * time.Second
+
+// progress periodically reports on stderr the progress of a blocking
+// maintenance operation, as polled from the member running it.
+type progress struct {
+	label string
+	// poll returns the progress of the operation, or nil if it is unknown.
+	poll func() *pb.OperationProgress
+
+	stopc chan struct{}
+	donec chan struct{}
+}
+
+// startProgress starts reporting on the operation described by label until
+// stop is called.
+func startProgress(label string, poll func() *pb.OperationProgress) *progress {
+	p := &progress{
+		label: label,
+		poll:  poll,
+		stopc: make(chan struct{}),
+		donec: make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *progress) run() {
+	defer close(p.donec)
+	start := time.Now()
+	t := time.NewTicker(progressInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stopc:
+			return
+		case <-t.C:
+			fmt.Fprintln(os.Stderr, progressLine(p.label, time.Since(start), p.poll()))
+		}
+	}
+}
+
+func (p *progress) stop() {
+	close(p.stopc)
+	<-p.donec
+}
+
+// maintenanceProgressPoller returns a poll function reading the progress of
+// the operation picked by op from the maintenance progress of the endpoint.
+func maintenanceProgressPoller(cmd *cobra.Command, c *v3.Client, ep string, op func(*v3.MaintenanceProgressResponse) *pb.OperationProgress) func() *pb.OperationProgress {
+	return func() *pb.OperationProgress {
+		ctx, cancel := commandCtx(cmd)
+		defer cancel()
+		resp, err := c.MaintenanceProgress(ctx, ep)
+		if err != nil {
+			return nil
+		}
+		return op(resp)
+	}
+}
+
+// waitCtx returns the context of an operation run with --wait, which has no
+// deadline as the operation may take longer than --command-timeout.
+func waitCtx() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+func progressLine(label string, elapsed time.Duration, op *pb.OperationProgress) string {
+	elapsed = elapsed.Round(time.Second)
+	if op == nil || op.Total <= 0 {
+		return fmt.Sprintf("%s: %v elapsed", label, elapsed)
+	}
+	done := op.Done
+	if done > op.Total {
+		done = op.Total
+	}
+	pct := int(100 * done / op.Total)
+	if done <= 0 {
+		return fmt.Sprintf("%s: %d%% done, %v elapsed", label, pct, elapsed)
+	}
+	// the remaining work is assumed to take as long per unit as the work done
+	remaining := time.Duration(float64(elapsed) * float64(op.Total-done) / float64(done)).Round(time.Second)
+	return fmt.Sprintf("%s: %d%% done, %v elapsed, about %v remaining", label, pct, elapsed, remaining)
+}
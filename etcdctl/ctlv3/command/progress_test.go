@@ -0,0 +1,43 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+func TestProgressLine(t *testing.T) {
+	tt := []struct {
+		elapsed time.Duration
+		op      *pb.OperationProgress
+
+		line string
+	}{
+		{12 * time.Second, nil, "op: 12s elapsed"},
+		{12 * time.Second, &pb.OperationProgress{}, "op: 12s elapsed"},
+		{5 * time.Second, &pb.OperationProgress{Done: 0, Total: 100}, "op: 0% done, 5s elapsed"},
+		{10 * time.Second, &pb.OperationProgress{Done: 25, Total: 100}, "op: 25% done, 10s elapsed, about 30s remaining"},
+		{1500 * time.Millisecond, &pb.OperationProgress{Done: 1, Total: 5}, "op: 20% done, 2s elapsed, about 8s remaining"},
+		{time.Minute, &pb.OperationProgress{Done: 120, Total: 100}, "op: 100% done, 1m0s elapsed, about 0s remaining"},
+	}
+	for i, ts := range tt {
+		if l := progressLine("op", ts.elapsed, ts.op); l != ts.line {
+			t.Errorf("#%d: expected %q, got %q", i, ts.line, l)
+		}
+	}
+}
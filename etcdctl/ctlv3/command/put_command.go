@@ -28,6 +28,7 @@ var (
 	putPrevKV      bool
 	putIgnoreVal   bool
 	putIgnoreLease bool
+	putTTL         int64
 )
 
 // NewPutCommand returns the cobra command for "put".
@@ -60,6 +61,7 @@ will store the content of the file to <key>.
 	cmd.Flags().BoolVar(&putPrevKV, "prev-kv", false, "return the previous key-value pair before modification")
 	cmd.Flags().BoolVar(&putIgnoreVal, "ignore-value", false, "updates the key using its current value")
 	cmd.Flags().BoolVar(&putIgnoreLease, "ignore-lease", false, "updates the key using its current lease")
+	cmd.Flags().Int64Var(&putTTL, "ttl", 0, "time to live in seconds of the key, deleted once it elapses unless put again")
 	return cmd
 }
 
@@ -113,6 +115,9 @@ func getPutOp(args []string) (string, string, []clientv3.OpOption) {
 	if putIgnoreLease {
 		opts = append(opts, clientv3.WithIgnoreLease())
 	}
+	if putTTL != 0 {
+		opts = append(opts, clientv3.WithExpiry(putTTL))
+	}
 
 	return key, value, opts
 }
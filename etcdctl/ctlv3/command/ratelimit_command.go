@@ -0,0 +1,251 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
+)
+
+// NewRateLimitCommand returns the cobra command for "ratelimit".
+func NewRateLimitCommand() *cobra.Command {
+	rc := &cobra.Command{
+		Use:   "ratelimit <subcommand>",
+		Short: "Rate limit related commands",
+	}
+
+	rc.AddCommand(newRateLimitSetCommand())
+	rc.AddCommand(newRateLimitGetCommand())
+	rc.AddCommand(newRateLimitClearCommand())
+	rc.AddCommand(newRateLimitStatusCommand())
+
+	return rc
+}
+
+func newRateLimitSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <policy file>",
+		Short: "Sets the rate limit policy of the cluster",
+		Long: `Sets the rate limit policy enforced by the members started with
+--experimental-enable-rate-limit, read as JSON from the file, or from the
+standard input if the file is "-". The policy gives token bucket limits of
+reads, writes and watch creations per authenticated user, per client
+certificate common name and per client address in a network range, e.g.
+
+{
+  "users": {"batch": {"writes": {"rate": 100, "burst": 200}}},
+  "common_names": {"indexer": {"reads": {"rate": 1000}}},
+  "networks": {"10.0.0.0/8": {"watches": {"rate": 10}}},
+  "default": {"reads": {"rate": 500}, "writes": {"rate": 100}}
+}
+
+A request is rejected if it is over the budget of any rule matching its
+client. The default budget applies to every client address matched by no rule.
+The limits take effect within seconds on every member.
+`,
+		Run: rateLimitSetCommandFunc,
+	}
+}
+
+func newRateLimitGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get",
+		Short: "Prints the rate limit policy of the cluster",
+		Run:   rateLimitGetCommandFunc,
+	}
+}
+
+func newRateLimitClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Removes the rate limit policy of the cluster",
+		Run:   rateLimitClearCommandFunc,
+	}
+}
+
+func newRateLimitStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Shows the rate limit policy and the rejected requests of every member",
+		Run:   rateLimitStatusCommandFunc,
+	}
+}
+
+// rateLimitSetCommandFunc executes the "ratelimit set" command.
+func rateLimitSetCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, errors.New("ratelimit set command needs 1 argument"))
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if args[0] == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	p, err := v3ratelimit.ParsePolicy(b)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	if b, err = json.Marshal(p); err != nil {
+		ExitWithError(ExitError, err)
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	if _, err = c.PolicyPut(ctx, clientv3.PolicyRateLimit, b); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Println("Rate limit policy set")
+}
+
+// rateLimitGetCommandFunc executes the "ratelimit get" command.
+func rateLimitGetCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("ratelimit get command does not accept any arguments"))
+	}
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	resp, err := c.PolicyGet(ctx, clientv3.PolicyRateLimit)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if len(resp.Policy) == 0 {
+		fmt.Println("No rate limit policy")
+		return
+	}
+	var out bytes.Buffer
+	if err = json.Indent(&out, resp.Policy, "", "  "); err != nil {
+		ExitWithError(ExitError, fmt.Errorf("invalid rate limit policy (%v)", err))
+	}
+	fmt.Println(out.String())
+}
+
+// rateLimitClearCommandFunc executes the "ratelimit clear" command.
+func rateLimitClearCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("ratelimit clear command does not accept any arguments"))
+	}
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	if _, err := c.PolicyPut(ctx, clientv3.PolicyRateLimit, nil); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Println("Rate limit policy cleared")
+}
+
+// rateLimitStatus is the rate limit status of a member.
+type rateLimitStatus struct {
+	Endpoint string              `json:"endpoint"`
+	Status   *v3ratelimit.Status `json:"status,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// rateLimitStatusCommandFunc executes the "ratelimit status" command.
+func rateLimitStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("ratelimit status command does not accept any arguments"))
+	}
+
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	defer cancel()
+	mresp, err := c.MemberList(ctx)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	var sts []rateLimitStatus
+	for _, m := range mresp.Members {
+		if len(m.ClientURLs) == 0 {
+			continue
+		}
+		ep := m.ClientURLs[0]
+		rs := rateLimitStatus{Endpoint: ep}
+		resp, err := c.RateLimitStatus(ctx, ep)
+		if err != nil {
+			rs.Error = err.Error()
+		} else if !resp.Enabled {
+			rs.Error = "rate limit is not enabled (see the server flag --experimental-enable-rate-limit)"
+		} else {
+			rs.Status = rateLimiterStatus(resp)
+		}
+		sts = append(sts, rs)
+	}
+	display.RateLimitStatus(sts)
+}
+
+// rateLimiterStatus converts the rate limit status of a member.
+func rateLimiterStatus(resp *clientv3.RateLimitStatusResponse) *v3ratelimit.Status {
+	st := &v3ratelimit.Status{PolicyError: resp.PolicyError, Buckets: int(resp.Buckets)}
+	if resp.Header != nil {
+		st.MemberID = resp.Header.MemberId
+	}
+	if p := resp.Policy; p != nil {
+		st.Policy = &v3ratelimit.Policy{
+			Users:       rateLimitBudgets(p.Users),
+			CommonNames: rateLimitBudgets(p.CommonNames),
+			Networks:    rateLimitBudgets(p.Networks),
+		}
+		if p.Default != nil {
+			b := rateLimitBudget(p.Default)
+			st.Policy.Default = &b
+		}
+	}
+	if len(resp.Rejected) > 0 {
+		st.Rejected = make(map[v3ratelimit.Class]uint64, len(resp.Rejected))
+		for c, n := range resp.Rejected {
+			st.Rejected[v3ratelimit.Class(c)] = n
+		}
+	}
+	return st
+}
+
+func rateLimitBudgets(pbs map[string]*pb.RateLimitBudget) map[string]v3ratelimit.Budget {
+	if len(pbs) == 0 {
+		return nil
+	}
+	bs := make(map[string]v3ratelimit.Budget, len(pbs))
+	for id, b := range pbs {
+		bs[id] = rateLimitBudget(b)
+	}
+	return bs
+}
+
+func rateLimitBudget(b *pb.RateLimitBudget) v3ratelimit.Budget {
+	limit := func(l *pb.RateLimit) *v3ratelimit.Limit {
+		if l == nil {
+			return nil
+		}
+		return &v3ratelimit.Limit{Rate: l.Rate, Burst: int(l.Burst)}
+	}
+	return v3ratelimit.Budget{Reads: limit(b.Reads), Writes: limit(b.Writes), Watches: limit(b.Watches)}
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
+)
+
+func TestRateLimiterStatus(t *testing.T) {
+	resp := &v3.RateLimitStatusResponse{
+		Header:  &pb.ResponseHeader{MemberId: 1},
+		Enabled: true,
+		Policy: &pb.RateLimitPolicy{
+			Users:   map[string]*pb.RateLimitBudget{"a": {Writes: &pb.RateLimit{Rate: 10}}},
+			Default: &pb.RateLimitBudget{Reads: &pb.RateLimit{Rate: 1.5, Burst: 3}},
+		},
+		Buckets:  2,
+		Rejected: map[string]uint64{"write": 3},
+	}
+	want := &v3ratelimit.Status{
+		MemberID: 1,
+		Policy: &v3ratelimit.Policy{
+			Users:   map[string]v3ratelimit.Budget{"a": {Writes: &v3ratelimit.Limit{Rate: 10}}},
+			Default: &v3ratelimit.Budget{Reads: &v3ratelimit.Limit{Rate: 1.5, Burst: 3}},
+		},
+		Buckets:  2,
+		Rejected: map[v3ratelimit.Class]uint64{v3ratelimit.ClassWrite: 3},
+	}
+	if got := rateLimiterStatus(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("status = %+v, want %+v", got, want)
+	}
+}
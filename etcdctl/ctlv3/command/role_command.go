@@ -17,8 +17,10 @@ package command
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/authpb"
 	"go.etcd.io/etcd/client/v3"
 )
 
@@ -40,6 +42,8 @@ func NewRoleCommand() *cobra.Command {
 	ac.AddCommand(newRoleListCommand())
 	ac.AddCommand(newRoleGrantPermissionCommand())
 	ac.AddCommand(newRoleRevokePermissionCommand())
+	ac.AddCommand(newRoleCloneCommand())
+	ac.AddCommand(newRoleDiffCommand())
 
 	return ac
 }
@@ -102,6 +106,22 @@ func newRoleRevokePermissionCommand() *cobra.Command {
 	return cmd
 }
 
+func newRoleCloneCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clone <source role> <destination role>",
+		Short: "Creates a new role with the permissions of an existing role",
+		Run:   roleCloneCommandFunc,
+	}
+}
+
+func newRoleDiffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <role name> <role name>",
+		Short: "Prints the permission differences between two roles",
+		Run:   roleDiffCommandFunc,
+	}
+}
+
 // roleAddCommandFunc executes the "role add" command.
 func roleAddCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
@@ -193,6 +213,125 @@ func roleRevokePermissionCommandFunc(cmd *cobra.Command, args []string) {
 	display.RoleRevokePermission(args[0], args[1], rangeEnd, *resp)
 }
 
+// roleCloneCommandFunc executes the "role clone" command.
+func roleCloneCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("role clone command requires source and destination role names as its arguments"))
+	}
+	src, dst := args[0], args[1]
+
+	c := mustClientFromCmd(cmd)
+	resp, err := c.Auth.RoleGet(context.TODO(), src)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if _, err = c.Auth.RoleAdd(context.TODO(), dst); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	for i, perm := range resp.Perm {
+		_, err = c.Auth.RoleGrantPermission(context.TODO(), dst, string(perm.Key), string(perm.RangeEnd), clientv3.PermissionType(perm.PermType))
+		if err != nil {
+			ExitWithError(ExitError, fmt.Errorf("role %s created but only %d of %d permissions were copied (%v)", dst, i, len(resp.Perm), err))
+		}
+	}
+
+	display.RoleClone(src, dst, *resp)
+}
+
+// roleDiffCommandFunc executes the "role diff" command.
+func roleDiffCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("role diff command requires two role names as its arguments"))
+	}
+
+	c := mustClientFromCmd(cmd)
+	from, err := c.Auth.RoleGet(context.TODO(), args[0])
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	to, err := c.Auth.RoleGet(context.TODO(), args[1])
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+
+	d := diffRolePerms(from.Perm, to.Perm)
+	d.From, d.To = args[0], args[1]
+	display.RoleDiff(d)
+}
+
+// rolePerm is a permission of a role, as printed by "role diff".
+type rolePerm struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"range_end,omitempty"`
+	PermType string `json:"perm_type"`
+}
+
+// rolePermChange is a key range granted to both roles with different
+// permission types.
+type rolePermChange struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"range_end,omitempty"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+type roleDiff struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Added   []rolePerm       `json:"added"`
+	Removed []rolePerm       `json:"removed"`
+	Changed []rolePermChange `json:"changed"`
+}
+
+// diffRolePerms compares the permissions of two roles by key range. Added
+// permissions are only granted to the second role, removed ones only to
+// the first.
+func diffRolePerms(from, to []*authpb.Permission) roleDiff {
+	type permRange struct{ key, end string }
+	index := func(perms []*authpb.Permission) map[permRange]string {
+		m := make(map[permRange]string, len(perms))
+		for _, p := range perms {
+			m[permRange{string(p.Key), string(p.RangeEnd)}] = p.PermType.String()
+		}
+		return m
+	}
+	fm, tm := index(from), index(to)
+
+	var d roleDiff
+	for r, t := range tm {
+		ft, ok := fm[r]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, rolePerm{Key: r.key, RangeEnd: r.end, PermType: t})
+		case ft != t:
+			d.Changed = append(d.Changed, rolePermChange{Key: r.key, RangeEnd: r.end, From: ft, To: t})
+		}
+	}
+	for r, t := range fm {
+		if _, ok := tm[r]; !ok {
+			d.Removed = append(d.Removed, rolePerm{Key: r.key, RangeEnd: r.end, PermType: t})
+		}
+	}
+
+	sortPerms := func(ps []rolePerm) {
+		sort.Slice(ps, func(i, j int) bool {
+			if ps[i].Key != ps[j].Key {
+				return ps[i].Key < ps[j].Key
+			}
+			return ps[i].RangeEnd < ps[j].RangeEnd
+		})
+	}
+	sortPerms(d.Added)
+	sortPerms(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool {
+		if d.Changed[i].Key != d.Changed[j].Key {
+			return d.Changed[i].Key < d.Changed[j].Key
+		}
+		return d.Changed[i].RangeEnd < d.Changed[j].RangeEnd
+	})
+	return d
+}
+
 func permRange(args []string) (string, string) {
 	key := args[0]
 	var rangeEnd string
@@ -0,0 +1,52 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/authpb"
+)
+
+func TestDiffRolePerms(t *testing.T) {
+	perm := func(key, end string, typ authpb.Permission_Type) *authpb.Permission {
+		return &authpb.Permission{Key: []byte(key), RangeEnd: []byte(end), PermType: typ}
+	}
+	from := []*authpb.Permission{
+		perm("a", "", authpb.READ),
+		perm("b", "c", authpb.READ),
+		perm("d", "", authpb.WRITE),
+	}
+	to := []*authpb.Permission{
+		perm("a", "", authpb.READ),
+		perm("b", "c", authpb.READWRITE),
+		perm("d", "\x00", authpb.WRITE),
+	}
+
+	d := diffRolePerms(from, to)
+	want := roleDiff{
+		Added:   []rolePerm{{Key: "d", RangeEnd: "\x00", PermType: "WRITE"}},
+		Removed: []rolePerm{{Key: "d", PermType: "WRITE"}},
+		Changed: []rolePermChange{{Key: "b", RangeEnd: "c", From: "READ", To: "READWRITE"}},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("expected %+v, got %+v", want, d)
+	}
+
+	if d = diffRolePerms(from, from); len(d.Added)+len(d.Removed)+len(d.Changed) != 0 {
+		t.Errorf("expected no difference, got %+v", d)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+var (
+	sizePrefix    string
+	sizeDepth     int
+	sizePageSize  int64
+	sizeSeparator string
+)
+
+// NewSizeCommand returns the cobra command for "size".
+func NewSizeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "size [options]",
+		Short: "Reports key count and space usage grouped by key prefix",
+		Run:   sizeCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&sizePrefix, "prefix", "", "Only account keys under this prefix")
+	cmd.Flags().IntVar(&sizeDepth, "depth", 1, "Number of key path segments after the prefix to group by")
+	cmd.Flags().StringVar(&sizeSeparator, "separator", "/", "Separator between key path segments")
+	cmd.Flags().Int64Var(&sizePageSize, "page-size", 1000, "Number of keys fetched per range request")
+	return cmd
+}
+
+// prefixUsage is the space used by all keys sharing a key prefix.
+type prefixUsage struct {
+	Prefix     string `json:"prefix"`
+	Keys       int64  `json:"keys"`
+	KeyBytes   int64  `json:"key_bytes"`
+	ValueBytes int64  `json:"value_bytes"`
+	// Revisions is the number of revisions of the keys since their creation.
+	Revisions int64 `json:"revisions"`
+}
+
+// sizeCommandFunc executes the "size" command.
+func sizeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("size command does not accept arguments, use --prefix instead"))
+	}
+	if sizeDepth < 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--depth must not be negative"))
+	}
+	if sizePageSize <= 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--page-size must be positive"))
+	}
+
+	key, end := sizePrefix, clientv3.GetPrefixRangeEnd(sizePrefix)
+	if key == "" {
+		key = "\x00"
+	}
+
+	groups := make(map[string]*prefixUsage)
+	c := mustClientFromCmd(cmd)
+	_, err := rangeAll(cmd, c, key, end, 0, sizePageSize, func(kv *mvccpb.KeyValue) {
+		p := groupPrefix(string(kv.Key), sizePrefix, sizeSeparator, sizeDepth)
+		u, ok := groups[p]
+		if !ok {
+			u = &prefixUsage{Prefix: p}
+			groups[p] = u
+		}
+		u.Keys++
+		u.KeyBytes += int64(len(kv.Key))
+		u.ValueBytes += int64(len(kv.Value))
+		u.Revisions += kv.Version
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+
+	usage := make([]prefixUsage, 0, len(groups))
+	for _, u := range groups {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		si, sj := usage[i].KeyBytes+usage[i].ValueBytes, usage[j].KeyBytes+usage[j].ValueBytes
+		if si != sj {
+			return si > sj
+		}
+		return usage[i].Prefix < usage[j].Prefix
+	})
+	display.KeyspaceUsage(usage)
+}
+
+// groupPrefix returns the prefix of key made of the given prefix followed by
+// at most depth separator-terminated path segments. A separator at the start
+// of the remaining key does not count as a segment.
+func groupPrefix(key, prefix, sep string, depth int) string {
+	rest := strings.TrimPrefix(key, prefix)
+	lead := ""
+	if sep != "" && strings.HasPrefix(rest, sep) {
+		lead, rest = sep, rest[len(sep):]
+	}
+	if depth == 0 || sep == "" {
+		return prefix
+	}
+	parts := strings.SplitAfterN(rest, sep, depth+1)
+	if len(parts) <= depth {
+		// fewer segments than depth; the key is its own group
+		return key
+	}
+	return prefix + lead + strings.Join(parts[:depth], "")
+}
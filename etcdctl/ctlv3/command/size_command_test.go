@@ -0,0 +1,41 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import "testing"
+
+func TestGroupPrefix(t *testing.T) {
+	tt := []struct {
+		key, prefix string
+		depth       int
+
+		group string
+	}{
+		{"/registry/pods/ns/a", "", 1, "/registry/"},
+		{"/registry/pods/ns/a", "", 2, "/registry/pods/"},
+		{"/registry/pods/ns/a", "/registry/", 1, "/registry/pods/"},
+		{"/registry/pods/ns/a", "/registry/", 2, "/registry/pods/ns/"},
+		{"/registry/pods/ns/a", "/registry/", 0, "/registry/"},
+		{"/registry/pods", "/registry/", 1, "/registry/pods"},
+		{"/registry/pods", "/registry/", 3, "/registry/pods"},
+		{"foo/bar", "", 1, "foo/"},
+		{"foo", "", 1, "foo"},
+	}
+	for i, ts := range tt {
+		if g := groupPrefix(ts.key, ts.prefix, "/", ts.depth); g != ts.group {
+			t.Errorf("#%d: group of %q expected %q, got %q", i, ts.key, ts.group, g)
+		}
+	}
+}
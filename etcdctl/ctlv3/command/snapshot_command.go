@@ -16,9 +16,16 @@ package command
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"go.etcd.io/etcd/etcdctl/v3/snapshot"
 
@@ -39,8 +46,42 @@ var (
 	restorePeerURLs     string
 	restoreName         string
 	skipHashCheck       bool
+	restoreIncrementals []string
+	restoreIncludes     []string
+	restoreExcludes     []string
+	restoreRewrites     []string
+	restoreResume       bool
+	restoreBatchBytes   int64
+	restoreBatchKeys    int
+
+	saveVerify   bool
+	saveMetadata bool
+
+	backupIncremental bool
+	backupSinceRev    int64
+
+	analyzeSeparator string
+	analyzeDepth     int
+	analyzeTop       int
+
+	trimExcludePrefixes []string
+	trimCompact         bool
+
+	snapshotDiffPrefix  string
+	snapshotDiffSummary bool
 )
 
+// snapshotMetadata describes a saved snapshot file.
+type snapshotMetadata struct {
+	Path           string    `json:"path"`
+	SHA256         string    `json:"sha256"`
+	Revision       int64     `json:"revision"`
+	TotalKey       int       `json:"totalKey"`
+	TotalSize      int64     `json:"totalSize"`
+	ClusterVersion string    `json:"clusterVersion,omitempty"`
+	SavedAt        time.Time `json:"savedAt"`
+}
+
 // NewSnapshotCommand returns the cobra command for "snapshot".
 func NewSnapshotCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -50,19 +91,41 @@ func NewSnapshotCommand() *cobra.Command {
 	cmd.AddCommand(NewSnapshotSaveCommand())
 	cmd.AddCommand(NewSnapshotRestoreCommand())
 	cmd.AddCommand(newSnapshotStatusCommand())
+	cmd.AddCommand(NewSnapshotBackupCommand())
+	cmd.AddCommand(newSnapshotAnalyzeCommand())
+	cmd.AddCommand(newSnapshotTrimCommand())
+	cmd.AddCommand(newSnapshotDiffCommand())
 	return cmd
 }
 
 func NewSnapshotSaveCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "save <filename>",
 		Short: "Stores an etcd node backend snapshot to a given file",
 		Run:   snapshotSaveCommandFunc,
 	}
+	cmd.Flags().BoolVar(&saveVerify, "verify", false, "Re-open the saved snapshot and verify its integrity before exiting")
+	cmd.Flags().BoolVar(&saveMetadata, "metadata", false, "Write the snapshot metadata as JSON to <filename>.metadata.json")
+	cmd.Flags().StringVar(&snapshotS3SSE, "s3-sse", "", "Server-side encryption for s3:// locations (AES256 or aws:kms)")
+	cmd.Flags().StringVar(&snapshotS3SSEKMSKeyID, "s3-sse-kms-key-id", "", "KMS key ID for s3:// locations encrypted with aws:kms")
+	cmd.Flags().IntVar(&snapshotUploadRetries, "upload-retries", 3, "Number of times to retry a failed upload to object storage")
+	cmd.Flags().StringVar(&snapshotEncryptKey, "encrypt-key", "", "Encrypt the snapshot with the key in this file, or with a data key of the KMS key kms://<key-id>")
+	return cmd
+}
+
+func NewSnapshotBackupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup --incremental --since-rev <revision> <filename>",
+		Short: "Stores the changes made since a previous snapshot to a given file",
+		Run:   snapshotBackupCommandFunc,
+	}
+	cmd.Flags().BoolVar(&backupIncremental, "incremental", false, "Store only the changes made after --since-rev")
+	cmd.Flags().Int64Var(&backupSinceRev, "since-rev", 0, "Revision of the previous snapshot or incremental backup")
+	return cmd
 }
 
 func newSnapshotStatusCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status <filename>",
 		Short: "Gets backend snapshot status of a given file",
 		Long: `When --write-out is set to simple, this command prints out comma-separated status lists for each endpoint.
@@ -70,6 +133,59 @@ The items in the lists are hash, revision, total keys, total size.
 `,
 		Run: snapshotStatusCommandFunc,
 	}
+	cmd.Flags().StringVar(&snapshotEncryptKey, "encrypt-key", "", "Key file, or kms://<key-id>, to decrypt an encrypted snapshot with")
+	return cmd
+}
+
+func newSnapshotAnalyzeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "analyze <filename> [options]",
+		Short: "Reports what fills a backend snapshot file",
+		Long: `Reports the key count, the histograms of the value sizes and of the revisions
+kept per key, the space used per key prefix, the keys attached to leases and
+the largest keys of a snapshot file. The file is only read; no member is
+contacted.
+`,
+		Run: snapshotAnalyzeCommandFunc,
+	}
+	cmd.Flags().StringVar(&analyzeSeparator, "separator", "/", "Separator between key path segments")
+	cmd.Flags().IntVar(&analyzeDepth, "depth", 1, "Number of key path segments to group the space usage by")
+	cmd.Flags().IntVar(&analyzeTop, "top", 10, "Number of largest keys, most revised keys and leases to list")
+	return cmd
+}
+
+func newSnapshotTrimCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trim --exclude-prefix <prefix> [options] <filename> <output filename>",
+		Short: "Writes a copy of a snapshot file without the keys of given prefixes",
+		Long: `Writes a copy of a snapshot file without the keys of the excluded prefixes
+and, with --compact, without the past revisions of the other keys, so that
+a smaller snapshot can be restored, for instance to clone a production
+cluster without its bulky or sensitive data. The copy is followed by its
+sha256 digest like a saved snapshot.
+`,
+		Run: snapshotTrimCommandFunc,
+	}
+	cmd.Flags().StringArrayVar(&trimExcludePrefixes, "exclude-prefix", nil, "Prefix of the keys to remove (repeatable)")
+	cmd.Flags().BoolVar(&trimCompact, "compact", false, "Keep only the latest revision of the remaining keys")
+	return cmd
+}
+
+func newSnapshotDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <filename> <other filename> [options]",
+		Short: "Compares the keys of two snapshot files",
+		Long: `Compares the keys of two snapshot files at their revisions, as "diff" compares
+the keyspace of two revisions or two clusters. Keys only present in the
+second snapshot are added, keys only present in the first one are removed,
+and keys whose value differs are changed. The files are only read; no member
+is contacted.
+`,
+		Run: snapshotDiffCommandFunc,
+	}
+	cmd.Flags().StringVar(&snapshotDiffPrefix, "prefix", "", "Only compare keys under this prefix")
+	cmd.Flags().BoolVar(&snapshotDiffSummary, "summary", false, "Only print the counts of the keys that differ")
+	return cmd
 }
 
 func NewSnapshotRestoreCommand() *cobra.Command {
@@ -85,6 +201,14 @@ func NewSnapshotRestoreCommand() *cobra.Command {
 	cmd.Flags().StringVar(&restorePeerURLs, "initial-advertise-peer-urls", defaultInitialAdvertisePeerURLs, "List of this member's peer URLs to advertise to the rest of the cluster")
 	cmd.Flags().StringVar(&restoreName, "name", defaultName, "Human-readable name for this member")
 	cmd.Flags().BoolVar(&skipHashCheck, "skip-hash-check", false, "Ignore snapshot integrity hash value (required if copied from data directory)")
+	cmd.Flags().StringArrayVar(&restoreIncrementals, "incremental", nil, "Incremental backup to apply on top of the snapshot (repeatable, in order)")
+	cmd.Flags().StringArrayVar(&restoreIncludes, "include-prefix", nil, "Prefix of the keys to restore (repeatable, all keys if none given)")
+	cmd.Flags().StringArrayVar(&restoreExcludes, "exclude-prefix", nil, "Prefix of the keys not to restore (repeatable)")
+	cmd.Flags().StringArrayVar(&restoreRewrites, "rewrite-prefix", nil, "Rewrites the prefix of the restored keys, as old=new (repeatable, first match applies)")
+	cmd.Flags().BoolVar(&restoreResume, "resume", false, "Resume the interrupted restore to the data directory, if any")
+	cmd.Flags().Int64Var(&restoreBatchBytes, "batch-bytes", 64*1024*1024, "Bytes of the snapshot copied between resumable checkpoints, and written by a backend transaction applying incremental backups before it is committed")
+	cmd.Flags().IntVar(&restoreBatchKeys, "batch-keys", 0, "Keys written by a backend transaction applying incremental backups before it is committed (backend default if 0)")
+	cmd.Flags().StringVar(&snapshotEncryptKey, "encrypt-key", "", "Key file, or kms://<key-id>, to decrypt an encrypted snapshot with")
 
 	return cmd
 }
@@ -109,11 +233,137 @@ func snapshotSaveCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	defer cancel()
 
+	enc, err := newSnapshotEncryption()
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	if enc != nil && (saveVerify || saveMetadata) {
+		// the snapshot digest is verified while it is encrypted
+		ExitWithError(ExitBadArgs, fmt.Errorf("--verify and --metadata are not supported with --encrypt-key"))
+	}
+
 	path := args[0]
+	if path == "-" {
+		if saveMetadata {
+			ExitWithError(ExitBadArgs, fmt.Errorf("--metadata is not supported when streaming to stdout"))
+		}
+		w, err := enc.writer(os.Stdout)
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		if err = streamSnapshot(ctx, *cfg, w, "stdout", os.Stderr); err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			ExitWithError(ExitInterrupted, err)
+		}
+		return
+	}
+	remote, isRemote, err := parseSnapshotRemote(path)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	if isRemote {
+		if saveMetadata {
+			ExitWithError(ExitBadArgs, fmt.Errorf("--metadata is not supported when uploading to object storage"))
+		}
+		if err := uploadSnapshot(ctx, *cfg, remote, enc); err != nil {
+			ExitWithError(ExitInterrupted, err)
+		}
+		fmt.Printf("Snapshot saved at %s\n", path)
+		return
+	}
+	if enc != nil {
+		if err := saveEncryptedSnapshot(ctx, *cfg, path, enc); err != nil {
+			ExitWithError(ExitInterrupted, err)
+		}
+		fmt.Printf("Snapshot saved at %s\n", path)
+		return
+	}
 	if err := sp.Save(ctx, *cfg, path); err != nil {
 		ExitWithError(ExitInterrupted, err)
 	}
 	fmt.Printf("Snapshot saved at %s\n", path)
+
+	if saveVerify {
+		if err := sp.Verify(path); err != nil {
+			ExitWithError(ExitError, fmt.Errorf("snapshot verification failed (%v)", err))
+		}
+		fmt.Println("Snapshot verified")
+	}
+
+	md, err := snapshotMetadataOf(sp, path)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("sha256: %s, revision: %d, total keys: %d, total size: %d, cluster version: %s\n",
+		md.SHA256, md.Revision, md.TotalKey, md.TotalSize, md.ClusterVersion)
+	if saveMetadata {
+		b, err := json.MarshalIndent(md, "", "  ")
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		if err = ioutil.WriteFile(path+".metadata.json", append(b, '\n'), 0600); err != nil {
+			ExitWithError(ExitIO, err)
+		}
+	}
+}
+
+// snapshotMetadataOf returns the checksum and the status of the snapshot
+// file at the given path.
+func snapshotMetadataOf(sp snapshot.Manager, path string) (snapshotMetadata, error) {
+	md := snapshotMetadata{Path: path, SavedAt: time.Now().UTC()}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return md, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return md, err
+	}
+	md.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	ds, err := sp.Status(path)
+	if err != nil {
+		return md, err
+	}
+	md.Revision, md.TotalKey, md.TotalSize, md.ClusterVersion = ds.Revision, ds.TotalKey, ds.TotalSize, ds.ClusterVersion
+	return md, nil
+}
+
+func snapshotBackupCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		err := fmt.Errorf("snapshot backup expects one argument")
+		ExitWithError(ExitBadArgs, err)
+	}
+	if !backupIncremental {
+		ExitWithError(ExitBadArgs, fmt.Errorf("snapshot backup only supports --incremental, use snapshot save for full backups"))
+	}
+	if backupSinceRev <= 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("snapshot backup --incremental requires --since-rev"))
+	}
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	sp := snapshot.NewV3(lg)
+	cfg := mustClientCfgFromCmd(cmd)
+
+	// as with snapshot save, there is no timeout unless "--command-timeout" is set
+	ctx, cancel := context.WithCancel(context.Background())
+	if isCommandTimeoutFlagSet(cmd) {
+		ctx, cancel = commandCtx(cmd)
+	}
+	defer cancel()
+
+	st, err := sp.SaveIncremental(ctx, *cfg, backupSinceRev, args[0])
+	if err != nil {
+		ExitWithError(ExitInterrupted, err)
+	}
+	fmt.Printf("Incremental backup saved at %s (base revision %d, revision %d, %d events)\n", args[0], st.BaseRevision, st.Revision, st.Events)
 }
 
 func snapshotStatusCommandFunc(cmd *cobra.Command, args []string) {
@@ -128,18 +378,110 @@ func snapshotStatusCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitError, err)
 	}
 	sp := snapshot.NewV3(lg)
-	ds, err := sp.Status(args[0])
+	path, cleanup := mustDecryptSnapshot(args[0])
+	ds, err := sp.Status(path)
+	cleanup()
 	if err != nil {
 		ExitWithError(ExitError, err)
 	}
 	display.DBStatus(ds)
 }
 
+func snapshotAnalyzeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		err := fmt.Errorf("snapshot analyze requires exactly one argument")
+		ExitWithError(ExitBadArgs, err)
+	}
+	if analyzeDepth < 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--depth must not be negative"))
+	}
+	if analyzeTop < 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--top must not be negative"))
+	}
+	initDisplayFromCmd(cmd)
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	sp := snapshot.NewV3(lg)
+	a, err := sp.Analyze(args[0], snapshot.AnalyzeConfig{
+		Group: func(key string) string { return groupPrefix(key, "", analyzeSeparator, analyzeDepth) },
+		Top:   analyzeTop,
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.SnapshotAnalysis(a)
+}
+
+func snapshotTrimCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		err := fmt.Errorf("snapshot trim requires exactly two arguments")
+		ExitWithError(ExitBadArgs, err)
+	}
+	if len(trimExcludePrefixes) == 0 && !trimCompact {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--exclude-prefix or --compact is required"))
+	}
+	for _, p := range trimExcludePrefixes {
+		if p == "" {
+			ExitWithError(ExitBadArgs, fmt.Errorf("--exclude-prefix must not be empty"))
+		}
+	}
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	sp := snapshot.NewV3(lg)
+	st, err := sp.Trim(snapshot.TrimConfig{
+		SnapshotPath:    args[0],
+		OutputPath:      args[1],
+		ExcludePrefixes: trimExcludePrefixes,
+		Compact:         trimCompact,
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Printf("Trimmed snapshot saved at %s (revision %d, %d revisions kept, %d removed, total size %d)\n",
+		args[1], st.Revision, st.Revisions, st.RemovedRevisions, st.TotalSize)
+}
+
+func snapshotDiffCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		err := fmt.Errorf("snapshot diff requires exactly two arguments")
+		ExitWithError(ExitBadArgs, err)
+	}
+	initDisplayFromCmd(cmd)
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	sp := snapshot.NewV3(lg)
+	d, err := sp.Diff(args[0], args[1], snapshotDiffPrefix)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.KeyspaceDiff(keyspaceDiff{
+		Revision:     d.RevisionA,
+		DestRevision: d.RevisionB,
+		Added:        d.Added,
+		Removed:      d.Removed,
+		Changed:      d.Changed,
+		summary:      snapshotDiffSummary,
+	})
+}
+
 func snapshotRestoreCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		err := fmt.Errorf("snapshot restore requires exactly one argument")
 		ExitWithError(ExitBadArgs, err)
 	}
+	rewrites, err := parsePrefixRewrites(restoreRewrites)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
 
 	dataDir := restoreDataDir
 	if dataDir == "" {
@@ -157,8 +499,21 @@ func snapshotRestoreCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	sp := snapshot.NewV3(lg)
 
-	if err := sp.Restore(snapshot.RestoreConfig{
-		SnapshotPath:        args[0],
+	snapshotPath := args[0]
+	remote, isRemote, err := parseSnapshotRemote(snapshotPath)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	if isRemote {
+		if snapshotPath, err = downloadSnapshot(context.Background(), remote); err != nil {
+			ExitWithError(ExitError, err)
+		}
+	}
+	downloadPath := snapshotPath
+	snapshotPath, cleanup := mustDecryptSnapshot(snapshotPath)
+
+	err = sp.Restore(snapshot.RestoreConfig{
+		SnapshotPath:        snapshotPath,
 		Name:                restoreName,
 		OutputDataDir:       dataDir,
 		OutputWALDir:        walDir,
@@ -166,7 +521,19 @@ func snapshotRestoreCommandFunc(cmd *cobra.Command, args []string) {
 		InitialCluster:      restoreCluster,
 		InitialClusterToken: restoreClusterToken,
 		SkipHashCheck:       skipHashCheck,
-	}); err != nil {
+		IncrementalPaths:    restoreIncrementals,
+		IncludePrefixes:     restoreIncludes,
+		ExcludePrefixes:     restoreExcludes,
+		RewritePrefixes:     rewrites,
+		Resume:              restoreResume,
+		BatchBytes:          restoreBatchBytes,
+		BatchKeys:           restoreBatchKeys,
+	})
+	cleanup()
+	if isRemote {
+		os.Remove(downloadPath)
+	}
+	if err != nil {
 		ExitWithError(ExitError, err)
 	}
 }
@@ -178,3 +545,16 @@ func initialClusterFromName(name string) string {
 	}
 	return fmt.Sprintf("%s=http://localhost:2380", n)
 }
+
+// parsePrefixRewrites parses the "old=new" prefix rewrites.
+func parsePrefixRewrites(rewrites []string) ([]snapshot.PrefixRewrite, error) {
+	var prs []snapshot.PrefixRewrite
+	for _, r := range rewrites {
+		kv := strings.SplitN(r, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid prefix rewrite %q (expected old=new)", r)
+		}
+		prs = append(prs, snapshot.PrefixRewrite{Old: kv[0], New: kv[1]})
+	}
+	return prs, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/etcdctl/v3/snapshot"
+	"go.uber.org/zap"
+)
+
+// writeTestSnapshot writes a snapshot of the given revisions and cluster
+// version, with the sha256 checksum appended as the server does.
+func writeTestSnapshot(t *testing.T, path string, revs int64, clusterVersion string) []byte {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		kb, err := tx.CreateBucket([]byte("key"))
+		if err != nil {
+			return err
+		}
+		for rev := int64(1); rev <= revs; rev++ {
+			k := make([]byte, 17)
+			binary.BigEndian.PutUint64(k, uint64(rev))
+			k[8] = '_'
+			if err = kb.Put(k, []byte("value")); err != nil {
+				return err
+			}
+		}
+		cb, err := tx.CreateBucket([]byte("cluster"))
+		if err != nil {
+			return err
+		}
+		return cb.Put([]byte("clusterVersion"), []byte(clusterVersion))
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(b)
+	b = append(b, sum[:]...)
+	if err = ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSnapshotVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.db")
+	snap := writeTestSnapshot(t, path, 3, "3.5.0")
+
+	corrupted := append([]byte(nil), snap...)
+	corrupted[len(corrupted)-sha256.Size-1] ^= 0xff
+
+	tt := []struct {
+		data []byte
+
+		ok bool
+	}{
+		{snap, true},
+		{corrupted, false},
+		// no checksum
+		{snap[:len(snap)-sha256.Size], false},
+	}
+	sp := snapshot.NewV3(zap.NewExample())
+	for i, ts := range tt {
+		p := filepath.Join(dir, "verify.db")
+		if err = ioutil.WriteFile(p, ts.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = sp.Verify(p); (err == nil) != ts.ok {
+			t.Errorf("#%d: verify expected ok=%v, got %v", i, ts.ok, err)
+		}
+	}
+}
+
+func TestSnapshotMetadataOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tt := []struct {
+		revs           int64
+		clusterVersion string
+	}{
+		{3, "3.5.0"},
+		{1, ""},
+	}
+	sp := snapshot.NewV3(zap.NewExample())
+	for i, ts := range tt {
+		path := filepath.Join(dir, "snapshot.db")
+		os.Remove(path)
+		snap := writeTestSnapshot(t, path, ts.revs, ts.clusterVersion)
+		sum := sha256.Sum256(snap)
+
+		md, err := snapshotMetadataOf(sp, path)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if md.Path != path || md.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("#%d: unexpected path or checksum %+v", i, md)
+		}
+		// the keys of the key and cluster buckets
+		if md.Revision != ts.revs || md.TotalKey != int(ts.revs)+1 || md.ClusterVersion != ts.clusterVersion {
+			t.Errorf("#%d: unexpected status %+v", i, md)
+		}
+		if md.TotalSize == 0 || md.SavedAt.IsZero() {
+			t.Errorf("#%d: expected size and save time, got %+v", i, md)
+		}
+	}
+
+	if _, err = snapshotMetadataOf(sp, filepath.Join(dir, "missing.db")); err == nil {
+		t.Error("expected error for a missing snapshot")
+	}
+}
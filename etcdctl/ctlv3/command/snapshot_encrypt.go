@@ -0,0 +1,229 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/etcdctl/v3/snapshot"
+	"go.etcd.io/etcd/pkg/v3/fileutil"
+)
+
+var snapshotEncryptKey string
+
+// snapshotEncryption is the key a snapshot is encrypted with, and the
+// header recording how to get it back. The key is either read from a file,
+// or is a data key of an AWS KMS key, kms://<key-id>, generated and
+// unwrapped with the aws command line tool, as object storage transfers
+// are.
+type snapshotEncryption struct {
+	key []byte
+	hdr snapshot.EncryptionHeader
+}
+
+// newSnapshotEncryption returns the encryption selected by --encrypt-key,
+// or nil if none is.
+func newSnapshotEncryption() (*snapshotEncryption, error) {
+	if snapshotEncryptKey == "" {
+		return nil, nil
+	}
+	keyID, isKMS := kmsKeyID(snapshotEncryptKey)
+	if !isKMS {
+		key, err := readEncryptionKeyFile(snapshotEncryptKey)
+		if err != nil {
+			return nil, err
+		}
+		return &snapshotEncryption{key: key}, nil
+	}
+
+	var out struct {
+		CiphertextBlob string
+		Plaintext      string
+	}
+	if err := runKMS(&out, "generate-data-key", "--key-id", keyID, "--key-spec", "AES_256"); err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(out.CiphertextBlob)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotEncryption{key: key, hdr: snapshot.EncryptionHeader{KMSKeyID: keyID, WrappedKey: wrapped}}, nil
+}
+
+// writer returns a writer encrypting into w, or w itself if e is nil.
+func (e *snapshotEncryption) writer(w io.Writer) (io.WriteCloser, error) {
+	if e == nil {
+		return nopWriteCloser{w}, nil
+	}
+	return snapshot.NewEncryptWriter(w, e.key, e.hdr)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// saveEncryptedSnapshot streams the snapshot of the single endpoint in cfg,
+// encrypted, to path. As with unencrypted snapshots, the file only appears
+// once it is complete and synced.
+func saveEncryptedSnapshot(ctx context.Context, cfg clientv3.Config, path string, e *snapshotEncryption) error {
+	partpath := path + ".part"
+	defer os.RemoveAll(partpath)
+
+	f, err := os.OpenFile(partpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileutil.PrivateFileMode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := e.writer(f)
+	if err != nil {
+		return err
+	}
+	if err = streamSnapshot(ctx, cfg, w, path, os.Stderr); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if err = fileutil.Fsync(f); err != nil {
+		return err
+	}
+	return os.Rename(partpath, path)
+}
+
+// snapshotDecryptionKey returns the key of the encrypted snapshot, as given
+// by --encrypt-key.
+func snapshotDecryptionKey(hdr snapshot.EncryptionHeader) ([]byte, error) {
+	if snapshotEncryptKey == "" {
+		return nil, errors.New("snapshot is encrypted, --encrypt-key is required")
+	}
+	keyID, isKMS := kmsKeyID(snapshotEncryptKey)
+	if hdr.KMSKeyID == "" {
+		if isKMS {
+			return nil, errors.New("snapshot is encrypted with a key file, not a KMS key")
+		}
+		return readEncryptionKeyFile(snapshotEncryptKey)
+	}
+	if !isKMS {
+		return nil, fmt.Errorf("snapshot is encrypted with KMS key %q, not a key file", hdr.KMSKeyID)
+	}
+
+	// the aws command line tool reads binary parameters from files only
+	f, err := ioutil.TempFile("", "etcd-snapshot-key")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(hdr.WrappedKey)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out struct{ Plaintext string }
+	if err = runKMS(&out, "decrypt", "--key-id", keyID, "--ciphertext-blob", "fileb://"+f.Name()); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(out.Plaintext)
+}
+
+// mustDecryptSnapshot returns the path of the snapshot to read: path itself,
+// or a temporary file the encrypted snapshot is decrypted to, which the
+// returned function removes.
+func mustDecryptSnapshot(path string) (string, func()) {
+	encrypted, err := snapshot.IsEncrypted(path)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if !encrypted {
+		return path, func() {}
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	defer in.Close()
+	out, err := ioutil.TempFile("", "etcd-snapshot-*.db")
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	remove := func() { os.Remove(out.Name()) }
+	r, err := snapshot.NewDecryptReader(in, snapshotDecryptionKey)
+	if err == nil {
+		_, err = io.Copy(out, r)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		remove()
+		ExitWithError(ExitError, fmt.Errorf("cannot decrypt snapshot %s (%v)", path, err))
+	}
+	return out.Name(), remove
+}
+
+func kmsKeyID(spec string) (string, bool) {
+	if !strings.HasPrefix(spec, "kms://") {
+		return "", false
+	}
+	return strings.TrimPrefix(spec, "kms://"), true
+}
+
+// readEncryptionKeyFile reads a key from the file, either raw or
+// hex-encoded.
+func readEncryptionKeyFile(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == snapshot.EncryptionKeySize {
+		return b, nil
+	}
+	if h := bytes.TrimSpace(b); len(h) == hex.EncodedLen(snapshot.EncryptionKeySize) {
+		if key, err := hex.DecodeString(string(h)); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("encryption key file %s must hold a %d-byte key, raw or hex-encoded", path, snapshot.EncryptionKeySize)
+}
+
+// runKMS runs the aws kms command and decodes its JSON output into v.
+func runKMS(v interface{}, args ...string) error {
+	args = append([]string{"kms"}, append(args, "--output", "json")...)
+	cmd := exec.Command("aws", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("aws kms %s failed (%v)", args[1], err)
+	}
+	return json.Unmarshal(out, v)
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.etcd.io/etcd/etcdctl/v3/snapshot"
+)
+
+func TestReadEncryptionKeyFile(t *testing.T) {
+	key := bytes.Repeat([]byte{0xab}, snapshot.EncryptionKeySize)
+	hexKey := strings.Repeat("ab", snapshot.EncryptionKeySize)
+	tt := []struct {
+		content string
+
+		err bool
+	}{
+		{string(key), false},
+		{hexKey, false},
+		{hexKey + "\n", false},
+		{hexKey[2:], true},
+		{strings.Repeat("zz", snapshot.EncryptionKeySize), true},
+		{"", true},
+	}
+	dir, err := ioutil.TempDir("", "etcd-snapshot-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, ts := range tt {
+		path := filepath.Join(dir, "key")
+		if err = ioutil.WriteFile(path, []byte(ts.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		k, err := readEncryptionKeyFile(path)
+		if (err != nil) != ts.err {
+			t.Errorf("#%d: expected error %v, got %v", i, ts.err, err)
+		}
+		if err == nil && !bytes.Equal(k, key) {
+			t.Errorf("#%d: expected key %x, got %x", i, key, k)
+		}
+	}
+}
+
+func TestSnapshotEncryptionRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcd-snapshot-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyPath := filepath.Join(dir, "key")
+	if err = ioutil.WriteFile(keyPath, []byte(strings.Repeat("01", snapshot.EncryptionKeySize)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(k string) { snapshotEncryptKey = k }(snapshotEncryptKey)
+	snapshotEncryptKey = keyPath
+
+	e, err := newSnapshotEncryption()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("etcd snapshot "), 20000)
+	var buf bytes.Buffer
+	w, err := e.writer(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := snapshot.NewDecryptReader(bytes.NewReader(buf.Bytes()), snapshotDecryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("decrypted snapshot differs from the original")
+	}
+
+	r, err = snapshot.NewDecryptReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), snapshotDecryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ioutil.ReadAll(r); err != snapshot.ErrEncryptedTruncated {
+		t.Fatalf("expected %v, got %v", snapshot.ErrEncryptedTruncated, err)
+	}
+
+	snapshotEncryptKey = ""
+	if _, err = snapshot.NewDecryptReader(bytes.NewReader(buf.Bytes()), snapshotDecryptionKey); err == nil {
+		t.Fatalf("expected decryption without --encrypt-key to fail")
+	}
+}
+
+func TestSnapshotDecryptInvalidHeader(t *testing.T) {
+	key := func(snapshot.EncryptionHeader) ([]byte, error) { return make([]byte, snapshot.EncryptionKeySize), nil }
+	encrypted := func(hdr []byte, n uint32) []byte {
+		b := append([]byte("etcd-enc"), 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[8:], n)
+		return append(b, hdr...)
+	}
+	hdr := func(chunkSize int) []byte {
+		b, err := json.Marshal(snapshot.EncryptionHeader{NoncePrefix: make([]byte, 7), ChunkSize: chunkSize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	tests := map[string][]byte{
+		"header too large":     encrypted(nil, 1<<31),
+		"chunk size zero":      encrypted(hdr(0), uint32(len(hdr(0)))),
+		"chunk size too large": encrypted(hdr(1<<30), uint32(len(hdr(1<<30)))),
+	}
+	for name, b := range tests {
+		if _, err := snapshot.NewDecryptReader(bytes.NewReader(b), key); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	// a valid header
+	if _, err := snapshot.NewDecryptReader(bytes.NewReader(encrypted(hdr(64*1024), uint32(len(hdr(64*1024))))), key); err != nil {
+		t.Fatal(err)
+	}
+}
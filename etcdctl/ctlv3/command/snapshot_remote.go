@@ -0,0 +1,193 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+var (
+	snapshotS3SSE         string
+	snapshotS3SSEKMSKeyID string
+	snapshotUploadRetries int
+)
+
+// snapshotRemote is a snapshot location in object storage. Transfers are
+// streamed through the storage provider's command line tool, which handles
+// credentials and multipart uploads:
+//
+//	s3://bucket/path              aws s3 cp
+//	gs://bucket/path              gsutil cp
+//	azblob://account/container/path  azcopy cp
+type snapshotRemote struct {
+	scheme string
+	url    string
+}
+
+// parseSnapshotRemote returns the remote location of path, or false if
+// path is a local file.
+func parseSnapshotRemote(path string) (snapshotRemote, bool, error) {
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme == "" {
+		return snapshotRemote{}, false, nil
+	}
+	switch u.Scheme {
+	case "s3", "gs", "azblob":
+	default:
+		return snapshotRemote{}, false, nil
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return snapshotRemote{}, false, fmt.Errorf("invalid snapshot location %q, expected %s://bucket/path", path, u.Scheme)
+	}
+	return snapshotRemote{scheme: u.Scheme, url: path}, true, nil
+}
+
+// azblobURL converts azblob://account/container/path to the blob endpoint,
+// authenticated with $AZURE_STORAGE_SAS_TOKEN if set.
+func (r snapshotRemote) azblobURL() string {
+	u := "https://" + strings.Replace(strings.TrimPrefix(r.url, "azblob://"), "/", ".blob.core.windows.net/", 1)
+	if sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sas != "" {
+		u += "?" + strings.TrimPrefix(sas, "?")
+	}
+	return u
+}
+
+// uploadArgs returns the command that uploads its standard input to r.
+func (r snapshotRemote) uploadArgs() ([]string, error) {
+	if r.scheme != "s3" && (snapshotS3SSE != "" || snapshotS3SSEKMSKeyID != "") {
+		return nil, errors.New("--s3-sse and --s3-sse-kms-key-id are only supported for s3:// locations")
+	}
+	switch r.scheme {
+	case "s3":
+		args := []string{"aws", "s3", "cp", "-", r.url}
+		if snapshotS3SSE != "" {
+			args = append(args, "--sse", snapshotS3SSE)
+		}
+		if snapshotS3SSEKMSKeyID != "" {
+			args = append(args, "--sse-kms-key-id", snapshotS3SSEKMSKeyID)
+		}
+		return args, nil
+	case "gs":
+		return []string{"gsutil", "cp", "-", r.url}, nil
+	default:
+		return []string{"azcopy", "cp", r.azblobURL(), "--from-to", "PipeBlob"}, nil
+	}
+}
+
+// downloadArgs returns the command that writes r to its standard output.
+func (r snapshotRemote) downloadArgs() []string {
+	switch r.scheme {
+	case "s3":
+		return []string{"aws", "s3", "cp", r.url, "-"}
+	case "gs":
+		return []string{"gsutil", "cp", r.url, "-"}
+	default:
+		return []string{"azcopy", "cp", r.azblobURL(), "--from-to", "BlobPipe"}
+	}
+}
+
+// uploadSnapshot streams the snapshot of the single endpoint in cfg to r,
+// encrypted with e if not nil, retrying the whole transfer on failure. An
+// upload is aborted, rather than completed, if the snapshot fails its
+// integrity check.
+func uploadSnapshot(ctx context.Context, cfg clientv3.Config, r snapshotRemote, e *snapshotEncryption) error {
+	args, err := r.uploadArgs()
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err = uploadSnapshotOnce(ctx, cfg, r, args, e)
+		if err == nil || attempt >= snapshotUploadRetries || ctx.Err() != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Failed to upload snapshot to %s (%v), retrying\n", r.url, err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+}
+
+func uploadSnapshotOnce(ctx context.Context, cfg clientv3.Config, r snapshotRemote, args []string, e *snapshotEncryption) error {
+	upctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(upctx, args[0], args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	w, err := e.writer(stdin)
+	if err == nil {
+		err = streamSnapshot(ctx, cfg, w, r.url, os.Stderr)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// kill the uploader before it sees the end of the input, so the
+		// object is never completed
+		cancel()
+		cmd.Wait()
+		return err
+	}
+	stdin.Close()
+	if err = cmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed (%v)", args[0], err)
+	}
+	return nil
+}
+
+// downloadSnapshot copies r to a temporary file and returns its path.
+func downloadSnapshot(ctx context.Context, r snapshotRemote) (string, error) {
+	f, err := ioutil.TempFile("", "etcd-snapshot-*.db")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	args := r.downloadArgs()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err = cmd.Start(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	_, err = io.Copy(f, stdout)
+	if werr := cmd.Wait(); err == nil && werr != nil {
+		err = fmt.Errorf("%s failed (%v)", args[0], werr)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
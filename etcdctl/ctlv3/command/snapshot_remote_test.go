@@ -0,0 +1,58 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSnapshotRemote(t *testing.T) {
+	tt := []struct {
+		path string
+
+		remote bool
+		err    bool
+		upload []string
+	}{
+		{"snapshot.db", false, false, nil},
+		{"/backups/snapshot.db", false, false, nil},
+		{"file:///backups/snapshot.db", false, false, nil},
+		{"s3://bucket/etcd/snapshot.db", true, false, []string{"aws", "s3", "cp", "-", "s3://bucket/etcd/snapshot.db"}},
+		{"gs://bucket/snapshot.db", true, false, []string{"gsutil", "cp", "-", "gs://bucket/snapshot.db"}},
+		{"azblob://account/container/snapshot.db", true, false, []string{"azcopy", "cp", "https://account.blob.core.windows.net/container/snapshot.db", "--from-to", "PipeBlob"}},
+		{"s3://bucket", false, true, nil},
+		{"s3://bucket/", false, true, nil},
+	}
+	for i, ts := range tt {
+		r, ok, err := parseSnapshotRemote(ts.path)
+		if (err != nil) != ts.err {
+			t.Errorf("#%d: %q expected error %v, got %v", i, ts.path, ts.err, err)
+		}
+		if ok != ts.remote {
+			t.Errorf("#%d: %q expected remote %v, got %v", i, ts.path, ts.remote, ok)
+		}
+		if !ok {
+			continue
+		}
+		args, err := r.uploadArgs()
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		if !reflect.DeepEqual(args, ts.upload) {
+			t.Errorf("#%d: upload command expected %v, got %v", i, ts.upload, args)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"go.etcd.io/etcd/client/v3"
+)
+
+// streamSnapshot writes the snapshot of the single endpoint in cfg to w,
+// described by dest, reporting progress and the result on status. The sha256 digest appended
+// by the server is verified as the snapshot streams by, since the output
+// cannot be re-opened afterwards.
+func streamSnapshot(ctx context.Context, cfg clientv3.Config, w io.Writer, dest string, status io.Writer) error {
+	if len(cfg.Endpoints) != 1 {
+		return fmt.Errorf("snapshot must be requested to one selected node, not multiple %v", cfg.Endpoints)
+	}
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	rd, err := cli.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	sw := &snapshotStreamWriter{w: w, sum: sha256.New(), digest: sha256.New()}
+	donec := make(chan struct{})
+	go func() {
+		t := time.NewTicker(progressInterval)
+		defer t.Stop()
+		start := time.Now()
+		for {
+			select {
+			case <-donec:
+				return
+			case <-t.C:
+				n := atomic.LoadInt64(&sw.n)
+				rate := uint64(float64(n) / time.Since(start).Seconds())
+				fmt.Fprintf(status, "streamed %s (%s/s)\n", humanize.Bytes(uint64(n)), humanize.Bytes(rate))
+			}
+		}
+	}()
+	_, err = io.Copy(sw, rd)
+	close(donec)
+	if err != nil {
+		return err
+	}
+	if err = sw.verify(); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "Snapshot streamed to %s (%s), sha256: %s\n", dest, humanize.Bytes(uint64(sw.n)), hex.EncodeToString(sw.sum.Sum(nil)))
+	return nil
+}
+
+// snapshotStreamWriter passes the snapshot through while hashing it. The
+// last sha256.Size bytes seen are held back from the digest, since they are
+// the digest of the database that precedes them.
+type snapshotStreamWriter struct {
+	w io.Writer
+	n int64
+
+	sum    hash.Hash
+	digest hash.Hash
+	tail   []byte
+}
+
+func (sw *snapshotStreamWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	atomic.AddInt64(&sw.n, int64(n))
+	if err != nil {
+		return n, err
+	}
+	sw.sum.Write(p)
+
+	buf := append(sw.tail, p...)
+	if len(buf) > sha256.Size {
+		sw.digest.Write(buf[:len(buf)-sha256.Size])
+		buf = append([]byte(nil), buf[len(buf)-sha256.Size:]...)
+	}
+	sw.tail = buf
+	return n, nil
+}
+
+func (sw *snapshotStreamWriter) verify() error {
+	// the appended digest makes the size a multiple of 512 plus its own size
+	if sw.n%512 != sha256.Size {
+		return fmt.Errorf("sha256 checksum not found [bytes: %d]", sw.n)
+	}
+	if dbsha := sw.digest.Sum(nil); !bytes.Equal(sw.tail, dbsha) {
+		return fmt.Errorf("expected sha256 %v, got %v", sw.tail, dbsha)
+	}
+	return nil
+}
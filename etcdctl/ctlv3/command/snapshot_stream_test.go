@@ -0,0 +1,62 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestSnapshotStreamWriterVerify(t *testing.T) {
+	db := bytes.Repeat([]byte("etcd"), 256)
+	sum := sha256.Sum256(db)
+	snap := append(append([]byte(nil), db...), sum[:]...)
+
+	corrupted := append([]byte(nil), snap...)
+	corrupted[10] ^= 0xff
+
+	tt := []struct {
+		data  []byte
+		chunk int
+
+		ok bool
+	}{
+		{snap, 1, true},
+		{snap, 7, true},
+		{snap, len(snap), true},
+		{corrupted, 100, false},
+		{db, 100, false},
+	}
+	for i, ts := range tt {
+		var out bytes.Buffer
+		sw := &snapshotStreamWriter{w: &out, sum: sha256.New(), digest: sha256.New()}
+		for off := 0; off < len(ts.data); off += ts.chunk {
+			end := off + ts.chunk
+			if end > len(ts.data) {
+				end = len(ts.data)
+			}
+			if _, err := sw.Write(ts.data[off:end]); err != nil {
+				t.Fatalf("#%d: unexpected write error %v", i, err)
+			}
+		}
+		if !bytes.Equal(out.Bytes(), ts.data) {
+			t.Errorf("#%d: output does not match the snapshot", i)
+		}
+		if err := sw.verify(); (err == nil) != ts.ok {
+			t.Errorf("#%d: verify expected ok=%v, got %v", i, ts.ok, err)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+)
+
+var (
+	topInterval   time.Duration
+	topIterations int
+	topBatch      bool
+)
+
+const (
+	metricProposalsCommitted = "etcd_server_proposals_committed_total"
+	metricSlowApplies        = "etcd_server_slow_apply_total"
+)
+
+// NewTopCommand returns the cobra command for "top".
+func NewTopCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Shows a live dashboard of the cluster members",
+		Long: `Polls the status and the metrics of every member and shows their database
+size, applied index lag, raft proposals per second and slow applies, along
+with the leader and the active alarms.
+`,
+		Run: topCommandFunc,
+	}
+
+	cmd.Flags().DurationVar(&topInterval, "interval", 2*time.Second, "time between refreshes")
+	cmd.Flags().IntVarP(&topIterations, "iterations", "n", 0, "number of refreshes before exiting, 0 to run until interrupted")
+	cmd.Flags().BoolVarP(&topBatch, "batch", "b", false, "append every refresh to the output instead of redrawing the screen")
+
+	return cmd
+}
+
+// topMember is the state of a member at one refresh.
+type topMember struct {
+	ID       uint64
+	Name     string
+	Endpoint string
+	Leader   bool
+
+	DBSize       int64
+	DBSizeInUse  int64
+	RaftIndex    uint64
+	AppliedIndex uint64
+	// Lag is the number of entries the member has yet to apply to catch up
+	// with the most advanced member.
+	Lag uint64
+
+	ProposalsCommitted float64
+	SlowApplies        float64
+	// ProposalRate is the rate of committed proposals since the previous
+	// refresh, or -1 if unknown.
+	ProposalRate float64
+	// NewSlowApplies is the number of slow applies since the previous
+	// refresh.
+	NewSlowApplies float64
+
+	Err string
+}
+
+// topSample is the state of the cluster at one refresh.
+type topSample struct {
+	Time    time.Time
+	Members []topMember
+	Alarms  []string
+}
+
+// topCommandFunc executes the "top" command.
+func topCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("top command does not accept any arguments"))
+	}
+	if topInterval <= 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--interval must be positive"))
+	}
+
+	cc := clientConfigFromCmd(cmd)
+	cfg, err := newClientCfg(cc.endpoints, cc.dialTimeout, cc.keepAliveTime, cc.keepAliveTimeout, cc.scfg, cc.acfg)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	c, err := clientv3.New(*cfg)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	defer c.Close()
+	hc := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg.TLS}}
+
+	var prev *topSample
+	for i := 0; topIterations == 0 || i < topIterations; i++ {
+		if i > 0 {
+			time.Sleep(topInterval)
+		}
+		ctx, cancel := commandCtx(cmd)
+		s, err := sampleTop(ctx, c, hc)
+		cancel()
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		updateTopSample(prev, s)
+		if topBatch {
+			if i > 0 {
+				fmt.Println()
+			}
+		} else {
+			// move the cursor home and clear the screen
+			fmt.Print("\x1b[H\x1b[2J")
+		}
+		printTop(os.Stdout, s)
+		prev = s
+	}
+}
+
+// sampleTop collects the status and the metrics of every member.
+func sampleTop(ctx context.Context, c *clientv3.Client, hc *http.Client) (*topSample, error) {
+	mresp, err := c.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &topSample{Time: time.Now()}
+	for _, m := range mresp.Members {
+		tm := topMember{ID: m.ID, Name: m.Name}
+		if len(m.ClientURLs) == 0 {
+			tm.Err = "not started"
+			s.Members = append(s.Members, tm)
+			continue
+		}
+		tm.Endpoint = m.ClientURLs[0]
+
+		st, err := c.Status(ctx, tm.Endpoint)
+		if err != nil {
+			tm.Err = err.Error()
+			s.Members = append(s.Members, tm)
+			continue
+		}
+		tm.Leader = st.Leader == m.ID
+		tm.DBSize, tm.DBSizeInUse = st.DbSize, st.DbSizeInUse
+		tm.RaftIndex, tm.AppliedIndex = st.RaftIndex, st.RaftAppliedIndex
+
+		ms, err := fetchMetrics(ctx, hc, tm.Endpoint)
+		if err != nil {
+			tm.Err = err.Error()
+		}
+		tm.ProposalsCommitted = ms[metricProposalsCommitted]
+		tm.SlowApplies = ms[metricSlowApplies]
+		s.Members = append(s.Members, tm)
+	}
+
+	aresp, err := c.AlarmList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range aresp.Alarms {
+		s.Alarms = append(s.Alarms, fmt.Sprintf("%x: %s", a.MemberID, a.Alarm))
+	}
+	return s, nil
+}
+
+// fetchMetrics reads the metrics shown by "top" from the metrics endpoint of
+// a member.
+func fetchMetrics(ctx context.Context, hc *http.Client, ep string) (map[string]float64, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(ep, "/")+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching metrics: %s", resp.Status)
+	}
+	return parseMetrics(resp.Body, metricProposalsCommitted, metricSlowApplies)
+}
+
+// parseMetrics returns the values of the named metrics from the Prometheus
+// text format, summed over their labels.
+func parseMetrics(r io.Reader, names ...string) (map[string]float64, error) {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	ms := make(map[string]float64)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name := line
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name = line[:i]
+		}
+		if !want[name] {
+			continue
+		}
+		fields := strings.Fields(line[strings.LastIndex(line, "}")+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		if name == fields[0] {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s (%v)", name, err)
+		}
+		ms[name] += v
+	}
+	return ms, sc.Err()
+}
+
+// updateTopSample computes the values of the sample that depend on the
+// other members or on the previous sample.
+func updateTopSample(prev, cur *topSample) {
+	var maxIndex uint64
+	for _, m := range cur.Members {
+		if m.RaftIndex > maxIndex {
+			maxIndex = m.RaftIndex
+		}
+	}
+
+	prevMembers := make(map[uint64]topMember)
+	if prev != nil {
+		for _, m := range prev.Members {
+			prevMembers[m.ID] = m
+		}
+	}
+	for i := range cur.Members {
+		m := &cur.Members[i]
+		if m.AppliedIndex < maxIndex && m.RaftIndex != 0 {
+			m.Lag = maxIndex - m.AppliedIndex
+		}
+		m.ProposalRate = -1
+		p, ok := prevMembers[m.ID]
+		if !ok || p.Err != "" || m.Err != "" {
+			continue
+		}
+		// counters restart from zero when the member restarts
+		if m.ProposalsCommitted >= p.ProposalsCommitted {
+			if d := cur.Time.Sub(prev.Time).Seconds(); d > 0 {
+				m.ProposalRate = (m.ProposalsCommitted - p.ProposalsCommitted) / d
+			}
+		}
+		if m.SlowApplies >= p.SlowApplies {
+			m.NewSlowApplies = m.SlowApplies - p.SlowApplies
+		}
+	}
+}
+
+func printTop(w io.Writer, s *topSample) {
+	fmt.Fprintf(w, "etcd top - %s - %d member(s)\n", s.Time.Format("15:04:05"), len(s.Members))
+	if len(s.Alarms) == 0 {
+		fmt.Fprintln(w, "Alarms: none")
+	} else {
+		fmt.Fprintln(w, "Alarms:", strings.Join(s.Alarms, ", "))
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"ID", "Name", "Leader", "DB Size", "In Use", "Applied", "Lag", "Proposals/s", "Slow Applies", "Error"})
+	for _, m := range s.Members {
+		rate := "-"
+		if m.ProposalRate >= 0 {
+			rate = fmt.Sprintf("%.1f", m.ProposalRate)
+		}
+		table.Append([]string{
+			fmt.Sprintf("%x", m.ID),
+			m.Name,
+			strconv.FormatBool(m.Leader),
+			humanize.Bytes(uint64(m.DBSize)),
+			humanize.Bytes(uint64(m.DBSizeInUse)),
+			fmt.Sprint(m.AppliedIndex),
+			fmt.Sprint(m.Lag),
+			rate,
+			fmt.Sprintf("%.0f (+%.0f)", m.SlowApplies, m.NewSlowApplies),
+			m.Err,
+		})
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMetrics(t *testing.T) {
+	text := `# HELP etcd_server_proposals_committed_total The total number of consensus proposals committed.
+# TYPE etcd_server_proposals_committed_total gauge
+etcd_server_proposals_committed_total 42
+etcd_server_proposals_committed_total_other 7
+etcd_server_slow_apply_total{a="x"} 2
+etcd_server_slow_apply_total{a="y"} 3 1600000000000
+process_resident_memory_bytes 1e+07
+`
+	ms, err := parseMetrics(strings.NewReader(text), metricProposalsCommitted, metricSlowApplies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]float64{metricProposalsCommitted: 42, metricSlowApplies: 5}
+	if !reflect.DeepEqual(ms, exp) {
+		t.Errorf("expected %v, got %v", exp, ms)
+	}
+
+	if _, err = parseMetrics(strings.NewReader("etcd_server_slow_apply_total x\n"), metricSlowApplies); err == nil {
+		t.Error("expected error for an invalid value")
+	}
+}
+
+func TestUpdateTopSample(t *testing.T) {
+	now := time.Now()
+	prev := &topSample{
+		Time: now,
+		Members: []topMember{
+			{ID: 1, RaftIndex: 100, AppliedIndex: 100, ProposalsCommitted: 100, SlowApplies: 1},
+			{ID: 2, RaftIndex: 90, AppliedIndex: 90, ProposalsCommitted: 90},
+		},
+	}
+	cur := &topSample{
+		Time: now.Add(2 * time.Second),
+		Members: []topMember{
+			{ID: 1, RaftIndex: 120, AppliedIndex: 120, ProposalsCommitted: 120, SlowApplies: 3},
+			// restarted, its counters are reset
+			{ID: 2, RaftIndex: 110, AppliedIndex: 105, ProposalsCommitted: 5},
+			{ID: 3, Err: "not started"},
+		},
+	}
+	updateTopSample(prev, cur)
+
+	exp := []topMember{
+		{ID: 1, RaftIndex: 120, AppliedIndex: 120, ProposalsCommitted: 120, SlowApplies: 3, ProposalRate: 10, NewSlowApplies: 2},
+		{ID: 2, RaftIndex: 110, AppliedIndex: 105, Lag: 15, ProposalsCommitted: 5, ProposalRate: -1},
+		{ID: 3, Err: "not started", ProposalRate: -1},
+	}
+	if !reflect.DeepEqual(cur.Members, exp) {
+		t.Errorf("expected %+v, got %+v", exp, cur.Members)
+	}
+
+	// the first sample has no rates
+	updateTopSample(nil, prev)
+	for _, m := range prev.Members {
+		if m.ProposalRate != -1 {
+			t.Errorf("expected unknown proposal rate, got %v", m.ProposalRate)
+		}
+	}
+}
@@ -16,19 +16,27 @@ package command
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/client/v3"
 
 	"github.com/spf13/cobra"
 )
 
-var txnInteractive bool
+var (
+	txnInteractive bool
+	txnFile        string
+	txnVars        []string
+)
 
 // NewTxnCommand returns the cobra command for "txn".
 func NewTxnCommand() *cobra.Command {
@@ -38,6 +46,8 @@ func NewTxnCommand() *cobra.Command {
 		Run:   txnCommandFunc,
 	}
 	cmd.Flags().BoolVarP(&txnInteractive, "interactive", "i", false, "Input transaction in interactive mode")
+	cmd.Flags().StringVar(&txnFile, "file", "", "Read the transaction from the given file instead of standard input")
+	cmd.Flags().StringArrayVar(&txnVars, "var", nil, "Substitute value for ${name} in --file, given as name=value; may be repeated")
 	return cmd
 }
 
@@ -47,11 +57,28 @@ func txnCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("txn command does not accept argument"))
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	var reader *bufio.Reader
+	if txnFile != "" {
+		if txnInteractive {
+			ExitWithError(ExitBadArgs, fmt.Errorf("--file and --interactive cannot be set at the same time"))
+		}
+		text, err := readTxnFile(txnFile, txnVars)
+		if err != nil {
+			ExitWithError(ExitInvalidInput, err)
+		}
+		reader = bufio.NewReader(strings.NewReader(text))
+	} else {
+		if len(txnVars) != 0 {
+			ExitWithError(ExitBadArgs, fmt.Errorf("--var requires --file"))
+		}
+		reader = bufio.NewReader(os.Stdin)
+	}
 
-	txn := mustClientFromCmd(cmd).Txn(context.Background())
+	c := mustClientFromCmd(cmd)
+	txn := c.Txn(context.Background())
 	promptInteractive("compares:")
-	txn.If(readCompares(reader)...)
+	cmps, cmpLines := readCompares(reader)
+	txn.If(cmps...)
 	promptInteractive("success requests (get, put, del):")
 	txn.Then(readOps(reader)...)
 	promptInteractive("failure requests (get, put, del):")
@@ -63,6 +90,10 @@ func txnCommandFunc(cmd *cobra.Command, args []string) {
 	}
 
 	display.Txn(*resp)
+
+	if txnFile != "" && !resp.Succeeded && len(cmps) != 0 {
+		reportFailedCompares(cmd, c, cmps, cmpLines, resp)
+	}
 }
 
 func promptInteractive(s string) {
@@ -71,33 +102,39 @@ func promptInteractive(s string) {
 	}
 }
 
-func readCompares(r *bufio.Reader) (cmps []clientv3.Cmp) {
-	for {
-		line, err := r.ReadString('\n')
+// readCompares reads the compares of the transaction, along with the lines
+// they were parsed from.
+func readCompares(r *bufio.Reader) (cmps []clientv3.Cmp, lines []string) {
+	for _, line := range readTxnLines(r) {
+		cmp, err := parseCompare(line)
 		if err != nil {
 			ExitWithError(ExitInvalidInput, err)
 		}
+		cmps = append(cmps, *cmp)
+		lines = append(lines, line)
+	}
 
-		// remove space from the line
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			break
-		}
+	return cmps, lines
+}
 
-		cmp, err := parseCompare(line)
+func readOps(r *bufio.Reader) (ops []clientv3.Op) {
+	for _, line := range readTxnLines(r) {
+		op, err := parseRequestUnion(line)
 		if err != nil {
 			ExitWithError(ExitInvalidInput, err)
 		}
-		cmps = append(cmps, *cmp)
+		ops = append(ops, *op)
 	}
 
-	return cmps
+	return ops
 }
 
-func readOps(r *bufio.Reader) (ops []clientv3.Op) {
+// readTxnLines reads the lines of one section of the transaction, which ends
+// with an empty line or with the input.
+func readTxnLines(r *bufio.Reader) (lines []string) {
 	for {
 		line, err := r.ReadString('\n')
-		if err != nil {
+		if err != nil && err != io.EOF {
 			ExitWithError(ExitInvalidInput, err)
 		}
 
@@ -106,15 +143,157 @@ func readOps(r *bufio.Reader) (ops []clientv3.Op) {
 		if len(line) == 0 {
 			break
 		}
+		lines = append(lines, line)
 
-		op, err := parseRequestUnion(line)
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return lines
+}
+
+// readTxnFile reads a transaction file, dropping its comment lines and
+// substituting the variables given as "name=value".
+func readTxnFile(path string, vars []string) (string, error) {
+	vs := make(map[string]string, len(vars))
+	for _, v := range vars {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return "", fmt.Errorf("invalid variable %q, expected name=value", v)
+		}
+		vs[kv[0]] = kv[1]
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return expandTxn(string(b), vs)
+}
+
+// expandTxn substitutes the variables referenced as $name or ${name} in the
+// transaction text; "$$" stands for "$". Lines starting with "#" are
+// comments.
+func expandTxn(text string, vars map[string]string) (string, error) {
+	var (
+		lines   []string
+		missing string
+	)
+	for i, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		line = os.Expand(line, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			v, ok := vars[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return "", fmt.Errorf("undefined variable %q on line %d", missing, i+1)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// reportFailedCompares prints the compares that failed the transaction to
+// standard error. The server does not tell which compares failed, so they
+// are evaluated again against the keys at the revision the transaction saw.
+func reportFailedCompares(cmd *cobra.Command, c *clientv3.Client, cmps []clientv3.Cmp, lines []string, resp *clientv3.TxnResponse) {
+	rev := resp.Header.Revision
+	if txnWrote(resp.Responses) {
+		rev--
+	}
+	for i, cmp := range cmps {
+		pc := pb.Compare(cmp)
+		opts := []clientv3.OpOption{clientv3.WithRev(rev)}
+		if len(pc.RangeEnd) != 0 {
+			opts = append(opts, clientv3.WithRange(string(pc.RangeEnd)))
+		}
+		ctx, cancel := commandCtx(cmd)
+		gresp, err := c.Get(ctx, string(pc.Key), opts...)
+		cancel()
 		if err != nil {
-			ExitWithError(ExitInvalidInput, err)
+			fmt.Fprintf(os.Stderr, "cannot tell which compares failed (%v)\n", err)
+			return
+		}
+		if !compareHolds(pc, gresp.Kvs) {
+			fmt.Fprintf(os.Stderr, "failed compare: %s\n", lines[i])
 		}
-		ops = append(ops, *op)
 	}
+}
 
-	return ops
+// txnWrote reports whether the responses of a transaction include a write,
+// in which case the transaction created a new revision.
+func txnWrote(resps []*pb.ResponseOp) bool {
+	for _, r := range resps {
+		switch {
+		case r.GetResponsePut() != nil:
+			return true
+		case r.GetResponseDeleteRange() != nil && r.GetResponseDeleteRange().Deleted > 0:
+			return true
+		case r.GetResponseTxn() != nil && txnWrote(r.GetResponseTxn().Responses):
+			return true
+		}
+	}
+	return false
+}
+
+// compareHolds evaluates the compare against the keys in its range the
+// same way the server does.
+func compareHolds(c pb.Compare, kvs []*mvccpb.KeyValue) bool {
+	if len(kvs) == 0 {
+		// a value compare on a missing key always fails
+		if c.Target == pb.Compare_VALUE {
+			return false
+		}
+		kvs = []*mvccpb.KeyValue{{}}
+	}
+	for _, kv := range kvs {
+		var result int
+		switch c.Target {
+		case pb.Compare_VALUE:
+			result = bytes.Compare(kv.Value, c.GetValue())
+		case pb.Compare_CREATE:
+			result = compareInt64(kv.CreateRevision, c.GetCreateRevision())
+		case pb.Compare_MOD:
+			result = compareInt64(kv.ModRevision, c.GetModRevision())
+		case pb.Compare_VERSION:
+			result = compareInt64(kv.Version, c.GetVersion())
+		case pb.Compare_LEASE:
+			result = compareInt64(kv.Lease, c.GetLease())
+		}
+		ok := true
+		switch c.Result {
+		case pb.Compare_EQUAL:
+			ok = result == 0
+		case pb.Compare_NOT_EQUAL:
+			ok = result != 0
+		case pb.Compare_GREATER:
+			ok = result > 0
+		case pb.Compare_LESS:
+			ok = result < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
 
 func parseRequestUnion(line string) (*clientv3.Op, error) {
@@ -0,0 +1,104 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestExpandTxn(t *testing.T) {
+	vars := map[string]string{"key": "foo", "rev": "5"}
+	tt := []struct {
+		text string
+
+		expanded string
+		err      bool
+	}{
+		{text: `mod("${key}") = "$rev"`, expanded: `mod("foo") = "5"`},
+		{text: "# update ${key}\nput $key \"$$1\"\n\n", expanded: "put foo \"$1\"\n\n"},
+		{text: "put ${key}\nput ${value} 1\n", err: true},
+	}
+	for i, tc := range tt {
+		s, err := expandTxn(tc.text, vars)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: expected error %v, got %v", i, tc.err, err)
+		}
+		if s != tc.expanded {
+			t.Errorf("#%d: expected %q, got %q", i, tc.expanded, s)
+		}
+	}
+}
+
+func TestCompareHolds(t *testing.T) {
+	kv := &mvccpb.KeyValue{Key: []byte("foo"), Value: []byte("bar"), Version: 2, ModRevision: 7}
+	tt := []struct {
+		cmp pb.Compare
+		kvs []*mvccpb.KeyValue
+
+		holds bool
+	}{
+		{
+			cmp:   pb.Compare{Target: pb.Compare_VALUE, Result: pb.Compare_EQUAL, TargetUnion: &pb.Compare_Value{Value: []byte("bar")}},
+			kvs:   []*mvccpb.KeyValue{kv},
+			holds: true,
+		},
+		{
+			cmp: pb.Compare{Target: pb.Compare_VALUE, Result: pb.Compare_NOT_EQUAL, TargetUnion: &pb.Compare_Value{Value: []byte("bar")}},
+			kvs: []*mvccpb.KeyValue{kv},
+		},
+		{
+			// a value compare on a missing key always fails
+			cmp: pb.Compare{Target: pb.Compare_VALUE, Result: pb.Compare_NOT_EQUAL, TargetUnion: &pb.Compare_Value{Value: []byte("bar")}},
+		},
+		{
+			cmp:   pb.Compare{Target: pb.Compare_VERSION, Result: pb.Compare_EQUAL, TargetUnion: &pb.Compare_Version{Version: 0}},
+			holds: true,
+		},
+		{
+			cmp:   pb.Compare{Target: pb.Compare_MOD, Result: pb.Compare_GREATER, TargetUnion: &pb.Compare_ModRevision{ModRevision: 6}},
+			kvs:   []*mvccpb.KeyValue{kv},
+			holds: true,
+		},
+		{
+			cmp: pb.Compare{Target: pb.Compare_VERSION, Result: pb.Compare_LESS, TargetUnion: &pb.Compare_Version{Version: 2}},
+			kvs: []*mvccpb.KeyValue{kv},
+		},
+	}
+	for i, tc := range tt {
+		if holds := compareHolds(tc.cmp, tc.kvs); holds != tc.holds {
+			t.Errorf("#%d: expected %v, got %v", i, tc.holds, holds)
+		}
+	}
+}
+
+func TestTxnWrote(t *testing.T) {
+	get := &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: &pb.RangeResponse{}}}
+	put := &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{}}}
+	noDel := &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: &pb.DeleteRangeResponse{}}}
+	del := &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: &pb.DeleteRangeResponse{Deleted: 1}}}
+
+	if txnWrote([]*pb.ResponseOp{get, noDel}) {
+		t.Error("expected no write")
+	}
+	if !txnWrote([]*pb.ResponseOp{get, put}) {
+		t.Error("expected a write")
+	}
+	if !txnWrote([]*pb.ResponseOp{noDel, del}) {
+		t.Error("expected a write")
+	}
+}
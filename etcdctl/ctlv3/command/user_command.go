@@ -16,16 +16,24 @@ package command
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/authpb"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/client/v3"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	userShowDetail bool
+	userShowDetail   bool
+	userDeleteDryRun bool
 )
 
 // NewUserCommand returns the cobra command for "user".
@@ -49,29 +57,38 @@ func NewUserCommand() *cobra.Command {
 var (
 	passwordInteractive bool
 	passwordFromFlag    string
+	passwordFile        string
+	passwordFromEnv     string
+	passwordBcryptCost  int
 	noPassword          bool
 )
 
 func newUserAddCommand() *cobra.Command {
 	cmd := cobra.Command{
-		Use:   "add <user name or user:password> [options]",
+		Use:   "add <user name> [options]",
 		Short: "Adds a new user",
 		Run:   userAddCommandFunc,
 	}
 
 	cmd.Flags().BoolVar(&passwordInteractive, "interactive", true, "Read password from stdin instead of interactive terminal")
 	cmd.Flags().StringVar(&passwordFromFlag, "new-user-password", "", "Supply password from the command line flag")
+	cmd.Flags().MarkDeprecated("new-user-password", "the password is visible to other users of the host, use --password-file instead")
 	cmd.Flags().BoolVar(&noPassword, "no-password", false, "Create a user without password (CN based auth only)")
+	addPasswordFlags(&cmd)
 
 	return &cmd
 }
 
 func newUserDeleteCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := cobra.Command{
 		Use:   "delete <user name>",
 		Short: "Deletes a user",
 		Run:   userDeleteCommandFunc,
 	}
+
+	cmd.Flags().BoolVar(&userDeleteDryRun, "dry-run", false, "verify the user exists without deleting it")
+
+	return &cmd
 }
 
 func newUserGetCommand() *cobra.Command {
@@ -102,6 +119,7 @@ func newUserChangePasswordCommand() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&passwordInteractive, "interactive", true, "If true, read password from stdin instead of interactive terminal")
+	addPasswordFlags(&cmd)
 
 	return &cmd
 }
@@ -127,41 +145,41 @@ func userAddCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		ExitWithError(ExitBadArgs, fmt.Errorf("user add command requires user name as its argument"))
 	}
+	user := args[0]
+	if strings.Contains(user, ":") {
+		ExitWithError(ExitBadArgs, errPasswordInArgs)
+	}
 
 	var password string
-	var user string
-
 	options := &clientv3.UserAddOptions{
 		NoPassword: false,
 	}
 
 	if !noPassword {
-		if passwordFromFlag != "" {
-			user = args[0]
-			password = passwordFromFlag
-		} else {
-			splitted := strings.SplitN(args[0], ":", 2)
-			if len(splitted) < 2 {
-				user = args[0]
-				if !passwordInteractive {
-					fmt.Scanf("%s", &password)
-				} else {
-					password = readPasswordInteractive(args[0])
-				}
-			} else {
-				user = splitted[0]
-				password = splitted[1]
-				if len(user) == 0 {
-					ExitWithError(ExitBadArgs, fmt.Errorf("empty user name is not allowed"))
-				}
-			}
-		}
+		password = mustReadPassword(user, passwordFromFlag)
 	} else {
-		user = args[0]
+		if passwordFromFlag != "" || passwordFile != "" || passwordFromEnv != "" {
+			ExitWithError(ExitBadArgs, errors.New("--no-password conflicts with the password options"))
+		}
 		options.NoPassword = true
 	}
 
-	resp, err := mustClientFromCmd(cmd).Auth.UserAddWithOptions(context.TODO(), user, password, options)
+	c := mustClientFromCmd(cmd)
+	var (
+		resp *clientv3.AuthUserAddResponse
+		err  error
+	)
+	if !options.NoPassword && passwordBcryptCost != 0 {
+		var r *pb.AuthUserAddResponse
+		r, err = clientv3.RetryAuthClient(c).UserAdd(context.TODO(), &pb.AuthUserAddRequest{
+			Name:           user,
+			Options:        (*authpb.UserAddOptions)(options),
+			HashedPassword: mustHashPassword(password),
+		})
+		resp = (*clientv3.AuthUserAddResponse)(r)
+	} else {
+		resp, err = c.Auth.UserAddWithOptions(context.TODO(), user, password, options)
+	}
 	if err != nil {
 		ExitWithError(ExitError, err)
 	}
@@ -175,6 +193,15 @@ func userDeleteCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("user delete command requires user name as its argument"))
 	}
 
+	if userDeleteDryRun {
+		resp, err := mustClientFromCmd(cmd).Auth.UserGet(context.TODO(), args[0])
+		if err != nil {
+			ExitWithError(ExitError, err)
+		}
+		fmt.Printf("dry-run: would delete user %q with roles [%s]\n", args[0], strings.Join(resp.Roles, ", "))
+		return
+	}
+
 	resp, err := mustClientFromCmd(cmd).Auth.UserDelete(context.TODO(), args[0])
 	if err != nil {
 		ExitWithError(ExitError, err)
@@ -230,15 +257,23 @@ func userChangePasswordCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("user passwd command requires user name as its argument"))
 	}
 
-	var password string
-
-	if !passwordInteractive {
-		fmt.Scanf("%s", &password)
+	password := mustReadPassword(args[0], "")
+
+	c := mustClientFromCmd(cmd)
+	var (
+		resp *clientv3.AuthUserChangePasswordResponse
+		err  error
+	)
+	if passwordBcryptCost != 0 {
+		var r *pb.AuthUserChangePasswordResponse
+		r, err = clientv3.RetryAuthClient(c).UserChangePassword(context.TODO(), &pb.AuthUserChangePasswordRequest{
+			Name:           args[0],
+			HashedPassword: mustHashPassword(password),
+		})
+		resp = (*clientv3.AuthUserChangePasswordResponse)(r)
 	} else {
-		password = readPasswordInteractive(args[0])
+		resp, err = c.Auth.UserChangePassword(context.TODO(), args[0], password)
 	}
-
-	resp, err := mustClientFromCmd(cmd).Auth.UserChangePassword(context.TODO(), args[0], password)
 	if err != nil {
 		ExitWithError(ExitError, err)
 	}
@@ -297,3 +332,91 @@ func readPasswordInteractive(name string) string {
 
 	return password1
 }
+
+var errPasswordInArgs = errors.New("passing the password as part of the user name is not supported since it is visible to other users of the host; use --password-file, --password-from-env or the interactive prompt")
+
+// addPasswordFlags adds the flags selecting where the password of a user
+// is read from and how it is hashed.
+func addPasswordFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&passwordFile, "password-file", "", "Read the password from the first line of the given file")
+	cmd.Flags().StringVar(&passwordFromEnv, "password-from-env", "", "Read the password from the given environment variable")
+	cmd.Flags().IntVar(&passwordBcryptCost, "bcrypt-cost", 0, "Hash the password on the client with the given bcrypt cost (requires --auth-allow-hashed-password on the server)")
+}
+
+// mustReadPassword reads the password of the user from the source selected
+// by the password flags, falling back to stdin or the interactive prompt.
+func mustReadPassword(name, fromFlag string) string {
+	sources := 0
+	for _, s := range []string{fromFlag, passwordFile, passwordFromEnv} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		ExitWithError(ExitBadArgs, errors.New("only one of --new-user-password, --password-file and --password-from-env can be given"))
+	}
+	if passwordBcryptCost != 0 && (passwordBcryptCost < bcrypt.MinCost || passwordBcryptCost > bcrypt.MaxCost) {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--bcrypt-cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+
+	var (
+		password string
+		err      error
+	)
+	switch {
+	case fromFlag != "":
+		password = fromFlag
+	case passwordFile != "":
+		password, err = readPasswordFile(passwordFile)
+	case passwordFromEnv != "":
+		password, err = readPasswordEnv(passwordFromEnv)
+	case !passwordInteractive:
+		fmt.Scanf("%s", &password)
+	default:
+		password = readPasswordInteractive(name)
+	}
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	return password
+}
+
+// readPasswordFile returns the first line of the file, without its line
+// terminator.
+func readPasswordFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	password := string(b)
+	if i := strings.IndexByte(password, '\n'); i >= 0 {
+		password = password[:i]
+	}
+	password = strings.TrimSuffix(password, "\r")
+	if password == "" {
+		return "", fmt.Errorf("empty password in %q", path)
+	}
+	return password, nil
+}
+
+// readPasswordEnv returns the value of the environment variable.
+func readPasswordEnv(name string) (string, error) {
+	password, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", name)
+	}
+	if password == "" {
+		return "", fmt.Errorf("environment variable %q is empty", name)
+	}
+	return password, nil
+}
+
+// mustHashPassword hashes the password with the cost given by
+// "--bcrypt-cost" and encodes it the way the server stores it.
+func mustHashPassword(password string) string {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), passwordBcryptCost)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	return base64.StdEncoding.EncodeToString(h)
+}
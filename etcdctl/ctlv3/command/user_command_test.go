@@ -0,0 +1,79 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPasswordFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcdctl-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tt := []struct {
+		content string
+
+		password string
+		err      bool
+	}{
+		{content: "secret", password: "secret"},
+		{content: "secret\n", password: "secret"},
+		{content: "secret\r\n", password: "secret"},
+		{content: "sec ret\nignored\n", password: "sec ret"},
+		{content: "", err: true},
+		{content: "\n", err: true},
+	}
+	for i, tc := range tt {
+		path := filepath.Join(dir, "password")
+		if err = ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		password, err := readPasswordFile(path)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: expected error %v, got %v", i, tc.err, err)
+		}
+		if password != tc.password {
+			t.Errorf("#%d: expected password %q, got %q", i, tc.password, password)
+		}
+	}
+
+	if _, err = readPasswordFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestReadPasswordEnv(t *testing.T) {
+	const name = "ETCDCTL_TEST_PASSWORD"
+	defer os.Unsetenv(name)
+
+	os.Unsetenv(name)
+	if _, err := readPasswordEnv(name); err == nil {
+		t.Error("expected error for an unset variable")
+	}
+	os.Setenv(name, "")
+	if _, err := readPasswordEnv(name); err == nil {
+		t.Error("expected error for an empty variable")
+	}
+	os.Setenv(name, "secret")
+	if password, err := readPasswordEnv(name); err != nil || password != "secret" {
+		t.Errorf("expected password %q, got %q (%v)", "secret", password, err)
+	}
+}
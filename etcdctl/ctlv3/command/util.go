@@ -129,6 +129,36 @@ func endpointMemoryMetrics(host string) float64 {
 	return residentMemoryBytes
 }
 
+// rangeAll iterates over all keys in [key, end) in pages of at most
+// pageSize keys. All pages are served at the same revision, which is
+// rev if it is positive or the revision of the first page otherwise.
+// It returns the revision of the iterated keyspace. Extra options, such as
+// v3.WithKeysOnly, are applied to every page.
+func rangeAll(cmd *cobra.Command, c *v3.Client, key, end string, rev int64, pageSize int64, f func(kv *pb.KeyValue), extra ...v3.OpOption) (int64, error) {
+	for {
+		opts := append([]v3.OpOption{v3.WithRange(end), v3.WithLimit(pageSize)}, extra...)
+		if rev > 0 {
+			opts = append(opts, v3.WithRev(rev))
+		}
+		ctx, cancel := commandCtx(cmd)
+		resp, err := c.Get(ctx, key, opts...)
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		if rev == 0 {
+			rev = resp.Header.Revision
+		}
+		for _, kv := range resp.Kvs {
+			f(kv)
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return rev, nil
+		}
+		key = string(append(resp.Kvs[len(resp.Kvs)-1].Key, 0))
+	}
+}
+
 // compact keyspace history to a provided revision
 func compact(c *v3.Client, rev int64) {
 	fmt.Printf("Compacting with revision %d\n", rev)
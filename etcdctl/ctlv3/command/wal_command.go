@@ -0,0 +1,394 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/v3/etcdserver/api/snap"
+	"go.etcd.io/etcd/v3/wal"
+	"go.etcd.io/etcd/v3/wal/walpb"
+	"go.uber.org/zap"
+)
+
+var (
+	walDataDir    string
+	walWALDir     string
+	walStartIndex uint64
+	walEndIndex   uint64
+
+	walTruncateAtIndex uint64
+	walDryRun          bool
+)
+
+// NewWALCommand returns the cobra command for "wal".
+func NewWALCommand() *cobra.Command {
+	wc := &cobra.Command{
+		Use:   "wal <subcommand>",
+		Short: "Write-ahead log related commands",
+	}
+
+	wc.AddCommand(newWALInspectCommand())
+	wc.AddCommand(newWALRepairCommand())
+
+	return wc
+}
+
+func newWALInspectCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect --data-dir <dir> [options]",
+		Short: "Decodes the entries of the write-ahead log of a data directory",
+		Long: `Decodes the entries of the write-ahead log of a data directory not in use by
+etcd: their term, index and type, the request they carry and the keys the
+request touches. Without --start-index, the entries after the latest snapshot
+of the member are decoded.
+`,
+		Run: walInspectCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&walDataDir, "data-dir", "", "Path to the data directory")
+	cmd.Flags().StringVar(&walWALDir, "wal-dir", "", "Path to the WAL directory (use --data-dir if none given)")
+	cmd.Flags().Uint64Var(&walStartIndex, "start-index", 0, "Index of the first entry to decode")
+	cmd.Flags().Uint64Var(&walEndIndex, "end-index", 0, "Index of the last entry to decode, 0 for the last entry of the log")
+
+	return cmd
+}
+
+func newWALRepairCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repair --data-dir <dir> [options]",
+		Short: "Drops the corrupted records at the end of the write-ahead log of a data directory",
+		Long: `Drops the records of the write-ahead log of a data directory not in use by
+etcd from the first torn or corrupted one, as left by a crash, so that the
+member can start again without being rebuilt. With --truncate-at-index, the
+entries from the given index are dropped instead. The dropped records are
+reported, and kept in ".broken" files; with --dry-run, they are only
+reported.
+
+The dropped entries that were committed are only recovered from the other
+members of the cluster.
+`,
+		Run: walRepairCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&walDataDir, "data-dir", "", "Path to the data directory")
+	cmd.Flags().StringVar(&walWALDir, "wal-dir", "", "Path to the WAL directory (use --data-dir if none given)")
+	cmd.Flags().Uint64Var(&walTruncateAtIndex, "truncate-at-index", 0, "Index of the first entry to drop")
+	cmd.Flags().BoolVar(&walDryRun, "dry-run", false, "Reports the records to drop without dropping them")
+
+	return cmd
+}
+
+// walInspection is the decoded write-ahead log of a member.
+type walInspection struct {
+	NodeID    uint64 `json:"node_id"`
+	ClusterID uint64 `json:"cluster_id"`
+	Term      uint64 `json:"term"`
+	Commit    uint64 `json:"commit"`
+	Vote      uint64 `json:"vote"`
+	// SnapshotIndex is the index of the snapshot the entries follow.
+	SnapshotIndex uint64     `json:"snapshot_index,omitempty"`
+	Entries       []walEntry `json:"entries"`
+}
+
+// walEntry is a decoded raft log entry.
+type walEntry struct {
+	Term  uint64 `json:"term"`
+	Index uint64 `json:"index"`
+	// Type is "normal" or "conf_change".
+	Type string `json:"type"`
+	// Request is the name of the request carried by the entry, such as
+	// "put", "lease_grant" or "ConfChangeAddNode", or "noop" for an empty
+	// entry.
+	Request string   `json:"request,omitempty"`
+	Keys    []string `json:"keys,omitempty"`
+	// Detail describes the requests that touch no key.
+	Detail string `json:"detail,omitempty"`
+}
+
+// walInspectCommandFunc executes the "wal inspect" command.
+func walInspectCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("wal inspect command does not accept any arguments"))
+	}
+	if walDataDir == "" {
+		ExitWithError(ExitBadArgs, errors.New("--data-dir is required"))
+	}
+	if walEndIndex != 0 && walEndIndex < walStartIndex {
+		ExitWithError(ExitBadArgs, errors.New("--end-index must not be lower than --start-index"))
+	}
+
+	waldir := walWALDir
+	if waldir == "" {
+		waldir = filepath.Join(walDataDir, "member", "wal")
+	}
+	lg := zap.NewNop()
+
+	var (
+		walsnap   walpb.Snapshot
+		snapIndex uint64
+	)
+	if walStartIndex > 0 {
+		walsnap.Index = walStartIndex - 1
+	} else {
+		snapshot, err := snap.New(lg, filepath.Join(walDataDir, "member", "snap")).Load()
+		switch {
+		case err == nil:
+			walsnap.Index, walsnap.Term = snapshot.Metadata.Index, snapshot.Metadata.Term
+			snapIndex = walsnap.Index
+		case err != snap.ErrNoSnapshot:
+			ExitWithError(ExitError, err)
+		}
+	}
+
+	w, err := wal.OpenForRead(lg, waldir, walsnap)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	metadata, state, ents, err := w.ReadAll()
+	w.Close()
+	// a start index needs not match a snapshot record of the log
+	if err != nil && (walStartIndex == 0 || err != wal.ErrSnapshotNotFound) {
+		ExitWithError(ExitError, err)
+	}
+
+	var md pb.Metadata
+	pbutil.MustUnmarshal(&md, metadata)
+	wi := walInspection{
+		NodeID:        md.NodeID,
+		ClusterID:     md.ClusterID,
+		Term:          state.Term,
+		Commit:        state.Commit,
+		Vote:          state.Vote,
+		SnapshotIndex: snapIndex,
+		Entries:       []walEntry{},
+	}
+	for _, e := range ents {
+		if walEndIndex != 0 && e.Index > walEndIndex {
+			break
+		}
+		wi.Entries = append(wi.Entries, decodeWALEntry(e))
+	}
+	display.WALInspection(wi)
+}
+
+// walRepairCommandFunc executes the "wal repair" command.
+func walRepairCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		ExitWithError(ExitBadArgs, errors.New("wal repair command does not accept any arguments"))
+	}
+	if walDataDir == "" && walWALDir == "" {
+		ExitWithError(ExitBadArgs, errors.New("--data-dir is required"))
+	}
+	waldir := walWALDir
+	if waldir == "" {
+		waldir = filepath.Join(walDataDir, "member", "wal")
+	}
+	lg := zap.NewNop()
+
+	r, err := wal.PlanTruncate(lg, waldir, walTruncateAtIndex)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	if r == nil {
+		fmt.Println("Nothing to drop")
+		return
+	}
+
+	verb := "Dropping"
+	if walDryRun {
+		verb = "Would drop"
+	}
+	fmt.Printf("%s the records (%s)", verb, r.Reason)
+	if r.File != "" {
+		fmt.Printf(" of %s from offset %d", r.File, r.Offset)
+	}
+	fmt.Println()
+	for _, p := range r.RemovedFiles {
+		fmt.Printf("%s %s\n", verb, p)
+	}
+	if r.Entries > 0 {
+		fmt.Printf("%d entries dropped, indexes %d to %d\n", r.Entries, r.FirstIndex, r.LastIndex)
+	}
+	if r.DroppedCommit >= r.FirstIndex && r.Entries > 0 {
+		fmt.Printf("The entries up to index %d were committed\n", r.DroppedCommit)
+	}
+	if walDryRun {
+		return
+	}
+	if err = wal.Truncate(lg, waldir, r); err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Println("WAL repaired")
+}
+
+// decodeWALEntry decodes the request carried by the entry.
+func decodeWALEntry(e raftpb.Entry) walEntry {
+	we := walEntry{Term: e.Term, Index: e.Index, Type: "normal"}
+	switch e.Type {
+	case raftpb.EntryConfChange:
+		we.Type = "conf_change"
+		var cc raftpb.ConfChange
+		if err := cc.Unmarshal(e.Data); err != nil {
+			we.Detail = fmt.Sprintf("undecodable (%v)", err)
+			return we
+		}
+		we.Request = cc.Type.String()
+		we.Detail = fmt.Sprintf("id=%s", types.ID(cc.NodeID))
+		return we
+	case raftpb.EntryConfChangeV2:
+		we.Type = "conf_change"
+		var cc raftpb.ConfChangeV2
+		if err := cc.Unmarshal(e.Data); err != nil {
+			we.Detail = fmt.Sprintf("undecodable (%v)", err)
+			return we
+		}
+		we.Request = "ConfChangeV2"
+		we.Detail = raftpb.ConfChangesToString(cc.Changes)
+		return we
+	}
+
+	if len(e.Data) == 0 {
+		we.Request = "noop"
+		return we
+	}
+	var rr pb.InternalRaftRequest
+	if !pbutil.MaybeUnmarshal(&rr, e.Data) {
+		// entries of v2 requests written before the internal raft requests
+		var r pb.Request
+		if !pbutil.MaybeUnmarshal(&r, e.Data) {
+			we.Detail = "undecodable"
+			return we
+		}
+		decodeV2Request(&we, &r)
+		return we
+	}
+
+	switch {
+	case rr.V2 != nil:
+		decodeV2Request(&we, rr.V2)
+	case rr.Range != nil:
+		we.Request = "range"
+		we.Keys = []string{walKeyRange(rr.Range.Key, rr.Range.RangeEnd)}
+	case rr.Put != nil:
+		we.Request = "put"
+		we.Keys = []string{string(rr.Put.Key)}
+		if rr.Put.Lease != 0 {
+			we.Detail = fmt.Sprintf("lease=%016x", rr.Put.Lease)
+		}
+	case rr.DeleteRange != nil:
+		we.Request = "delete_range"
+		we.Keys = []string{walKeyRange(rr.DeleteRange.Key, rr.DeleteRange.RangeEnd)}
+	case rr.Txn != nil:
+		we.Request = "txn"
+		we.Keys = txnKeys(rr.Txn)
+	case rr.Compaction != nil:
+		we.Request = "compaction"
+		we.Detail = fmt.Sprintf("revision=%d", rr.Compaction.Revision)
+	case rr.LeaseGrant != nil:
+		we.Request = "lease_grant"
+		we.Detail = fmt.Sprintf("id=%016x ttl=%d", rr.LeaseGrant.ID, rr.LeaseGrant.TTL)
+	case rr.LeaseRevoke != nil:
+		we.Request = "lease_revoke"
+		we.Detail = fmt.Sprintf("id=%016x", rr.LeaseRevoke.ID)
+	default:
+		we.Request = internalRequestName(&rr)
+		if we.Request == "" {
+			we.Detail = "unknown request"
+		}
+	}
+	return we
+}
+
+func decodeV2Request(we *walEntry, r *pb.Request) {
+	we.Request = "v2_" + strings.ToLower(r.Method)
+	if r.Method == "" {
+		we.Request = "noop"
+	}
+	if r.Path != "" {
+		we.Keys = []string{r.Path}
+	}
+}
+
+// internalRequestName returns the protobuf name of the request set in rr.
+func internalRequestName(rr *pb.InternalRaftRequest) string {
+	v := reflect.ValueOf(rr).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Ptr || f.IsNil() || v.Type().Field(i).Name == "Header" {
+			continue
+		}
+		for _, opt := range strings.Split(v.Type().Field(i).Tag.Get("protobuf"), ",") {
+			if strings.HasPrefix(opt, "name=") {
+				return strings.TrimPrefix(opt, "name=")
+			}
+		}
+	}
+	return ""
+}
+
+// txnKeys returns the keys compared and the keys touched by the operations
+// of the transaction, in order and without duplicates.
+func txnKeys(txn *pb.TxnRequest) []string {
+	var keys []string
+	add := func(k string) {
+		for _, key := range keys {
+			if key == k {
+				return
+			}
+		}
+		keys = append(keys, k)
+	}
+	for _, c := range txn.Compare {
+		add(walKeyRange(c.Key, c.RangeEnd))
+	}
+	for _, ops := range [][]*pb.RequestOp{txn.Success, txn.Failure} {
+		for _, op := range ops {
+			switch {
+			case op.GetRequestRange() != nil:
+				add(walKeyRange(op.GetRequestRange().Key, op.GetRequestRange().RangeEnd))
+			case op.GetRequestPut() != nil:
+				add(string(op.GetRequestPut().Key))
+			case op.GetRequestDeleteRange() != nil:
+				add(walKeyRange(op.GetRequestDeleteRange().Key, op.GetRequestDeleteRange().RangeEnd))
+			case op.GetRequestTxn() != nil:
+				for _, k := range txnKeys(op.GetRequestTxn()) {
+					add(k)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// walKeyRange formats a key or a key range.
+func walKeyRange(key, end []byte) string {
+	switch {
+	case len(end) == 0:
+		return string(key)
+	case len(end) == 1 && end[0] == 0:
+		return fmt.Sprintf("[%s, +inf)", key)
+	}
+	return fmt.Sprintf("[%s, %s)", key, end)
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+func TestDecodeWALEntry(t *testing.T) {
+	txn := &pb.TxnRequest{
+		Compare: []*pb.Compare{{Key: []byte("a")}},
+		Success: []*pb.RequestOp{
+			{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("a")}}},
+			{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("b"), RangeEnd: []byte("c")}}},
+		},
+		Failure: []*pb.RequestOp{
+			{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{
+				Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("d"), RangeEnd: []byte{0}}}}},
+			}}},
+		},
+	}
+	normal := func(rr *pb.InternalRaftRequest) raftpb.Entry {
+		return raftpb.Entry{Term: 2, Index: 7, Type: raftpb.EntryNormal, Data: pbutil.MustMarshal(rr)}
+	}
+	cc := raftpb.ConfChange{Type: raftpb.ConfChangeAddNode, NodeID: 0x10}
+
+	tt := []struct {
+		e  raftpb.Entry
+		we walEntry
+	}{
+		{
+			raftpb.Entry{Term: 2, Index: 7, Type: raftpb.EntryNormal},
+			walEntry{Term: 2, Index: 7, Type: "normal", Request: "noop"},
+		},
+		{
+			normal(&pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte("foo"), Lease: 0x20}}),
+			walEntry{Term: 2, Index: 7, Type: "normal", Request: "put", Keys: []string{"foo"}, Detail: "lease=0000000000000020"},
+		},
+		{
+			normal(&pb.InternalRaftRequest{Txn: txn}),
+			walEntry{Term: 2, Index: 7, Type: "normal", Request: "txn", Keys: []string{"a", "[b, c)", "[d, +inf)"}},
+		},
+		{
+			normal(&pb.InternalRaftRequest{AuthEnable: &pb.AuthEnableRequest{}}),
+			walEntry{Term: 2, Index: 7, Type: "normal", Request: "auth_enable"},
+		},
+		{
+			raftpb.Entry{Term: 1, Index: 3, Type: raftpb.EntryConfChange, Data: pbutil.MustMarshal(&cc)},
+			walEntry{Term: 1, Index: 3, Type: "conf_change", Request: "ConfChangeAddNode", Detail: "id=10"},
+		},
+	}
+	for i, tc := range tt {
+		if we := decodeWALEntry(tc.e); !reflect.DeepEqual(we, tc.we) {
+			t.Errorf("#%d: entry = %+v, want %+v", i, we, tc.we)
+		}
+	}
+}
@@ -20,8 +20,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"go.etcd.io/etcd/client/v3"
 
@@ -33,6 +34,7 @@ var (
 	errBadArgsNumConflictEnv   = errors.New("bad number of arguments (found conflicting environment key)")
 	errBadArgsNumSeparator     = errors.New("bad number of arguments (found separator --, but no commands)")
 	errBadArgsInteractiveWatch = errors.New("args[0] must be 'watch' for interactive calls")
+	errBadArgsInteractiveMulti = errors.New("--watch-key and --key-prefix are not supported in interactive mode")
 )
 
 var (
@@ -41,6 +43,22 @@ var (
 	watchInteractive bool
 	watchPrevKey     bool
 	progressNotify   bool
+	watchKeys        []string
+	watchKeyPrefixes []string
+
+	watchFromRev  int64
+	watchToRev    int64
+	watchNoFollow bool
+
+	watchExecTemplate    string
+	watchExecConcurrency int
+	watchExecDebounce    time.Duration
+
+	watchKeyPattern      string
+	watchKeyRegex        string
+	watchValueContains   string
+	watchValueJSONPath   string
+	watchValueJSONEquals string
 )
 
 // NewWatchCommand returns the cobra command for "watch".
@@ -49,6 +67,8 @@ func NewWatchCommand() *cobra.Command {
 		Use:   "watch [options] [key or prefix] [range_end] [--] [exec-command arg1 arg2 ...]",
 		Short: "Watches events stream on keys or prefixes",
 		Run:   watchCommandFunc,
+
+		ValidArgsFunction: completeKeys,
 	}
 
 	cmd.Flags().BoolVarP(&watchInteractive, "interactive", "i", false, "Interactive mode")
@@ -56,6 +76,19 @@ func NewWatchCommand() *cobra.Command {
 	cmd.Flags().Int64Var(&watchRev, "rev", 0, "Revision to start watching")
 	cmd.Flags().BoolVar(&watchPrevKey, "prev-kv", false, "get the previous key-value pair before the event happens")
 	cmd.Flags().BoolVar(&progressNotify, "progress-notify", false, "get periodic watch progress notification from server")
+	cmd.Flags().StringArrayVar(&watchKeys, "watch-key", nil, "Watch on the given key (can be repeated to watch multiple keys)")
+	cmd.Flags().StringArrayVar(&watchKeyPrefixes, "key-prefix", nil, "Watch on the given prefix (can be repeated to watch multiple prefixes)")
+	cmd.Flags().Int64Var(&watchFromRev, "from-rev", 0, "Revision to start watching (alias of --rev)")
+	cmd.Flags().Int64Var(&watchToRev, "to-rev", 0, "Stop watching once the events up to this revision are printed")
+	cmd.Flags().BoolVar(&watchNoFollow, "no-follow", false, "Replay the events written before the watch started and exit")
+	cmd.Flags().StringVar(&watchExecTemplate, "exec-template", "", "Command line template (Go text/template) to execute on every event, e.g. 'echo {{.Key}} {{.Value}}'")
+	cmd.Flags().IntVar(&watchExecConcurrency, "exec-concurrency", 1, "Maximum number of commands executed concurrently")
+	cmd.Flags().DurationVar(&watchExecDebounce, "exec-debounce", 0, "Coalesce events on the same key within this window into a single command execution")
+	cmd.Flags().StringVar(&watchKeyPattern, "key-pattern", "", "Only watch the events of keys matching the glob pattern, evaluated by the server")
+	cmd.Flags().StringVar(&watchKeyRegex, "key-regex", "", "Only watch the events of keys matching the regular expression, evaluated by the server")
+	cmd.Flags().StringVar(&watchValueContains, "value-contains", "", "Only watch the put events whose value contains the string")
+	cmd.Flags().StringVar(&watchValueJSONPath, "value-json-path", "", "Only watch the put events whose JSON value has something at the dot separated path")
+	cmd.Flags().StringVar(&watchValueJSONEquals, "value-json-equals", "", "Only watch the put events whose JSON value at --value-json-path equals the JSON encoded value")
 
 	return cmd
 }
@@ -67,7 +100,21 @@ func watchCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, fmt.Errorf("ETCDCTL_WATCH_KEY is empty but got ETCDCTL_WATCH_RANGE_END=%q", envRange))
 	}
 
+	if watchFromRev != 0 {
+		if watchRev != 0 {
+			ExitWithError(ExitBadArgs, errors.New("--rev and --from-rev are mutually exclusive"))
+		}
+		watchRev = watchFromRev
+	}
+	replay := watchToRev != 0 || watchNoFollow
+
 	if watchInteractive {
+		if len(watchKeys) > 0 || len(watchKeyPrefixes) > 0 {
+			ExitWithError(ExitBadArgs, errBadArgsInteractiveMulti)
+		}
+		if replay {
+			ExitWithError(ExitBadArgs, errors.New("--to-rev and --no-follow are not supported in interactive mode"))
+		}
 		watchInteractiveFunc(cmd, os.Args, envKey, envRange)
 		return
 	}
@@ -77,17 +124,70 @@ func watchCommandFunc(cmd *cobra.Command, args []string) {
 		ExitWithError(ExitBadArgs, err)
 	}
 
+	if replay {
+		watchReplayFunc(cmd, watchArgs, execArgs)
+		return
+	}
+
 	c := mustClientFromCmd(cmd)
-	wc, err := getWatchChan(c, watchArgs)
+	if len(watchKeys) > 0 || len(watchKeyPrefixes) > 0 {
+		specs, err := getWatchSpecs(watchArgs, watchKeys, watchKeyPrefixes)
+		if err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+		printMultiWatchCh(c, getMultiWatchChan(c, specs), execArgs)
+	} else {
+		wc, err := getWatchChan(c, watchArgs)
+		if err != nil {
+			ExitWithError(ExitBadArgs, err)
+		}
+		printWatchCh(c, wc, execArgs)
+	}
+	if err = c.Close(); err != nil {
+		ExitWithError(ExitBadConnection, err)
+	}
+	ExitWithError(ExitInterrupted, fmt.Errorf("watch is canceled by the server"))
+}
+
+// watchReplayFunc replays the events between "--from-rev" and "--to-rev"
+// (or the current revision) and exits.
+func watchReplayFunc(cmd *cobra.Command, watchArgs, execArgs []string) {
+	if watchRev <= 0 {
+		ExitWithError(ExitBadArgs, errReplayNoStartRev)
+	}
+	if watchToRev < 0 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--to-rev must not be negative, got %d", watchToRev))
+	}
+	if watchToRev != 0 && watchToRev < watchRev {
+		ExitWithError(ExitBadArgs, fmt.Errorf("--to-rev %d is lower than the start revision %d", watchToRev, watchRev))
+	}
+	tagged := len(watchKeys) > 0 || len(watchKeyPrefixes) > 0
+	specs, err := getWatchSpecs(watchArgs, watchKeys, watchKeyPrefixes)
 	if err != nil {
 		ExitWithError(ExitBadArgs, err)
 	}
+	if len(specs) == 0 {
+		ExitWithError(ExitBadArgs, errBadArgsNum)
+	}
 
-	printWatchCh(c, wc, execArgs)
+	c := mustClientFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	resp, err := c.Get(ctx, "foo", clientv3.WithCountOnly())
+	cancel()
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	end := replayEndRev(resp.Header.Revision, watchToRev, !watchNoFollow)
+	switch err = watchReplay(c, specs, tagged, watchRev, end, execArgs); err {
+	case nil:
+	case errReplayCanceled:
+		ExitWithError(ExitInterrupted, err)
+	default:
+		ExitWithError(ExitError, err)
+	}
 	if err = c.Close(); err != nil {
 		ExitWithError(ExitBadConnection, err)
 	}
-	ExitWithError(ExitInterrupted, fmt.Errorf("watch is canceled by the server"))
 }
 
 func watchInteractiveFunc(cmd *cobra.Command, osArgs []string, envKey, envRange string) {
@@ -159,34 +259,178 @@ func getWatchChan(c *clientv3.Client, args []string) (clientv3.WatchChan, error)
 	if progressNotify {
 		opts = append(opts, clientv3.WithProgressNotify())
 	}
+	opts = append(opts, watchFilterOpts()...)
 	return c.Watch(clientv3.WithRequireLeader(context.Background()), key, opts...), nil
 }
 
+// watchFilterOpts returns the options of the event filters given by flags.
+func watchFilterOpts() []clientv3.OpOption {
+	var opts []clientv3.OpOption
+	if watchKeyPattern != "" {
+		opts = append(opts, clientv3.WithKeyPattern(watchKeyPattern))
+	}
+	if watchKeyRegex != "" {
+		opts = append(opts, clientv3.WithKeyRegex(watchKeyRegex))
+	}
+	if watchValueContains != "" {
+		opts = append(opts, clientv3.WithValueContains(watchValueContains))
+	}
+	if watchValueJSONPath != "" || watchValueJSONEquals != "" {
+		opts = append(opts, clientv3.WithValueJSONPath(watchValueJSONPath, watchValueJSONEquals))
+	}
+	return opts
+}
+
 func printWatchCh(c *clientv3.Client, ch clientv3.WatchChan, execArgs []string) {
+	e := mustWatchExecutor(c, execArgs)
 	for resp := range ch {
-		if resp.Canceled {
-			fmt.Fprintf(os.Stderr, "watch was canceled (%v)\n", resp.Err())
+		printWatchResp(e, "", resp)
+	}
+	if e != nil {
+		e.Wait()
+	}
+}
+
+// mustWatchExecutor returns the executor for the exec-command or the
+// "--exec-template" flag, or nil if no command is to be executed.
+func mustWatchExecutor(c *clientv3.Client, execArgs []string) *watchExecutor {
+	if len(execArgs) == 0 && watchExecTemplate == "" {
+		return nil
+	}
+	e, err := newWatchExecutor(c.Ctx(), execArgs, watchExecTemplate, watchExecConcurrency, watchExecDebounce)
+	if err != nil {
+		ExitWithError(ExitBadArgs, err)
+	}
+	return e
+}
+
+func printWatchResp(e *watchExecutor, watcher string, resp clientv3.WatchResponse) {
+	if resp.Canceled {
+		fmt.Fprintf(os.Stderr, "watch was canceled (%v)\n", resp.Err())
+	}
+	if resp.IsProgressNotify() {
+		fmt.Fprintf(os.Stdout, "progress notify: %d\n", resp.Header.Revision)
+	}
+	if watcher == "" {
+		display.Watch(resp)
+	} else {
+		display.TaggedWatch(watcher, resp)
+	}
+
+	if e != nil {
+		for _, ev := range resp.Events {
+			e.Exec(watchExecEvent{
+				Watcher:   watcher,
+				Key:       string(ev.Kv.Key),
+				Value:     string(ev.Kv.Value),
+				Revision:  resp.Header.Revision,
+				EventType: ev.Type.String(),
+			})
+		}
+	}
+}
+
+// watchSpec describes one of the watchers multiplexed by a single
+// non-interactive "watch" invocation.
+type watchSpec struct {
+	// tag identifies the watcher in the output stream.
+	tag      string
+	key      string
+	rangeEnd string
+	prefix   bool
+}
+
+// taggedWatchResponse is a watch response annotated with the watcher
+// that produced it.
+type taggedWatchResponse struct {
+	Watcher string `json:"watcher"`
+	clientv3.WatchResponse
+}
+
+// getWatchSpecs builds the list of watchers from the positional arguments
+// (if any) and the repeated "--watch-key" and "--key-prefix" flags.
+func getWatchSpecs(args []string, keys []string, prefixes []string) ([]watchSpec, error) {
+	var specs []watchSpec
+	switch len(args) {
+	case 0:
+	case 1:
+		if watchPrefix {
+			specs = append(specs, watchSpec{tag: "prefix:" + args[0], key: args[0], prefix: true})
+		} else {
+			specs = append(specs, watchSpec{tag: "key:" + args[0], key: args[0]})
 		}
-		if resp.IsProgressNotify() {
-			fmt.Fprintf(os.Stdout, "progress notify: %d\n", resp.Header.Revision)
+	case 2:
+		if watchPrefix {
+			return nil, fmt.Errorf("`range_end` and `--prefix` are mutually exclusive")
 		}
-		display.Watch(resp)
+		specs = append(specs, watchSpec{tag: "range:" + args[0] + ":" + args[1], key: args[0], rangeEnd: args[1]})
+	default:
+		return nil, errBadArgsNum
+	}
+	for _, k := range keys {
+		specs = append(specs, watchSpec{tag: "key:" + k, key: k})
+	}
+	for _, p := range prefixes {
+		specs = append(specs, watchSpec{tag: "prefix:" + p, key: p, prefix: true})
+	}
+	seen := make(map[string]struct{}, len(specs))
+	for _, s := range specs {
+		if _, ok := seen[s.tag]; ok {
+			return nil, fmt.Errorf("duplicate watcher %q", s.tag)
+		}
+		seen[s.tag] = struct{}{}
+	}
+	return specs, nil
+}
 
-		if len(execArgs) > 0 {
-			for _, ev := range resp.Events {
-				cmd := exec.CommandContext(c.Ctx(), execArgs[0], execArgs[1:]...)
-				cmd.Env = os.Environ()
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_REVISION=%d", resp.Header.Revision))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_EVENT_TYPE=%q", ev.Type))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_KEY=%q", ev.Kv.Key))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_VALUE=%q", ev.Kv.Value))
-				cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-				if err := cmd.Run(); err != nil {
-					fmt.Fprintf(os.Stderr, "command %q error (%v)\n", execArgs, err)
-					os.Exit(1)
-				}
+// watchOpts returns the options of the watch of the spec from the revision.
+func (s watchSpec) watchOpts(rev int64) []clientv3.OpOption {
+	opts := []clientv3.OpOption{clientv3.WithRev(rev)}
+	if s.rangeEnd != "" {
+		opts = append(opts, clientv3.WithRange(s.rangeEnd))
+	}
+	if s.prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	if watchPrevKey {
+		opts = append(opts, clientv3.WithPrevKV())
+	}
+	if progressNotify {
+		opts = append(opts, clientv3.WithProgressNotify())
+	}
+	return append(opts, watchFilterOpts()...)
+}
+
+// getMultiWatchChan opens one watch per spec and multiplexes all of them
+// onto a single channel. The returned channel is closed once every
+// underlying watch channel is closed.
+func getMultiWatchChan(c *clientv3.Client, specs []watchSpec) <-chan taggedWatchResponse {
+	out := make(chan taggedWatchResponse)
+	var wg sync.WaitGroup
+	wg.Add(len(specs))
+	for _, s := range specs {
+		wch := c.Watch(clientv3.WithRequireLeader(context.Background()), s.key, s.watchOpts(watchRev)...)
+		go func(tag string, wch clientv3.WatchChan) {
+			defer wg.Done()
+			for resp := range wch {
+				out <- taggedWatchResponse{Watcher: tag, WatchResponse: resp}
 			}
-		}
+		}(s.tag, wch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func printMultiWatchCh(c *clientv3.Client, ch <-chan taggedWatchResponse, execArgs []string) {
+	e := mustWatchExecutor(c, execArgs)
+	for resp := range ch {
+		printWatchResp(e, resp.Watcher, resp.WatchResponse)
+	}
+	if e != nil {
+		e.Wait()
 	}
 }
 
@@ -556,3 +556,63 @@ func Test_parseWatchArgs(t *testing.T) {
 		}
 	}
 }
+
+func Test_getWatchSpecs(t *testing.T) {
+	tt := []struct {
+		args     []string
+		prefix   bool
+		keys     []string
+		prefixes []string
+
+		specs []watchSpec
+		err   bool
+	}{
+		{
+			keys:     []string{"foo", "bar"},
+			prefixes: []string{"/a/"},
+			specs: []watchSpec{
+				{tag: "key:foo", key: "foo"},
+				{tag: "key:bar", key: "bar"},
+				{tag: "prefix:/a/", key: "/a/", prefix: true},
+			},
+		},
+		{
+			args:   []string{"foo"},
+			prefix: true,
+			keys:   []string{"bar"},
+			specs: []watchSpec{
+				{tag: "prefix:foo", key: "foo", prefix: true},
+				{tag: "key:bar", key: "bar"},
+			},
+		},
+		{
+			args: []string{"a", "b"},
+			keys: []string{"c"},
+			specs: []watchSpec{
+				{tag: "range:a:b", key: "a", rangeEnd: "b"},
+				{tag: "key:c", key: "c"},
+			},
+		},
+		{
+			args:   []string{"a", "b"},
+			prefix: true,
+			keys:   []string{"c"},
+			err:    true,
+		},
+		{
+			keys: []string{"foo", "foo"},
+			err:  true,
+		},
+	}
+	for i, ts := range tt {
+		watchPrefix = ts.prefix
+		specs, err := getWatchSpecs(ts.args, ts.keys, ts.prefixes)
+		if (err != nil) != ts.err {
+			t.Fatalf("#%d: error expected %v, got %v", i, ts.err, err)
+		}
+		if !reflect.DeepEqual(specs, ts.specs) {
+			t.Fatalf("#%d: specs expected %+v, got %+v", i, ts.specs, specs)
+		}
+	}
+	watchPrefix = false
+}
@@ -0,0 +1,250 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+var errEmptyExecTemplate = errors.New("exec template rendered an empty command")
+
+// watchExecEvent is the data passed to the "--exec-template" template
+// and exported to the executed command as ETCD_WATCH_* variables.
+type watchExecEvent struct {
+	Watcher   string
+	Key       string
+	Value     string
+	Revision  int64
+	EventType string
+}
+
+// env returns the ETCD_WATCH_* environment variables for the event.
+func (ev watchExecEvent) env() []string {
+	env := []string{
+		fmt.Sprintf("ETCD_WATCH_REVISION=%d", ev.Revision),
+		fmt.Sprintf("ETCD_WATCH_EVENT_TYPE=%q", ev.EventType),
+		fmt.Sprintf("ETCD_WATCH_KEY=%q", ev.Key),
+		fmt.Sprintf("ETCD_WATCH_VALUE=%q", ev.Value),
+	}
+	if ev.Watcher != "" {
+		env = append(env, fmt.Sprintf("ETCD_WATCH_WATCHER=%q", ev.Watcher))
+	}
+	return env
+}
+
+// watchExecutor runs a command for every watch event, bounding the number
+// of commands running at the same time and optionally coalescing bursts of
+// events on the same key into a single execution.
+type watchExecutor struct {
+	ctx context.Context
+
+	args []string
+	// tmpl holds a template per command argument, so that the rendered
+	// values never split into or inject other arguments.
+	tmpl []*template.Template
+
+	debounce time.Duration
+	sem      chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]*pendingExec
+
+	wg sync.WaitGroup
+}
+
+type pendingExec struct {
+	ev    watchExecEvent
+	timer *time.Timer
+}
+
+// newWatchExecutor returns an executor for either the given command
+// arguments or, if tmpl is not empty, the given command line template.
+func newWatchExecutor(ctx context.Context, args []string, tmpl string, concurrency int, debounce time.Duration) (*watchExecutor, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("exec concurrency must be at least 1, got %d", concurrency)
+	}
+	if debounce < 0 {
+		return nil, fmt.Errorf("exec debounce must not be negative, got %v", debounce)
+	}
+	e := &watchExecutor{
+		ctx:      ctx,
+		args:     args,
+		debounce: debounce,
+		sem:      make(chan struct{}, concurrency),
+		pending:  make(map[string]*pendingExec),
+	}
+	if tmpl != "" {
+		if len(args) > 0 {
+			return nil, errors.New("--exec-template and exec-command are mutually exclusive")
+		}
+		words, err := splitExecTemplate(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		if len(words) == 0 {
+			return nil, errEmptyExecTemplate
+		}
+		for _, w := range words {
+			t, err := template.New("exec").Option("missingkey=error").Parse(w)
+			if err != nil {
+				return nil, err
+			}
+			e.tmpl = append(e.tmpl, t)
+		}
+	}
+	return e, nil
+}
+
+// Exec schedules the command for the given event. Without a debounce window
+// it blocks until a concurrency slot is available.
+func (e *watchExecutor) Exec(ev watchExecEvent) {
+	if e.debounce == 0 {
+		e.wg.Add(1)
+		e.run(ev)
+		if cap(e.sem) == 1 {
+			// keep the command output in line with the watch output
+			e.Wait()
+		}
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p, ok := e.pending[ev.Key]; ok && p.timer.Stop() {
+		// the previous event on this key has not fired yet; replace it
+		p.ev = ev
+		p.timer.Reset(e.debounce)
+		return
+	}
+	p := &pendingExec{ev: ev}
+	e.pending[ev.Key] = p
+	e.wg.Add(1)
+	p.timer = time.AfterFunc(e.debounce, func() {
+		e.mu.Lock()
+		ev := p.ev
+		if e.pending[ev.Key] == p {
+			delete(e.pending, ev.Key)
+		}
+		e.mu.Unlock()
+		e.run(ev)
+	})
+}
+
+// Wait blocks until every scheduled command has finished.
+func (e *watchExecutor) Wait() {
+	e.wg.Wait()
+}
+
+func (e *watchExecutor) run(ev watchExecEvent) {
+	args, err := e.commandArgs(ev)
+	if err != nil {
+		e.wg.Done()
+		fmt.Fprintf(os.Stderr, "exec template error (%v)\n", err)
+		os.Exit(1)
+	}
+
+	e.sem <- struct{}{}
+	go func() {
+		defer func() {
+			<-e.sem
+			e.wg.Done()
+		}()
+		cmd := exec.CommandContext(e.ctx, args[0], args[1:]...)
+		cmd.Env = append(os.Environ(), ev.env()...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "command %q error (%v)\n", args, err)
+			os.Exit(1)
+		}
+	}()
+}
+
+// commandArgs returns the command to execute for the event.
+func (e *watchExecutor) commandArgs(ev watchExecEvent) ([]string, error) {
+	if e.tmpl == nil {
+		return e.args, nil
+	}
+	args := make([]string, len(e.tmpl))
+	for i, t := range e.tmpl {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, ev); err != nil {
+			return nil, err
+		}
+		args[i] = buf.String()
+	}
+	if args[0] == "" {
+		return nil, errEmptyExecTemplate
+	}
+	return args, nil
+}
+
+// splitExecTemplate splits the command line template into the templates of
+// its arguments. Arguments are separated by white space outside of quotes
+// and template actions, and the quotes are removed.
+func splitExecTemplate(s string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		quote  byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			end := strings.Index(s[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed action in exec template %q", s)
+			}
+			word.WriteString(s[i : i+end+2])
+			inWord = true
+			i += end + 1
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == '"' && c == '\\' && i+1 < len(s):
+			i++
+			word.WriteByte(s[i])
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+			inWord = true
+		case quote == 0 && unicode.IsSpace(rune(c)):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in exec template %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWatchExecutorCommandArgs(t *testing.T) {
+	ev := watchExecEvent{Key: "/config/a", Value: "1", Revision: 5, EventType: "PUT"}
+	tt := []struct {
+		args []string
+		tmpl string
+
+		cmdArgs []string
+		err     bool
+	}{
+		{
+			args:    []string{"echo", "reload"},
+			cmdArgs: []string{"echo", "reload"},
+		},
+		{
+			tmpl:    `echo {{.EventType}} {{.Key}} "{{.Value}} at {{.Revision}}"`,
+			cmdArgs: []string{"echo", "PUT", "/config/a", "1 at 5"},
+		},
+		{
+			tmpl:    `echo {{ printf "%s=%s" .Key .Value }} 'a b' "c\"d"`,
+			cmdArgs: []string{"echo", "/config/a=1", "a b", `c"d`},
+		},
+		{
+			tmpl: `{{.Unknown}}`,
+			err:  true,
+		},
+		{
+			tmpl: `{{if false}}echo{{end}}`,
+			err:  true,
+		},
+	}
+	for i, ts := range tt {
+		e, err := newWatchExecutor(context.Background(), ts.args, ts.tmpl, 1, 0)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		cmdArgs, err := e.commandArgs(ev)
+		if (err != nil) != ts.err {
+			t.Fatalf("#%d: error expected %v, got %v", i, ts.err, err)
+		}
+		if !reflect.DeepEqual(cmdArgs, ts.cmdArgs) {
+			t.Fatalf("#%d: command expected %q, got %q", i, ts.cmdArgs, cmdArgs)
+		}
+	}
+}
+
+func TestWatchExecutorCommandArgsValue(t *testing.T) {
+	ev := watchExecEvent{Key: "/config/a", Value: `1 "--force" 'x'`, Revision: 5, EventType: "PUT"}
+	e, err := newWatchExecutor(context.Background(), nil, `echo {{.Key}} {{.Value}}`, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmdArgs, err := e.commandArgs(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the value is passed as a single argument, whatever its content
+	if want := []string{"echo", "/config/a", `1 "--force" 'x'`}; !reflect.DeepEqual(cmdArgs, want) {
+		t.Fatalf("command expected %q, got %q", want, cmdArgs)
+	}
+}
+
+func TestNewWatchExecutorBadArgs(t *testing.T) {
+	if _, err := newWatchExecutor(context.Background(), []string{"echo"}, "echo {{.Key}}", 1, 0); err == nil {
+		t.Fatal("expected error when both exec-command and template are given")
+	}
+	for _, tmpl := range []string{" ", "echo {{.Key", `echo "{{.Key}}`, "echo {{.Key}"} {
+		if _, err := newWatchExecutor(context.Background(), nil, tmpl, 1, 0); err == nil {
+			t.Fatalf("expected error on template %q", tmpl)
+		}
+	}
+	if _, err := newWatchExecutor(context.Background(), []string{"echo"}, "", 0, 0); err == nil {
+		t.Fatal("expected error on zero concurrency")
+	}
+	if _, err := newWatchExecutor(context.Background(), []string{"echo"}, "", 1, -1); err == nil {
+		t.Fatal("expected error on negative debounce")
+	}
+}
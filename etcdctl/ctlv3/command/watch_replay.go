@@ -0,0 +1,146 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// replayProgressInterval is how often the progress of the replay watches is
+// requested until they caught up with the end revision.
+const replayProgressInterval = 100 * time.Millisecond
+
+var (
+	errReplayNoStartRev = errors.New("--from-rev (or --rev) is required with --to-rev and --no-follow")
+	errReplayCanceled   = errors.New("watch is canceled by the server")
+)
+
+// replayEndRev returns the last revision to replay. Without "--to-rev" the
+// replay ends at the current revision; with "--no-follow" it never waits
+// for revisions that have not been written yet.
+func replayEndRev(cur, to int64, follow bool) int64 {
+	if to == 0 || (!follow && to > cur) {
+		return cur
+	}
+	return to
+}
+
+// trimReplayEvents drops the events written after the end revision and
+// reports whether the replay has reached it.
+func trimReplayEvents(evs []*clientv3.Event, end int64) ([]*clientv3.Event, bool) {
+	for i, ev := range evs {
+		if ev.Kv.ModRevision > end {
+			return evs[:i], true
+		}
+	}
+	return evs, len(evs) > 0 && evs[len(evs)-1].Kv.ModRevision >= end
+}
+
+// replayReached reports whether a watch response of a replay shows that the
+// watch has sent its history up to the end revision, and returns the events
+// of the response to print.
+func replayReached(resp clientv3.WatchResponse, end int64) ([]*clientv3.Event, bool) {
+	if resp.IsProgressNotify() {
+		return nil, resp.Header.Revision >= end
+	}
+	return trimReplayEvents(resp.Events, end)
+}
+
+// replayResponse is a response of the watch of the i-th spec of a replay,
+// or the end of the watch if closed is set.
+type replayResponse struct {
+	i      int
+	resp   clientv3.WatchResponse
+	closed bool
+}
+
+// watchReplay prints the events on the watched keys between the start
+// revision and the end revision, then returns.
+//
+// Every spec is watched on its own range. A watch has sent its history up
+// to the end revision once it sends an event at or past it, or a progress
+// notification at or past it. The progress is only notified once all the
+// watches caught up with the member, so it is requested until the replay
+// is complete.
+func watchReplay(c *clientv3.Client, specs []watchSpec, tagged bool, from, end int64, execArgs []string) error {
+	if from > end {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(context.Background()))
+	defer cancel()
+	respc := make(chan replayResponse)
+	for i, s := range specs {
+		go func(i int, wch clientv3.WatchChan) {
+			for resp := range wch {
+				select {
+				case respc <- replayResponse{i: i, resp: resp}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case respc <- replayResponse{i: i, closed: true}:
+			case <-ctx.Done():
+			}
+		}(i, c.Watch(ctx, s.key, s.watchOpts(from)...))
+	}
+
+	e := mustWatchExecutor(c, execArgs)
+	defer func() {
+		if e != nil {
+			e.Wait()
+		}
+	}()
+	ticker := time.NewTicker(replayProgressInterval)
+	defer ticker.Stop()
+	done := make([]bool, len(specs))
+	for left := len(specs); left > 0; {
+		select {
+		case r := <-respc:
+			if r.closed {
+				return errReplayCanceled
+			}
+			if err := r.resp.Err(); err != nil {
+				return err
+			}
+			if done[r.i] {
+				continue
+			}
+			evs, reached := replayReached(r.resp, end)
+			if len(evs) > 0 {
+				r.resp.Events = evs
+				if tagged {
+					printWatchResp(e, specs[r.i].tag, r.resp)
+				} else {
+					printWatchResp(e, "", r.resp)
+				}
+			}
+			if reached {
+				done[r.i] = true
+				left--
+			}
+		case <-ticker.C:
+			if err := c.RequestProgress(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+func TestReplayEndRev(t *testing.T) {
+	tt := []struct {
+		cur, to int64
+		follow  bool
+
+		end int64
+	}{
+		{cur: 10, to: 0, follow: false, end: 10},
+		{cur: 10, to: 0, follow: true, end: 10},
+		{cur: 10, to: 5, follow: false, end: 5},
+		{cur: 10, to: 20, follow: false, end: 10},
+		{cur: 10, to: 20, follow: true, end: 20},
+	}
+	for i, tc := range tt {
+		if end := replayEndRev(tc.cur, tc.to, tc.follow); end != tc.end {
+			t.Errorf("#%d: expected end revision %d, got %d", i, tc.end, end)
+		}
+	}
+}
+
+func TestTrimReplayEvents(t *testing.T) {
+	evs := func(revs ...int64) []*clientv3.Event {
+		var evs []*clientv3.Event
+		for _, rev := range revs {
+			evs = append(evs, &clientv3.Event{Kv: &mvccpb.KeyValue{ModRevision: rev}})
+		}
+		return evs
+	}
+	tt := []struct {
+		evs []*clientv3.Event
+		end int64
+
+		kept int
+		done bool
+	}{
+		{evs: nil, end: 5, kept: 0, done: false},
+		{evs: evs(2, 3), end: 5, kept: 2, done: false},
+		{evs: evs(4, 5, 5), end: 5, kept: 3, done: true},
+		{evs: evs(4, 6, 7), end: 5, kept: 1, done: true},
+		{evs: evs(6), end: 5, kept: 0, done: true},
+	}
+	for i, tc := range tt {
+		kept, done := trimReplayEvents(tc.evs, tc.end)
+		if len(kept) != tc.kept || done != tc.done {
+			t.Errorf("#%d: expected (%d, %v), got (%d, %v)", i, tc.kept, tc.done, len(kept), done)
+		}
+	}
+}
+
+func TestReplayReached(t *testing.T) {
+	progress := func(rev int64) clientv3.WatchResponse {
+		return clientv3.WatchResponse{Header: pb.ResponseHeader{Revision: rev}}
+	}
+	events := func(revs ...int64) clientv3.WatchResponse {
+		resp := progress(revs[len(revs)-1])
+		for _, rev := range revs {
+			resp.Events = append(resp.Events, &clientv3.Event{Kv: &mvccpb.KeyValue{ModRevision: rev}})
+		}
+		return resp
+	}
+	tt := []struct {
+		resp clientv3.WatchResponse
+		end  int64
+
+		evs     int
+		reached bool
+	}{
+		{resp: progress(4), end: 5, evs: 0, reached: false},
+		{resp: progress(5), end: 5, evs: 0, reached: true},
+		{resp: progress(9), end: 5, evs: 0, reached: true},
+		{resp: events(3, 4), end: 5, evs: 2, reached: false},
+		{resp: events(4, 6), end: 5, evs: 1, reached: true},
+	}
+	for i, tc := range tt {
+		evs, reached := replayReached(tc.resp, tc.end)
+		if len(evs) != tc.evs || reached != tc.reached {
+			t.Errorf("#%d: expected (%d, %v), got (%d, %v)", i, tc.evs, tc.reached, len(evs), reached)
+		}
+	}
+}
@@ -42,6 +42,9 @@ var (
 		Use:        cliName,
 		Short:      cliDescription,
 		SuggestFor: []string{"etcdctl"},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			command.InitGlobalFlags(cmd)
+		},
 	}
 )
 
@@ -68,6 +71,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&globalFlags.Password, "password", "", "password for authentication (if this option is used, --user option shouldn't include password)")
 	rootCmd.PersistentFlags().StringVarP(&globalFlags.TLS.ServerName, "discovery-srv", "d", "", "domain name to query for SRV records describing cluster endpoints")
 	rootCmd.PersistentFlags().StringVarP(&globalFlags.DNSClusterServiceName, "discovery-srv-name", "", "", "service name to query when using DNS discovery")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.Context, "context", "", "use the named context of the configuration file (defaults to its current context)")
+	rootCmd.PersistentFlags().StringVar(&globalFlags.ConfigFile, "config-file", "", "path of the configuration file with named contexts (defaults to ~/.etcdctl/config.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.CompleteKeys, "complete-keys", false, "complete key arguments in the shell with a bounded keys-only range")
 
 	rootCmd.AddCommand(
 		command.NewGetCommand(),
@@ -78,6 +84,7 @@ func init() {
 		command.NewAlarmCommand(),
 		command.NewDefragCommand(),
 		command.NewEndpointCommand(),
+		command.NewClusterCommand(),
 		command.NewMoveLeaderCommand(),
 		command.NewWatchCommand(),
 		command.NewVersionCommand(),
@@ -92,6 +99,17 @@ func init() {
 		command.NewUserCommand(),
 		command.NewRoleCommand(),
 		command.NewCheckCommand(),
+		command.NewSizeCommand(),
+		command.NewDiffCommand(),
+		command.NewConfigCommand(),
+		command.NewCompletionCommand(),
+		command.NewTopCommand(),
+		command.NewDebugCommand(),
+		command.NewDowngradeCommand(),
+		command.NewMaintenanceCommand(),
+		command.NewRateLimitCommand(),
+		command.NewWALCommand(),
+		command.NewBackendCommand(),
 	)
 }
 
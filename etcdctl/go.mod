@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/bgentry/speakeasy v0.1.0
+	github.com/coreos/go-semver v0.3.0
 	github.com/dustin/go-humanize v1.0.0
 	github.com/gogo/protobuf v1.3.1
 	github.com/olekukonko/tablewriter v0.0.4
@@ -18,9 +19,11 @@ require (
 	go.etcd.io/etcd/raft/v3 v3.0.0-00010101000000-000000000000
 	go.etcd.io/etcd/v3 v3.0.0-00010101000000-000000000000
 	go.uber.org/zap v1.16.0
+	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
 	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
 	google.golang.org/grpc v1.29.1
 	gopkg.in/cheggaaa/pb.v1 v1.0.28
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (
@@ -0,0 +1,279 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+var (
+	// valueSizeBounds are the upper bounds of the value size histogram.
+	valueSizeBounds = []int64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+	// revisionCountBounds are the upper bounds of the histogram of the
+	// number of revisions per key.
+	revisionCountBounds = []int64{1, 2, 5, 10, 100, 1000}
+)
+
+// AnalyzeConfig configures the analysis of a snapshot file.
+type AnalyzeConfig struct {
+	// Group returns the prefix a key is accounted under. If nil, every key
+	// is accounted under the empty prefix.
+	Group func(key string) string
+	// Top is the number of keys and leases listed by size, revisions and
+	// attached keys.
+	Top int
+}
+
+// Analysis is the breakdown of the keyspace and the storage of a snapshot.
+type Analysis struct {
+	Revision        int64 `json:"revision"`
+	CompactRevision int64 `json:"compactRevision"`
+	TotalSize       int64 `json:"totalSize"`
+
+	// Keys is the number of keys at the snapshot revision.
+	Keys int64 `json:"keys"`
+	// Revisions is the number of revisions kept in the snapshot, including
+	// the deletions.
+	Revisions  int64 `json:"revisions"`
+	Tombstones int64 `json:"tombstones"`
+	// KeyBytes and ValueBytes are the sizes of all the revisions.
+	KeyBytes   int64 `json:"keyBytes"`
+	ValueBytes int64 `json:"valueBytes"`
+
+	// ValueSizes is the histogram of the value sizes of the keys.
+	ValueSizes []HistogramBucket `json:"valueSizes"`
+	// RevisionCounts is the histogram of the number of revisions kept per
+	// key, including the deleted keys.
+	RevisionCounts []HistogramBucket `json:"revisionCounts"`
+
+	// Prefixes are the space used per key prefix, largest first.
+	Prefixes []PrefixUsage `json:"prefixes"`
+
+	Leases int64 `json:"leases"`
+	// KeysWithLease is the number of keys attached to a lease.
+	KeysWithLease int64 `json:"keysWithLease"`
+	// TopLeases are the leases with the most attached keys.
+	TopLeases []LeaseUsage `json:"topLeases,omitempty"`
+
+	// LargestKeys are the keys with the largest values.
+	LargestKeys []KeyUsage `json:"largestKeys,omitempty"`
+	// MostRevisedKeys are the keys with the most revisions kept.
+	MostRevisedKeys []KeyUsage `json:"mostRevisedKeys,omitempty"`
+}
+
+// HistogramBucket counts the values up to and including UpTo, and above
+// the bound of the previous bucket. UpTo is 0 for the last bucket.
+type HistogramBucket struct {
+	UpTo  int64 `json:"upTo,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// PrefixUsage is the space used by the keys sharing a prefix.
+type PrefixUsage struct {
+	Prefix string `json:"prefix"`
+	// Keys is the number of keys at the snapshot revision.
+	Keys      int64 `json:"keys"`
+	Revisions int64 `json:"revisions"`
+	// Bytes is the size of the keys and values of all the revisions.
+	Bytes int64 `json:"bytes"`
+	// LiveBytes is the size of the keys and values at the snapshot
+	// revision.
+	LiveBytes int64 `json:"liveBytes"`
+}
+
+// LeaseUsage is the number of keys attached to a lease.
+type LeaseUsage struct {
+	ID   int64 `json:"id"`
+	Keys int64 `json:"keys"`
+}
+
+// KeyUsage is the space used by a key.
+type KeyUsage struct {
+	Key       string `json:"key"`
+	ValueSize int64  `json:"valueSize"`
+	Revisions int64  `json:"revisions"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+// keyState is the state of a key while walking the revisions.
+type keyState struct {
+	revisions int64
+	bytes     int64
+	valueSize int64
+	lease     int64
+	deleted   bool
+}
+
+// Analyze returns the breakdown of the keyspace and the storage of the
+// snapshot file.
+func (s *v3Manager) Analyze(dbPath string, cfg AnalyzeConfig) (a Analysis, err error) {
+	if _, err = os.Stat(dbPath); err != nil {
+		return a, err
+	}
+
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return a, err
+	}
+	defer db.Close()
+
+	keys := make(map[string]*keyState)
+	if err = db.View(func(tx *bolt.Tx) error {
+		a.TotalSize = tx.Size()
+		if b := tx.Bucket([]byte("meta")); b != nil {
+			if v := b.Get([]byte("finishedCompactRev")); len(v) >= 17 {
+				a.CompactRevision = bytesToRev(v).main
+			}
+		}
+		if b := tx.Bucket([]byte("lease")); b != nil {
+			a.Leases = int64(b.Stats().KeyN)
+		}
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return fmt.Errorf("snapshot has no key bucket")
+		}
+		// revisions are walked in order, so the last one of a key is its
+		// state at the snapshot revision
+		return b.ForEach(func(k, v []byte) error {
+			var kv mvccpb.KeyValue
+			if err := kv.Unmarshal(v); err != nil {
+				return fmt.Errorf("cannot decode revision %x (%v)", k, err)
+			}
+			a.Revision = bytesToRev(k).main
+			a.Revisions++
+			a.KeyBytes += int64(len(kv.Key))
+			a.ValueBytes += int64(len(kv.Value))
+
+			ks, ok := keys[string(kv.Key)]
+			if !ok {
+				ks = &keyState{}
+				keys[string(kv.Key)] = ks
+			}
+			ks.revisions++
+			ks.bytes += int64(len(kv.Key) + len(kv.Value))
+			ks.deleted = isTombstone(k)
+			if ks.deleted {
+				a.Tombstones++
+			}
+			ks.valueSize, ks.lease = int64(len(kv.Value)), kv.Lease
+			return nil
+		})
+	}); err != nil {
+		return a, err
+	}
+
+	a.ValueSizes = newHistogram(valueSizeBounds)
+	a.RevisionCounts = newHistogram(revisionCountBounds)
+	prefixes := make(map[string]*PrefixUsage)
+	leases := make(map[int64]int64)
+	var usage []KeyUsage
+	for k, ks := range keys {
+		p := ""
+		if cfg.Group != nil {
+			p = cfg.Group(k)
+		}
+		pu, ok := prefixes[p]
+		if !ok {
+			pu = &PrefixUsage{Prefix: p}
+			prefixes[p] = pu
+		}
+		pu.Revisions += ks.revisions
+		pu.Bytes += ks.bytes
+		observe(a.RevisionCounts, revisionCountBounds, ks.revisions)
+		usage = append(usage, KeyUsage{Key: k, ValueSize: ks.valueSize, Revisions: ks.revisions, Deleted: ks.deleted})
+		if ks.deleted {
+			continue
+		}
+
+		a.Keys++
+		pu.Keys++
+		pu.LiveBytes += int64(len(k)) + ks.valueSize
+		observe(a.ValueSizes, valueSizeBounds, ks.valueSize)
+		if ks.lease != 0 {
+			a.KeysWithLease++
+			leases[ks.lease]++
+		}
+	}
+
+	for _, pu := range prefixes {
+		a.Prefixes = append(a.Prefixes, *pu)
+	}
+	sort.Slice(a.Prefixes, func(i, j int) bool {
+		if a.Prefixes[i].Bytes != a.Prefixes[j].Bytes {
+			return a.Prefixes[i].Bytes > a.Prefixes[j].Bytes
+		}
+		return a.Prefixes[i].Prefix < a.Prefixes[j].Prefix
+	})
+
+	if cfg.Top <= 0 {
+		return a, nil
+	}
+	for id, n := range leases {
+		a.TopLeases = append(a.TopLeases, LeaseUsage{ID: id, Keys: n})
+	}
+	sort.Slice(a.TopLeases, func(i, j int) bool {
+		if a.TopLeases[i].Keys != a.TopLeases[j].Keys {
+			return a.TopLeases[i].Keys > a.TopLeases[j].Keys
+		}
+		return a.TopLeases[i].ID < a.TopLeases[j].ID
+	})
+	if len(a.TopLeases) > cfg.Top {
+		a.TopLeases = a.TopLeases[:cfg.Top]
+	}
+
+	var live []KeyUsage
+	for _, u := range usage {
+		if !u.Deleted {
+			live = append(live, u)
+		}
+	}
+	a.LargestKeys = topKeys(live, cfg.Top, func(u KeyUsage) int64 { return u.ValueSize })
+	a.MostRevisedKeys = topKeys(usage, cfg.Top, func(u KeyUsage) int64 { return u.Revisions })
+	return a, nil
+}
+
+func newHistogram(bounds []int64) []HistogramBucket {
+	h := make([]HistogramBucket, len(bounds)+1)
+	for i, b := range bounds {
+		h[i].UpTo = b
+	}
+	return h
+}
+
+func observe(h []HistogramBucket, bounds []int64, v int64) {
+	i := sort.Search(len(bounds), func(i int) bool { return v <= bounds[i] })
+	h[i].Count++
+}
+
+// topKeys returns the n keys with the largest metric, ties broken by key.
+func topKeys(usage []KeyUsage, n int, metric func(KeyUsage) int64) []KeyUsage {
+	sorted := append([]KeyUsage(nil), usage...)
+	sort.Slice(sorted, func(i, j int) bool {
+		mi, mj := metric(sorted[i]), metric(sorted[j])
+		if mi != mj {
+			return mi > mj
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// Diff lists the keys that differ between two snapshots. Keys are relative
+// to the first snapshot, e.g. keys only present in the second one are
+// "added".
+type Diff struct {
+	RevisionA int64    `json:"revisionA"`
+	RevisionB int64    `json:"revisionB"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+}
+
+// Diff compares the keys with the prefix at the revisions of two snapshot
+// files. A key changed if its value differs.
+func (s *v3Manager) Diff(dbPathA, dbPathB, prefix string) (d Diff, err error) {
+	a, revA, err := latestKVs(dbPathA, []byte(prefix))
+	if err != nil {
+		return d, err
+	}
+	b, revB, err := latestKVs(dbPathB, []byte(prefix))
+	if err != nil {
+		return d, err
+	}
+	d.RevisionA, d.RevisionB = revA, revB
+
+	for k, kvb := range b {
+		kva, ok := a[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, k)
+		case !bytes.Equal(kva.Value, kvb.Value):
+			d.Changed = append(d.Changed, k)
+		}
+		delete(a, k)
+	}
+	for k := range a {
+		d.Removed = append(d.Removed, k)
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d, nil
+}
+
+// latestKVs returns the keys with the prefix at the revision of the
+// snapshot file, and that revision.
+func latestKVs(dbPath string, prefix []byte) (kvs map[string]mvccpb.KeyValue, rev int64, err error) {
+	if _, err = os.Stat(dbPath); err != nil {
+		return nil, 0, err
+	}
+	db, err := bolt.Open(dbPath, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer db.Close()
+
+	kvs = make(map[string]mvccpb.KeyValue)
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return fmt.Errorf("snapshot %s has no key bucket", dbPath)
+		}
+		// revisions are walked in order, so the last one of a key is its
+		// state at the snapshot revision
+		return b.ForEach(func(k, v []byte) error {
+			rev = bytesToRev(k).main
+			var kv mvccpb.KeyValue
+			if err := kv.Unmarshal(v); err != nil {
+				return fmt.Errorf("cannot decode revision %x (%v)", k, err)
+			}
+			if !bytes.HasPrefix(kv.Key, prefix) {
+				return nil
+			}
+			if isTombstone(k) {
+				delete(kvs, string(kv.Key))
+				return nil
+			}
+			kvs[string(kv.Key)] = kv
+			return nil
+		})
+	})
+	return kvs, rev, err
+}
@@ -0,0 +1,265 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// An encrypted snapshot starts with encryptedMagic, followed by the length,
+// as a big-endian uint32, of the JSON EncryptionHeader and the header. The
+// snapshot is then sealed with AES-256-GCM in chunks of ChunkSize bytes,
+// each stored as a final flag byte, the big-endian uint32 length of the
+// sealed chunk and the sealed chunk. The nonce of a chunk is the nonce
+// prefix, its big-endian uint32 index and its final flag, so that chunks
+// cannot be reordered, and the snapshot cannot be truncated, unnoticed.
+var encryptedMagic = []byte("etcd-enc")
+
+const (
+	// EncryptionKeySize is the size of the keys snapshots are encrypted
+	// with.
+	EncryptionKeySize = 32
+
+	encryptChunkSize   = 64 * 1024
+	encryptNoncePrefix = 7
+
+	// maxEncryptHeaderSize and maxEncryptChunkSize bound what is read from
+	// an encrypted snapshot before it is authenticated.
+	maxEncryptHeaderSize = 64 * 1024
+	maxEncryptChunkSize  = 16 * 1024 * 1024
+)
+
+// ErrEncryptedTruncated is returned reading an encrypted snapshot that ends
+// before its final chunk.
+var ErrEncryptedTruncated = errors.New("encrypted snapshot is truncated")
+
+// EncryptionHeader describes how a snapshot is encrypted.
+type EncryptionHeader struct {
+	// KMSKeyID is the KMS key WrappedKey, the key the snapshot is
+	// encrypted with, is encrypted with. It is empty if the key is held
+	// by the user.
+	KMSKeyID   string `json:"kmsKeyID,omitempty"`
+	WrappedKey []byte `json:"wrappedKey,omitempty"`
+
+	NoncePrefix []byte `json:"noncePrefix"`
+	ChunkSize   int    `json:"chunkSize"`
+}
+
+// IsEncrypted returns true if the file is an encrypted snapshot.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(encryptedMagic))
+	if _, err = io.ReadFull(f, magic); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return bytes.Equal(magic, encryptedMagic), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, final bool) []byte {
+	nonce := make([]byte, encryptNoncePrefix+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptNoncePrefix:], index)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	hdr   EncryptionHeader
+	aad   []byte
+	buf   []byte
+	index uint32
+}
+
+// NewEncryptWriter returns a writer encrypting what is written to it with
+// the key into w. The header records how to get the key back, and is
+// completed with the nonce prefix and the chunk size. The writer must be
+// closed to write the final chunk.
+func NewEncryptWriter(w io.Writer, key []byte, hdr EncryptionHeader) (io.WriteCloser, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	hdr.NoncePrefix = make([]byte, encryptNoncePrefix)
+	if _, err = rand.Read(hdr.NoncePrefix); err != nil {
+		return nil, err
+	}
+	hdr.ChunkSize = encryptChunkSize
+	b, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	head := append([]byte(nil), encryptedMagic...)
+	head = append(head, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(head[len(encryptedMagic):], uint32(len(b)))
+	if _, err = w.Write(append(head, b...)); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, hdr: hdr, aad: b, buf: make([]byte, 0, hdr.ChunkSize)}, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// a full chunk is only sealed once more data follows, since the
+		// last chunk must be sealed as final
+		if len(ew.buf) == ew.hdr.ChunkSize {
+			if err := ew.seal(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(ew.buf[len(ew.buf):ew.hdr.ChunkSize], p)
+		ew.buf = ew.buf[:len(ew.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+func (ew *encryptWriter) Close() error {
+	return ew.seal(true)
+}
+
+func (ew *encryptWriter) seal(final bool) error {
+	sealed := ew.aead.Seal(nil, chunkNonce(ew.hdr.NoncePrefix, ew.index, final), ew.buf, ew.aad)
+	head := make([]byte, 5)
+	if final {
+		head[0] = 1
+	}
+	binary.BigEndian.PutUint32(head[1:], uint32(len(sealed)))
+	if _, err := ew.w.Write(append(head, sealed...)); err != nil {
+		return err
+	}
+	ew.buf = ew.buf[:0]
+	ew.index++
+	return nil
+}
+
+type decryptReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	hdr   EncryptionHeader
+	aad   []byte
+	buf   []byte
+	index uint32
+	final bool
+}
+
+// NewDecryptReader reads the header of the encrypted snapshot from r, and
+// returns a reader decrypting the snapshot with the key returned for the
+// header.
+func NewDecryptReader(r io.Reader, key func(EncryptionHeader) ([]byte, error)) (io.Reader, error) {
+	head := make([]byte, len(encryptedMagic)+4)
+	if _, err := io.ReadFull(r, head); err != nil || !bytes.Equal(head[:len(encryptedMagic)], encryptedMagic) {
+		return nil, errors.New("not an encrypted snapshot")
+	}
+	n := binary.BigEndian.Uint32(head[len(encryptedMagic):])
+	if n > maxEncryptHeaderSize {
+		return nil, fmt.Errorf("encryption header of %d bytes exceeds %d bytes", n, maxEncryptHeaderSize)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrEncryptedTruncated
+	}
+	var hdr EncryptionHeader
+	if err := json.Unmarshal(b, &hdr); err != nil {
+		return nil, fmt.Errorf("cannot decode the encryption header (%v)", err)
+	}
+	if len(hdr.NoncePrefix) != encryptNoncePrefix || hdr.ChunkSize <= 0 || hdr.ChunkSize > maxEncryptChunkSize {
+		return nil, errors.New("invalid encryption header")
+	}
+	k, err := key(hdr)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead, hdr: hdr, aad: b}, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.final {
+			return 0, io.EOF
+		}
+		if err := dr.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+func (dr *decryptReader) open() error {
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(dr.r, head); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrEncryptedTruncated
+		}
+		return err
+	}
+	n := binary.BigEndian.Uint32(head[1:])
+	if int64(n) > int64(dr.hdr.ChunkSize+dr.aead.Overhead()) {
+		return fmt.Errorf("chunk %d of the encrypted snapshot is corrupted", dr.index)
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(dr.r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrEncryptedTruncated
+		}
+		return err
+	}
+	final := head[0] == 1
+	b, err := dr.aead.Open(sealed[:0], chunkNonce(dr.hdr.NoncePrefix, dr.index, final), sealed, dr.aad)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt chunk %d of the snapshot, wrong key or corrupted snapshot (%v)", dr.index, err)
+	}
+	dr.buf, dr.final = b, final
+	dr.index++
+	return nil
+}
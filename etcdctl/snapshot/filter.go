@@ -0,0 +1,150 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.uber.org/zap"
+)
+
+// PrefixRewrite replaces the Old prefix of the keys by New.
+type PrefixRewrite struct {
+	Old string
+	New string
+}
+
+// keyFilter selects the keys to keep and rewrites them. A key is kept if it
+// has one of the included prefixes, or if none is given, and none of the
+// excluded prefixes. Its prefix is then rewritten by the first rewrite it
+// matches.
+type keyFilter struct {
+	include  []string
+	exclude  []string
+	rewrites []PrefixRewrite
+}
+
+func (f keyFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0 && len(f.rewrites) == 0
+}
+
+func (f keyFilter) keep(key []byte) bool {
+	if len(f.include) > 0 && !hasAnyPrefix(key, f.include) {
+		return false
+	}
+	return !hasAnyPrefix(key, f.exclude)
+}
+
+// rewrite returns the key with its prefix rewritten, or the key itself if it
+// matches no rewrite.
+func (f keyFilter) rewrite(key []byte) []byte {
+	for _, r := range f.rewrites {
+		if bytes.HasPrefix(key, []byte(r.Old)) {
+			return append([]byte(r.New), key[len(r.Old):]...)
+		}
+	}
+	return key
+}
+
+func hasAnyPrefix(key []byte, prefixes []string) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(key, []byte(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKeys removes the revisions of the keys the filter does not keep
+// from the restored database, and rewrites the prefixes of the others. It
+// fails if two keys would be rewritten to a same key, since their
+// revisions would then be mixed up.
+func (s *v3Manager) filterKeys(f keyFilter) error {
+	db, err := bolt.Open(filepath.Join(s.snapDir, "db"), 0600, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var removed, rewritten int
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("key"))
+		if b == nil {
+			return nil
+		}
+
+		var (
+			dels [][]byte
+			puts = make(map[string][]byte)
+			// origins maps the keys after rewriting to their key in the
+			// snapshot
+			origins = make(map[string]string)
+		)
+		if err := b.ForEach(func(k, v []byte) error {
+			var kv mvccpb.KeyValue
+			if err := kv.Unmarshal(v); err != nil {
+				return fmt.Errorf("cannot decode revision %x (%v)", k, err)
+			}
+			if !f.keep(kv.Key) {
+				dels = append(dels, append([]byte(nil), k...))
+				return nil
+			}
+			nk := f.rewrite(kv.Key)
+			if o, ok := origins[string(nk)]; ok && o != string(kv.Key) {
+				return fmt.Errorf("keys %q and %q would both be restored as %q", o, kv.Key, nk)
+			}
+			origins[string(nk)] = string(kv.Key)
+			if bytes.Equal(nk, kv.Key) {
+				return nil
+			}
+			kv.Key = nk
+			nv, err := kv.Marshal()
+			if err != nil {
+				return err
+			}
+			puts[string(k)] = nv
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range dels {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		for k, v := range puts {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		removed, rewritten = len(dels), len(puts)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.lg.Info(
+		"filtered restored keys",
+		zap.Int("removed-revisions", removed),
+		zap.Int("rewritten-revisions", rewritten),
+	)
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+)
+
+// KVHash is the hash of the keyspace of a backend database, as returned by
+// the HashKV RPC of a member.
+type KVHash struct {
+	Hash uint32 `json:"hash"`
+	// Revision is the revision of the database; the hash covers the
+	// revisions up to the requested revision, or up to this one.
+	Revision        int64 `json:"revision"`
+	CompactRevision int64 `json:"compactRevision"`
+}
+
+// HashKV computes the hash of the revisions of the database up to rev, or
+// up to its latest revision if rev is 0, as a member serving it would. The
+// database, a snapshot file or the db file of a data directory, is copied
+// beforehand so that it is left untouched by the store recovery.
+func (s *v3Manager) HashKV(dbPath string, rev int64) (h KVHash, err error) {
+	dir, err := ioutil.TempDir("", "etcd-hashkv")
+	if err != nil {
+		return h, err
+	}
+	defer os.RemoveAll(dir)
+	copyPath := filepath.Join(dir, "db")
+	if err = copyDB(dbPath, copyPath); err != nil {
+		return h, err
+	}
+
+	be := backend.NewDefaultBackend(copyPath)
+	defer be.Close()
+	ci := cindex.NewConsistentIndex(be.BatchTx())
+	mvs := mvcc.NewStore(s.lg, be, &lease.FakeLessor{}, ci, mvcc.StoreConfig{CompactionBatchLimit: math.MaxInt32})
+	defer mvs.Close()
+
+	h.Hash, h.Revision, h.CompactRevision, err = mvs.HashByRev(rev)
+	return h, err
+}
+
+// copyDB copies the database file, without the sha256 digest appended to
+// snapshot files.
+func copyDB(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+	if hasChecksum(size) {
+		size -= sha256.Size
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.CopyN(out, f, size); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
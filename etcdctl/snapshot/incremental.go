@@ -0,0 +1,289 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/fileutil"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.uber.org/zap"
+)
+
+// incrementalVersion is the version of the incremental backup format. An
+// incremental backup is a stream of JSON objects: an incrementalHeader
+// followed by the incrementalEvents of every revision after the base
+// revision, up to and including the backup revision, in revision order.
+const incrementalVersion = 1
+
+type incrementalHeader struct {
+	Version      int   `json:"version"`
+	BaseRevision int64 `json:"baseRevision"`
+	Revision     int64 `json:"revision"`
+}
+
+type incrementalEvent struct {
+	Type     string `json:"type"`
+	Key      []byte `json:"key"`
+	Value    []byte `json:"value,omitempty"`
+	Lease    int64  `json:"lease,omitempty"`
+	Revision int64  `json:"revision"`
+}
+
+// IncrementalStatus describes a saved incremental backup.
+type IncrementalStatus struct {
+	BaseRevision int64 `json:"baseRevision"`
+	Revision     int64 `json:"revision"`
+	Events       int   `json:"events"`
+}
+
+// SaveIncremental saves the changes made after sinceRev to the given path,
+// by replaying the event history from the cluster. It fails if sinceRev has
+// been compacted.
+func (s *v3Manager) SaveIncremental(ctx context.Context, cfg clientv3.Config, sinceRev int64, path string) (st IncrementalStatus, err error) {
+	if sinceRev < 1 {
+		return st, fmt.Errorf("invalid base revision %d", sinceRev)
+	}
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		return st, err
+	}
+	defer cli.Close()
+
+	resp, err := cli.Get(ctx, "\x00", clientv3.WithFromKey(), clientv3.WithCountOnly())
+	if err != nil {
+		return st, err
+	}
+	st = IncrementalStatus{BaseRevision: sinceRev, Revision: resp.Header.Revision}
+	if sinceRev > st.Revision {
+		return st, fmt.Errorf("base revision %d is newer than the current revision %d", sinceRev, st.Revision)
+	}
+
+	partpath := path + ".part"
+	defer os.RemoveAll(partpath)
+	f, err := os.OpenFile(partpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileutil.PrivateFileMode)
+	if err != nil {
+		return st, fmt.Errorf("could not open %s (%v)", partpath, err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	if err = enc.Encode(incrementalHeader{Version: incrementalVersion, BaseRevision: st.BaseRevision, Revision: st.Revision}); err != nil {
+		return st, err
+	}
+
+	if st.Revision > sinceRev {
+		wctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		// every revision changes at least one key, so the history is
+		// complete once the events of the backup revision are seen
+		last := sinceRev
+		wch := cli.Watch(wctx, "\x00", clientv3.WithFromKey(), clientv3.WithRev(sinceRev+1))
+		for last < st.Revision {
+			wr, ok := <-wch
+			if !ok {
+				return st, fmt.Errorf("watch closed at revision %d before reaching revision %d", last, st.Revision)
+			}
+			if wr.CompactRevision != 0 {
+				return st, fmt.Errorf("base revision %d has been compacted (compacted revision %d)", sinceRev, wr.CompactRevision)
+			}
+			if err = wr.Err(); err != nil {
+				return st, err
+			}
+			for _, ev := range wr.Events {
+				if ev.Kv.ModRevision > st.Revision {
+					break
+				}
+				ie := incrementalEvent{Type: "PUT", Key: ev.Kv.Key, Revision: ev.Kv.ModRevision}
+				if ev.Type == mvccpb.DELETE {
+					ie.Type = "DELETE"
+				} else {
+					ie.Value, ie.Lease = ev.Kv.Value, ev.Kv.Lease
+				}
+				if err = enc.Encode(ie); err != nil {
+					return st, err
+				}
+				st.Events++
+				last = ev.Kv.ModRevision
+			}
+		}
+	}
+
+	if err = fileutil.Fsync(f); err != nil {
+		return st, err
+	}
+	if err = f.Close(); err != nil {
+		return st, err
+	}
+	if err = os.Rename(partpath, path); err != nil {
+		return st, fmt.Errorf("could not rename %s to %s (%v)", partpath, path, err)
+	}
+	s.lg.Info("saved incremental backup",
+		zap.String("path", path),
+		zap.Int64("base-revision", st.BaseRevision),
+		zap.Int64("revision", st.Revision),
+		zap.Int("events", st.Events),
+	)
+	return st, nil
+}
+
+// applyIncrementals replays the incremental backups, in order, on top of
+// the restored database. Each revision is applied as one transaction so the
+// restored revisions match the original ones; the transactions are committed
+// in batches of the configured number of keys or bytes.
+func (s *v3Manager) applyIncrementals(paths []string) error {
+	bcfg := backend.DefaultBackendConfig()
+	bcfg.Path = filepath.Join(s.snapDir, "db")
+	if s.batchKeys > 0 {
+		bcfg.BatchLimit = s.batchKeys
+	}
+	// batches are committed by size, or when the backups are applied
+	bcfg.BatchInterval = time.Hour
+	be := backend.New(bcfg)
+	defer be.Close()
+
+	ci := cindex.NewConsistentIndex(be.BatchTx())
+	// load the consistent index set by prepareDB, which the store saves
+	// back with each write
+	ci.ConsistentIndex()
+	// a lessor never timeouts leases
+	lessor := lease.NewLessor(s.lg, be, lease.LessorConfig{MinLeaseTTL: math.MaxInt64}, ci)
+	mvs := mvcc.NewStore(s.lg, be, lessor, ci, mvcc.StoreConfig{CompactionBatchLimit: math.MaxInt32})
+	defer mvs.Close()
+
+	for _, p := range paths {
+		if err := s.applyIncremental(mvs, lessor, p); err != nil {
+			return fmt.Errorf("failed to apply incremental backup %s (%v)", p, err)
+		}
+	}
+	mvs.Commit()
+	return nil
+}
+
+func (s *v3Manager) applyIncremental(mvs mvcc.KV, lessor lease.Lessor, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	var hdr incrementalHeader
+	if err = dec.Decode(&hdr); err != nil {
+		return err
+	}
+	if hdr.Version != incrementalVersion {
+		return fmt.Errorf("unsupported incremental backup version %d", hdr.Version)
+	}
+	// a resumed restore may have applied part of the backup already
+	applied := mvs.Rev()
+	if hdr.BaseRevision != applied && !(s.state.resumed && applied > hdr.BaseRevision && applied <= hdr.Revision) {
+		return fmt.Errorf("backup starts at revision %d, but the restored data is at revision %d", hdr.BaseRevision, applied)
+	}
+
+	var (
+		txn       mvcc.TxnWrite
+		txnRev    int64
+		noLeaseKs int
+		// batchBytes is the size of the keys and values written since the
+		// last commit
+		batchBytes int64
+	)
+	end := func() error {
+		if txn == nil {
+			return nil
+		}
+		txn.End()
+		txn = nil
+		if rev := mvs.Rev(); rev != txnRev {
+			return fmt.Errorf("revision %d was restored as revision %d", txnRev, rev)
+		}
+		if batchBytes >= s.batchBytes {
+			mvs.Commit()
+			batchBytes = 0
+			s.lg.Info("applied incremental backup revisions",
+				zap.String("path", path),
+				zap.Int64("revision", txnRev),
+				zap.Int64("backup-revision", hdr.Revision),
+			)
+		}
+		return nil
+	}
+	for {
+		var ev incrementalEvent
+		if err = dec.Decode(&ev); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if ev.Revision <= applied {
+			continue
+		}
+		if ev.Revision != txnRev {
+			if err = end(); err != nil {
+				return err
+			}
+			txn, txnRev = mvs.Write(traceutil.TODO()), ev.Revision
+		}
+		switch ev.Type {
+		case "PUT":
+			id := lease.LeaseID(ev.Lease)
+			if id != lease.NoLease && lessor.Lookup(id) == nil {
+				// leases granted after the base snapshot are not part of
+				// the event history
+				id = lease.NoLease
+				noLeaseKs++
+			}
+			txn.Put(ev.Key, ev.Value, id)
+			batchBytes += int64(len(ev.Key) + len(ev.Value))
+		case "DELETE":
+			txn.DeleteRange(ev.Key, nil)
+			batchBytes += int64(len(ev.Key))
+		default:
+			return fmt.Errorf("unknown event type %q at revision %d", ev.Type, ev.Revision)
+		}
+	}
+	if err = end(); err != nil {
+		return err
+	}
+	if rev := mvs.Rev(); rev != hdr.Revision {
+		return fmt.Errorf("backup ends at revision %d, but only revision %d was restored", hdr.Revision, rev)
+	}
+	if noLeaseKs > 0 {
+		s.lg.Warn("restored keys without their lease, since the lease was granted after the base snapshot",
+			zap.String("path", path),
+			zap.Int("keys", noLeaseKs),
+		)
+	}
+	s.lg.Info("applied incremental backup",
+		zap.String("path", path),
+		zap.Int64("base-revision", hdr.BaseRevision),
+		zap.Int64("revision", hdr.Revision),
+	)
+	return nil
+}
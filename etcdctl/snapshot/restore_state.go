@@ -0,0 +1,111 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/etcd/pkg/v3/fileutil"
+	pioutil "go.etcd.io/etcd/pkg/v3/ioutil"
+	"go.uber.org/zap"
+)
+
+const (
+	// restoreStateName is the file, in the snap directory of the restored
+	// data directory, recording the progress of a restore until it is
+	// completed.
+	restoreStateName = "db.restore"
+
+	// defaultRestoreBatchBytes is the default of RestoreConfig.BatchBytes.
+	defaultRestoreBatchBytes = 64 * 1024 * 1024
+)
+
+// restoreState is the progress of a restore, saved so that an interrupted
+// restore can be resumed.
+type restoreState struct {
+	SnapshotSize int64 `json:"snapshotSize"`
+	// Copied is the number of bytes of the snapshot copied, and synced, to
+	// the restored database.
+	Copied int64 `json:"copied"`
+	// Done are the restore stages completed after the copy.
+	Done []string `json:"done"`
+
+	path string
+	// resumed is true if the state is the one of an interrupted restore.
+	resumed bool
+}
+
+// newRestoreState returns the state of a restore of a snapshot of the
+// given size in the snap directory, resuming the saved one if resume is
+// true.
+func newRestoreState(snapDir string, size int64, resume bool) (*restoreState, error) {
+	st := &restoreState{SnapshotSize: size, path: filepath.Join(snapDir, restoreStateName), resumed: resume}
+	if !resume {
+		return st, nil
+	}
+	b, err := ioutil.ReadFile(st.path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, st); err != nil {
+		return nil, err
+	}
+	if st.SnapshotSize != size {
+		return nil, fmt.Errorf("snapshot of %d bytes cannot resume the restore of a snapshot of %d bytes", size, st.SnapshotSize)
+	}
+	return st, nil
+}
+
+func (st *restoreState) save() error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return pioutil.WriteAndSyncFile(st.path, b, fileutil.PrivateFileMode)
+}
+
+func (st *restoreState) done(stage string) bool {
+	for _, s := range st.Done {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// runStage runs the restore stage, unless it was completed by the restore
+// being resumed, and records its completion.
+func (s *v3Manager) runStage(stage string, f func() error) error {
+	if s.state.done(stage) {
+		s.lg.Info("skipping restore stage completed before resuming", zap.String("stage", stage))
+		return nil
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	s.state.Done = append(s.state.Done, stage)
+	return s.state.save()
+}
+
+// restoreInterrupted returns true if the data directory has a restore that
+// was interrupted.
+func restoreInterrupted(dataDir string) bool {
+	_, err := os.Stat(filepath.Join(dataDir, "member", "snap", restoreStateName))
+	return err == nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/fileutil"
+	"go.uber.org/zap"
+)
+
+// TrimConfig configures the trimming of a snapshot file.
+type TrimConfig struct {
+	// SnapshotPath is the path of the snapshot file to trim.
+	SnapshotPath string
+	// OutputPath is the path of the trimmed snapshot. It must not exist.
+	OutputPath string
+
+	// ExcludePrefixes are the prefixes of the keys to remove, with all
+	// their revisions.
+	ExcludePrefixes []string
+	// Compact is "true" to keep only the latest revision of the remaining
+	// keys, as if the snapshot was compacted at its revision.
+	Compact bool
+}
+
+// TrimStatus describes a trimmed snapshot.
+type TrimStatus struct {
+	// Revision is the revision of the source snapshot.
+	Revision int64 `json:"revision"`
+	// Revisions and RemovedRevisions are the number of revisions kept in
+	// and removed from the key bucket.
+	Revisions        int64 `json:"revisions"`
+	RemovedRevisions int64 `json:"removedRevisions"`
+	TotalSize        int64 `json:"totalSize"`
+}
+
+// Trim writes a copy of the snapshot file without the keys of the excluded
+// prefixes, and only with the latest revision of the other keys if
+// compacting. The copy only holds the pages in use, and is followed by its
+// sha256 digest so it is restored like a snapshot saved from a member.
+//
+// Without compaction, the revision of the trimmed snapshot is the latest
+// revision it keeps, which is lower than the source revision if the latest
+// revisions were removed.
+func (s *v3Manager) Trim(cfg TrimConfig) (st TrimStatus, err error) {
+	if fileutil.Exist(cfg.OutputPath) {
+		return st, fmt.Errorf("%q exists", cfg.OutputPath)
+	}
+	src, err := bolt.Open(cfg.SnapshotPath, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return st, err
+	}
+	defer src.Close()
+
+	partpath := cfg.OutputPath + ".part"
+	dst, err := bolt.Open(partpath, 0600, nil)
+	if err != nil {
+		return st, err
+	}
+	err = src.View(func(stx *bolt.Tx) error {
+		return dst.Update(func(dtx *bolt.Tx) error {
+			return s.trimTx(stx, dtx, cfg, &st)
+		})
+	})
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		st.TotalSize, err = appendChecksum(partpath)
+	}
+	if err == nil {
+		err = os.Rename(partpath, cfg.OutputPath)
+	}
+	if err != nil {
+		os.Remove(partpath)
+		return st, err
+	}
+
+	s.lg.Info(
+		"trimmed snapshot",
+		zap.String("path", cfg.SnapshotPath),
+		zap.String("output", cfg.OutputPath),
+		zap.Int64("revisions", st.Revisions),
+		zap.Int64("removed-revisions", st.RemovedRevisions),
+	)
+	return st, nil
+}
+
+func (s *v3Manager) trimTx(stx, dtx *bolt.Tx, cfg TrimConfig, st *TrimStatus) error {
+	f := keyFilter{exclude: cfg.ExcludePrefixes}
+	return stx.ForEach(func(name []byte, sb *bolt.Bucket) error {
+		db, err := dtx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		// records are copied in order, so the pages can be filled up
+		db.FillPercent = 0.9
+		if string(name) != "key" {
+			if err = sb.ForEach(func(k, v []byte) error { return db.Put(k, v) }); err != nil {
+				return err
+			}
+			// the meta bucket follows the key bucket, so the revision
+			// of the snapshot is known
+			if string(name) == "meta" && cfg.Compact && st.Revision > 0 {
+				rev := make([]byte, 17)
+				binary.BigEndian.PutUint64(rev, uint64(st.Revision))
+				rev[8] = '_'
+				if err = db.Put([]byte("scheduledCompactRev"), rev); err != nil {
+					return err
+				}
+				return db.Put([]byte("finishedCompactRev"), rev)
+			}
+			return nil
+		}
+
+		// the latest revision of every key, when compacting
+		var latest map[string][]byte
+		if cfg.Compact {
+			latest = make(map[string][]byte)
+		}
+		if err = sb.ForEach(func(k, v []byte) error {
+			st.Revision = bytesToRev(k).main
+			var kv mvccpb.KeyValue
+			if err := kv.Unmarshal(v); err != nil {
+				return fmt.Errorf("cannot decode revision %x (%v)", k, err)
+			}
+			switch {
+			case !f.keep(kv.Key):
+				st.RemovedRevisions++
+			case cfg.Compact:
+				if _, ok := latest[string(kv.Key)]; ok {
+					st.RemovedRevisions++
+				}
+				latest[string(kv.Key)] = append([]byte(nil), k...)
+			default:
+				st.Revisions++
+				return db.Put(k, v)
+			}
+			return nil
+		}); err != nil || !cfg.Compact {
+			return err
+		}
+
+		revs := make([][]byte, 0, len(latest))
+		for _, k := range latest {
+			revs = append(revs, k)
+		}
+		sort.Slice(revs, func(i, j int) bool { return bytes.Compare(revs[i], revs[j]) < 0 })
+		for _, k := range revs {
+			if isTombstone(k) {
+				st.RemovedRevisions++
+				continue
+			}
+			st.Revisions++
+			if err = db.Put(k, sb.Get(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// appendChecksum appends the sha256 digest of the file to it, and returns
+// the size of the file without the digest.
+func appendChecksum(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = f.Write(h.Sum(nil)); err != nil {
+		return 0, err
+	}
+	return n, fileutil.Fsync(f)
+}
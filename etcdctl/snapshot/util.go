@@ -29,3 +29,9 @@ func bytesToRev(bytes []byte) revision {
 		sub:  int64(binary.BigEndian.Uint64(bytes[9:])),
 	}
 }
+
+// isTombstone checks whether the revision bytes of the key bucket mark a
+// deletion.
+func isTombstone(b []byte) bool {
+	return len(b) == 18 && b[17] == 't'
+}
@@ -63,6 +63,30 @@ type Manager interface {
 	// Status returns the snapshot file information.
 	Status(dbPath string) (Status, error)
 
+	// Verify checks that the snapshot file matches the sha256 digest
+	// appended to it by the server and that its database is consistent.
+	Verify(dbPath string) error
+
+	// Analyze returns the breakdown of the keyspace and the storage of the
+	// snapshot file.
+	Analyze(dbPath string, cfg AnalyzeConfig) (Analysis, error)
+
+	// Trim writes a copy of the snapshot file without the keys of the
+	// excluded prefixes, and optionally without their past revisions.
+	Trim(cfg TrimConfig) (TrimStatus, error)
+
+	// Diff compares the keys at the revisions of two snapshot files.
+	Diff(dbPathA, dbPathB, prefix string) (Diff, error)
+
+	// HashKV computes the hash of the keyspace of the database file up to
+	// the given revision, as the HashKV RPC of a member would.
+	HashKV(dbPath string, rev int64) (KVHash, error)
+
+	// SaveIncremental fetches the changes made after sinceRev from the
+	// cluster and saves them to target path, to be applied on top of a
+	// snapshot at revision sinceRev by Restore.
+	SaveIncremental(ctx context.Context, cfg clientv3.Config, sinceRev int64, path string) (IncrementalStatus, error)
+
 	// Restore restores a new etcd data directory from given snapshot
 	// file. It returns an error if specified data directory already
 	// exists, to prevent unintended data directory overwrites.
@@ -87,6 +111,9 @@ type v3Manager struct {
 	cl      *membership.RaftCluster
 
 	skipHashCheck bool
+	batchBytes    int64
+	batchKeys     int
+	state         *restoreState
 }
 
 // hasChecksum returns "true" if the file size "n"
@@ -108,6 +135,9 @@ type Status struct {
 	Revision  int64  `json:"revision"`
 	TotalKey  int    `json:"totalKey"`
 	TotalSize int64  `json:"totalSize"`
+	// ClusterVersion is the cluster version recorded in the snapshot,
+	// empty if the cluster version was not decided yet.
+	ClusterVersion string `json:"clusterVersion,omitempty"`
 }
 
 // Status returns the snapshot file information.
@@ -134,6 +164,9 @@ func (s *v3Manager) Status(dbPath string) (ds Status, err error) {
 			return fmt.Errorf("snapshot file integrity check failed. %d errors found.\n"+strings.Join(dbErrStrings, "\n"), len(dbErrStrings))
 		}
 		ds.TotalSize = tx.Size()
+		if b := tx.Bucket([]byte("cluster")); b != nil {
+			ds.ClusterVersion = string(b.Get([]byte("clusterVersion")))
+		}
 		c := tx.Cursor()
 		for next, _ := c.First(); next != nil; next, _ = c.Next() {
 			b := tx.Bucket(next)
@@ -170,6 +203,39 @@ func (s *v3Manager) Status(dbPath string) (ds Status, err error) {
 	return ds, nil
 }
 
+// Verify checks that the snapshot file matches the sha256 digest appended
+// to it by the server and that its database is consistent.
+func (s *v3Manager) Verify(dbPath string) error {
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+	if !hasChecksum(size) {
+		return fmt.Errorf("sha256 checksum not found [bytes: %d]", size)
+	}
+	h := sha256.New()
+	if _, err = io.CopyN(h, f, size-sha256.Size); err != nil {
+		return err
+	}
+	sha := make([]byte, sha256.Size)
+	if _, err = io.ReadFull(f, sha); err != nil {
+		return err
+	}
+	if dbsha := h.Sum(nil); !reflect.DeepEqual(sha, dbsha) {
+		return fmt.Errorf("expected sha256 %v, got %v", sha, dbsha)
+	}
+
+	_, err = s.Status(dbPath)
+	return err
+}
+
 // RestoreConfig configures snapshot restore operation.
 type RestoreConfig struct {
 	// SnapshotPath is the path of snapshot file to restore from.
@@ -199,6 +265,35 @@ type RestoreConfig struct {
 	// SkipHashCheck is "true" to ignore snapshot integrity hash value
 	// (required if copied from data directory).
 	SkipHashCheck bool
+
+	// IncrementalPaths are the incremental backups to apply, in order, on
+	// top of the snapshot. Each must start at the revision the previous
+	// one, or the snapshot, ends at.
+	IncrementalPaths []string
+
+	// IncludePrefixes are the prefixes of the keys to restore. If empty,
+	// every key not excluded is restored.
+	IncludePrefixes []string
+	// ExcludePrefixes are the prefixes of the keys not to restore.
+	ExcludePrefixes []string
+	// RewritePrefixes are applied, first match only, to the prefixes of
+	// the restored keys.
+	RewritePrefixes []PrefixRewrite
+
+	// Resume continues the interrupted restore to OutputDataDir, if any,
+	// instead of failing on the non-empty data directory. It must be given
+	// the same snapshot and options as the interrupted restore.
+	Resume bool
+	// BatchBytes is the number of bytes written to the restored database
+	// between commits: the copy of the snapshot is synced, and can be
+	// resumed from, every BatchBytes, and the backend transactions
+	// applying incremental backups are committed when they reach it.
+	// Defaults to 64 MiB.
+	BatchBytes int64
+	// BatchKeys is the number of keys the backend transactions applying
+	// incremental backups write before being committed. Defaults to the
+	// batch limit of the backend.
+	BatchKeys int
 }
 
 // Restore restores a new etcd data directory from given snapshot file.
@@ -233,14 +328,18 @@ func (s *v3Manager) Restore(cfg RestoreConfig) error {
 	if dataDir == "" {
 		dataDir = cfg.Name + ".etcd"
 	}
-	if fileutil.Exist(dataDir) && !fileutil.DirEmpty(dataDir) {
+	resume := cfg.Resume && restoreInterrupted(dataDir)
+	if !resume && fileutil.Exist(dataDir) && !fileutil.DirEmpty(dataDir) {
+		if restoreInterrupted(dataDir) {
+			return fmt.Errorf("data-dir %q has an interrupted restore, resume it or remove the data-dir", dataDir)
+		}
 		return fmt.Errorf("data-dir %q not empty or could not be read", dataDir)
 	}
 
 	walDir := cfg.OutputWALDir
 	if walDir == "" {
 		walDir = filepath.Join(dataDir, "member", "wal")
-	} else if fileutil.Exist(walDir) {
+	} else if !resume && fileutil.Exist(walDir) {
 		return fmt.Errorf("wal-dir %q exists", walDir)
 	}
 
@@ -249,6 +348,26 @@ func (s *v3Manager) Restore(cfg RestoreConfig) error {
 	s.walDir = walDir
 	s.snapDir = filepath.Join(dataDir, "member", "snap")
 	s.skipHashCheck = cfg.SkipHashCheck
+	s.batchBytes, s.batchKeys = cfg.BatchBytes, cfg.BatchKeys
+	if s.batchBytes <= 0 {
+		s.batchBytes = defaultRestoreBatchBytes
+	}
+
+	fi, err := os.Stat(s.dbPath)
+	if err != nil {
+		return err
+	}
+	if !resume {
+		if err = fileutil.CreateDirAll(s.snapDir); err != nil {
+			return err
+		}
+	}
+	if s.state, err = newRestoreState(s.snapDir, fi.Size(), resume); err != nil {
+		return fmt.Errorf("cannot resume the restore to %q (%v)", dataDir, err)
+	}
+	if err = s.state.save(); err != nil {
+		return err
+	}
 
 	s.lg.Info(
 		"restoring snapshot",
@@ -256,11 +375,38 @@ func (s *v3Manager) Restore(cfg RestoreConfig) error {
 		zap.String("wal-dir", s.walDir),
 		zap.String("data-dir", dataDir),
 		zap.String("snap-dir", s.snapDir),
+		zap.Bool("resume", resume),
 	)
 	if err = s.saveDB(); err != nil {
 		return err
 	}
-	if err = s.saveWALAndSnap(); err != nil {
+	if err = s.runStage("incremental", func() error {
+		if len(cfg.IncrementalPaths) == 0 {
+			return nil
+		}
+		return s.applyIncrementals(cfg.IncrementalPaths)
+	}); err != nil {
+		return err
+	}
+	f := keyFilter{include: cfg.IncludePrefixes, exclude: cfg.ExcludePrefixes, rewrites: cfg.RewritePrefixes}
+	if err = s.runStage("filter", func() error {
+		if f.empty() {
+			return nil
+		}
+		return s.filterKeys(f)
+	}); err != nil {
+		return err
+	}
+	if err = s.runStage("wal", func() error {
+		// a WAL left by the interrupted restore is incomplete
+		if err := os.RemoveAll(s.walDir); err != nil {
+			return err
+		}
+		return s.saveWALAndSnap()
+	}); err != nil {
+		return err
+	}
+	if err = os.Remove(s.state.path); err != nil {
 		return err
 	}
 	s.lg.Info(
@@ -290,65 +436,107 @@ func (s *v3Manager) saveDB() error {
 	if _, err := f.Read(sha); err != nil {
 		return err
 	}
-	if _, err := f.Seek(0, io.SeekStart); err != nil {
-		return err
-	}
 
-	if err := fileutil.CreateDirAll(s.snapDir); err != nil {
-		return err
+	size := s.state.SnapshotSize
+	hasHash := hasChecksum(size)
+	if !hasHash && !s.skipHashCheck {
+		return fmt.Errorf("snapshot missing hash but --skip-hash-check=false")
+	}
+	if hasHash {
+		size -= sha256.Size
 	}
 
 	dbpath := filepath.Join(s.snapDir, "db")
-	db, dberr := os.OpenFile(dbpath, os.O_RDWR|os.O_CREATE, 0600)
-	if dberr != nil {
-		return dberr
-	}
-	dbClosed := false
-	defer func() {
-		if !dbClosed {
-			db.Close()
-			dbClosed = true
+	if err := s.runStage("copy", func() error {
+		if err := s.copySnapshot(f, dbpath); err != nil {
+			return err
 		}
-	}()
-	if _, err := io.Copy(db, f); err != nil {
+		// truncate away integrity hash, if any.
+		return os.Truncate(dbpath, size)
+	}); err != nil {
 		return err
 	}
 
-	// truncate away integrity hash, if any.
-	off, serr := db.Seek(0, io.SeekEnd)
-	if serr != nil {
-		return serr
-	}
-	hasHash := hasChecksum(off)
-	if hasHash {
-		if err := db.Truncate(off - sha256.Size); err != nil {
+	if hasHash && !s.skipHashCheck {
+		// check for match
+		if err := s.runStage("verify", func() error { return s.verifyDB(dbpath, size, sha) }); err != nil {
 			return err
 		}
 	}
 
-	if !hasHash && !s.skipHashCheck {
-		return fmt.Errorf("snapshot missing hash but --skip-hash-check=false")
+	// db hash is OK, can now modify DB so it can be part of a new cluster
+	return s.runStage("prepare", func() error { return s.prepareDB(dbpath) })
+}
+
+// copySnapshot copies the snapshot to the database path, from where the
+// restore being resumed stopped. The copy is synced, and its progress
+// saved, every batch of bytes.
+func (s *v3Manager) copySnapshot(f *os.File, dbpath string) error {
+	db, err := os.OpenFile(dbpath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	if hasHash && !s.skipHashCheck {
-		// check for match
-		if _, err := db.Seek(0, io.SeekStart); err != nil {
+	// drop what was copied after the last saved progress
+	copied := s.state.Copied
+	if err = db.Truncate(copied); err != nil {
+		return err
+	}
+	if _, err = f.Seek(copied, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = db.Seek(copied, io.SeekStart); err != nil {
+		return err
+	}
+	for copied < s.state.SnapshotSize {
+		n, err := io.CopyN(db, f, s.batchBytes)
+		copied += n
+		if err != nil && err != io.EOF {
 			return err
 		}
-		h := sha256.New()
-		if _, err := io.Copy(h, db); err != nil {
+		if err = fileutil.Fsync(db); err != nil {
 			return err
 		}
-		dbsha := h.Sum(nil)
-		if !reflect.DeepEqual(sha, dbsha) {
-			return fmt.Errorf("expected sha256 %v, got %v", sha, dbsha)
+		s.state.Copied = copied
+		if err = s.state.save(); err != nil {
+			return err
 		}
+		s.lg.Info("copied snapshot", zap.Int64("bytes", copied), zap.Int64("total-bytes", s.state.SnapshotSize))
 	}
+	return nil
+}
 
-	// db hash is OK, can now modify DB so it can be part of a new cluster
-	db.Close()
-	dbClosed = true
+// verifyDB checks the sha256 of the first size bytes of the database.
+func (s *v3Manager) verifyDB(dbpath string, size int64, sha []byte) error {
+	db, err := os.Open(dbpath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	h := sha256.New()
+	for hashed := int64(0); hashed < size; {
+		n, err := io.CopyN(h, db, s.batchBytes)
+		hashed += n
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		s.lg.Info("verified snapshot", zap.Int64("bytes", hashed), zap.Int64("total-bytes", size))
+	}
+	dbsha := h.Sum(nil)
+	if !reflect.DeepEqual(sha, dbsha) {
+		return fmt.Errorf("expected sha256 %v, got %v", sha, dbsha)
+	}
+	return nil
+}
 
+// prepareDB updates the restored database so it can be part of a new
+// cluster.
+func (s *v3Manager) prepareDB(dbpath string) error {
 	commit := len(s.cl.Members())
 
 	// update consistentIndex so applies go through on etcdserver despite
@@ -240,6 +240,7 @@ func newConfig() *config {
 	// auth
 	fs.StringVar(&cfg.ec.AuthToken, "auth-token", cfg.ec.AuthToken, "Specify auth token specific options.")
 	fs.UintVar(&cfg.ec.BcryptCost, "bcrypt-cost", cfg.ec.BcryptCost, "Specify bcrypt algorithm cost factor for auth password hashing.")
+	fs.BoolVar(&cfg.ec.AuthAllowHashedPassword, "auth-allow-hashed-password", cfg.ec.AuthAllowHashedPassword, "Accept passwords hashed by the client with a bcrypt cost not lower than --bcrypt-cost.")
 	fs.UintVar(&cfg.ec.AuthTokenTTL, "auth-token-ttl", cfg.ec.AuthTokenTTL, "The lifetime in seconds of the auth token.")
 
 	// gateway
@@ -252,6 +253,12 @@ func newConfig() *config {
 	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpoint, "experimental-enable-lease-checkpoint", false, "Enable to persist lease remaining TTL to prevent indefinite auto-renewal of long lived leases.")
 	fs.IntVar(&cfg.ec.ExperimentalCompactionBatchLimit, "experimental-compaction-batch-limit", cfg.ec.ExperimentalCompactionBatchLimit, "Sets the maximum revisions deleted in each compaction batch.")
 	fs.DurationVar(&cfg.ec.ExperimentalWatchProgressNotifyInterval, "experimental-watch-progress-notify-interval", cfg.ec.ExperimentalWatchProgressNotifyInterval, "Duration of periodic watch progress notifications.")
+	fs.BoolVar(&cfg.ec.ExperimentalEnableMaintenanceScheduler, "experimental-enable-maintenance-scheduler", false, "Enable the compactions and defragmentations scheduled by the maintenance policy.")
+	fs.StringVar(&cfg.ec.ExperimentalPrefixQuotas, "experimental-prefix-quotas", cfg.ec.ExperimentalPrefixQuotas, "Comma separated list of quotas on the size and number of the keys under a prefix, each 'prefix=bytes[:keys]'.")
+	fs.Var(flags.NewStringsValue(""), "experimental-audit-log-outputs", "Comma separated list of the sinks of the audit log of mutating and auth requests, file paths or 'stdout' and 'stderr' (empty disables the audit log).")
+	fs.Float64Var(&cfg.ec.ExperimentalAuditLogRateLimit, "experimental-audit-log-rate-limit", 0, "Maximum number of audit records written per second, 0 is no limit.")
+	fs.Var(flags.NewStringsValue(""), "experimental-audit-log-redact-fields", "Comma separated list of the audit record fields whose values are replaced by a hash, out of 'user', 'cert-cn', 'remote', 'keys' and 'target'.")
+	fs.BoolVar(&cfg.ec.ExperimentalEnableRateLimit, "experimental-enable-rate-limit", false, "Enable the rate limits of the client requests set by the rate limit policy.")
 
 	// unsafe
 	fs.BoolVar(&cfg.ec.UnsafeNoFsync, "unsafe-no-fsync", false, "Disables fsync, unsafe, will cause data loss.")
@@ -347,6 +354,9 @@ func (cfg *config) configFromCmdLine() error {
 
 	cfg.ec.LogOutputs = flags.UniqueStringsFromFlag(cfg.cf.flagSet, "log-outputs")
 
+	cfg.ec.ExperimentalAuditLogOutputs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-audit-log-outputs")
+	cfg.ec.ExperimentalAuditLogRedactFields = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-audit-log-redact-fields")
+
 	cfg.ec.ClusterState = cfg.cf.clusterState.String()
 	cfg.cp.Fallback = cfg.cf.fallback.String()
 	cfg.cp.Proxy = cfg.cf.proxy.String()
@@ -164,6 +164,8 @@ Auth:
     Specify a v3 authentication token type and its options ('simple' or 'jwt').
   --bcrypt-cost ` + fmt.Sprintf("%d", bcrypt.DefaultCost) + `
     Specify the cost / strength of the bcrypt algorithm for hashing auth passwords. Valid values are between ` + fmt.Sprintf("%d", bcrypt.MinCost) + ` and ` + fmt.Sprintf("%d", bcrypt.MaxCost) + `.
+  --auth-allow-hashed-password 'false'
+    Accept passwords hashed by the client, such as with 'etcdctl user add --bcrypt-cost', if their bcrypt cost is not lower than --bcrypt-cost.
   --auth-token-ttl 300
     Time (in seconds) of the auth-token-ttl.
 
@@ -212,6 +214,18 @@ Experimental feature:
     Skip verification of SAN field in client certificate for peer connections.
   --experimental-watch-progress-notify-interval '10m'
     Duration of periodical watch progress notification.
+  --experimental-enable-maintenance-scheduler 'false'
+    Enable the compactions and defragmentations scheduled by the maintenance policy (see "etcdctl maintenance schedule").
+  --experimental-prefix-quotas ''
+    Comma separated list of quotas on the size and number of the keys under a prefix, each 'prefix=bytes[:keys]' such as '/tenant-a/=1GB:10000', where 0 is no limit. Writes over a quota are rejected and raise a PREFIX_QUOTA alarm, disarmed once the keys under the prefixes are shrunk back within their quotas, or with 'etcdctl alarm disarm'.
+  --experimental-audit-log-outputs ''
+    Comma separated list of the sinks of the audit log, file paths or 'stdout' and 'stderr'. The audit log records the user, client certificate common name, source address, operation, keys written and result of the mutating and auth requests. Empty disables the audit log.
+  --experimental-audit-log-rate-limit '0'
+    Maximum number of audit records written per second, 0 is no limit. The records over the limit are dropped and counted in the next record written.
+  --experimental-audit-log-redact-fields ''
+    Comma separated list of the audit record fields whose values are replaced by a hash, out of 'user', 'cert-cn', 'remote', 'keys' and 'target'.
+  --experimental-enable-rate-limit 'false'
+    Enable the token bucket rate limits of the reads, writes and watch creations of the clients, per user, client certificate and address range, following the rate limit policy (see "etcdctl ratelimit set").
 
 Unsafe feature:
   --force-new-cluster 'false'
@@ -115,20 +115,23 @@ type Health struct {
 func checkHealth(lg *zap.Logger, srv etcdserver.ServerV2) Health {
 	h := Health{}
 	h.Health = "true"
-	as := srv.Alarms()
-	if len(as) > 0 {
-		h.Health = "false"
-		for _, v := range as {
-			switch v.Alarm {
-			case etcdserverpb.AlarmType_NOSPACE:
-				h.Reason = "ALARM NOSPACE"
-			case etcdserverpb.AlarmType_CORRUPT:
-				h.Reason = "ALARM CORRUPT"
-			default:
-				h.Reason = "ALARM UNKNOWN"
-			}
-			lg.Warn("serving /health false due to an alarm", zap.String("alarm", v.String()))
+	for _, v := range srv.Alarms() {
+		switch v.Alarm {
+		case etcdserverpb.AlarmType_NOSPACE:
+			h.Reason = "ALARM NOSPACE"
+		case etcdserverpb.AlarmType_CORRUPT:
+			h.Reason = "ALARM CORRUPT"
+		case etcdserverpb.AlarmType_PREFIX_QUOTA:
+			// only the writes to the prefixes over their quota fail, and
+			// the alarm is disarmed once they are back under their quota
+			continue
+		default:
+			h.Reason = "ALARM UNKNOWN"
 		}
+		h.Health = "false"
+		lg.Warn("serving /health false due to an alarm", zap.String("alarm", v.String()))
+	}
+	if h.Health == "false" {
 		return h
 	}
 
@@ -16,7 +16,10 @@
 package v3alarm
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"sync"
+	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/pkg/v3/types"
@@ -26,9 +29,23 @@ import (
 )
 
 var (
-	alarmBucketName = []byte("alarm")
+	alarmBucketName        = []byte("alarm")
+	alarmHistoryBucketName = []byte("alarmHistory")
 )
 
+// MaxAlarmHistory is the number of alarm events kept in the history.
+const MaxAlarmHistory = 1000
+
+// AlarmEvent records an alarm being raised or cleared. The time is when the
+// local member applied the change, in UTC, so it differs slightly across
+// members.
+type AlarmEvent struct {
+	MemberID uint64    `json:"member_id"`
+	Alarm    string    `json:"alarm"`
+	Raised   bool      `json:"raised"`
+	Time     time.Time `json:"time"`
+}
+
 type BackendGetter interface {
 	Backend() backend.Backend
 }
@@ -41,6 +58,11 @@ type AlarmStore struct {
 	mu    sync.Mutex
 	types map[pb.AlarmType]alarmSet
 
+	// history holds the most recent alarm events, oldest first; seq is the
+	// backend key of the latest one.
+	history []AlarmEvent
+	seq     uint64
+
 	bg BackendGetter
 }
 
@@ -70,6 +92,7 @@ func (a *AlarmStore) Activate(id types.ID, at pb.AlarmType) *pb.AlarmMember {
 	b := a.bg.Backend()
 	b.BatchTx().Lock()
 	b.BatchTx().UnsafePut(alarmBucketName, v, nil)
+	a.unsafeRecord(AlarmEvent{MemberID: uint64(id), Alarm: at.String(), Raised: true, Time: time.Now().UTC()})
 	b.BatchTx().Unlock()
 
 	return newAlarm
@@ -99,11 +122,44 @@ func (a *AlarmStore) Deactivate(id types.ID, at pb.AlarmType) *pb.AlarmMember {
 	b := a.bg.Backend()
 	b.BatchTx().Lock()
 	b.BatchTx().UnsafeDelete(alarmBucketName, v)
+	a.unsafeRecord(AlarmEvent{MemberID: uint64(id), Alarm: at.String(), Raised: false, Time: time.Now().UTC()})
 	b.BatchTx().Unlock()
 
 	return m
 }
 
+// History returns the most recent alarm events, oldest first.
+func (a *AlarmStore) History() []AlarmEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AlarmEvent(nil), a.history...)
+}
+
+// unsafeRecord appends the event to the history, dropping the oldest events
+// beyond MaxAlarmHistory. It must be called holding the backend batch tx lock.
+func (a *AlarmStore) unsafeRecord(ev AlarmEvent) {
+	v, err := json.Marshal(ev)
+	if err != nil {
+		a.lg.Panic("failed to marshal alarm event", zap.Error(err))
+	}
+	tx := a.bg.Backend().BatchTx()
+	a.seq++
+	tx.UnsafeSeqPut(alarmHistoryBucketName, alarmHistoryKey(a.seq), v)
+	a.history = append(a.history, ev)
+	if n := len(a.history) - MaxAlarmHistory; n > 0 {
+		for seq := a.seq - uint64(len(a.history)) + 1; n > 0; seq, n = seq+1, n-1 {
+			tx.UnsafeDelete(alarmHistoryBucketName, alarmHistoryKey(seq))
+			a.history = a.history[1:]
+		}
+	}
+}
+
+func alarmHistoryKey(seq uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, seq)
+	return k
+}
+
 func (a *AlarmStore) Get(at pb.AlarmType) (ret []*pb.AlarmMember) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -135,6 +191,18 @@ func (a *AlarmStore) restore() error {
 		a.addToMap(&m)
 		return nil
 	})
+	if err == nil {
+		tx.UnsafeCreateBucket(alarmHistoryBucketName)
+		err = tx.UnsafeForEach(alarmHistoryBucketName, func(k, v []byte) error {
+			var ev AlarmEvent
+			if err := json.Unmarshal(v, &ev); err != nil {
+				return err
+			}
+			a.history = append(a.history, ev)
+			a.seq = binary.BigEndian.Uint64(k)
+			return nil
+		})
+	}
 	tx.Unlock()
 
 	b.ForceCommit()
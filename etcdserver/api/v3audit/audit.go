@@ -0,0 +1,180 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"go.etcd.io/etcd/pkg/v3/logutil"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// The fields of the audit records that can be redacted.
+const (
+	FieldUser       = "user"
+	FieldCommonName = "cert-cn"
+	FieldRemote     = "remote"
+	FieldKeys       = "keys"
+	FieldTarget     = "target"
+)
+
+var redactableFields = map[string]bool{
+	FieldUser:       true,
+	FieldCommonName: true,
+	FieldRemote:     true,
+	FieldKeys:       true,
+	FieldTarget:     true,
+}
+
+// Config configures the audit log.
+type Config struct {
+	// OutputPaths are the sinks of the audit records, file paths or
+	// "stdout" and "stderr".
+	OutputPaths []string
+	// RateLimit is the maximum number of records written per second, the
+	// records over it are dropped. Zero is no limit.
+	RateLimit float64
+	// RedactFields are the fields whose values are replaced by a hash, so
+	// records of the same value can be matched without revealing it.
+	RedactFields []string
+}
+
+// Validate returns an error if the configuration is invalid.
+func (cfg Config) Validate() error {
+	if len(cfg.OutputPaths) == 0 {
+		return errors.New("no audit log output")
+	}
+	if cfg.RateLimit < 0 {
+		return fmt.Errorf("negative audit log rate limit %v", cfg.RateLimit)
+	}
+	for _, f := range cfg.RedactFields {
+		if !redactableFields[f] {
+			return fmt.Errorf("unknown audit log field %q to redact", f)
+		}
+	}
+	return nil
+}
+
+// Record is the audit record of a request.
+type Record struct {
+	// User is the authenticated user, if any.
+	User string
+	// CommonName is the common name of the client certificate, if any.
+	CommonName string
+	// Remote is the address the request came from.
+	Remote string
+	// Operation is the full gRPC method of the request.
+	Operation string
+	// Keys are the keys and key ranges the request writes.
+	Keys []string
+	// Target is what else the request acts on, such as a user, role,
+	// lease or member.
+	Target string
+	// Result is the gRPC status code of the response.
+	Result string
+	// Error is the error message of a failed request.
+	Error string
+}
+
+// Logger writes audit records.
+type Logger struct {
+	lg      *zap.Logger
+	limiter *rate.Limiter
+	redact  map[string]bool
+
+	// dropped is the number of records dropped by the rate limit since the
+	// last record written.
+	dropped uint64
+}
+
+// NewLogger returns a Logger writing to the sinks of the configuration.
+func NewLogger(cfg Config) (*Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	zcfg := logutil.DefaultZapLoggerConfig
+	// every record is kept, the rate limit reports what it drops
+	zcfg.Sampling = nil
+	zcfg.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	zcfg.OutputPaths = cfg.OutputPaths
+	lg, err := zcfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(lg, cfg), nil
+}
+
+func newLogger(lg *zap.Logger, cfg Config) *Logger {
+	l := &Logger{lg: lg, redact: make(map[string]bool)}
+	if cfg.RateLimit > 0 {
+		l.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), int(math.Ceil(cfg.RateLimit)))
+	}
+	for _, f := range cfg.RedactFields {
+		l.redact[f] = true
+	}
+	return l
+}
+
+// Log writes the record, unless it is over the rate limit.
+func (l *Logger) Log(r Record) {
+	if l.limiter != nil && !l.limiter.Allow() {
+		atomic.AddUint64(&l.dropped, 1)
+		droppedRecords.Inc()
+		return
+	}
+	fields := []zap.Field{
+		zap.String(FieldUser, l.redacted(FieldUser, r.User)),
+		zap.String(FieldCommonName, l.redacted(FieldCommonName, r.CommonName)),
+		zap.String(FieldRemote, l.redacted(FieldRemote, r.Remote)),
+		zap.String("operation", r.Operation),
+	}
+	if len(r.Keys) > 0 {
+		keys := make([]string, len(r.Keys))
+		for i, k := range r.Keys {
+			keys[i] = l.redacted(FieldKeys, k)
+		}
+		fields = append(fields, zap.Strings(FieldKeys, keys))
+	}
+	if r.Target != "" {
+		fields = append(fields, zap.String(FieldTarget, l.redacted(FieldTarget, r.Target)))
+	}
+	fields = append(fields, zap.String("result", r.Result))
+	if r.Error != "" {
+		fields = append(fields, zap.String("error", r.Error))
+	}
+	if n := atomic.SwapUint64(&l.dropped, 0); n > 0 {
+		fields = append(fields, zap.Uint64("dropped", n))
+	}
+	l.lg.Info("audit", fields...)
+	writtenRecords.Inc()
+}
+
+// Sync flushes the buffered records.
+func (l *Logger) Sync() error { return l.lg.Sync() }
+
+func (l *Logger) redacted(field, v string) string {
+	if v == "" || !l.redact[field] {
+		return v
+	}
+	h := sha256.Sum256([]byte(v))
+	return "sha256:" + hex.EncodeToString(h[:8])
+}
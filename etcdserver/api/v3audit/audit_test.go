@@ -0,0 +1,105 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3audit
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/time/rate"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{OutputPaths: []string{"stderr"}}, true},
+		{Config{OutputPaths: []string{"stderr"}, RateLimit: 10, RedactFields: []string{FieldKeys, FieldUser}}, true},
+		{Config{}, false},
+		{Config{OutputPaths: []string{"stderr"}, RateLimit: -1}, false},
+		{Config{OutputPaths: []string{"stderr"}, RedactFields: []string{"operation"}}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.ok {
+			t.Errorf("#%d: expected ok %v, got error %v", i, tt.ok, err)
+		}
+	}
+}
+
+func TestLoggerLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	l := newLogger(zap.New(core), Config{RedactFields: []string{FieldKeys}})
+
+	l.Log(Record{
+		User:       "root",
+		CommonName: "client",
+		Remote:     "127.0.0.1:2379",
+		Operation:  "/etcdserverpb.KV/DeleteRange",
+		Keys:       []string{"foo"},
+		Result:     "OK",
+	})
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for k, v := range map[string]string{
+		FieldUser:       "root",
+		FieldCommonName: "client",
+		FieldRemote:     "127.0.0.1:2379",
+		"operation":     "/etcdserverpb.KV/DeleteRange",
+		"result":        "OK",
+	} {
+		if fields[k] != v {
+			t.Errorf("expected %s %q, got %v", k, v, fields[k])
+		}
+	}
+	keys, ok := fields[FieldKeys].([]interface{})
+	if !ok || len(keys) != 1 {
+		t.Fatalf("expected 1 key, got %v", fields[FieldKeys])
+	}
+	if k := keys[0].(string); k == "foo" || !strings.HasPrefix(k, "sha256:") {
+		t.Errorf("expected redacted key, got %q", k)
+	}
+	if _, ok := fields["error"]; ok {
+		t.Errorf("unexpected error field in record of a successful request")
+	}
+}
+
+func TestLoggerRateLimit(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	l := newLogger(zap.New(core), Config{RateLimit: 1})
+
+	for i := 0; i < 3; i++ {
+		l.Log(Record{Operation: "/etcdserverpb.KV/Put", Result: "OK"})
+	}
+	if n := logs.Len(); n != 1 {
+		t.Fatalf("expected 1 record within the rate limit, got %d", n)
+	}
+
+	l.limiter.SetLimit(rate.Inf)
+	l.Log(Record{Operation: "/etcdserverpb.KV/Put", Result: "OK"})
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(entries))
+	}
+	if dropped := entries[1].ContextMap()["dropped"]; dropped != uint64(2) {
+		t.Errorf("expected 2 dropped records reported, got %v", dropped)
+	}
+}
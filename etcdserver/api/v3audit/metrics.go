@@ -0,0 +1,38 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3audit
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	writtenRecords = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "audit_records_total",
+		Help:      "The total number of audit records written.",
+	})
+
+	droppedRecords = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "audit_records_dropped_total",
+		Help:      "The total number of audit records dropped by the audit log rate limit.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(writtenRecords)
+	prometheus.MustRegister(droppedRecords)
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	for i, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("#%d: expected error for %q", i, spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2020, 1, 1, 10, 30, 15, 0, time.UTC)
+	tt := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2020, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2020, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 1-3,22 * * *", time.Date(2020, 1, 1, 22, 0, 0, 0, time.UTC)},
+		{"0 3 * * 0", time.Date(2020, 1, 5, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2020, 1, 5, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// either day field matches if both are restricted
+		{"0 0 15 * 5", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for i, tc := range tt {
+		c, err := ParseCron(tc.spec)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		if next := c.Next(from); !next.Equal(tc.next) {
+			t.Errorf("#%d: %q: expected %v, got %v", i, tc.spec, tc.next, next)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultDefragStagger is the delay between the scheduled defragmentations
+// of two members if the policy does not set one.
+const DefaultDefragStagger = 10 * time.Minute
+
+// Policy is the maintenance policy shared by the members of the cluster.
+type Policy struct {
+	// CompactRetention is how long the leader keeps revisions before
+	// compacting them, e.g. "1h". Empty disables scheduled compactions.
+	CompactRetention string `json:"compact_retention,omitempty"`
+	// DefragCron is when the members defragment their backend, as a
+	// "minute hour day-of-month month day-of-week" expression in the local
+	// time of each member. Empty disables scheduled defragmentations.
+	DefragCron string `json:"defrag_cron,omitempty"`
+	// DefragStagger delays the defragmentation of the n-th member, in the
+	// order of member IDs, by n times the stagger so that members do not
+	// stall at the same time, e.g. "10m".
+	DefragStagger string `json:"defrag_stagger,omitempty"`
+}
+
+// schedule is a parsed policy.
+type schedule struct {
+	compactRetention time.Duration
+	defragCron       *Cron
+	defragStagger    time.Duration
+}
+
+// ParsePolicy parses a policy stored as JSON.
+func ParsePolicy(b []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("invalid maintenance policy (%v)", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate returns an error if the policy cannot be scheduled.
+func (p *Policy) Validate() error {
+	_, err := p.schedule()
+	return err
+}
+
+func (p *Policy) schedule() (*schedule, error) {
+	s := &schedule{defragStagger: DefaultDefragStagger}
+	if p.CompactRetention == "" && p.DefragCron == "" {
+		return nil, errors.New("maintenance policy schedules neither compactions nor defragmentations")
+	}
+	if p.CompactRetention != "" {
+		d, err := time.ParseDuration(p.CompactRetention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compaction retention %q (%v)", p.CompactRetention, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("compaction retention %q must be positive", p.CompactRetention)
+		}
+		s.compactRetention = d
+	}
+	if p.DefragCron != "" {
+		c, err := ParseCron(p.DefragCron)
+		if err != nil {
+			return nil, err
+		}
+		s.defragCron = c
+	}
+	if p.DefragStagger != "" {
+		d, err := time.ParseDuration(p.DefragStagger)
+		if err != nil {
+			return nil, fmt.Errorf("invalid defragmentation stagger %q (%v)", p.DefragStagger, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("defragmentation stagger %q must not be negative", p.DefragStagger)
+		}
+		s.defragStagger = d
+	}
+	return s, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tt := []struct {
+		policy string
+
+		retention time.Duration
+		stagger   time.Duration
+		cron      bool
+		err       bool
+	}{
+		{policy: `{"compact_retention":"1h"}`, retention: time.Hour, stagger: DefaultDefragStagger},
+		{policy: `{"defrag_cron":"0 3 * * *","defrag_stagger":"5m"}`, stagger: 5 * time.Minute, cron: true},
+		{policy: `{"compact_retention":"1h","defrag_cron":"0 3 * * *","defrag_stagger":"0s"}`, retention: time.Hour, cron: true},
+		{policy: `{}`, err: true},
+		{policy: `{"compact_retention":"-1h"}`, err: true},
+		{policy: `{"compact_retention":"1 hour"}`, err: true},
+		{policy: `{"defrag_cron":"0 3 * *"}`, err: true},
+		{policy: `{"defrag_cron":"0 3 * * *","defrag_stagger":"-1m"}`, err: true},
+		{policy: `not json`, err: true},
+	}
+	for i, tc := range tt {
+		p, err := ParsePolicy([]byte(tc.policy))
+		if (err != nil) != tc.err {
+			t.Fatalf("#%d: expected error %v, got %v", i, tc.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		s, _ := p.schedule()
+		if s.compactRetention != tc.retention || s.defragStagger != tc.stagger || (s.defragCron != nil) != tc.cron {
+			t.Errorf("#%d: unexpected schedule %+v", i, s)
+		}
+	}
+}
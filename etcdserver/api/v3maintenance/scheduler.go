@@ -0,0 +1,291 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3maintenance
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/types"
+	"go.etcd.io/etcd/v3/etcdserver/api"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3compactor"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
+)
+
+const (
+	// checkInterval is how often the policy is read and the schedule is
+	// checked.
+	checkInterval = 10 * time.Second
+	// maxRuns is the number of runs kept in the status.
+	maxRuns = 16
+
+	RunCompaction = "compaction"
+	RunDefrag     = "defrag"
+)
+
+// Server is the part of the etcd server the scheduler maintains.
+type Server interface {
+	v3compactor.Compactable
+
+	ID() types.ID
+	Leader() types.ID
+	Cluster() api.Cluster
+	KV() mvcc.ConsistentWatchableKV
+	Backend() backend.Backend
+	// Policy returns the stored policy of the given type, or nil if there
+	// is none.
+	Policy(t pb.PolicyType) []byte
+}
+
+// Run is a maintenance operation run by the scheduler.
+type Run struct {
+	Kind     string        `json:"kind"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// Revision is the revision compacted to.
+	Revision int64  `json:"revision,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Status is the state of the scheduler of a member.
+type Status struct {
+	MemberID    uint64     `json:"member_id"`
+	Policy      *Policy    `json:"policy,omitempty"`
+	PolicyError string     `json:"policy_error,omitempty"`
+	NextDefrag  *time.Time `json:"next_defrag,omitempty"`
+	// Runs are the most recent runs, oldest first.
+	Runs []Run `json:"runs,omitempty"`
+}
+
+// Scheduler compacts and defragments the storage of a member following the
+// maintenance policy stored in the cluster. Compactions are run by the
+// leader only, as with the auto compaction; defragmentations are run by
+// every member, staggered in the order of member IDs.
+type Scheduler struct {
+	lg    *zap.Logger
+	clock clockwork.Clock
+	s     Server
+
+	// policy is the stored policy last applied.
+	policy    []byte
+	sched     *schedule
+	compactor v3compactor.Compactor
+	// defragBase is the cron time of the next defragmentation, before the
+	// stagger of the member is applied.
+	defragBase time.Time
+
+	// mu protects status
+	mu     sync.Mutex
+	status Status
+}
+
+// New returns a scheduler for the server.
+func New(lg *zap.Logger, s Server) *Scheduler {
+	return newScheduler(lg, clockwork.NewRealClock(), s)
+}
+
+func newScheduler(lg *zap.Logger, clock clockwork.Clock, s Server) *Scheduler {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	return &Scheduler{
+		lg:     lg,
+		clock:  clock,
+		s:      s,
+		status: Status{MemberID: uint64(s.ID())},
+	}
+}
+
+// Run runs the scheduler until stopc is closed.
+func (sc *Scheduler) Run(stopc <-chan struct{}) {
+	defer func() {
+		if sc.compactor != nil {
+			sc.compactor.Stop()
+		}
+	}()
+	for {
+		sc.check()
+		select {
+		case <-stopc:
+			return
+		case <-sc.clock.After(checkInterval):
+		}
+	}
+}
+
+// Status returns the state of the scheduler.
+func (sc *Scheduler) Status() Status {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	st := sc.status
+	st.Runs = append([]Run(nil), sc.status.Runs...)
+	return st
+}
+
+func (sc *Scheduler) check() {
+	sc.loadPolicy()
+
+	if sc.compactor != nil {
+		if sc.s.Leader() == sc.s.ID() {
+			sc.compactor.Resume()
+		} else {
+			sc.compactor.Pause()
+		}
+	}
+
+	if sc.sched == nil || sc.sched.defragCron == nil || sc.defragBase.IsZero() {
+		return
+	}
+	offset := sc.defragOffset()
+	if now := sc.clock.Now(); !now.Before(sc.defragBase.Add(offset)) {
+		sc.defrag()
+		sc.defragBase = sc.sched.defragCron.Next(sc.clock.Now().Add(-offset))
+	}
+	sc.setNextDefrag(sc.defragBase, offset)
+}
+
+// loadPolicy applies the stored policy if it changed.
+func (sc *Scheduler) loadPolicy() {
+	b := sc.s.Policy(pb.PolicyType_MAINTENANCE)
+	if b == nil {
+		if sc.policy != nil {
+			sc.lg.Info("maintenance policy removed")
+			sc.policy = nil
+			sc.apply(nil, nil, "")
+		}
+		return
+	}
+	if bytes.Equal(b, sc.policy) {
+		return
+	}
+	sc.policy = b
+
+	p, err := ParsePolicy(b)
+	if err != nil {
+		sc.lg.Warn("ignored invalid maintenance policy", zap.Error(err))
+		sc.apply(nil, nil, err.Error())
+		return
+	}
+	sched, _ := p.schedule()
+	sc.lg.Info(
+		"applied maintenance policy",
+		zap.String("compact-retention", p.CompactRetention),
+		zap.String("defrag-cron", p.DefragCron),
+		zap.Duration("defrag-stagger", sched.defragStagger),
+	)
+	sc.apply(p, sched, "")
+}
+
+func (sc *Scheduler) apply(p *Policy, sched *schedule, policyErr string) {
+	if sc.compactor != nil {
+		sc.compactor.Stop()
+		sc.compactor = nil
+	}
+	sc.sched = sched
+	sc.defragBase = time.Time{}
+
+	if sched != nil && sched.compactRetention > 0 {
+		c, err := v3compactor.New(sc.lg, v3compactor.ModePeriodic, sched.compactRetention, sc.s.KV(), &compactRecorder{sc})
+		if err != nil {
+			sc.lg.Warn("failed to create compactor", zap.Error(err))
+		} else {
+			// resumed on the leader
+			c.Pause()
+			c.Run()
+			sc.compactor = c
+		}
+	}
+	if sched != nil && sched.defragCron != nil {
+		sc.defragBase = sched.defragCron.Next(sc.clock.Now().Add(-sc.defragOffset()))
+	}
+
+	sc.mu.Lock()
+	sc.status.Policy = p
+	sc.status.PolicyError = policyErr
+	sc.status.NextDefrag = nil
+	sc.mu.Unlock()
+}
+
+// defragOffset is how long the defragmentation of the member is delayed
+// after the cron time.
+func (sc *Scheduler) defragOffset() time.Duration {
+	rank := 0
+	for _, m := range sc.s.Cluster().Members() {
+		if m.ID < sc.s.ID() {
+			rank++
+		}
+	}
+	return time.Duration(rank) * sc.sched.defragStagger
+}
+
+func (sc *Scheduler) defrag() {
+	sc.lg.Info("starting scheduled defragment")
+	start := sc.clock.Now()
+	err := sc.s.Backend().Defrag()
+	r := Run{Kind: RunDefrag, Start: start, Duration: sc.clock.Since(start)}
+	if err != nil {
+		sc.lg.Warn("failed to run scheduled defragment", zap.Error(err))
+		r.Error = err.Error()
+	} else {
+		sc.lg.Info("finished scheduled defragment", zap.Duration("took", r.Duration))
+	}
+	sc.record(r)
+}
+
+func (sc *Scheduler) setNextDefrag(base time.Time, offset time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.status.NextDefrag = nil
+	if !base.IsZero() {
+		next := base.Add(offset)
+		sc.status.NextDefrag = &next
+	}
+}
+
+func (sc *Scheduler) record(r Run) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.status.Runs = append(sc.status.Runs, r)
+	if len(sc.status.Runs) > maxRuns {
+		sc.status.Runs = sc.status.Runs[len(sc.status.Runs)-maxRuns:]
+	}
+}
+
+// compactRecorder records the compactions of the periodic compactor.
+type compactRecorder struct {
+	sc *Scheduler
+}
+
+func (cr *compactRecorder) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	start := cr.sc.clock.Now()
+	resp, err := cr.sc.s.Compact(ctx, r)
+	if err == mvcc.ErrCompacted {
+		// compacted past the revision already, e.g. by a client
+		return resp, err
+	}
+	run := Run{Kind: RunCompaction, Start: start, Duration: cr.sc.clock.Since(start), Revision: r.Revision}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	cr.sc.record(run)
+	return resp, err
+}
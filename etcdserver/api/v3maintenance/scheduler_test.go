@@ -0,0 +1,161 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3maintenance
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/jonboulle/clockwork"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/types"
+	"go.etcd.io/etcd/v3/etcdserver/api"
+	"go.etcd.io/etcd/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+	"go.uber.org/zap"
+)
+
+func TestSchedulerDefrag(t *testing.T) {
+	s := newFakeServer(2, 1, 2)
+	defer s.close()
+	fc := clockwork.NewFakeClockAt(time.Date(2020, 1, 1, 10, 0, 30, 0, time.Local))
+	sc := newScheduler(zap.NewExample(), fc, s)
+
+	s.policy = []byte(`{"defrag_cron":"* * * * *","defrag_stagger":"1m"}`)
+	sc.check()
+	st := sc.Status()
+	if st.Policy == nil || st.Policy.DefragCron != "* * * * *" {
+		t.Fatalf("expected the policy to be applied, got %+v", st)
+	}
+	// the second member defragments one stagger after the cron time
+	if want := time.Date(2020, 1, 1, 10, 1, 0, 0, time.Local); st.NextDefrag == nil || !st.NextDefrag.Equal(want) {
+		t.Fatalf("expected next defragment at %v, got %v", want, st.NextDefrag)
+	}
+	if len(st.Runs) != 0 {
+		t.Fatalf("expected no run, got %+v", st.Runs)
+	}
+
+	fc.Advance(time.Minute)
+	sc.check()
+	st = sc.Status()
+	if len(st.Runs) != 1 || st.Runs[0].Kind != RunDefrag || st.Runs[0].Error != "" {
+		t.Fatalf("expected one defragment, got %+v", st.Runs)
+	}
+	if want := time.Date(2020, 1, 1, 10, 2, 0, 0, time.Local); st.NextDefrag == nil || !st.NextDefrag.Equal(want) {
+		t.Fatalf("expected next defragment at %v, got %v", want, st.NextDefrag)
+	}
+
+	s.policy = nil
+	sc.check()
+	if st = sc.Status(); st.Policy != nil || st.NextDefrag != nil {
+		t.Fatalf("expected the policy to be removed, got %+v", st)
+	}
+}
+
+func TestSchedulerInvalidPolicy(t *testing.T) {
+	s := newFakeServer(1, 1)
+	defer s.close()
+	sc := newScheduler(zap.NewExample(), clockwork.NewFakeClock(), s)
+
+	s.policy = []byte(`{"defrag_cron":"0 3 * *"}`)
+	sc.check()
+	if st := sc.Status(); st.Policy != nil || st.PolicyError == "" {
+		t.Fatalf("expected a policy error, got %+v", st)
+	}
+
+	s.policy = []byte(`{"compact_retention":"1h"}`)
+	sc.check()
+	defer sc.compactor.Stop()
+	if st := sc.Status(); st.Policy == nil || st.PolicyError != "" || st.NextDefrag != nil {
+		t.Fatalf("expected the policy to be applied, got %+v", st)
+	}
+	if sc.compactor == nil {
+		t.Fatal("expected a compactor")
+	}
+}
+
+func TestCompactRecorder(t *testing.T) {
+	s := newFakeServer(1, 1)
+	defer s.close()
+	sc := newScheduler(zap.NewExample(), clockwork.NewFakeClock(), s)
+	cr := &compactRecorder{sc}
+
+	cr.Compact(context.Background(), &pb.CompactionRequest{Revision: 5})
+	s.compactErr = mvcc.ErrCompacted
+	cr.Compact(context.Background(), &pb.CompactionRequest{Revision: 3})
+
+	runs := sc.Status().Runs
+	if len(runs) != 1 || runs[0].Kind != RunCompaction || runs[0].Revision != 5 {
+		t.Fatalf("expected one compaction to revision 5, got %+v", runs)
+	}
+}
+
+type fakeServer struct {
+	id      types.ID
+	cluster *fakeCluster
+	be      backend.Backend
+	bePath  string
+	kv      mvcc.ConsistentWatchableKV
+	policy  []byte
+
+	compactErr error
+}
+
+func newFakeServer(id uint64, memberIDs ...uint64) *fakeServer {
+	be, path := backend.NewDefaultTmpBackend()
+	s := &fakeServer{
+		id:      types.ID(id),
+		cluster: &fakeCluster{},
+		be:      be,
+		bePath:  path,
+	}
+	s.kv = mvcc.New(zap.NewExample(), be, &lease.FakeLessor{}, cindex.NewConsistentIndex(be.BatchTx()), mvcc.StoreConfig{})
+	for _, mid := range memberIDs {
+		s.cluster.members = append(s.cluster.members, &membership.Member{ID: types.ID(mid)})
+	}
+	return s
+}
+
+func (s *fakeServer) close() {
+	s.kv.Close()
+	s.be.Close()
+	os.Remove(s.bePath)
+}
+
+func (s *fakeServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	return &pb.CompactionResponse{}, s.compactErr
+}
+func (s *fakeServer) ID() types.ID                   { return s.id }
+func (s *fakeServer) Leader() types.ID               { return s.id }
+func (s *fakeServer) Cluster() api.Cluster           { return s.cluster }
+func (s *fakeServer) KV() mvcc.ConsistentWatchableKV { return s.kv }
+func (s *fakeServer) Backend() backend.Backend       { return s.be }
+func (s *fakeServer) Policy(t pb.PolicyType) []byte  { return s.policy }
+
+type fakeCluster struct {
+	members []*membership.Member
+}
+
+func (c *fakeCluster) ID() types.ID                          { return 0 }
+func (c *fakeCluster) ClientURLs() []string                  { return nil }
+func (c *fakeCluster) Members() []*membership.Member         { return c.members }
+func (c *fakeCluster) Member(id types.ID) *membership.Member { return nil }
+func (c *fakeCluster) Version() *semver.Version              { return nil }
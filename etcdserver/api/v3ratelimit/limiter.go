@@ -0,0 +1,283 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3ratelimit
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/types"
+
+	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// checkInterval is how often the policy is read and the idle buckets
+	// are removed.
+	checkInterval = 5 * time.Second
+	// idleBucketTimeout is how long a bucket is kept after its last use.
+	idleBucketTimeout = 10 * time.Minute
+
+	ruleUser       = "user"
+	ruleCommonName = "common_name"
+	ruleNetwork    = "network"
+	ruleDefault    = "default"
+)
+
+// Server is the part of the etcd server the limiter reads the policy from.
+type Server interface {
+	ID() types.ID
+	// Policy returns the stored policy of the given type, or nil if there
+	// is none.
+	Policy(t pb.PolicyType) []byte
+}
+
+// Client identifies the client of a request.
+type Client struct {
+	// User is the authenticated user, if any.
+	User string
+	// CommonName is the common name of the client certificate, if any.
+	CommonName string
+	// IP is the address of the client, if known.
+	IP net.IP
+}
+
+// Status is the state of the limiter of a member.
+type Status struct {
+	MemberID    uint64  `json:"member_id"`
+	Policy      *Policy `json:"policy,omitempty"`
+	PolicyError string  `json:"policy_error,omitempty"`
+	// Buckets is the number of token buckets in use.
+	Buckets int `json:"buckets"`
+	// Rejected is the number of requests rejected since the member started,
+	// by class.
+	Rejected map[Class]uint64 `json:"rejected,omitempty"`
+}
+
+// Limiter rate limits the requests of the clients of a member following the
+// rate limit policy stored in the cluster.
+type Limiter struct {
+	lg    *zap.Logger
+	clock clockwork.Clock
+	s     Server
+
+	// mu protects the fields below
+	mu sync.Mutex
+	// stored is the stored policy last applied.
+	stored       []byte
+	policy       *Policy
+	networks     []network
+	policyErr    string
+	buckets      map[bucketKey]*bucket
+	rejected     map[Class]uint64
+	reservations []*rate.Reservation
+}
+
+type bucketKey struct {
+	rule  string
+	id    string
+	class Class
+}
+
+type bucket struct {
+	l        *rate.Limiter
+	lastUsed time.Time
+}
+
+// New returns a limiter for the server.
+func New(lg *zap.Logger, s Server) *Limiter {
+	return newLimiter(lg, clockwork.NewRealClock(), s)
+}
+
+func newLimiter(lg *zap.Logger, clock clockwork.Clock, s Server) *Limiter {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	return &Limiter{
+		lg:       lg,
+		clock:    clock,
+		s:        s,
+		buckets:  make(map[bucketKey]*bucket),
+		rejected: make(map[Class]uint64),
+	}
+}
+
+// Run reads the policy and removes the idle buckets until stopc is closed.
+func (l *Limiter) Run(stopc <-chan struct{}) {
+	for {
+		l.loadPolicy()
+		l.removeIdleBuckets()
+		select {
+		case <-stopc:
+			return
+		case <-l.clock.After(checkInterval):
+		}
+	}
+}
+
+// Allow returns false if a request of the class by the client is over the
+// budget of a rule matching the client. An allowed request takes a token
+// from the bucket of every rule matching it.
+func (l *Limiter) Allow(c Client, class Class) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.policy == nil {
+		return true
+	}
+
+	now := l.clock.Now()
+	l.reservations = l.reservations[:0]
+	matched := false
+	take := func(rule, id string, b *Budget) bool {
+		matched = true
+		lim := b.limit(class)
+		if lim == nil {
+			return true
+		}
+		bk := l.bucket(bucketKey{rule: rule, id: id, class: class}, lim, now)
+		r := bk.l.ReserveN(now, 1)
+		if !r.OK() || r.DelayFrom(now) > 0 {
+			r.CancelAt(now)
+			rejectedRequests.WithLabelValues(string(class), rule).Inc()
+			return false
+		}
+		l.reservations = append(l.reservations, r)
+		return true
+	}
+
+	ok := true
+	if b, found := l.policy.Users[c.User]; found && c.User != "" {
+		ok = take(ruleUser, c.User, &b)
+	}
+	if b, found := l.policy.CommonNames[c.CommonName]; ok && found && c.CommonName != "" {
+		ok = take(ruleCommonName, c.CommonName, &b)
+	}
+	if ok && c.IP != nil {
+		for i := range l.networks {
+			if l.networks[i].ipnet.Contains(c.IP) {
+				ok = take(ruleNetwork, c.IP.String(), &l.networks[i].budget)
+				break
+			}
+		}
+	}
+	if ok && !matched && l.policy.Default != nil && c.IP != nil {
+		ok = take(ruleDefault, c.IP.String(), l.policy.Default)
+	}
+	if !ok {
+		// a rejected request takes no token
+		for _, r := range l.reservations {
+			r.CancelAt(now)
+		}
+		l.rejected[class]++
+	}
+	return ok
+}
+
+// Status returns the state of the limiter.
+func (l *Limiter) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := Status{
+		MemberID:    uint64(l.s.ID()),
+		Policy:      l.policy,
+		PolicyError: l.policyErr,
+		Buckets:     len(l.buckets),
+	}
+	if len(l.rejected) > 0 {
+		st.Rejected = make(map[Class]uint64, len(l.rejected))
+		for c, n := range l.rejected {
+			st.Rejected[c] = n
+		}
+	}
+	return st
+}
+
+func (l *Limiter) bucket(k bucketKey, lim *Limit, now time.Time) *bucket {
+	bk, ok := l.buckets[k]
+	if !ok {
+		bk = &bucket{l: rate.NewLimiter(rate.Limit(lim.Rate), lim.burst())}
+		l.buckets[k] = bk
+		buckets.Set(float64(len(l.buckets)))
+	}
+	// the bucket may predate the policy, keep its tokens but follow the
+	// current limit
+	if bk.l.Limit() != rate.Limit(lim.Rate) {
+		bk.l.SetLimitAt(now, rate.Limit(lim.Rate))
+	}
+	if bk.l.Burst() != lim.burst() {
+		bk.l.SetBurstAt(now, lim.burst())
+	}
+	bk.lastUsed = now
+	return bk
+}
+
+// loadPolicy applies the stored policy if it changed.
+func (l *Limiter) loadPolicy() {
+	b := l.s.Policy(pb.PolicyType_RATE_LIMIT)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b == nil {
+		if l.stored != nil {
+			l.lg.Info("rate limit policy removed")
+			l.stored = nil
+			l.apply(nil, nil, "")
+		}
+		return
+	}
+	if bytes.Equal(b, l.stored) {
+		return
+	}
+	l.stored = b
+
+	p, err := ParsePolicy(b)
+	if err != nil {
+		l.lg.Warn("ignored invalid rate limit policy", zap.Error(err))
+		l.apply(nil, nil, err.Error())
+		return
+	}
+	nets, _ := p.networks()
+	l.lg.Info(
+		"applied rate limit policy",
+		zap.Int("users", len(p.Users)),
+		zap.Int("common-names", len(p.CommonNames)),
+		zap.Int("networks", len(p.Networks)),
+		zap.Bool("default", p.Default != nil),
+	)
+	l.apply(p, nets, "")
+}
+
+// apply replaces the policy. The buckets are kept so that reloading the
+// policy does not refill them; their limits are updated on their next use.
+// It must be called with mu held.
+func (l *Limiter) apply(p *Policy, nets []network, policyErr string) {
+	l.policy, l.networks, l.policyErr = p, nets, policyErr
+}
+
+func (l *Limiter) removeIdleBuckets() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	for k, bk := range l.buckets {
+		if now.Sub(bk.lastUsed) > idleBucketTimeout {
+			delete(l.buckets, k)
+		}
+	}
+	buckets.Set(float64(len(l.buckets)))
+}
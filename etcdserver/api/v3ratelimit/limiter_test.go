@@ -0,0 +1,202 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3ratelimit
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/types"
+	"go.uber.org/zap"
+)
+
+func TestParsePolicyInvalid(t *testing.T) {
+	for i, s := range []string{
+		`{`,
+		`{}`,
+		`{"users":{"a":{"reads":{"rate":-1}}}}`,
+		`{"common_names":{"a":{"writes":{"rate":0}}}}`,
+		`{"networks":{"10.0.0.0":{"watches":{"rate":1}}}}`,
+		`{"default":{"reads":{"rate":1,"burst":-1}}}`,
+	} {
+		if _, err := ParsePolicy([]byte(s)); err == nil {
+			t.Errorf("#%d: expected error for %s", i, s)
+		}
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	s := newFakeServer()
+	fc := clockwork.NewFakeClock()
+	l := newLimiter(zap.NewExample(), fc, s)
+
+	alice := Client{User: "alice", IP: net.ParseIP("10.0.0.1")}
+	if !l.Allow(alice, ClassWrite) {
+		t.Fatal("expected requests to be allowed without a policy")
+	}
+
+	s.policy = []byte(`{
+		"users": {"alice": {"writes": {"rate": 1, "burst": 2}}},
+		"networks": {
+			"10.0.0.0/8": {"reads": {"rate": 1}},
+			"10.1.0.0/16": {"reads": {"rate": 1, "burst": 3}}
+		},
+		"default": {"watches": {"rate": 1}}
+	}`)
+	l.loadPolicy()
+	if st := l.Status(); st.Policy == nil || st.PolicyError != "" {
+		t.Fatalf("expected the policy to be applied, got %+v", st)
+	}
+
+	// the user and the network rules apply to alice
+	for i := 0; i < 2; i++ {
+		if !l.Allow(alice, ClassWrite) {
+			t.Fatalf("#%d: expected write within the burst to be allowed", i)
+		}
+	}
+	if l.Allow(alice, ClassWrite) {
+		t.Fatal("expected write over the burst to be rejected")
+	}
+	if !l.Allow(alice, ClassRead) {
+		t.Fatal("expected read within the network budget to be allowed")
+	}
+	if l.Allow(alice, ClassRead) {
+		t.Fatal("expected read over the network budget to be rejected")
+	}
+	// the default does not apply to clients matched by a rule
+	for i := 0; i < 3; i++ {
+		if !l.Allow(alice, ClassWatch) {
+			t.Fatalf("#%d: expected unlimited watch creation", i)
+		}
+	}
+
+	// the longest range applies, every address has its own budget
+	bob := Client{User: "bob", IP: net.ParseIP("10.1.0.1")}
+	for i := 0; i < 3; i++ {
+		if !l.Allow(bob, ClassRead) {
+			t.Fatalf("#%d: expected read within the burst of the longest range to be allowed", i)
+		}
+	}
+	if !l.Allow(Client{IP: net.ParseIP("10.1.0.2")}, ClassRead) {
+		t.Fatal("expected read of another address to be allowed")
+	}
+
+	carol := Client{IP: net.ParseIP("192.168.0.1")}
+	if !l.Allow(carol, ClassWatch) || l.Allow(carol, ClassWatch) {
+		t.Fatal("expected the default budget to apply to clients matched by no rule")
+	}
+
+	fc.Advance(time.Second)
+	if !l.Allow(alice, ClassWrite) || !l.Allow(carol, ClassWatch) {
+		t.Fatal("expected the buckets to be refilled")
+	}
+
+	if st := l.Status(); st.Rejected[ClassWrite] != 1 || st.Rejected[ClassRead] != 1 || st.Rejected[ClassWatch] != 1 {
+		t.Fatalf("expected one rejected request by class, got %v", st.Rejected)
+	}
+
+	fc.Advance(idleBucketTimeout + time.Second)
+	l.removeIdleBuckets()
+	if st := l.Status(); st.Buckets != 0 {
+		t.Fatalf("expected the idle buckets to be removed, got %d", st.Buckets)
+	}
+
+	s.policy = nil
+	l.loadPolicy()
+	if st := l.Status(); st.Policy != nil {
+		t.Fatalf("expected the policy to be removed, got %+v", st)
+	}
+}
+
+func TestLimiterRejectTakesNoToken(t *testing.T) {
+	s := newFakeServer()
+	l := newLimiter(zap.NewExample(), clockwork.NewFakeClock(), s)
+
+	s.policy = []byte(`{
+		"users": {"alice": {"reads": {"rate": 1, "burst": 2}}},
+		"common_names": {"client": {"reads": {"rate": 1}}}
+	}`)
+	l.loadPolicy()
+
+	if !l.Allow(Client{User: "alice", CommonName: "client"}, ClassRead) {
+		t.Fatal("expected read to be allowed")
+	}
+	// rejected by the common name, the read takes no token of alice
+	if l.Allow(Client{User: "alice", CommonName: "client"}, ClassRead) {
+		t.Fatal("expected read over the common name budget to be rejected")
+	}
+	if !l.Allow(Client{User: "alice"}, ClassRead) {
+		t.Fatal("expected read within the user budget to be allowed")
+	}
+}
+
+func TestLimiterReloadKeepsBuckets(t *testing.T) {
+	s := newFakeServer()
+	fc := clockwork.NewFakeClock()
+	l := newLimiter(zap.NewExample(), fc, s)
+
+	s.policy = []byte(`{"users": {"alice": {"writes": {"rate": 1, "burst": 2}}}}`)
+	l.loadPolicy()
+	alice := Client{User: "alice"}
+	for i := 0; i < 2; i++ {
+		if !l.Allow(alice, ClassWrite) {
+			t.Fatalf("#%d: expected write within the burst to be allowed", i)
+		}
+	}
+
+	// a new policy does not refill the bucket of alice
+	s.policy = []byte(`{"users": {"alice": {"writes": {"rate": 2, "burst": 4}}}}`)
+	l.loadPolicy()
+	if l.Allow(alice, ClassWrite) {
+		t.Fatal("expected write with an empty bucket to be rejected after the reload")
+	}
+
+	// the bucket follows the new rate
+	fc.Advance(time.Second)
+	for i := 0; i < 2; i++ {
+		if !l.Allow(alice, ClassWrite) {
+			t.Fatalf("#%d: expected write within the new rate to be allowed", i)
+		}
+	}
+	if l.Allow(alice, ClassWrite) {
+		t.Fatal("expected write over the new rate to be rejected")
+	}
+}
+
+func TestLimiterInvalidPolicy(t *testing.T) {
+	s := newFakeServer()
+	l := newLimiter(zap.NewExample(), clockwork.NewFakeClock(), s)
+
+	s.policy = []byte(`{"networks":{"nope":{}}}`)
+	l.loadPolicy()
+	if st := l.Status(); st.Policy != nil || st.PolicyError == "" {
+		t.Fatalf("expected a policy error, got %+v", st)
+	}
+	if !l.Allow(Client{IP: net.ParseIP("10.0.0.1")}, ClassRead) {
+		t.Fatal("expected requests to be allowed with an invalid policy")
+	}
+}
+
+type fakeServer struct {
+	policy []byte
+}
+
+func newFakeServer() *fakeServer { return &fakeServer{} }
+
+func (s *fakeServer) ID() types.ID                { return 1 }
+func (s *fakeServer) Policy(pb.PolicyType) []byte { return s.policy }
@@ -0,0 +1,40 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3ratelimit
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	rejectedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "rate_limited_requests_total",
+		Help:      "The total number of client requests rejected by the rate limit, by class of request and rule.",
+	},
+		[]string{"class", "rule"},
+	)
+
+	buckets = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "rate_limit_buckets",
+		Help:      "The number of token buckets of the rate limit in use.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(rejectedRequests)
+	prometheus.MustRegister(buckets)
+}
@@ -0,0 +1,168 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+)
+
+// Class is a class of requests with its own budget.
+type Class string
+
+const (
+	ClassRead  Class = "read"
+	ClassWrite Class = "write"
+	ClassWatch Class = "watch"
+)
+
+// Limit is a token bucket.
+type Limit struct {
+	// Rate is the number of requests allowed per second.
+	Rate float64 `json:"rate"`
+	// Burst is the number of requests allowed at once. It defaults to the
+	// rate rounded up.
+	Burst int `json:"burst,omitempty"`
+}
+
+func (l *Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return int(math.Ceil(l.Rate))
+}
+
+// Budget is the limits of the classes of requests of a client. A class
+// without a limit is not limited.
+type Budget struct {
+	Reads   *Limit `json:"reads,omitempty"`
+	Writes  *Limit `json:"writes,omitempty"`
+	Watches *Limit `json:"watches,omitempty"`
+}
+
+func (b *Budget) limit(c Class) *Limit {
+	switch c {
+	case ClassRead:
+		return b.Reads
+	case ClassWrite:
+		return b.Writes
+	case ClassWatch:
+		return b.Watches
+	}
+	return nil
+}
+
+// Policy is the rate limit policy shared by the members of the cluster. A
+// request is allowed if it is within the budgets of all the rules matching
+// its client; the budgets are per member.
+type Policy struct {
+	// Users are the budgets of the authenticated users, by name.
+	Users map[string]Budget `json:"users,omitempty"`
+	// CommonNames are the budgets of the client certificates, by common name.
+	CommonNames map[string]Budget `json:"common_names,omitempty"`
+	// Networks are the budgets of every client address in the ranges, by
+	// CIDR notation such as "10.0.0.0/8". The longest range matching the
+	// address of a client applies.
+	Networks map[string]Budget `json:"networks,omitempty"`
+	// Default is the budget of every client address matched by no rule.
+	Default *Budget `json:"default,omitempty"`
+}
+
+// network is a parsed range of Policy.Networks.
+type network struct {
+	cidr   string
+	ipnet  *net.IPNet
+	budget Budget
+}
+
+// ParsePolicy parses a policy stored as JSON.
+func ParsePolicy(b []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("invalid rate limit policy (%v)", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate returns an error if the policy cannot be enforced.
+func (p *Policy) Validate() error {
+	_, err := p.networks()
+	return err
+}
+
+func (p *Policy) networks() ([]network, error) {
+	if len(p.Users) == 0 && len(p.CommonNames) == 0 && len(p.Networks) == 0 && p.Default == nil {
+		return nil, errors.New("rate limit policy has no rule")
+	}
+	for name, b := range p.Users {
+		if err := b.validate(); err != nil {
+			return nil, fmt.Errorf("invalid budget of user %q (%v)", name, err)
+		}
+	}
+	for cn, b := range p.CommonNames {
+		if err := b.validate(); err != nil {
+			return nil, fmt.Errorf("invalid budget of common name %q (%v)", cn, err)
+		}
+	}
+	if p.Default != nil {
+		if err := p.Default.validate(); err != nil {
+			return nil, fmt.Errorf("invalid default budget (%v)", err)
+		}
+	}
+	nets := make([]network, 0, len(p.Networks))
+	for cidr, b := range p.Networks {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q (%v)", cidr, err)
+		}
+		if err = b.validate(); err != nil {
+			return nil, fmt.Errorf("invalid budget of network %q (%v)", cidr, err)
+		}
+		nets = append(nets, network{cidr: cidr, ipnet: ipnet, budget: b})
+	}
+	// longest ranges first
+	sort.Slice(nets, func(i, j int) bool {
+		oi, _ := nets[i].ipnet.Mask.Size()
+		oj, _ := nets[j].ipnet.Mask.Size()
+		if oi != oj {
+			return oi > oj
+		}
+		return nets[i].cidr < nets[j].cidr
+	})
+	return nets, nil
+}
+
+func (b *Budget) validate() error {
+	for _, c := range []Class{ClassRead, ClassWrite, ClassWatch} {
+		l := b.limit(c)
+		if l == nil {
+			continue
+		}
+		if l.Rate < 0 || l.Burst < 0 {
+			return fmt.Errorf("negative %s limit", c)
+		}
+		if l.burst() == 0 {
+			return fmt.Errorf("%s limit allows no request", c)
+		}
+	}
+	return nil
+}
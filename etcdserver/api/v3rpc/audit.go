@@ -0,0 +1,201 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"fmt"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3audit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// newAuditUnaryInterceptor writes the audit records of the mutating and auth
+// requests, including those rejected, to the audit log of the server.
+func newAuditUnaryInterceptor(s *etcdserver.EtcdServer, al *v3audit.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		keys, target, ok := auditRequest(req, resp)
+		if !ok {
+			return resp, err
+		}
+		r := v3audit.Record{
+			Operation: info.FullMethod,
+			Keys:      keys,
+			Target:    target,
+		}
+		if ai, aerr := s.AuthInfoFromCtx(ctx); aerr == nil && ai != nil {
+			r.User = ai.Username
+		}
+		if p, ok := peer.FromContext(ctx); ok && p != nil {
+			if p.Addr != nil {
+				r.Remote = p.Addr.String()
+			}
+			r.CommonName = certCommonName(p)
+		}
+		st := status.Convert(err)
+		r.Result = st.Code().String()
+		if err != nil {
+			r.Error = st.Message()
+		}
+		al.Log(r)
+		return resp, err
+	}
+}
+
+// certCommonName returns the common name of the verified client certificate
+// of the peer, if any.
+func certCommonName(p *peer.Peer) string {
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ""
+	}
+	for _, chains := range tlsInfo.State.VerifiedChains {
+		if len(chains) > 0 {
+			return chains[0].Subject.CommonName
+		}
+	}
+	return ""
+}
+
+// auditRequest returns the keys the request writes and what else it acts on.
+// It returns false if the request is neither mutating nor an auth request.
+func auditRequest(req, resp interface{}) (keys []string, target string, ok bool) {
+	switch r := req.(type) {
+	case *pb.PutRequest:
+		return []string{string(r.Key)}, "", true
+	case *pb.DeleteRangeRequest:
+		return []string{auditKeyRange(r.Key, r.RangeEnd)}, "", true
+	case *pb.TxnRequest:
+		txnResp, _ := resp.(*pb.TxnResponse)
+		keys = auditTxnKeys(r, txnResp)
+		return keys, "", len(keys) > 0
+	case *pb.CompactionRequest:
+		return nil, fmt.Sprintf("revision:%d", r.Revision), true
+
+	case *pb.LeaseGrantRequest:
+		if lresp, _ := resp.(*pb.LeaseGrantResponse); lresp != nil {
+			return nil, fmt.Sprintf("lease:%016x", lresp.ID), true
+		}
+		return nil, fmt.Sprintf("lease:%016x", r.ID), true
+	case *pb.LeaseRevokeRequest:
+		return nil, fmt.Sprintf("lease:%016x", r.ID), true
+
+	case *pb.MemberAddRequest:
+		if mresp, _ := resp.(*pb.MemberAddResponse); mresp != nil && mresp.Member != nil {
+			return nil, fmt.Sprintf("member:%016x", mresp.Member.ID), true
+		}
+		return nil, fmt.Sprintf("member:%v", r.PeerURLs), true
+	case *pb.MemberRemoveRequest:
+		return nil, fmt.Sprintf("member:%016x", r.ID), true
+	case *pb.MemberUpdateRequest:
+		return nil, fmt.Sprintf("member:%016x", r.ID), true
+	case *pb.MemberPromoteRequest:
+		return nil, fmt.Sprintf("member:%016x", r.ID), true
+
+	case *pb.AlarmRequest:
+		if r.Action == pb.AlarmRequest_GET {
+			return nil, "", false
+		}
+		return nil, fmt.Sprintf("alarm:%s member:%016x", r.Alarm, r.MemberID), true
+	case *pb.DefragmentRequest:
+		return nil, "", true
+	case *pb.MoveLeaderRequest:
+		return nil, fmt.Sprintf("member:%016x", r.TargetID), true
+	case *pb.DowngradeRequest:
+		if r.Action == pb.DowngradeRequest_VALIDATE {
+			return nil, "", false
+		}
+		return nil, "version:" + r.Version, true
+
+	case *pb.AuthEnableRequest, *pb.AuthDisableRequest, *pb.AuthStatusRequest,
+		*pb.AuthUserListRequest, *pb.AuthRoleListRequest:
+		return nil, "", true
+	case *pb.AuthenticateRequest:
+		return nil, "user:" + r.Name, true
+	case *pb.AuthUserAddRequest:
+		return nil, "user:" + r.Name, true
+	case *pb.AuthUserGetRequest:
+		return nil, "user:" + r.Name, true
+	case *pb.AuthUserDeleteRequest:
+		return nil, "user:" + r.Name, true
+	case *pb.AuthUserChangePasswordRequest:
+		return nil, "user:" + r.Name, true
+	case *pb.AuthUserTokenRevokeRequest:
+		return nil, "user:" + r.Name, true
+	case *pb.AuthUserGrantRoleRequest:
+		return nil, fmt.Sprintf("user:%s role:%s", r.User, r.Role), true
+	case *pb.AuthUserRevokeRoleRequest:
+		return nil, fmt.Sprintf("user:%s role:%s", r.Name, r.Role), true
+	case *pb.AuthRoleAddRequest:
+		return nil, "role:" + r.Name, true
+	case *pb.AuthRoleGetRequest:
+		return nil, "role:" + r.Role, true
+	case *pb.AuthRoleDeleteRequest:
+		return nil, "role:" + r.Role, true
+	case *pb.AuthRoleGrantPermissionRequest:
+		if r.Perm == nil {
+			return nil, "role:" + r.Name, true
+		}
+		return []string{auditKeyRange(r.Perm.Key, r.Perm.RangeEnd)}, fmt.Sprintf("role:%s permission:%s", r.Name, r.Perm.PermType), true
+	case *pb.AuthRoleRevokePermissionRequest:
+		return []string{auditKeyRange(r.Key, r.RangeEnd)}, "role:" + r.Role, true
+	}
+	return nil, "", false
+}
+
+// auditTxnKeys returns the keys the txn writes. If the txn is done, they are
+// the keys of the branch taken, otherwise the keys of both branches.
+func auditTxnKeys(r *pb.TxnRequest, resp *pb.TxnResponse) []string {
+	var keys []string
+	if resp == nil || resp.Succeeded {
+		keys = appendAuditOpKeys(keys, r.Success, resp)
+	}
+	if resp == nil || !resp.Succeeded {
+		keys = appendAuditOpKeys(keys, r.Failure, resp)
+	}
+	return keys
+}
+
+func appendAuditOpKeys(keys []string, ops []*pb.RequestOp, resp *pb.TxnResponse) []string {
+	for i, op := range ops {
+		switch tv := op.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			keys = append(keys, string(tv.RequestPut.Key))
+		case *pb.RequestOp_RequestDeleteRange:
+			keys = append(keys, auditKeyRange(tv.RequestDeleteRange.Key, tv.RequestDeleteRange.RangeEnd))
+		case *pb.RequestOp_RequestTxn:
+			var txnResp *pb.TxnResponse
+			if resp != nil && i < len(resp.Responses) {
+				txnResp = resp.Responses[i].GetResponseTxn()
+			}
+			keys = append(keys, auditTxnKeys(tv.RequestTxn, txnResp)...)
+		}
+	}
+	return keys
+}
+
+func auditKeyRange(key, end []byte) string {
+	if len(end) == 0 {
+		return string(key)
+	}
+	return fmt.Sprintf("[%s, %s)", key, end)
+}
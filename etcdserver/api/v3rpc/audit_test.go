@@ -0,0 +1,71 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+func TestAuditRequest(t *testing.T) {
+	put := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(key)}}}
+	}
+	get := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte(key)}}}
+	}
+	txn := &pb.TxnRequest{
+		Success: []*pb.RequestOp{put("a"), get("b")},
+		Failure: []*pb.RequestOp{
+			{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("c"), RangeEnd: []byte("d")}}},
+		},
+	}
+	tests := []struct {
+		req, resp interface{}
+
+		keys   []string
+		target string
+		ok     bool
+	}{
+		{&pb.RangeRequest{Key: []byte("a")}, nil, nil, "", false},
+		{&pb.PutRequest{Key: []byte("a")}, nil, []string{"a"}, "", true},
+		{&pb.DeleteRangeRequest{Key: []byte("a"), RangeEnd: []byte("b")}, nil, []string{"[a, b)"}, "", true},
+		{&pb.TxnRequest{Success: []*pb.RequestOp{get("a")}}, nil, nil, "", false},
+		{txn, nil, []string{"a", "[c, d)"}, "", true},
+		{txn, &pb.TxnResponse{Succeeded: true}, []string{"a"}, "", true},
+		{txn, &pb.TxnResponse{Succeeded: false}, []string{"[c, d)"}, "", true},
+		{&pb.LeaseGrantRequest{}, &pb.LeaseGrantResponse{ID: 0x10}, nil, "lease:0000000000000010", true},
+		{&pb.MemberRemoveRequest{ID: 0x20}, nil, nil, "member:0000000000000020", true},
+		{&pb.AlarmRequest{Action: pb.AlarmRequest_GET}, nil, nil, "", false},
+		{&pb.AuthUserListRequest{}, nil, nil, "", true},
+		{&pb.AuthUserGrantRoleRequest{User: "u", Role: "r"}, nil, nil, "user:u role:r", true},
+		{&pb.AuthRoleRevokePermissionRequest{Role: "r", Key: []byte("k")}, nil, []string{"k"}, "role:r", true},
+		{&pb.StatusRequest{}, nil, nil, "", false},
+	}
+	for i, tt := range tests {
+		keys, target, ok := auditRequest(tt.req, tt.resp)
+		if ok != tt.ok {
+			t.Fatalf("#%d: expected audited %v, got %v", i, tt.ok, ok)
+		}
+		if !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("#%d: expected keys %v, got %v", i, tt.keys, keys)
+		}
+		if target != tt.target {
+			t.Errorf("#%d: expected target %q, got %q", i, tt.target, target)
+		}
+	}
+}
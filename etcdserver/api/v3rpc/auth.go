@@ -164,3 +164,11 @@ func (as *AuthServer) UserChangePassword(ctx context.Context, r *pb.AuthUserChan
 	}
 	return resp, nil
 }
+
+func (as *AuthServer) UserTokenRevoke(ctx context.Context, r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error) {
+	resp, err := as.authenticator.UserTokenRevoke(ctx, r)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	return resp, nil
+}
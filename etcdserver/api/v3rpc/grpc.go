@@ -42,11 +42,15 @@ func Server(s *etcdserver.EtcdServer, tls *tls.Config, gopts ...grpc.ServerOptio
 		bundle := credentials.NewBundle(credentials.Config{TLSConfig: tls})
 		opts = append(opts, grpc.Creds(bundle.TransportCredentials()))
 	}
-	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-		newLogUnaryInterceptor(s),
-		newUnaryInterceptor(s),
-		grpc_prometheus.UnaryServerInterceptor,
-	)))
+	unaryInterceptors := []grpc.UnaryServerInterceptor{newLogUnaryInterceptor(s)}
+	if al := s.AuditLog(); al != nil {
+		unaryInterceptors = append(unaryInterceptors, newAuditUnaryInterceptor(s, al))
+	}
+	if rl := s.RateLimiter(); rl != nil {
+		unaryInterceptors = append(unaryInterceptors, newRateLimitUnaryInterceptor(s, rl))
+	}
+	unaryInterceptors = append(unaryInterceptors, newUnaryInterceptor(s), grpc_prometheus.UnaryServerInterceptor)
+	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)))
 	opts = append(opts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 		newStreamInterceptor(s),
 		grpc_prometheus.StreamServerInterceptor,
@@ -32,10 +32,12 @@ type kvServer struct {
 	// Txn.Success can have at most 128 operations,
 	// and Txn.Failure can have at most 128 operations.
 	maxTxnOps uint
+	// keyTTL reports whether the cluster version supports key ttls.
+	keyTTL func() bool
 }
 
 func NewKVServer(s *etcdserver.EtcdServer) pb.KVServer {
-	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps}
+	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps, keyTTL: s.KeyExpiryEnabled}
 }
 
 func (s *kvServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
@@ -53,7 +55,7 @@ func (s *kvServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResp
 }
 
 func (s *kvServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
-	if err := checkPutRequest(r); err != nil {
+	if err := checkPutRequest(r, s.keyTTL()); err != nil {
 		return nil, err
 	}
 
@@ -81,7 +83,7 @@ func (s *kvServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*
 }
 
 func (s *kvServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
-	if err := checkTxnRequest(r, int(s.maxTxnOps)); err != nil {
+	if err := checkTxnRequest(r, int(s.maxTxnOps), s.keyTTL()); err != nil {
 		return nil, err
 	}
 	// check for forbidden put/del overlaps after checking request to avoid quadratic blowup
@@ -118,7 +120,7 @@ func checkRangeRequest(r *pb.RangeRequest) error {
 	return nil
 }
 
-func checkPutRequest(r *pb.PutRequest) error {
+func checkPutRequest(r *pb.PutRequest, keyTTL bool) error {
 	if len(r.Key) == 0 {
 		return rpctypes.ErrGRPCEmptyKey
 	}
@@ -128,6 +130,13 @@ func checkPutRequest(r *pb.PutRequest) error {
 	if r.IgnoreLease && r.Lease != 0 {
 		return rpctypes.ErrGRPCLeaseProvided
 	}
+	if r.Ttl < 0 {
+		return rpctypes.ErrGRPCInvalidKeyTTL
+	}
+	// members of an older cluster version would ignore the ttl
+	if r.Ttl != 0 && !keyTTL {
+		return rpctypes.ErrGRPCKeyTTLNotSupported
+	}
 	return nil
 }
 
@@ -138,7 +147,7 @@ func checkDeleteRequest(r *pb.DeleteRangeRequest) error {
 	return nil
 }
 
-func checkTxnRequest(r *pb.TxnRequest, maxTxnOps int) error {
+func checkTxnRequest(r *pb.TxnRequest, maxTxnOps int, keyTTL bool) error {
 	opc := len(r.Compare)
 	if opc < len(r.Success) {
 		opc = len(r.Success)
@@ -156,12 +165,12 @@ func checkTxnRequest(r *pb.TxnRequest, maxTxnOps int) error {
 		}
 	}
 	for _, u := range r.Success {
-		if err := checkRequestOp(u, maxTxnOps-opc); err != nil {
+		if err := checkRequestOp(u, maxTxnOps-opc, keyTTL); err != nil {
 			return err
 		}
 	}
 	for _, u := range r.Failure {
-		if err := checkRequestOp(u, maxTxnOps-opc); err != nil {
+		if err := checkRequestOp(u, maxTxnOps-opc, keyTTL); err != nil {
 			return err
 		}
 	}
@@ -253,17 +262,17 @@ func checkIntervals(reqs []*pb.RequestOp) (map[string]struct{}, adt.IntervalTree
 	return puts, dels, nil
 }
 
-func checkRequestOp(u *pb.RequestOp, maxTxnOps int) error {
+func checkRequestOp(u *pb.RequestOp, maxTxnOps int, keyTTL bool) error {
 	// TODO: ensure only one of the field is set.
 	switch uv := u.Request.(type) {
 	case *pb.RequestOp_RequestRange:
 		return checkRangeRequest(uv.RequestRange)
 	case *pb.RequestOp_RequestPut:
-		return checkPutRequest(uv.RequestPut)
+		return checkPutRequest(uv.RequestPut, keyTTL)
 	case *pb.RequestOp_RequestDeleteRange:
 		return checkDeleteRequest(uv.RequestDeleteRange)
 	case *pb.RequestOp_RequestTxn:
-		return checkTxnRequest(uv.RequestTxn, maxTxnOps)
+		return checkTxnRequest(uv.RequestTxn, maxTxnOps, keyTTL)
 	default:
 		// empty op / nil entry
 		return rpctypes.ErrGRPCKeyNotFound
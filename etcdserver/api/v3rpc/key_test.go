@@ -0,0 +1,52 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+func TestCheckPutRequestTTL(t *testing.T) {
+	put := func(ttl int64) *pb.PutRequest {
+		return &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar"), Ttl: ttl}
+	}
+	txn := func(ttl int64) *pb.TxnRequest {
+		nested := &pb.TxnRequest{Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestPut{RequestPut: put(ttl)}}}}
+		return &pb.TxnRequest{Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestTxn{RequestTxn: nested}}}}
+	}
+	tests := []struct {
+		ttl    int64
+		keyTTL bool
+
+		err error
+	}{
+		{0, false, nil},
+		{0, true, nil},
+		{10, true, nil},
+		{10, false, rpctypes.ErrGRPCKeyTTLNotSupported},
+		{-1, true, rpctypes.ErrGRPCInvalidKeyTTL},
+	}
+	for i, tt := range tests {
+		if err := checkPutRequest(put(tt.ttl), tt.keyTTL); err != tt.err {
+			t.Errorf("#%d: put error expected %v, got %v", i, tt.err, err)
+		}
+		if err := checkTxnRequest(txn(tt.ttl), 128, tt.keyTTL); err != tt.err {
+			t.Errorf("#%d: txn error expected %v, got %v", i, tt.err, err)
+		}
+	}
+}
@@ -27,10 +27,15 @@ import (
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/v3/auth"
 	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3alarm"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3maintenance"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
 	"go.etcd.io/etcd/v3/mvcc"
 	"go.etcd.io/etcd/v3/mvcc/backend"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type KVGetter interface {
@@ -48,6 +53,31 @@ type Alarmer interface {
 	Alarm(ctx context.Context, ar *pb.AlarmRequest) (*pb.AlarmResponse, error)
 }
 
+type AlarmHistorian interface {
+	// AlarmHistory is implemented in Server interface located in etcdserver/server.go
+	// It returns the most recent alarms raised and cleared, oldest first
+	AlarmHistory() []v3alarm.AlarmEvent
+}
+
+type MaintenanceScheduler interface {
+	// MaintenanceStatus is implemented in Server interface located in etcdserver/server.go
+	// It returns the state of the maintenance scheduler, or nil if it is not enabled
+	MaintenanceStatus() *v3maintenance.Status
+}
+
+type RateLimitStatusGetter interface {
+	// RateLimitStatus is implemented in Server interface located in etcdserver/server.go
+	// It returns the state of the rate limiter, or nil if it is not enabled
+	RateLimitStatus() *v3ratelimit.Status
+}
+
+type PolicyStore interface {
+	// PolicyPut and Policy are implemented in Server interface located in etcdserver/server.go
+	// They store a policy shared by the members of the cluster and return the stored one
+	PolicyPut(ctx context.Context, r *pb.PolicyPutRequest) (*pb.PolicyPutResponse, error)
+	Policy(t pb.PolicyType) []byte
+}
+
 type Downgrader interface {
 	Downgrade(ctx context.Context, dr *pb.DowngradeRequest) (*pb.DowngradeResponse, error)
 }
@@ -71,6 +101,10 @@ type maintenanceServer struct {
 	kg  KVGetter
 	bg  BackendGetter
 	a   Alarmer
+	ah  AlarmHistorian
+	sc  MaintenanceScheduler
+	rl  RateLimitStatusGetter
+	ps  PolicyStore
 	lt  LeaderTransferrer
 	hdr header
 	cs  ClusterStatusGetter
@@ -78,7 +112,7 @@ type maintenanceServer struct {
 }
 
 func NewMaintenanceServer(s *etcdserver.EtcdServer) pb.MaintenanceServer {
-	srv := &maintenanceServer{lg: s.Cfg.Logger, rg: s, kg: s, bg: s, a: s, lt: s, hdr: newHeader(s), cs: s, d: s}
+	srv := &maintenanceServer{lg: s.Cfg.Logger, rg: s, kg: s, bg: s, a: s, ah: s, sc: s, rl: s, ps: s, lt: s, hdr: newHeader(s), cs: s, d: s}
 	if srv.lg == nil {
 		srv.lg = zap.NewNop()
 	}
@@ -212,6 +246,21 @@ func (ms *maintenanceServer) Alarm(ctx context.Context, ar *pb.AlarmRequest) (*p
 	return resp, nil
 }
 
+func (ms *maintenanceServer) AlarmHistory(ctx context.Context, r *pb.AlarmHistoryRequest) (*pb.AlarmHistoryResponse, error) {
+	history := ms.ah.AlarmHistory()
+	resp := &pb.AlarmHistoryResponse{Header: &pb.ResponseHeader{}, Events: make([]*pb.AlarmEvent, 0, len(history))}
+	ms.hdr.fill(resp.Header)
+	for _, ev := range history {
+		resp.Events = append(resp.Events, &pb.AlarmEvent{
+			MemberID: ev.MemberID,
+			Alarm:    pb.AlarmType(pb.AlarmType_value[ev.Alarm]),
+			Raised:   ev.Raised,
+			Time:     ev.Time.UnixNano(),
+		})
+	}
+	return resp, nil
+}
+
 func (ms *maintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (*pb.StatusResponse, error) {
 	hdr := &pb.ResponseHeader{}
 	ms.hdr.fill(hdr)
@@ -235,6 +284,104 @@ func (ms *maintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (
 	return resp, nil
 }
 
+func (ms *maintenanceServer) MaintenanceProgress(ctx context.Context, r *pb.MaintenanceProgressRequest) (*pb.MaintenanceProgressResponse, error) {
+	resp := &pb.MaintenanceProgressResponse{Header: &pb.ResponseHeader{}}
+	ms.hdr.fill(resp.Header)
+	if cp := ms.kg.KV().CompactionProgress(); cp.Revision != 0 {
+		resp.Compaction = &pb.OperationProgress{
+			Done:     cp.DoneRevision - cp.StartRevision,
+			Total:    cp.Revision - cp.StartRevision,
+			Revision: cp.Revision,
+		}
+	}
+	if done, total := ms.bg.Backend().DefragProgress(); total != 0 {
+		resp.Defragment = &pb.OperationProgress{Done: done, Total: total}
+	}
+	return resp, nil
+}
+
+func (ms *maintenanceServer) ScheduleStatus(ctx context.Context, r *pb.ScheduleStatusRequest) (*pb.ScheduleStatusResponse, error) {
+	resp := &pb.ScheduleStatusResponse{Header: &pb.ResponseHeader{}}
+	ms.hdr.fill(resp.Header)
+	st := ms.sc.MaintenanceStatus()
+	if st == nil {
+		return resp, nil
+	}
+	resp.Enabled = true
+	if st.Policy != nil {
+		resp.Policy = &pb.MaintenancePolicy{
+			CompactRetention: st.Policy.CompactRetention,
+			DefragCron:       st.Policy.DefragCron,
+			DefragStagger:    st.Policy.DefragStagger,
+		}
+	}
+	resp.PolicyError = st.PolicyError
+	if st.NextDefrag != nil {
+		resp.NextDefrag = st.NextDefrag.UnixNano()
+	}
+	resp.Runs = make([]*pb.ScheduleRun, 0, len(st.Runs))
+	for _, run := range st.Runs {
+		resp.Runs = append(resp.Runs, &pb.ScheduleRun{
+			Kind:     run.Kind,
+			Start:    run.Start.UnixNano(),
+			Duration: int64(run.Duration),
+			Revision: run.Revision,
+			Error:    run.Error,
+		})
+	}
+	return resp, nil
+}
+
+func (ms *maintenanceServer) RateLimitStatus(ctx context.Context, r *pb.RateLimitStatusRequest) (*pb.RateLimitStatusResponse, error) {
+	resp := &pb.RateLimitStatusResponse{Header: &pb.ResponseHeader{}}
+	ms.hdr.fill(resp.Header)
+	st := ms.rl.RateLimitStatus()
+	if st == nil {
+		return resp, nil
+	}
+	resp.Enabled = true
+	if p := st.Policy; p != nil {
+		resp.Policy = &pb.RateLimitPolicy{
+			Users:       rateLimitBudgets(p.Users),
+			CommonNames: rateLimitBudgets(p.CommonNames),
+			Networks:    rateLimitBudgets(p.Networks),
+		}
+		if p.Default != nil {
+			resp.Policy.Default = rateLimitBudget(*p.Default)
+		}
+	}
+	resp.PolicyError = st.PolicyError
+	resp.Buckets = int64(st.Buckets)
+	if len(st.Rejected) > 0 {
+		resp.Rejected = make(map[string]uint64, len(st.Rejected))
+		for c, n := range st.Rejected {
+			resp.Rejected[string(c)] = n
+		}
+	}
+	return resp, nil
+}
+
+func rateLimitBudgets(bs map[string]v3ratelimit.Budget) map[string]*pb.RateLimitBudget {
+	if len(bs) == 0 {
+		return nil
+	}
+	pbs := make(map[string]*pb.RateLimitBudget, len(bs))
+	for id, b := range bs {
+		pbs[id] = rateLimitBudget(b)
+	}
+	return pbs
+}
+
+func rateLimitBudget(b v3ratelimit.Budget) *pb.RateLimitBudget {
+	limit := func(l *v3ratelimit.Limit) *pb.RateLimit {
+		if l == nil {
+			return nil
+		}
+		return &pb.RateLimit{Rate: l.Rate, Burst: int64(l.Burst)}
+	}
+	return &pb.RateLimitBudget{Reads: limit(b.Reads), Writes: limit(b.Writes), Watches: limit(b.Watches)}
+}
+
 func (ms *maintenanceServer) MoveLeader(ctx context.Context, tr *pb.MoveLeaderRequest) (*pb.MoveLeaderResponse, error) {
 	if ms.rg.ID() != ms.rg.Leader() {
 		return nil, rpctypes.ErrGRPCNotLeader
@@ -256,6 +403,24 @@ func (ms *maintenanceServer) Downgrade(ctx context.Context, r *pb.DowngradeReque
 	return resp, nil
 }
 
+func (ms *maintenanceServer) PolicyPut(ctx context.Context, r *pb.PolicyPutRequest) (*pb.PolicyPutResponse, error) {
+	if err := etcdserver.ValidatePolicy(r); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := ms.ps.PolicyPut(ctx, r)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) PolicyGet(ctx context.Context, r *pb.PolicyGetRequest) (*pb.PolicyGetResponse, error) {
+	resp := &pb.PolicyGetResponse{Header: &pb.ResponseHeader{}, Policy: ms.ps.Policy(r.Type)}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
 type authMaintenanceServer struct {
 	*maintenanceServer
 	ag AuthGetter
@@ -301,10 +466,49 @@ func (ams *authMaintenanceServer) HashKV(ctx context.Context, r *pb.HashKVReques
 	return ams.maintenanceServer.HashKV(ctx, r)
 }
 
+func (ams *authMaintenanceServer) AlarmHistory(ctx context.Context, r *pb.AlarmHistoryRequest) (*pb.AlarmHistoryResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.AlarmHistory(ctx, r)
+}
+
+func (ams *authMaintenanceServer) ScheduleStatus(ctx context.Context, r *pb.ScheduleStatusRequest) (*pb.ScheduleStatusResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.ScheduleStatus(ctx, r)
+}
+
+func (ams *authMaintenanceServer) RateLimitStatus(ctx context.Context, r *pb.RateLimitStatusRequest) (*pb.RateLimitStatusResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.RateLimitStatus(ctx, r)
+}
+
+func (ams *authMaintenanceServer) PolicyPut(ctx context.Context, r *pb.PolicyPutRequest) (*pb.PolicyPutResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.PolicyPut(ctx, r)
+}
+
+func (ams *authMaintenanceServer) PolicyGet(ctx context.Context, r *pb.PolicyGetRequest) (*pb.PolicyGetResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.PolicyGet(ctx, r)
+}
+
 func (ams *authMaintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (*pb.StatusResponse, error) {
 	return ams.maintenanceServer.Status(ctx, ar)
 }
 
+func (ams *authMaintenanceServer) MaintenanceProgress(ctx context.Context, r *pb.MaintenanceProgressRequest) (*pb.MaintenanceProgressResponse, error) {
+	return ams.maintenanceServer.MaintenanceProgress(ctx, r)
+}
+
 func (ams *authMaintenanceServer) MoveLeader(ctx context.Context, tr *pb.MoveLeaderRequest) (*pb.MoveLeaderResponse, error) {
 	return ams.maintenanceServer.MoveLeader(ctx, tr)
 }
@@ -26,16 +26,21 @@ import (
 type quotaKVServer struct {
 	pb.KVServer
 	qa quotaAlarmer
+	// pqa checks the prefix quotas
+	pqa quotaAlarmer
 }
 
 type quotaAlarmer struct {
 	q  etcdserver.Quota
 	a  Alarmer
 	id types.ID
+
+	alarm pb.AlarmType
+	err   error
 }
 
 // check whether request satisfies the quota. If there is not enough space,
-// ignore request and raise the free space alarm.
+// ignore request and raise the alarm of the quota.
 func (qa *quotaAlarmer) check(ctx context.Context, r interface{}) error {
 	if qa.q.Available(r) {
 		return nil
@@ -43,16 +48,17 @@ func (qa *quotaAlarmer) check(ctx context.Context, r interface{}) error {
 	req := &pb.AlarmRequest{
 		MemberID: uint64(qa.id),
 		Action:   pb.AlarmRequest_ACTIVATE,
-		Alarm:    pb.AlarmType_NOSPACE,
+		Alarm:    qa.alarm,
 	}
 	qa.a.Alarm(ctx, req)
-	return rpctypes.ErrGRPCNoSpace
+	return qa.err
 }
 
 func NewQuotaKVServer(s *etcdserver.EtcdServer) pb.KVServer {
 	return &quotaKVServer{
 		NewKVServer(s),
-		quotaAlarmer{etcdserver.NewBackendQuota(s, "kv"), s, s.ID()},
+		quotaAlarmer{etcdserver.NewBackendQuota(s, "kv"), s, s.ID(), pb.AlarmType_NOSPACE, rpctypes.ErrGRPCNoSpace},
+		quotaAlarmer{etcdserver.NewPrefixQuota(s), s, s.ID(), pb.AlarmType_PREFIX_QUOTA, rpctypes.ErrGRPCPrefixQuotaExceeded},
 	}
 }
 
@@ -60,6 +66,9 @@ func (s *quotaKVServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutRespo
 	if err := s.qa.check(ctx, r); err != nil {
 		return nil, err
 	}
+	if err := s.pqa.check(ctx, r); err != nil {
+		return nil, err
+	}
 	return s.KVServer.Put(ctx, r)
 }
 
@@ -67,6 +76,9 @@ func (s *quotaKVServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnRespo
 	if err := s.qa.check(ctx, r); err != nil {
 		return nil, err
 	}
+	if err := s.pqa.check(ctx, r); err != nil {
+		return nil, err
+	}
 	return s.KVServer.Txn(ctx, r)
 }
 
@@ -85,6 +97,6 @@ func (s *quotaLeaseServer) LeaseGrant(ctx context.Context, cr *pb.LeaseGrantRequ
 func NewQuotaLeaseServer(s *etcdserver.EtcdServer) pb.LeaseServer {
 	return &quotaLeaseServer{
 		NewLeaseServer(s),
-		quotaAlarmer{etcdserver.NewBackendQuota(s, "lease"), s, s.ID()},
+		quotaAlarmer{etcdserver.NewBackendQuota(s, "lease"), s, s.ID(), pb.AlarmType_NOSPACE, rpctypes.ErrGRPCNoSpace},
 	}
 }
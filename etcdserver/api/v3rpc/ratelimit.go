@@ -0,0 +1,90 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"net"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// newRateLimitUnaryInterceptor rejects the reads and writes over the rate
+// limits of their client.
+func newRateLimitUnaryInterceptor(ag AuthGetter, rl *v3ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if class, ok := rateLimitClass(req); ok && !rl.Allow(rateLimitClient(ctx, ag), class) {
+			return nil, rpctypes.ErrGRPCRateLimitExceeded
+		}
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitClient identifies the client of the request context.
+func rateLimitClient(ctx context.Context, ag AuthGetter) v3ratelimit.Client {
+	var c v3ratelimit.Client
+	if ai, err := ag.AuthInfoFromCtx(ctx); err == nil && ai != nil {
+		c.User = ai.Username
+	}
+	if p, ok := peer.FromContext(ctx); ok && p != nil {
+		c.CommonName = certCommonName(p)
+		if p.Addr != nil {
+			if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+				c.IP = net.ParseIP(host)
+			}
+		}
+	}
+	return c
+}
+
+// rateLimitClass returns the class of budget the request takes from. It
+// returns false if the request is not rate limited. The maintenance requests,
+// PolicyPut among them, are not limited, so that a policy too strict can be
+// fixed.
+func rateLimitClass(req interface{}) (v3ratelimit.Class, bool) {
+	switch r := req.(type) {
+	case *pb.RangeRequest, *pb.LeaseTimeToLiveRequest, *pb.LeaseLeasesRequest:
+		return v3ratelimit.ClassRead, true
+	case *pb.TxnRequest:
+		if isTxnWrite(r) {
+			return v3ratelimit.ClassWrite, true
+		}
+		return v3ratelimit.ClassRead, true
+	case *pb.PutRequest, *pb.DeleteRangeRequest, *pb.CompactionRequest, *pb.LeaseGrantRequest, *pb.LeaseRevokeRequest:
+		return v3ratelimit.ClassWrite, true
+	}
+	return "", false
+}
+
+func isTxnWrite(r *pb.TxnRequest) bool {
+	for _, ops := range [][]*pb.RequestOp{r.Success, r.Failure} {
+		for _, op := range ops {
+			switch tv := op.Request.(type) {
+			case *pb.RequestOp_RequestPut, *pb.RequestOp_RequestDeleteRange:
+				return true
+			case *pb.RequestOp_RequestTxn:
+				if isTxnWrite(tv.RequestTxn) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
+)
+
+func TestRateLimitClass(t *testing.T) {
+	get := &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("a")}}}
+	put := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("a")}}}
+	nested := &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{Failure: []*pb.RequestOp{put}}}}
+	tests := []struct {
+		req interface{}
+
+		class v3ratelimit.Class
+		ok    bool
+	}{
+		{&pb.RangeRequest{Key: []byte("a")}, v3ratelimit.ClassRead, true},
+		{&pb.PutRequest{Key: []byte("a")}, v3ratelimit.ClassWrite, true},
+		{&pb.DeleteRangeRequest{Key: []byte("a"), RangeEnd: []byte{0}}, v3ratelimit.ClassWrite, true},
+		{&pb.TxnRequest{Success: []*pb.RequestOp{get}}, v3ratelimit.ClassRead, true},
+		{&pb.TxnRequest{Success: []*pb.RequestOp{get}, Failure: []*pb.RequestOp{nested}}, v3ratelimit.ClassWrite, true},
+		{&pb.LeaseGrantRequest{TTL: 10}, v3ratelimit.ClassWrite, true},
+		{&pb.MemberListRequest{}, "", false},
+		{&pb.PolicyPutRequest{Type: pb.PolicyType_RATE_LIMIT}, "", false},
+	}
+	for i, tt := range tests {
+		class, ok := rateLimitClass(tt.req)
+		if ok != tt.ok || (ok && class != tt.class) {
+			t.Errorf("#%d: expected class %q limited %v, got %q %v", i, tt.class, tt.ok, class, ok)
+		}
+	}
+}
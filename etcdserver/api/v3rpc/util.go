@@ -46,6 +46,8 @@ var toGRPCErrorMap = map[error]error{
 	etcdserver.ErrNoSpace:         rpctypes.ErrGRPCNoSpace,
 	etcdserver.ErrTooManyRequests: rpctypes.ErrTooManyRequests,
 
+	etcdserver.ErrPrefixQuotaExceeded: rpctypes.ErrGRPCPrefixQuotaExceeded,
+
 	etcdserver.ErrNoLeader:                   rpctypes.ErrGRPCNoLeader,
 	etcdserver.ErrNotLeader:                  rpctypes.ErrGRPCNotLeader,
 	etcdserver.ErrLeaderChanged:              rpctypes.ErrGRPCLeaderChanged,
@@ -64,6 +66,9 @@ var toGRPCErrorMap = map[error]error{
 	etcdserver.ErrDowngradeInProcess:            rpctypes.ErrGRPCDowngradeInProcess,
 	etcdserver.ErrNoInflightDowngrade:           rpctypes.ErrGRPCNoInflightDowngrade,
 
+	etcdserver.ErrHashedPasswordNotAllowed: rpctypes.ErrGRPCHashedPasswordNotAllowed,
+	etcdserver.ErrInvalidHashedPassword:    rpctypes.ErrGRPCInvalidHashedPassword,
+
 	lease.ErrLeaseNotFound:    rpctypes.ErrGRPCLeaseNotFound,
 	lease.ErrLeaseExists:      rpctypes.ErrGRPCLeaseExist,
 	lease.ErrLeaseTTLTooLarge: rpctypes.ErrGRPCLeaseTTLTooLarge,
@@ -132,7 +137,7 @@ func isClientCtxErr(ctxErr error, err error) bool {
 // in v3.4, learner is allowed to serve serializable read and endpoint status
 func isRPCSupportedForLearner(req interface{}) bool {
 	switch r := req.(type) {
-	case *pb.StatusRequest:
+	case *pb.StatusRequest, *pb.MaintenanceProgressRequest:
 		return true
 	case *pb.RangeRequest:
 		return r.Serializable
@@ -26,6 +26,7 @@ import (
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/v3/auth"
 	"go.etcd.io/etcd/v3/etcdserver"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
 	"go.etcd.io/etcd/v3/mvcc"
 
 	"go.uber.org/zap"
@@ -44,6 +45,8 @@ type watchServer struct {
 	sg        etcdserver.RaftStatusGetter
 	watchable mvcc.WatchableKV
 	ag        AuthGetter
+	// rl limits the rate of watch creations, if enabled.
+	rl *v3ratelimit.Limiter
 }
 
 // NewWatchServer returns a new watch server.
@@ -59,6 +62,7 @@ func NewWatchServer(s *etcdserver.EtcdServer) pb.WatchServer {
 		sg:        s,
 		watchable: s.Watchable(),
 		ag:        s,
+		rl:        s.RateLimiter(),
 	}
 	if srv.lg == nil {
 		srv.lg = zap.NewNop()
@@ -127,20 +131,28 @@ type serverWatchStream struct {
 	sg        etcdserver.RaftStatusGetter
 	watchable mvcc.WatchableKV
 	ag        AuthGetter
+	rl        *v3ratelimit.Limiter
 
 	gRPCStream  pb.Watch_WatchServer
 	watchStream mvcc.WatchStream
 	ctrlStream  chan *pb.WatchResponse
 
-	// mu protects progress, prevKV, fragment
+	// mu protects progress, prevKV, fragment, sendFilters, deferProgress
 	mu sync.RWMutex
 	// tracks the watchID that stream might need to send progress to
 	// TODO: combine progress and prevKV into a single struct?
 	progress map[mvcc.WatchID]bool
+	// deferProgress is set when a progress request waits for the watchers
+	// of the stream to be synced
+	deferProgress bool
 	// record watch IDs that need return previous key-value pair
 	prevKV map[mvcc.WatchID]bool
 	// records fragmented watch IDs
 	fragment map[mvcc.WatchID]bool
+	// sendFilters are the filters of the events run by the send loop rather
+	// than by the watchable store, as they are too costly to run under its
+	// lock
+	sendFilters map[mvcc.WatchID][]mvcc.FilterFunc
 
 	// closec indicates the stream is closed.
 	closec chan struct{}
@@ -161,15 +173,17 @@ func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
 		sg:        ws.sg,
 		watchable: ws.watchable,
 		ag:        ws.ag,
+		rl:        ws.rl,
 
 		gRPCStream:  stream,
 		watchStream: ws.watchable.NewWatchStream(),
 		// chan for sending control response like watcher created and canceled.
 		ctrlStream: make(chan *pb.WatchResponse, ctrlStreamBufLen),
 
-		progress: make(map[mvcc.WatchID]bool),
-		prevKV:   make(map[mvcc.WatchID]bool),
-		fragment: make(map[mvcc.WatchID]bool),
+		progress:    make(map[mvcc.WatchID]bool),
+		prevKV:      make(map[mvcc.WatchID]bool),
+		fragment:    make(map[mvcc.WatchID]bool),
+		sendFilters: make(map[mvcc.WatchID][]mvcc.FilterFunc),
 
 		closec: make(chan struct{}),
 	}
@@ -273,7 +287,30 @@ func (sws *serverWatchStream) recvLoop() error {
 				}
 			}
 
-			filters := FiltersFromRequest(creq)
+			filters, err := FiltersFromRequest(creq)
+			var sendFilters []mvcc.FilterFunc
+			if err == nil {
+				sendFilters, err = SendFiltersFromRequest(creq)
+			}
+			if err == nil && sws.rl != nil && !sws.rl.Allow(rateLimitClient(sws.gRPCStream.Context(), sws.ag), v3ratelimit.ClassWatch) {
+				err = rpctypes.ErrGRPCRateLimitExceeded
+			}
+			if err != nil {
+				wr := &pb.WatchResponse{
+					Header:       sws.newResponseHeader(sws.watchStream.Rev()),
+					WatchId:      creq.WatchId,
+					Canceled:     true,
+					Created:      true,
+					CancelReason: err.Error(),
+				}
+
+				select {
+				case sws.ctrlStream <- wr:
+					continue
+				case <-sws.closec:
+					return nil
+				}
+			}
 
 			wsrev := sws.watchStream.Rev()
 			rev := creq.StartRevision
@@ -292,6 +329,9 @@ func (sws *serverWatchStream) recvLoop() error {
 				if creq.Fragment {
 					sws.fragment[id] = true
 				}
+				if len(sendFilters) != 0 {
+					sws.sendFilters[id] = sendFilters
+				}
 				sws.mu.Unlock()
 			}
 			wr := &pb.WatchResponse{
@@ -323,15 +363,18 @@ func (sws *serverWatchStream) recvLoop() error {
 					delete(sws.progress, mvcc.WatchID(id))
 					delete(sws.prevKV, mvcc.WatchID(id))
 					delete(sws.fragment, mvcc.WatchID(id))
+					delete(sws.sendFilters, mvcc.WatchID(id))
 					sws.mu.Unlock()
 				}
 			}
 		case *pb.WatchRequest_ProgressRequest:
 			if uv.ProgressRequest != nil {
-				sws.ctrlStream <- &pb.WatchResponse{
-					Header:  sws.newResponseHeader(sws.watchStream.Rev()),
-					WatchId: -1, // response is not associated with any WatchId and will be broadcast to all watch channels
-				}
+				// the progress is sent with the events of the stream, once
+				// all its watchers are synced, so that it is not ahead of
+				// the events it reports
+				sws.mu.Lock()
+				sws.deferProgress = !sws.watchStream.RequestProgressAll()
+				sws.mu.Unlock()
 			}
 		default:
 			// we probably should not shutdown the entire stream when
@@ -399,7 +442,10 @@ func (sws *serverWatchStream) sendLoop() {
 				Canceled:        canceled,
 			}
 
-			if _, okID := ids[wresp.WatchID]; !okID {
+			// progress notifications of all the watchers are not
+			// associated with any WatchId and are broadcast to all watch
+			// channels by the client
+			if _, okID := ids[wresp.WatchID]; !okID && wresp.WatchID != mvcc.ProgressWatchID {
 				// buffer if id not yet announced
 				wrs := append(pending[wresp.WatchID], wr)
 				pending[wresp.WatchID] = wrs
@@ -407,6 +453,9 @@ func (sws *serverWatchStream) sendLoop() {
 			}
 
 			mvcc.ReportEventReceived(len(evs))
+			if !sws.filterResponse(wr) {
+				continue
+			}
 
 			sws.mu.RLock()
 			fragmented, ok := sws.fragment[wresp.WatchID]
@@ -434,6 +483,9 @@ func (sws *serverWatchStream) sendLoop() {
 				// elide next progress update if sent a key update
 				sws.progress[wresp.WatchID] = false
 			}
+			if sws.deferProgress {
+				sws.deferProgress = !sws.watchStream.RequestProgressAll()
+			}
 			sws.mu.Unlock()
 
 		case c, ok := <-sws.ctrlStream:
@@ -462,6 +514,9 @@ func (sws *serverWatchStream) sendLoop() {
 				ids[wid] = struct{}{}
 				for _, v := range pending[wid] {
 					mvcc.ReportEventReceived(len(v.Events))
+					if !sws.filterResponse(v) {
+						continue
+					}
 					if err := sws.gRPCStream.Send(v); err != nil {
 						if isClientCtxErr(sws.gRPCStream.Context().Err(), err) {
 							sws.lg.Debug("failed to send pending watch response to gRPC stream", zap.Error(err))
@@ -554,7 +609,10 @@ func filterNoPut(e mvccpb.Event) bool {
 }
 
 // FiltersFromRequest returns "mvcc.FilterFunc" from a given watch create request.
-func FiltersFromRequest(creq *pb.WatchCreateRequest) []mvcc.FilterFunc {
+// It returns rpctypes.ErrGRPCInvalidWatchFilter if a key or value filter is malformed.
+// The filters by key regular expression and JSON value are returned by
+// SendFiltersFromRequest instead.
+func FiltersFromRequest(creq *pb.WatchCreateRequest) ([]mvcc.FilterFunc, error) {
 	filters := make([]mvcc.FilterFunc, 0, len(creq.Filters))
 	for _, ft := range creq.Filters {
 		switch ft {
@@ -565,5 +623,21 @@ func FiltersFromRequest(creq *pb.WatchCreateRequest) []mvcc.FilterFunc {
 		default:
 		}
 	}
-	return filters
+	kvFilters, err := keyValueFiltersFromRequest(creq)
+	if err != nil {
+		return nil, rpctypes.ErrGRPCInvalidWatchFilter
+	}
+	return append(filters, kvFilters...), nil
+}
+
+// SendFiltersFromRequest returns the filters by key regular expression and
+// JSON value of a given watch create request, which are run on the events
+// before they are sent rather than by the watchable store. It returns
+// rpctypes.ErrGRPCInvalidWatchFilter if one of them is malformed.
+func SendFiltersFromRequest(creq *pb.WatchCreateRequest) ([]mvcc.FilterFunc, error) {
+	filters, err := sendFiltersFromRequest(creq)
+	if err != nil {
+		return nil, rpctypes.ErrGRPCInvalidWatchFilter
+	}
+	return filters, nil
 }
@@ -0,0 +1,166 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/v3/mvcc"
+)
+
+var errNoJSONPath = errors.New("value_json_equals is given without value_json_path")
+
+// keyValueFiltersFromRequest returns the filters of the events by key pattern
+// and value substring set in the watch create request.
+func keyValueFiltersFromRequest(creq *pb.WatchCreateRequest) ([]mvcc.FilterFunc, error) {
+	var filters []mvcc.FilterFunc
+	if creq.KeyPattern != "" {
+		if _, err := path.Match(creq.KeyPattern, ""); err != nil {
+			return nil, err
+		}
+		filters = append(filters, filterKeyPattern(creq.KeyPattern))
+	}
+	if len(creq.ValueContains) != 0 {
+		filters = append(filters, filterValueContains(creq.ValueContains))
+	}
+	return filters, nil
+}
+
+// sendFiltersFromRequest returns the filters of the events by key regular
+// expression and JSON value set in the watch create request.
+func sendFiltersFromRequest(creq *pb.WatchCreateRequest) ([]mvcc.FilterFunc, error) {
+	var filters []mvcc.FilterFunc
+	if creq.KeyRegex != "" {
+		re, err := regexp.Compile(creq.KeyRegex)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filterKeyRegex(re))
+	}
+	if creq.ValueJsonPath != "" {
+		var want interface{}
+		if len(creq.ValueJsonEquals) != 0 {
+			if err := json.Unmarshal(creq.ValueJsonEquals, &want); err != nil {
+				return nil, err
+			}
+		}
+		filters = append(filters, filterValueJSONPath(creq.ValueJsonPath, want, len(creq.ValueJsonEquals) != 0))
+	} else if len(creq.ValueJsonEquals) != 0 {
+		return nil, errNoJSONPath
+	}
+	return filters, nil
+}
+
+func filterKeyPattern(pattern string) mvcc.FilterFunc {
+	return func(e mvccpb.Event) bool {
+		ok, _ := path.Match(pattern, string(e.Kv.Key))
+		return !ok
+	}
+}
+
+func filterKeyRegex(re *regexp.Regexp) mvcc.FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return !re.Match(e.Kv.Key)
+	}
+}
+
+func filterValueContains(sub []byte) mvcc.FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.PUT && !bytes.Contains(e.Kv.Value, sub)
+	}
+}
+
+func filterValueJSONPath(jsonPath string, want interface{}, equals bool) mvcc.FilterFunc {
+	segs := strings.Split(jsonPath, ".")
+	return func(e mvccpb.Event) bool {
+		if e.Type != mvccpb.PUT {
+			return false
+		}
+		var doc interface{}
+		if err := json.Unmarshal(e.Kv.Value, &doc); err != nil {
+			return true
+		}
+		v, ok := jsonPathLookup(doc, segs)
+		if !ok {
+			return true
+		}
+		return equals && !reflect.DeepEqual(v, want)
+	}
+}
+
+// filterResponse removes the events of the response rejected by the send
+// filters of its watcher. It returns false if all the events of a response
+// that had some are removed, in which case the response is not sent.
+// Progress responses are left untouched.
+func (sws *serverWatchStream) filterResponse(wr *pb.WatchResponse) bool {
+	if wr.WatchId == int64(mvcc.ProgressWatchID) || len(wr.Events) == 0 {
+		return true
+	}
+	sws.mu.RLock()
+	filters := sws.sendFilters[mvcc.WatchID(wr.WatchId)]
+	sws.mu.RUnlock()
+	if len(filters) == 0 {
+		return true
+	}
+	events := make([]*mvccpb.Event, 0, len(wr.Events))
+	for _, ev := range wr.Events {
+		if !isFiltered(*ev, filters) {
+			events = append(events, ev)
+		}
+	}
+	wr.Events = events
+	return len(events) != 0 || wr.Canceled
+}
+
+func isFiltered(ev mvccpb.Event, filters []mvcc.FilterFunc) bool {
+	for _, filter := range filters {
+		if filter(ev) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonPathLookup returns the value at the path of segments into the decoded
+// JSON document doc. A segment indexes an array if doc holds one there.
+func jsonPathLookup(doc interface{}, segs []string) (interface{}, bool) {
+	for _, seg := range segs {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[seg]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
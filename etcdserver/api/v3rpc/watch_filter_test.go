@@ -0,0 +1,149 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/v3/mvcc"
+)
+
+func TestFiltersFromRequest(t *testing.T) {
+	put := func(key, val string) mvccpb.Event {
+		return mvccpb.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val)}}
+	}
+	del := func(key string) mvccpb.Event {
+		return mvccpb.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(key)}}
+	}
+	tests := []struct {
+		creq *pb.WatchCreateRequest
+
+		sent     []mvccpb.Event
+		filtered []mvccpb.Event
+	}{
+		{
+			&pb.WatchCreateRequest{KeyPattern: "/pods/*/status"},
+			[]mvccpb.Event{put("/pods/a/status", ""), del("/pods/b/status")},
+			[]mvccpb.Event{put("/pods/a/spec", ""), put("/pods/a/b/status", "")},
+		},
+		{
+			&pb.WatchCreateRequest{KeyRegex: "^/pods/[0-9]+$"},
+			[]mvccpb.Event{put("/pods/12", ""), del("/pods/3")},
+			[]mvccpb.Event{put("/pods/a", ""), put("/pods/12/x", "")},
+		},
+		{
+			&pb.WatchCreateRequest{ValueContains: []byte("error")},
+			[]mvccpb.Event{put("a", "an error"), del("a")},
+			[]mvccpb.Event{put("a", "ok")},
+		},
+		{
+			&pb.WatchCreateRequest{ValueJsonPath: "status.replicas"},
+			[]mvccpb.Event{put("a", `{"status":{"replicas":0}}`), del("a")},
+			[]mvccpb.Event{put("a", `{"status":{}}`), put("a", `not json`)},
+		},
+		{
+			&pb.WatchCreateRequest{ValueJsonPath: "items.1.phase", ValueJsonEquals: []byte(`"Running"`)},
+			[]mvccpb.Event{put("a", `{"items":[{},{"phase":"Running"}]}`)},
+			[]mvccpb.Event{put("a", `{"items":[{"phase":"Running"}]}`), put("a", `{"items":[{},{"phase":"Failed"}]}`)},
+		},
+		{
+			&pb.WatchCreateRequest{KeyPattern: "a*", ValueContains: []byte("x"), Filters: []pb.WatchCreateRequest_FilterType{pb.WatchCreateRequest_NODELETE}},
+			[]mvccpb.Event{put("ab", "x")},
+			[]mvccpb.Event{put("ba", "x"), put("ab", "y"), del("ab")},
+		},
+	}
+	for i, tt := range tests {
+		filters, err := FiltersFromRequest(tt.creq)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		sendFilters, err := SendFiltersFromRequest(tt.creq)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error %v", i, err)
+		}
+		filters = append(filters, sendFilters...)
+		for _, ev := range tt.sent {
+			if isFiltered(ev, filters) {
+				t.Errorf("#%d: event %v filtered out", i, ev)
+			}
+		}
+		for _, ev := range tt.filtered {
+			if !isFiltered(ev, filters) {
+				t.Errorf("#%d: event %v not filtered out", i, ev)
+			}
+		}
+	}
+}
+
+func TestFiltersFromRequestInvalid(t *testing.T) {
+	creqs := []*pb.WatchCreateRequest{
+		{KeyPattern: "[a"},
+		{KeyRegex: "(a"},
+		{ValueJsonPath: "a", ValueJsonEquals: []byte("{")},
+		{ValueJsonEquals: []byte("1")},
+	}
+	for i, creq := range creqs {
+		_, err := FiltersFromRequest(creq)
+		if err == nil {
+			_, err = SendFiltersFromRequest(creq)
+		}
+		if err == nil {
+			t.Errorf("#%d: expected error for %+v", i, creq)
+		}
+	}
+}
+
+func TestFiltersFromRequestSendFilters(t *testing.T) {
+	creq := &pb.WatchCreateRequest{KeyRegex: "^a$", ValueJsonPath: "x"}
+	if filters, err := FiltersFromRequest(creq); err != nil || len(filters) != 0 {
+		t.Fatalf("expected no filters run by the watchable store, got %d (%v)", len(filters), err)
+	}
+	if filters, err := SendFiltersFromRequest(creq); err != nil || len(filters) != 2 {
+		t.Fatalf("expected 2 filters run by the send loop, got %d (%v)", len(filters), err)
+	}
+}
+
+func TestFilterResponse(t *testing.T) {
+	sendFilters, err := SendFiltersFromRequest(&pb.WatchCreateRequest{KeyRegex: "^a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sws := &serverWatchStream{sendFilters: map[mvcc.WatchID][]mvcc.FilterFunc{1: sendFilters}}
+	put := func(key string) *mvccpb.Event {
+		return &mvccpb.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key)}}
+	}
+
+	wr := &pb.WatchResponse{WatchId: 1, Events: []*mvccpb.Event{put("ab"), put("b"), put("ac")}}
+	if !sws.filterResponse(wr) || len(wr.Events) != 2 || string(wr.Events[1].Kv.Key) != "ac" {
+		t.Fatalf("expected the events of b to be removed, got %v", wr.Events)
+	}
+	if wr = (&pb.WatchResponse{WatchId: 1, Events: []*mvccpb.Event{put("b")}}); sws.filterResponse(wr) {
+		t.Fatal("expected a response without events left not to be sent")
+	}
+	if wr = (&pb.WatchResponse{WatchId: 1, Events: []*mvccpb.Event{put("b")}, Canceled: true, CompactRevision: 5}); !sws.filterResponse(wr) {
+		t.Fatal("expected a canceled response to be sent")
+	}
+	if wr = (&pb.WatchResponse{WatchId: 1}); !sws.filterResponse(wr) {
+		t.Fatal("expected a progress response of the watcher to be sent")
+	}
+	if wr = (&pb.WatchResponse{WatchId: int64(mvcc.ProgressWatchID)}); !sws.filterResponse(wr) {
+		t.Fatal("expected a progress response of the stream to be sent")
+	}
+	if wr = (&pb.WatchResponse{WatchId: 2, Events: []*mvccpb.Event{put("b")}}); !sws.filterResponse(wr) || len(wr.Events) != 1 {
+		t.Fatal("expected the events of a watcher without send filters to be kept")
+	}
+}
@@ -75,6 +75,8 @@ type applierV3 interface {
 
 	Alarm(*pb.AlarmRequest) (*pb.AlarmResponse, error)
 
+	PolicyPut(*pb.PolicyPutRequest) (*pb.PolicyPutResponse, error)
+
 	Authenticate(r *pb.InternalAuthenticateRequest) (*pb.AuthenticateResponse, error)
 
 	AuthEnable() (*pb.AuthEnableResponse, error)
@@ -84,6 +86,7 @@ type applierV3 interface {
 	UserAdd(ua *pb.AuthUserAddRequest) (*pb.AuthUserAddResponse, error)
 	UserDelete(ua *pb.AuthUserDeleteRequest) (*pb.AuthUserDeleteResponse, error)
 	UserChangePassword(ua *pb.AuthUserChangePasswordRequest) (*pb.AuthUserChangePasswordResponse, error)
+	UserTokenRevoke(ua *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error)
 	UserGrantRole(ua *pb.AuthUserGrantRoleRequest) (*pb.AuthUserGrantRoleResponse, error)
 	UserGet(ua *pb.AuthUserGetRequest) (*pb.AuthUserGetResponse, error)
 	UserRevokeRole(ua *pb.AuthUserRevokeRoleRequest) (*pb.AuthUserRevokeRoleResponse, error)
@@ -124,7 +127,7 @@ func (s *EtcdServer) newApplierV3Internal() applierV3Internal {
 func (s *EtcdServer) newApplierV3() applierV3 {
 	return newAuthApplierV3(
 		s.AuthStore(),
-		newQuotaApplierV3(s, s.newApplierV3Backend()),
+		newQuotaApplierV3(s, newPrefixQuotaApplierV3(s, s.newApplierV3Backend())),
 		s.lessor,
 	)
 }
@@ -156,8 +159,13 @@ func (a *applierV3backend) Apply(r *pb.InternalRaftRequest) *applyResult {
 		ar.resp, ar.err = a.s.applyV3.LeaseRevoke(r.LeaseRevoke)
 	case r.LeaseCheckpoint != nil:
 		ar.resp, ar.err = a.s.applyV3.LeaseCheckpoint(r.LeaseCheckpoint)
+	case r.KeyExpiry != nil:
+		// key expiry is internal to the server; skip the auth and quota checks
+		ar.resp, ar.trace, ar.err = a.s.applyV3Base.Txn(context.TODO(), r.KeyExpiry)
 	case r.Alarm != nil:
 		ar.resp, ar.err = a.s.applyV3.Alarm(r.Alarm)
+	case r.PolicyPut != nil:
+		ar.resp, ar.err = a.s.applyV3.PolicyPut(r.PolicyPut)
 	case r.Authenticate != nil:
 		ar.resp, ar.err = a.s.applyV3.Authenticate(r.Authenticate)
 	case r.AuthEnable != nil:
@@ -172,6 +180,8 @@ func (a *applierV3backend) Apply(r *pb.InternalRaftRequest) *applyResult {
 		ar.resp, ar.err = a.s.applyV3.UserDelete(r.AuthUserDelete)
 	case r.AuthUserChangePassword != nil:
 		ar.resp, ar.err = a.s.applyV3.UserChangePassword(r.AuthUserChangePassword)
+	case r.AuthUserTokenRevoke != nil:
+		ar.resp, ar.err = a.s.applyV3.UserTokenRevoke(r.AuthUserTokenRevoke)
 	case r.AuthUserGrantRole != nil:
 		ar.resp, ar.err = a.s.applyV3.UserGrantRole(r.AuthUserGrantRole)
 	case r.AuthUserGet != nil:
@@ -255,7 +265,7 @@ func (a *applierV3backend) Put(ctx context.Context, txn mvcc.TxnWrite, p *pb.Put
 		}
 	}
 
-	resp.Header.Revision = txn.Put(p.Key, val, leaseID)
+	resp.Header.Revision = txn.PutWithTTL(p.Key, val, leaseID, p.Ttl)
 	trace.AddField(traceutil.Field{Key: "response_revision", Value: resp.Header.Revision})
 	return resp, trace, nil
 }
@@ -381,10 +391,33 @@ func (a *applierV3backend) Range(ctx context.Context, txn mvcc.TxnRead, r *pb.Ra
 		}
 		resp.Kvs[i] = &rr.KVs[i]
 	}
+	if r.ShowDeleted && r.Revision > 0 {
+		resp.DeletedKeys, err = deletedKeys(txn, resp.Kvs)
+		if err != nil {
+			return nil, err
+		}
+		trace.Step("find the deleted keys")
+	}
 	trace.Step("assemble the response")
 	return resp, nil
 }
 
+// deletedKeys returns the keys of kvs that do not exist at the current
+// revision of txn.
+func deletedKeys(txn mvcc.TxnRead, kvs []*mvccpb.KeyValue) ([][]byte, error) {
+	var deleted [][]byte
+	for _, kv := range kvs {
+		rr, err := txn.Range(kv.Key, nil, mvcc.RangeOptions{Count: true})
+		if err != nil {
+			return nil, err
+		}
+		if rr.Count == 0 {
+			deleted = append(deleted, kv.Key)
+		}
+	}
+	return deleted, nil
+}
+
 func (a *applierV3backend) Txn(ctx context.Context, rt *pb.TxnRequest) (*pb.TxnResponse, *traceutil.Trace, error) {
 	trace := traceutil.Get(ctx)
 	if trace.IsEmpty() {
@@ -687,6 +720,11 @@ func (a *applierV3backend) Alarm(ar *pb.AlarmRequest) (*pb.AlarmResponse, error)
 			a.s.applyV3 = newApplierV3Corrupt(a)
 		case pb.AlarmType_NOSPACE:
 			a.s.applyV3 = newApplierV3Capped(a)
+		case pb.AlarmType_PREFIX_QUOTA:
+			// writes over a prefix quota are rejected whether or not the
+			// alarm is raised, and other prefixes are not affected; the
+			// alarm is disarmed once the prefixes are back under their
+			// quotas
 		default:
 			lg.Warn("unimplemented alarm activation", zap.String("alarm", fmt.Sprintf("%+v", m)))
 		}
@@ -706,6 +744,8 @@ func (a *applierV3backend) Alarm(ar *pb.AlarmRequest) (*pb.AlarmResponse, error)
 			// TODO: check kv hash before deactivating CORRUPT?
 			lg.Warn("alarm disarmed", zap.String("alarm", m.Alarm.String()), zap.String("from", types.ID(m.MemberID).String()))
 			a.s.applyV3 = a.s.newApplierV3()
+		case pb.AlarmType_PREFIX_QUOTA:
+			lg.Warn("alarm disarmed", zap.String("alarm", m.Alarm.String()), zap.String("from", types.ID(m.MemberID).String()))
 		default:
 			lg.Warn("unimplemented alarm deactivation", zap.String("alarm", fmt.Sprintf("%+v", m)))
 		}
@@ -791,6 +831,14 @@ func (a *applierV3backend) UserChangePassword(r *pb.AuthUserChangePasswordReques
 	return resp, err
 }
 
+func (a *applierV3backend) UserTokenRevoke(r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error) {
+	resp, err := a.s.AuthStore().UserTokenRevoke(r)
+	if resp != nil {
+		resp.Header = newHeader(a.s)
+	}
+	return resp, err
+}
+
 func (a *applierV3backend) UserGrantRole(r *pb.AuthUserGrantRoleRequest) (*pb.AuthUserGrantRoleResponse, error) {
 	resp, err := a.s.AuthStore().UserGrantRole(r)
 	if resp != nil {
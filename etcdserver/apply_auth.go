@@ -143,6 +143,15 @@ func checkTxnReqsPermission(as auth.AuthStore, ai *auth.AuthInfo, reqs []*pb.Req
 			if err != nil {
 				return err
 			}
+
+		case *pb.RequestOp_RequestTxn:
+			if tv.RequestTxn == nil {
+				continue
+			}
+
+			if err := checkTxnAuth(as, ai, tv.RequestTxn); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -199,6 +208,20 @@ func (aa *authApplierV3) UserGet(r *pb.AuthUserGetRequest) (*pb.AuthUserGetRespo
 	return aa.applierV3.UserGet(r)
 }
 
+func (aa *authApplierV3) UserTokenRevoke(r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error) {
+	if r.Name == "" {
+		r = &pb.AuthUserTokenRevokeRequest{Name: aa.authInfo.Username}
+	}
+	err := aa.as.IsAdminPermitted(&aa.authInfo)
+	if err != nil && (r.Name == "" || r.Name != aa.authInfo.Username) {
+		aa.authInfo.Username = ""
+		aa.authInfo.Revision = 0
+		return &pb.AuthUserTokenRevokeResponse{}, err
+	}
+
+	return aa.applierV3.UserTokenRevoke(r)
+}
+
 func (aa *authApplierV3) RoleGet(r *pb.AuthRoleGetRequest) (*pb.AuthRoleGetResponse, error) {
 	err := aa.as.IsAdminPermitted(&aa.authInfo)
 	if err != nil && !aa.as.HasRole(aa.authInfo.Username, r.Role) {
@@ -240,6 +263,8 @@ func needAdminPermission(r *pb.InternalRaftRequest) bool {
 		return true
 	case r.AuthRoleList != nil:
 		return true
+	case r.PolicyPut != nil:
+		return true
 	default:
 		return false
 	}
@@ -25,6 +25,7 @@ import (
 	"go.etcd.io/etcd/pkg/v3/netutil"
 	"go.etcd.io/etcd/pkg/v3/transport"
 	"go.etcd.io/etcd/pkg/v3/types"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3audit"
 
 	bolt "go.etcd.io/bbolt"
 	"go.uber.org/zap"
@@ -128,6 +129,9 @@ type ServerConfig struct {
 	BcryptCost uint
 	TokenTTL   uint
 
+	// AuthAllowHashedPassword accepts passwords hashed by the client.
+	AuthAllowHashedPassword bool
+
 	// InitialCorruptCheck is true to check data corruption on boot
 	// before serving any peer/client traffic.
 	InitialCorruptCheck bool
@@ -159,6 +163,21 @@ type ServerConfig struct {
 
 	WatchProgressNotifyInterval time.Duration
 
+	// EnableMaintenanceScheduler enables the compactions and defragmentations
+	// scheduled by the maintenance policy stored in the keyspace.
+	EnableMaintenanceScheduler bool
+
+	// PrefixQuotas limit the size and number of the keys under prefixes.
+	PrefixQuotas []PrefixQuota
+
+	// AuditLog configures the audit log of the mutating and auth requests.
+	// Nil disables it.
+	AuditLog *v3audit.Config
+
+	// EnableRateLimit enables the rate limits of the client requests
+	// following the rate limit policy stored in the keyspace.
+	EnableRateLimit bool
+
 	// UnsafeNoFsync disables all uses of fsync.
 	// Setting this is unsafe and will cause data loss.
 	UnsafeNoFsync bool `json:"unsafe-no-fsync"`
@@ -34,6 +34,7 @@ var (
 	ErrNotLeader                     = errors.New("etcdserver: not leader")
 	ErrRequestTooLarge               = errors.New("etcdserver: request is too large")
 	ErrNoSpace                       = errors.New("etcdserver: no space")
+	ErrPrefixQuotaExceeded           = errors.New("etcdserver: key prefix quota exceeded")
 	ErrTooManyRequests               = errors.New("etcdserver: too many requests")
 	ErrUnhealthy                     = errors.New("etcdserver: unhealthy cluster")
 	ErrKeyNotFound                   = errors.New("etcdserver: key not found")
@@ -44,6 +45,8 @@ var (
 	ErrInvalidDowngradeTargetVersion = errors.New("etcdserver: invalid downgrade target version")
 	ErrDowngradeInProcess            = errors.New("etcdserver: cluster has a downgrade job in progress")
 	ErrNoInflightDowngrade           = errors.New("etcdserver: no inflight downgrade job")
+	ErrHashedPasswordNotAllowed      = errors.New("etcdserver: client hashed password is not allowed")
+	ErrInvalidHashedPassword         = errors.New("etcdserver: invalid or too weak hashed password")
 )
 
 type DiscoveryError struct {
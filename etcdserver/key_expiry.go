@@ -0,0 +1,131 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	"github.com/coreos/go-semver/semver"
+	"go.uber.org/zap"
+)
+
+var (
+	// keyExpiryInterval is how often the leader checks for keys whose ttl elapsed.
+	keyExpiryInterval = 500 * time.Millisecond
+	// keyExpiryBatchLimit is the maximum number of keys deleted per proposal.
+	keyExpiryBatchLimit = 1000
+
+	// keyExpiryMinClusterVersion is the cluster version from which members
+	// know how to apply key expiry requests.
+	keyExpiryMinClusterVersion = semver.Version{Major: 3, Minor: 5}
+)
+
+// expireKeys proposes the deletion of the keys whose ttl elapsed while the
+// member is the leader. The deadlines are tracked by the local mvcc store,
+// so only the leader acts on them and the deletion is agreed through raft.
+func (s *EtcdServer) expireKeys() {
+	lg := s.getLogger()
+	t := time.NewTicker(keyExpiryInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-s.stopping:
+			return
+		}
+
+		if !s.isLeader() {
+			continue
+		}
+		if !s.KeyExpiryEnabled() {
+			continue
+		}
+
+		for {
+			eks := s.KV().Expired(keyExpiryBatchLimit)
+			if len(eks) == 0 {
+				break
+			}
+			ctx, cancel := context.WithTimeout(s.ctx, s.Cfg.ReqTimeout())
+			n, err := s.keyExpiry(ctx, eks)
+			cancel()
+			if err != nil {
+				lg.Warn("failed to delete expired keys", zap.Int("keys", len(eks)), zap.Error(err))
+				break
+			}
+			keysExpired.Add(float64(n))
+			if len(eks) < keyExpiryBatchLimit {
+				break
+			}
+		}
+	}
+}
+
+// KeyExpiryEnabled reports whether the cluster version supports key ttls.
+func (s *EtcdServer) KeyExpiryEnabled() bool {
+	v := s.ClusterVersion()
+	return v != nil && !v.LessThan(keyExpiryMinClusterVersion)
+}
+
+// keyExpiry deletes the given expired keys, skipping those modified since
+// they expired, and returns the number of keys deleted.
+func (s *EtcdServer) keyExpiry(ctx context.Context, eks []mvcc.ExpiredKey) (int, error) {
+	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{KeyExpiry: newKeyExpiryTxn(eks)})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range resp.(*pb.TxnResponse).Responses {
+		if r.GetResponseTxn().Succeeded {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// newKeyExpiryTxn returns a txn holding one txn per expired key, which
+// deletes the key if its mod revision did not change since it expired.
+func newKeyExpiryTxn(eks []mvcc.ExpiredKey) *pb.TxnRequest {
+	ops := make([]*pb.RequestOp, len(eks))
+	for i, ek := range eks {
+		ops[i] = &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{
+			Compare: []*pb.Compare{{
+				Key:         ek.Key,
+				Target:      pb.Compare_MOD,
+				Result:      pb.Compare_EQUAL,
+				TargetUnion: &pb.Compare_ModRevision{ModRevision: ek.ModRevision},
+			}},
+			Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestDeleteRange{
+				RequestDeleteRange: &pb.DeleteRangeRequest{Key: ek.Key},
+			}}},
+		}}}
+	}
+	return &pb.TxnRequest{Success: ops}
+}
+
+// expiryKeys returns the keys the key expiry txn rt may delete.
+func expiryKeys(rt *pb.TxnRequest) [][]byte {
+	keys := make([][]byte, 0, len(rt.Success))
+	for _, op := range rt.Success {
+		for _, dop := range op.GetRequestTxn().Success {
+			keys = append(keys, dop.GetRequestDeleteRange().Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/v3/mvcc"
+)
+
+func TestKeyExpiryTxn(t *testing.T) {
+	eks := []mvcc.ExpiredKey{
+		{Key: []byte("foo"), ModRevision: 2},
+		{Key: []byte("bar"), ModRevision: 5},
+	}
+	rt := newKeyExpiryTxn(eks)
+	if len(rt.Compare) != 0 || len(rt.Success) != len(eks) {
+		t.Fatalf("unexpected txn %+v", rt)
+	}
+	for i, op := range rt.Success {
+		txn := op.GetRequestTxn()
+		if len(txn.Compare) != 1 || txn.Compare[0].GetModRevision() != eks[i].ModRevision {
+			t.Fatalf("#%d: unexpected compare %+v", i, txn.Compare)
+		}
+	}
+	wkeys := [][]byte{[]byte("foo"), []byte("bar")}
+	if keys := expiryKeys(rt); !reflect.DeepEqual(keys, wkeys) {
+		t.Fatalf("keys = %q, want %q", keys, wkeys)
+	}
+}
@@ -124,6 +124,12 @@ var (
 		Name:      "lease_expired_total",
 		Help:      "The total number of expired leases.",
 	})
+	keysExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "server",
+		Name:      "keys_expired_total",
+		Help:      "The total number of keys deleted because their ttl elapsed.",
+	})
 	quotaBackendBytes = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -137,6 +143,34 @@ var (
 		Help:      "Which version is running. 1 for 'server_version' label with current version.",
 	},
 		[]string{"server_version"})
+	prefixQuotaBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "prefix_quota_bytes",
+		Help:      "Maximum size in bytes of the keys and values under a key prefix, 0 for no limit.",
+	},
+		[]string{"prefix"})
+	prefixQuotaKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "prefix_quota_keys",
+		Help:      "Maximum number of keys under a key prefix, 0 for no limit.",
+	},
+		[]string{"prefix"})
+	prefixQuotaUsedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "prefix_quota_used_bytes",
+		Help:      "Size in bytes of the keys and values under a key prefix with a quota, as of the last write to the prefix.",
+	},
+		[]string{"prefix"})
+	prefixQuotaUsedKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "prefix_quota_used_keys",
+		Help:      "Number of keys under a key prefix with a quota, as of the last write to the prefix.",
+	},
+		[]string{"prefix"})
 	currentGoVersion = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -180,7 +214,12 @@ func init() {
 	prometheus.MustRegister(slowReadIndex)
 	prometheus.MustRegister(readIndexFailed)
 	prometheus.MustRegister(leaseExpired)
+	prometheus.MustRegister(keysExpired)
 	prometheus.MustRegister(quotaBackendBytes)
+	prometheus.MustRegister(prefixQuotaBytes)
+	prometheus.MustRegister(prefixQuotaKeys)
+	prometheus.MustRegister(prefixQuotaUsedBytes)
+	prometheus.MustRegister(prefixQuotaUsedKeys)
 	prometheus.MustRegister(currentVersion)
 	prometheus.MustRegister(currentGoVersion)
 	prometheus.MustRegister(serverID)
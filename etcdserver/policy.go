@@ -0,0 +1,87 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"strings"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3maintenance"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+)
+
+// policyBucketName is the backend bucket of the policies shared by the
+// members of the cluster. Like the auth and membership data, the policies
+// are kept out of the key space, so that they are only written by root
+// through the PolicyPut RPC.
+var policyBucketName = []byte("policy")
+
+func policyKey(t pb.PolicyType) []byte {
+	return []byte(strings.ToLower(t.String()))
+}
+
+func createPolicyBucket(be backend.Backend) {
+	tx := be.BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	tx.UnsafeCreateBucket(policyBucketName)
+}
+
+// ValidatePolicy returns an error if the policy of the request cannot be
+// stored. An empty policy is valid, as it removes the stored one.
+func ValidatePolicy(r *pb.PolicyPutRequest) error {
+	if _, ok := pb.PolicyType_name[int32(r.Type)]; !ok {
+		return fmt.Errorf("unknown policy type %d", r.Type)
+	}
+	if len(r.Policy) == 0 {
+		return nil
+	}
+	switch r.Type {
+	case pb.PolicyType_MAINTENANCE:
+		_, err := v3maintenance.ParsePolicy(r.Policy)
+		return err
+	case pb.PolicyType_RATE_LIMIT:
+		_, err := v3ratelimit.ParsePolicy(r.Policy)
+		return err
+	}
+	return nil
+}
+
+// Policy returns the stored policy of the given type, or nil if there is
+// none.
+func (s *EtcdServer) Policy(t pb.PolicyType) []byte {
+	tx := s.Backend().BatchTx()
+	tx.Lock()
+	defer tx.Unlock()
+	_, vs := tx.UnsafeRange(policyBucketName, policyKey(t), nil, 0)
+	if len(vs) == 0 {
+		return nil
+	}
+	return append([]byte(nil), vs[0]...)
+}
+
+func (a *applierV3backend) PolicyPut(r *pb.PolicyPutRequest) (*pb.PolicyPutResponse, error) {
+	tx := a.s.Backend().BatchTx()
+	tx.Lock()
+	if len(r.Policy) == 0 {
+		tx.UnsafeDelete(policyBucketName, policyKey(r.Type))
+	} else {
+		tx.UnsafePut(policyBucketName, policyKey(r.Type), r.Policy)
+	}
+	tx.Unlock()
+	return &pb.PolicyPutResponse{Header: newHeader(a.s)}, nil
+}
@@ -0,0 +1,695 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/pkg/v3/types"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+
+	humanize "github.com/dustin/go-humanize"
+	"go.uber.org/zap"
+)
+
+// PrefixQuota limits the size and the number of the keys under a prefix,
+// so that the clients of a prefix cannot use up the backend quota shared
+// by all prefixes. A key under several prefixes counts against each.
+type PrefixQuota struct {
+	Prefix string
+	// MaxBytes is the maximum size of the keys and values under Prefix,
+	// 0 for no limit.
+	MaxBytes int64
+	// MaxKeys is the maximum number of keys under Prefix, 0 for no limit.
+	MaxKeys int64
+}
+
+// ParsePrefixQuotas parses a comma separated list of prefix quotas, each of
+// the form "prefix=bytes[:keys]", such as "/tenant-a/=1GB:10000". Bytes
+// may be given with a unit, and 0 is no limit.
+func ParsePrefixQuotas(s string) ([]PrefixQuota, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var qs []PrefixQuota
+	for _, v := range strings.Split(s, ",") {
+		i := strings.LastIndex(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid prefix quota %q, want prefix=bytes[:keys]", v)
+		}
+		q := PrefixQuota{Prefix: v[:i]}
+		limits := strings.SplitN(v[i+1:], ":", 2)
+		b, err := humanize.ParseBytes(limits[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bytes of prefix quota %q (%v)", v, err)
+		}
+		q.MaxBytes = int64(b)
+		if len(limits) == 2 {
+			if q.MaxKeys, err = strconv.ParseInt(limits[1], 10, 64); err != nil || q.MaxKeys < 0 {
+				return nil, fmt.Errorf("invalid keys of prefix quota %q", v)
+			}
+		}
+		qs = append(qs, q)
+	}
+	return qs, nil
+}
+
+// prefixUsage is the usage of a prefix quota.
+type prefixUsage struct {
+	quota PrefixQuota
+	end   []byte
+
+	bytes int64
+	keys  int64
+	// stale is true if the usage must be counted again from the store,
+	// once the store is opened or restored from a snapshot.
+	stale bool
+	// rejected is true if a write to the prefix was rejected by the member
+	// since the prefix was last back under its quota.
+	rejected bool
+}
+
+func (u *prefixUsage) contains(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(u.quota.Prefix))
+}
+
+// intersects returns true if the range [key, end) has keys of the prefix.
+func (u *prefixUsage) intersects(key, end []byte) bool {
+	if len(end) == 0 {
+		return u.contains(key)
+	}
+	if len(u.end) > 0 && bytes.Compare(key, u.end) >= 0 {
+		return false
+	}
+	// an end of "\x00" is the end of the keyspace
+	return (len(end) == 1 && end[0] == 0) || bytes.Compare([]byte(u.quota.Prefix), end) < 0
+}
+
+func (u *prefixUsage) set(bytes, keys int64) {
+	u.bytes, u.keys = bytes, keys
+	prefixQuotaUsedBytes.WithLabelValues(u.quota.Prefix).Set(float64(bytes))
+	prefixQuotaUsedKeys.WithLabelValues(u.quota.Prefix).Set(float64(keys))
+}
+
+// count counts the usage of the prefix from rv.
+func (u *prefixUsage) count(rv mvcc.ReadView) (bytes, keys int64, err error) {
+	rr, err := rv.Range([]byte(u.quota.Prefix), u.end, mvcc.RangeOptions{})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, kv := range rr.KVs {
+		bytes += int64(len(kv.Key) + len(kv.Value))
+	}
+	return bytes, int64(len(rr.KVs)), nil
+}
+
+// over returns true if the usage of the prefix is over its quota.
+func (u *prefixUsage) over() bool {
+	q := u.quota
+	return (q.MaxBytes > 0 && u.bytes > q.MaxBytes) || (q.MaxKeys > 0 && u.keys > q.MaxKeys)
+}
+
+// exceeds returns true if a request changing the usage of the prefix by d
+// leaves it over its quota with the given bytes and keys. A request that
+// does not grow the prefix is accepted even if the prefix is over its
+// quota, so that its keys can be shrunk.
+func (u *prefixUsage) exceeds(d usageDelta, bytes, keys int64) bool {
+	q := u.quota
+	return (d.bytes > 0 && q.MaxBytes > 0 && bytes > q.MaxBytes) ||
+		(d.keys > 0 && q.MaxKeys > 0 && keys > q.MaxKeys)
+}
+
+// usageDelta is the change of usage of a prefix quota by a request.
+type usageDelta struct {
+	bytes int64
+	keys  int64
+}
+
+// prefixQuotas tracks the usage of the prefix quotas of a server, as the
+// requests are applied. Writes are checked against the quotas before they
+// are proposed, then again before they are applied. As the usage is
+// counted from the store every member applies the same requests to, a
+// write over a quota is rejected with ErrPrefixQuotaExceeded by every
+// member, without being applied.
+//
+// The usage of a prefix is counted from the store when first needed, then
+// updated by the size of each key put to or deleted from the prefix, as
+// given by the previous key-value pairs of the applied requests.
+type prefixQuotas struct {
+	lg *zap.Logger
+
+	mu     sync.Mutex
+	usages []*prefixUsage
+}
+
+func newPrefixQuotas(lg *zap.Logger, qs []PrefixQuota) *prefixQuotas {
+	pq := &prefixQuotas{lg: lg}
+	for _, q := range qs {
+		// the end of the keyspace if the prefix has no end
+		end := []byte{}
+		for i := len(q.Prefix) - 1; i >= 0; i-- {
+			if q.Prefix[i] < 0xff {
+				end = append([]byte(q.Prefix[:i]), q.Prefix[i]+1)
+				break
+			}
+		}
+		pq.usages = append(pq.usages, &prefixUsage{quota: q, end: end, stale: true})
+		prefixQuotaBytes.WithLabelValues(q.Prefix).Set(float64(q.MaxBytes))
+		prefixQuotaKeys.WithLabelValues(q.Prefix).Set(float64(q.MaxKeys))
+	}
+	return pq
+}
+
+func (pq *prefixQuotas) deltas() []usageDelta { return make([]usageDelta, len(pq.usages)) }
+
+// intersects returns true if the range [key, end) has keys of a prefix with
+// a quota.
+func (pq *prefixQuotas) intersects(key, end []byte) bool {
+	for _, u := range pq.usages {
+		if u.intersects(key, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// put adds the change of usage by a put of key with value to deltas,
+// where prev is the key-value pair it replaced, if any.
+func (pq *prefixQuotas) put(deltas []usageDelta, key, value []byte, prev *mvccpb.KeyValue) {
+	for i, u := range pq.usages {
+		if !u.contains(key) {
+			continue
+		}
+		deltas[i].bytes += int64(len(key) + len(value))
+		if prev == nil {
+			deltas[i].keys++
+			continue
+		}
+		deltas[i].bytes -= int64(len(prev.Key) + len(prev.Value))
+	}
+}
+
+// remove adds the change of usage by the delete of kv to deltas.
+func (pq *prefixQuotas) remove(deltas []usageDelta, kv *mvccpb.KeyValue) {
+	for i, u := range pq.usages {
+		if u.contains(kv.Key) {
+			deltas[i].bytes -= int64(len(kv.Key) + len(kv.Value))
+			deltas[i].keys--
+		}
+	}
+}
+
+// exceeds returns true if the puts and deletes of ops applied on rv would
+// take a prefix over its quota. Before a write is proposed, it is only an
+// estimate, as rv may be behind the requests yet to be applied.
+func (pq *prefixQuotas) exceeds(rv mvcc.ReadView, ops []*pb.RequestOp) (bool, error) {
+	deltas := pq.deltas()
+	for _, op := range ops {
+		switch tv := op.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			p := tv.RequestPut
+			if !pq.intersects(p.Key, nil) {
+				continue
+			}
+			rr, err := rv.Range(p.Key, nil, mvcc.RangeOptions{})
+			if err != nil {
+				return false, err
+			}
+			var prev *mvccpb.KeyValue
+			value := p.Value
+			if len(rr.KVs) > 0 {
+				prev = &rr.KVs[0]
+				if p.IgnoreValue {
+					value = prev.Value
+				}
+			}
+			pq.put(deltas, p.Key, value, prev)
+		case *pb.RequestOp_RequestDeleteRange:
+			dr := tv.RequestDeleteRange
+			if !pq.intersects(dr.Key, dr.RangeEnd) {
+				continue
+			}
+			rr, err := rv.Range(dr.Key, mkGteRange(dr.RangeEnd), mvcc.RangeOptions{})
+			if err != nil {
+				return false, err
+			}
+			for i := range rr.KVs {
+				pq.remove(deltas, &rr.KVs[i])
+			}
+		}
+	}
+
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	for i, u := range pq.usages {
+		d := deltas[i]
+		if d == (usageDelta{}) {
+			continue
+		}
+		bytes, keys := u.bytes, u.keys
+		if u.stale {
+			var err error
+			if bytes, keys, err = u.count(rv); err != nil {
+				return false, err
+			}
+		}
+		if u.exceeds(d, bytes+d.bytes, keys+d.keys) {
+			u.rejected = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// add adds the deltas of a request applied on kv to the usage of the
+// prefix quotas. A prefix that rejected a write is back under its quota
+// once a request shrinks it within its quota; add returns true if the
+// request took the last of them back under its quota.
+func (pq *prefixQuotas) add(kv mvcc.KV, deltas []usageDelta) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	var back bool
+	for i, u := range pq.usages {
+		d := deltas[i]
+		if d == (usageDelta{}) {
+			continue
+		}
+		if u.stale {
+			// the store already holds the request
+			rv := kv.Read(traceutil.TODO())
+			bytes, keys, cerr := u.count(rv)
+			rv.End()
+			if cerr != nil {
+				pq.lg.Warn("failed to count prefix quota usage", zap.String("prefix", u.quota.Prefix), zap.Error(cerr))
+				continue
+			}
+			u.set(bytes, keys)
+			u.stale = false
+		} else {
+			u.set(u.bytes+d.bytes, u.keys+d.keys)
+		}
+		if u.rejected && (d.bytes < 0 || d.keys < 0) && !u.over() {
+			u.rejected, back = false, true
+		}
+	}
+	if !back {
+		return false
+	}
+	for _, u := range pq.usages {
+		if u.rejected {
+			return false
+		}
+	}
+	return true
+}
+
+// invalidateAll marks the usage of all prefixes as stale, once the store
+// is restored from a snapshot.
+func (pq *prefixQuotas) invalidateAll() {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	for _, u := range pq.usages {
+		u.stale = true
+	}
+}
+
+type prefixQuota struct {
+	s  *EtcdServer
+	pq *prefixQuotas
+}
+
+// NewPrefixQuota creates a quota layer that checks the puts, and the
+// transactions with puts, against the prefix quotas of the server.
+func NewPrefixQuota(s *EtcdServer) Quota {
+	if s.prefixQuotas == nil {
+		return &passthroughQuota{}
+	}
+	return &prefixQuota{s, s.prefixQuotas}
+}
+
+func (q *prefixQuota) Available(v interface{}) bool {
+	rv := q.s.KV().Read(traceutil.TODO())
+	defer rv.End()
+	exceeded, err := q.pq.exceeds(rv, writeOps(rv, v))
+	if err != nil {
+		q.pq.lg.Warn("failed to check prefix quotas", zap.Error(err))
+		return true
+	}
+	return !exceeded
+}
+
+func (q *prefixQuota) Cost(v interface{}) int {
+	switch r := v.(type) {
+	case *pb.PutRequest:
+		return len(r.Key) + len(r.Value)
+	case *pb.TxnRequest:
+		return costPrefixTxn(r)
+	default:
+		panic("unexpected cost")
+	}
+}
+
+// costPrefixTxn returns the size of the keys and values put by the larger
+// branch of r.
+func costPrefixTxn(r *pb.TxnRequest) int {
+	size := func(ops []*pb.RequestOp) (n int) {
+		for _, op := range ops {
+			switch tv := op.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				n += len(tv.RequestPut.Key) + len(tv.RequestPut.Value)
+			case *pb.RequestOp_RequestTxn:
+				n += costPrefixTxn(tv.RequestTxn)
+			}
+		}
+		return n
+	}
+	s, f := size(r.Success), size(r.Failure)
+	if f > s {
+		return f
+	}
+	return s
+}
+
+// Remaining is the fewest bytes left under a prefix with a limit on its
+// bytes.
+func (q *prefixQuota) Remaining() int64 {
+	q.pq.mu.Lock()
+	defer q.pq.mu.Unlock()
+	remaining := int64(math.MaxInt64)
+	for _, u := range q.pq.usages {
+		if u.quota.MaxBytes > 0 && u.quota.MaxBytes-u.bytes < remaining {
+			remaining = u.quota.MaxBytes - u.bytes
+		}
+	}
+	return remaining
+}
+
+// prefixQuotaApplierV3 accounts for the writes to the prefixes with a
+// quota, and rejects the puts, and the transactions with puts, that would
+// take a prefix over its quota before they are applied.
+type prefixQuotaApplierV3 struct {
+	applierV3
+	s  *EtcdServer
+	pq *prefixQuotas
+}
+
+func newPrefixQuotaApplierV3(s *EtcdServer, app applierV3) applierV3 {
+	if s.prefixQuotas == nil {
+		return app
+	}
+	return &prefixQuotaApplierV3{app, s, s.prefixQuotas}
+}
+
+func (a *prefixQuotaApplierV3) Apply(r *pb.InternalRaftRequest) *applyResult {
+	if r.KeyExpiry == nil {
+		return a.applierV3.Apply(r)
+	}
+	// the deleted keys are only known from their previous key-value pairs
+	er := *r
+	er.KeyExpiry = a.pq.withPrevKV(r.KeyExpiry)
+	ar := a.applierV3.Apply(&er)
+	if resp, ok := ar.resp.(*pb.TxnResponse); ok && ar.err == nil {
+		deltas := a.pq.deltas()
+		a.txnDeltas(deltas, er.KeyExpiry, resp)
+		a.add(deltas)
+	}
+	return ar
+}
+
+func (a *prefixQuotaApplierV3) Put(ctx context.Context, txn mvcc.TxnWrite, p *pb.PutRequest) (*pb.PutResponse, *traceutil.Trace, error) {
+	if !a.pq.intersects(p.Key, nil) {
+		return a.applierV3.Put(ctx, txn, p)
+	}
+	if err := a.check(txn, p); err != nil {
+		return nil, nil, err
+	}
+	pp := *p
+	pp.PrevKv = true
+	resp, trace, err := a.applierV3.Put(ctx, txn, &pp)
+	if err != nil {
+		return resp, trace, err
+	}
+	deltas := a.pq.deltas()
+	a.putDeltas(deltas, &pp, resp)
+	if !p.PrevKv {
+		resp.PrevKv = nil
+	}
+	a.add(deltas)
+	return resp, trace, nil
+}
+
+func (a *prefixQuotaApplierV3) DeleteRange(txn mvcc.TxnWrite, dr *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	if !a.pq.intersects(dr.Key, dr.RangeEnd) {
+		return a.applierV3.DeleteRange(txn, dr)
+	}
+	dd := *dr
+	dd.PrevKv = true
+	resp, err := a.applierV3.DeleteRange(txn, &dd)
+	if err != nil {
+		return resp, err
+	}
+	deltas := a.pq.deltas()
+	for _, kv := range resp.PrevKvs {
+		a.pq.remove(deltas, kv)
+	}
+	if !dr.PrevKv {
+		resp.PrevKvs = nil
+	}
+	// deletes never grow a prefix
+	a.add(deltas)
+	return resp, nil
+}
+
+func (a *prefixQuotaApplierV3) Txn(ctx context.Context, rt *pb.TxnRequest) (*pb.TxnResponse, *traceutil.Trace, error) {
+	if isTxnReadonly(rt) {
+		return a.applierV3.Txn(ctx, rt)
+	}
+	if err := a.check(nil, rt); err != nil {
+		return nil, nil, err
+	}
+	tt := a.pq.withPrevKV(rt)
+	resp, trace, err := a.applierV3.Txn(ctx, tt)
+	if err != nil {
+		return resp, trace, err
+	}
+	deltas := a.pq.deltas()
+	a.txnDeltas(deltas, tt, resp)
+	stripPrevKV(rt, resp)
+	a.add(deltas)
+	return resp, trace, nil
+}
+
+func (a *prefixQuotaApplierV3) LeaseRevoke(lc *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	deltas := a.pq.deltas()
+	if l := a.s.lessor.Lookup(lease.LeaseID(lc.ID)); l != nil {
+		// the keys of the lease are deleted by the revoke
+		rv := a.s.KV().Read(traceutil.TODO())
+		for _, key := range l.Keys() {
+			rr, err := rv.Range([]byte(key), nil, mvcc.RangeOptions{})
+			if err != nil || len(rr.KVs) == 0 {
+				continue
+			}
+			a.pq.remove(deltas, &rr.KVs[0])
+		}
+		rv.End()
+	}
+	resp, err := a.applierV3.LeaseRevoke(lc)
+	if err == nil {
+		a.add(deltas)
+	}
+	return resp, err
+}
+
+// check returns ErrPrefixQuotaExceeded if the put or txn request r would
+// take a prefix over its quota, once applied on txn, or on the store if
+// txn is nil.
+func (a *prefixQuotaApplierV3) check(txn mvcc.TxnWrite, r interface{}) error {
+	var rv mvcc.ReadView
+	if txn != nil {
+		rv = txn
+	} else {
+		tr := a.s.KV().Read(traceutil.TODO())
+		defer tr.End()
+		rv = tr
+	}
+	exceeded, err := a.pq.exceeds(rv, writeOps(rv, r))
+	if err != nil {
+		return err
+	}
+	if exceeded {
+		return ErrPrefixQuotaExceeded
+	}
+	return nil
+}
+
+// add adds the change of usage by an applied request to the prefix quotas,
+// and disarms the PREFIX_QUOTA alarm of the member once every prefix that
+// rejected a write is back under its quota.
+func (a *prefixQuotaApplierV3) add(deltas []usageDelta) {
+	if !a.pq.add(a.s.KV(), deltas) {
+		return
+	}
+	for _, m := range a.s.alarmStore.Get(pb.AlarmType_PREFIX_QUOTA) {
+		if types.ID(m.MemberID) != a.s.ID() {
+			continue
+		}
+		a.s.getLogger().Info("key prefixes back under their quotas; disarming alarm")
+		a.s.GoAttach(func() {
+			ar := &pb.AlarmRequest{
+				MemberID: uint64(a.s.ID()),
+				Action:   pb.AlarmRequest_DEACTIVATE,
+				Alarm:    pb.AlarmType_PREFIX_QUOTA,
+			}
+			a.s.raftRequest(a.s.ctx, pb.InternalRaftRequest{Alarm: ar})
+		})
+	}
+}
+
+// putDeltas adds the change of usage by the applied put p to deltas.
+func (a *prefixQuotaApplierV3) putDeltas(deltas []usageDelta, p *pb.PutRequest, resp *pb.PutResponse) {
+	value := p.Value
+	if p.IgnoreValue && resp.PrevKv != nil {
+		value = resp.PrevKv.Value
+	}
+	a.pq.put(deltas, p.Key, value, resp.PrevKv)
+}
+
+// txnDeltas adds the change of usage by the applied txn rt to deltas.
+func (a *prefixQuotaApplierV3) txnDeltas(deltas []usageDelta, rt *pb.TxnRequest, resp *pb.TxnResponse) {
+	reqs := rt.Success
+	if !resp.Succeeded {
+		reqs = rt.Failure
+	}
+	for i, req := range reqs {
+		switch tv := req.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			a.putDeltas(deltas, tv.RequestPut, resp.Responses[i].GetResponsePut())
+		case *pb.RequestOp_RequestDeleteRange:
+			for _, kv := range resp.Responses[i].GetResponseDeleteRange().PrevKvs {
+				a.pq.remove(deltas, kv)
+			}
+		case *pb.RequestOp_RequestTxn:
+			a.txnDeltas(deltas, tv.RequestTxn, resp.Responses[i].GetResponseTxn())
+		}
+	}
+}
+
+// withPrevKV returns a copy of rt whose puts and deletes of keys of the
+// prefixes with a quota return their previous key-value pairs.
+func (pq *prefixQuotas) withPrevKV(rt *pb.TxnRequest) *pb.TxnRequest {
+	ops := func(reqs []*pb.RequestOp) []*pb.RequestOp {
+		if reqs == nil {
+			return nil
+		}
+		ops := make([]*pb.RequestOp, len(reqs))
+		for i, req := range reqs {
+			switch tv := req.Request.(type) {
+			case *pb.RequestOp_RequestPut:
+				if !pq.intersects(tv.RequestPut.Key, nil) {
+					ops[i] = req
+					continue
+				}
+				p := *tv.RequestPut
+				p.PrevKv = true
+				ops[i] = &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &p}}
+			case *pb.RequestOp_RequestDeleteRange:
+				if !pq.intersects(tv.RequestDeleteRange.Key, tv.RequestDeleteRange.RangeEnd) {
+					ops[i] = req
+					continue
+				}
+				dr := *tv.RequestDeleteRange
+				dr.PrevKv = true
+				ops[i] = &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &dr}}
+			case *pb.RequestOp_RequestTxn:
+				ops[i] = &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: pq.withPrevKV(tv.RequestTxn)}}
+			default:
+				ops[i] = req
+			}
+		}
+		return ops
+	}
+	return &pb.TxnRequest{Compare: rt.Compare, Success: ops(rt.Success), Failure: ops(rt.Failure)}
+}
+
+// stripPrevKV removes from resp the previous key-value pairs that were not
+// requested by rt.
+func stripPrevKV(rt *pb.TxnRequest, resp *pb.TxnResponse) {
+	reqs := rt.Success
+	if !resp.Succeeded {
+		reqs = rt.Failure
+	}
+	for i, req := range reqs {
+		switch tv := req.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			if !tv.RequestPut.PrevKv {
+				resp.Responses[i].GetResponsePut().PrevKv = nil
+			}
+		case *pb.RequestOp_RequestDeleteRange:
+			if !tv.RequestDeleteRange.PrevKv {
+				resp.Responses[i].GetResponseDeleteRange().PrevKvs = nil
+			}
+		case *pb.RequestOp_RequestTxn:
+			stripPrevKV(tv.RequestTxn, resp.Responses[i].GetResponseTxn())
+		}
+	}
+}
+
+// writeOps returns the puts and deletes of the put or txn request r, as
+// applied on rv.
+func writeOps(rv mvcc.ReadView, r interface{}) []*pb.RequestOp {
+	switch tv := r.(type) {
+	case *pb.PutRequest:
+		return []*pb.RequestOp{{Request: &pb.RequestOp_RequestPut{RequestPut: tv}}}
+	case *pb.TxnRequest:
+		return txnWrites(tv, compareToPath(rv, tv))
+	default:
+		panic("unexpected request")
+	}
+}
+
+// txnWrites returns the puts and deletes of the ops of rt on txnPath, in
+// the order they are applied.
+func txnWrites(rt *pb.TxnRequest, txnPath []bool) (ops []*pb.RequestOp) {
+	var walk func(rt *pb.TxnRequest, txnPath []bool) int
+	walk = func(rt *pb.TxnRequest, txnPath []bool) (txns int) {
+		reqs := rt.Success
+		if !txnPath[0] {
+			reqs = rt.Failure
+		}
+		for _, req := range reqs {
+			switch tv := req.Request.(type) {
+			case *pb.RequestOp_RequestPut, *pb.RequestOp_RequestDeleteRange:
+				ops = append(ops, req)
+			case *pb.RequestOp_RequestTxn:
+				n := walk(tv.RequestTxn, txnPath[1:])
+				txns += n + 1
+				txnPath = txnPath[n+1:]
+			}
+		}
+		return txns
+	}
+	walk(rt, txnPath)
+	return ops
+}
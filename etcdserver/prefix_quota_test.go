@@ -0,0 +1,310 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/v3/etcdserver/cindex"
+	"go.etcd.io/etcd/v3/lease"
+	"go.etcd.io/etcd/v3/mvcc"
+	"go.etcd.io/etcd/v3/mvcc/backend"
+
+	"go.uber.org/zap"
+)
+
+func TestParsePrefixQuotas(t *testing.T) {
+	tests := []struct {
+		s string
+
+		wqs  []PrefixQuota
+		werr bool
+	}{
+		{"", nil, false},
+		{"/a/=1KB", []PrefixQuota{{Prefix: "/a/", MaxBytes: 1000}}, false},
+		{"/a/=0:10,/b=c/=1MiB:0", []PrefixQuota{{Prefix: "/a/", MaxKeys: 10}, {Prefix: "/b=c/", MaxBytes: 1 << 20}}, false},
+		{"=1KB", nil, true},
+		{"/a/", nil, true},
+		{"/a/=x", nil, true},
+		{"/a/=1KB:-1", nil, true},
+	}
+	for i, tt := range tests {
+		qs, err := ParsePrefixQuotas(tt.s)
+		if (err != nil) != tt.werr {
+			t.Errorf("#%d: err = %v, want error %v", i, err, tt.werr)
+			continue
+		}
+		if !reflect.DeepEqual(qs, tt.wqs) {
+			t.Errorf("#%d: quotas = %+v, want %+v", i, qs, tt.wqs)
+		}
+	}
+}
+
+func TestPrefixQuotas(t *testing.T) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	defer os.RemoveAll(tmpPath)
+	kv := mvcc.New(zap.NewExample(), be, &lease.FakeLessor{}, cindex.NewConsistentIndex(be.BatchTx()), mvcc.StoreConfig{})
+	defer kv.Close()
+	defer be.Close()
+
+	pq := newPrefixQuotas(zap.NewExample(), []PrefixQuota{{Prefix: "/a/", MaxBytes: 12, MaxKeys: 2}})
+	prev := func(key string) *mvccpb.KeyValue {
+		rr, err := kv.Range([]byte(key), nil, mvcc.RangeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rr.KVs) == 0 {
+			return nil
+		}
+		return &rr.KVs[0]
+	}
+	apply := func(key, val string) {
+		deltas := pq.deltas()
+		pq.put(deltas, []byte(key), []byte(val), prev(key))
+		kv.Put([]byte(key), []byte(val), lease.NoLease)
+		pq.add(kv, deltas)
+	}
+	put := func(key, val string) error {
+		rv := kv.Read(traceutil.TODO())
+		op := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(key), Value: []byte(val)}}}
+		exceeded, err := pq.exceeds(rv, []*pb.RequestOp{op})
+		rv.End()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exceeded {
+			return ErrPrefixQuotaExceeded
+		}
+		apply(key, val)
+		return nil
+	}
+	usage := func(wbytes, wkeys int64) {
+		t.Helper()
+		if u := pq.usages[0]; u.bytes != wbytes || u.keys != wkeys {
+			t.Fatalf("usage = %d bytes, %d keys, want %d bytes, %d keys", u.bytes, u.keys, wbytes, wkeys)
+		}
+	}
+
+	tests := []struct {
+		key, val string
+		werr     error
+	}{
+		{"/a/x", "1", nil},
+		{"/a/y", "12", nil},
+		// over the keys
+		{"/a/z", "1", ErrPrefixQuotaExceeded},
+		// over the bytes
+		{"/a/x", "123", ErrPrefixQuotaExceeded},
+		// shrinking a key
+		{"/a/y", "1", nil},
+		// outside the prefix
+		{"/b/z", "123456789", nil},
+	}
+	for i, tt := range tests {
+		if err := put(tt.key, tt.val); err != tt.werr {
+			t.Fatalf("#%d: put %s = %v, want %v", i, tt.key, err, tt.werr)
+		}
+	}
+	usage(10, 2)
+
+	// a deleted key is counted out from its previous key-value pair
+	deltas := pq.deltas()
+	pq.remove(deltas, prev("/a/y"))
+	kv.DeleteRange([]byte("/a/y"), nil)
+	pq.add(kv, deltas)
+	usage(5, 1)
+
+	// the usage is counted again once stale
+	pq.invalidateAll()
+	if err := put("/a/x", "12"); err != nil {
+		t.Fatal(err)
+	}
+	usage(6, 1)
+	if err := put("/a/z", "1"); err != nil {
+		t.Fatal(err)
+	}
+	usage(11, 2)
+
+	// a key replacing a deleted one is within the quota
+	ops := []*pb.RequestOp{
+		{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("/a/z")}}},
+		{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("/a/w"), Value: []byte("1")}}},
+	}
+	rv := kv.Read(traceutil.TODO())
+	exceeded, err := pq.exceeds(rv, ops)
+	rv.End()
+	if err != nil || exceeded {
+		t.Fatalf("exceeds = %v, %v, want false, <nil>", exceeded, err)
+	}
+
+	// a prefix that rejected a write is back under its quota once shrunk
+	if err = put("/a/v", "1"); err != ErrPrefixQuotaExceeded {
+		t.Fatalf("put = %v, want %v", err, ErrPrefixQuotaExceeded)
+	}
+	deltas = pq.deltas()
+	pq.put(deltas, []byte("/a/x"), []byte("1"), prev("/a/x"))
+	kv.Put([]byte("/a/x"), []byte("1"), lease.NoLease)
+	if !pq.add(kv, deltas) {
+		t.Fatal("expected the prefix back under its quota")
+	}
+	if err = put("/a/x", "12"); err != nil {
+		t.Fatal(err)
+	}
+	deltas = pq.deltas()
+	pq.remove(deltas, prev("/a/z"))
+	kv.DeleteRange([]byte("/a/z"), nil)
+	if pq.add(kv, deltas) {
+		t.Fatal("expected no prefix to be back under its quota without a rejected write")
+	}
+}
+
+func TestPrefixUsageIntersects(t *testing.T) {
+	a := newPrefixQuotas(zap.NewExample(), []PrefixQuota{{Prefix: "/a/"}}).usages[0]
+	// a prefix without end
+	ff := &prefixUsage{quota: PrefixQuota{Prefix: "\xff\xff"}, end: []byte{}}
+	tests := []struct {
+		key, end string
+		wa, wff  bool
+	}{
+		{"/a/x", "", true, false},
+		{"/a", "", false, false},
+		{"/a/", "/a0", true, false},
+		{"/", "/a/", false, false},
+		{"/", "/a/\x00", true, false},
+		{"/a0", "\xff", false, false},
+		{"/a/z", "\x00", true, true},
+		{"/b", "\x00", false, true},
+		{"\xff\xff\x01", "", false, true},
+	}
+	for i, tt := range tests {
+		ga := a.intersects([]byte(tt.key), []byte(tt.end))
+		gff := ff.intersects([]byte(tt.key), []byte(tt.end))
+		if ga != tt.wa || gff != tt.wff {
+			t.Errorf("#%d: intersects [%q, %q) = %v, %v, want %v, %v", i, tt.key, tt.end, ga, gff, tt.wa, tt.wff)
+		}
+	}
+}
+
+func TestPrefixQuotaApplierV3(t *testing.T) {
+	be, tmpPath := backend.NewDefaultTmpBackend()
+	defer os.RemoveAll(tmpPath)
+	kv := mvcc.New(zap.NewExample(), be, &lease.FakeLessor{}, cindex.NewConsistentIndex(be.BatchTx()), mvcc.StoreConfig{})
+	defer kv.Close()
+	defer be.Close()
+
+	srv := &EtcdServer{
+		lgMu:         new(sync.RWMutex),
+		lg:           zap.NewExample(),
+		kv:           kv,
+		prefixQuotas: newPrefixQuotas(zap.NewExample(), []PrefixQuota{{Prefix: "/a/", MaxKeys: 1}}),
+	}
+	a := newPrefixQuotaApplierV3(srv, srv.newApplierV3Backend())
+	put := &pb.PutRequest{Key: []byte("/a/x"), Value: []byte("1")}
+	if _, _, err := a.Put(context.TODO(), nil, put); err != nil {
+		t.Fatal(err)
+	}
+
+	// the writes over the quota are rejected without being applied
+	put = &pb.PutRequest{Key: []byte("/a/y"), Value: []byte("1")}
+	if _, _, err := a.Put(context.TODO(), nil, put); err != ErrPrefixQuotaExceeded {
+		t.Fatalf("put = %v, want %v", err, ErrPrefixQuotaExceeded)
+	}
+	txn := &pb.TxnRequest{Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestPut{RequestPut: put}}}}
+	if _, _, err := a.Txn(context.TODO(), txn); err != ErrPrefixQuotaExceeded {
+		t.Fatalf("txn = %v, want %v", err, ErrPrefixQuotaExceeded)
+	}
+	rr, err := kv.Range([]byte("/a/"), []byte("/a0"), mvcc.RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Rev != 2 || len(rr.KVs) != 1 {
+		t.Fatalf("got revision %d, %d keys, want revision 2, 1 key", rr.Rev, len(rr.KVs))
+	}
+
+	// a txn replacing the key of the prefix is applied
+	txn = &pb.TxnRequest{Success: []*pb.RequestOp{
+		{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("/a/x")}}},
+		{Request: &pb.RequestOp_RequestPut{RequestPut: put}},
+	}}
+	if _, _, err = a.Txn(context.TODO(), txn); err != nil {
+		t.Fatal(err)
+	}
+	if u := srv.prefixQuotas.usages[0]; u.keys != 1 {
+		t.Fatalf("usage = %d keys, want 1", u.keys)
+	}
+}
+
+func TestWithPrevKV(t *testing.T) {
+	pq := newPrefixQuotas(zap.NewExample(), []PrefixQuota{{Prefix: "/a/", MaxKeys: 1}})
+	put := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("/a/x")}}}
+	del := &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("/"), RangeEnd: []byte("/b")}}}
+	outside := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("/b/x")}}}
+	nested := &pb.TxnRequest{Failure: []*pb.RequestOp{del}}
+	rt := &pb.TxnRequest{Success: []*pb.RequestOp{put, {Request: &pb.RequestOp_RequestTxn{RequestTxn: nested}}, outside}}
+
+	tt := pq.withPrevKV(rt)
+	if !tt.Success[0].GetRequestPut().PrevKv || !tt.Success[1].GetRequestTxn().Failure[0].GetRequestDeleteRange().PrevKv {
+		t.Fatalf("previous key-value pairs not requested by %+v", tt)
+	}
+	if tt.Success[2].GetRequestPut().PrevKv {
+		t.Fatalf("previous key-value pair requested outside the prefixes by %+v", tt)
+	}
+	if put.GetRequestPut().PrevKv || del.GetRequestDeleteRange().PrevKv {
+		t.Fatalf("request %+v changed", rt)
+	}
+
+	kv := &mvccpb.KeyValue{Key: []byte("a")}
+	resp := &pb.TxnResponse{Succeeded: true, Responses: []*pb.ResponseOp{
+		{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{PrevKv: kv}}},
+		{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: &pb.TxnResponse{Responses: []*pb.ResponseOp{
+			{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: &pb.DeleteRangeResponse{PrevKvs: []*mvccpb.KeyValue{kv}}}},
+		}}}},
+		{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{}}},
+	}}
+	stripPrevKV(rt, resp)
+	if resp.Responses[0].GetResponsePut().PrevKv != nil ||
+		resp.Responses[1].GetResponseTxn().Responses[0].GetResponseDeleteRange().PrevKvs != nil {
+		t.Fatalf("previous key-value pairs not stripped from %+v", resp)
+	}
+}
+
+func TestTxnWrites(t *testing.T) {
+	put := func(key string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(key)}}}
+	}
+	del := &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("d")}}}
+	nested := &pb.TxnRequest{Success: []*pb.RequestOp{put("b")}, Failure: []*pb.RequestOp{put("c"), del}}
+	rt := &pb.TxnRequest{
+		Success: []*pb.RequestOp{put("a"), {Request: &pb.RequestOp_RequestTxn{RequestTxn: nested}}, put("e")},
+	}
+	var keys []string
+	for _, op := range txnWrites(rt, []bool{true, false}) {
+		if p := op.GetRequestPut(); p != nil {
+			keys = append(keys, string(p.Key))
+		} else {
+			keys = append(keys, string(op.GetRequestDeleteRange().Key))
+		}
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "c", "d", "e"}) {
+		t.Fatalf("writes = %v, want [a c d e]", keys)
+	}
+}
@@ -57,7 +57,10 @@ import (
 	stats "go.etcd.io/etcd/v3/etcdserver/api/v2stats"
 	"go.etcd.io/etcd/v3/etcdserver/api/v2store"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3alarm"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3audit"
 	"go.etcd.io/etcd/v3/etcdserver/api/v3compactor"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3maintenance"
+	"go.etcd.io/etcd/v3/etcdserver/api/v3ratelimit"
 	"go.etcd.io/etcd/v3/etcdserver/cindex"
 	"go.etcd.io/etcd/v3/lease"
 	"go.etcd.io/etcd/v3/lease/leasehttp"
@@ -254,6 +257,15 @@ type EtcdServer struct {
 	SyncTicker *time.Ticker
 	// compactor is used to auto-compact the KV.
 	compactor v3compactor.Compactor
+	// maintenance runs the compactions and defragmentations of the
+	// maintenance policy, if enabled.
+	maintenance *v3maintenance.Scheduler
+	// prefixQuotas tracks the usage of the prefix quotas, if any.
+	prefixQuotas *prefixQuotas
+	// auditLog records the mutating and auth requests, if enabled.
+	auditLog *v3audit.Logger
+	// rateLimiter limits the rate of the client requests, if enabled.
+	rateLimiter *v3ratelimit.Limiter
 
 	// peerRt used to send requests (version, lease) to peers.
 	peerRt   http.RoundTripper
@@ -572,6 +584,25 @@ func NewServer(cfg ServerConfig) (srv *EtcdServer, err error) {
 		}
 		srv.compactor.Run()
 	}
+	if cfg.EnableMaintenanceScheduler {
+		srv.maintenance = v3maintenance.New(cfg.Logger, srv)
+	}
+
+	if len(cfg.PrefixQuotas) > 0 {
+		srv.prefixQuotas = newPrefixQuotas(cfg.Logger, cfg.PrefixQuotas)
+	}
+
+	if cfg.AuditLog != nil {
+		if srv.auditLog, err = v3audit.NewLogger(*cfg.AuditLog); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.EnableRateLimit {
+		srv.rateLimiter = v3ratelimit.New(cfg.Logger, srv)
+	}
+
+	createPolicyBucket(srv.be)
 
 	srv.applyV3Base = srv.newApplierV3Backend()
 	srv.applyV3Internal = srv.newApplierV3Internal()
@@ -705,6 +736,13 @@ func (s *EtcdServer) Start() {
 	s.GoAttach(s.monitorVersions)
 	s.GoAttach(s.linearizableReadLoop)
 	s.GoAttach(s.monitorKVHash)
+	s.GoAttach(s.expireKeys)
+	if s.maintenance != nil {
+		s.GoAttach(func() { s.maintenance.Run(s.stopping) })
+	}
+	if s.rateLimiter != nil {
+		s.GoAttach(func() { s.rateLimiter.Run(s.stopping) })
+	}
 }
 
 // start prepares and starts server in a new goroutine. It is no longer safe to
@@ -972,6 +1010,9 @@ func (s *EtcdServer) run() {
 		if s.compactor != nil {
 			s.compactor.Stop()
 		}
+		if s.auditLog != nil {
+			s.auditLog.Sync()
+		}
 		close(s.done)
 	}()
 
@@ -1111,6 +1152,10 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, apply *apply) {
 	s.consistIndex.SetConsistentIndex(s.kv.ConsistentIndex())
 	lg.Info("restored mvcc store")
 
+	if s.prefixQuotas != nil {
+		s.prefixQuotas.invalidateAll()
+	}
+
 	// Closing old backend might block until all the txns
 	// on the backend are finished.
 	// We do not want to wait on closing the old backend.
@@ -1129,6 +1174,9 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, apply *apply) {
 	s.be = newbe
 	s.bemu.Unlock()
 
+	// the snapshot of a member of an older version has no policy bucket
+	createPolicyBucket(newbe)
+
 	lg.Info("restoring alarm store")
 
 	if err := s.restoreAlarms(); err != nil {
@@ -2042,6 +2090,18 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		return
 	}
 
+	if ar.err == ErrPrefixQuotaExceeded && len(s.alarmStore.Get(pb.AlarmType_PREFIX_QUOTA)) == 0 {
+		s.getLogger().Warn("message exceeded key prefix quota; raising alarm", zap.Error(ar.err))
+		s.GoAttach(func() {
+			a := &pb.AlarmRequest{
+				MemberID: uint64(s.ID()),
+				Action:   pb.AlarmRequest_ACTIVATE,
+				Alarm:    pb.AlarmType_PREFIX_QUOTA,
+			}
+			s.raftRequest(s.ctx, pb.InternalRaftRequest{Alarm: a})
+		})
+	}
+
 	if ar.err != ErrNoSpace || len(s.alarmStore.Get(pb.AlarmType_NOSPACE)) > 0 {
 		s.w.Trigger(id, ar)
 		return
@@ -2402,6 +2462,40 @@ func (s *EtcdServer) Alarms() []*pb.AlarmMember {
 	return s.alarmStore.Get(pb.AlarmType_NONE)
 }
 
+// AlarmHistory returns the most recent alarms raised and cleared, as applied
+// by this member.
+func (s *EtcdServer) AlarmHistory() []v3alarm.AlarmEvent {
+	return s.alarmStore.History()
+}
+
+// MaintenanceStatus returns the state of the maintenance scheduler, or nil if
+// it is not enabled.
+func (s *EtcdServer) MaintenanceStatus() *v3maintenance.Status {
+	if s.maintenance == nil {
+		return nil
+	}
+	st := s.maintenance.Status()
+	return &st
+}
+
+// AuditLog returns the audit log of the mutating and auth requests, or nil if
+// it is not enabled.
+func (s *EtcdServer) AuditLog() *v3audit.Logger { return s.auditLog }
+
+// RateLimiter returns the limiter of the client requests, or nil if it is not
+// enabled.
+func (s *EtcdServer) RateLimiter() *v3ratelimit.Limiter { return s.rateLimiter }
+
+// RateLimitStatus returns the state of the rate limiter, or nil if it is not
+// enabled.
+func (s *EtcdServer) RateLimitStatus() *v3ratelimit.Status {
+	if s.rateLimiter == nil {
+		return nil
+	}
+	st := s.rateLimiter.Status()
+	return &st
+}
+
 func (s *EtcdServer) Logger() *zap.Logger {
 	return s.lg
 }
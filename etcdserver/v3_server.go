@@ -79,6 +79,7 @@ type Authenticator interface {
 	UserAdd(ctx context.Context, r *pb.AuthUserAddRequest) (*pb.AuthUserAddResponse, error)
 	UserDelete(ctx context.Context, r *pb.AuthUserDeleteRequest) (*pb.AuthUserDeleteResponse, error)
 	UserChangePassword(ctx context.Context, r *pb.AuthUserChangePasswordRequest) (*pb.AuthUserChangePasswordResponse, error)
+	UserTokenRevoke(ctx context.Context, r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error)
 	UserGrantRole(ctx context.Context, r *pb.AuthUserGrantRoleRequest) (*pb.AuthUserGrantRoleResponse, error)
 	UserGet(ctx context.Context, r *pb.AuthUserGetRequest) (*pb.AuthUserGetResponse, error)
 	UserRevokeRole(ctx context.Context, r *pb.AuthUserRevokeRoleRequest) (*pb.AuthUserRevokeRoleResponse, error)
@@ -397,6 +398,14 @@ func (s *EtcdServer) Alarm(ctx context.Context, r *pb.AlarmRequest) (*pb.AlarmRe
 	return resp.(*pb.AlarmResponse), nil
 }
 
+func (s *EtcdServer) PolicyPut(ctx context.Context, r *pb.PolicyPutRequest) (*pb.PolicyPutResponse, error) {
+	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{PolicyPut: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.PolicyPutResponse), nil
+}
+
 func (s *EtcdServer) AuthEnable(ctx context.Context, r *pb.AuthEnableRequest) (*pb.AuthEnableResponse, error) {
 	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{AuthEnable: r})
 	if err != nil {
@@ -470,11 +479,11 @@ func (s *EtcdServer) Authenticate(ctx context.Context, r *pb.AuthenticateRequest
 
 func (s *EtcdServer) UserAdd(ctx context.Context, r *pb.AuthUserAddRequest) (*pb.AuthUserAddResponse, error) {
 	if r.Options == nil || !r.Options.NoPassword {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(r.Password), s.authStore.BcryptCost())
+		hashedPassword, err := s.hashPassword(r.Password, r.HashedPassword)
 		if err != nil {
 			return nil, err
 		}
-		r.HashedPassword = base64.StdEncoding.EncodeToString(hashedPassword)
+		r.HashedPassword = hashedPassword
 		r.Password = ""
 	}
 
@@ -485,6 +494,32 @@ func (s *EtcdServer) UserAdd(ctx context.Context, r *pb.AuthUserAddRequest) (*pb
 	return resp.(*pb.AuthUserAddResponse), nil
 }
 
+// hashPassword returns the base64 encoded bcrypt hash to store for a user.
+// A hash computed by the client is only accepted when the server allows it
+// and its cost is not lower than the cost the server hashes with.
+func (s *EtcdServer) hashPassword(password, hashedPassword string) (string, error) {
+	if hashedPassword == "" {
+		h, err := bcrypt.GenerateFromPassword([]byte(password), s.authStore.BcryptCost())
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(h), nil
+	}
+
+	if !s.Cfg.AuthAllowHashedPassword || password != "" {
+		return "", ErrHashedPasswordNotAllowed
+	}
+	h, err := base64.StdEncoding.DecodeString(hashedPassword)
+	if err != nil {
+		return "", ErrInvalidHashedPassword
+	}
+	cost, err := bcrypt.Cost(h)
+	if err != nil || cost < s.authStore.BcryptCost() {
+		return "", ErrInvalidHashedPassword
+	}
+	return hashedPassword, nil
+}
+
 func (s *EtcdServer) UserDelete(ctx context.Context, r *pb.AuthUserDeleteRequest) (*pb.AuthUserDeleteResponse, error) {
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{AuthUserDelete: r})
 	if err != nil {
@@ -494,12 +529,12 @@ func (s *EtcdServer) UserDelete(ctx context.Context, r *pb.AuthUserDeleteRequest
 }
 
 func (s *EtcdServer) UserChangePassword(ctx context.Context, r *pb.AuthUserChangePasswordRequest) (*pb.AuthUserChangePasswordResponse, error) {
-	if r.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(r.Password), s.authStore.BcryptCost())
+	if r.Password != "" || r.HashedPassword != "" {
+		hashedPassword, err := s.hashPassword(r.Password, r.HashedPassword)
 		if err != nil {
 			return nil, err
 		}
-		r.HashedPassword = base64.StdEncoding.EncodeToString(hashedPassword)
+		r.HashedPassword = hashedPassword
 		r.Password = ""
 	}
 
@@ -510,6 +545,14 @@ func (s *EtcdServer) UserChangePassword(ctx context.Context, r *pb.AuthUserChang
 	return resp.(*pb.AuthUserChangePasswordResponse), nil
 }
 
+func (s *EtcdServer) UserTokenRevoke(ctx context.Context, r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error) {
+	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{AuthUserTokenRevoke: r})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.AuthUserTokenRevokeResponse), nil
+}
+
 func (s *EtcdServer) UserGrantRole(ctx context.Context, r *pb.AuthUserGrantRoleRequest) (*pb.AuthUserGrantRoleResponse, error) {
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{AuthUserGrantRole: r})
 	if err != nil {
@@ -66,6 +66,10 @@ type Backend interface {
 	// OpenReadTxN returns the number of currently open read transactions in the backend.
 	OpenReadTxN() int64
 	Defrag() error
+	// DefragProgress returns the number of keys copied by the running
+	// defragmentation and the number of keys it copies, or zeros if no
+	// defragmentation is running.
+	DefragProgress() (done, total int64)
 	ForceCommit()
 	Close() error
 }
@@ -91,6 +95,10 @@ type backend struct {
 	commits int64
 	// openReadTxN is the number of currently open read transactions in the backend
 	openReadTxN int64
+	// defragDone and defragTotal are the number of keys copied and to copy
+	// by the running defragmentation
+	defragDone  int64
+	defragTotal int64
 
 	mu sync.RWMutex
 	db *bolt.DB
@@ -400,7 +408,8 @@ func (b *backend) defrag() error {
 		)
 	}
 	// gofail: var defragBeforeCopy struct{}
-	err = defragdb(b.db, tmpdb, defragLimit)
+	err = defragdb(b.db, tmpdb, defragLimit, b.setDefragProgress)
+	b.setDefragProgress(0, 0)
 	if err != nil {
 		tmpdb.Close()
 		if rmErr := os.RemoveAll(tmpdb.Path()); rmErr != nil {
@@ -457,7 +466,17 @@ func (b *backend) defrag() error {
 	return nil
 }
 
-func defragdb(odb, tmpdb *bolt.DB, limit int) error {
+// setDefragProgress records the progress of the running defragmentation.
+func (b *backend) setDefragProgress(done, total int64) {
+	atomic.StoreInt64(&b.defragDone, done)
+	atomic.StoreInt64(&b.defragTotal, total)
+}
+
+func (b *backend) DefragProgress() (done, total int64) {
+	return atomic.LoadInt64(&b.defragDone), atomic.LoadInt64(&b.defragTotal)
+}
+
+func defragdb(odb, tmpdb *bolt.DB, limit int, progress func(done, total int64)) error {
 	// open a tx on tmpdb for writes
 	tmptx, err := tmpdb.Begin(true)
 	if err != nil {
@@ -478,6 +497,14 @@ func defragdb(odb, tmpdb *bolt.DB, limit int) error {
 
 	c := tx.Cursor()
 
+	var copied, total int64
+	for next, _ := c.First(); next != nil; next, _ = c.Next() {
+		if b := tx.Bucket(next); b != nil {
+			total += int64(b.Stats().KeyN)
+		}
+	}
+	progress(0, total)
+
 	count := 0
 	for next, _ := c.First(); next != nil; next, _ = c.Next() {
 		b := tx.Bucket(next)
@@ -506,7 +533,9 @@ func defragdb(odb, tmpdb *bolt.DB, limit int) error {
 				tmpb.FillPercent = 0.9 // for seq write in for each
 
 				count = 0
+				progress(copied, total)
 			}
+			copied++
 			return tmpb.Put(k, v)
 		}); err != nil {
 			return err
@@ -177,6 +177,44 @@ func TestBackendDefrag(t *testing.T) {
 }
 
 // TestBackendWriteback ensures writes are stored to the read txn on write txn unlock.
+func TestDefragdbProgress(t *testing.T) {
+	b, tmpPath := NewDefaultTmpBackend()
+	defer cleanup(b, tmpPath)
+
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket([]byte("a"))
+	tx.UnsafeCreateBucket([]byte("b"))
+	for i := 0; i < 3; i++ {
+		tx.UnsafePut([]byte("a"), []byte(fmt.Sprintf("foo_%d", i)), []byte("bar"))
+		tx.UnsafePut([]byte("b"), []byte(fmt.Sprintf("foo_%d", i)), []byte("bar"))
+	}
+	tx.Unlock()
+	b.ForceCommit()
+
+	tmpdb, err := bolt.Open(tmpPath+".defrag", 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpdb.Path())
+	defer tmpdb.Close()
+
+	var got [][2]int64
+	b.batchTx.Lock()
+	err = defragdb(b.db, tmpdb, 2, func(done, total int64) { got = append(got, [2]int64{done, total}) })
+	b.batchTx.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the progress is reported before copying and at every commit
+	if w := [][2]int64{{0, 6}, {2, 6}, {5, 6}}; !reflect.DeepEqual(got, w) {
+		t.Errorf("progress = %v, want %v", got, w)
+	}
+	if done, total := b.DefragProgress(); done != 0 || total != 0 {
+		t.Errorf("progress = %d/%d, want none", done, total)
+	}
+}
+
 func TestBackendWriteback(t *testing.T) {
 	b, tmpPath := NewDefaultTmpBackend()
 	defer cleanup(b, tmpPath)
@@ -0,0 +1,158 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// ExpiredKey is a key put with a ttl that elapsed. The key is expected to be
+// deleted only if its mod revision still matches ModRevision; a key put again
+// after it expired is not deleted.
+type ExpiredKey struct {
+	Key         []byte
+	ModRevision int64
+}
+
+// keyExpiry is the deadline of a key put with a ttl.
+type keyExpiry struct {
+	key      string
+	rev      int64
+	deadline time.Time
+	// index is the index of the item in the expiryQueue.
+	index int
+}
+
+// expiryQueue is a min-heap of key expiries ordered by deadline.
+type expiryQueue []*keyExpiry
+
+func (eq expiryQueue) Len() int { return len(eq) }
+
+func (eq expiryQueue) Less(i, j int) bool {
+	return eq[i].deadline.Before(eq[j].deadline)
+}
+
+func (eq expiryQueue) Swap(i, j int) {
+	eq[i], eq[j] = eq[j], eq[i]
+	eq[i].index = i
+	eq[j].index = j
+}
+
+func (eq *expiryQueue) Push(x interface{}) {
+	item := x.(*keyExpiry)
+	item.index = len(*eq)
+	*eq = append(*eq, item)
+}
+
+func (eq *expiryQueue) Pop() interface{} {
+	old := *eq
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*eq = old[0 : n-1]
+	return item
+}
+
+// expiryIndex keeps track of the keys put with a ttl. Deadlines are computed
+// from the local clock when a key is put or restored, so the index only tells
+// which keys are due for deletion; the deletion itself must go through
+// consensus.
+type expiryIndex struct {
+	mu    sync.Mutex
+	keys  map[string]*keyExpiry
+	queue expiryQueue
+
+	now func() time.Time
+}
+
+func newExpiryIndex() *expiryIndex {
+	return &expiryIndex{
+		keys: make(map[string]*keyExpiry),
+		now:  time.Now,
+	}
+}
+
+// put sets the deadline of the key put at revision rev to ttl seconds from
+// now. A ttl of zero or less removes any deadline of the key.
+func (ei *expiryIndex) put(key []byte, rev, ttl int64) {
+	if ttl <= 0 {
+		ei.remove(key)
+		return
+	}
+	deadline := ei.now().Add(time.Duration(ttl) * time.Second)
+
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	if item, ok := ei.keys[string(key)]; ok {
+		item.rev, item.deadline = rev, deadline
+		heap.Fix(&ei.queue, item.index)
+		return
+	}
+	item := &keyExpiry{key: string(key), rev: rev, deadline: deadline}
+	ei.keys[item.key] = item
+	heap.Push(&ei.queue, item)
+}
+
+// remove removes the deadline of the key, if any.
+func (ei *expiryIndex) remove(key []byte) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	item, ok := ei.keys[string(key)]
+	if !ok {
+		return
+	}
+	delete(ei.keys, item.key)
+	heap.Remove(&ei.queue, item.index)
+}
+
+// expired returns up to limit keys whose deadline has passed, earliest first.
+// A limit of zero or less returns all of them. The keys stay in the index
+// until they are deleted or put again.
+func (ei *expiryIndex) expired(limit int) []ExpiredKey {
+	now := ei.now()
+
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	if len(ei.queue) == 0 || ei.queue[0].deadline.After(now) {
+		return nil
+	}
+	// pop in deadline order and push the items back once done
+	var (
+		items []*keyExpiry
+		eks   []ExpiredKey
+	)
+	for len(ei.queue) > 0 && (limit <= 0 || len(eks) < limit) {
+		if ei.queue[0].deadline.After(now) {
+			break
+		}
+		item := heap.Pop(&ei.queue).(*keyExpiry)
+		items = append(items, item)
+		eks = append(eks, ExpiredKey{Key: []byte(item.key), ModRevision: item.rev})
+	}
+	for _, item := range items {
+		heap.Push(&ei.queue, item)
+	}
+	return eks
+}
+
+// len returns the number of keys with a deadline.
+func (ei *expiryIndex) len() int {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	return len(ei.keys)
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiryIndex(t *testing.T) {
+	now := time.Now()
+	ei := newExpiryIndex()
+	ei.now = func() time.Time { return now }
+
+	ei.put([]byte("a"), 2, 3)
+	ei.put([]byte("b"), 3, 1)
+	ei.put([]byte("c"), 4, 2)
+	ei.put([]byte("d"), 5, 0)
+	if n := ei.len(); n != 3 {
+		t.Fatalf("len = %d, want 3", n)
+	}
+	if eks := ei.expired(0); eks != nil {
+		t.Fatalf("expected no expired keys, got %+v", eks)
+	}
+
+	now = now.Add(2 * time.Second)
+	wkeys := []ExpiredKey{{Key: []byte("b"), ModRevision: 3}, {Key: []byte("c"), ModRevision: 4}}
+	if eks := ei.expired(0); !reflect.DeepEqual(eks, wkeys) {
+		t.Fatalf("expired = %+v, want %+v", eks, wkeys)
+	}
+	// expired keys stay until deleted
+	if eks := ei.expired(1); !reflect.DeepEqual(eks, wkeys[:1]) {
+		t.Fatalf("expired = %+v, want %+v", eks, wkeys[:1])
+	}
+
+	// putting the key again pushes back its deadline
+	ei.put([]byte("b"), 6, 5)
+	ei.remove([]byte("c"))
+	if eks := ei.expired(0); eks != nil {
+		t.Fatalf("expected no expired keys, got %+v", eks)
+	}
+
+	// a put without ttl clears the deadline
+	ei.put([]byte("a"), 7, 0)
+	now = now.Add(10 * time.Second)
+	wkeys = []ExpiredKey{{Key: []byte("b"), ModRevision: 6}}
+	if eks := ei.expired(0); !reflect.DeepEqual(eks, wkeys) {
+		t.Fatalf("expired = %+v, want %+v", eks, wkeys)
+	}
+	if n := ei.len(); n != 1 {
+		t.Fatalf("len = %d, want 1", n)
+	}
+}
@@ -77,6 +77,11 @@ type WriteView interface {
 	// A put also increases the rev of the store, and generates one event in the event history.
 	// The returned rev is the current revision of the KV when the operation is executed.
 	Put(key, value []byte, lease lease.LeaseID) (rev int64)
+
+	// PutWithTTL puts the given key, value into the store like Put. The key expires ttl seconds
+	// after it is put unless it is put again; expired keys are reported by KV.Expired. A ttl of 0
+	// puts a key that does not expire.
+	PutWithTTL(key, value []byte, lease lease.LeaseID, ttl int64) (rev int64)
 }
 
 // TxnWrite represents a transaction that can modify the store.
@@ -94,6 +99,9 @@ func (trw *txnReadWrite) DeleteRange(key, end []byte) (n, rev int64) { panic("un
 func (trw *txnReadWrite) Put(key, value []byte, lease lease.LeaseID) (rev int64) {
 	panic("unexpected Put")
 }
+func (trw *txnReadWrite) PutWithTTL(key, value []byte, lease lease.LeaseID, ttl int64) (rev int64) {
+	panic("unexpected PutWithTTL")
+}
 func (trw *txnReadWrite) Changes() []mvccpb.KeyValue { return nil }
 
 func NewReadOnlyTxnWrite(txn TxnRead) TxnWrite { return &txnReadWrite{txn} }
@@ -117,6 +125,14 @@ type KV interface {
 	// Compact frees all superseded keys with revisions less than rev.
 	Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, error)
 
+	// CompactionProgress returns the progress of the compaction freeing the
+	// superseded keys in the backend, if any.
+	CompactionProgress() CompactionProgress
+
+	// Expired returns up to limit keys whose ttl elapsed, earliest deadline first.
+	// A limit of 0 returns all expired keys. Expired keys are not deleted by the KV.
+	Expired(limit int) []ExpiredKey
+
 	// Commit commits outstanding txns into the underlying backend.
 	Commit()
 
@@ -125,6 +141,17 @@ type KV interface {
 	Close() error
 }
 
+// CompactionProgress is the progress of a compaction freeing the superseded
+// keys of the revisions up to Revision, scanned in revision order.
+type CompactionProgress struct {
+	// Revision is the compacted revision, or 0 if no compaction is running.
+	Revision int64
+	// StartRevision is the lowest revision the compaction scans.
+	StartRevision int64
+	// DoneRevision is the revision up to which the keys were scanned.
+	DoneRevision int64
+}
+
 // WatchableKV is a KV that can be watched.
 type WatchableKV interface {
 	KV
@@ -52,3 +52,9 @@ func (wv *writeView) Put(key, value []byte, lease lease.LeaseID) (rev int64) {
 	defer tw.End()
 	return tw.Put(key, value, lease)
 }
+
+func (wv *writeView) PutWithTTL(key, value []byte, lease lease.LeaseID, ttl int64) (rev int64) {
+	tw := wv.kv.Write(traceutil.TODO())
+	defer tw.End()
+	return tw.PutWithTTL(key, value, lease, ttl)
+}
@@ -78,6 +78,9 @@ type store struct {
 
 	le lease.Lessor
 
+	// expiries tracks the deadlines of keys put with a ttl.
+	expiries *expiryIndex
+
 	// revMuLock protects currentRev and compactMainRev.
 	// Locked at end of write txn and released after write txn unlock lock.
 	// Locked before locking read txn and released after locking.
@@ -87,6 +90,11 @@ type store struct {
 	// compactMainRev is the main revision of the last compaction.
 	compactMainRev int64
 
+	// compactProgressMu protects compactProgress.
+	compactProgressMu sync.Mutex
+	// compactProgress is the progress of the scheduled compaction running.
+	compactProgress CompactionProgress
+
 	fifoSched schedule.Scheduler
 
 	stopc chan struct{}
@@ -109,7 +117,8 @@ func NewStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, ci cindex.Cons
 		ci:      ci,
 		kvindex: newTreeIndex(lg),
 
-		le: le,
+		le:       le,
+		expiries: newExpiryIndex(),
 
 		currentRev:     1,
 		compactMainRev: -1,
@@ -333,6 +342,7 @@ func (s *store) Restore(b backend.Backend) error {
 
 	s.b = b
 	s.kvindex = newTreeIndex(s.lg)
+	s.expiries = newExpiryIndex()
 
 	{
 		// During restore the metrics might report 'special' values
@@ -358,6 +368,7 @@ func (s *store) restore() error {
 	revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
 
 	keyToLease := make(map[string]lease.LeaseID)
+	keyToTTL := make(map[string]revTTL)
 
 	// restore index
 	tx := s.b.BatchTx()
@@ -392,7 +403,7 @@ func (s *store) restore() error {
 		}
 		// rkvc blocks if the total pending keys exceeds the restore
 		// chunk size to keep keys from consuming too much memory.
-		restoreChunk(s.lg, rkvc, keys, vals, keyToLease)
+		restoreChunk(s.lg, rkvc, keys, vals, keyToLease, keyToTTL)
 		if len(keys) < restoreChunkKeys {
 			// partial set implies final set
 			break
@@ -436,6 +447,11 @@ func (s *store) restore() error {
 		}
 	}
 
+	// deadlines restart from the time of restore
+	for key, rt := range keyToTTL {
+		s.expiries.put([]byte(key), rt.rev, rt.ttl)
+	}
+
 	tx.Unlock()
 
 	if scheduledCompact != 0 {
@@ -454,6 +470,12 @@ func (s *store) restore() error {
 	return nil
 }
 
+// revTTL is the mod revision and ttl of a key put with a ttl.
+type revTTL struct {
+	rev int64
+	ttl int64
+}
+
 type revKeyValue struct {
 	key  []byte
 	kv   mvccpb.KeyValue
@@ -507,7 +529,7 @@ func restoreIntoIndex(lg *zap.Logger, idx index) (chan<- revKeyValue, <-chan int
 	return rkvc, revc
 }
 
-func restoreChunk(lg *zap.Logger, kvc chan<- revKeyValue, keys, vals [][]byte, keyToLease map[string]lease.LeaseID) {
+func restoreChunk(lg *zap.Logger, kvc chan<- revKeyValue, keys, vals [][]byte, keyToLease map[string]lease.LeaseID, keyToTTL map[string]revTTL) {
 	for i, key := range keys {
 		rkv := revKeyValue{key: key}
 		if err := rkv.kv.Unmarshal(vals[i]); err != nil {
@@ -521,10 +543,32 @@ func restoreChunk(lg *zap.Logger, kvc chan<- revKeyValue, keys, vals [][]byte, k
 		} else {
 			delete(keyToLease, rkv.kstr)
 		}
+		if !isTombstone(key) && rkv.kv.Ttl > 0 {
+			keyToTTL[rkv.kstr] = revTTL{rev: rkv.kv.ModRevision, ttl: rkv.kv.Ttl}
+		} else {
+			delete(keyToTTL, rkv.kstr)
+		}
 		kvc <- rkv
 	}
 }
 
+func (s *store) CompactionProgress() CompactionProgress {
+	s.compactProgressMu.Lock()
+	defer s.compactProgressMu.Unlock()
+	return s.compactProgress
+}
+
+func (s *store) setCompactionProgress(p CompactionProgress) {
+	s.compactProgressMu.Lock()
+	s.compactProgress = p
+	s.compactProgressMu.Unlock()
+}
+
+// Expired returns up to limit keys whose ttl elapsed, earliest deadline first.
+func (s *store) Expired(limit int) []ExpiredKey {
+	return s.expiries.expired(limit)
+}
+
 func (s *store) Close() error {
 	close(s.stopc)
 	s.fifoSched.Stop()
@@ -27,11 +27,13 @@ func (s *store) scheduleCompaction(compactMainRev int64, keep map[revision]struc
 	keyCompactions := 0
 	defer func() { dbCompactionKeysCounter.Add(float64(keyCompactions)) }()
 	defer func() { dbCompactionLast.Set(float64(time.Now().Unix())) }()
+	defer s.setCompactionProgress(CompactionProgress{})
 
 	end := make([]byte, 8)
 	binary.BigEndian.PutUint64(end, uint64(compactMainRev+1))
 
 	last := make([]byte, 8+1+8)
+	var progress CompactionProgress
 	for {
 		var rev revision
 
@@ -40,6 +42,11 @@ func (s *store) scheduleCompaction(compactMainRev int64, keep map[revision]struc
 		tx := s.b.BatchTx()
 		tx.Lock()
 		keys, _ := tx.UnsafeRange(keyBucketName, last, end, int64(s.cfg.CompactionBatchLimit))
+		if progress.Revision == 0 && len(keys) > 0 {
+			// the revisions up to the previous compaction are mostly freed
+			// already, so the scan starts at the lowest revision left
+			progress = CompactionProgress{Revision: compactMainRev, StartRevision: bytesToRev(keys[0]).main}
+		}
 		for _, key := range keys {
 			rev = bytesToRev(key)
 			if _, ok := keep[rev]; !ok {
@@ -64,6 +71,8 @@ func (s *store) scheduleCompaction(compactMainRev int64, keep map[revision]struc
 		// update last
 		revToBytes(revision{main: rev.main, sub: rev.sub + 1}, last)
 		tx.Unlock()
+		progress.DoneRevision = rev.main
+		s.setCompactionProgress(progress)
 		// Immediately commit the compaction deletes instead of letting them accumulate in the write buffer
 		s.b.ForceCommit()
 		dbCompactionPauseMs.Observe(float64(time.Since(start) / time.Millisecond))
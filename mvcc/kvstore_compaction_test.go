@@ -98,6 +98,53 @@ func TestScheduleCompaction(t *testing.T) {
 	}
 }
 
+func TestScheduleCompactionProgress(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{CompactionBatchLimit: 1})
+	defer cleanup(s, b, tmpPath)
+
+	tx := s.b.BatchTx()
+	tx.Lock()
+	ibytes := newRevBytes()
+	for rev := int64(5); rev <= 20; rev++ {
+		revToBytes(revision{main: rev}, ibytes)
+		tx.UnsafePut(keyBucketName, ibytes, []byte("bar"))
+	}
+	tx.Unlock()
+
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		s.scheduleCompaction(20, nil)
+	}()
+
+	var seen []CompactionProgress
+	for running := true; running; {
+		select {
+		case <-donec:
+			running = false
+		case <-time.After(time.Millisecond):
+			if p := s.CompactionProgress(); p.Revision != 0 {
+				seen = append(seen, p)
+			}
+		}
+	}
+	if len(seen) == 0 {
+		t.Fatal("expected the progress of the running compaction")
+	}
+	for i, p := range seen {
+		if p.Revision != 20 || p.StartRevision != 5 || p.DoneRevision < 5 || p.DoneRevision > 20 {
+			t.Errorf("#%d: unexpected progress %+v", i, p)
+		}
+		if i > 0 && p.DoneRevision < seen[i-1].DoneRevision {
+			t.Errorf("#%d: progress %+v went back from %+v", i, p, seen[i-1])
+		}
+	}
+	if p := s.CompactionProgress(); p != (CompactionProgress{}) {
+		t.Errorf("progress = %+v, want none after the compaction", p)
+	}
+}
+
 func TestCompactAllAndRestore(t *testing.T) {
 	b, tmpPath := backend.NewDefaultTmpBackend()
 	s0 := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
@@ -533,6 +533,30 @@ type hashKVResult struct {
 }
 
 // TestHashKVWhenCompacting ensures that HashKV returns correct hash when compacting.
+func TestRestoreExpiry(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+	s0 := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer os.Remove(tmpPath)
+
+	s0.PutWithTTL([]byte("foo"), []byte("bar"), lease.NoLease, 1)
+	s0.PutWithTTL([]byte("foo1"), []byte("bar"), lease.NoLease, 1)
+	s0.Put([]byte("foo1"), []byte("bar1"), lease.NoLease)
+	s0.PutWithTTL([]byte("foo2"), []byte("bar"), lease.NoLease, 1)
+	s0.DeleteRange([]byte("foo2"), nil)
+	s0.Close()
+
+	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
+	defer s.Close()
+	if n := s.expiries.len(); n != 1 {
+		t.Fatalf("expected 1 key with ttl, got %d", n)
+	}
+	s.expiries.now = func() time.Time { return time.Now().Add(time.Second) }
+	wkeys := []ExpiredKey{{Key: []byte("foo"), ModRevision: 2}}
+	if eks := s.Expired(0); !reflect.DeepEqual(eks, wkeys) {
+		t.Fatalf("expired = %+v, want %+v", eks, wkeys)
+	}
+}
+
 func TestHashKVWhenCompacting(t *testing.T) {
 	b, tmpPath := backend.NewDefaultTmpBackend()
 	s := NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{})
@@ -851,6 +875,7 @@ func newFakeStore() *store {
 		b:              b,
 		le:             &lease.FakeLessor{},
 		kvindex:        fi,
+		expiries:       newExpiryIndex(),
 		currentRev:     0,
 		compactMainRev: -1,
 		fifoSched:      schedule.NewFIFOScheduler(),
@@ -910,6 +935,7 @@ func (b *fakeBackend) OpenReadTxN() int64
 func (b *fakeBackend) Snapshot() backend.Snapshot                                  { return nil }
 func (b *fakeBackend) ForceCommit()                                                {}
 func (b *fakeBackend) Defrag() error                                               { return nil }
+func (b *fakeBackend) DefragProgress() (int64, int64)                              { return 0, 0 }
 func (b *fakeBackend) Close() error                                                { return nil }
 
 type indexGetResp struct {
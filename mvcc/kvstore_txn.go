@@ -95,7 +95,12 @@ func (tw *storeTxnWrite) DeleteRange(key, end []byte) (int64, int64) {
 }
 
 func (tw *storeTxnWrite) Put(key, value []byte, lease lease.LeaseID) int64 {
-	tw.put(key, value, lease)
+	tw.put(key, value, lease, 0)
+	return tw.beginRev + 1
+}
+
+func (tw *storeTxnWrite) PutWithTTL(key, value []byte, lease lease.LeaseID, ttl int64) int64 {
+	tw.put(key, value, lease, ttl)
 	return tw.beginRev + 1
 }
 
@@ -164,7 +169,7 @@ func (tr *storeTxnRead) rangeKeys(key, end []byte, curRev int64, ro RangeOptions
 	return &RangeResult{KVs: kvs, Count: len(revpairs), Rev: curRev}, nil
 }
 
-func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
+func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID, ttl int64) {
 	rev := tw.beginRev + 1
 	c := rev
 	oldLease := lease.NoLease
@@ -189,6 +194,7 @@ func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
 		ModRevision:    rev,
 		Version:        ver,
 		Lease:          int64(leaseID),
+		Ttl:            ttl,
 	}
 
 	d, err := kv.Marshal()
@@ -202,6 +208,7 @@ func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
 	tw.trace.Step("marshal mvccpb.KeyValue")
 	tw.tx.UnsafeSeqPut(keyBucketName, ibytes, d)
 	tw.s.kvindex.Put(key, idxRev)
+	tw.s.expiries.put(key, rev, ttl)
 	tw.changes = append(tw.changes, kv)
 	tw.trace.Step("store kv pair into bolt db")
 
@@ -270,6 +277,7 @@ func (tw *storeTxnWrite) delete(key []byte) {
 			zap.Error(err),
 		)
 	}
+	tw.s.expiries.remove(key)
 	tw.changes = append(tw.changes, kv)
 
 	item := lease.LeaseItem{Key: string(key)}
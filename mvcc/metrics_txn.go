@@ -49,6 +49,13 @@ func (tw *metricsTxnWrite) Put(key, value []byte, lease lease.LeaseID) (rev int6
 	return tw.TxnWrite.Put(key, value, lease)
 }
 
+func (tw *metricsTxnWrite) PutWithTTL(key, value []byte, lease lease.LeaseID, ttl int64) (rev int64) {
+	tw.puts++
+	size := int64(len(key) + len(value))
+	tw.putSize += size
+	return tw.TxnWrite.PutWithTTL(key, value, lease, ttl)
+}
+
 func (tw *metricsTxnWrite) End() {
 	defer tw.TxnWrite.End()
 	if sum := tw.ranges + tw.puts + tw.deletes; sum > 1 {
@@ -41,6 +41,7 @@ var (
 type watchable interface {
 	watch(key, end []byte, startRev int64, id WatchID, ch chan<- WatchResponse, fcs ...FilterFunc) (*watcher, cancelFunc)
 	progress(w *watcher)
+	progressAll(watchers map[WatchID]*watcher) bool
 	rev() int64
 }
 
@@ -484,6 +485,23 @@ func (s *watchableStore) progress(w *watcher) {
 	}
 }
 
+func (s *watchableStore) progressAll(watchers map[WatchID]*watcher) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, w := range watchers {
+		if _, ok := s.synced.watchers[w]; !ok {
+			return false
+		}
+	}
+	// the watchers of a stream share its channel, the notification is sent
+	// once for all of them
+	for _, w := range watchers {
+		return w.send(WatchResponse{WatchID: ProgressWatchID, Revision: s.rev()})
+	}
+	return true
+}
+
 type watcher struct {
 	// the watcher key
 	key []byte
@@ -26,6 +26,10 @@ import (
 // user-provided ID is available. If pass, an ID will automatically be assigned.
 const AutoWatchID WatchID = 0
 
+// ProgressWatchID is the WatchID of the progress notifications sent on
+// behalf of all the watchers of a stream.
+const ProgressWatchID WatchID = -1
+
 var (
 	ErrWatcherNotExist    = errors.New("mvcc: watcher does not exist")
 	ErrEmptyWatcherRange  = errors.New("mvcc: watcher range is empty")
@@ -61,6 +65,12 @@ type WatchStream interface {
 	// of the watchers since the watcher is currently synced.
 	RequestProgress(id WatchID)
 
+	// RequestProgressAll requests the progress of all the watchers of the stream.
+	// The response is only sent if every watcher is currently synced, once,
+	// with ProgressWatchID through the WatchResponse Chan. It returns false if
+	// some watcher is not synced yet and no response was sent.
+	RequestProgressAll() bool
+
 	// Cancel cancels a watcher by giving its ID. If watcher does not exist, an error will be
 	// returned.
 	Cancel(id WatchID) error
@@ -191,3 +201,9 @@ func (ws *watchStream) RequestProgress(id WatchID) {
 	}
 	ws.watchable.progress(w)
 }
+
+func (ws *watchStream) RequestProgressAll() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.watchable.progressAll(ws.watchers)
+}
@@ -342,6 +342,63 @@ func TestWatcherRequestProgress(t *testing.T) {
 	}
 }
 
+// TestWatcherRequestProgressAll ensures the progress of all the watchers
+// of a stream is only reported once they are all synced.
+func TestWatcherRequestProgressAll(t *testing.T) {
+	b, tmpPath := backend.NewDefaultTmpBackend()
+
+	// manually create watchableStore instead of newWatchableStore
+	// to keep the watchers unsynced until syncWatchers is called
+	s := &watchableStore{
+		store:    NewStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{}),
+		unsynced: newWatcherGroup(),
+		synced:   newWatcherGroup(),
+	}
+
+	defer func() {
+		s.store.Close()
+		os.Remove(tmpPath)
+	}()
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+
+	w := s.NewWatchStream()
+	if !w.RequestProgressAll() {
+		t.Fatal("expected the progress of a stream without watchers to be reported")
+	}
+
+	w.Watch(0, []byte("bad"), nil, 1)
+	w.Watch(0, []byte("foo"), nil, 3)
+	if w.RequestProgressAll() {
+		t.Fatal("expected the progress not to be reported with unsynced watchers")
+	}
+	select {
+	case resp := <-w.Chan():
+		t.Fatalf("unexpected %+v", resp)
+	default:
+	}
+
+	s.syncWatchers()
+
+	if !w.RequestProgressAll() {
+		t.Fatal("expected the progress of synced watchers to be reported")
+	}
+	wrs := WatchResponse{WatchID: ProgressWatchID, Revision: 2}
+	select {
+	case resp := <-w.Chan():
+		if !reflect.DeepEqual(resp, wrs) {
+			t.Fatalf("got %+v, expect %+v", resp, wrs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed to receive progress")
+	}
+	select {
+	case resp := <-w.Chan():
+		t.Fatalf("expected a single progress response, got %+v", resp)
+	default:
+	}
+}
+
 func TestWatcherWatchWithFilter(t *testing.T) {
 	b, tmpPath := backend.NewDefaultTmpBackend()
 	s := WatchableKV(newWatchableStore(zap.NewExample(), b, &lease.FakeLessor{}, nil, StoreConfig{}))
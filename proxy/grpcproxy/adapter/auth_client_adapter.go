@@ -95,3 +95,7 @@ func (s *as2ac) UserRevokeRole(ctx context.Context, in *pb.AuthUserRevokeRoleReq
 func (s *as2ac) UserChangePassword(ctx context.Context, in *pb.AuthUserChangePasswordRequest, opts ...grpc.CallOption) (*pb.AuthUserChangePasswordResponse, error) {
 	return s.as.UserChangePassword(ctx, in)
 }
+
+func (s *as2ac) UserTokenRevoke(ctx context.Context, in *pb.AuthUserTokenRevokeRequest, opts ...grpc.CallOption) (*pb.AuthUserTokenRevokeResponse, error) {
+	return s.as.UserTokenRevoke(ctx, in)
+}
@@ -56,6 +56,30 @@ func (s *mts2mtc) Downgrade(ctx context.Context, r *pb.DowngradeRequest, opts ..
 	return s.mts.Downgrade(ctx, r)
 }
 
+func (s *mts2mtc) AlarmHistory(ctx context.Context, r *pb.AlarmHistoryRequest, opts ...grpc.CallOption) (*pb.AlarmHistoryResponse, error) {
+	return s.mts.AlarmHistory(ctx, r)
+}
+
+func (s *mts2mtc) MaintenanceProgress(ctx context.Context, r *pb.MaintenanceProgressRequest, opts ...grpc.CallOption) (*pb.MaintenanceProgressResponse, error) {
+	return s.mts.MaintenanceProgress(ctx, r)
+}
+
+func (s *mts2mtc) ScheduleStatus(ctx context.Context, r *pb.ScheduleStatusRequest, opts ...grpc.CallOption) (*pb.ScheduleStatusResponse, error) {
+	return s.mts.ScheduleStatus(ctx, r)
+}
+
+func (s *mts2mtc) RateLimitStatus(ctx context.Context, r *pb.RateLimitStatusRequest, opts ...grpc.CallOption) (*pb.RateLimitStatusResponse, error) {
+	return s.mts.RateLimitStatus(ctx, r)
+}
+
+func (s *mts2mtc) PolicyPut(ctx context.Context, r *pb.PolicyPutRequest, opts ...grpc.CallOption) (*pb.PolicyPutResponse, error) {
+	return s.mts.PolicyPut(ctx, r)
+}
+
+func (s *mts2mtc) PolicyGet(ctx context.Context, r *pb.PolicyGetRequest, opts ...grpc.CallOption) (*pb.PolicyGetResponse, error) {
+	return s.mts.PolicyGet(ctx, r)
+}
+
 func (s *mts2mtc) Snapshot(ctx context.Context, in *pb.SnapshotRequest, opts ...grpc.CallOption) (pb.Maintenance_SnapshotClient, error) {
 	cs := newPipeStream(ctx, func(ss chanServerStream) error {
 		return s.mts.Snapshot(in, &ss2scServerStream{ss})
@@ -113,3 +113,8 @@ func (ap *AuthProxy) UserChangePassword(ctx context.Context, r *pb.AuthUserChang
 	conn := ap.client.ActiveConnection()
 	return pb.NewAuthClient(conn).UserChangePassword(ctx, r)
 }
+
+func (ap *AuthProxy) UserTokenRevoke(ctx context.Context, r *pb.AuthUserTokenRevokeRequest) (*pb.AuthUserTokenRevokeResponse, error) {
+	conn := ap.client.ActiveConnection()
+	return pb.NewAuthClient(conn).UserTokenRevoke(ctx, r)
+}
@@ -185,6 +185,9 @@ func RangeRequestToOp(r *pb.RangeRequest) clientv3.Op {
 	if r.Serializable {
 		opts = append(opts, clientv3.WithSerializable())
 	}
+	if r.ShowDeleted {
+		opts = append(opts, clientv3.WithShowDeleted())
+	}
 
 	return clientv3.OpGet(string(r.Key), opts...)
 }
@@ -198,6 +201,9 @@ func PutRequestToOp(r *pb.PutRequest) clientv3.Op {
 	if r.IgnoreLease {
 		opts = append(opts, clientv3.WithIgnoreLease())
 	}
+	if r.Ttl != 0 {
+		opts = append(opts, clientv3.WithExpiry(r.Ttl))
+	}
 	if r.PrevKv {
 		opts = append(opts, clientv3.WithPrevKV())
 	}
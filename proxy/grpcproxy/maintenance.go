@@ -93,3 +93,33 @@ func (mp *maintenanceProxy) Downgrade(ctx context.Context, r *pb.DowngradeReques
 	conn := mp.client.ActiveConnection()
 	return pb.NewMaintenanceClient(conn).Downgrade(ctx, r)
 }
+
+func (mp *maintenanceProxy) AlarmHistory(ctx context.Context, r *pb.AlarmHistoryRequest) (*pb.AlarmHistoryResponse, error) {
+	conn := mp.client.ActiveConnection()
+	return pb.NewMaintenanceClient(conn).AlarmHistory(ctx, r)
+}
+
+func (mp *maintenanceProxy) MaintenanceProgress(ctx context.Context, r *pb.MaintenanceProgressRequest) (*pb.MaintenanceProgressResponse, error) {
+	conn := mp.client.ActiveConnection()
+	return pb.NewMaintenanceClient(conn).MaintenanceProgress(ctx, r)
+}
+
+func (mp *maintenanceProxy) ScheduleStatus(ctx context.Context, r *pb.ScheduleStatusRequest) (*pb.ScheduleStatusResponse, error) {
+	conn := mp.client.ActiveConnection()
+	return pb.NewMaintenanceClient(conn).ScheduleStatus(ctx, r)
+}
+
+func (mp *maintenanceProxy) RateLimitStatus(ctx context.Context, r *pb.RateLimitStatusRequest) (*pb.RateLimitStatusResponse, error) {
+	conn := mp.client.ActiveConnection()
+	return pb.NewMaintenanceClient(conn).RateLimitStatus(ctx, r)
+}
+
+func (mp *maintenanceProxy) PolicyPut(ctx context.Context, r *pb.PolicyPutRequest) (*pb.PolicyPutResponse, error) {
+	conn := mp.client.ActiveConnection()
+	return pb.NewMaintenanceClient(conn).PolicyPut(ctx, r)
+}
+
+func (mp *maintenanceProxy) PolicyGet(ctx context.Context, r *pb.PolicyGetRequest) (*pb.PolicyGetResponse, error) {
+	conn := mp.client.ActiveConnection()
+	return pb.NewMaintenanceClient(conn).PolicyGet(ctx, r)
+}
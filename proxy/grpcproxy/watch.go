@@ -246,6 +246,23 @@ func (wps *watchProxyStream) recvLoop() error {
 				continue
 			}
 
+			filters, err := v3rpc.FiltersFromRequest(cr)
+			if err == nil {
+				// the proxy runs all the filters outside of any store lock
+				sendFilters, serr := v3rpc.SendFiltersFromRequest(cr)
+				filters, err = append(filters, sendFilters...), serr
+			}
+			if err != nil {
+				wps.watchCh <- &pb.WatchResponse{
+					Header:       &pb.ResponseHeader{},
+					WatchId:      -1,
+					Created:      true,
+					Canceled:     true,
+					CancelReason: err.Error(),
+				}
+				continue
+			}
+
 			wps.mu.Lock()
 			w := &watcher{
 				wr:  watchRange{string(cr.Key), string(cr.RangeEnd)},
@@ -255,7 +272,7 @@ func (wps *watchProxyStream) recvLoop() error {
 				nextrev:  cr.StartRevision,
 				progress: cr.ProgressNotify,
 				prevKV:   cr.PrevKv,
-				filters:  v3rpc.FiltersFromRequest(cr),
+				filters:  filters,
 			}
 			if !w.wr.valid() {
 				w.post(&pb.WatchResponse{WatchId: -1, Created: true, Canceled: true})
@@ -137,6 +137,8 @@ type etcdProcessClusterConfig struct {
 	initialCorruptCheck bool
 	authTokenOpts       string
 
+	maintenanceScheduler bool
+
 	rollingStart bool
 }
 
@@ -260,6 +262,9 @@ func (cfg *etcdProcessClusterConfig) etcdServerProcessConfigs() []*etcdServerPro
 		if cfg.initialCorruptCheck {
 			args = append(args, "--experimental-initial-corrupt-check")
 		}
+		if cfg.maintenanceScheduler {
+			args = append(args, "--experimental-enable-maintenance-scheduler")
+		}
 		var murl string
 		if cfg.metricsURLScheme != "" {
 			murl = (&url.URL{
@@ -51,6 +51,9 @@ func alarmTest(cx ctlCtx) {
 	if err := ctlV3Alarm(cx, "list", "alarm:NOSPACE"); err != nil {
 		cx.t.Fatal(err)
 	}
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "alarm", "list", "--details"), "alarm:NOSPACE name:"); err != nil {
+		cx.t.Fatal(err)
+	}
 
 	// '/health' handler should return 'false'
 	if err := cURLGet(cx.epc, cURLReq{endpoint: "/health", expected: `{"health":"false","reason":"ALARM NOSPACE"}`}); err != nil {
@@ -93,6 +96,11 @@ func alarmTest(cx ctlCtx) {
 		cx.t.Fatal(err)
 	}
 
+	// the history records both the alarm and its disarming
+	if err := ctlV3Alarm(cx, "history", "raised memberID:", "cleared memberID:"); err != nil {
+		cx.t.Fatal(err)
+	}
+
 	// put one more key below quota
 	if err := ctlV3Put(cx, "4th_test", smallbuf, ""); err != nil {
 		cx.t.Fatal(err)
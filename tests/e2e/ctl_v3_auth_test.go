@@ -17,6 +17,7 @@ package e2e
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"syscall"
 	"testing"
@@ -27,6 +28,7 @@ import (
 
 func TestCtlV3AuthEnable(t *testing.T)              { testCtl(t, authEnableTest) }
 func TestCtlV3AuthDisable(t *testing.T)             { testCtl(t, authDisableTest) }
+func TestCtlV3AuthRecoverOffline(t *testing.T)      { testCtl(t, authRecoverOfflineTest) }
 func TestCtlV3AuthStatus(t *testing.T)              { testCtl(t, authStatusTest) }
 func TestCtlV3AuthWriteKey(t *testing.T)            { testCtl(t, authCredWriteKeyTest) }
 func TestCtlV3AuthRoleUpdate(t *testing.T)          { testCtl(t, authRoleUpdateTest) }
@@ -68,6 +70,12 @@ func TestCtlV3AuthSnapshot(t *testing.T)            { testCtl(t, authTestSnapsho
 func TestCtlV3AuthSnapshotJWT(t *testing.T)         { testCtl(t, authTestSnapshot, withCfg(configJWT)) }
 func TestCtlV3AuthJWTExpire(t *testing.T)           { testCtl(t, authTestJWTExpire, withCfg(configJWT)) }
 func TestCtlV3AuthRevisionConsistency(t *testing.T) { testCtl(t, authTestRevisionConsistency) }
+func TestCtlV3AuthApply(t *testing.T)               { testCtl(t, authTestApply) }
+func TestCtlV3AuthTokenInspect(t *testing.T)        { testCtl(t, authTestTokenInspect) }
+func TestCtlV3AuthTokenInspectJWT(t *testing.T) {
+	testCtl(t, authTestTokenInspect, withCfg(configJWT))
+}
+func TestCtlV3AuthTokenRevoke(t *testing.T) { testCtl(t, authTestTokenRevoke) }
 
 func authEnableTest(cx ctlCtx) {
 	if err := authEnable(cx); err != nil {
@@ -140,6 +148,59 @@ func ctlV3AuthDisable(cx ctlCtx) error {
 	return spawnWithExpect(cmdArgs, "Authentication Disabled")
 }
 
+func authRecoverOfflineTest(cx ctlCtx) {
+	if err := authEnable(cx); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "root", "root"
+	if err := ctlV3Put(cx, "foo", "bar", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// the root password is lost; reset it in every data directory, once
+	// the members are stopped gracefully so that their backends hold the
+	// auth data
+	cx.epc.WithStopSignal(syscall.SIGINT)
+	if err := cx.epc.Stop(); err != nil {
+		cx.t.Fatal(err)
+	}
+	for _, p := range cx.epc.procs {
+		cmdArgs := []string{ctlBinPath, "auth", "add-root-user", "--data-dir", p.Config().dataDirPath, "--new-user-password", "newpass"}
+		if err := spawnWithExpect(cmdArgs, "Root user added"); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err := cx.epc.Restart(); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	if err := ctlV3PutFailAuth(cx, "foo", "bar"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.pass = "newpass"
+	if err := ctlV3Put(cx, "foo", "bar2", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// then disable authentication altogether
+	if err := cx.epc.Stop(); err != nil {
+		cx.t.Fatal(err)
+	}
+	for _, p := range cx.epc.procs {
+		cmdArgs := []string{ctlBinPath, "auth", "disable", "--data-dir", p.Config().dataDirPath}
+		if err := spawnWithExpect(cmdArgs, "Authentication Disabled"); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err := cx.epc.Restart(); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "", ""
+	if err := ctlV3Get(cx, []string{"foo"}, kv{"foo", "bar2"}); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
 func authStatusTest(cx ctlCtx) {
 	cmdArgs := append(cx.PrefixArgs(), "auth", "status")
 	if err := spawnWithExpects(cmdArgs, "Authentication Status: false", "AuthRevision:"); err != nil {
@@ -980,6 +1041,103 @@ func authTestRoleList(cx ctlCtx) {
 	}
 }
 
+func authTestApply(cx ctlCtx) {
+	if err := authEnable(cx); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "root", "root"
+	authSetupTestUser(cx)
+
+	f, err := ioutil.TempFile("", "rbac.yaml")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	spec := `
+roles:
+- name: reader
+  permissions:
+  - key: /app/
+    prefix: true
+    type: read
+users:
+- name: alice
+  password: secret
+  roles: [reader]
+`
+	if _, err = f.WriteString(spec); err != nil {
+		cx.t.Fatal(err)
+	}
+	f.Close()
+
+	applyArgs := append(cx.PrefixArgs(), "auth", "apply", "-f", f.Name(), "--prune")
+	if err = spawnWithExpects(append(applyArgs, "--dry-run"), "+ role reader", "- user test-user", "dry-run: 6 change(s) not applied"); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = spawnWithExpects(applyArgs, "+ user alice role reader", "6 change(s) applied"); err != nil {
+		cx.t.Fatal(err)
+	}
+	// the cluster now matches the spec
+	if err = spawnWithExpects(applyArgs, "0 change(s) applied"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	cx.user, cx.pass = "alice", "secret"
+	if err = ctlV3PutFailPerm(cx, "/app/foo", "bar"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func authTestTokenInspect(cx ctlCtx) {
+	if err := authEnable(cx); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "root", "root"
+
+	args := append(cx.PrefixArgs(), "auth", "token", "inspect")
+	if err := spawnWithExpects(args, "User: root", "Valid: true"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	f, err := ioutil.TempFile("", "token")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString("dHhWOZyfyaaIPqWK.1\n"); err != nil {
+		cx.t.Fatal(err)
+	}
+	f.Close()
+
+	cx.user, cx.pass = "", ""
+	args = append(cx.PrefixArgs(), "auth", "token", "inspect", "--token-file", f.Name())
+	if err = spawnWithExpects(args, "Type: simple", "Valid: false"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func authTestTokenRevoke(cx ctlCtx) {
+	if err := authEnable(cx); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "root", "root"
+	authSetupTestUser(cx)
+
+	// ordinary user cannot revoke the tokens of another user
+	cx.user, cx.pass = "test-user", "pass"
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "auth", "token", "revoke", "root"), "permission denied"); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "auth", "token", "revoke"), "Tokens revoked"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	cx.user, cx.pass = "root", "root"
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "auth", "token", "revoke", "test-user"), "Tokens of user test-user revoked"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
 func authTestDefrag(cx ctlCtx) {
 	maintenanceInitKeys(cx)
 
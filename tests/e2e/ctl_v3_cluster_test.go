@@ -0,0 +1,30 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import "testing"
+
+func TestCtlV3ClusterHealth(t *testing.T) { testCtl(t, clusterHealthTest, withQuorum()) }
+
+func clusterHealthTest(cx ctlCtx) {
+	if err := ctlV3ClusterHealth(cx); err != nil {
+		cx.t.Fatalf("clusterHealthTest ctlV3ClusterHealth error (%v)", err)
+	}
+}
+
+func ctlV3ClusterHealth(cx ctlCtx) error {
+	cmdArgs := append(cx.PrefixArgs(), "cluster", "health")
+	return spawnWithExpect(cmdArgs, "is healthy")
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCtlV3CompletionKeys(t *testing.T)    { testCtl(t, completionKeysTest) }
+func TestCtlV3CompletionMembers(t *testing.T) { testCtl(t, completionMembersTest) }
+
+func completionKeysTest(cx ctlCtx) {
+	for _, k := range []string{"/app/a/x", "/app/a/y", "/app/b"} {
+		if err := ctlV3Put(cx, k, "v", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+
+	// keys are only completed when enabled
+	args := append(cx.PrefixArgs(), "__complete", "get", "/app/")
+	if err := spawnWithExpect(args, ":4"); err != nil {
+		cx.t.Fatal(err)
+	}
+	args = append(cx.PrefixArgs(), "--complete-keys", "__complete", "get", "/app/")
+	if err := spawnWithExpects(args, "/app/a/", "/app/b", ":6"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func completionMembersTest(cx ctlCtx) {
+	resp, err := getMemberList(cx)
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	m := resp.Members[0]
+	args := append(cx.PrefixArgs(), "__complete", "member", "remove", "")
+	if err = spawnWithExpect(args, fmt.Sprintf("%x\t%s", m.ID, m.Name)); err != nil {
+		cx.t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCtlV3ConfigContext(t *testing.T) {
+	testCtl(t, configContextTest, withCfg(configNoTLS))
+}
+
+func configContextTest(cx ctlCtx) {
+	f, err := ioutil.TempFile("", "etcdctl-config")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	cfg := fmt.Sprintf(`currentContext: unreachable
+contexts:
+- name: unreachable
+  endpoints: [127.0.0.1:1]
+- name: test
+  endpoints: [%s]
+  writeOut: json
+`, strings.Join(cx.epc.EndpointsV3(), ","))
+	if _, err = f.WriteString(cfg); err != nil {
+		cx.t.Fatal(err)
+	}
+	f.Close()
+
+	ctl := []string{ctlBinPath + "3", "--config-file", f.Name()}
+	if err = spawnWithExpect(append(ctl, "config", "use-context", "test"), `Switched to context "test"`); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = spawnWithExpect(append(ctl, "config", "get-contexts"), "* test"); err != nil {
+		cx.t.Fatal(err)
+	}
+	// the current context sets the endpoints and the output format
+	if err = spawnWithExpect(append(ctl, "put", "foo", "bar"), `"header"`); err != nil {
+		cx.t.Fatal(err)
+	}
+	// flags take precedence over the context
+	if err = spawnWithExpects(append(ctl, "--context", "test", "-w", "simple", "get", "foo"), "foo", "bar"); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = spawnWithExpect(append(ctl, "--context", "missing", "get", "foo"), `context "missing" not found`); err != nil {
+		cx.t.Fatal(err)
+	}
+}
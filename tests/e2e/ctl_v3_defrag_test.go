@@ -14,9 +14,15 @@
 
 package e2e
 
-import "testing"
+import (
+	"syscall"
+	"testing"
+)
 
 func TestCtlV3Defrag(t *testing.T) { testCtl(t, defragTest) }
+func TestCtlV3DefragOffline(t *testing.T) {
+	testCtl(t, defragOfflineTest, withCfg(etcdProcessClusterConfig{clusterSize: 1, initialToken: "new"}))
+}
 
 func maintenanceInitKeys(cx ctlCtx) {
 	var kvs = []kv{{"key", "val1"}, {"key", "val2"}, {"key", "val3"}}
@@ -47,3 +53,34 @@ func ctlV3Defrag(cx ctlCtx) error {
 	}
 	return spawnWithExpects(cmdArgs, lines...)
 }
+
+func defragOfflineTest(cx ctlCtx) {
+	maintenanceInitKeys(cx)
+
+	// stop gracefully, so that the backend holds the keys
+	cx.epc.procs[0].WithStopSignal(syscall.SIGINT)
+	if err := cx.epc.procs[0].Stop(); err != nil {
+		cx.t.Fatal(err)
+	}
+	dataDir := cx.epc.procs[0].Config().dataDirPath
+	cmdArgs := []string{ctlBinPath, "compaction", "--data-dir", dataDir, "3"}
+	if err := spawnWithExpect(cmdArgs, "Finished compacting etcd data["+dataDir+"]"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = []string{ctlBinPath, "defrag", "--data-dir", dataDir}
+	if err := spawnWithExpect(cmdArgs, "Finished defragmenting etcd data["+dataDir+"]"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	if err := cx.epc.procs[0].Restart(); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cx.PrefixArgs(), "get", "key", "--rev", "2")
+	if err := spawnWithExpect(cmdArgs, "required revision has been compacted"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cx.PrefixArgs(), "get", "key")
+	if err := spawnWithExpect(cmdArgs, "val3"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"fmt"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/version"
+)
+
+func TestCtlV3DowngradeRun(t *testing.T) { testCtl(t, downgradeRunTest) }
+
+func downgradeRunTest(cx ctlCtx) {
+	var major, minor int
+	if _, err := fmt.Sscanf(version.Cluster(version.Version), "%d.%d", &major, &minor); err != nil {
+		cx.t.Fatal(err)
+	}
+	target := fmt.Sprintf("%d.%d", major, minor-1)
+
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "downgrade", "validate", target), "Downgrade validate success"); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "downgrade", "run", target, "--status"), "is not enabled"); err != nil {
+		cx.t.Fatal(err)
+	}
+	lines := []string{
+		fmt.Sprintf("Downgrade to %s enabled", target),
+		version.Version + ", pending",
+		"0/1 members downgraded; it is safe to replace the binary",
+	}
+	if err := spawnWithExpects(append(cx.PrefixArgs(), "downgrade", "run", target), lines...); err != nil {
+		cx.t.Fatal(err)
+	}
+	lines[0] = fmt.Sprintf("Downgrade to %s is enabled", target)
+	if err := spawnWithExpects(append(cx.PrefixArgs(), "downgrade", "run", target, "--status"), lines...); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := spawnWithExpect(append(cx.PrefixArgs(), "downgrade", "cancel"), "Downgrade cancel success"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
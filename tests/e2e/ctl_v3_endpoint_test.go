@@ -24,9 +24,10 @@ import (
 	"go.etcd.io/etcd/client/v3"
 )
 
-func TestCtlV3EndpointHealth(t *testing.T) { testCtl(t, endpointHealthTest, withQuorum()) }
-func TestCtlV3EndpointStatus(t *testing.T) { testCtl(t, endpointStatusTest, withQuorum()) }
-func TestCtlV3EndpointHashKV(t *testing.T) { testCtl(t, endpointHashKVTest, withQuorum()) }
+func TestCtlV3EndpointHealth(t *testing.T)  { testCtl(t, endpointHealthTest, withQuorum()) }
+func TestCtlV3EndpointStatus(t *testing.T)  { testCtl(t, endpointStatusTest, withQuorum()) }
+func TestCtlV3EndpointHashKV(t *testing.T)  { testCtl(t, endpointHashKVTest, withQuorum()) }
+func TestCtlV3EndpointLatency(t *testing.T) { testCtl(t, endpointLatencyTest, withQuorum()) }
 
 func endpointHealthTest(cx ctlCtx) {
 	if err := ctlV3EndpointHealth(cx); err != nil {
@@ -59,6 +60,22 @@ func ctlV3EndpointStatus(cx ctlCtx) error {
 	return spawnWithExpects(cmdArgs, eps...)
 }
 
+func endpointLatencyTest(cx ctlCtx) {
+	if err := ctlV3EndpointLatency(cx); err != nil {
+		cx.t.Fatalf("endpointLatencyTest ctlV3EndpointLatency error (%v)", err)
+	}
+}
+
+func ctlV3EndpointLatency(cx ctlCtx) error {
+	cmdArgs := append(cx.PrefixArgs(), "endpoint", "latency", "--samples", "3")
+	var eps []string
+	for _, ep := range cx.epc.EndpointsV3() {
+		u, _ := url.Parse(ep)
+		eps = append(eps, u.Host)
+	}
+	return spawnWithExpects(cmdArgs, eps...)
+}
+
 func endpointHashKVTest(cx ctlCtx) {
 	if err := ctlV3EndpointHashKV(cx); err != nil {
 		cx.t.Fatalf("endpointHashKVTest ctlV3EndpointHashKV error (%v)", err)
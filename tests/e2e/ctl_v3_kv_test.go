@@ -16,6 +16,8 @@ package e2e
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -41,10 +43,13 @@ func TestCtlV3GetPeerTLS(t *testing.T)       { testCtl(t, getTest, withCfg(confi
 func TestCtlV3GetTimeout(t *testing.T)       { testCtl(t, getTest, withDialTimeout(0)) }
 func TestCtlV3GetQuorum(t *testing.T)        { testCtl(t, getTest, withQuorum()) }
 
-func TestCtlV3GetFormat(t *testing.T)    { testCtl(t, getFormatTest) }
-func TestCtlV3GetRev(t *testing.T)       { testCtl(t, getRevTest) }
-func TestCtlV3GetKeysOnly(t *testing.T)  { testCtl(t, getKeysOnlyTest) }
-func TestCtlV3GetCountOnly(t *testing.T) { testCtl(t, getCountOnlyTest) }
+func TestCtlV3GetFormat(t *testing.T)       { testCtl(t, getFormatTest) }
+func TestCtlV3GetRev(t *testing.T)          { testCtl(t, getRevTest) }
+func TestCtlV3GetKeysOnly(t *testing.T)     { testCtl(t, getKeysOnlyTest) }
+func TestCtlV3GetCountOnly(t *testing.T)    { testCtl(t, getCountOnlyTest) }
+func TestCtlV3GetShowDeleted(t *testing.T)  { testCtl(t, getShowDeletedTest) }
+func TestCtlV3GetStream(t *testing.T)       { testCtl(t, getStreamTest) }
+func TestCtlV3GetKeysFromFile(t *testing.T) { testCtl(t, getKeysFromFileTest) }
 
 func TestCtlV3Del(t *testing.T)          { testCtl(t, delTest) }
 func TestCtlV3DelNoTLS(t *testing.T)     { testCtl(t, delTest, withCfg(configNoTLS)) }
@@ -236,6 +241,85 @@ func getKeysOnlyTest(cx ctlCtx) {
 	}
 }
 
+func getShowDeletedTest(cx ctlCtx) {
+	for _, k := range []string{"key1", "key2", "key3"} {
+		if err := ctlV3Put(cx, k, "val", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	// revision 4 has all three keys; delete key2 and recreate key3
+	if err := ctlV3Del(cx, []string{"key2"}, 1); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Del(cx, []string{"key3"}, 1); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Put(cx, "key3", "val", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs := append(cx.PrefixArgs(), "get", "--keys-only", "--rev", "4", "--show-deleted", "key", "--prefix")
+	if err := spawnWithExpects(cmdArgs, "key1", "key2", "[deleted]", "key3"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func getStreamTest(cx ctlCtx) {
+	kvs := []kv{{"key1", "val1"}, {"key2", "val2"}, {"key3", "val3"}, {"key4", "val4"}, {"key5", "val5"}}
+	for i := range kvs {
+		if err := ctlV3Put(cx, kvs[i].key, kvs[i].val, ""); err != nil {
+			cx.t.Fatalf("getStreamTest #%d: ctlV3Put error (%v)", i, err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+
+		wkv []kv
+	}{
+		{[]string{"key1", "--stream"}, kvs[:1]},
+		{[]string{"key", "--prefix", "--stream", "--stream-page-size=2"}, kvs},
+		{[]string{"key2", "key4", "--stream", "--stream-page-size=1"}, kvs[1:3]},
+		{[]string{"key4", "--from-key", "--stream", "--stream-page-size=1"}, kvs[3:]},
+	}
+	for i, tt := range tests {
+		if err := ctlV3Get(cx, tt.args, tt.wkv...); err != nil {
+			cx.t.Errorf("getStreamTest #%d: ctlV3Get error (%v)", i, err)
+		}
+	}
+
+	if err := ctlV3GetWithErr(cx, []string{"key", "--prefix", "--stream", "--limit=2"}, []string{"cannot be combined"}); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func getKeysFromFileTest(cx ctlCtx) {
+	for _, k := range []string{"key1", "key3"} {
+		if err := ctlV3Put(cx, k, "val"+k[3:], ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	f, err := ioutil.TempFile("", "keys")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString("key3\nkey2\n\nkey1\n"); err != nil {
+		cx.t.Fatal(err)
+	}
+	f.Close()
+
+	if err = ctlV3Get(cx, []string{"--keys-from-file", f.Name()}, kv{"key3", "val3"}, kv{"key2", "[missing]"}, kv{"key1", "val1"}); err != nil {
+		cx.t.Fatal(err)
+	}
+	// the keys are read at the revision given
+	if err = ctlV3Get(cx, []string{"--keys-from-file", f.Name(), "--rev", "2"}, kv{"key3", "[missing]"}, kv{"key2", "[missing]"}, kv{"key1", "val1"}); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = ctlV3GetWithErr(cx, []string{"--keys-from-file", f.Name(), "--prefix"}, []string{"cannot be combined"}); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
 func getCountOnlyTest(cx ctlCtx) {
 	cmdArgs := append(cx.PrefixArgs(), []string{"get", "--count-only", "key", "--prefix", "--write-out=fields"}...)
 	if err := spawnWithExpects(cmdArgs, "\"Count\" : 0"); err != nil {
@@ -100,6 +100,11 @@ func TestCtlV3LeaseRevokeClientAutoTLS(t *testing.T) {
 }
 func TestCtlV3LeaseRevokePeerTLS(t *testing.T) { testCtl(t, leaseTestRevoked, withCfg(configPeerTLS)) }
 
+func TestCtlV3LeaseAttachDetach(t *testing.T) { testCtl(t, leaseTestAttachDetach) }
+func TestCtlV3LeaseAttachDetachNoTLS(t *testing.T) {
+	testCtl(t, leaseTestAttachDetach, withCfg(configNoTLS))
+}
+
 func leaseTestGrantTimeToLive(cx ctlCtx) {
 	id, err := ctlV3LeaseGrant(cx, 10)
 	if err != nil {
@@ -239,6 +244,60 @@ func leaseTestRevoke(cx ctlCtx) error {
 	return nil
 }
 
+func leaseTestAttachDetach(cx ctlCtx) {
+	if err := leaseTestAttach(cx); err != nil {
+		cx.t.Fatalf("leaseTestAttach: (%v)", err)
+	}
+	if err := leaseTestDetach(cx); err != nil {
+		cx.t.Fatalf("leaseTestDetach: (%v)", err)
+	}
+}
+
+func leaseTestAttach(cx ctlCtx) error {
+	// attach a key put without lease, then revoke the lease
+	leaseID, err := ctlV3LeaseGrant(cx, 10)
+	if err != nil {
+		return fmt.Errorf("ctlV3LeaseGrant error (%v)", err)
+	}
+	if err := ctlV3Put(cx, "attach", "val", ""); err != nil {
+		return fmt.Errorf("ctlV3Put error (%v)", err)
+	}
+	if err := ctlV3LeaseAttach(cx, leaseID, "attach"); err != nil {
+		return fmt.Errorf("ctlV3LeaseAttach error (%v)", err)
+	}
+	if err := ctlV3Get(cx, []string{"attach"}, kv{"attach", "val"}); err != nil {
+		return fmt.Errorf("ctlV3Get error (%v)", err)
+	}
+	if err := ctlV3LeaseRevoke(cx, leaseID); err != nil {
+		return fmt.Errorf("ctlV3LeaseRevoke error (%v)", err)
+	}
+	if err := ctlV3Get(cx, []string{"attach"}); err != nil { // expect no output
+		return fmt.Errorf("ctlV3Get error (%v)", err)
+	}
+	return nil
+}
+
+func leaseTestDetach(cx ctlCtx) error {
+	// detach a key put with lease, then revoke the lease
+	leaseID, err := ctlV3LeaseGrant(cx, 10)
+	if err != nil {
+		return fmt.Errorf("ctlV3LeaseGrant error (%v)", err)
+	}
+	if err := ctlV3Put(cx, "detach", "val", leaseID); err != nil {
+		return fmt.Errorf("ctlV3Put error (%v)", err)
+	}
+	if err := ctlV3LeaseDetach(cx, "detach"); err != nil {
+		return fmt.Errorf("ctlV3LeaseDetach error (%v)", err)
+	}
+	if err := ctlV3LeaseRevoke(cx, leaseID); err != nil {
+		return fmt.Errorf("ctlV3LeaseRevoke error (%v)", err)
+	}
+	if err := ctlV3Get(cx, []string{"detach"}, kv{"detach", "val"}); err != nil {
+		return fmt.Errorf("ctlV3Get error (%v)", err)
+	}
+	return nil
+}
+
 func ctlV3LeaseGrant(cx ctlCtx, ttl int) (string, error) {
 	cmdArgs := append(cx.PrefixArgs(), "lease", "grant", strconv.Itoa(ttl))
 	proc, err := spawnCmd(cmdArgs)
@@ -294,3 +353,13 @@ func ctlV3LeaseRevoke(cx ctlCtx, leaseID string) error {
 	cmdArgs := append(cx.PrefixArgs(), "lease", "revoke", leaseID)
 	return spawnWithExpect(cmdArgs, fmt.Sprintf("lease %s revoked", leaseID))
 }
+
+func ctlV3LeaseAttach(cx ctlCtx, leaseID, key string) error {
+	cmdArgs := append(cx.PrefixArgs(), "lease", "attach", leaseID, key)
+	return spawnWithExpect(cmdArgs, fmt.Sprintf("key %q attached to lease %s", key, leaseID))
+}
+
+func ctlV3LeaseDetach(cx ctlCtx, key string) error {
+	cmdArgs := append(cx.PrefixArgs(), "lease", "detach", key)
+	return spawnWithExpect(cmdArgs, fmt.Sprintf("key %q detached from its lease", key))
+}
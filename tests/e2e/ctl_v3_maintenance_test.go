@@ -0,0 +1,66 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCtlV3MaintenanceSchedule(t *testing.T) {
+	cfg := configNoTLS
+	cfg.maintenanceScheduler = true
+	testCtl(t, maintenanceScheduleTest, withCfg(cfg))
+}
+
+func TestCtlV3MaintenanceStatusDisabled(t *testing.T) {
+	testCtl(t, maintenanceStatusDisabledTest, withCfg(configNoTLS))
+}
+
+func maintenanceScheduleTest(cx ctlCtx) {
+	args := append(cx.PrefixArgs(), "maintenance", "schedule", "--defrag-cron", "0 3 * *")
+	if err := spawnWithExpect(args, "invalid cron expression"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	args = append(cx.PrefixArgs(), "maintenance", "schedule", "--compact-retention", "1h", "--defrag-cron", "0 3 * * *")
+	if err := spawnWithExpect(args, "Maintenance policy set"); err != nil {
+		cx.t.Fatal(err)
+	}
+	// the scheduler reads the policy every few seconds
+	args = append(cx.PrefixArgs(), "maintenance", "status")
+	var err error
+	for i := 0; i < 15; i++ {
+		if err = spawnWithExpects(args, `compact-retention="1h"`, "next defrag:"); err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+
+	args = append(cx.PrefixArgs(), "maintenance", "schedule", "--clear")
+	if err := spawnWithExpect(args, "Maintenance policy cleared"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func maintenanceStatusDisabledTest(cx ctlCtx) {
+	args := append(cx.PrefixArgs(), "maintenance", "status")
+	if err := spawnWithExpect(args, "maintenance scheduler is not enabled"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
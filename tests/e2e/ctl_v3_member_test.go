@@ -72,6 +72,9 @@ func TestCtlV3MemberUpdateClientAutoTLS(t *testing.T) {
 	testCtl(t, memberUpdateTest, withCfg(configClientAutoTLS))
 }
 func TestCtlV3MemberUpdatePeerTLS(t *testing.T) { testCtl(t, memberUpdateTest, withCfg(configPeerTLS)) }
+func TestCtlV3MemberReplace(t *testing.T) {
+	testCtl(t, memberReplaceTest, withQuorum(), withNoStrictReconfig())
+}
 
 func memberListTest(cx ctlCtx) {
 	if err := ctlV3MemberList(cx); err != nil {
@@ -207,3 +210,18 @@ func ctlV3MemberUpdate(cx ctlCtx, memberID, peerURL string) error {
 	cmdArgs := append(cx.PrefixArgs(), "member", "update", memberID, fmt.Sprintf("--peer-urls=%s", peerURL))
 	return spawnWithExpect(cmdArgs, " updated in cluster ")
 }
+
+func memberReplaceTest(cx ctlCtx) {
+	ep, memIDToRemove, clusterID := cx.memberToRemove()
+	peerURL := fmt.Sprintf("http://localhost:%d", etcdProcessBasePort+11)
+	cmdArgs := append(cx.prefixArgs([]string{ep}), "member", "replace", memIDToRemove, "--peer-urls", peerURL, "--promote-timeout", "2s")
+	// the new member is never started, so it cannot be promoted
+	if err := spawnWithExpects(cmdArgs,
+		fmt.Sprintf("%s removed from cluster %s", memIDToRemove, clusterID),
+		" added to cluster ",
+		"ETCD_INITIAL_CLUSTER_STATE",
+		"did not catch up",
+	); err != nil {
+		cx.t.Fatal(err)
+	}
+}
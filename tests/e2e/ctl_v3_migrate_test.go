@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
@@ -106,3 +107,31 @@ func ctlV3Migrate(cx ctlCtx, dataDir, walDir string) error {
 	cmdArgs := append(cx.PrefixArgs(), "migrate", "--data-dir", dataDir, "--wal-dir", walDir)
 	return spawnWithExpects(cmdArgs, "finished transforming keys")
 }
+
+func TestCtlV3MigrateStorageVersion(t *testing.T) { testCtl(t, migrateStorageVersionTest) }
+
+func migrateStorageVersionTest(cx ctlCtx) {
+	if err := ctlV3Put(cx, "key", "val", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	// stop gracefully, so that the backend holds the storage version
+	cx.epc.procs[0].WithStopSignal(syscall.SIGINT)
+	if err := cx.epc.procs[0].Stop(); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// the member attributes published on start are not covered by a snapshot
+	dataDir := cx.epc.procs[0].Config().dataDirPath
+	cmdArgs := []string{ctlBinPath, "migrate", "--data-dir", dataDir, "--target-version", "3.4"}
+	if err := spawnWithExpect(cmdArgs, "cannot be applied by 3.4"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// the data directory is left untouched
+	if err := cx.epc.procs[0].Restart(); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Get(cx, []string{"key"}, kv{"key", "val"}); err != nil {
+		cx.t.Fatal(err)
+	}
+}
@@ -117,3 +117,15 @@ func testCtlV3MoveLeader(t *testing.T, cfg etcdProcessClusterConfig) {
 		}
 	}
 }
+
+func TestCtlV3MoveLeaderAuto(t *testing.T) {
+	testCtl(t, moveLeaderAutoTest, withCfg(configNoTLS), withQuorum())
+}
+
+func moveLeaderAutoTest(cx ctlCtx) {
+	// any member works as an endpoint; the leader is looked up from the member list
+	cmdArgs := append(cx.prefixArgs([]string{cx.epc.EndpointsV3()[0]}), "move-leader", "--auto")
+	if err := spawnWithExpects(cmdArgs, "Selected member ", "Leadership transferred from "); err != nil {
+		cx.t.Fatal(err)
+	}
+}
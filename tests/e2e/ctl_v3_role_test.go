@@ -26,6 +26,7 @@ func TestCtlV3RoleAddPeerTLS(t *testing.T)   { testCtl(t, roleAddTest, withCfg(c
 func TestCtlV3RoleAddTimeout(t *testing.T)   { testCtl(t, roleAddTest, withDialTimeout(0)) }
 
 func TestCtlV3RoleGrant(t *testing.T) { testCtl(t, roleGrantTest) }
+func TestCtlV3RoleClone(t *testing.T) { testCtl(t, roleCloneTest) }
 
 func roleAddTest(cx ctlCtx) {
 	cmdSet := []struct {
@@ -92,6 +93,53 @@ func roleGrantTest(cx ctlCtx) {
 	}
 }
 
+func roleCloneTest(cx ctlCtx) {
+	cmdSet := []struct {
+		args        []string
+		expectedStr string
+	}{
+		{
+			args:        []string{"add", "src"},
+			expectedStr: "Role src created",
+		},
+		{
+			args:        []string{"grant-permission", "src", "read", "foo"},
+			expectedStr: "Role src updated",
+		},
+		{
+			args:        []string{"grant-permission", "src", "write", "bar", "--prefix"},
+			expectedStr: "Role src updated",
+		},
+		{
+			args:        []string{"clone", "src", "dst"},
+			expectedStr: "Role dst cloned from src with 2 permission(s)",
+		},
+		// Try cloning to an existing role.
+		{
+			args:        []string{"clone", "src", "dst"},
+			expectedStr: "role name already exists",
+		},
+		{
+			args:        []string{"diff", "src", "dst"},
+			expectedStr: "0 added, 0 removed, 0 changed (role src to role dst)",
+		},
+		{
+			args:        []string{"grant-permission", "dst", "readwrite", "foo"},
+			expectedStr: "Role dst updated",
+		},
+		{
+			args:        []string{"diff", "src", "dst"},
+			expectedStr: "~ foo READ -> READWRITE",
+		},
+	}
+
+	for i, cmd := range cmdSet {
+		if err := ctlV3Role(cx, cmd.args, cmd.expectedStr); err != nil {
+			cx.t.Fatalf("roleCloneTest #%d: ctlV3Role error (%v)", i, err)
+		}
+	}
+}
+
 func ctlV3Role(cx ctlCtx, args []string, expStr string) error {
 	cmdArgs := append(cx.PrefixArgs(), "role")
 	cmdArgs = append(cmdArgs, args...)
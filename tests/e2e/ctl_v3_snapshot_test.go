@@ -15,6 +15,7 @@
 package e2e
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,6 +26,7 @@ import (
 	"testing"
 	"time"
 
+	"go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/etcdctl/v3/snapshot"
 	"go.etcd.io/etcd/pkg/v3/expect"
 	"go.etcd.io/etcd/pkg/v3/testutil"
@@ -122,6 +124,273 @@ func snapshotStatusBeforeRestoreTest(cx ctlCtx) {
 	}
 }
 
+func TestCtlV3SnapshotAnalyze(t *testing.T) { testCtl(t, snapshotAnalyzeTest) }
+
+func snapshotAnalyzeTest(cx ctlCtx) {
+	fpath := "test4.snapshot"
+	defer os.RemoveAll(fpath)
+
+	for _, kv := range []struct{ key, val string }{
+		{"/a/1", "1"}, {"/a/1", "2"}, {"/a/1", "3"},
+		{"/b/1", strings.Repeat("x", 2048)},
+	} {
+		if err := ctlV3Put(cx, kv.key, kv.val, ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err := ctlV3SnapshotSave(cx, fpath); err != nil {
+		cx.t.Fatalf("snapshotAnalyzeTest ctlV3SnapshotSave error (%v)", err)
+	}
+
+	cmdArgs := append(cx.PrefixArgs(), "snapshot", "analyze", fpath)
+	if err := spawnWithExpects(cmdArgs,
+		"keys: 2, revisions: 4, tombstones: 0",
+		"/a/, 1 keys, 3 revisions",
+		"largest keys:",
+		"/b/1, 2.0 kB, 1 revisions",
+		"most revised keys:",
+		"/a/1, 3 revisions",
+	); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3SnapshotTrim(t *testing.T) { testCtl(t, snapshotTrimTest) }
+
+func snapshotTrimTest(cx ctlCtx) {
+	fpath, tpath := "test5.snapshot", "test5-trimmed.snapshot"
+	defer os.RemoveAll(fpath)
+	defer os.RemoveAll(tpath)
+
+	for _, kv := range []struct{ key, val string }{
+		{"/a/1", "1"}, {"/a/1", "2"}, {"/a/1", "3"},
+		{"/b/1", "1"},
+	} {
+		if err := ctlV3Put(cx, kv.key, kv.val, ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err := ctlV3SnapshotSave(cx, fpath); err != nil {
+		cx.t.Fatalf("snapshotTrimTest ctlV3SnapshotSave error (%v)", err)
+	}
+
+	cmdArgs := append(cx.PrefixArgs(), "snapshot", "trim", "--exclude-prefix", "/b/", "--compact", fpath, tpath)
+	if err := spawnWithExpect(cmdArgs, fmt.Sprintf("Trimmed snapshot saved at %s (revision 5, 1 revisions kept, 3 removed", tpath)); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cx.PrefixArgs(), "snapshot", "analyze", tpath)
+	if err := spawnWithExpects(cmdArgs, "keys: 1, revisions: 1, tombstones: 0", "/a/, 1 keys, 1 revisions"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3SnapshotRestoreFilter(t *testing.T) { testCtl(t, snapshotRestoreFilterTest) }
+
+func snapshotRestoreFilterTest(cx ctlCtx) {
+	fpath := "test6.snapshot"
+	defer os.RemoveAll(fpath)
+	dataDir, err := ioutil.TempDir("", "restore-filter")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	for _, key := range []string{"/a/1", "/a/2", "/b/1", "/c/1"} {
+		if err = ctlV3Put(cx, key, "v", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err = ctlV3SnapshotSave(cx, fpath); err != nil {
+		cx.t.Fatalf("snapshotRestoreFilterTest ctlV3SnapshotSave error (%v)", err)
+	}
+
+	cmdArgs := []string{ctlBinPath, "snapshot", "restore", fpath, "--data-dir", dataDir,
+		"--include-prefix", "/a/", "--include-prefix", "/b/", "--exclude-prefix", "/a/2",
+		"--rewrite-prefix", "/a/=/x/"}
+	if err = spawnWithExpect(cmdArgs, "filtered restored keys"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// only /b/1 and /a/1, restored as /x/1, are left
+	dbPath := filepath.Join(dataDir, "member", "snap", "db")
+	if err = spawnWithExpect([]string{ctlBinPath, "snapshot", "analyze", dbPath}, "keys: 2, revisions: 2, tombstones: 0"); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = spawnWithExpects([]string{ctlBinPath, "backend", "dump", "--format", "sql", dbPath},
+		"INSERT INTO kvs VALUES (X'2f622f31'",
+		"INSERT INTO kvs VALUES (X'2f782f31'",
+	); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3SnapshotRestoreResume(t *testing.T) { testCtl(t, snapshotRestoreResumeTest) }
+
+func snapshotRestoreResumeTest(cx ctlCtx) {
+	fpath := "test9.snapshot"
+	defer os.RemoveAll(fpath)
+	dataDir, err := ioutil.TempDir("", "restore-resume")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	for _, key := range []string{"/a/1", "/b/1"} {
+		if err = ctlV3Put(cx, key, "v", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err = ctlV3SnapshotSave(cx, fpath); err != nil {
+		cx.t.Fatalf("snapshotRestoreResumeTest ctlV3SnapshotSave error (%v)", err)
+	}
+
+	// the restore is interrupted by the colliding rewrite, after the copy
+	cmdArgs := []string{ctlBinPath, "snapshot", "restore", fpath, "--data-dir", dataDir,
+		"--batch-bytes", "4096", "--rewrite-prefix", "/a/=/b/"}
+	if err = spawnWithExpects(cmdArgs, "copied snapshot", "would both be restored as"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = []string{ctlBinPath, "snapshot", "restore", fpath, "--data-dir", dataDir}
+	if err = spawnWithExpect(cmdArgs, "has an interrupted restore"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cmdArgs, "--resume")
+	if err = spawnWithExpects(cmdArgs, "skipping restore stage completed before resuming", "restored snapshot"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	dbPath := filepath.Join(dataDir, "member", "snap", "db")
+	if err = spawnWithExpect([]string{ctlBinPath, "snapshot", "analyze", dbPath}, "keys: 2, revisions: 2, tombstones: 0"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3SnapshotHashKV(t *testing.T) { testCtl(t, snapshotHashKVTest) }
+
+func snapshotHashKVTest(cx ctlCtx) {
+	fpath := "test7.snapshot"
+	defer os.RemoveAll(fpath)
+
+	for _, key := range []string{"foo1", "foo2", "foo3"} {
+		if err := ctlV3Put(cx, key, "v", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err := ctlV3SnapshotSave(cx, fpath); err != nil {
+		cx.t.Fatalf("snapshotHashKVTest ctlV3SnapshotSave error (%v)", err)
+	}
+	// changes after the snapshot are not covered by the hash at its revision
+	if err := ctlV3Put(cx, "foo4", "v", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	eps := cx.epc.EndpointsV3()
+	cli, err := clientv3.New(clientv3.Config{Endpoints: eps, DialTimeout: 3 * time.Second})
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer cli.Close()
+	hresp, err := cli.HashKV(context.TODO(), eps[0], 4)
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+
+	cmdArgs := append(cx.PrefixArgs(), "backend", "hashkv", "--rev", "4", fpath)
+	if err = spawnWithExpect(cmdArgs, fmt.Sprintf("%s, %d", fpath, hresp.Hash)); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3SnapshotDiff(t *testing.T) { testCtl(t, snapshotDiffTest) }
+
+func snapshotDiffTest(cx ctlCtx) {
+	fpath1, fpath2 := "test8-1.snapshot", "test8-2.snapshot"
+	defer os.RemoveAll(fpath1)
+	defer os.RemoveAll(fpath2)
+
+	for _, key := range []string{"/a/1", "/a/2", "/a/3", "/b/1"} {
+		if err := ctlV3Put(cx, key, "1", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	if err := ctlV3SnapshotSave(cx, fpath1); err != nil {
+		cx.t.Fatalf("snapshotDiffTest ctlV3SnapshotSave error (%v)", err)
+	}
+	// change, rewrite unchanged, remove and add a key
+	if err := ctlV3Put(cx, "/a/1", "2", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Put(cx, "/a/2", "1", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Del(cx, []string{"/a/3"}, 1); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Put(cx, "/a/4", "1", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3SnapshotSave(cx, fpath2); err != nil {
+		cx.t.Fatalf("snapshotDiffTest ctlV3SnapshotSave error (%v)", err)
+	}
+
+	cmdArgs := append(cx.PrefixArgs(), "snapshot", "diff", fpath1, fpath2, "--prefix", "/a/")
+	if err := spawnWithExpects(cmdArgs,
+		"+ /a/4",
+		"- /a/3",
+		"~ /a/1",
+		"1 added, 1 removed, 1 changed (revision 5, destination revision 9)",
+	); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3SnapshotEncrypt(t *testing.T) { testCtl(t, snapshotEncryptTest) }
+
+func snapshotEncryptTest(cx ctlCtx) {
+	fpath := "test10.snapshot"
+	defer os.RemoveAll(fpath)
+	keyPath := "test10.key"
+	defer os.RemoveAll(keyPath)
+	if err := ioutil.WriteFile(keyPath, []byte(strings.Repeat("5e", 32)+"\n"), 0600); err != nil {
+		cx.t.Fatal(err)
+	}
+	dataDir, err := ioutil.TempDir("", "restore-encrypted")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	for _, key := range []string{"foo1", "foo2"} {
+		if err = ctlV3Put(cx, key, "v", ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	cmdArgs := append(cx.PrefixArgs(), "snapshot", "save", "--encrypt-key", keyPath, fpath)
+	if err = spawnWithExpect(cmdArgs, fmt.Sprintf("Snapshot saved at %s", fpath)); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	if err = spawnWithExpect([]string{ctlBinPath, "snapshot", "status", fpath}, "--encrypt-key is required"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = []string{ctlBinPath, "--write-out", "json", "snapshot", "status", "--encrypt-key", keyPath, fpath}
+	if err = spawnWithExpect(cmdArgs, `"totalKey":`); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	cmdArgs = []string{ctlBinPath, "snapshot", "restore", fpath, "--encrypt-key", keyPath, "--data-dir", dataDir}
+	if err = spawnWithExpect(cmdArgs, "restored snapshot"); err != nil {
+		cx.t.Fatal(err)
+	}
+	dbPath := filepath.Join(dataDir, "member", "snap", "db")
+	if err = spawnWithExpect([]string{ctlBinPath, "snapshot", "analyze", dbPath}, "keys: 2, revisions: 2, tombstones: 0"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
 func ctlV3SnapshotSave(cx ctlCtx, fpath string) error {
 	cmdArgs := append(cx.PrefixArgs(), "snapshot", "save", fpath)
 	return spawnWithExpect(cmdArgs, fmt.Sprintf("Snapshot saved at %s", fpath))
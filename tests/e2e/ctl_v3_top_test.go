@@ -0,0 +1,28 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import "testing"
+
+func TestCtlV3Top(t *testing.T)      { testCtl(t, topTest) }
+func TestCtlV3TopNoTLS(t *testing.T) { testCtl(t, topTest, withCfg(configNoTLS)) }
+
+func topTest(cx ctlCtx) {
+	args := append(cx.PrefixArgs(), "top", "--batch", "--iterations", "2", "--interval", "100ms")
+	lines := []string{"etcd top", "Alarms: none", "PROPOSALS/S", "etcd top", "true"}
+	if err := spawnWithExpects(args, lines...); err != nil {
+		cx.t.Fatal(err)
+	}
+}
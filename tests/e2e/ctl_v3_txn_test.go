@@ -14,7 +14,11 @@
 
 package e2e
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
 
 func TestCtlV3TxnInteractiveSuccess(t *testing.T) {
 	testCtl(t, txnTestSuccess, withInteractive())
@@ -31,6 +35,9 @@ func TestCtlV3TxnInteractiveSuccessPeerTLS(t *testing.T) {
 func TestCtlV3TxnInteractiveFail(t *testing.T) {
 	testCtl(t, txnTestFail, withInteractive())
 }
+func TestCtlV3TxnFile(t *testing.T) {
+	testCtl(t, txnTestFile)
+}
 
 func txnTestSuccess(cx ctlCtx) {
 	if err := ctlV3Put(cx, "key1", "value1", ""); err != nil {
@@ -89,6 +96,42 @@ func txnTestFail(cx ctlCtx) {
 	}
 }
 
+func txnTestFile(cx ctlCtx) {
+	if err := ctlV3Put(cx, "key1", "value1", ""); err != nil {
+		cx.t.Fatalf("txnTestFile ctlV3Put error (%v)", err)
+	}
+	f, err := ioutil.TempFile("", "txn")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	txn := `# overwrite ${key} if it did not change
+value("${key}") = "value1"
+version("${key}") = "${version}"
+
+put ${key} "${value}"
+
+get ${key}
+`
+	if _, err = f.WriteString(txn); err != nil {
+		cx.t.Fatal(err)
+	}
+	f.Close()
+
+	cmdArgs := append(cx.PrefixArgs(), "txn", "--file", f.Name(), "--var", "key=key1", "--var", "version=2", "--var", "value=value2")
+	if err = spawnWithExpects(cmdArgs, "FAILURE", "key1", "value1", `failed compare: version("key1") = "2"`); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cx.PrefixArgs(), "txn", "--file", f.Name(), "--var", "key=key1", "--var", "version=1", "--var", "value=value2")
+	if err = spawnWithExpects(cmdArgs, "SUCCESS", "OK"); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cx.PrefixArgs(), "txn", "--file", f.Name(), "--var", "key=key1")
+	if err = spawnWithExpect(cmdArgs, `undefined variable "version" on line 3`); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
 type txnRequests struct {
 	compare  []string
 	ifSucess []string
@@ -14,7 +14,12 @@
 
 package e2e
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestCtlV3UserAdd(t *testing.T)          { testCtl(t, userAddTest) }
 func TestCtlV3UserAddNoTLS(t *testing.T)     { testCtl(t, userAddTest, withCfg(configNoTLS)) }
@@ -60,16 +65,22 @@ func userAddTest(cx ctlCtx) {
 			expectedStr: "User username created",
 			stdIn:       []string{"password"},
 		},
-		// Adds a user name using the usertest:password syntax.
+		// Refuses the usertest:password syntax that exposes the password.
 		{
 			args:        []string{"add", "usertest:password"},
-			expectedStr: "User usertest created",
+			expectedStr: "passing the password as part of the user name is not supported",
 			stdIn:       []string{},
 		},
-		// Tries to add a user with empty username.
+		// Adds a user name with the password read from a file.
 		{
-			args:        []string{"add", ":password"},
-			expectedStr: "empty user name is not allowed",
+			args:        []string{"add", "userfile", "--password-file", "{{passwordFile}}"},
+			expectedStr: "User userfile created",
+			stdIn:       []string{},
+		},
+		// Tries to add a user with the password read from an unset variable.
+		{
+			args:        []string{"add", "userenv", "--password-from-env", "ETCDCTL_E2E_UNSET_PASSWORD"},
+			expectedStr: "environment variable \"ETCDCTL_E2E_UNSET_PASSWORD\" is not set",
 			stdIn:       []string{},
 		},
 		// Tries to add a user name that already exists.
@@ -86,7 +97,20 @@ func userAddTest(cx ctlCtx) {
 		},
 	}
 
+	f, err := ioutil.TempFile("", "e2e-user-password")
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString("password\n"); err != nil {
+		cx.t.Fatal(err)
+	}
+	f.Close()
+
 	for i, cmd := range cmdSet {
+		for j := range cmd.args {
+			cmd.args[j] = strings.Replace(cmd.args[j], "{{passwordFile}}", f.Name(), 1)
+		}
 		if err := ctlV3User(cx, cmd.args, cmd.expectedStr, cmd.stdIn); err != nil {
 			if cx.dialTimeout > 0 && !isGRPCTimedout(err) {
 				cx.t.Fatalf("userAddTest #%d: ctlV3User error (%v)", i, err)
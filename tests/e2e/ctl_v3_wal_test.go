@@ -0,0 +1,57 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import "testing"
+
+func TestCtlV3WALInspect(t *testing.T) { testCtl(t, walInspectTest) }
+
+func walInspectTest(cx ctlCtx) {
+	if err := ctlV3Put(cx, "wal-key", "wal-value", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// the WAL is read without locking it, so the member keeps running
+	dataDir := cx.epc.procs[0].Config().dataDirPath
+	args := append(cx.PrefixArgs(), "wal", "inspect", "--data-dir", dataDir)
+	if err := spawnWithExpects(args, "nodeID:", "conf_change, ConfChangeAddNode", "normal, put, wal-key"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	args = append(cx.PrefixArgs(), "wal", "inspect", "--data-dir", dataDir, "--start-index", "2", "--end-index", "1")
+	if err := spawnWithExpect(args, "--end-index must not be lower than --start-index"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+func TestCtlV3WALRepairDryRun(t *testing.T) { testCtl(t, walRepairDryRunTest) }
+
+func walRepairDryRunTest(cx ctlCtx) {
+	if err := ctlV3Put(cx, "wal-key", "wal-value", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// a dry run only reads the WAL, so the member keeps running
+	dataDir := cx.epc.procs[0].Config().dataDirPath
+	args := append(cx.PrefixArgs(), "wal", "repair", "--dry-run", "--data-dir", dataDir)
+	if err := spawnWithExpect(args, "Nothing to drop"); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	args = append(cx.PrefixArgs(), "wal", "repair", "--dry-run", "--data-dir", dataDir, "--truncate-at-index", "2")
+	if err := spawnWithExpects(args, "Would drop the records (truncated at index 2)", "entries dropped, indexes 2 to", "were committed"); err != nil {
+		cx.t.Fatal(err)
+	}
+}
@@ -14,7 +14,10 @@
 
 package e2e
 
-import "strings"
+import (
+	"strings"
+	"testing"
+)
 
 type kvExec struct {
 	key, val   string
@@ -87,3 +90,37 @@ func ctlV3WatchFailPerm(cx ctlCtx, args []string) error {
 	}
 	return proc.Close()
 }
+
+func TestCtlV3WatchReplay(t *testing.T) { testCtl(t, watchReplayTest) }
+
+func watchReplayTest(cx ctlCtx) {
+	// key1@2, key2@3, other@4, key3@5
+	for _, p := range []kv{{"key1", "val1"}, {"key2", "val2"}, {"other", "val"}, {"key3", "val3"}} {
+		if err := ctlV3Put(cx, p.key, p.val, ""); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	cmdArgs := append(cx.PrefixArgs(), "watch", "key", "--prefix", "--from-rev", "2", "--to-rev", "4", "--no-follow")
+	proc, err := spawnCmd(cmdArgs)
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	for _, s := range []string{"key1", "val1", "key2", "val2"} {
+		if _, err = proc.Expect(s); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+	// the replay exits on its own once revision 4 is reached
+	for {
+		l, lerr := proc.ExpectFunc(func(string) bool { return true })
+		if lerr != nil {
+			break
+		}
+		if strings.Contains(l, "key3") {
+			cx.t.Fatalf("unexpected event past --to-rev %q", l)
+		}
+	}
+	if err = proc.Close(); err != nil {
+		cx.t.Fatal(err)
+	}
+}
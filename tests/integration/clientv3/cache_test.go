@@ -0,0 +1,111 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/cache"
+	"go.etcd.io/etcd/pkg/v3/testutil"
+	"go.etcd.io/etcd/tests/v3/integration"
+)
+
+func TestCacheGetList(t *testing.T) {
+	defer testutil.AfterTest(t)
+
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	c := clus.Client(0)
+	for _, k := range []string{"a/1", "a/2", "b/1"} {
+		if _, err := c.Put(context.TODO(), k, "v1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cc, err := cache.New(context.TODO(), c, "a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	kvs, rev, err := cc.List(context.TODO(), "a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || string(kvs[0].Key) != "a/1" || string(kvs[1].Key) != "a/2" || rev != 4 {
+		t.Fatalf("expected a/1 and a/2 at revision 4, got %v at revision %d", kvs, rev)
+	}
+
+	if _, err = c.Put(context.TODO(), "a/1", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Delete(context.TODO(), "a/2"); err != nil {
+		t.Fatal(err)
+	}
+	for cc.Rev() < 6 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	kv, _, err := cc.Get(context.TODO(), "a/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv == nil || string(kv.Value) != "v2" {
+		t.Fatalf("expected a/1 to be v2, got %v", kv)
+	}
+	if kv, _, err = cc.Get(context.TODO(), "a/2"); err != nil || kv != nil {
+		t.Fatalf("expected a/2 to be deleted, got %v (%v)", kv, err)
+	}
+	if kv, _, err = cc.Get(context.TODO(), "b/1"); err != nil || kv != nil {
+		t.Fatalf("expected b/1 not to be cached, got %v (%v)", kv, err)
+	}
+
+	cc.Close()
+	if _, _, err = cc.Get(context.TODO(), "a/1"); err != cache.ErrClosed {
+		t.Fatalf("expected %v, got %v", cache.ErrClosed, err)
+	}
+}
+
+func TestCacheMaxStaleness(t *testing.T) {
+	defer testutil.AfterTest(t)
+
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	c := clus.Client(0)
+	cc, err := cache.New(context.TODO(), c, "a/", cache.WithMaxStaleness(time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	// the cache is confirmed up to date before the read, so the read sees
+	// the put that preceded it
+	presp, err := c.Put(context.TODO(), "a/1", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	kv, rev, err := cc.Get(ctx, "a/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv == nil || string(kv.Value) != "v1" || rev < presp.Header.Revision {
+		t.Fatalf("expected a/1 to be v1 at revision %d, got %v at revision %d", presp.Header.Revision, kv, rev)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestBarrier(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	ctx := context.TODO()
+
+	b := concurrency.NewBarrier(cli, "/test-barrier")
+	if err := b.Hold(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Hold(ctx); err != concurrency.ErrBarrierHeld {
+		t.Fatalf("err = %v, want %v", err, concurrency.ErrBarrierHeld)
+	}
+
+	donec := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { donec <- concurrency.NewBarrier(cli, "/test-barrier").Wait(ctx) }()
+	}
+	select {
+	case err := <-donec:
+		t.Fatalf("barrier did not wait (err %v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-donec; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a released barrier does not block
+	if err := b.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDoubleBarrier(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	ctx := context.TODO()
+
+	waiters := 5
+	donec := make(chan error, waiters)
+	run := func(f func(*concurrency.DoubleBarrier) error) {
+		s, err := concurrency.NewSession(cli)
+		if err != nil {
+			donec <- err
+			return
+		}
+		defer s.Close()
+		donec <- f(concurrency.NewDoubleBarrier(s, "/test-double-barrier", waiters))
+	}
+
+	leavec := make(chan struct{})
+	for i := 0; i < waiters-1; i++ {
+		go run(func(b *concurrency.DoubleBarrier) error {
+			if err := b.Enter(ctx); err != nil {
+				return err
+			}
+			donec <- nil
+			<-leavec
+			return b.Leave(ctx)
+		})
+	}
+	select {
+	case err := <-donec:
+		t.Fatalf("barrier did not enter-wait (err %v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	s, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	b := concurrency.NewDoubleBarrier(s, "/test-double-barrier", waiters)
+	if err := b.Enter(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < waiters-1; i++ {
+		if err := <-donec; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(leavec)
+	select {
+	case err := <-donec:
+		t.Fatalf("barrier did not leave-wait (err %v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if err := b.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < waiters-1; i++ {
+		if err := <-donec; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRendezvous(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	ctx := context.TODO()
+
+	n := 3
+	type result struct {
+		vals []string
+		err  error
+	}
+	resc := make(chan result, n+1)
+	for i := 0; i < n+1; i++ {
+		s, err := concurrency.NewSession(cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		r := concurrency.NewRendezvous(s, "/test-rendezvous", n)
+		go func(i int) {
+			vals, err := r.Arrive(ctx, fmt.Sprint(i))
+			resc <- result{vals, err}
+		}(i)
+		if i < n-1 {
+			select {
+			case res := <-resc:
+				t.Fatalf("rendezvous did not wait (%+v)", res)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+
+	// the first n-1 arrived in order, the last two raced for the last place
+	want := []string{"0", "1"}
+	var met, late int
+	for i := 0; i < n+1; i++ {
+		res := <-resc
+		if res.err == concurrency.ErrTooManyParticipants {
+			late++
+			continue
+		}
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		met++
+		if len(res.vals) != n || !reflect.DeepEqual(res.vals[:n-1], want) {
+			t.Fatalf("vals = %v, want %v and one of 2 and 3", res.vals, want)
+		}
+	}
+	if met != n || late != 1 {
+		t.Fatalf("met = %d, late = %d, want %d and 1", met, late, n)
+	}
+}
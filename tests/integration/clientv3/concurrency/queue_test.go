@@ -0,0 +1,141 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestQueue(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	s, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	q := concurrency.NewQueue(s, "/test-queue")
+	ctx := context.TODO()
+
+	// lower priority values are delivered first
+	if _, err := q.Enqueue(ctx, "later", concurrency.WithPriority(1)); err != nil {
+		t.Fatal(err)
+	}
+	for _, val := range []string{"a", "b"} {
+		if _, err := q.Enqueue(ctx, val); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tasks []*concurrency.Task
+	for _, want := range []string{"a", "b", "later"} {
+		task, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Value != want {
+			t.Fatalf("value = %q, want %q", task.Value, want)
+		}
+		tasks = append(tasks, task)
+	}
+	if task, err := q.TryClaim(ctx); err != nil || task != nil {
+		t.Fatalf("TryClaim = %+v, %v, want no task", task, err)
+	}
+
+	// a requeued task is delivered again
+	if err := q.Requeue(ctx, tasks[0]); err != nil {
+		t.Fatal(err)
+	}
+	task, err := q.Claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Key != tasks[0].Key {
+		t.Fatalf("key = %q, want %q", task.Key, tasks[0].Key)
+	}
+	for _, task := range tasks {
+		if err := q.Ack(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Ack(ctx, tasks[0]); err != concurrency.ErrTaskLost {
+		t.Fatalf("err = %v, want %v", err, concurrency.ErrTaskLost)
+	}
+}
+
+func TestQueueRequeueOnExpiry(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	s1, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	q1 := concurrency.NewQueue(s1, "/test-queue-expiry")
+	q2 := concurrency.NewQueue(s2, "/test-queue-expiry")
+	ctx := context.TODO()
+
+	if _, err := q1.Enqueue(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	task, err := q1.Claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed := make(chan *concurrency.Task, 1)
+	go func() {
+		task, err := q2.Claim(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		claimed <- task
+	}()
+	select {
+	case <-claimed:
+		t.Fatal("claimed a task claimed by another worker")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// the worker fails; its claim expires with its session
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	task2 := <-claimed
+	if task2 == nil || task2.Key != task.Key {
+		t.Fatalf("claimed %+v, want %q", task2, task.Key)
+	}
+	if err := q1.Ack(ctx, task); err != concurrency.ErrTaskLost {
+		t.Fatalf("err = %v, want %v", err, concurrency.ErrTaskLost)
+	}
+	if err := q2.Ack(ctx, task2); err != nil {
+		t.Fatal(err)
+	}
+}
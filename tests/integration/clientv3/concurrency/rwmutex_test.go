@@ -0,0 +1,138 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// newRWMutexes creates n RWMutexes on the same prefix, each with its own
+// session, closed by the returned function.
+func newRWMutexes(t *testing.T, cli *clientv3.Client, n int, opts ...concurrency.RWMutexOption) ([]*concurrency.RWMutex, func()) {
+	var rws []*concurrency.RWMutex
+	var ss []*concurrency.Session
+	closeAll := func() {
+		for _, s := range ss {
+			s.Close()
+		}
+	}
+	for i := 0; i < n; i++ {
+		s, err := concurrency.NewSession(cli)
+		if err != nil {
+			closeAll()
+			t.Fatal(err)
+		}
+		ss = append(ss, s)
+		rws = append(rws, concurrency.NewRWMutex(s, "/my-rwlock", opts...))
+	}
+	return rws, closeAll
+}
+
+func TestRWMutex(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	rws, closeAll := newRWMutexes(t, cli, 4)
+	defer closeAll()
+	ctx := context.TODO()
+
+	// readers share the lock
+	if err := rws[0].RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rws[1].TryRLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rws[2].TryLock(ctx); err != concurrency.ErrLocked {
+		t.Fatalf("err = %v, want %v", err, concurrency.ErrLocked)
+	}
+
+	wlocked := make(chan error, 1)
+	go func() { wlocked <- rws[2].Lock(ctx) }()
+	// wait for the writer to queue
+	time.Sleep(500 * time.Millisecond)
+
+	// a reader queued after a waiting writer waits for it
+	rlocked := make(chan error, 1)
+	go func() { rlocked <- rws[3].RLock(ctx) }()
+
+	if err := rws[0].RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-wlocked:
+		t.Fatalf("writer locked with a reader holding the lock (err %v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if err := rws[1].RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-wlocked; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-rlocked:
+		t.Fatalf("reader locked with a writer holding the lock (err %v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if err := rws[2].Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-rlocked; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRWMutexWriterPriority(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	rws, closeAll := newRWMutexes(t, cli, 3, concurrency.WithWriterPriority())
+	defer closeAll()
+	ctx := context.TODO()
+
+	if err := rws[0].RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wlocked := make(chan error, 1)
+	go func() { wlocked <- rws[1].Lock(ctx) }()
+	time.Sleep(500 * time.Millisecond)
+
+	// a new reader does not join the readers holding the lock
+	if err := rws[2].TryRLock(ctx); err != concurrency.ErrLocked {
+		t.Fatalf("err = %v, want %v", err, concurrency.ErrLocked)
+	}
+	if err := rws[0].RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-wlocked; err != nil {
+		t.Fatal(err)
+	}
+	if err := rws[1].Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rws[2].TryRLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestSemaphore(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	var sms []*concurrency.Semaphore
+	for i := 0; i < 3; i++ {
+		s, err := concurrency.NewSession(cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		sms = append(sms, concurrency.NewSemaphore(s, "/my-semaphore", 2))
+	}
+	ctx := context.TODO()
+
+	if err := sms[0].Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := sms[1].TryAcquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := sms[2].TryAcquire(ctx); err != concurrency.ErrNoPermits {
+		t.Fatalf("err = %v, want %v", err, concurrency.ErrNoPermits)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- sms[2].Acquire(ctx) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("acquired with all permits held (err %v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	// releasing any permit frees one for the waiter
+	if err := sms[1].Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSemaphoreSessionExpired(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	s1, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm1 := concurrency.NewSemaphore(s1, "/my-semaphore-expired", 1)
+	s2, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	sm2 := concurrency.NewSemaphore(s2, "/my-semaphore-expired", 1)
+
+	if err := sm1.Acquire(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	// the permit of a closed session is released with its lease
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm2.TryAcquire(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestSessionReestablish(t *testing.T) {
+	cli, err := clientv3.New(clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	healthc := make(chan concurrency.SessionHealth, 8)
+	// the lost lease is noticed at the next keepalive, a third of the TTL
+	s, err := concurrency.NewSession(cli,
+		concurrency.WithTTL(5),
+		concurrency.WithHealthCallback(0, func(h concurrency.SessionHealth) { healthc <- h }),
+		concurrency.WithReestablish(3, 10*time.Millisecond),
+		concurrency.WithResignOnRisk(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	lease := s.Lease()
+
+	m := concurrency.NewMutex(s, "/test-session-lock")
+	if err := m.Lock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	// lose the lease
+	if _, err := cli.Revoke(context.TODO(), lease); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []concurrency.SessionState{concurrency.SessionLost, concurrency.SessionHealthy} {
+		select {
+		case h := <-healthc:
+			if h.State != want {
+				t.Fatalf("state = %v, want %v", h.State, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for state %v", want)
+		}
+	}
+	if s.Lease() == lease {
+		t.Fatal("expected a new lease")
+	}
+	select {
+	case <-s.Done():
+		t.Fatal("re-established session is done")
+	default:
+	}
+
+	// the mutex was resigned, and is locked again with the new lease
+	if err := m.Lock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := cli.Get(context.TODO(), m.Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || clientv3.LeaseID(resp.Kvs[0].Lease) != s.Lease() {
+		t.Fatalf("lock key %+v, want lease %x", resp.Kvs, s.Lease())
+	}
+}
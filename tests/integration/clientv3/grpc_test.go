@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	etcd "go.etcd.io/etcd/client/v3"
 	namingv3 "go.etcd.io/etcd/client/v3/naming"
@@ -137,3 +138,75 @@ func TestGRPCResolverMulti(t *testing.T) {
 		t.Fatalf("expected two updates, got %+v", updates)
 	}
 }
+
+// TestServiceResolver ensures the service resolver resolves the endpoints
+// registered for several services by weight, and removes the endpoints
+// whose registration is closed.
+func TestServiceResolver(t *testing.T) {
+	defer testutil.AfterTest(t)
+
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	r := namingv3.ServiceResolver{Client: clus.RandClient()}
+	ctx := context.TODO()
+	register := func(service string, ep namingv3.Endpoint) *etcd.Registration {
+		reg, err := r.Register(ctx, service, ep, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			if _, ok := reg.Registered(); ok {
+				return reg
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("timed out registering %+v", ep)
+		return nil
+	}
+	regFoo := register("foo", namingv3.Endpoint{Addr: "127.0.0.1:1", Weight: 2, Attributes: map[string]string{"zone": "a"}})
+	defer regFoo.Close(ctx)
+	regBar := register("bar", namingv3.Endpoint{Addr: "127.0.0.1:2"})
+
+	eps, err := r.Endpoints(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weps := []namingv3.Endpoint{{Addr: "127.0.0.1:1", Weight: 2, Attributes: map[string]string{"zone": "a"}}}
+	if !reflect.DeepEqual(eps, weps) {
+		t.Fatalf("endpoints = %+v, want %+v", eps, weps)
+	}
+
+	w, err := r.Resolve("foo,bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	us, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wus := []*naming.Update{
+		{Op: naming.Add, Addr: "127.0.0.1:1", Metadata: namingv3.Instance{Service: "foo", Replica: 0}},
+		{Op: naming.Add, Addr: "127.0.0.1:1", Metadata: namingv3.Instance{Service: "foo", Replica: 1}},
+		{Op: naming.Add, Addr: "127.0.0.1:2", Metadata: namingv3.Instance{Service: "bar", Replica: 0}},
+	}
+	if !reflect.DeepEqual(us, wus) {
+		t.Fatalf("updates = %+v, want %+v", us, wus)
+	}
+
+	if err = regBar.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	us, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wus = []*naming.Update{
+		{Op: naming.Delete, Addr: "127.0.0.1:2", Metadata: namingv3.Instance{Service: "bar", Replica: 0}},
+	}
+	if !reflect.DeepEqual(us, wus) {
+		t.Fatalf("updates = %+v, want %+v", us, wus)
+	}
+}
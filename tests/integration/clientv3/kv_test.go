@@ -464,6 +464,66 @@ func TestKVRange(t *testing.T) {
 	}
 }
 
+func TestKVRangeShowDeleted(t *testing.T) {
+	defer testutil.AfterTest(t)
+
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	for i, key := range []string{"a", "b", "c"} {
+		if _, err := kv.Put(ctx, key, ""); err != nil {
+			t.Fatalf("#%d: couldn't put %q (%v)", i, key, err)
+		}
+	}
+	// revision 4 has all three keys; delete b and recreate c
+	for i, key := range []string{"b", "c"} {
+		if _, err := kv.Delete(ctx, key); err != nil {
+			t.Fatalf("#%d: couldn't delete %q (%v)", i, key, err)
+		}
+	}
+	if _, err := kv.Put(ctx, "c", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rev  int64
+		opts []clientv3.OpOption
+
+		wantDeleted [][]byte
+	}{
+		{4, nil, nil},
+		{4, []clientv3.OpOption{clientv3.WithShowDeleted()}, [][]byte{[]byte("b")}},
+		{4, []clientv3.OpOption{clientv3.WithShowDeleted(), clientv3.WithKeysOnly()}, [][]byte{[]byte("b")}},
+		{4, []clientv3.OpOption{clientv3.WithShowDeleted(), clientv3.WithSerializable()}, [][]byte{[]byte("b")}},
+		{2, []clientv3.OpOption{clientv3.WithShowDeleted()}, nil},
+		// the current revision has no deleted keys
+		{0, []clientv3.OpOption{clientv3.WithShowDeleted()}, nil},
+	}
+	for i, tt := range tests {
+		opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithRev(tt.rev)}
+		opts = append(opts, tt.opts...)
+		resp, err := kv.Get(ctx, "", opts...)
+		if err != nil {
+			t.Fatalf("#%d: couldn't range (%v)", i, err)
+		}
+		if !reflect.DeepEqual(tt.wantDeleted, resp.DeletedKeys) {
+			t.Errorf("#%d: deleted keys expected %q, got %q", i, tt.wantDeleted, resp.DeletedKeys)
+		}
+	}
+
+	// ranges in a transaction mark the deleted keys as well
+	tresp, err := kv.Txn(ctx).Then(clientv3.OpGet("", clientv3.WithPrefix(), clientv3.WithRev(4), clientv3.WithShowDeleted())).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted := tresp.Responses[0].GetResponseRange().DeletedKeys; !reflect.DeepEqual(deleted, [][]byte{[]byte("b")}) {
+		t.Errorf("expected deleted keys [b] in txn, got %q", deleted)
+	}
+}
+
 func TestKVGetErrConnClosed(t *testing.T) {
 	defer testutil.AfterTest(t)
 
@@ -123,3 +123,56 @@ func TestMirrorSyncBase(t *testing.T) {
 		t.Errorf("unexpected kv count: %d", count)
 	}
 }
+
+func TestMirrorSyncPrefixesTransform(t *testing.T) {
+	defer testutil.AfterTest(t)
+
+	clus := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	c := clus.Client(0)
+	for _, key := range []string{"a/1", "a/skip/1", "b/1", "c/1"} {
+		if _, err := c.Put(context.TODO(), key, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	syncer := mirror.NewSyncer(c, "a/", 0,
+		mirror.WithPrefixes("c/"),
+		mirror.WithExcludePrefixes("a/skip/"),
+		mirror.WithTransform(func(key, value []byte) ([]byte, []byte) {
+			return append([]byte("dst/"), key...), value
+		}),
+	)
+	gch, ech := syncer.SyncBase(context.TODO())
+	var keys []string
+	for g := range gch {
+		for _, kv := range g.Kvs {
+			keys = append(keys, string(kv.Key))
+		}
+	}
+	for e := range ech {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if wkeys := []string{"dst/a/1", "dst/c/1"}; !reflect.DeepEqual(keys, wkeys) {
+		t.Fatalf("keys = %q, want %q", keys, wkeys)
+	}
+
+	wch := syncer.SyncUpdates(context.TODO())
+	for _, key := range []string{"b/2", "a/skip/2", "c/2"} {
+		if _, err := c.Put(context.TODO(), key, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case r := <-wch:
+		if len(r.Events) != 1 || string(r.Events[0].Kv.Key) != "dst/c/2" {
+			t.Fatalf("events = %v, want a put of dst/c/2", r.Events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed to receive update in one second")
+	}
+	if p := syncer.Progress(); p.BaseKeys != 2 || p.Updates != 1 || p.Revision != 8 {
+		t.Fatalf("progress = %+v, want 2 base keys, 1 update at revision 8", p)
+	}
+}
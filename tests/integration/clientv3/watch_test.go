@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -922,6 +923,67 @@ func TestWatchWithFilter(t *testing.T) {
 	}
 }
 
+// TestWatchWithKeyValueFilters checks that the server only sends the events
+// matching the key and value filters of a watcher.
+func TestWatchWithKeyValueFilters(t *testing.T) {
+	cluster := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer cluster.Terminate(t)
+
+	client := cluster.RandClient()
+	ctx := context.Background()
+
+	wcPattern := client.Watch(ctx, "/pods/", clientv3.WithPrefix(), clientv3.WithKeyPattern("/pods/*/status"))
+	wcJSON := client.Watch(ctx, "/pods/", clientv3.WithPrefix(), clientv3.WithValueJSONPath("phase", `"Failed"`))
+	wcRegex := client.Watch(ctx, "/pods/", clientv3.WithPrefix(), clientv3.WithKeyRegex("^/pods/b/"))
+
+	puts := [][2]string{
+		{"/pods/a/spec", `{}`},
+		{"/pods/a/status", `{"phase":"Running"}`},
+		{"/pods/b/status", `{"phase":"Failed"}`},
+	}
+	for _, kv := range puts {
+		if _, err := client.Put(ctx, kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var keys []string
+	for len(keys) < 2 {
+		resp := <-wcPattern
+		for _, ev := range resp.Events {
+			keys = append(keys, string(ev.Kv.Key))
+		}
+	}
+	if wkeys := []string{"/pods/a/status", "/pods/b/status"}; !reflect.DeepEqual(keys, wkeys) {
+		t.Fatalf("expected events of %v, got %v", wkeys, keys)
+	}
+	resp := <-wcJSON
+	if len(resp.Events) != 1 || string(resp.Events[0].Kv.Key) != "/pods/b/status" {
+		t.Fatalf("expected put event of /pods/b/status, got %+v", resp.Events)
+	}
+	resp = <-wcRegex
+	if len(resp.Events) != 1 || string(resp.Events[0].Kv.Key) != "/pods/b/status" {
+		t.Fatalf("expected put event of /pods/b/status, got %+v", resp.Events)
+	}
+	// the progress notifications are not filtered
+	if err := client.RequestProgress(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case resp = <-wcRegex:
+		if !resp.IsProgressNotify() {
+			t.Fatalf("expected a progress notification, got %+v", resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a progress notification")
+	}
+
+	resp = <-client.Watch(ctx, "/pods/", clientv3.WithPrefix(), clientv3.WithKeyRegex("(a"))
+	if err := resp.Err(); !resp.Canceled || err == nil || !strings.Contains(err.Error(), rpctypes.ErrorDesc(rpctypes.ErrGRPCInvalidWatchFilter)) {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrGRPCInvalidWatchFilter, err)
+	}
+}
+
 // TestWatchWithCreatedNotification checks that WithCreatedNotify returns a
 // Created watch response.
 func TestWatchWithCreatedNotification(t *testing.T) {
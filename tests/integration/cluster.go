@@ -131,6 +131,7 @@ type ClusterConfig struct {
 	UseGRPC bool
 
 	QuotaBackendBytes int64
+	PrefixQuotas      []etcdserver.PrefixQuota
 
 	MaxTxnOps              uint
 	MaxRequestBytes        uint
@@ -289,6 +290,7 @@ func (c *cluster) mustNewMember(t testing.TB) *member {
 			peerTLS:                     c.cfg.PeerTLS,
 			clientTLS:                   c.cfg.ClientTLS,
 			quotaBackendBytes:           c.cfg.QuotaBackendBytes,
+			prefixQuotas:                c.cfg.PrefixQuotas,
 			maxTxnOps:                   c.cfg.MaxTxnOps,
 			maxRequestBytes:             c.cfg.MaxRequestBytes,
 			snapshotCount:               c.cfg.SnapshotCount,
@@ -588,6 +590,7 @@ type memberConfig struct {
 	clientTLS                   *transport.TLSInfo
 	authToken                   string
 	quotaBackendBytes           int64
+	prefixQuotas                []etcdserver.PrefixQuota
 	maxTxnOps                   uint
 	maxRequestBytes             uint
 	snapshotCount               uint64
@@ -649,6 +652,7 @@ func mustNewMember(t testing.TB, mcfg memberConfig) *member {
 	m.InitialElectionTickAdvance = true
 	m.TickMs = uint(tickDuration / time.Millisecond)
 	m.QuotaBackendBytes = mcfg.quotaBackendBytes
+	m.PrefixQuotas = mcfg.prefixQuotas
 	m.MaxTxnOps = mcfg.maxTxnOps
 	if m.MaxTxnOps == 0 {
 		m.MaxTxnOps = embed.DefaultMaxTxnOps
@@ -142,6 +142,140 @@ func TestSnapshotV3RestoreMulti(t *testing.T) {
 	}
 }
 
+// TestSnapshotV3RestoreIncremental ensures that a single node cluster
+// restored from a snapshot and an incremental backup has the keys and the
+// revision of the original cluster.
+func TestSnapshotV3RestoreIncremental(t *testing.T) {
+	testutil.SkipTestIfShortMode(t,
+		"Snapshot creation tests are depending on embedded etcServer so are integration-level tests.")
+	urls := newEmbedURLs(2)
+	cfg := embed.NewConfig()
+	cfg.Logger = "zap"
+	cfg.LogOutputs = []string{"/dev/null"}
+	cfg.Name = "default"
+	cfg.ClusterState = "new"
+	cfg.LCUrls, cfg.ACUrls = urls[:1], urls[:1]
+	cfg.LPUrls, cfg.APUrls = urls[1:], urls[1:]
+	cfg.InitialCluster = fmt.Sprintf("%s=%s", cfg.Name, urls[1].String())
+	cfg.Dir = filepath.Join(os.TempDir(), fmt.Sprint(time.Now().Nanosecond()))
+	srv, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.RemoveAll(cfg.Dir)
+		srv.Close()
+	}()
+	select {
+	case <-srv.Server.ReadyNotify():
+	case <-time.After(3 * time.Second):
+		t.Fatalf("failed to start embed.Etcd for creating snapshots")
+	}
+
+	ccfg := clientv3.Config{Endpoints: []string{cfg.ACUrls[0].String()}}
+	cli, err := clientv3.New(ccfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	ctx := context.Background()
+	for _, k := range []string{"foo1", "foo2", "foo3"} {
+		if _, err = cli.Put(ctx, k, "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sp := snapshot.NewV3(zap.NewExample())
+	dbPath := filepath.Join(os.TempDir(), fmt.Sprintf("snapshot%d.db", time.Now().Nanosecond()))
+	defer os.RemoveAll(dbPath)
+	if err = sp.Save(ctx, ccfg, dbPath); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := sp.Status(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cli.Put(ctx, "foo1", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Delete(ctx, "foo2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Txn(ctx).Then(clientv3.OpPut("foo4", "bar"), clientv3.OpPut("foo5", "bar")).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	incPath := dbPath + ".inc"
+	defer os.RemoveAll(incPath)
+	st, err := sp.SaveIncremental(ctx, ccfg, ds.Revision, incPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Revision != ds.Revision+3 || st.Events != 4 {
+		t.Fatalf("incremental backup expected revision %d with 4 events, got %+v", ds.Revision+3, st)
+	}
+	cli.Close()
+	srv.Close()
+
+	rurls := newEmbedURLs(2)
+	rcfg := embed.NewConfig()
+	rcfg.Logger = "zap"
+	rcfg.LogOutputs = []string{"/dev/null"}
+	rcfg.Name = "s1"
+	rcfg.InitialClusterToken = testClusterTkn
+	rcfg.ClusterState = "existing"
+	rcfg.LCUrls, rcfg.ACUrls = rurls[:1], rurls[:1]
+	rcfg.LPUrls, rcfg.APUrls = rurls[1:], rurls[1:]
+	rcfg.InitialCluster = fmt.Sprintf("%s=%s", rcfg.Name, rurls[1].String())
+	rcfg.Dir = filepath.Join(os.TempDir(), fmt.Sprint(time.Now().Nanosecond()))
+	if err = sp.Restore(snapshot.RestoreConfig{
+		SnapshotPath:        dbPath,
+		Name:                rcfg.Name,
+		OutputDataDir:       rcfg.Dir,
+		InitialCluster:      rcfg.InitialCluster,
+		InitialClusterToken: rcfg.InitialClusterToken,
+		PeerURLs:            []string{rurls[1].String()},
+		IncrementalPaths:    []string{incPath},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rsrv, err := embed.StartEtcd(rcfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.RemoveAll(rcfg.Dir)
+		rsrv.Close()
+	}()
+	select {
+	case <-rsrv.Server.ReadyNotify():
+	case <-time.After(3 * time.Second):
+		t.Fatalf("failed to start restored etcd member")
+	}
+
+	rcli, err := clientv3.New(clientv3.Config{Endpoints: []string{rcfg.ACUrls[0].String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rcli.Close()
+	gresp, err := rcli.Get(ctx, "foo", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wkvs := []kv{{"foo1", "baz"}, {"foo3", "bar"}, {"foo4", "bar"}, {"foo5", "bar"}}
+	if len(gresp.Kvs) != len(wkvs) {
+		t.Fatalf("expected %d keys, got %d", len(wkvs), len(gresp.Kvs))
+	}
+	for i := range wkvs {
+		if string(gresp.Kvs[i].Key) != wkvs[i].k || string(gresp.Kvs[i].Value) != wkvs[i].v {
+			t.Fatalf("#%d: expected %s=%s, got %s=%s", i, wkvs[i].k, wkvs[i].v, gresp.Kvs[i].Key, gresp.Kvs[i].Value)
+		}
+	}
+	if gresp.Kvs[3].ModRevision != st.Revision {
+		t.Fatalf("foo5 mod revision expected %d, got %d", st.Revision, gresp.Kvs[3].ModRevision)
+	}
+}
+
 // TestCorruptedBackupFileCheck tests if we can correctly identify a corrupted backup file.
 func TestCorruptedBackupFileCheck(t *testing.T) {
 	dbPath := "testdata/corrupted_backup.db"
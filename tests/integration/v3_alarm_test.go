@@ -26,6 +26,7 @@ import (
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/pkg/v3/testutil"
 	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/v3/etcdserver"
 	"go.etcd.io/etcd/v3/etcdserver/cindex"
 	"go.etcd.io/etcd/v3/mvcc"
 	"go.etcd.io/etcd/v3/mvcc/backend"
@@ -146,6 +147,93 @@ func TestV3AlarmDeactivate(t *testing.T) {
 	}
 }
 
+// TestV3PrefixQuotaApply ensures that writes over the quota of a prefix are
+// rejected and raise a PREFIX_QUOTA alarm, disarmed once the prefix is back
+// under its quota, while other prefixes are unaffected.
+func TestV3PrefixQuotaApply(t *testing.T) {
+	defer testutil.AfterTest(t)
+	clus := NewClusterV3(t, &ClusterConfig{
+		Size:         3,
+		PrefixQuotas: []etcdserver.PrefixQuota{{Prefix: "/a/", MaxKeys: 1}},
+	})
+	defer clus.Terminate(t)
+	kvc := toGRPC(clus.RandClient()).KV
+
+	if _, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("/a/x"), Value: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("/a/y"), Value: []byte("1")})
+	if !eqErrGRPC(err, rpctypes.ErrGRPCPrefixQuotaExceeded) {
+		t.Fatalf("put got %v, expected %v", err, rpctypes.ErrGRPCPrefixQuotaExceeded)
+	}
+	txn := &pb.TxnRequest{Success: []*pb.RequestOp{{Request: &pb.RequestOp_RequestPut{
+		RequestPut: &pb.PutRequest{Key: []byte("/a/y"), Value: []byte("1")}}}}}
+	if _, err = kvc.Txn(context.TODO(), txn); !eqErrGRPC(err, rpctypes.ErrGRPCPrefixQuotaExceeded) {
+		t.Fatalf("txn got %v, expected %v", err, rpctypes.ErrGRPCPrefixQuotaExceeded)
+	}
+	if _, err = kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("/b/y"), Value: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait until alarm is raised for sure-- poll the alarms
+	stopc := time.After(5 * time.Second)
+	for {
+		req := &pb.AlarmRequest{Action: pb.AlarmRequest_GET, Alarm: pb.AlarmType_PREFIX_QUOTA}
+		resp, aerr := clus.Members[0].s.Alarm(context.TODO(), req)
+		if aerr != nil {
+			t.Fatal(aerr)
+		}
+		if len(resp.Alarms) != 0 {
+			break
+		}
+		select {
+		case <-stopc:
+			t.Fatalf("timed out waiting for alarm")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// deleting a key of the prefix frees its quota and disarms the alarm
+	if _, err = kvc.DeleteRange(context.TODO(), &pb.DeleteRangeRequest{Key: []byte("/a/x")}); err != nil {
+		t.Fatal(err)
+	}
+	stopc = time.After(5 * time.Second)
+	for {
+		req := &pb.AlarmRequest{Action: pb.AlarmRequest_GET, Alarm: pb.AlarmType_PREFIX_QUOTA}
+		resp, aerr := clus.Members[0].s.Alarm(context.TODO(), req)
+		if aerr != nil {
+			t.Fatal(aerr)
+		}
+		if len(resp.Alarms) == 0 {
+			break
+		}
+		select {
+		case <-stopc:
+			t.Fatalf("timed out waiting for alarm to be disarmed, got %+v", resp.Alarms)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if _, err = kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("/a/y"), Value: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a txn replacing the key of the prefix stays within its quota
+	txn = &pb.TxnRequest{Success: []*pb.RequestOp{
+		{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("/a/y")}}},
+		{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("/a/z"), Value: []byte("1")}}},
+	}}
+	tresp, err := kvc.Txn(context.TODO(), txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kvs := tresp.Responses[0].GetResponseDeleteRange().PrevKvs; len(kvs) != 0 {
+		t.Fatalf("got unrequested previous key-values %v", kvs)
+	}
+	if _, err = kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("/a/x"), Value: []byte("1")}); !eqErrGRPC(err, rpctypes.ErrGRPCPrefixQuotaExceeded) {
+		t.Fatalf("put got %v, expected %v", err, rpctypes.ErrGRPCPrefixQuotaExceeded)
+	}
+}
+
 func TestV3CorruptAlarm(t *testing.T) {
 	defer testutil.AfterTest(t)
 	clus := NewClusterV3(t, &ClusterConfig{Size: 3})
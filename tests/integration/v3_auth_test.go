@@ -15,8 +15,10 @@
 package integration
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -26,6 +28,10 @@ import (
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/pkg/v3/testutil"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // TestV3AuthEmptyUserGet ensures that a get with an empty user will return an empty user error.
@@ -356,6 +362,240 @@ func TestV3AuthNonAuthorizedRPCs(t *testing.T) {
 	}
 }
 
+// TestV3AuthUserTokenRevoke ensures that the revoked tokens of a user are
+// rejected, and that only root can revoke the tokens of another user.
+func TestV3AuthUserTokenRevoke(t *testing.T) {
+	defer testutil.AfterTest(t)
+	clus := NewClusterV3(t, &ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	api := toGRPC(clus.Client(0))
+	users := []user{
+		{
+			name:     "user1",
+			password: "user1-123",
+			role:     "role1",
+			key:      "k1",
+			end:      "k3",
+		},
+		{
+			name:     "user2",
+			password: "user2-123",
+			role:     "role2",
+			key:      "k2",
+			end:      "k4",
+		},
+	}
+	authSetupUsers(t, api.Auth, users)
+	authSetupRoot(t, api.Auth)
+
+	aresp, err := api.Auth.Authenticate(context.TODO(), &pb.AuthenticateRequest{Name: "user1", Password: "user1-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tctx := metadata.AppendToOutgoingContext(context.TODO(), rpctypes.TokenFieldNameGRPC, aresp.Token)
+	if _, err = api.KV.Range(tctx, &pb.RangeRequest{Key: []byte("k1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	user2c, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "user2", Password: "user2-123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer user2c.Close()
+	if _, err = user2c.UserTokenRevoke(context.TODO(), "user1"); err != rpctypes.ErrPermissionDenied {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrPermissionDenied, err)
+	}
+	if _, err = user2c.UserTokenRevoke(context.TODO(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = api.KV.Range(tctx, &pb.RangeRequest{Key: []byte("k1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	rootc, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "root", Password: "123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer rootc.Close()
+	if _, err = rootc.UserTokenRevoke(context.TODO(), "user1"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.KV.Range(tctx, &pb.RangeRequest{Key: []byte("k1")})
+	if rpctypes.Error(err) != rpctypes.ErrInvalidAuthToken {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrInvalidAuthToken, err)
+	}
+}
+
+// TestV3AuthNestedTxn ensures that the operations of nested txns are
+// checked against the permissions of the user, as the top-level ones are.
+func TestV3AuthNestedTxn(t *testing.T) {
+	defer testutil.AfterTest(t)
+	clus := NewClusterV3(t, &ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	api := toGRPC(clus.Client(0))
+	authSetupUsers(t, api.Auth, []user{{name: "user1", password: "user1-123", role: "role1", key: "k1", end: "k3"}})
+	authSetupRoot(t, api.Auth)
+
+	user1c, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "user1", Password: "user1-123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer user1c.Close()
+
+	nested := func(ops ...clientv3.Op) clientv3.Op {
+		return clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpTxn(nil, ops, nil)}, nil)
+	}
+	if _, err := user1c.Txn(context.TODO()).Then(nested(clientv3.OpPut("k1", "v"), clientv3.OpGet("k2"))).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []clientv3.Op{
+		nested(clientv3.OpGet("k5")),
+		nested(clientv3.OpPut("k5", "v")),
+		nested(clientv3.OpDelete("k5")),
+		nested(clientv3.OpDelete("k", clientv3.WithPrefix())),
+		clientv3.OpTxn([]clientv3.Cmp{clientv3.Compare(clientv3.Version("k5"), "=", 0)}, nil, nil),
+		nested(clientv3.OpTxn([]clientv3.Cmp{clientv3.Compare(clientv3.Version("k5"), "=", 0)}, nil, nil)),
+	}
+	for i, op := range tests {
+		if _, err := user1c.Txn(context.TODO()).Then(op).Commit(); err != rpctypes.ErrPermissionDenied {
+			t.Errorf("#%d: expected %v, got %v", i, rpctypes.ErrPermissionDenied, err)
+		}
+	}
+}
+
+// TestV3AuthMaintenancePolicy ensures that only root stores the maintenance
+// policy and reads the maintenance status, and that the policy is stored
+// out of the key space.
+func TestV3AuthMaintenancePolicy(t *testing.T) {
+	defer testutil.AfterTest(t)
+	clus := NewClusterV3(t, &ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	api := toGRPC(clus.Client(0))
+	authSetupUsers(t, api.Auth, []user{{name: "user1", password: "user1-123", role: "role1", key: "\x00", end: "\x00"}})
+	authSetupRoot(t, api.Auth)
+
+	user1c, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "user1", Password: "user1-123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer user1c.Close()
+	policy := []byte(`{"compact_retention":"1h"}`)
+	if _, err := user1c.PolicyPut(context.TODO(), clientv3.PolicyMaintenance, policy); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("policy put: expected permission denied, got %v", err)
+	}
+	if _, err := user1c.PolicyGet(context.TODO(), clientv3.PolicyMaintenance); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("policy get: expected permission denied, got %v", err)
+	}
+	if _, err := user1c.ScheduleStatus(context.TODO(), clus.Client(0).Endpoints()[0]); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("schedule status: expected permission denied, got %v", err)
+	}
+
+	rootc, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "root", Password: "123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer rootc.Close()
+	if _, err := rootc.PolicyPut(context.TODO(), clientv3.PolicyMaintenance, []byte(`{"defrag_cron":"0 3 * *"}`)); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid policy put: expected %v, got %v", codes.InvalidArgument, err)
+	}
+	if _, err := rootc.PolicyPut(context.TODO(), clientv3.PolicyMaintenance, policy); err != nil {
+		t.Fatal(err)
+	}
+	presp, err := rootc.PolicyGet(context.TODO(), clientv3.PolicyMaintenance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(presp.Policy, policy) {
+		t.Fatalf("expected policy %q, got %q", policy, presp.Policy)
+	}
+	gresp, err := rootc.Get(context.TODO(), "\x00", clientv3.WithFromKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gresp.Kvs) != 0 {
+		t.Fatalf("expected no key, got %+v", gresp.Kvs)
+	}
+	resp, err := rootc.ScheduleStatus(context.TODO(), clus.Client(0).Endpoints()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Enabled {
+		t.Fatal("expected the maintenance scheduler to be disabled")
+	}
+
+	if _, err = rootc.PolicyPut(context.TODO(), clientv3.PolicyMaintenance, nil); err != nil {
+		t.Fatal(err)
+	}
+	if presp, err = rootc.PolicyGet(context.TODO(), clientv3.PolicyMaintenance); err != nil || len(presp.Policy) != 0 {
+		t.Fatalf("expected no policy, got %q (%v)", presp.Policy, err)
+	}
+}
+
+// TestV3AuthRateLimitPolicy ensures that only root writes the rate limit
+// policy and reads the rate limit status, even with a permission on every
+// key, and that the policy is stored out of the key space.
+func TestV3AuthRateLimitPolicy(t *testing.T) {
+	defer testutil.AfterTest(t)
+	clus := NewClusterV3(t, &ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	api := toGRPC(clus.Client(0))
+	authSetupUsers(t, api.Auth, []user{{name: "user1", password: "user1-123", role: "role1", key: "\x00", end: "\x00"}})
+	authSetupRoot(t, api.Auth)
+
+	user1c, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "user1", Password: "user1-123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer user1c.Close()
+	policy := []byte(`{"default":{"reads":{"rate":1}}}`)
+	if _, err := user1c.PolicyPut(context.TODO(), clientv3.PolicyRateLimit, policy); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("policy put: expected permission denied, got %v", err)
+	}
+	if _, err := user1c.RateLimitStatus(context.TODO(), clus.Client(0).Endpoints()[0]); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("rate limit status: expected permission denied, got %v", err)
+	}
+
+	rootc, cerr := clientv3.New(clientv3.Config{Endpoints: clus.Client(0).Endpoints(), Username: "root", Password: "123"})
+	if cerr != nil {
+		t.Fatal(cerr)
+	}
+	defer rootc.Close()
+	if _, err := rootc.PolicyPut(context.TODO(), clientv3.PolicyRateLimit, []byte(`{"networks":{"nope":{}}}`)); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid policy put: expected %v, got %v", codes.InvalidArgument, err)
+	}
+	if _, err := rootc.PolicyPut(context.TODO(), clientv3.PolicyRateLimit, policy); err != nil {
+		t.Fatal(err)
+	}
+	presp, err := rootc.PolicyGet(context.TODO(), clientv3.PolicyRateLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(presp.Policy, policy) {
+		t.Fatalf("expected policy %q, got %q", policy, presp.Policy)
+	}
+	if presp, err = rootc.PolicyGet(context.TODO(), clientv3.PolicyMaintenance); err != nil || len(presp.Policy) != 0 {
+		t.Fatalf("expected no maintenance policy, got %q (%v)", presp.Policy, err)
+	}
+	resp, err := rootc.RateLimitStatus(context.TODO(), clus.Client(0).Endpoints()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Enabled {
+		t.Fatal("expected the rate limit to be disabled")
+	}
+
+	if _, err = rootc.PolicyPut(context.TODO(), clientv3.PolicyRateLimit, nil); err != nil {
+		t.Fatal(err)
+	}
+	if presp, err = rootc.PolicyGet(context.TODO(), clientv3.PolicyRateLimit); err != nil || len(presp.Policy) != 0 {
+		t.Fatalf("expected no policy, got %q (%v)", presp.Policy, err)
+	}
+}
+
 func TestV3AuthOldRevConcurrent(t *testing.T) {
 	t.Skip() // TODO(jingyih): re-enable the test when #10408 is fixed.
 	defer testutil.AfterTest(t)
@@ -938,6 +938,56 @@ func TestV3PutIgnoreLease(t *testing.T) {
 	}
 }
 
+// TestV3PutExpiry ensures that keys put with a ttl are deleted once it elapses,
+// unless they are put again.
+func TestV3PutExpiry(t *testing.T) {
+	defer testutil.AfterTest(t)
+
+	clus := NewClusterV3(t, &ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	kvc := toGRPC(clus.RandClient()).KV
+
+	if _, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar"), Ttl: -1}); !eqErrGRPC(err, rpctypes.ErrGRPCInvalidKeyTTL) {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrGRPCInvalidKeyTTL)
+	}
+
+	puts := []pb.PutRequest{
+		{Key: []byte("foo"), Value: []byte("bar"), Ttl: 1},
+		{Key: []byte("foo1"), Value: []byte("bar"), Ttl: 1},
+		{Key: []byte("foo1"), Value: []byte("bar1")},
+		{Key: []byte("foo2"), Value: []byte("bar"), Ttl: 1},
+		{Key: []byte("foo2"), Value: []byte("bar2"), Ttl: 60},
+	}
+	for i := range puts {
+		if _, err := kvc.Put(context.TODO(), &puts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rreq := &pb.RangeRequest{Key: []byte("foo"), RangeEnd: []byte("fop")}
+	var rresp *pb.RangeResponse
+	for i := 0; i < 20; i++ {
+		var err error
+		if rresp, err = kvc.Range(context.TODO(), rreq); err != nil {
+			t.Fatal(err)
+		}
+		if len(rresp.Kvs) == 2 {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if len(rresp.Kvs) != 2 {
+		t.Fatalf("expected foo to expire, got %+v", rresp.Kvs)
+	}
+	if kv := rresp.Kvs[0]; string(kv.Key) != "foo1" || kv.Ttl != 0 {
+		t.Fatalf("unexpected kv %+v", kv)
+	}
+	if kv := rresp.Kvs[1]; string(kv.Key) != "foo2" || kv.Ttl != 60 {
+		t.Fatalf("unexpected kv %+v", kv)
+	}
+}
+
 // TestV3PutMissingLease ensures that a Put on a key with a bogus lease fails.
 func TestV3PutMissingLease(t *testing.T) {
 	defer testutil.AfterTest(t)
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -287,3 +288,60 @@ func TestSTMSerializableSnapshotPut(t *testing.T) {
 		t.Fatalf("bad version. got %+v, expected version 2", resp)
 	}
 }
+
+// TestSTMGetPrefixConflict tests that a write to a read prefix is a conflict.
+func TestSTMGetPrefixConflict(t *testing.T) {
+	clus := NewClusterV3(t, &ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	_, err := cli.Put(context.TODO(), "pfx/a", "1")
+	testutil.AssertNil(t, err)
+
+	for _, iso := range []concurrency.Isolation{concurrency.SerializableSnapshot, concurrency.Serializable, concurrency.RepeatableReads} {
+		tries := 0
+		var got map[string]string
+		applyf := func(stm concurrency.STM) error {
+			tries++
+			stm.Put("pfx/b", "2")
+			got = stm.GetPrefix("pfx/")
+			if tries == 1 {
+				// a key added to the prefix after it was read
+				if _, err := cli.Put(context.TODO(), fmt.Sprintf("pfx/new-%d", iso), "3"); err != nil {
+					return err
+				}
+			}
+			stm.Put("sum", strconv.Itoa(len(got)))
+			return nil
+		}
+		_, err = concurrency.NewSTM(cli, applyf, concurrency.WithIsolation(iso))
+		testutil.AssertNil(t, err)
+		if tries != 2 {
+			t.Fatalf("isolation %d: STM apply expected to run twice, got %d", iso, tries)
+		}
+		if got["pfx/b"] != "2" || got[fmt.Sprintf("pfx/new-%d", iso)] != "3" {
+			t.Fatalf("isolation %d: prefix = %v, want pending put and new key", iso, got)
+		}
+	}
+}
+
+func TestSTMPendingWrites(t *testing.T) {
+	clus := NewClusterV3(t, &ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	var ws []concurrency.STMWrite
+	applyf := func(stm concurrency.STM) error {
+		stm.Put("b", "1")
+		stm.Del("a")
+		ws = stm.PendingWrites()
+		return nil
+	}
+	runner := concurrency.NewSTMRunner(clus.Client(0), concurrency.WithIsolation(concurrency.Serializable))
+	_, err := runner.Run(applyf, concurrency.WithIsolation(concurrency.ReadCommitted))
+	testutil.AssertNil(t, err)
+
+	wws := []concurrency.STMWrite{{Key: "a", Delete: true}, {Key: "b", Value: "1"}}
+	if !reflect.DeepEqual(ws, wws) {
+		t.Fatalf("pending writes = %+v, want %+v", ws, wws)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/etcd/pkg/v3/fileutil"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/v3/wal/walpb"
+	"go.uber.org/zap"
+)
+
+// TruncateReport describes the records dropped by truncating a WAL.
+type TruncateReport struct {
+	// Reason is why the WAL is truncated.
+	Reason string
+	// File is the WAL file truncated at Offset, or empty if the WAL is cut
+	// at the start of a file. The files after it, RemovedFiles, are
+	// removed.
+	File         string
+	Offset       int64
+	RemovedFiles []string
+
+	// Entries is the number of entry records dropped, from FirstIndex to
+	// LastIndex. Entries of a corrupted file past the corruption cannot be
+	// read and are not counted.
+	Entries    int
+	FirstIndex uint64
+	LastIndex  uint64
+	// DroppedCommit is the highest commit index of the dropped hard
+	// states. The dropped entries up to it were committed, and are only
+	// recovered if the other members still have them.
+	DroppedCommit uint64
+}
+
+// cutPoint is the position of the first dropped record.
+type cutPoint struct {
+	file   int
+	offset int64
+	reason string
+}
+
+// PlanTruncate reads through the WAL and returns what truncating it would
+// drop: the records from the first corrupted or torn one, or, if index is
+// not 0, from the first entry at index or after. It returns nil if nothing
+// would be dropped. It fails to truncate at an index the latest snapshot
+// recorded in the WAL is at or after, since the member could not start from
+// that snapshot any more.
+func PlanTruncate(lg *zap.Logger, dirpath string, index uint64) (*TruncateReport, error) {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	names, err := readWALNames(lg, dirpath)
+	if err != nil {
+		return nil, err
+	}
+	if !isValidSeq(lg, names) {
+		return nil, fmt.Errorf("wal: non-contiguous sequence of WAL files in %s", dirpath)
+	}
+
+	var (
+		cut      *cutPoint
+		r        TruncateReport
+		lastSnap uint64
+		crc      uint32
+		rec      walpb.Record
+	)
+	for i, name := range names {
+		f, err := os.Open(filepath.Join(dirpath, name))
+		if err != nil {
+			return nil, err
+		}
+		d := newDecoder(f)
+		d.updateCRC(crc)
+		for {
+			off := d.lastOffset()
+			err := d.decode(&rec)
+			if err == io.EOF {
+				break
+			}
+			if err == nil && rec.Type == crcType {
+				// the crc of a file must match the one the previous file ends with
+				if c := d.lastCRC(); c != 0 && rec.Validate(c) != nil {
+					err = ErrCRCMismatch
+				}
+				d.updateCRC(rec.Crc)
+			}
+			if err != nil {
+				if cut == nil {
+					reason := err.Error()
+					if err == io.ErrUnexpectedEOF {
+						reason = "torn write"
+					}
+					cut = &cutPoint{file: i, offset: off, reason: reason}
+				}
+				// the rest of the file cannot be read
+				break
+			}
+
+			switch rec.Type {
+			case entryType:
+				e := mustUnmarshalEntry(rec.Data)
+				if cut == nil && index > 0 && e.Index >= index {
+					cut = &cutPoint{file: i, offset: off, reason: fmt.Sprintf("truncated at index %d", index)}
+				}
+				if cut != nil {
+					if r.Entries == 0 || e.Index < r.FirstIndex {
+						r.FirstIndex = e.Index
+					}
+					if e.Index > r.LastIndex {
+						r.LastIndex = e.Index
+					}
+					r.Entries++
+				}
+			case stateType:
+				if st := mustUnmarshalState(rec.Data); cut != nil && st.Commit > r.DroppedCommit {
+					r.DroppedCommit = st.Commit
+				}
+			case snapshotType:
+				var snap walpb.Snapshot
+				pbutil.MustUnmarshal(&snap, rec.Data)
+				lastSnap = snap.Index
+			}
+		}
+		crc = d.lastCRC()
+		f.Close()
+	}
+
+	if cut == nil {
+		return nil, nil
+	}
+	if index > 0 && index <= lastSnap {
+		return nil, fmt.Errorf("wal: cannot truncate at index %d, at or before the latest snapshot at index %d", index, lastSnap)
+	}
+	if cut.file == 0 && cut.offset == 0 {
+		return nil, fmt.Errorf("wal: the first record of %s is corrupted, nothing can be kept", names[0])
+	}
+
+	r.Reason = cut.reason
+	removed := names[cut.file+1:]
+	if cut.offset == 0 {
+		removed = names[cut.file:]
+	} else {
+		r.File, r.Offset = filepath.Join(dirpath, names[cut.file]), cut.offset
+	}
+	for _, name := range removed {
+		r.RemovedFiles = append(r.RemovedFiles, filepath.Join(dirpath, name))
+	}
+	return &r, nil
+}
+
+// Truncate drops the records of the report from the WAL. As Repair does,
+// the truncated file is first copied to a ".broken" file, and the removed
+// files are renamed to ".broken" files, so that no data is lost for good.
+// The WAL must not be in use.
+func Truncate(lg *zap.Logger, dirpath string, r *TruncateReport) error {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	if r.File != "" {
+		if err := truncateFile(lg, r.File, r.Offset); err != nil {
+			return err
+		}
+	}
+	for _, p := range r.RemovedFiles {
+		// fails if the WAL is in use
+		l, err := fileutil.TryLockFile(p, os.O_RDWR, fileutil.PrivateFileMode)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(p, p+".broken")
+		l.Close()
+		if err != nil {
+			return err
+		}
+		lg.Info("removed WAL file", zap.String("path", p), zap.String("backup", p+".broken"))
+	}
+
+	d, err := fileutil.OpenDir(dirpath)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return fileutil.Fsync(d)
+}
+
+func truncateFile(lg *zap.Logger, p string, offset int64) error {
+	f, err := fileutil.TryLockFile(p, os.O_RDWR, fileutil.PrivateFileMode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bf, err := os.Create(p + ".broken")
+	if err != nil {
+		return err
+	}
+	defer bf.Close()
+	if _, err = io.Copy(bf, f); err != nil {
+		return err
+	}
+	if err = f.Truncate(offset); err != nil {
+		return err
+	}
+	if err = fileutil.Fsync(f.File); err != nil {
+		return err
+	}
+	lg.Info("truncated WAL file", zap.String("path", p), zap.Int64("offset", offset), zap.String("backup", p+".broken"))
+	return nil
+}
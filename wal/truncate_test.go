@@ -0,0 +1,156 @@
+// Copyright 2020 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wal
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/v3/wal/walpb"
+	"go.uber.org/zap"
+)
+
+// createTestWAL creates a WAL of ten entries, each saved with a hard state
+// committing it, and returns its directory and the offset of its end.
+func createTestWAL(t *testing.T) (string, int64) {
+	p, err := ioutil.TempDir(os.TempDir(), "waltest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := Create(zap.NewExample(), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for i, es := range makeEnts(10) {
+		if err = w.Save(raftpb.HardState{Term: 1, Commit: uint64(i + 1)}, es); err != nil {
+			t.Fatal(err)
+		}
+	}
+	offset, err := w.tail().Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, offset
+}
+
+func readTestWAL(t *testing.T, p string) (raftpb.HardState, []raftpb.Entry) {
+	w, err := Open(zap.NewExample(), p, walpb.Snapshot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, st, ents, err := w.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st, ents
+}
+
+func TestTruncateAtIndex(t *testing.T) {
+	p, _ := createTestWAL(t)
+	defer os.RemoveAll(p)
+
+	r, err := PlanTruncate(zap.NewExample(), p, 0)
+	if err != nil || r != nil {
+		t.Fatalf("expected nothing to drop, got %+v, %v", r, err)
+	}
+
+	r, err = PlanTruncate(zap.NewExample(), p, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Entries != 5 || r.FirstIndex != 6 || r.LastIndex != 10 || r.DroppedCommit != 10 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.File == "" || len(r.RemovedFiles) != 0 {
+		t.Fatalf("expected the only WAL file to be truncated, got %+v", r)
+	}
+	if err = Truncate(zap.NewExample(), p, r); err != nil {
+		t.Fatal(err)
+	}
+
+	st, ents := readTestWAL(t, p)
+	if len(ents) != 5 || ents[4].Index != 5 {
+		t.Fatalf("expected entries up to index 5, got %+v", ents)
+	}
+	if st.Commit != 5 {
+		t.Fatalf("commit = %d, want 5", st.Commit)
+	}
+	if _, err = os.Stat(r.File + ".broken"); err != nil {
+		t.Fatalf("expected a backup of the truncated file (%v)", err)
+	}
+}
+
+func TestTruncateTornWrite(t *testing.T) {
+	p, offset := createTestWAL(t)
+	defer os.RemoveAll(p)
+
+	f, err := openLast(zap.NewExample(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Truncate(offset - 4)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := PlanTruncate(zap.NewExample(), p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.Reason != "torn write" {
+		t.Fatalf("expected a torn write to drop, got %+v", r)
+	}
+	if err = Truncate(zap.NewExample(), p, r); err != nil {
+		t.Fatal(err)
+	}
+	// the torn hard state of the last entry is dropped
+	if st, ents := readTestWAL(t, p); len(ents) != 10 || st.Commit != 9 {
+		t.Fatalf("expected 10 entries committed up to 9, got %d entries and %+v", len(ents), st)
+	}
+}
+
+func TestPlanTruncateBeforeSnapshot(t *testing.T) {
+	p, err := ioutil.TempDir(os.TempDir(), "waltest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(p)
+	w, err := Create(zap.NewExample(), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, es := range makeEnts(10) {
+		if err = w.Save(raftpb.HardState{}, es); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.SaveSnapshot(walpb.Snapshot{Index: 5, Term: 1}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if _, err = PlanTruncate(zap.NewExample(), p, 5); err == nil {
+		t.Fatal("expected an error truncating at the snapshot index")
+	}
+	if r, err := PlanTruncate(zap.NewExample(), p, 6); err != nil || r.Entries != 5 {
+		t.Fatalf("expected 5 entries to drop, got %+v, %v", r, err)
+	}
+}